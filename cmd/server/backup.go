@@ -0,0 +1,50 @@
+package main
+
+import (
+	"browser-detection/internal/backup"
+	"browser-detection/internal/config"
+	"browser-detection/internal/utils"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+)
+
+// runBackup 生成数据库备份写入 backup.dir（配置了S3时同时上传），服务运行时也可执行
+func runBackup(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	fs.Parse(args)
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	db, err := utils.NewDatabase(cfg.DatabasePath, cfg.Storage.BusyTimeout.Std(), cfg.Storage.Shards)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	_, err = backup.New(cfg.Backup, db).Create(context.Background())
+	return err
+}
+
+// runRestore 用备份文件替换 DATABASE_PATH 处的数据库，须在服务停止时执行；
+// 恢复须在打开数据库之前进行，因此不打开数据库
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: server restore <backup file>")
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("expected exactly one backup file")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	return backup.Restore(fs.Arg(0), cfg.DatabasePath, cfg.Backup.EncryptionKey)
+}
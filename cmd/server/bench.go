@@ -0,0 +1,177 @@
+package main
+
+import (
+	"browser-detection/internal/models"
+	"browser-detection/internal/synth"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// benchResult 一次提交的结果
+type benchResult struct {
+	Latency  time.Duration
+	Status   int // 0 表示请求未得到响应（超时、连接失败）
+	Degraded bool
+}
+
+// runBench 以固定速率向运行中的实例提交合成指纹，报告延迟分位数、错误率和数据库写入吞吐
+// 采用开环压测：请求按时间表发出而不等待前一个返回，在途请求达到上限时丢弃并计数
+func runBench(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	target := fs.String("target", "http://localhost:8080", "base URL of the instance under test")
+	rate := fs.Float64("rate", 100, "submissions per second")
+	duration := fs.Duration("duration", 30*time.Second, "how long to send submissions")
+	maxInflight := fs.Int("max-inflight", 1000, "maximum concurrent requests; submissions beyond it are dropped and counted")
+	timeout := fs.Duration("timeout", 10*time.Second, "per-request timeout")
+	apiKey := fs.String("api-key", "", "X-API-Key header sent with each submission")
+	seed := fs.Int64("seed", time.Now().UnixNano(), "random seed for the generated fingerprints")
+	botRatio := fs.Float64("bot-ratio", 0.1, "share of bot-like fingerprints")
+	noiseRatio := fs.Float64("noise-ratio", 0.05, "share of fingerprints with injected canvas/audio noise")
+	returnRatio := fs.Float64("return-ratio", 0.2, "share of human fingerprints that repeat an earlier device")
+	fs.Parse(args)
+
+	if *rate <= 0 || *duration <= 0 || *maxInflight <= 0 {
+		return errors.New("-rate, -duration and -max-inflight must be positive")
+	}
+
+	base := strings.TrimRight(*target, "/")
+	client := &http.Client{
+		Timeout:   *timeout,
+		Transport: &http.Transport{MaxIdleConnsPerHost: *maxInflight},
+	}
+	storedBefore, err := storedFingerprints(client, base)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", base, err)
+	}
+
+	gen := synth.New(synth.Options{Seed: *seed, BotRatio: *botRatio, NoiseRatio: *noiseRatio, ReturnRatio: *returnRatio})
+	url := base + "/api/fingerprint"
+	var (
+		mu      sync.Mutex
+		results []benchResult
+		wg      sync.WaitGroup
+		dropped int
+	)
+	inflight := make(chan struct{}, *maxInflight)
+
+	log.Printf("Benchmarking %s at %.0f req/s for %s", url, *rate, *duration)
+	start := time.Now()
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / *rate))
+	for deadline := start.Add(*duration); time.Now().Before(deadline); {
+		<-ticker.C
+		sample := gen.Next()
+		select {
+		case inflight <- struct{}{}:
+		default:
+			dropped++
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-inflight }()
+			began := time.Now()
+			status, resp, _ := submitSample(client, url, *apiKey, sample)
+			r := benchResult{Latency: time.Since(began), Status: status, Degraded: resp != nil && resp.Degraded}
+			mu.Lock()
+			results = append(results, r)
+			mu.Unlock()
+		}()
+	}
+	ticker.Stop()
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	storedAfter, err := storedFingerprints(client, base)
+	if err != nil {
+		log.Printf("Failed to read stored fingerprint count: %v", err)
+	}
+	reportBench(results, dropped, elapsed, storedAfter-storedBefore, err == nil)
+	return nil
+}
+
+// reportBench 输出压测报告
+func reportBench(results []benchResult, dropped int, elapsed time.Duration, stored int, storedKnown bool) {
+	errorsByStatus := make(map[int]int)
+	degraded, failed := 0, 0
+	latencies := make([]time.Duration, 0, len(results))
+	for _, r := range results {
+		if r.Status != http.StatusOK {
+			errorsByStatus[r.Status]++
+			failed++
+		}
+		if r.Degraded {
+			degraded++
+		}
+		if r.Status != 0 {
+			latencies = append(latencies, r.Latency)
+		}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	total := len(results)
+	seconds := elapsed.Seconds()
+	fmt.Printf("Requests:    %d completed, %d ok, %d dropped (client at -max-inflight)\n", total, total-failed, dropped)
+	fmt.Printf("Throughput:  %.1f req/s\n", float64(total)/seconds)
+	if total > 0 {
+		fmt.Printf("Errors:      %d (%.2f%%)\n", failed, 100*float64(failed)/float64(total))
+	}
+	statuses := make([]int, 0, len(errorsByStatus))
+	for status := range errorsByStatus {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+	for _, status := range statuses {
+		label := fmt.Sprintf("HTTP %d", status)
+		if status == 0 {
+			label = "no response"
+		}
+		fmt.Printf("  %-11s %d\n", label+":", errorsByStatus[status])
+	}
+	if len(latencies) > 0 {
+		fmt.Printf("Latency:     p50 %s  p90 %s  p99 %s  max %s\n",
+			percentile(latencies, 0.5), percentile(latencies, 0.9), percentile(latencies, 0.99), percentile(latencies, 1))
+	}
+	fmt.Printf("Degraded:    %d responses scored without storage\n", degraded)
+	if storedKnown {
+		fmt.Printf("DB writes:   %d new fingerprints (%.1f/s), %.1f analyses/s\n",
+			stored, float64(stored)/seconds, float64(total-failed-degraded)/seconds)
+	}
+}
+
+// percentile 返回已排序延迟的分位数
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	idx := int(float64(len(sorted))*p+0.5) - 1
+	return sorted[max(0, min(idx, len(sorted)-1))].Round(10 * time.Microsecond)
+}
+
+// storedFingerprints 通过版本统计接口读取实例中已存储的指纹总数
+func storedFingerprints(client *http.Client, base string) (int, error) {
+	resp, err := client.Get(base + "/api/stats/versions")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	var body struct {
+		Versions []models.VersionCount `json:"versions"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, v := range body.Versions {
+		total += v.Count
+	}
+	return total, nil
+}
@@ -0,0 +1,11 @@
+package main
+
+// subcommands 以 `server <name> [flags]` 调用的子命令：gen、bench 不加载配置也不打开数据库，
+// 其余子命令按 CONFIG_FILE 和环境变量加载配置，执行完退出
+var subcommands = map[string]func(args []string) error{
+	"gen":     runGen,
+	"bench":   runBench,
+	"backup":  runBackup,
+	"restore": runRestore,
+	"replay":  runReplay,
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"browser-detection/internal/models"
+	"browser-detection/internal/synth"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// genWorkers 向目标实例提交样本的并发数
+const genWorkers = 16
+
+// genTally 某类样本的提交结果
+type genTally struct {
+	Sent    int
+	Failed  int
+	Flagged int // 被判定为爬虫
+}
+
+// runGen 生成合成指纹：未指定 -target 时按行输出JSON，否则以给定速率提交到运行中的实例
+func runGen(args []string) error {
+	fs := flag.NewFlagSet("gen", flag.ExitOnError)
+	count := fs.Int("n", 100, "number of fingerprints to generate")
+	seed := fs.Int64("seed", time.Now().UnixNano(), "random seed; the same seed yields the same fingerprints")
+	botRatio := fs.Float64("bot-ratio", 0.1, "share of bot-like fingerprints")
+	noiseRatio := fs.Float64("noise-ratio", 0.05, "share of fingerprints with injected canvas/audio noise")
+	returnRatio := fs.Float64("return-ratio", 0.2, "share of human fingerprints that repeat an earlier device")
+	target := fs.String("target", "", "base URL of a running instance to POST to, e.g. http://localhost:8080; prints JSON lines when empty")
+	rate := fs.Float64("rate", 10, "submissions per second when posting to -target")
+	apiKey := fs.String("api-key", "", "X-API-Key header sent with each submission")
+	fs.Parse(args)
+
+	if *count <= 0 || *rate <= 0 {
+		return errors.New("-n and -rate must be positive")
+	}
+	if *botRatio < 0 || *noiseRatio < 0 || *botRatio+*noiseRatio > 1 {
+		return errors.New("-bot-ratio and -noise-ratio must be non-negative and sum to at most 1")
+	}
+
+	gen := synth.New(synth.Options{Seed: *seed, BotRatio: *botRatio, NoiseRatio: *noiseRatio, ReturnRatio: *returnRatio})
+	if *target == "" {
+		enc := json.NewEncoder(os.Stdout)
+		for i := 0; i < *count; i++ {
+			if err := enc.Encode(gen.Next()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	url := strings.TrimRight(*target, "/") + "/api/fingerprint"
+	client := &http.Client{Timeout: 10 * time.Second}
+	samples := make(chan synth.Sample)
+	var (
+		mu      sync.Mutex
+		tally   = make(map[string]*genTally)
+		wg      sync.WaitGroup
+		lastErr error
+	)
+	for i := 0; i < genWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for s := range samples {
+				_, result, err := submitSample(client, url, *apiKey, s)
+				flagged := result != nil && result.Analysis != nil && result.Analysis.IsBot
+				mu.Lock()
+				t := tally[s.Kind]
+				if t == nil {
+					t = &genTally{}
+					tally[s.Kind] = t
+				}
+				t.Sent++
+				if err != nil {
+					t.Failed++
+					lastErr = err
+				} else if flagged {
+					t.Flagged++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / *rate))
+	for i := 0; i < *count; i++ {
+		<-ticker.C
+		samples <- gen.Next()
+	}
+	ticker.Stop()
+	close(samples)
+	wg.Wait()
+
+	kinds := make([]string, 0, len(tally))
+	for kind := range tally {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		t := tally[kind]
+		log.Printf("%s: %d sent, %d failed, %d flagged as bot", kind, t.Sent, t.Failed, t.Flagged)
+	}
+	if lastErr != nil {
+		log.Printf("Last submission error: %v", lastErr)
+	}
+	return nil
+}
+
+// submitSample 提交一个样本，返回HTTP状态码与响应；状态码不是200时返回错误
+func submitSample(client *http.Client, url, apiKey string, s synth.Sample) (int, *models.FingerprintResponse, error) {
+	body, err := json.Marshal(s.Request)
+	if err != nil {
+		return 0, nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", s.Request.UserAgent)
+	req.Header.Set("X-Forwarded-For", s.IP)
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	var result models.FingerprintResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("unexpected response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, &result, fmt.Errorf("status %d: %s", resp.StatusCode, result.Message)
+	}
+	return resp.StatusCode, &result, nil
+}
@@ -3,36 +3,163 @@ package main
 import (
 	"browser-detection/internal/api/handlers"
 	"browser-detection/internal/api/routes"
+	"browser-detection/internal/backup"
+	"browser-detection/internal/config"
 	"browser-detection/internal/services"
-	"browser-detection/internal/utils"
+	"context"
+	"errors"
+	"flag"
 	"log"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"sort"
 	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
 )
 
 func main() {
-	// 初始化数据库
-	db, err := utils.NewDatabase("fingerprints.db")
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			if err := run(os.Args[2:]); err != nil {
+				log.Fatalf("%s: %v", os.Args[1], err)
+			}
+			return
+		}
+	}
+
+	rehashSite := flag.String("rehash-site", "", "rehash stored fingerprints of the site with its current hash_secret, then exit")
+	archivePath := flag.String("archive", "", "move fingerprints not seen for -archive-after, and soft-deleted ones, into the SQLite file, then exit")
+	archiveAfter := flag.Duration("archive-after", 90*24*time.Hour, "age after which fingerprints are archived by -archive")
+	replayJournal := flag.Bool("replay-journal", false, "replay submissions left unfinished in storage.journal_path by a crash, then exit; the server must be stopped")
+	exportPath := flag.String("export", "", "write a signed bundle of fingerprints, analyses and active blocklist entries to the file, then exit")
+	exportSite := flag.String("export-site", "", "limit -export to fingerprints of the site")
+	importPath := flag.String("import", "", "import a signed bundle written by -export, then exit")
+	exportFeatures := flag.String("export-ml-features", "", "write the ML features of labeled fingerprints to the CSV file for offline training, then exit")
+	importConflict := flag.String("import-conflict", services.BundleConflictNewer, "how -import resolves existing records: newer, skip or overwrite")
+	flag.Parse()
+
+	// 加载配置
+	cfg, err := config.Load()
 	if err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// 初始化数据库和服务
+	db, fingerprintService, err := openService(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize service: %v", err)
 	}
 	defer db.Close()
+	backups := backup.New(cfg.Backup, db)
+
+	if err := fingerprintService.OpenJournal(); err != nil {
+		log.Fatalf("Failed to open journal: %v", err)
+	}
+	defer fingerprintService.CloseJournal()
+
+	// 崩溃恢复：重放预写日志中未完成的提交后退出
+	if *replayJournal {
+		if _, err := fingerprintService.ReplayJournal(context.Background()); err != nil {
+			log.Fatalf("Failed to replay journal: %v", err)
+		}
+		return
+	}
 
-	// 初始化服务
-	fingerprintService := services.NewFingerprintService(db)
+	// 密钥轮换：重新计算已存储记录的哈希后退出
+	if *rehashSite != "" {
+		if _, err := fingerprintService.RehashSite(context.Background(), *rehashSite); err != nil {
+			log.Fatalf("Failed to rehash site %s: %v", *rehashSite, err)
+		}
+		return
+	}
+
+	// 归档：将冷数据移到单独的SQLite文件后退出
+	if *archivePath != "" {
+		if _, err := fingerprintService.ArchiveFingerprints(context.Background(), *archivePath, time.Now().Add(-*archiveAfter)); err != nil {
+			log.Fatalf("Failed to archive fingerprints: %v", err)
+		}
+		return
+	}
+
+	// 实例间同步：导出或导入签名的数据包后退出
+	if *exportPath != "" {
+		if err := fingerprintService.ExportBundle(context.Background(), *exportPath, *exportSite); err != nil {
+			log.Fatalf("Failed to export bundle: %v", err)
+		}
+		return
+	}
+	if *importPath != "" {
+		stats, err := fingerprintService.ImportBundle(context.Background(), *importPath, *importConflict, "system")
+		if err != nil {
+			log.Fatalf("Failed to import bundle: %v", err)
+		}
+		tables := make([]string, 0, len(stats))
+		for table := range stats {
+			tables = append(tables, table)
+		}
+		sort.Strings(tables)
+		for _, table := range tables {
+			s := stats[table]
+			log.Printf("Imported %s: %d inserted, %d updated, %d skipped", table, s.Inserted, s.Updated, s.Skipped)
+		}
+		return
+	}
+
+	// 模型训练：导出已标注指纹的特征向量后退出
+	if *exportFeatures != "" {
+		f, err := os.Create(*exportFeatures)
+		if err != nil {
+			log.Fatalf("Failed to create %s: %v", *exportFeatures, err)
+		}
+		n, err := fingerprintService.ExportMLFeatures(context.Background(), f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			log.Fatalf("Failed to export ML features: %v", err)
+		}
+		log.Printf("Exported ML features of %d labeled fingerprints to %s", n, *exportFeatures)
+		return
+	}
+
+	// 加载指纹特征向量的近邻索引
+	if err := fingerprintService.LoadVectorIndex(context.Background()); err != nil {
+		log.Fatalf("Failed to load vector index: %v", err)
+	}
+	saverCtx, stopSaver := context.WithCancel(context.Background())
+	defer stopSaver()
+	go fingerprintService.RunVectorIndexSaver(saverCtx)
+	go fingerprintService.RunFarmDetection(saverCtx)
+	go fingerprintService.RunOutlierTraining(saverCtx)
+	go fingerprintService.RunAnomalyDetection(saverCtx)
+	go fingerprintService.RunScoreDriftDetection(saverCtx)
+	go fingerprintService.RunRetention(saverCtx)
+	go backups.Run(saverCtx, fingerprintService.IsLeader)
+	go fingerprintService.RunLeaderElection(saverCtx)
+	go fingerprintService.RunBufferReplay(saverCtx)
+	go fingerprintService.RunThreatIntel(saverCtx)
+	go fingerprintService.RunBrowserReleases(saverCtx)
+	go fingerprintService.RunAccessLogs(saverCtx)
+	go fingerprintService.RunReplication(saverCtx)
 
 	// 初始化处理器
-	fingerprintHandler := handlers.NewFingerprintHandler(fingerprintService)
+	fingerprintHandler := handlers.NewFingerprintHandler(fingerprintService, cfg)
+	adminHandler := handlers.NewAdminHandler(fingerprintService)
+
+	collectorHandler, err := handlers.NewCollectorHandler("./static")
+	if err != nil {
+		log.Fatalf("Failed to initialize collector handler: %v", err)
+	}
 
 	// 设置路由
-	router := routes.SetupRoutes(fingerprintHandler)
+	router := routes.SetupRoutes(cfg, fingerprintService.Counters(), fingerprintHandler, collectorHandler, adminHandler)
 
 	// 启动服务器
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
+	port := cfg.Port
 
 	log.Printf("Starting server on port %s", port)
 	log.Printf("Access the application at http://localhost:%s", port)
@@ -41,14 +168,72 @@ func main() {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
+	// 配置超时以防御慢速客户端（slowloris）
+	server := &http.Server{
+		Addr:              ":" + port,
+		Handler:           router,
+		ReadTimeout:       cfg.Server.ReadTimeout.Std(),
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout.Std(),
+		WriteTimeout:      cfg.Server.WriteTimeout.Std(),
+		IdleTimeout:       cfg.Server.IdleTimeout.Std(),
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+	}
+
 	// 在goroutine中启动服务器
 	go func() {
-		if err := router.Run(":" + port); err != nil {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Fatalf("Failed to start server: %v", err)
 		}
 	}()
 
+	// 配置了 server.grpc_port 时同时提供指纹提交的gRPC服务
+	var grpcServer *grpc.Server
+	if cfg.Server.GRPCPort != "" {
+		lis, err := net.Listen("tcp", ":"+cfg.Server.GRPCPort)
+		if err != nil {
+			log.Fatalf("Failed to listen on gRPC port: %v", err)
+		}
+		grpcServer = handlers.NewGRPCServer(fingerprintHandler, cfg)
+		log.Printf("Starting gRPC server on port %s", cfg.Server.GRPCPort)
+		go func() {
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Fatalf("Failed to start gRPC server: %v", err)
+			}
+		}()
+	}
+
 	// 等待信号
 	<-quit
 	log.Println("Shutting down server...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.Std())
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Server forced to shutdown: %v", err)
+	}
+	if grpcServer != nil {
+		stopGRPC(ctx, grpcServer)
+	}
+	if err := fingerprintService.SaveVectorIndex(); err != nil {
+		log.Printf("Failed to save vector index: %v", err)
+	}
+	stopSaver()
+	if err := fingerprintService.StepDown(ctx); err != nil {
+		log.Printf("Failed to release lease: %v", err)
+	}
+}
+
+// stopGRPC 等待进行中的gRPC调用完成后停止服务，超过关闭期限时直接断开
+func stopGRPC(ctx context.Context, server *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Printf("gRPC server forced to shutdown: %v", ctx.Err())
+		server.Stop()
+	}
 }
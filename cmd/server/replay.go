@@ -0,0 +1,53 @@
+package main
+
+import (
+	"browser-detection/internal/config"
+	"browser-detection/internal/services"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runReplay 用当前规则和候选规则文件分别对已存储的指纹评分，以JSON输出差异报告；存储的分析结果不会被修改
+func runReplay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	site := fs.String("site", "", "only replay fingerprints of the site")
+	limit := fs.Int("limit", 100, "maximum number of changed fingerprints listed in the report")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: server replay [-site id] [-limit n] <candidate rules file>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("expected exactly one candidate rules file")
+	}
+	if *limit <= 0 {
+		return errors.New("-limit must be positive")
+	}
+
+	rules, err := services.LoadCandidateRules(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to load candidate rules: %w", err)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	db, fingerprintService, err := openService(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	report, err := fingerprintService.ReplayRules(context.Background(), rules, *site, *limit)
+	if err != nil {
+		return fmt.Errorf("failed to replay fingerprints: %w", err)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
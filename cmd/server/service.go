@@ -0,0 +1,75 @@
+package main
+
+import (
+	"browser-detection/internal/config"
+	"browser-detection/internal/services"
+	"browser-detection/internal/utils"
+	"context"
+	"fmt"
+)
+
+// openService 打开数据库并创建指纹服务，供服务启动和需要完整检测流程的子命令使用；
+// 返回的数据库由调用方关闭
+func openService(cfg *config.Config) (*utils.Database, *services.FingerprintService, error) {
+	db, err := utils.NewDatabase(cfg.DatabasePath, cfg.Storage.BusyTimeout.Std(), cfg.Storage.Shards)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+	fingerprintService, err := loadService(db, cfg)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return db, fingerprintService, nil
+}
+
+// loadService 创建指纹服务并加载站点策略、运行时修改过的规则和各类检测数据
+func loadService(db *utils.Database, cfg *config.Config) (*services.FingerprintService, error) {
+	if cfg.DatabaseReadPath != "" {
+		if err := db.OpenReadReplica(cfg.DatabaseReadPath); err != nil {
+			return nil, fmt.Errorf("failed to initialize read replica: %w", err)
+		}
+	}
+	fingerprintService := services.NewFingerprintService(db, cfg)
+
+	// 为引入版本号之前的记录推断指纹结构版本
+	if _, err := fingerprintService.MigrateFingerprintVersions(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to migrate fingerprint versions: %w", err)
+	}
+
+	// 加载站点的评分与策略覆盖，以及运行时修改过的评分阈值、规则集、功能开关和告警webhook
+	if err := fingerprintService.LoadSitePolicies(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to load site policies: %w", err)
+	}
+	if err := fingerprintService.LoadThresholds(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to load thresholds: %w", err)
+	}
+	if err := fingerprintService.LoadRuleSet(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to load rule set: %w", err)
+	}
+	if err := fingerprintService.LoadFeatures(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to load features: %w", err)
+	}
+	if err := fingerprintService.LoadWebhooks(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to load webhooks: %w", err)
+	}
+	if err := fingerprintService.LoadTokenKeys(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to load token keys: %w", err)
+	}
+	fingerprintService.LoadUARegexes()
+	fingerprintService.LoadBaselines()
+	fingerprintService.LoadCrawlers()
+	if err := fingerprintService.LoadASNDatabase(); err != nil {
+		return nil, fmt.Errorf("failed to load ASN database: %w", err)
+	}
+	if err := fingerprintService.LoadThreatIntel(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to load threat intel: %w", err)
+	}
+	if err := fingerprintService.LoadBrowserReleases(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to load browser releases: %w", err)
+	}
+	if err := fingerprintService.LoadMLModel(); err != nil {
+		return nil, fmt.Errorf("failed to load ML model: %w", err)
+	}
+	return fingerprintService, nil
+}
@@ -0,0 +1,160 @@
+package alerting
+
+import (
+	"browser-detection/internal/config"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Alert 告警事件
+type Alert struct {
+	Kind    string                 `json:"kind"`
+	SiteID  string                 `json:"site_id"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+	Time    time.Time              `json:"time"`
+}
+
+// Notifier 告警通道
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// New 按配置创建告警通道：告警始终写入日志，配置了 webhook_url 时同时发送到webhook；
+// webhooks 不为 nil 时同时发送到通过管理API配置的webhook
+func New(cfg config.AlertingConfig, webhooks *Webhooks) Notifier {
+	notifiers := multiNotifier{logNotifier{}}
+	if cfg.WebhookURL != "" {
+		notifiers = append(notifiers, &webhookNotifier{
+			url:    cfg.WebhookURL,
+			client: &http.Client{Timeout: cfg.Timeout.Std()},
+		})
+	}
+	if webhooks != nil {
+		notifiers = append(notifiers, webhooks)
+	}
+	return notifiers
+}
+
+// logNotifier 把告警写入日志
+type logNotifier struct{}
+
+func (logNotifier) Notify(_ context.Context, alert Alert) error {
+	log.Printf("ALERT [%s] site=%q: %s", alert.Kind, alert.SiteID, alert.Message)
+	return nil
+}
+
+// webhookNotifier 把告警以JSON POST到webhook
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (w *webhookNotifier) Notify(ctx context.Context, alert Alert) error {
+	return post(ctx, w.client, w.url, alert)
+}
+
+// post 把告警以JSON POST到url，返回码不是2xx时返回错误
+func post(ctx context.Context, client *http.Client, url string, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send alert webhook: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("alert webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// multiNotifier 依次发送到每个通道，返回第一个错误
+type multiNotifier []Notifier
+
+func (m multiNotifier) Notify(ctx context.Context, alert Alert) error {
+	var first error
+	for _, n := range m {
+		if err := n.Notify(ctx, alert); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Webhook 通过管理API配置的告警webhook
+type Webhook struct {
+	Name string
+	URL  string
+	// Kinds 只发送这些类型的告警，为空时发送所有告警
+	Kinds   []string
+	Enabled bool
+}
+
+// accepts 判断webhook是否接收该类型的告警
+func (w Webhook) accepts(kind string) bool {
+	if !w.Enabled {
+		return false
+	}
+	if len(w.Kinds) == 0 {
+		return true
+	}
+	for _, k := range w.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhooks 可在运行时替换的一组webhook，告警只发送到已启用且接收该类型的webhook
+type Webhooks struct {
+	list   atomic.Pointer[[]Webhook]
+	client *http.Client
+}
+
+// NewWebhooks 创建空的webhook组，timeout 为每次发送的超时
+func NewWebhooks(timeout time.Duration) *Webhooks {
+	w := &Webhooks{client: &http.Client{Timeout: timeout}}
+	w.list.Store(&[]Webhook{})
+	return w
+}
+
+// Set 替换全部webhook，之后的告警按新的列表发送
+func (w *Webhooks) Set(list []Webhook) {
+	list = append([]Webhook(nil), list...)
+	w.list.Store(&list)
+}
+
+// Notify 依次发送到接收该告警的webhook，返回第一个错误
+func (w *Webhooks) Notify(ctx context.Context, alert Alert) error {
+	var first error
+	for _, hook := range *w.list.Load() {
+		if !hook.accepts(alert.Kind) {
+			continue
+		}
+		if err := post(ctx, w.client, hook.URL, alert); err != nil && first == nil {
+			first = fmt.Errorf("webhook %s: %w", hook.Name, err)
+		}
+	}
+	return first
+}
+
+// Send 不论是否启用，把告警发送到指定地址，用于测试webhook
+func (w *Webhooks) Send(ctx context.Context, url string, alert Alert) error {
+	return post(ctx, w.client, url, alert)
+}
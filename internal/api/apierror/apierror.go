@@ -0,0 +1,267 @@
+package apierror
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Code 稳定的错误代码，客户端按代码分支处理，消息文本随语言变化
+type Code string
+
+// 错误代码
+const (
+	InvalidPayload          Code = "ERR_INVALID_PAYLOAD"
+	InvalidFields           Code = "ERR_INVALID_FIELDS"
+	UnsupportedEncoding     Code = "ERR_UNSUPPORTED_ENCODING"
+	PayloadTooLarge         Code = "ERR_PAYLOAD_TOO_LARGE"
+	FieldLimits             Code = "ERR_FIELD_LIMITS"
+	InvalidTiming           Code = "ERR_INVALID_TIMING"
+	InvalidEvent            Code = "ERR_INVALID_EVENT"
+	InvalidAction           Code = "ERR_INVALID_ACTION"
+	InvalidParameter        Code = "ERR_INVALID_PARAMETER"
+	InvalidTimestamp        Code = "ERR_INVALID_TIMESTAMP"
+	InvalidIP               Code = "ERR_INVALID_IP"
+	InvalidLabel            Code = "ERR_INVALID_LABEL"
+	InvalidSitePolicy       Code = "ERR_INVALID_SITE_POLICY"
+	InvalidThresholds       Code = "ERR_INVALID_THRESHOLDS"
+	InvalidBlocklistEntry   Code = "ERR_INVALID_BLOCKLIST_ENTRY"
+	InvalidRules            Code = "ERR_INVALID_RULES"
+	InvalidWebhook          Code = "ERR_INVALID_WEBHOOK"
+	InvalidRevocation       Code = "ERR_INVALID_REVOCATION"
+	InvalidAPIKey           Code = "ERR_INVALID_API_KEY"
+	APIKeyRequired          Code = "ERR_API_KEY_REQUIRED"
+	InvalidAdminToken       Code = "ERR_INVALID_ADMIN_TOKEN"
+	AdminDisabled           Code = "ERR_ADMIN_DISABLED"
+	InvalidReplicationToken Code = "ERR_INVALID_REPLICATION_TOKEN"
+	UnknownSite             Code = "ERR_UNKNOWN_SITE"
+	OriginNotAllowed        Code = "ERR_ORIGIN_NOT_ALLOWED"
+	RateLimited             Code = "ERR_RATE_LIMITED"
+	Timeout                 Code = "ERR_TIMEOUT"
+	StorageUnavailable      Code = "ERR_STORAGE_UNAVAILABLE"
+	ProofDisabled           Code = "ERR_PROOF_DISABLED"
+	AccessLogsDisabled      Code = "ERR_ACCESS_LOGS_DISABLED"
+	EdgeDisabled            Code = "ERR_EDGE_DISABLED"
+	ReplicationDisabled     Code = "ERR_REPLICATION_DISABLED"
+	UARegexesNotConfigured  Code = "ERR_UA_REGEXES_NOT_CONFIGURED"
+	BaselinesNotConfigured  Code = "ERR_BASELINES_NOT_CONFIGURED"
+	MLNotConfigured         Code = "ERR_ML_NOT_CONFIGURED"
+	ReleasesNotConfigured   Code = "ERR_RELEASES_NOT_CONFIGURED"
+	TooFewOutlierSamples    Code = "ERR_TOO_FEW_OUTLIER_SAMPLES"
+	FingerprintNotFound     Code = "ERR_FINGERPRINT_NOT_FOUND"
+	DeletedNotFound         Code = "ERR_DELETED_FINGERPRINT_NOT_FOUND"
+	AnalysisNotFound        Code = "ERR_ANALYSIS_NOT_FOUND"
+	CanvasNotFound          Code = "ERR_CANVAS_NOT_FOUND"
+	VisitorNotFound         Code = "ERR_VISITOR_NOT_FOUND"
+	AccountNotFound         Code = "ERR_ACCOUNT_NOT_FOUND"
+	IPNotFound              Code = "ERR_IP_NOT_FOUND"
+	ReputationNotFound      Code = "ERR_REPUTATION_NOT_FOUND"
+	SitePolicyNotFound      Code = "ERR_SITE_POLICY_NOT_FOUND"
+	LabelNotFound           Code = "ERR_LABEL_NOT_FOUND"
+	BlocklistEntryNotFound  Code = "ERR_BLOCKLIST_ENTRY_NOT_FOUND"
+	ThreatFeedNotConfigured Code = "ERR_THREAT_FEED_NOT_CONFIGURED"
+	RuleSetNotFound         Code = "ERR_RULE_SET_NOT_FOUND"
+	WebhookNotFound         Code = "ERR_WEBHOOK_NOT_FOUND"
+	FeatureNotFound         Code = "ERR_FEATURE_NOT_FOUND"
+	WebhookExists           Code = "ERR_WEBHOOK_EXISTS"
+	Internal                Code = "ERR_INTERNAL"
+)
+
+// DefaultLanguage 请求未指定或不支持的语言时使用的消息语言
+const DefaultLanguage = "en"
+
+// catalogs 各语言的消息模板，按 fmt 格式填入参数；英文消息尽量沿用原有文本，原有消息中拼接的错误细节移到 detail 字段
+var catalogs = map[string]map[Code]string{
+	"en": {
+		InvalidPayload:          "Invalid request format",
+		InvalidFields:           "Invalid request fields",
+		UnsupportedEncoding:     "Unsupported Content-Encoding: %s",
+		PayloadTooLarge:         "Request body too large",
+		FieldLimits:             "Request fields exceed configured limits",
+		InvalidTiming:           "Invalid collection timing",
+		InvalidEvent:            "Invalid event",
+		InvalidAction:           "Invalid action",
+		InvalidParameter:        "Invalid query parameter %s",
+		InvalidTimestamp:        "%s must be an RFC3339 timestamp",
+		InvalidIP:               "Invalid IP address",
+		InvalidLabel:            "Label must be bot or human",
+		InvalidSitePolicy:       "Invalid site policy",
+		InvalidThresholds:       "Invalid thresholds",
+		InvalidBlocklistEntry:   "kind must be fingerprint, ip, ip_range or visitor and key is required",
+		InvalidRules:            "Invalid rules",
+		InvalidWebhook:          "Invalid webhook",
+		InvalidRevocation:       "Exactly one of jti and fingerprint_hash is required",
+		InvalidAPIKey:           "Invalid API key",
+		APIKeyRequired:          "X-API-Key is required",
+		InvalidAdminToken:       "Invalid admin token",
+		AdminDisabled:           "Admin API is disabled",
+		InvalidReplicationToken: "Invalid replication token",
+		UnknownSite:             "Unknown site",
+		OriginNotAllowed:        "Origin not allowed",
+		RateLimited:             "Too many requests",
+		Timeout:                 "Request timed out",
+		StorageUnavailable:      "Storage unavailable, analysis deferred",
+		ProofDisabled:           "Execution proof is disabled",
+		AccessLogsDisabled:      "Access log ingestion is disabled",
+		EdgeDisabled:            "Edge decisions are disabled",
+		ReplicationDisabled:     "Replication is disabled",
+		UARegexesNotConfigured:  "UA regexes path is not configured",
+		BaselinesNotConfigured:  "Baselines path is not configured",
+		MLNotConfigured:         "ML model path is not configured",
+		ReleasesNotConfigured:   "Browser releases feed is not configured",
+		TooFewOutlierSamples:    "Not enough fingerprints to train the outlier model",
+		FingerprintNotFound:     "Fingerprint not found",
+		DeletedNotFound:         "Deleted fingerprint not found",
+		AnalysisNotFound:        "Analysis not found",
+		CanvasNotFound:          "Fingerprint not found or canvas not analyzable",
+		VisitorNotFound:         "Visitor not found",
+		AccountNotFound:         "Account not found",
+		IPNotFound:              "No activity or reputation record for this IP",
+		ReputationNotFound:      "No reputation record for this IP",
+		SitePolicyNotFound:      "Site policy not found",
+		LabelNotFound:           "Label not found",
+		BlocklistEntryNotFound:  "Blocklist entry not found",
+		ThreatFeedNotConfigured: "Threat feed not configured",
+		RuleSetNotFound:         "Rule set version not found",
+		WebhookNotFound:         "Webhook not found",
+		FeatureNotFound:         "Feature not found",
+		WebhookExists:           "Webhook already exists",
+		Internal:                "Internal server error",
+	},
+	"zh": {
+		InvalidPayload:          "请求格式无效",
+		InvalidFields:           "请求字段无效",
+		UnsupportedEncoding:     "不支持的 Content-Encoding：%s",
+		PayloadTooLarge:         "请求体过大",
+		FieldLimits:             "请求字段超出配置的限制",
+		InvalidTiming:           "采集时间无效",
+		InvalidEvent:            "事件无效",
+		InvalidAction:           "操作名无效",
+		InvalidParameter:        "查询参数 %s 无效",
+		InvalidTimestamp:        "%s 必须是 RFC3339 格式的时间",
+		InvalidIP:               "IP地址无效",
+		InvalidLabel:            "标注必须是 bot 或 human",
+		InvalidSitePolicy:       "站点策略无效",
+		InvalidThresholds:       "阈值无效",
+		InvalidBlocklistEntry:   "kind 必须是 fingerprint、ip、ip_range 或 visitor，且 key 不能为空",
+		InvalidRules:            "规则无效",
+		InvalidWebhook:          "webhook 无效",
+		InvalidRevocation:       "jti 和 fingerprint_hash 必须且只能提供一个",
+		InvalidAPIKey:           "API密钥无效",
+		APIKeyRequired:          "须提供 X-API-Key",
+		InvalidAdminToken:       "管理令牌无效",
+		AdminDisabled:           "管理API未启用",
+		InvalidReplicationToken: "同步令牌无效",
+		UnknownSite:             "未知站点",
+		OriginNotAllowed:        "来源不被允许",
+		RateLimited:             "请求过于频繁",
+		Timeout:                 "请求超时",
+		StorageUnavailable:      "存储不可用，分析已推迟",
+		ProofDisabled:           "未启用执行证明",
+		AccessLogsDisabled:      "未启用访问日志接入",
+		EdgeDisabled:            "未启用边缘决策接口",
+		ReplicationDisabled:     "未启用跨区域同步",
+		UARegexesNotConfigured:  "未配置UA正则文件路径",
+		BaselinesNotConfigured:  "未配置基线数据文件路径",
+		MLNotConfigured:         "未配置机器学习模型文件路径",
+		ReleasesNotConfigured:   "未配置浏览器版本表的更新源",
+		TooFewOutlierSamples:    "指纹数量不足，无法训练离群检测模型",
+		FingerprintNotFound:     "指纹不存在",
+		DeletedNotFound:         "已删除的指纹不存在",
+		AnalysisNotFound:        "分析结果不存在",
+		CanvasNotFound:          "指纹不存在或Canvas无法分析",
+		VisitorNotFound:         "访客不存在",
+		AccountNotFound:         "账号不存在",
+		IPNotFound:              "该IP没有活动或信誉记录",
+		ReputationNotFound:      "该IP没有信誉记录",
+		SitePolicyNotFound:      "站点策略不存在",
+		LabelNotFound:           "标注不存在",
+		BlocklistEntryNotFound:  "封禁记录不存在",
+		ThreatFeedNotConfigured: "未配置该威胁情报源",
+		RuleSetNotFound:         "规则集版本不存在",
+		WebhookNotFound:         "webhook 不存在",
+		FeatureNotFound:         "功能开关不存在",
+		WebhookExists:           "同名的 webhook 已存在",
+		Internal:                "服务器内部错误",
+	},
+}
+
+// Language 按 Accept-Language 选择消息语言：取权重最高的受支持主语言，权重相同时按出现顺序，都不支持时返回 DefaultLanguage
+func Language(header string) string {
+	lang, best := DefaultLanguage, 0.0
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if i := strings.IndexAny(tag, "-_"); i >= 0 {
+			tag = tag[:i]
+		}
+		if _, ok := catalogs[tag]; !ok {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > best {
+			lang, best = tag, q
+		}
+	}
+	return lang
+}
+
+// Message 返回错误代码在该语言下的消息，缺少译文时使用英文
+func Message(lang string, code Code, args ...interface{}) string {
+	tmpl, ok := catalogs[lang][code]
+	if !ok {
+		tmpl, ok = catalogs[DefaultLanguage][code]
+	}
+	if !ok {
+		return string(code)
+	}
+	if len(args) == 0 {
+		return tmpl
+	}
+	return fmt.Sprintf(tmpl, args...)
+}
+
+// Localize 按请求的 Accept-Language 返回错误代码的消息，并设置 Content-Language 响应头
+func Localize(c *gin.Context, code Code, args ...interface{}) string {
+	lang := Language(c.GetHeader("Accept-Language"))
+	c.Header("Content-Language", lang)
+	return Message(lang, code, args...)
+}
+
+// body 构造错误响应体：success、code、本地化的 message，以及附加字段
+func body(c *gin.Context, code Code, fields gin.H, args []interface{}) gin.H {
+	h := gin.H{
+		"success": false,
+		"code":    code,
+		"message": Localize(c, code, args...),
+	}
+	for k, v := range fields {
+		h[k] = v
+	}
+	return h
+}
+
+// Respond 写入错误响应，fields 附加到响应体（如 errors、detail），args 填入消息模板
+func Respond(c *gin.Context, status int, code Code, fields gin.H, args ...interface{}) {
+	c.JSON(status, body(c, code, fields, args))
+}
+
+// Abort 写入错误响应并中止后续处理，供中间件使用
+func Abort(c *gin.Context, status int, code Code, fields gin.H, args ...interface{}) {
+	c.AbortWithStatusJSON(status, body(c, code, fields, args))
+}
+
+// Detail 返回只含 detail 字段的附加字段，detail 为面向运维的英文原始错误，不做本地化
+func Detail(detail string) gin.H {
+	return gin.H{"detail": detail}
+}
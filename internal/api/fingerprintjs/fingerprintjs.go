@@ -0,0 +1,213 @@
+// Package fingerprintjs 将 FingerprintJS 的识别结果转换为指纹提交，便于从 FingerprintJS 迁移
+//
+// 支持的格式：
+//   - 开源版 get() 的结果：{"visitorId": ..., "components": {"fonts": {"value": [...]}, ...}}
+//   - Pro 版 Server API 事件：{"products": {"identification": {"data": ...}, "rawDeviceAttributes": {"data": {...}}}}
+//   - Pro 版 Webhook：顶层带 browserDetails 与 rawDeviceAttributes
+//
+// Pro 版只有开启 Raw device attributes 才返回设备组件。FingerprintJS 不提供的信号
+// （设备像素比、媒体设备、传感器等）保持未采集状态，不参与对应的检测。
+package fingerprintjs
+
+import (
+	"browser-detection/internal/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrNoComponents 结果中没有设备组件（Pro 版未开启 Raw device attributes）
+var ErrNoComponents = errors.New("FingerprintJS result has no device components; enable Raw device attributes for Pro results")
+
+// component 单个组件：{"value": ...} 或 {"error": ...}
+type component struct {
+	Value json.RawMessage `json:"value"`
+}
+
+// browserDetails Pro 版识别结果中的浏览器信息
+type browserDetails struct {
+	UserAgent string `json:"userAgent"`
+}
+
+// result 各种格式的并集
+type result struct {
+	// UserAgent 调用方补充的User Agent（开源版结果不含User Agent）
+	UserAgent      string               `json:"user_agent"`
+	Components     map[string]component `json:"components"`
+	RawDeviceAttrs map[string]component `json:"rawDeviceAttributes"`
+	BrowserDetails *browserDetails      `json:"browserDetails"`
+	Products       *struct {
+		Identification *struct {
+			Data struct {
+				BrowserDetails *browserDetails `json:"browserDetails"`
+			} `json:"data"`
+		} `json:"identification"`
+		RawDeviceAttributes *struct {
+			Data map[string]component `json:"data"`
+		} `json:"rawDeviceAttributes"`
+	} `json:"products"`
+}
+
+// webglBasics webGlBasics 组件
+type webglBasics struct {
+	Version          string `json:"version"`
+	Vendor           string `json:"vendor"`
+	VendorUnmasked   string `json:"vendorUnmasked"`
+	Renderer         string `json:"renderer"`
+	RendererUnmasked string `json:"rendererUnmasked"`
+}
+
+// UnmarshalResult 将 FingerprintJS 结果JSON转换为指纹提交
+// 结果中没有User Agent时使用 userAgent（通常取自请求头）
+func UnmarshalResult(b []byte, userAgent string, req *models.FingerprintRequest) error {
+	var r result
+	if err := json.Unmarshal(b, &r); err != nil {
+		return err
+	}
+
+	components := r.Components
+	if components == nil {
+		components = r.RawDeviceAttrs
+	}
+	if components == nil && r.Products != nil && r.Products.RawDeviceAttributes != nil {
+		components = r.Products.RawDeviceAttributes.Data
+	}
+	if len(components) == 0 {
+		return ErrNoComponents
+	}
+
+	details := r.BrowserDetails
+	if details == nil && r.Products != nil && r.Products.Identification != nil {
+		details = r.Products.Identification.Data.BrowserDetails
+	}
+	switch {
+	case r.UserAgent != "":
+		req.UserAgent = r.UserAgent
+	case details != nil && details.UserAgent != "":
+		req.UserAgent = details.UserAgent
+	default:
+		req.UserAgent = userAgent
+	}
+
+	c := componentReader(components)
+	var screen []int
+	if c.decode("screenResolution", &screen) && len(screen) == 2 {
+		req.ScreenResolution = fmt.Sprintf("%dx%d", screen[0], screen[1])
+	}
+	c.decode("timezone", &req.Timezone)
+	var languages [][]string
+	if c.decode("languages", &languages) && len(languages) > 0 && len(languages[0]) > 0 {
+		req.Language = languages[0][0]
+	}
+	c.decode("platform", &req.Platform)
+	req.Canvas = c.canvas()
+	req.WebGL = c.webgl()
+	req.Audio = c.audio()
+
+	req.Fonts = []string{}
+	c.decode("fonts", &req.Fonts)
+	var plugins []struct {
+		Name string `json:"name"`
+	}
+	req.Plugins = []string{}
+	if c.decode("plugins", &plugins) {
+		for _, p := range plugins {
+			req.Plugins = append(req.Plugins, p.Name)
+		}
+	}
+
+	var touch struct {
+		MaxTouchPoints int  `json:"maxTouchPoints"`
+		TouchEvent     bool `json:"touchEvent"`
+	}
+	if c.decode("touchSupport", &touch) {
+		req.TouchSupport = touch.MaxTouchPoints > 0 || touch.TouchEvent
+	}
+	c.decode("cookiesEnabled", &req.CookieEnabled)
+	c.decode("hardwareConcurrency", &req.HardwareConcurrency)
+	c.decode("deviceMemory", &req.DeviceMemory)
+	req.Math = c.math()
+	return nil
+}
+
+// componentReader 按组件名读取组件值
+type componentReader map[string]component
+
+// decode 将组件值解码到 dst，组件缺失、出错或类型不符时返回 false 且不修改 dst
+func (c componentReader) decode(name string, dst interface{}) bool {
+	comp, ok := c[name]
+	if !ok || len(comp.Value) == 0 || string(comp.Value) == "null" {
+		return false
+	}
+	return json.Unmarshal(comp.Value, dst) == nil
+}
+
+// canvas 优先使用文字画布（包含字体渲染差异），不稳定或被跳过时使用几何画布
+func (c componentReader) canvas() string {
+	var v struct {
+		Geometry string `json:"geometry"`
+		Text     string `json:"text"`
+	}
+	if !c.decode("canvas", &v) {
+		return ""
+	}
+	if strings.HasPrefix(v.Text, "data:image/") {
+		return v.Text
+	}
+	if strings.HasPrefix(v.Geometry, "data:image/") {
+		return v.Geometry
+	}
+	return ""
+}
+
+// webgl 转换为前端 collectWebGLBasicInfo 的结构，renderer 为未屏蔽的渲染器名
+func (c componentReader) webgl() string {
+	var v webglBasics
+	if !c.decode("webGlBasics", &v) {
+		return ""
+	}
+	renderer := v.RendererUnmasked
+	if renderer == "" {
+		renderer = v.Renderer
+	}
+	data, _ := json.Marshal(map[string]string{
+		"version":        v.Version,
+		"vendor":         v.Vendor,
+		"renderer":       renderer,
+		"vendorUnmasked": v.VendorUnmasked,
+	})
+	return string(data)
+}
+
+// audio FingerprintJS 用负数表示不支持或超时
+func (c componentReader) audio() string {
+	var v float64
+	if !c.decode("audio", &v) {
+		return ""
+	}
+	if v < 0 {
+		return "not supported"
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// math 按函数名排序输出结果，同一JS引擎得到相同的向量
+func (c componentReader) math() []string {
+	var v map[string]float64
+	if !c.decode("math", &v) || len(v) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(v))
+	for name := range v {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([]string, len(names))
+	for i, name := range names {
+		out[i] = name + "=" + strconv.FormatFloat(v[name], 'g', -1, 64)
+	}
+	return out
+}
@@ -0,0 +1,694 @@
+package handlers
+
+import (
+	"browser-detection/internal/api/apierror"
+	"browser-detection/internal/config"
+	"browser-detection/internal/models"
+	"browser-detection/internal/services"
+	"browser-detection/internal/utils"
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler 管理API处理器
+type AdminHandler struct {
+	service *services.FingerprintService
+}
+
+// NewAdminHandler 创建管理API处理器
+func NewAdminHandler(service *services.FingerprintService) *AdminHandler {
+	return &AdminHandler{service: service}
+}
+
+// maxAuditEntries 审计记录查询允许的最大数量
+const maxAuditEntries = 500
+
+// maxQuarantineEntries 隔离记录查询允许的最大数量
+const maxQuarantineEntries = 500
+
+// maxMathHashSamples 统计Math结果向量哈希时允许读取的最大记录数
+const maxMathHashSamples = 100000
+
+// maxBlockDuration 通过管理API封禁允许的最长时间
+const maxBlockDuration = 365 * 24 * time.Hour
+
+// adminActor 审计记录中的操作者：管理令牌不区分用户，记录客户端IP
+func adminActor(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// GetSitePolicy 返回站点的评分与策略覆盖
+func (h *AdminHandler) GetSitePolicy(c *gin.Context) {
+	policy, err := h.service.GetSitePolicy(c.Param("id"))
+	if err != nil {
+		apierror.Respond(c, http.StatusNotFound, apierror.SitePolicyNotFound, nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"policy":  policy,
+	})
+}
+
+// PutSitePolicy 替换站点的评分与策略覆盖
+func (h *AdminHandler) PutSitePolicy(c *gin.Context) {
+	var policy models.SitePolicyOverride
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	policy.SiteID = c.Param("id")
+
+	fieldErrors, err := h.service.SaveSitePolicy(c.Request.Context(), &policy, adminActor(c))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			apierror.Respond(c, http.StatusNotFound, apierror.UnknownSite, nil)
+			return
+		}
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to save site policy: "+err.Error()))
+		return
+	}
+	if len(fieldErrors) > 0 {
+		apierror.Respond(c, http.StatusUnprocessableEntity, apierror.InvalidSitePolicy, gin.H{"errors": fieldErrors})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"policy":  policy,
+	})
+}
+
+// DeleteSitePolicy 删除站点的策略覆盖，恢复默认策略
+func (h *AdminHandler) DeleteSitePolicy(c *gin.Context) {
+	if err := h.service.DeleteSitePolicy(c.Request.Context(), c.Param("id"), adminActor(c)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			apierror.Respond(c, http.StatusNotFound, apierror.SitePolicyNotFound, nil)
+			return
+		}
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to delete site policy: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// GetThresholds 返回当前的全局评分阈值
+func (h *AdminHandler) GetThresholds(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"thresholds": h.service.Thresholds(),
+	})
+}
+
+// PutThresholds 修改全局评分阈值，未提交的字段保持当前值；
+// 提交 event_bot_score 时整体替换按事件类型的阈值
+func (h *AdminHandler) PutThresholds(c *gin.Context) {
+	current := h.service.Thresholds()
+	thresholds := current
+	thresholds.EventBotScore = nil
+	if err := c.ShouldBindJSON(&thresholds); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if thresholds.EventBotScore == nil {
+		thresholds.EventBotScore = current.EventBotScore
+	}
+
+	fieldErrors, err := h.service.UpdateThresholds(c.Request.Context(), thresholds, adminActor(c))
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to update thresholds: "+err.Error()))
+		return
+	}
+	if len(fieldErrors) > 0 {
+		apierror.Respond(c, http.StatusUnprocessableEntity, apierror.InvalidThresholds, gin.H{"errors": fieldErrors})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"thresholds": thresholds,
+	})
+}
+
+// GetAuditLog 返回管理操作的审计记录，limit 默认50
+func (h *AdminHandler) GetAuditLog(c *gin.Context) {
+	limit := 50
+	if raw := c.Query("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > maxAuditEntries {
+			apierror.Respond(c, http.StatusBadRequest, apierror.InvalidParameter, gin.H{"param": "limit", "min": 1, "max": maxAuditEntries}, "limit")
+			return
+		}
+		limit = v
+	}
+
+	entries, err := h.service.GetAuditLog(c.Request.Context(), limit)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get audit log: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"entries": entries,
+	})
+}
+
+// GetBlocklistFilter 返回封禁名单内存过滤器的大小、误判率和查询统计
+func (h *AdminHandler) GetBlocklistFilter(c *gin.Context) {
+	status, err := h.service.BlocklistFilter(c.Request.Context())
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get blocklist filter: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"filter":  status,
+	})
+}
+
+// GetQuarantine 返回被拒绝的提交，可按 site_id、reason（错误代码）和 ip 过滤，limit 默认50
+func (h *AdminHandler) GetQuarantine(c *gin.Context) {
+	limit := 50
+	if raw := c.Query("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > maxQuarantineEntries {
+			apierror.Respond(c, http.StatusBadRequest, apierror.InvalidParameter, gin.H{"param": "limit", "min": 1, "max": maxQuarantineEntries}, "limit")
+			return
+		}
+		limit = v
+	}
+
+	entries, err := h.service.GetQuarantine(c.Request.Context(), c.Query("site_id"), c.Query("reason"), c.Query("ip"), limit)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get quarantine: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"entries": entries,
+	})
+}
+
+// GetBlocklist 返回未过期的临时封禁名单，可按 kind 过滤
+func (h *AdminHandler) GetBlocklist(c *gin.Context) {
+	kind := c.Query("kind")
+	if kind != "" && !validBlockKind(kind) {
+		apierror.Respond(c, http.StatusBadRequest, apierror.InvalidParameter, gin.H{"param": "kind"}, "kind")
+		return
+	}
+	entries, err := h.service.GetBlocklist(c.Request.Context(), kind)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get blocklist: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"entries": entries,
+	})
+}
+
+// GetDetectors 返回已注册的检测器插件及其是否启用
+func (h *AdminHandler) GetDetectors(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"detectors": h.service.Detectors(),
+	})
+}
+
+// GetFeatures 返回检测功能和检测器插件的开关
+func (h *AdminHandler) GetFeatures(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"features": h.service.Features(),
+	})
+}
+
+// PutFeature 启用或停用功能，立即对之后的提交生效
+func (h *AdminHandler) PutFeature(c *gin.Context) {
+	var req struct {
+		Enabled *bool `json:"enabled" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	h.setFeature(c, req.Enabled)
+}
+
+// DeleteFeature 撤销通过管理API对功能开关的修改，恢复默认值
+func (h *AdminHandler) DeleteFeature(c *gin.Context) {
+	h.setFeature(c, nil)
+}
+
+// setFeature 修改功能开关并返回其状态
+func (h *AdminHandler) setFeature(c *gin.Context, enabled *bool) {
+	feature, err := h.service.SetFeature(c.Request.Context(), c.Param("name"), enabled, adminActor(c))
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.Respond(c, http.StatusNotFound, apierror.FeatureNotFound, nil)
+		return
+	}
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to update feature: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"feature": feature,
+	})
+}
+
+// GetAutoBlock 返回自动封禁规则及其触发次数和未过期的封禁数，封禁操作本身见审计记录
+func (h *AdminHandler) GetAutoBlock(c *gin.Context) {
+	rules, err := h.service.AutoBlockStatus(c.Request.Context())
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get auto-block rules: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"rules":   rules,
+	})
+}
+
+// validBlockKind 判断是否为封禁名单的条目类型
+func validBlockKind(kind string) bool {
+	switch kind {
+	case models.BlockFingerprint, models.BlockIP, models.BlockIPRange, models.BlockVisitor:
+		return true
+	}
+	return false
+}
+
+// blockEntryKey 规范化管理API封禁的键：ip 须为IP地址，ip_range 须为IP所在的 /24（IPv4）或 /48（IPv6）网段，
+// 与提交时查询封禁名单的键一致
+func blockEntryKey(kind, key string) (string, bool) {
+	switch kind {
+	case models.BlockIP:
+		ip := utils.NormalizeIP(key)
+		if _, err := netip.ParseAddr(ip); err != nil {
+			return "", false
+		}
+		return ip, true
+	case models.BlockIPRange:
+		prefix, err := netip.ParsePrefix(key)
+		if err != nil {
+			return "", false
+		}
+		ipRange := utils.IPRange(prefix.Addr().String())
+		if prefix.Masked().String() != ipRange {
+			return "", false
+		}
+		return ipRange, true
+	}
+	return key, true
+}
+
+// PostBlocklistEntry 临时封禁指纹、IP、IP段或访客并写入审计记录，已在封禁中时延长到新的过期时间；
+// duration 为封禁时长（如 "24h"），reason 默认为 manual
+func (h *AdminHandler) PostBlocklistEntry(c *gin.Context) {
+	var req struct {
+		Kind     string          `json:"kind"`
+		Key      string          `json:"key"`
+		Reason   string          `json:"reason" binding:"max=200"`
+		Duration config.Duration `json:"duration"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if !validBlockKind(req.Kind) || req.Key == "" {
+		apierror.Respond(c, http.StatusBadRequest, apierror.InvalidBlocklistEntry, nil)
+		return
+	}
+	var fieldErrors []models.FieldError
+	key, ok := blockEntryKey(req.Kind, req.Key)
+	if !ok {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "key", Constraint: "IP address for ip, /24 (IPv4) or /48 (IPv6) network for ip_range", Got: req.Key})
+	}
+	if d := req.Duration.Std(); d <= 0 || d > maxBlockDuration {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "duration", Constraint: "range (0s, 8760h]", Got: d.String()})
+	}
+	if len(fieldErrors) > 0 {
+		apierror.Respond(c, http.StatusUnprocessableEntity, apierror.InvalidFields, gin.H{"errors": fieldErrors})
+		return
+	}
+	reason := req.Reason
+	if reason == "" {
+		reason = "manual"
+	}
+
+	entry, err := h.service.AddBlock(c.Request.Context(), req.Kind, key, reason, req.Duration.Std(), adminActor(c))
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to add blocklist entry: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"entry":   entry,
+	})
+}
+
+// DeleteBlocklistEntry 提前解除封禁，条目由 kind 和 key 查询参数指定（IP段的键含有斜杠）
+func (h *AdminHandler) DeleteBlocklistEntry(c *gin.Context) {
+	kind, key := c.Query("kind"), c.Query("key")
+	if !validBlockKind(kind) || key == "" {
+		apierror.Respond(c, http.StatusBadRequest, apierror.InvalidBlocklistEntry, nil)
+		return
+	}
+
+	if err := h.service.Unblock(c.Request.Context(), kind, key, adminActor(c)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			apierror.Respond(c, http.StatusNotFound, apierror.BlocklistEntryNotFound, nil)
+			return
+		}
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to delete blocklist entry: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// DeleteFingerprint 软删除指纹及其分析结果
+func (h *AdminHandler) DeleteFingerprint(c *gin.Context) {
+	if err := h.service.SoftDeleteFingerprint(c.Request.Context(), c.Param("hash"), adminActor(c)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			apierror.Respond(c, http.StatusNotFound, apierror.FingerprintNotFound, nil)
+			return
+		}
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to delete fingerprint: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// PutLabel 标注指纹的真实类别（bot 或 human），用于评估检测质量
+func (h *AdminHandler) PutLabel(c *gin.Context) {
+	var req models.LabelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	label, err := h.service.SetLabel(c.Request.Context(), c.Param("hash"), req, adminActor(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidLabel):
+			apierror.Respond(c, http.StatusBadRequest, apierror.InvalidLabel, nil)
+		case errors.Is(err, sql.ErrNoRows):
+			apierror.Respond(c, http.StatusNotFound, apierror.FingerprintNotFound, nil)
+		default:
+			apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to set label: "+err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"label":   label,
+	})
+}
+
+// DeleteLabel 删除指纹的标注
+func (h *AdminHandler) DeleteLabel(c *gin.Context) {
+	if err := h.service.DeleteLabel(c.Request.Context(), c.Param("hash"), adminActor(c)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			apierror.Respond(c, http.StatusNotFound, apierror.LabelNotFound, nil)
+			return
+		}
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to delete label: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// RestoreFingerprint 恢复软删除的指纹
+func (h *AdminHandler) RestoreFingerprint(c *gin.Context) {
+	if err := h.service.RestoreFingerprint(c.Request.Context(), c.Param("hash"), adminActor(c)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			apierror.Respond(c, http.StatusNotFound, apierror.DeletedNotFound, nil)
+			return
+		}
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to restore fingerprint: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// GetUARegexes 返回当前使用的User Agent解析规则
+func (h *AdminHandler) GetUARegexes(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"ua_regexes": h.service.UARegexes(),
+	})
+}
+
+// RefreshUARegexes 重新读取 uap-core regexes.yaml，失败时继续使用当前规则
+func (h *AdminHandler) RefreshUARegexes(c *gin.Context) {
+	status, err := h.service.RefreshUARegexes(c.Request.Context(), adminActor(c))
+	if errors.Is(err, services.ErrUARegexesNotConfigured) {
+		apierror.Respond(c, http.StatusConflict, apierror.UARegexesNotConfigured, nil)
+		return
+	}
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, gin.H{
+			"detail":     "Failed to refresh UA regexes: " + err.Error(),
+			"ua_regexes": status,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"ua_regexes": status,
+	})
+}
+
+// GetBaselines 返回当前使用的基线数据
+func (h *AdminHandler) GetBaselines(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"baselines": h.service.Baselines(),
+	})
+}
+
+// GetMathHashes 返回最近的指纹记录中出现最多的Math结果向量哈希及各自声明的JS引擎，供整理基线数据，
+// samples 为统计的记录数，默认10000
+func (h *AdminHandler) GetMathHashes(c *gin.Context) {
+	samples := 10000
+	if raw := c.Query("samples"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > maxMathHashSamples {
+			apierror.Respond(c, http.StatusBadRequest, apierror.InvalidParameter, gin.H{"param": "samples", "min": 1, "max": maxMathHashSamples}, "samples")
+			return
+		}
+		samples = v
+	}
+
+	hashes, err := h.service.MathHashes(c.Request.Context(), samples)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get math hashes: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"hashes":  hashes,
+	})
+}
+
+// GetCrawlers 返回当前使用的爬虫特征库，站点爬虫策略中的爬虫名称取自这里
+func (h *AdminHandler) GetCrawlers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"crawlers": h.service.Crawlers(),
+	})
+}
+
+// RefreshBaselines 重新读取基线数据文件，失败时继续使用当前数据
+func (h *AdminHandler) RefreshBaselines(c *gin.Context) {
+	status, err := h.service.RefreshBaselines(c.Request.Context(), adminActor(c))
+	if errors.Is(err, services.ErrBaselinesNotConfigured) {
+		apierror.Respond(c, http.StatusConflict, apierror.BaselinesNotConfigured, nil)
+		return
+	}
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, gin.H{
+			"detail":    "Failed to refresh baselines: " + err.Error(),
+			"baselines": status,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"baselines": status,
+	})
+}
+
+// GetML 返回机器学习评分模型的配置和各模型版本的统计
+func (h *AdminHandler) GetML(c *gin.Context) {
+	status, err := h.service.MLStatus(c.Request.Context())
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get ML status: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"ml":      status,
+	})
+}
+
+// RefreshML 重新读取模型文件，失败时继续使用当前模型
+func (h *AdminHandler) RefreshML(c *gin.Context) {
+	status, err := h.service.RefreshMLModel(c.Request.Context(), adminActor(c))
+	if errors.Is(err, services.ErrMLNotConfigured) {
+		apierror.Respond(c, http.StatusConflict, apierror.MLNotConfigured, nil)
+		return
+	}
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to refresh ML model: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"ml":      status,
+	})
+}
+
+// GetBrowserReleases 返回当前使用的浏览器版本表
+func (h *AdminHandler) GetBrowserReleases(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success":          true,
+		"browser_releases": h.service.BrowserReleases(),
+	})
+}
+
+// RefreshBrowserReleases 立即从更新源获取版本表，失败时继续使用当前版本表
+func (h *AdminHandler) RefreshBrowserReleases(c *gin.Context) {
+	status, err := h.service.RefreshBrowserReleases(c.Request.Context(), adminActor(c))
+	if errors.Is(err, services.ErrReleasesNotConfigured) {
+		apierror.Respond(c, http.StatusConflict, apierror.ReleasesNotConfigured, nil)
+		return
+	}
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, gin.H{
+			"detail":           "Failed to refresh browser releases: " + err.Error(),
+			"browser_releases": status,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":          true,
+		"browser_releases": status,
+	})
+}
+
+// GetThreatFeeds 返回IP信誉情报源的状态，stale 表示超过 stale_after 未成功更新
+func (h *AdminHandler) GetThreatFeeds(c *gin.Context) {
+	feeds, err := h.service.ThreatFeeds(c.Request.Context())
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get threat feeds: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"feeds":   feeds,
+	})
+}
+
+// PutThreatFeed 启用或停用情报源
+func (h *AdminHandler) PutThreatFeed(c *gin.Context) {
+	var req struct {
+		Enabled *bool `json:"enabled" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	feed, err := h.service.SetThreatFeedEnabled(c.Request.Context(), c.Param("name"), *req.Enabled, adminActor(c))
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.Respond(c, http.StatusNotFound, apierror.ThreatFeedNotConfigured, nil)
+		return
+	}
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to update threat feed: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"feed":    feed,
+	})
+}
+
+// RefreshThreatFeed 立即下载并导入情报源，失败时保留上次导入的记录
+// 下载受 threat_intel.timeout 限制，不受请求处理期限限制
+func (h *AdminHandler) RefreshThreatFeed(c *gin.Context) {
+	ctx := context.WithoutCancel(c.Request.Context())
+	feed, err := h.service.UpdateThreatFeed(ctx, c.Param("name"))
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.Respond(c, http.StatusNotFound, apierror.ThreatFeedNotConfigured, nil)
+		return
+	}
+	if err != nil {
+		apierror.Respond(c, http.StatusBadGateway, apierror.Internal, gin.H{"detail": "Failed to refresh threat feed: " + err.Error(), "feed": feed})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"feed":    feed,
+	})
+}
+
+// GetReplication 返回跨区域同步的状态：本区域最新的变更序号和从各区域拉取的进度
+func (h *AdminHandler) GetReplication(c *gin.Context) {
+	status, err := h.service.ReplicationStatus(c.Request.Context())
+	if err != nil {
+		if errors.Is(err, services.ErrReplicationDisabled) {
+			apierror.Respond(c, http.StatusNotFound, apierror.ReplicationDisabled, nil)
+			return
+		}
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get replication status: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"replication": status,
+	})
+}
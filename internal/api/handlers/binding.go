@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"browser-detection/internal/api/fingerprintjs"
+	"browser-detection/internal/api/protobuf"
+	"browser-detection/internal/models"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/ugorji/go/codec"
+)
+
+// MIMECBOR CBOR请求体的Content-Type
+const MIMECBOR = "application/cbor"
+
+// cborBinding CBOR请求体绑定，字段名与JSON标签保持一致
+type cborBinding struct{}
+
+func (cborBinding) Name() string {
+	return "cbor"
+}
+
+func (cborBinding) Bind(req *http.Request, obj any) error {
+	return decodeCBOR(req.Body, obj)
+}
+
+func (cborBinding) BindBody(body []byte, obj any) error {
+	return decodeCBOR(bytes.NewReader(body), obj)
+}
+
+func decodeCBOR(r io.Reader, obj any) error {
+	cdc := new(codec.CborHandle)
+	if err := codec.NewDecoder(r, cdc).Decode(&obj); err != nil {
+		return err
+	}
+	if binding.Validator == nil {
+		return nil
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// protobufBinding Protobuf请求体绑定，消息定义见 api/proto/fingerprint.proto
+type protobufBinding struct{}
+
+func (protobufBinding) Name() string {
+	return "protobuf"
+}
+
+func (b protobufBinding) Bind(req *http.Request, obj any) error {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	return b.BindBody(body, obj)
+}
+
+func (protobufBinding) BindBody(body []byte, obj any) error {
+	req, ok := obj.(*models.FingerprintRequest)
+	if !ok {
+		return fmt.Errorf("protobuf binding does not support %T", obj)
+	}
+	if err := protobuf.UnmarshalFingerprintRequest(body, req); err != nil {
+		return err
+	}
+	if binding.Validator == nil {
+		return nil
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// fingerprintJSBinding FingerprintJS 识别结果绑定，结果不含User Agent时使用请求头中的值
+type fingerprintJSBinding struct {
+	userAgent string
+}
+
+func (fingerprintJSBinding) Name() string {
+	return "fingerprintjs"
+}
+
+func (b fingerprintJSBinding) Bind(req *http.Request, obj any) error {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	return b.BindBody(body, obj)
+}
+
+func (b fingerprintJSBinding) BindBody(body []byte, obj any) error {
+	req, ok := obj.(*models.FingerprintRequest)
+	if !ok {
+		return fmt.Errorf("fingerprintjs binding does not support %T", obj)
+	}
+	if err := fingerprintjs.UnmarshalResult(body, b.userAgent, req); err != nil {
+		return err
+	}
+	if binding.Validator == nil {
+		return nil
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// bodyBinding 根据Content-Type选择请求体绑定方式（JSON、MessagePack、CBOR、Protobuf）
+func bodyBinding(c *gin.Context) binding.BindingBody {
+	switch c.ContentType() {
+	case binding.MIMEMSGPACK, binding.MIMEMSGPACK2:
+		return binding.MsgPack
+	case MIMECBOR:
+		return cborBinding{}
+	case binding.MIMEPROTOBUF:
+		return protobufBinding{}
+	default:
+		return binding.JSON
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"browser-detection/internal/api/middleware"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var scriptSrcPattern = regexp.MustCompile(`<script\s+src="(/static/[^"]+)"`)
+
+// collectorScript 采集页面引用的脚本及其完整性哈希
+type collectorScript struct {
+	Src       string `json:"src"`
+	Integrity string `json:"integrity"` // SRI，可直接用于 <script integrity>
+	CSPHash   string `json:"csp_hash"`  // CSP 哈希源，配合 integrity 属性使用
+}
+
+// CollectorHandler 采集页面与CSP辅助接口
+type CollectorHandler struct {
+	staticDir string
+	indexHTML string
+	scripts   []collectorScript
+}
+
+// NewCollectorHandler 创建采集页面处理器，启动时读取页面并计算脚本哈希
+func NewCollectorHandler(staticDir string) (*CollectorHandler, error) {
+	index, err := os.ReadFile(filepath.Join(staticDir, "index.html"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read collector page: %w", err)
+	}
+
+	h := &CollectorHandler{staticDir: staticDir, indexHTML: string(index)}
+	for _, match := range scriptSrcPattern.FindAllStringSubmatch(h.indexHTML, -1) {
+		src := match[1]
+		data, err := os.ReadFile(filepath.Join(staticDir, strings.TrimPrefix(src, "/static/")))
+		if err != nil {
+			log.Printf("Failed to hash collector script %s: %v", src, err)
+			continue
+		}
+		sum384 := sha512.Sum384(data)
+		sum256 := sha256.Sum256(data)
+		h.scripts = append(h.scripts, collectorScript{
+			Src:       src,
+			Integrity: "sha384-" + base64.StdEncoding.EncodeToString(sum384[:]),
+			CSPHash:   "'sha256-" + base64.StdEncoding.EncodeToString(sum256[:]) + "'",
+		})
+	}
+
+	return h, nil
+}
+
+// Index 输出采集页面，为每个 <script> 标签注入本次响应的CSP nonce
+func (h *CollectorHandler) Index(c *gin.Context) {
+	nonce, err := middleware.NonceCSP(c)
+	if err != nil {
+		log.Printf("Failed to generate CSP nonce: %v", err)
+		c.File(filepath.Join(h.staticDir, "index.html"))
+		return
+	}
+
+	page := strings.ReplaceAll(h.indexHTML, "<script", `<script nonce="`+nonce+`"`)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+}
+
+// CSP 返回集成方在严格CSP下嵌入采集脚本所需的策略片段
+func (h *CollectorHandler) CSP(c *gin.Context) {
+	origin := requestOrigin(c)
+
+	scriptSources := []string{origin}
+	scripts := make([]collectorScript, 0, len(h.scripts))
+	for _, script := range h.scripts {
+		scriptSources = append(scriptSources, script.CSPHash)
+		scripts = append(scripts, collectorScript{
+			Src:       origin + script.Src,
+			Integrity: script.Integrity,
+			CSPHash:   script.CSPHash,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"directives": gin.H{
+			"script-src":  strings.Join(scriptSources, " "),
+			"connect-src": origin,
+		},
+		"header":  fmt.Sprintf("script-src %s; connect-src %s", strings.Join(scriptSources, " "), origin),
+		"scripts": scripts,
+	})
+}
+
+// requestOrigin 根据请求推断本服务对外的来源（考虑反向代理传入的协议头）
+func requestOrigin(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = strings.TrimSpace(strings.Split(proto, ",")[0])
+	}
+	return scheme + "://" + c.Request.Host
+}
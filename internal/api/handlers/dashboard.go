@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"browser-detection/internal/api/apierror"
+	"browser-detection/internal/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxDetections 检测列表一次查询允许的最大数量
+const maxDetections = 500
+
+// timeParam 解析可省略的 RFC3339 时间参数（可带小数秒），格式错误时返回400且 ok 为 false
+func timeParam(c *gin.Context, name string) (t *time.Time, ok bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, true
+	}
+	v, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.InvalidTimestamp, gin.H{"param": name}, name)
+		return nil, false
+	}
+	return &v, true
+}
+
+// GetDashboardOverview 返回看板首页所需的全部数据：汇总、图表、告警、实时计数和最近的检测，
+// 可按 from、to（RFC3339，默认最近24小时）和 site_id 过滤
+func (h *FingerprintHandler) GetDashboardOverview(c *gin.Context) {
+	from, to, ok := timeRange(c)
+	if !ok {
+		return
+	}
+
+	overview, err := h.service.DashboardOverview(c.Request.Context(), from, to, c.Query("site_id"))
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get dashboard overview: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"overview": overview,
+	})
+}
+
+// GetDashboardLive 供看板轮询：返回实时计数和 since 之后的检测（最多 limit 条，默认50），
+// cursor 为下次轮询的 since；未给出 since 时只返回实时计数和当前的 cursor
+func (h *FingerprintHandler) GetDashboardLive(c *gin.Context) {
+	since, ok := timeParam(c, "since")
+	if !ok {
+		return
+	}
+	limit, ok := detectionLimit(c)
+	if !ok {
+		return
+	}
+	siteID := c.Query("site_id")
+
+	live, err := h.service.DashboardLive(c.Request.Context(), siteID)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get live counters: "+err.Error()))
+		return
+	}
+	detections := []models.Detection{}
+	cursor := live.At
+	if since != nil {
+		detections, err = h.service.Detections(c.Request.Context(), models.DetectionQuery{SiteID: siteID, Since: since, Limit: limit})
+		if err != nil {
+			apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get detections: "+err.Error()))
+			return
+		}
+		// 超过 limit 条时只返回最新的部分，cursor 取最新一条，较早的由检测列表翻页取得
+		cursor = *since
+		if len(detections) > 0 {
+			cursor = detections[0].LastSeen
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"live":       live,
+		"detections": detections,
+		"cursor":     cursor,
+	})
+}
+
+// GetDetections 按最近一次分析的时间倒序返回检测列表，可按 site_id 和 bots=true（只返回爬虫）过滤；
+// before 为上一页的 next_before，limit 默认50
+func (h *FingerprintHandler) GetDetections(c *gin.Context) {
+	before, ok := timeParam(c, "before")
+	if !ok {
+		return
+	}
+	limit, ok := detectionLimit(c)
+	if !ok {
+		return
+	}
+	q := models.DetectionQuery{SiteID: c.Query("site_id"), Before: before, Limit: limit}
+	if raw := c.Query("bots"); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			apierror.Respond(c, http.StatusBadRequest, apierror.InvalidParameter, gin.H{"param": "bots"}, "bots")
+			return
+		}
+		q.BotsOnly = v
+	}
+
+	detections, err := h.service.Detections(c.Request.Context(), q)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get detections: "+err.Error()))
+		return
+	}
+	var next *time.Time
+	if len(detections) == limit {
+		next = &detections[len(detections)-1].LastSeen
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"detections":  detections,
+		"next_before": next,
+	})
+}
+
+// detectionLimit 解析检测列表的 limit 参数，默认50
+func detectionLimit(c *gin.Context) (int, bool) {
+	limit := 50
+	if raw := c.Query("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > maxDetections {
+			apierror.Respond(c, http.StatusBadRequest, apierror.InvalidParameter, gin.H{"param": "limit", "min": 1, "max": maxDetections}, "limit")
+			return 0, false
+		}
+		limit = v
+	}
+	return limit, true
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"browser-detection/internal/api/apierror"
+	"browser-detection/internal/api/middleware"
+	"browser-detection/internal/api/protobuf"
+	"browser-detection/internal/models"
+	"browser-detection/internal/services"
+	"browser-detection/internal/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// GetEdgeDecision 供 Cloudflare Workers、Fastly Compute 等边缘节点查询处理建议，须携带站点API Key。
+// 依次按 hash（指纹哈希）、visitor（访客Cookie，未给出时取请求中的 bd_visitor Cookie）和 ja4 查找；
+// ip、country 为边缘节点看到的访客IP和国家，ip 未给出时取请求来源。
+// Accept 为 application/x-protobuf 时返回 api/proto/edge.proto 中的 EdgeDecision 消息，否则返回JSON
+func (h *FingerprintHandler) GetEdgeDecision(c *gin.Context) {
+	start := time.Now()
+	if c.GetHeader(middleware.APIKeyHeader) == "" {
+		apierror.Respond(c, http.StatusUnauthorized, apierror.APIKeyRequired, nil)
+		return
+	}
+
+	req := models.EdgeRequest{
+		SiteID:          c.GetString(middleware.SiteIDKey),
+		FingerprintHash: c.Query("hash"),
+		VisitorID:       c.Query("visitor"),
+		JA4:             strings.ToLower(c.Query("ja4")),
+		IPAddress:       c.Query("ip"),
+		Country:         utils.NormalizeCountry(c.Query("country")),
+		Action:          c.Query("action"),
+	}
+	if req.VisitorID == "" {
+		req.VisitorID, _ = c.Cookie(visitorCookie)
+	}
+	if req.VisitorID != "" && !visitorIDPattern.MatchString(req.VisitorID) {
+		apierror.Respond(c, http.StatusBadRequest, apierror.InvalidParameter, gin.H{"param": "visitor"}, "visitor")
+		return
+	}
+	if req.JA4 != "" && !services.ValidJA4(req.JA4) {
+		apierror.Respond(c, http.StatusBadRequest, apierror.InvalidParameter, gin.H{"param": "ja4"}, "ja4")
+		return
+	}
+	if req.Action != "" && !services.ValidAction(req.Action) {
+		apierror.Respond(c, http.StatusBadRequest, apierror.InvalidAction, nil)
+		return
+	}
+	if req.IPAddress == "" {
+		req.IPAddress = utils.GetClientIP(c.GetHeader("X-Forwarded-For"), c.GetHeader("X-Real-IP"), c.Request.RemoteAddr)
+	} else if net.ParseIP(req.IPAddress) == nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.InvalidIP, nil)
+		return
+	}
+
+	decision, cached, err := h.service.EdgeDecision(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, services.ErrEdgeDisabled) {
+			apierror.Respond(c, http.StatusNotFound, apierror.EdgeDisabled, nil)
+			return
+		}
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get edge decision: "+err.Error()))
+		return
+	}
+
+	contentType := binding.MIMEJSON
+	var body []byte
+	if strings.Contains(c.GetHeader("Accept"), binding.MIMEPROTOBUF) {
+		contentType = binding.MIMEPROTOBUF
+		if body, err = protobuf.MarshalEdgeDecision(decision); err != nil {
+			apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to encode edge decision: "+err.Error()))
+			return
+		}
+	} else {
+		body, _ = json.Marshal(gin.H{"success": true, "decision": decision})
+	}
+
+	// 边缘节点按URL缓存决策，过期后 stale_ttl 内可先使用旧决策并在后台重新查询
+	sum := fnv.New64a()
+	sum.Write(body)
+	etag := fmt.Sprintf(`"%016x"`, sum.Sum64())
+	stale := int(h.edgeStaleTTL.Seconds())
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d, stale-while-revalidate=%d, stale-if-error=%d", decision.MaxAge, stale, stale))
+	c.Header("ETag", etag)
+	c.Header("Vary", "Accept")
+	source := "db"
+	if cached {
+		source = "cache"
+	}
+	c.Header("Server-Timing", fmt.Sprintf(`decision;desc="%s";dur=%.1f`, source, float64(time.Since(start).Microseconds())/1000))
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, contentType, body)
+}
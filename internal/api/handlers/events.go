@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"browser-detection/internal/api/apierror"
+	"browser-detection/internal/api/middleware"
+	"browser-detection/internal/models"
+	"browser-detection/internal/services"
+	"browser-detection/internal/utils"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAccountRisk 返回账号使用过的设备和账号接管信号，须携带站点API Key，只返回该站点的账号
+func (h *FingerprintHandler) GetAccountRisk(c *gin.Context) {
+	if c.GetHeader(middleware.APIKeyHeader) == "" {
+		apierror.Respond(c, http.StatusUnauthorized, apierror.APIKeyRequired, nil)
+		return
+	}
+	report, err := h.service.GetAccountRisk(c.Request.Context(), c.GetString(middleware.SiteIDKey), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			apierror.Respond(c, http.StatusNotFound, apierror.AccountNotFound, nil)
+			return
+		}
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get account risk: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"account": report,
+	})
+}
+
+// SubmitEvent 接收登录、注册、下单等业务事件，返回按受保护操作或事件类型策略给出的处理建议
+func (h *FingerprintHandler) SubmitEvent(c *gin.Context) {
+	var req models.EventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	meta := models.RequestMeta{
+		IPAddress: utils.GetClientIP(
+			c.GetHeader("X-Forwarded-For"),
+			c.GetHeader("X-Real-IP"),
+			c.Request.RemoteAddr,
+		),
+		SiteID: c.GetString(middleware.SiteIDKey),
+	}
+	if h.countryHeader != "" {
+		meta.Country = utils.NormalizeCountry(c.GetHeader(h.countryHeader))
+	}
+	event, fieldErrors, err := h.service.RecordEvent(c.Request.Context(), &req, meta)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			apierror.Respond(c, http.StatusNotFound, apierror.FingerprintNotFound, nil)
+			return
+		}
+		log.Printf("Failed to record event: %v", err)
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to record event: "+err.Error()))
+		return
+	}
+	if len(fieldErrors) > 0 {
+		apierror.Respond(c, http.StatusUnprocessableEntity, apierror.InvalidEvent, gin.H{"errors": fieldErrors})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"event_id": event.ID,
+		"decision": event.Decision,
+	})
+}
+
+// GetDecision 按受保护操作（query 参数 action）的策略返回指纹当前的处理建议，不记录事件
+func (h *FingerprintHandler) GetDecision(c *gin.Context) {
+	action := c.Query("action")
+	if action != "" && !services.ValidAction(action) {
+		apierror.Respond(c, http.StatusBadRequest, apierror.InvalidAction, nil)
+		return
+	}
+
+	meta := models.RequestMeta{
+		IPAddress: utils.GetClientIP(
+			c.GetHeader("X-Forwarded-For"),
+			c.GetHeader("X-Real-IP"),
+			c.Request.RemoteAddr,
+		),
+		SiteID: c.GetString(middleware.SiteIDKey),
+	}
+	if h.countryHeader != "" {
+		meta.Country = utils.NormalizeCountry(c.GetHeader(h.countryHeader))
+	}
+	decision, err := h.service.Decide(c.Request.Context(), c.Param("hash"), action, meta)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			apierror.Respond(c, http.StatusNotFound, apierror.FingerprintNotFound, nil)
+			return
+		}
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get decision: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"decision": decision,
+	})
+}
+
+// GetIPProfile 返回IP所在网段（IPv4 /24，IPv6 /64）的近期活动、该地址出现的指纹和网段信誉
+func (h *FingerprintHandler) GetIPProfile(c *gin.Context) {
+	profile, err := h.service.GetIPProfile(c.Request.Context(), c.Param("ip"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidIP):
+			apierror.Respond(c, http.StatusBadRequest, apierror.InvalidIP, nil)
+		case errors.Is(err, sql.ErrNoRows):
+			apierror.Respond(c, http.StatusNotFound, apierror.IPNotFound, nil)
+		default:
+			apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get IP profile: "+err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"profile": profile,
+	})
+}
+
+// GetIPReputation 返回IP的历史信誉（爬虫判定、人机验证失败、情报源命中，均已衰减）和当前命中的情报记录
+func (h *FingerprintHandler) GetIPReputation(c *gin.Context) {
+	reputation, err := h.service.GetIPReputation(c.Request.Context(), c.Param("ip"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidIP):
+			apierror.Respond(c, http.StatusBadRequest, apierror.InvalidIP, nil)
+		case errors.Is(err, sql.ErrNoRows):
+			apierror.Respond(c, http.StatusNotFound, apierror.ReputationNotFound, nil)
+		default:
+			apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get IP reputation: "+err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"reputation": reputation,
+	})
+}
@@ -1,54 +1,196 @@
 package handlers
 
 import (
+	"browser-detection/internal/api/apierror"
+	"browser-detection/internal/api/middleware"
+	"browser-detection/internal/config"
+	"browser-detection/internal/counters"
+	"browser-detection/internal/crawlers"
 	"browser-detection/internal/models"
 	"browser-detection/internal/services"
 	"browser-detection/internal/utils"
-	"bytes"
+	"context"
 	"database/sql"
-	"io"
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
 // FingerprintHandler 指纹处理器
 type FingerprintHandler struct {
-	service *services.FingerprintService
+	service       *services.FingerprintService
+	limits        config.LimitsConfig
+	countryHeader string
+	// ttlHeader、rttHeader 反向代理测得的传输层信息请求头，用于住宅代理检测
+	ttlHeader string
+	rttHeader string
+	// ja4Header 边缘节点转发的 JA4 TLS 指纹请求头，edgeStaleTTL 边缘决策响应可过期使用的时长
+	ja4Header    string
+	edgeStaleTTL time.Duration
 }
 
 // NewFingerprintHandler 创建新的指纹处理器
-func NewFingerprintHandler(service *services.FingerprintService) *FingerprintHandler {
-	return &FingerprintHandler{service: service}
+func NewFingerprintHandler(service *services.FingerprintService, cfg *config.Config) *FingerprintHandler {
+	return &FingerprintHandler{
+		service:       service,
+		limits:        cfg.Limits,
+		countryHeader: cfg.Server.CountryHeader,
+		ttlHeader:     cfg.Detection.Proxy.TTLHeader,
+		rttHeader:     cfg.Detection.Proxy.RTTHeader,
+		ja4Header:     cfg.Edge.JA4Header,
+		edgeStaleTTL:  cfg.Edge.StaleTTL.Std(),
+	}
+}
+
+// maxAcceptLanguageLength Accept-Language 请求头保存的最大长度
+const maxAcceptLanguageLength = 256
+
+// maxPageURLLength 采集页面地址保存的最大长度
+const maxPageURLLength = 1024
+
+// maxClientHintsLength Sec-CH-UA 请求头读取的最大长度
+const maxClientHintsLength = 512
+
+// pageURL 返回采集页面的地址：Referer 请求头，没有时为 Origin
+func pageURL(c *gin.Context) string {
+	if referer := c.GetHeader("Referer"); referer != "" {
+		return referer
+	}
+	return c.GetHeader("Origin")
+}
+
+// truncate 将字符串截断到最多 n 个字节
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// transportMeta 从反向代理的请求头读取来源连接的IP TTL、TCP往返时延（微秒）和边缘节点转发的 JA4，无法解析时忽略
+func (h *FingerprintHandler) transportMeta(c *gin.Context, meta *models.RequestMeta) {
+	if h.ttlHeader != "" {
+		if ttl, err := strconv.Atoi(c.GetHeader(h.ttlHeader)); err == nil && ttl > 0 && ttl <= 255 {
+			meta.IPTTL = ttl
+		}
+	}
+	if h.rttHeader != "" {
+		if rtt, err := strconv.ParseFloat(c.GetHeader(h.rttHeader), 64); err == nil && rtt > 0 {
+			meta.TCPRTT = rtt / 1000
+		}
+	}
+	if h.ja4Header != "" {
+		if ja4 := strings.ToLower(c.GetHeader(h.ja4Header)); services.ValidJA4(ja4) {
+			meta.JA4 = ja4
+		}
+	}
+}
+
+// quarantineHeaders 隔离记录保存的请求头，不包含Cookie、Authorization、X-API-Key等凭据；Sec-Fetch-* 另外按前缀保存
+var quarantineHeaders = []string{
+	"User-Agent", "Content-Type", "Content-Encoding", "Content-Length", "Origin", "Referer",
+	"Accept", "Accept-Language", "Accept-Encoding", "Sec-Ch-Ua", "Sec-Ch-Ua-Mobile", "Sec-Ch-Ua-Platform",
+}
+
+// maxQuarantineHeaderLength 隔离记录中每个请求头保存的最大长度
+const maxQuarantineHeaderLength = 512
+
+// quarantine 将被拒绝的提交写入隔离存储，失败时只记录日志，不影响错误响应
+func (h *FingerprintHandler) quarantine(c *gin.Context, body []byte, code apierror.Code, detail string) {
+	headers := make(map[string]string)
+	for _, name := range quarantineHeaders {
+		if v := c.GetHeader(name); v != "" {
+			headers[name] = truncate(v, maxQuarantineHeaderLength)
+		}
+	}
+	for name, values := range c.Request.Header {
+		if strings.HasPrefix(name, "Sec-Fetch-") && len(values) > 0 {
+			headers[name] = truncate(values[0], maxQuarantineHeaderLength)
+		}
+	}
+	entry := models.QuarantineEntry{
+		SiteID: c.GetString(middleware.SiteIDKey),
+		IPAddress: utils.GetClientIP(
+			c.GetHeader("X-Forwarded-For"),
+			c.GetHeader("X-Real-IP"),
+			c.Request.RemoteAddr,
+		),
+		Reason:  string(code),
+		Detail:  detail,
+		Headers: headers,
+	}
+	if err := h.service.Quarantine(c.Request.Context(), entry, body); err != nil {
+		log.Printf("Failed to quarantine rejected submission: %v", err)
+	}
+}
+
+// fieldErrorsDetail 将字段错误拼接为隔离记录的说明
+func fieldErrorsDetail(errs []models.FieldError) string {
+	parts := make([]string, len(errs))
+	for i, e := range errs {
+		parts[i] = e.Field + ": " + e.Constraint
+	}
+	return strings.Join(parts, "; ")
 }
 
 // SubmitFingerprint 提交指纹数据
 func (h *FingerprintHandler) SubmitFingerprint(c *gin.Context) {
-	// 先读取原始请求体用于调试
+	h.submit(c, bodyBinding(c))
+}
+
+// SubmitFingerprintJS 提交 FingerprintJS 识别结果，转换后与原生指纹走相同的处理流程
+func (h *FingerprintHandler) SubmitFingerprintJS(c *gin.Context) {
+	h.submit(c, fingerprintJSBinding{userAgent: c.Request.UserAgent()})
+}
+
+// submit 按指定绑定方式解析请求体并处理指纹
+func (h *FingerprintHandler) submit(c *gin.Context, b binding.BindingBody) {
+	// 先读取原始请求体，被拒绝时写入隔离存储
 	bodyBytes, err := c.GetRawData()
 	if err != nil {
 		log.Printf("Failed to read request body: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Failed to read request body",
-		})
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			h.quarantine(c, bodyBytes, apierror.PayloadTooLarge, err.Error())
+			apierror.Respond(c, http.StatusRequestEntityTooLarge, apierror.PayloadTooLarge, gin.H{"limit": maxErr.Limit})
+			return
+		}
+		h.quarantine(c, bodyBytes, apierror.InvalidPayload, err.Error())
+		apierror.Respond(c, http.StatusBadRequest, apierror.InvalidPayload, apierror.Detail("Failed to read request body"))
 		return
 	}
 
-	// 重新设置请求体，以便后续绑定
-	c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
-
 	var req models.FingerprintRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		// 记录详细的错误信息
-		log.Printf("Failed to bind JSON request: %v", err)
-		log.Printf("Raw request body: %s", string(bodyBytes))
-		
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid request data: " + err.Error(),
-		})
+	if err := b.BindBody(bodyBytes, &req); err != nil {
+		log.Printf("Failed to bind %s request: %v", b.Name(), err)
+		code := apierror.InvalidPayload
+		if len(bindFieldErrors(err)) > 0 {
+			code = apierror.InvalidFields
+		}
+		h.quarantine(c, bodyBytes, code, err.Error())
+		respondBindError(c, err)
+		return
+	}
+
+	// 检查字段长度限制
+	if fieldErrors := checkRequestLimits(&req, h.limits); len(fieldErrors) > 0 {
+		log.Printf("Rejected oversized fingerprint fields: %+v", fieldErrors)
+		h.quarantine(c, bodyBytes, apierror.FieldLimits, fieldErrorsDetail(fieldErrors))
+		apierror.Respond(c, http.StatusUnprocessableEntity, apierror.FieldLimits, gin.H{"errors": fieldErrors})
+		return
+	}
+
+	// 校验分辨率、时区和语言标签的格式
+	if fieldErrors := validateRequestSemantics(&req); len(fieldErrors) > 0 {
+		log.Printf("Rejected malformed fingerprint fields: %+v", fieldErrors)
+		h.quarantine(c, bodyBytes, apierror.InvalidFields, fieldErrorsDetail(fieldErrors))
+		apierror.Respond(c, http.StatusUnprocessableEntity, apierror.InvalidFields, gin.H{"errors": fieldErrors})
 		return
 	}
 
@@ -62,13 +204,41 @@ func (h *FingerprintHandler) SubmitFingerprint(c *gin.Context) {
 	)
 
 	// 处理指纹
-	response, err := h.service.ProcessFingerprint(&req, ipAddress)
+	meta := models.RequestMeta{
+		IPAddress: ipAddress,
+		SiteID:    c.GetString(middleware.SiteIDKey),
+		VisitorID: visitorID(c),
+		// 浏览器实际发送的值不会很长，截断异常的超长请求头避免写入数据库
+		AcceptLanguage: truncate(c.GetHeader("Accept-Language"), maxAcceptLanguageLength),
+		PageURL:        truncate(pageURL(c), maxPageURLLength),
+		ClientHintsUA:  truncate(c.GetHeader("Sec-Ch-Ua"), maxClientHintsLength),
+	}
+	if h.countryHeader != "" {
+		meta.Country = utils.NormalizeCountry(c.GetHeader(h.countryHeader))
+	}
+	h.transportMeta(c, &meta)
+	response, err := h.service.ProcessFingerprint(c.Request.Context(), &req, meta)
 	if err != nil {
 		log.Printf("Failed to process fingerprint: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Failed to process fingerprint: " + err.Error(),
-		})
+		if errors.Is(err, services.ErrStorageUnavailable) {
+			if retryAfter := h.service.StorageRetryAfter(); retryAfter > 0 {
+				c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+			}
+			response.Code = string(apierror.StorageUnavailable)
+			response.Message = apierror.Localize(c, apierror.StorageUnavailable)
+			c.JSON(http.StatusServiceUnavailable, response)
+			return
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			apierror.Respond(c, http.StatusServiceUnavailable, apierror.Timeout, nil)
+			return
+		}
+		if errors.Is(err, services.ErrInvalidTiming) {
+			h.quarantine(c, bodyBytes, apierror.InvalidTiming, err.Error())
+			apierror.Respond(c, http.StatusBadRequest, apierror.InvalidTiming, nil)
+			return
+		}
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to process fingerprint: "+err.Error()))
 		return
 	}
 
@@ -80,27 +250,18 @@ func (h *FingerprintHandler) SubmitFingerprint(c *gin.Context) {
 func (h *FingerprintHandler) GetAnalysis(c *gin.Context) {
 	fingerprintHash := c.Param("hash")
 	if fingerprintHash == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Fingerprint hash is required",
-		})
+		apierror.Respond(c, http.StatusBadRequest, apierror.InvalidParameter, gin.H{"param": "hash"}, "hash")
 		return
 	}
 
-	analysis, err := h.service.GetAnalysis(fingerprintHash)
+	analysis, err := h.service.GetAnalysis(c.Request.Context(), fingerprintHash)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{
-				"success": false,
-				"message": "Analysis not found",
-			})
+			apierror.Respond(c, http.StatusNotFound, apierror.AnalysisNotFound, nil)
 			return
 		}
 
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Failed to get analysis: " + err.Error(),
-		})
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get analysis: "+err.Error()))
 		return
 	}
 
@@ -110,10 +271,588 @@ func (h *FingerprintHandler) GetAnalysis(c *gin.Context) {
 	})
 }
 
+// GetSimilar 查询与指定指纹共享组件哈希的其他指纹
+// max_diff 允许不同的组件个数（默认2），用于容忍浏览器升级、窗口缩放等带来的漂移
+func (h *FingerprintHandler) GetSimilar(c *gin.Context) {
+	maxDiff := 2
+	if raw := c.Query("max_diff"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 {
+			apierror.Respond(c, http.StatusBadRequest, apierror.InvalidParameter, gin.H{"param": "max_diff", "min": 0}, "max_diff")
+			return
+		}
+		maxDiff = v
+	}
+
+	similar, err := h.service.FindSimilar(c.Request.Context(), c.Param("hash"), maxDiff)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			apierror.Respond(c, http.StatusNotFound, apierror.FingerprintNotFound, nil)
+			return
+		}
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to find similar fingerprints: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"similar": similar,
+	})
+}
+
+// GetSimilarCanvas 查询Canvas输出与指定指纹相近的其他指纹
+// max_distance 为SimHash汉明距离上限（默认3）
+func (h *FingerprintHandler) GetSimilarCanvas(c *gin.Context) {
+	maxDistance := services.MaxCanvasDistance
+	if raw := c.Query("max_distance"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 || v > services.MaxCanvasDistance {
+			apierror.Respond(c, http.StatusBadRequest, apierror.InvalidParameter, gin.H{"param": "max_distance", "min": 0, "max": services.MaxCanvasDistance}, "max_distance")
+			return
+		}
+		maxDistance = v
+	}
+
+	matches, err := h.service.FindSimilarCanvas(c.Request.Context(), c.Param("hash"), maxDistance)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			apierror.Respond(c, http.StatusNotFound, apierror.CanvasNotFound, nil)
+			return
+		}
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to find similar canvases: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"matches": matches,
+	})
+}
+
+// maxNeighbors 近邻查询允许的最大数量
+const maxNeighbors = 100
+
+// GetNeighbors 查询特征向量最接近的其他指纹，k 默认10
+func (h *FingerprintHandler) GetNeighbors(c *gin.Context) {
+	k := 10
+	if raw := c.Query("k"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > maxNeighbors {
+			apierror.Respond(c, http.StatusBadRequest, apierror.InvalidParameter, gin.H{"param": "k", "min": 1, "max": maxNeighbors}, "k")
+			return
+		}
+		k = v
+	}
+
+	neighbors, err := h.service.FindNeighbors(c.Request.Context(), c.Param("hash"), k)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			apierror.Respond(c, http.StatusNotFound, apierror.FingerprintNotFound, nil)
+			return
+		}
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to find neighbors: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"neighbors": neighbors,
+	})
+}
+
+// maxFarms 农场报告查询允许的最大数量
+const maxFarms = 500
+
+// maxAnomalies 流量异常查询允许的最大数量
+const maxAnomalies = 500
+
+// maxStuffingDetections 撞库检测记录查询允许的最大数量
+const maxStuffingDetections = 500
+
+// maxScrapingDetections 采集爬虫检测记录查询允许的最大数量
+const maxScrapingDetections = 500
+
+// GetFarms 返回设备农场报告，limit 默认50
+func (h *FingerprintHandler) GetFarms(c *gin.Context) {
+	limit := 50
+	if raw := c.Query("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > maxFarms {
+			apierror.Respond(c, http.StatusBadRequest, apierror.InvalidParameter, gin.H{"param": "limit", "min": 1, "max": maxFarms}, "limit")
+			return
+		}
+		limit = v
+	}
+
+	farms, err := h.service.GetFarms(c.Request.Context(), limit)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get farms: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"farms":   farms,
+	})
+}
+
+// DetectFarms 立即运行一次设备农场检测任务
+func (h *FingerprintHandler) DetectFarms(c *gin.Context) {
+	farms, err := h.service.DetectFarms(c.Request.Context())
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to detect farms: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"farms":   farms,
+	})
+}
+
+// GetOutliers 返回离群检测模型的训练情况
+func (h *FingerprintHandler) GetOutliers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"outliers": h.service.OutlierStatus(),
+	})
+}
+
+// TrainOutliers 立即重新训练离群检测模型，样本不足时保留已有模型
+func (h *FingerprintHandler) TrainOutliers(c *gin.Context) {
+	status, err := h.service.TrainOutlierModel(c.Request.Context())
+	if errors.Is(err, services.ErrTooFewOutlierSamples) {
+		apierror.Respond(c, http.StatusConflict, apierror.TooFewOutlierSamples, gin.H{"detail": err.Error(), "outliers": status})
+		return
+	}
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to train outlier model: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"outliers": status,
+	})
+}
+
+// GetAnomalies 返回流量异常记录，可按 site_id 过滤，limit 默认50
+func (h *FingerprintHandler) GetAnomalies(c *gin.Context) {
+	limit := 50
+	if raw := c.Query("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > maxAnomalies {
+			apierror.Respond(c, http.StatusBadRequest, apierror.InvalidParameter, gin.H{"param": "limit", "min": 1, "max": maxAnomalies}, "limit")
+			return
+		}
+		limit = v
+	}
+
+	anomalies, err := h.service.GetAnomalies(c.Request.Context(), c.Query("site_id"), limit)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get anomalies: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"anomalies": anomalies,
+	})
+}
+
+// GetCredentialStuffing 返回撞库检测记录，可按 site_id 过滤，limit 默认50
+func (h *FingerprintHandler) GetCredentialStuffing(c *gin.Context) {
+	limit := 50
+	if raw := c.Query("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > maxStuffingDetections {
+			apierror.Respond(c, http.StatusBadRequest, apierror.InvalidParameter, gin.H{"param": "limit", "min": 1, "max": maxStuffingDetections}, "limit")
+			return
+		}
+		limit = v
+	}
+
+	detections, err := h.service.GetStuffingDetections(c.Request.Context(), c.Query("site_id"), limit)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get credential stuffing detections: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"detections": detections,
+	})
+}
+
+// GetScrapingDetections 返回采集爬虫检测记录，limit 默认50
+func (h *FingerprintHandler) GetScrapingDetections(c *gin.Context) {
+	limit := 50
+	if raw := c.Query("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > maxScrapingDetections {
+			apierror.Respond(c, http.StatusBadRequest, apierror.InvalidParameter, gin.H{"param": "limit", "min": 1, "max": maxScrapingDetections}, "limit")
+			return
+		}
+		limit = v
+	}
+
+	detections, err := h.service.GetScrapingDetections(c.Request.Context(), c.Query("site_id"), limit)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get scraping detections: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"detections": detections,
+	})
+}
+
+// GetAnonymity 返回指纹的k-匿名报告
+func (h *FingerprintHandler) GetAnonymity(c *gin.Context) {
+	report, err := h.service.GetAnonymity(c.Request.Context(), c.Param("hash"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			apierror.Respond(c, http.StatusNotFound, apierror.FingerprintNotFound, nil)
+			return
+		}
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to compute anonymity: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"anonymity": report,
+	})
+}
+
+// ExportAggregates 导出加入差分隐私噪声的聚合统计（国家、浏览器、操作系统分布）
+func (h *FingerprintHandler) ExportAggregates(c *gin.Context) {
+	export, err := h.service.ExportAggregates(c.Request.Context())
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to export aggregates: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"export":  export,
+	})
+}
+
+// GetVersionStats 返回各指纹结构版本的记录数
+func (h *FingerprintHandler) GetVersionStats(c *gin.Context) {
+	versions, err := h.service.VersionStats(c.Request.Context())
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get version stats: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":         true,
+		"current_version": models.CurrentFingerprintVersion,
+		"versions":        versions,
+	})
+}
+
+// timeRange 解析查询参数 from、to（RFC3339，可省略），格式错误时返回400且 ok 为 false
+func timeRange(c *gin.Context) (from, to *time.Time, ok bool) {
+	var bounds [2]*time.Time
+	for i, name := range []string{"from", "to"} {
+		raw := c.Query(name)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			apierror.Respond(c, http.StatusBadRequest, apierror.InvalidTimestamp, gin.H{"param": name}, name)
+			return nil, nil, false
+		}
+		bounds[i] = &t
+	}
+	return bounds[0], bounds[1], true
+}
+
+// GetReferrerStats 返回采集页面的来源统计，可按时间范围（RFC3339）和站点过滤
+func (h *FingerprintHandler) GetReferrerStats(c *gin.Context) {
+	from, to, ok := timeRange(c)
+	if !ok {
+		return
+	}
+	stats, err := h.service.ReferrerStats(c.Request.Context(), from, to, c.Query("site_id"))
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get referrer stats: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"referrers": stats,
+	})
+}
+
+// GetGPUStats 返回各GPU家族的指纹数和爬虫比例，可按时间范围（RFC3339）和站点过滤
+func (h *FingerprintHandler) GetGPUStats(c *gin.Context) {
+	from, to, ok := timeRange(c)
+	if !ok {
+		return
+	}
+	families, err := h.service.GPUStats(c.Request.Context(), from, to, c.Query("site_id"))
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get GPU stats: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"families": families,
+	})
+}
+
+// GetBrowserStats 返回各浏览器家族当前、过时、不可能仍在使用和尚未发布的版本的指纹数，可按时间范围（RFC3339）和站点过滤
+func (h *FingerprintHandler) GetBrowserStats(c *gin.Context) {
+	from, to, ok := timeRange(c)
+	if !ok {
+		return
+	}
+	browsers, err := h.service.BrowserVersionStats(c.Request.Context(), from, to, c.Query("site_id"))
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get browser stats: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"browsers": browsers,
+	})
+}
+
+// GetCrawlerStats 返回已识别爬虫的流量，按类别、AI爬虫用途、爬虫和天汇总，可按时间范围（RFC3339）、站点和类别过滤
+func (h *FingerprintHandler) GetCrawlerStats(c *gin.Context) {
+	from, to, ok := timeRange(c)
+	if !ok {
+		return
+	}
+	category := c.Query("category")
+	if category != "" && !crawlers.ValidCategory(category) {
+		apierror.Respond(c, http.StatusBadRequest, apierror.InvalidParameter, gin.H{"param": "category", "allowed": models.CrawlerCategories}, "category")
+		return
+	}
+	stats, err := h.service.CrawlerStats(c.Request.Context(), from, to, c.Query("site_id"), category)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get crawler stats: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"crawlers": stats,
+	})
+}
+
+// CheckCrawler 按 user_agent 参数（未提供时为请求的User-Agent）识别爬虫并按站点的爬虫策略给出处理，
+// 提供 path 参数时同时审计该路径是否被站点的 robots.txt 禁止；供服务端或边缘节点对不执行采集脚本的请求调用
+func (h *FingerprintHandler) CheckCrawler(c *gin.Context) {
+	userAgent := c.Query("user_agent")
+	if userAgent == "" {
+		userAgent = c.Request.UserAgent()
+	}
+	check, err := h.service.CheckCrawler(c.Request.Context(), c.GetString(middleware.SiteIDKey), userAgent, c.Query("path"))
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to check crawler: "+err.Error()))
+		return
+	}
+
+	resp := gin.H{
+		"success": true,
+		"crawler": check.Crawler,
+		"action":  check.Action,
+	}
+	if check.RobotsDisallowed != nil {
+		resp["robots_disallowed"] = *check.RobotsDisallowed
+		resp["robots_rule"] = check.RobotsRule
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// IngestAccessLogs 接收一批访问日志（每行一条，format 参数为 nginx、caddy、cloudflare 或 alb，默认 nginx），
+// 须携带站点API Key，日志计入该站点
+func (h *FingerprintHandler) IngestAccessLogs(c *gin.Context) {
+	if c.GetHeader(middleware.APIKeyHeader) == "" {
+		apierror.Respond(c, http.StatusUnauthorized, apierror.APIKeyRequired, nil)
+		return
+	}
+	format := c.DefaultQuery("format", utils.AccessLogNginx)
+	if !utils.ValidAccessLogFormat(format) {
+		apierror.Respond(c, http.StatusBadRequest, apierror.InvalidParameter, gin.H{"param": "format", "allowed": utils.AccessLogFormats}, "format")
+		return
+	}
+	result, err := h.service.IngestAccessLogs(c.Request.Context(), c.GetString(middleware.SiteIDKey), format, c.Request.Body)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		switch {
+		case errors.Is(err, services.ErrAccessLogsDisabled):
+			apierror.Respond(c, http.StatusNotFound, apierror.AccessLogsDisabled, nil)
+		case errors.As(err, &maxErr):
+			apierror.Respond(c, http.StatusRequestEntityTooLarge, apierror.PayloadTooLarge, gin.H{"limit": maxErr.Limit})
+		default:
+			apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to ingest access logs: "+err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"ingest":  result,
+	})
+}
+
+// GetAccessLogStats 返回访问日志中客户端的汇总和评分最高的客户端，可按时间范围（RFC3339）和站点过滤
+func (h *FingerprintHandler) GetAccessLogStats(c *gin.Context) {
+	from, to, ok := timeRange(c)
+	if !ok {
+		return
+	}
+	stats, err := h.service.AccessLogStats(c.Request.Context(), from, to, c.Query("site_id"))
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get access log stats: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"access_logs": stats,
+	})
+}
+
+// GetRobotsReport 返回已识别爬虫的 robots.txt 遵守情况，列出访问过禁止路径的爬虫及其最近访问的禁止路径，
+// 可按时间范围（RFC3339）和站点过滤
+func (h *FingerprintHandler) GetRobotsReport(c *gin.Context) {
+	from, to, ok := timeRange(c)
+	if !ok {
+		return
+	}
+	report, err := h.service.RobotsReport(c.Request.Context(), from, to, c.Query("site_id"))
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get robots report: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"robots":  report,
+	})
+}
+
+// GetQualityStats 以人工标注为真实值返回检测质量报告，可按 from、to（RFC3339）和 site_id 过滤
+func (h *FingerprintHandler) GetQualityStats(c *gin.Context) {
+	from, to, ok := timeRange(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.service.QualityReport(c.Request.Context(), from, to, c.Query("site_id"))
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get quality stats: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"quality": report,
+	})
+}
+
+// GetScoreStats 返回线上提交的爬虫评分分布、按统计桶的变化和漂移情况，可按 from、to（RFC3339）和 site_id 过滤
+func (h *FingerprintHandler) GetScoreStats(c *gin.Context) {
+	from, to, ok := timeRange(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.service.ScoreStats(c.Request.Context(), from, to, c.Query("site_id"))
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get score stats: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"scores":  stats,
+	})
+}
+
+// GetDrift 返回访客的指纹漂移报告
+func (h *FingerprintHandler) GetDrift(c *gin.Context) {
+	report, err := h.service.GetDriftReport(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			apierror.Respond(c, http.StatusNotFound, apierror.VisitorNotFound, nil)
+			return
+		}
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get drift report: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"drift":   report,
+	})
+}
+
+// GetVisitorFingerprints 返回访客Cookie关联过的指纹
+func (h *FingerprintHandler) GetVisitorFingerprints(c *gin.Context) {
+	fingerprints, err := h.service.GetVisitorFingerprints(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			apierror.Respond(c, http.StatusNotFound, apierror.VisitorNotFound, nil)
+			return
+		}
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get visitor fingerprints: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"fingerprints": fingerprints,
+	})
+}
+
+// GetProofSeed 签发采集脚本执行证明的种子，每次采集前获取
+func (h *FingerprintHandler) GetProofSeed(c *gin.Context) {
+	seed, expiresAt, err := h.service.IssueProofSeed()
+	if err != nil {
+		if errors.Is(err, services.ErrProofDisabled) {
+			apierror.Respond(c, http.StatusNotFound, apierror.ProofDisabled, nil)
+			return
+		}
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to issue proof seed: "+err.Error()))
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"seed":       seed,
+		"expires_at": expiresAt,
+	})
+}
+
 // HealthCheck 健康检查
 func (h *FingerprintHandler) HealthCheck(c *gin.Context) {
+	status := "healthy"
+	storage := h.service.StorageState()
+	if storage != utils.BreakerClosed {
+		status = "degraded"
+	}
+	counterBackend := h.service.Counters().Backend()
+	if counterBackend == counters.BackendFallback {
+		status = "degraded"
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"status":  "healthy",
-		"service": "browser-fingerprint-detection",
+		"status":   status,
+		"service":  "browser-fingerprint-detection",
+		"storage":  storage,
+		"counters": counterBackend,
 	})
 }
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"browser-detection/internal/api/middleware"
+	"browser-detection/internal/api/protobuf"
+	"browser-detection/internal/config"
+	"browser-detection/internal/models"
+	"browser-detection/internal/services"
+	"browser-detection/internal/utils"
+	"context"
+	"errors"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// grpcAPIKeyMetadata gRPC调用携带站点API密钥的元数据键
+const grpcAPIKeyMetadata = "x-api-key"
+
+// grpcSiteKey 上下文中保存按API密钥识别出的站点ID的键
+type grpcSiteKey struct{}
+
+// FingerprintServer 指纹提交gRPC服务，与 POST /api/fingerprint 共用校验和处理流程
+type FingerprintServer struct {
+	protobuf.UnimplementedFingerprintServiceServer
+	handler *FingerprintHandler
+}
+
+// NewGRPCServer 创建注册了指纹提交服务的gRPC服务器：每次调用须在元数据 x-api-key 中携带站点API密钥，
+// 消息大小受 POST /api/fingerprint 的请求体上限限制，处理期限为 server.request_timeout
+func NewGRPCServer(h *FingerprintHandler, cfg *config.Config) *grpc.Server {
+	maxBytes := cfg.Limits.MaxBodyBytes
+	if v, ok := cfg.Limits.RouteMaxBodyBytes["POST /api/fingerprint"]; ok {
+		maxBytes = v
+	}
+	server := grpc.NewServer(
+		grpc.MaxRecvMsgSize(int(maxBytes)),
+		grpc.UnaryInterceptor(grpcAuth(middleware.SiteAPIKeys(cfg), cfg.Server.RequestTimeout.Std())),
+	)
+	protobuf.RegisterFingerprintServiceServer(server, &FingerprintServer{handler: h})
+	return server
+}
+
+// grpcAuth 按元数据中的站点API密钥识别接入站点并设置处理期限，未携带或密钥未知时返回 Unauthenticated
+func grpcAuth(keys map[string]string, timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get(grpcAPIKeyMetadata)
+		if len(values) == 0 || values[0] == "" {
+			return nil, status.Error(codes.Unauthenticated, "site API key required")
+		}
+		siteID, ok := keys[values[0]]
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "invalid API key")
+		}
+		ctx = context.WithValue(ctx, grpcSiteKey{}, siteID)
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		return handler(ctx, req)
+	}
+}
+
+// Submit 校验并处理一次采集结果，访客的IP和请求头取自请求消息；
+// 字段不合法时返回 InvalidArgument，存储不可用时返回 Unavailable，处理超时返回 DeadlineExceeded
+func (s *FingerprintServer) Submit(ctx context.Context, in *protobuf.SubmitRequest) (*protobuf.SubmitResponse, error) {
+	if in.GetFingerprint() == nil {
+		return nil, status.Error(codes.InvalidArgument, "fingerprint is required")
+	}
+	var req models.FingerprintRequest
+	in.GetFingerprint().ToModel(&req)
+	if binding.Validator != nil {
+		if err := binding.Validator.ValidateStruct(&req); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	if fieldErrors := checkRequestLimits(&req, s.handler.limits); len(fieldErrors) > 0 {
+		return nil, status.Error(codes.InvalidArgument, fieldErrorsDetail(fieldErrors))
+	}
+	if fieldErrors := validateRequestSemantics(&req); len(fieldErrors) > 0 {
+		return nil, status.Error(codes.InvalidArgument, fieldErrorsDetail(fieldErrors))
+	}
+
+	meta := models.RequestMeta{
+		IPAddress:      utils.NormalizeIP(in.GetIpAddress()),
+		SiteID:         ctx.Value(grpcSiteKey{}).(string),
+		AcceptLanguage: truncate(in.GetAcceptLanguage(), maxAcceptLanguageLength),
+		PageURL:        truncate(in.GetPageUrl(), maxPageURLLength),
+		ClientHintsUA:  truncate(in.GetClientHintsUa(), maxClientHintsLength),
+		Country:        utils.NormalizeCountry(in.GetCountry()),
+	}
+	if meta.IPAddress == "" {
+		if p, ok := peer.FromContext(ctx); ok {
+			meta.IPAddress = utils.GetClientIP("", "", p.Addr.String())
+		}
+	} else if net.ParseIP(meta.IPAddress) == nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid ip_address")
+	}
+	if visitorIDPattern.MatchString(in.GetVisitorId()) {
+		meta.VisitorID = in.GetVisitorId()
+	}
+	if ja4 := strings.ToLower(in.GetJa4()); services.ValidJA4(ja4) {
+		meta.JA4 = ja4
+	}
+
+	response, err := s.handler.service.ProcessFingerprint(ctx, &req, meta)
+	if err != nil {
+		log.Printf("Failed to process gRPC fingerprint: %v", err)
+		switch {
+		case errors.Is(err, services.ErrStorageUnavailable):
+			return nil, status.Error(codes.Unavailable, "storage unavailable")
+		case errors.Is(err, context.DeadlineExceeded):
+			return nil, status.Error(codes.DeadlineExceeded, "request timed out")
+		case errors.Is(err, services.ErrInvalidTiming):
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, status.Error(codes.Internal, "failed to process fingerprint")
+	}
+
+	out := &protobuf.SubmitResponse{
+		FingerprintHash: response.FingerprintHash,
+		Challenge:       response.Challenge,
+		CountryPolicy:   response.CountryPolicy,
+		CrawlerPolicy:   response.CrawlerPolicy,
+		VisitorToken:    response.VisitorToken,
+	}
+	if a := response.Analysis; a != nil {
+		out.BotScore = a.BotScore
+		out.IsBot = a.IsBot
+		out.RiskLevel = a.RiskLevel
+		out.ReasonCodes = utils.JSONToStringSlice(a.ReasonCodes)
+	}
+	return out, nil
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"browser-detection/internal/config"
+	"browser-detection/internal/models"
+	"sort"
+)
+
+// requestStringFields 返回请求中需要限制长度的字符串字段，键为JSON字段名
+func requestStringFields(req *models.FingerprintRequest) map[string]string {
+	return map[string]string{
+		"fingerprint_hash":  req.FingerprintHash,
+		"user_agent":        req.UserAgent,
+		"screen_resolution": req.ScreenResolution,
+		"timezone":          req.Timezone,
+		"language":          req.Language,
+		"platform":          req.Platform,
+		"canvas":            req.Canvas,
+		"webgl":             req.WebGL,
+		"audio":             req.Audio,
+		"do_not_track":      req.DoNotTrack,
+	}
+}
+
+// requestArrayFields 返回请求中需要限制元素个数的数组字段，键为JSON字段名
+func requestArrayFields(req *models.FingerprintRequest) map[string][]string {
+	return map[string][]string{
+		"fonts":             req.Fonts,
+		"plugins":           req.Plugins,
+		"webrtc_local_ips":  req.WebRTCLocalIPs,
+		"webrtc_public_ips": req.WebRTCPublicIPs,
+		"math":              req.Math,
+		"font_metrics":      fontMetricNames(req.FontMetrics),
+		"extensions":        req.Extensions,
+		"webgl_extensions":  webglExtensions(req.WebGLParams),
+		"webgl_params":      webglParamNames(req.WebGLParams),
+	}
+}
+
+// webglExtensions 提取WebGL扩展列表，与其他数组字段一起做个数和长度限制
+func webglExtensions(params *models.WebGLParams) []string {
+	if params == nil {
+		return nil
+	}
+	return params.Extensions
+}
+
+// webglParamNames 提取WebGL参数和着色器精度格式的名称，与其他数组字段一起做个数和长度限制
+func webglParamNames(params *models.WebGLParams) []string {
+	if params == nil {
+		return nil
+	}
+	names := make([]string, 0, len(params.Params)+len(params.Precisions))
+	for name := range params.Params {
+		names = append(names, name)
+	}
+	for name := range params.Precisions {
+		names = append(names, name)
+	}
+	return names
+}
+
+// fontMetricNames 提取字体渲染尺寸中的字体名，与其他数组字段一起做个数和长度限制
+func fontMetricNames(metrics []models.FontMetric) []string {
+	names := make([]string, len(metrics))
+	for i, m := range metrics {
+		names[i] = m.Font
+	}
+	return names
+}
+
+// checkRequestLimits 检查字段长度与数组长度，在写入数据库之前拒绝超大字段
+func checkRequestLimits(req *models.FingerprintRequest, limits config.LimitsConfig) []models.FieldError {
+	var errs []models.FieldError
+
+	strs := requestStringFields(req)
+	for _, field := range sortedKeys(strs) {
+		if max, ok := limits.MaxFieldLengths[field]; ok && max > 0 && len(strs[field]) > max {
+			errs = append(errs, models.FieldError{Field: field, Constraint: "max_length", Limit: max, Got: len(strs[field])})
+		}
+	}
+
+	arrays := requestArrayFields(req)
+	for _, field := range sortedKeys(arrays) {
+		items := arrays[field]
+		if max, ok := limits.MaxArrayLengths[field]; ok && max > 0 && len(items) > max {
+			errs = append(errs, models.FieldError{Field: field, Constraint: "max_items", Limit: max, Got: len(items)})
+		}
+		if limits.MaxArrayItemLength <= 0 {
+			continue
+		}
+		for _, item := range items {
+			if len(item) > limits.MaxArrayItemLength {
+				errs = append(errs, models.FieldError{Field: field, Constraint: "max_item_length", Limit: limits.MaxArrayItemLength, Got: len(item)})
+				break
+			}
+		}
+	}
+
+	// 音频原始采样：限制渲染次数和每次的采样数
+	if max := limits.MaxArrayLengths["audio_samples"]; max > 0 && len(req.AudioSamples) > max {
+		errs = append(errs, models.FieldError{Field: "audio_samples", Constraint: "max_items", Limit: max, Got: len(req.AudioSamples)})
+	}
+	if max := limits.MaxArrayLengths["audio_samples_run"]; max > 0 {
+		for _, run := range req.AudioSamples {
+			if len(run) > max {
+				errs = append(errs, models.FieldError{Field: "audio_samples", Constraint: "max_run_length", Limit: max, Got: len(run)})
+				break
+			}
+		}
+	}
+
+	return errs
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"browser-detection/internal/api/apierror"
+	"browser-detection/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxReplicationChanges 一次拉取允许的最大变更数
+const maxReplicationChanges = 10000
+
+// GetReplicationChanges 供其他区域拉取本区域序号大于 since 的变更（封禁名单、IP信誉增量、人工标注），
+// 须携带 Authorization: Bearer <replication.token>
+func (h *FingerprintHandler) GetReplicationChanges(c *gin.Context) {
+	var since int64
+	if raw := c.Query("since"); raw != "" {
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || v < 0 {
+			apierror.Respond(c, http.StatusBadRequest, apierror.InvalidParameter, gin.H{"param": "since"}, "since")
+			return
+		}
+		since = v
+	}
+	limit := 1000
+	if raw := c.Query("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > maxReplicationChanges {
+			apierror.Respond(c, http.StatusBadRequest, apierror.InvalidParameter, gin.H{"param": "limit", "min": 1, "max": maxReplicationChanges}, "limit")
+			return
+		}
+		limit = v
+	}
+
+	batch, err := h.service.ReplicationChanges(c.Request.Context(), since, limit)
+	if err != nil {
+		if errors.Is(err, services.ErrReplicationDisabled) {
+			apierror.Respond(c, http.StatusNotFound, apierror.ReplicationDisabled, nil)
+			return
+		}
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get replication changes: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"region":    batch.Region,
+		"changes":   batch.Changes,
+		"next":      batch.Next,
+		"more":      batch.More,
+		"truncated": batch.Truncated,
+	})
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"browser-detection/internal/api/apierror"
+	"browser-detection/internal/models"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxRuleSetVersions 规则集版本列表一次查询允许的最大数量
+const maxRuleSetVersions = 200
+
+// rulesRequest 保存或校验规则集的请求，一个规则集最多500条规则
+type rulesRequest struct {
+	Rules   []models.ScoreRule `json:"rules" binding:"required,max=500"`
+	Comment string             `json:"comment" binding:"max=500"`
+}
+
+// GetRules 返回当前生效的规则集
+func (h *AdminHandler) GetRules(c *gin.Context) {
+	set, err := h.service.RuleSet(c.Request.Context())
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get rules: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"rule_set": set,
+	})
+}
+
+// PutRules 以请求中的规则创建新的规则集版本并立即生效，规则不合法时返回422和字段级错误
+func (h *AdminHandler) PutRules(c *gin.Context) {
+	var req rulesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	set, fieldErrors, err := h.service.SaveRuleSet(c.Request.Context(), req.Rules, req.Comment, adminActor(c))
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to save rules: "+err.Error()))
+		return
+	}
+	if len(fieldErrors) > 0 {
+		apierror.Respond(c, http.StatusUnprocessableEntity, apierror.InvalidRules, gin.H{"errors": fieldErrors})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"rule_set": set,
+	})
+}
+
+// ValidateRules 校验规则但不保存，规则不合法时返回422和字段级错误
+func (h *AdminHandler) ValidateRules(c *gin.Context) {
+	var req rulesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if fieldErrors := h.service.ValidateRules(req.Rules); len(fieldErrors) > 0 {
+		apierror.Respond(c, http.StatusUnprocessableEntity, apierror.InvalidRules, gin.H{"errors": fieldErrors})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// GetRuleSetVersions 按版本倒序返回规则集的历史版本，limit 默认50；最后是配置文件中的表达式（版本0）
+func (h *AdminHandler) GetRuleSetVersions(c *gin.Context) {
+	limit := 50
+	if raw := c.Query("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > maxRuleSetVersions {
+			apierror.Respond(c, http.StatusBadRequest, apierror.InvalidParameter, gin.H{"param": "limit", "min": 1, "max": maxRuleSetVersions}, "limit")
+			return
+		}
+		limit = v
+	}
+
+	versions, err := h.service.RuleSetVersions(c.Request.Context(), limit)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get rule set versions: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"versions": versions,
+	})
+}
+
+// GetRuleSetVersion 返回规则集的一个版本
+func (h *AdminHandler) GetRuleSetVersion(c *gin.Context) {
+	version, err := strconv.ParseInt(c.Param("version"), 10, 64)
+	if err != nil || version < 0 {
+		apierror.Respond(c, http.StatusNotFound, apierror.RuleSetNotFound, nil)
+		return
+	}
+
+	set, err := h.service.RuleSetVersion(c.Request.Context(), version)
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.Respond(c, http.StatusNotFound, apierror.RuleSetNotFound, nil)
+		return
+	}
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get rule set: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"rule_set": set,
+	})
+}
+
+// RollbackRules 以指定版本的规则创建新的规则集版本并立即生效；
+// 旧版本的规则已不合法时返回422和字段级错误
+func (h *AdminHandler) RollbackRules(c *gin.Context) {
+	var req struct {
+		Version *int64 `json:"version" binding:"required,min=0"`
+		Comment string `json:"comment" binding:"max=500"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	set, fieldErrors, err := h.service.RollbackRuleSet(c.Request.Context(), *req.Version, req.Comment, adminActor(c))
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.Respond(c, http.StatusNotFound, apierror.RuleSetNotFound, nil)
+		return
+	}
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to roll back rules: "+err.Error()))
+		return
+	}
+	if len(fieldErrors) > 0 {
+		apierror.Respond(c, http.StatusUnprocessableEntity, apierror.InvalidRules, gin.H{"errors": fieldErrors})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"rule_set": set,
+	})
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"browser-detection/internal/api/apierror"
+	"browser-detection/internal/models"
+	"browser-detection/internal/services"
+	"browser-detection/internal/utils"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// verifyTokenRequest 访客令牌校验请求，IP 为接入方看到的访客IP，为空时不校验IP
+type verifyTokenRequest struct {
+	Token string `json:"token" binding:"required"`
+	IP    string `json:"ip"`
+}
+
+// VerifyVisitorToken 校验访客令牌，包括吊销记录；无法在本地校验的接入方使用
+// 令牌无效时返回200和 valid=false 及原因
+func (h *FingerprintHandler) VerifyVisitorToken(c *gin.Context) {
+	var req verifyTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	claims, err := h.service.VerifyVisitorToken(c.Request.Context(), req.Token, req.IP)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrTokenMalformed), errors.Is(err, utils.ErrTokenUnknownKey),
+			errors.Is(err, utils.ErrTokenSignature), errors.Is(err, utils.ErrTokenExpired),
+			errors.Is(err, utils.ErrTokenIPMismatch), errors.Is(err, services.ErrTokenRevoked):
+			c.JSON(http.StatusOK, gin.H{
+				"success": true,
+				"valid":   false,
+				"reason":  err.Error(),
+			})
+		default:
+			apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to verify token: "+err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"valid":   true,
+		"claims":  claims,
+	})
+}
+
+// GetTokenKeys 返回仍可用于校验访客令牌的密钥，供接入方的中间件在本地校验
+func (h *AdminHandler) GetTokenKeys(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"keys":    h.service.TokenVerificationKeys(),
+	})
+}
+
+// RotateTokenKey 生成新的访客令牌签发密钥，旧密钥在令牌有效期内仍可校验
+func (h *AdminHandler) RotateTokenKey(c *gin.Context) {
+	key, err := h.service.RotateTokenKey(c.Request.Context(), adminActor(c))
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to rotate token key: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"key":     key,
+	})
+}
+
+// RevokeVisitorTokens 按 jti 吊销单个访客令牌，或按 fingerprint_hash 吊销指纹此前签发的所有令牌
+func (h *AdminHandler) RevokeVisitorTokens(c *gin.Context) {
+	var req models.TokenRevocation
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if (req.JTI == "") == (req.FingerprintHash == "") {
+		apierror.Respond(c, http.StatusBadRequest, apierror.InvalidRevocation, nil)
+		return
+	}
+
+	if err := h.service.RevokeVisitorTokens(c.Request.Context(), &req, adminActor(c)); err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to revoke tokens: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"browser-detection/internal/api/apierror"
+	"browser-detection/internal/models"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // 系统没有时区数据库时也能校验时区名
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	// resolutionPattern 屏幕分辨率的格式：宽x高
+	resolutionPattern = regexp.MustCompile(`^\d{1,5}x\d{1,5}$`)
+	// languageTagPattern BCP 47 语言标签的基本结构：2~3个字母的主语言子标签，后接若干以连字符分隔的子标签
+	languageTagPattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{1,8})*$`)
+	// knownTimezones 已确认有效的时区名，避免每次提交都重新读取时区数据
+	knownTimezones sync.Map
+)
+
+func init() {
+	// 校验错误中的字段名使用JSON字段名，与请求体和其他字段错误保持一致
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return f.Name
+			}
+			return name
+		})
+	}
+}
+
+// bindFieldErrors 将请求体绑定错误转换为字段错误：缺少必填字段、字段类型不符；无法定位到字段的错误（如JSON语法错误）返回 nil
+func bindFieldErrors(err error) []models.FieldError {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		errs := make([]models.FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			field := fe.Namespace()
+			if i := strings.Index(field, "."); i >= 0 {
+				field = field[i+1:]
+			}
+			e := models.FieldError{Field: field, Constraint: fe.Tag()}
+			if fe.Param() != "" {
+				e.Constraint += " " + fe.Param()
+			}
+			if fe.Tag() != "required" {
+				e.Got = fe.Value()
+			}
+			errs = append(errs, e)
+		}
+		return errs
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return []models.FieldError{{Field: typeErr.Field, Constraint: "type " + typeErr.Type.String(), Got: typeErr.Value}}
+	}
+	return nil
+}
+
+// respondBindError 返回请求体绑定错误：能定位到字段时返回422和结构化的字段错误，否则返回400和原始错误
+func respondBindError(c *gin.Context, err error) {
+	if fieldErrors := bindFieldErrors(err); len(fieldErrors) > 0 {
+		apierror.Respond(c, http.StatusUnprocessableEntity, apierror.InvalidFields, gin.H{"errors": fieldErrors})
+		return
+	}
+	apierror.Respond(c, http.StatusBadRequest, apierror.InvalidPayload, apierror.Detail(err.Error()))
+}
+
+// validTimezone 判断是否为IANA时区数据库中的时区名
+func validTimezone(name string) bool {
+	if _, ok := knownTimezones.Load(name); ok {
+		return true
+	}
+	if name == "Local" || strings.HasPrefix(name, "/") {
+		return false
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return false
+	}
+	knownTimezones.Store(name, struct{}{})
+	return true
+}
+
+// validateRequestSemantics 校验指纹请求中格式固定的字段：屏幕分辨率、IANA时区名和BCP 47语言标签
+// 为空的字段由 binding 标签负责，这里不重复报告
+func validateRequestSemantics(req *models.FingerprintRequest) []models.FieldError {
+	var errs []models.FieldError
+	if req.ScreenResolution != "" && !resolutionPattern.MatchString(req.ScreenResolution) {
+		errs = append(errs, models.FieldError{Field: "screen_resolution", Constraint: "format WIDTHxHEIGHT", Got: req.ScreenResolution})
+	}
+	if req.Timezone != "" && !validTimezone(req.Timezone) {
+		errs = append(errs, models.FieldError{Field: "timezone", Constraint: "IANA time zone name", Got: req.Timezone})
+	}
+	if req.Language != "" && !languageTagPattern.MatchString(req.Language) {
+		errs = append(errs, models.FieldError{Field: "language", Constraint: "BCP 47 language tag", Got: req.Language})
+	}
+	return errs
+}
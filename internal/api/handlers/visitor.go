@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// visitorCookie 访客Cookie名，用于跨访问跟踪同一浏览器的指纹漂移
+	visitorCookie = "bd_visitor"
+	// visitorCookieMaxAge 访客Cookie有效期（秒）
+	visitorCookieMaxAge = 365 * 24 * 3600
+)
+
+// visitorIDPattern 服务端签发的访客ID格式，其他取值一律重新签发
+var visitorIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// visitorID 读取请求中的访客Cookie，缺失或格式不对时签发新的访客ID
+// HTTPS下使用 SameSite=None，使嵌入在其他站点的采集脚本也能带上Cookie
+func visitorID(c *gin.Context) string {
+	if id, err := c.Cookie(visitorCookie); err == nil && visitorIDPattern.MatchString(id) {
+		return id
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		log.Printf("Failed to generate visitor ID: %v", err)
+		return ""
+	}
+	id := hex.EncodeToString(buf)
+
+	secure := c.Request.TLS != nil
+	if secure {
+		c.SetSameSite(http.SameSiteNoneMode)
+	} else {
+		c.SetSameSite(http.SameSiteLaxMode)
+	}
+	c.SetCookie(visitorCookie, id, visitorCookieMaxAge, "/", "", secure, true)
+	return id
+}
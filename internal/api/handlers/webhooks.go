@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"browser-detection/internal/api/apierror"
+	"browser-detection/internal/models"
+	"browser-detection/internal/services"
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// webhookRequest 添加或修改告警webhook的请求，修改时未给出的字段沿用当前值
+type webhookRequest struct {
+	Name    string    `json:"name"`
+	URL     *string   `json:"url"`
+	Kinds   *[]string `json:"kinds"`
+	Enabled *bool     `json:"enabled"`
+}
+
+// apply 将请求中给出的字段写入webhook
+func (r *webhookRequest) apply(h *models.Webhook) {
+	if r.URL != nil {
+		h.URL = *r.URL
+	}
+	if r.Kinds != nil {
+		h.Kinds = *r.Kinds
+	}
+	if r.Enabled != nil {
+		h.Enabled = *r.Enabled
+	}
+}
+
+// GetWebhooks 返回通过管理API配置的告警webhook
+func (h *AdminHandler) GetWebhooks(c *gin.Context) {
+	hooks, err := h.service.Webhooks(c.Request.Context())
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get webhooks: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"webhooks": hooks,
+	})
+}
+
+// PostWebhook 添加告警webhook（默认启用、接收所有告警），立即对之后的告警生效；
+// 取值不合法时返回422和字段级错误，同名的webhook已存在时返回409
+func (h *AdminHandler) PostWebhook(c *gin.Context) {
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	hook := models.Webhook{Name: req.Name, Enabled: true}
+	req.apply(&hook)
+
+	fieldErrors, err := h.service.CreateWebhook(c.Request.Context(), &hook, adminActor(c))
+	if errors.Is(err, services.ErrWebhookExists) {
+		apierror.Respond(c, http.StatusConflict, apierror.WebhookExists, nil)
+		return
+	}
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to create webhook: "+err.Error()))
+		return
+	}
+	if len(fieldErrors) > 0 {
+		apierror.Respond(c, http.StatusUnprocessableEntity, apierror.InvalidWebhook, gin.H{"errors": fieldErrors})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"webhook": hook,
+	})
+}
+
+// PutWebhook 修改告警webhook的地址、告警类型或是否启用，立即对之后的告警生效；
+// 取值不合法时返回422和字段级错误
+func (h *AdminHandler) PutWebhook(c *gin.Context) {
+	hook, err := h.service.Webhook(c.Request.Context(), c.Param("name"))
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.Respond(c, http.StatusNotFound, apierror.WebhookNotFound, nil)
+		return
+	}
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to get webhook: "+err.Error()))
+		return
+	}
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	req.apply(hook)
+
+	fieldErrors, err := h.service.UpdateWebhook(c.Request.Context(), hook, adminActor(c))
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.Respond(c, http.StatusNotFound, apierror.WebhookNotFound, nil)
+		return
+	}
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to update webhook: "+err.Error()))
+		return
+	}
+	if len(fieldErrors) > 0 {
+		apierror.Respond(c, http.StatusUnprocessableEntity, apierror.InvalidWebhook, gin.H{"errors": fieldErrors})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"webhook": hook,
+	})
+}
+
+// DeleteWebhook 删除告警webhook
+func (h *AdminHandler) DeleteWebhook(c *gin.Context) {
+	if err := h.service.DeleteWebhook(c.Request.Context(), c.Param("name"), adminActor(c)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			apierror.Respond(c, http.StatusNotFound, apierror.WebhookNotFound, nil)
+			return
+		}
+		apierror.Respond(c, http.StatusInternalServerError, apierror.Internal, apierror.Detail("Failed to delete webhook: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// TestWebhook 向告警webhook发送一条 test 类型的告警，发送失败时返回502
+// 发送受 alerting.timeout 限制，不受请求处理期限限制
+func (h *AdminHandler) TestWebhook(c *gin.Context) {
+	ctx := context.WithoutCancel(c.Request.Context())
+	err := h.service.TestWebhook(ctx, c.Param("name"))
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.Respond(c, http.StatusNotFound, apierror.WebhookNotFound, nil)
+		return
+	}
+	if err != nil {
+		apierror.Respond(c, http.StatusBadGateway, apierror.Internal, apierror.Detail("Failed to send test alert: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"browser-detection/internal/api/apierror"
+	"browser-detection/internal/config"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader 站点API密钥请求头
+const APIKeyHeader = "X-API-Key"
+
+// SiteAPIKeys 返回各站点API密钥对应的站点ID
+func SiteAPIKeys(cfg *config.Config) map[string]string {
+	keys := make(map[string]string)
+	for _, site := range cfg.Sites {
+		for _, key := range site.APIKeys {
+			keys[key] = site.ID
+		}
+	}
+	return keys
+}
+
+// APIKey 按请求头 X-API-Key 识别接入站点，匹配时覆盖按来源匹配的站点
+// 携带了未知密钥的请求返回401，未携带时不做处理
+func APIKey(cfg *config.Config) gin.HandlerFunc {
+	keys := SiteAPIKeys(cfg)
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(APIKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		siteID, ok := keys[key]
+		if !ok {
+			apierror.Abort(c, http.StatusUnauthorized, apierror.InvalidAPIKey, nil)
+			return
+		}
+		c.Set(SiteIDKey, siteID)
+		c.Next()
+	}
+}
+
+// AdminAuth 校验管理API的Bearer令牌，未配置令牌时管理API不可用
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			apierror.Abort(c, http.StatusNotFound, apierror.AdminDisabled, nil)
+			return
+		}
+		got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			apierror.Abort(c, http.StatusUnauthorized, apierror.InvalidAdminToken, nil)
+			return
+		}
+		c.Next()
+	}
+}
+
+// ReplicationAuth 校验其他区域拉取变更时携带的 Bearer 令牌，未配置 replication.region 时同步接口不可用
+func ReplicationAuth(cfg config.ReplicationConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cfg.Region == "" {
+			apierror.Abort(c, http.StatusNotFound, apierror.ReplicationDisabled, nil)
+			return
+		}
+		got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(cfg.Token)) != 1 {
+			apierror.Abort(c, http.StatusUnauthorized, apierror.InvalidReplicationToken, nil)
+			return
+		}
+		c.Next()
+	}
+}
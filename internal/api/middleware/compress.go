@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"browser-detection/internal/api/apierror"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Decompress 解压 Content-Encoding 为 gzip/deflate 的请求体
+// 解压后的字节数受限（防解压炸弹），超出时读取请求体会返回 *http.MaxBytesError；
+// 压缩状态下的原始大小由 BodyLimit 限制
+func Decompress(maxDecompressed int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		encoding := strings.ToLower(strings.TrimSpace(c.GetHeader("Content-Encoding")))
+		if encoding == "" || encoding == "identity" {
+			c.Next()
+			return
+		}
+
+		var reader io.ReadCloser
+		var err error
+		switch encoding {
+		case "gzip", "x-gzip":
+			reader, err = gzip.NewReader(c.Request.Body)
+		case "deflate":
+			reader, err = zlib.NewReader(c.Request.Body)
+		default:
+			apierror.Abort(c, http.StatusUnsupportedMediaType, apierror.UnsupportedEncoding, nil, encoding)
+			return
+		}
+		if err != nil {
+			apierror.Abort(c, http.StatusBadRequest, apierror.InvalidPayload, apierror.Detail("Invalid compressed request body"))
+			return
+		}
+		defer reader.Close()
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, reader, maxDecompressed)
+		c.Request.Header.Del("Content-Encoding")
+		c.Request.Header.Del("Content-Length")
+		c.Request.ContentLength = -1
+		c.Next()
+	}
+}
+
+var gzipWriterPool = sync.Pool{
+	New: func() interface{} {
+		return gzip.NewWriter(io.Discard)
+	},
+}
+
+// gzipResponseWriter 在首次写入响应体时才启用gzip，避免空响应（204/304）被写入压缩尾部
+type gzipResponseWriter struct {
+	gin.ResponseWriter
+	gz *gzip.Writer
+}
+
+func (w *gzipResponseWriter) start() {
+	if w.gz != nil {
+		return
+	}
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" {
+		return
+	}
+	header.Set("Content-Encoding", "gzip")
+	header.Add("Vary", "Accept-Encoding")
+	header.Del("Content-Length")
+	w.gz = gzipWriterPool.Get().(*gzip.Writer)
+	w.gz.Reset(w.ResponseWriter)
+}
+
+func (w *gzipResponseWriter) Write(data []byte) (int, error) {
+	w.start()
+	if w.gz == nil {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.gz.Write(data)
+}
+
+func (w *gzipResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *gzipResponseWriter) close() {
+	if w.gz == nil {
+		return
+	}
+	w.gz.Close()
+	gzipWriterPool.Put(w.gz)
+	w.gz = nil
+}
+
+// Gzip 响应压缩中间件，客户端声明支持gzip时压缩响应体
+func Gzip() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodHead ||
+			!strings.Contains(c.GetHeader("Accept-Encoding"), "gzip") ||
+			strings.EqualFold(c.GetHeader("Connection"), "upgrade") {
+			c.Next()
+			return
+		}
+
+		writer := &gzipResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		defer func() {
+			writer.close()
+			c.Writer = writer.ResponseWriter
+		}()
+
+		c.Next()
+	}
+}
@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"browser-detection/internal/api/apierror"
+	"browser-detection/internal/config"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SiteIDKey 上下文中保存匹配到的站点ID的键
+const SiteIDKey = "site_id"
+
+// corsPolicy 单个站点（或全局）的跨域策略
+type corsPolicy struct {
+	siteID      string
+	origins     []string
+	methods     string
+	headers     string
+	credentials bool
+	maxAge      string
+}
+
+func newCORSPolicy(cfg, fallback config.CORSConfig) corsPolicy {
+	methods, headers := cfg.AllowedMethods, cfg.AllowedHeaders
+	if len(methods) == 0 {
+		methods = fallback.AllowedMethods
+	}
+	if len(headers) == 0 {
+		headers = fallback.AllowedHeaders
+	}
+	maxAge := cfg.MaxAge
+	if maxAge == 0 {
+		maxAge = fallback.MaxAge
+	}
+	return corsPolicy{
+		origins:     cfg.AllowedOrigins,
+		methods:     strings.Join(methods, ", "),
+		headers:     strings.Join(headers, ", "),
+		credentials: cfg.AllowCredentials || fallback.AllowCredentials,
+		maxAge:      strconv.Itoa(int(maxAge.Std().Seconds())),
+	}
+}
+
+func (p corsPolicy) allows(origin string) bool {
+	for _, pattern := range p.origins {
+		if matchOrigin(pattern, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// CORS 跨域中间件
+// 按配置的来源白名单（全局 + 各站点）放行跨域请求；来源不在白名单内的预检请求和
+// 非GET请求（如指纹提交）直接返回403，防止第三方页面滥用采集接口
+func CORS(cfg *config.Config) gin.HandlerFunc {
+	global := newCORSPolicy(cfg.CORS, cfg.CORS)
+	policies := make([]corsPolicy, 0, len(cfg.Sites)+1)
+	for _, site := range cfg.Sites {
+		policy := newCORSPolicy(site.CORS, cfg.CORS)
+		policy.siteID = site.ID
+		policies = append(policies, policy)
+	}
+	policies = append(policies, global)
+
+	return func(c *gin.Context) {
+		if !cfg.EnableCORS {
+			c.Next()
+			return
+		}
+
+		origin := c.GetHeader("Origin")
+		if origin == "" || isSameOrigin(origin, c.Request.Host) {
+			c.Next()
+			return
+		}
+
+		c.Writer.Header().Add("Vary", "Origin")
+
+		var matched *corsPolicy
+		for i := range policies {
+			if policies[i].allows(origin) {
+				matched = &policies[i]
+				break
+			}
+		}
+
+		if matched == nil {
+			if c.Request.Method == http.MethodOptions ||
+				(c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead) {
+				apierror.Abort(c, http.StatusForbidden, apierror.OriginNotAllowed, nil)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if matched.siteID != "" {
+			c.Set(SiteIDKey, matched.siteID)
+		}
+		c.Header("Access-Control-Allow-Origin", origin)
+		if matched.credentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		if c.Request.Method == http.MethodOptions {
+			c.Header("Access-Control-Allow-Methods", matched.methods)
+			c.Header("Access-Control-Allow-Headers", matched.headers)
+			c.Header("Access-Control-Max-Age", matched.maxAge)
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// matchOrigin 判断来源是否匹配白名单条目，支持 "*" 与 "https://*.example.com" 通配子域名
+func matchOrigin(pattern, origin string) bool {
+	pattern = strings.ToLower(strings.TrimSuffix(pattern, "/"))
+	origin = strings.ToLower(origin)
+	if pattern == "*" || pattern == origin {
+		return true
+	}
+
+	scheme, host, ok := strings.Cut(pattern, "://*.")
+	if !ok {
+		return false
+	}
+	prefix := scheme + "://"
+	if !strings.HasPrefix(origin, prefix) {
+		return false
+	}
+	originHost := strings.TrimPrefix(origin, prefix)
+	return strings.HasSuffix(originHost, "."+host) && len(originHost) > len(host)+1
+}
+
+// isSameOrigin 判断来源与请求的Host是否一致
+func isSameOrigin(origin, host string) bool {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, host)
+}
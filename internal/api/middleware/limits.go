@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"browser-detection/internal/api/apierror"
+	"browser-detection/internal/config"
+	"browser-detection/internal/counters"
+	"browser-detection/internal/utils"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BodyLimit 按路由限制请求体大小
+// Content-Length 已超限时直接返回413，否则读取请求体超限时返回 *http.MaxBytesError
+func BodyLimit(limits config.LimitsConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		max := limits.MaxBodyFor(c.Request.Method, c.FullPath())
+		if max <= 0 || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > max {
+			apierror.Abort(c, http.StatusRequestEntityTooLarge, apierror.PayloadTooLarge, gin.H{"limit": max})
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, max)
+		c.Next()
+	}
+}
+
+// RateLimiter 按客户端IP限制请求速率，窗口内的请求数超过 requests 时返回429和 Retry-After；
+// 计数在 counters 中，配置 Redis 时多个实例合计。exempt 中的IP和网段不受限制
+func RateLimiter(cfg config.RateLimitConfig, store *counters.Counters) gin.HandlerFunc {
+	if cfg.Requests <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	var exemptAddrs []netip.Addr
+	var exemptPrefixes []netip.Prefix
+	for _, entry := range cfg.Exempt {
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			exemptAddrs = append(exemptAddrs, addr.Unmap())
+		} else if prefix, err := netip.ParsePrefix(entry); err == nil {
+			exemptPrefixes = append(exemptPrefixes, prefix)
+		}
+	}
+	exempt := func(ip string) bool {
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return false
+		}
+		addr = addr.Unmap()
+		for _, a := range exemptAddrs {
+			if a == addr {
+				return true
+			}
+		}
+		for _, p := range exemptPrefixes {
+			if p.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	window := cfg.Window.Std()
+	limit := strconv.Itoa(cfg.Requests)
+	return func(c *gin.Context) {
+		ip := utils.GetClientIP(c.GetHeader("X-Forwarded-For"), c.GetHeader("X-Real-IP"), c.Request.RemoteAddr)
+		if ip == "" || exempt(ip) {
+			c.Next()
+			return
+		}
+		count, reset, err := store.Window(c.Request.Context(), "rate:"+ip, window, time.Now())
+		if err != nil {
+			// 计数失败时不限制
+			c.Next()
+			return
+		}
+		remaining := cfg.Requests - int(math.Ceil(count))
+		c.Header("X-RateLimit-Limit", limit)
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(max(remaining, 0)))
+		if remaining < 0 {
+			retryAfter := int(math.Ceil(reset.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			apierror.Abort(c, http.StatusTooManyRequests, apierror.RateLimited, gin.H{"retry_after": retryAfter})
+			return
+		}
+		c.Next()
+	}
+}
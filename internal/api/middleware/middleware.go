@@ -1,8 +1,13 @@
 package middleware
 
 import (
+	"browser-detection/internal/api/apierror"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"fmt"
 	"log"
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -25,23 +30,6 @@ func Logger() gin.HandlerFunc {
 	})
 }
 
-// CORS 跨域中间件
-func CORS() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
-
-		c.Next()
-	}
-}
-
 // Security 安全头中间件
 func Security() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -54,14 +42,20 @@ func Security() gin.HandlerFunc {
 	}
 }
 
-// RateLimiter 简单的速率限制中间件
-func RateLimiter() gin.HandlerFunc {
-	// 这里可以实现更复杂的速率限制逻辑
-	// 现在只是一个占位符
-	return func(c *gin.Context) {
-		// TODO: 实现速率限制逻辑
-		c.Next()
+// NonceCSP 为当前响应生成CSP nonce，并以基于nonce的严格脚本策略替换 Security 设置的默认策略
+// 页面中的每个 <script> 标签都必须带上返回的nonce才能执行
+func NonceCSP(c *gin.Context) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
 	}
+	nonce := base64.StdEncoding.EncodeToString(buf)
+
+	c.Header("Content-Security-Policy", fmt.Sprintf(
+		"default-src 'self'; script-src 'self' 'nonce-%s'; style-src 'self' 'unsafe-inline'; object-src 'none'; base-uri 'self'",
+		nonce,
+	))
+	return nonce, nil
 }
 
 // ErrorHandler 错误处理中间件
@@ -70,13 +64,25 @@ func ErrorHandler() gin.HandlerFunc {
 		defer func() {
 			if err := recover(); err != nil {
 				log.Printf("Panic recovered: %v", err)
-				c.JSON(500, gin.H{
-					"success": false,
-					"message": "Internal server error",
-				})
-				c.Abort()
+				apierror.Abort(c, http.StatusInternalServerError, apierror.Internal, nil)
 			}
 		}()
 		c.Next()
 	}
 }
+
+// Timeout 为每个请求设置处理期限，期限随请求context传递到服务层和数据库调用
+func Timeout(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
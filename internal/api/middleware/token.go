@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"browser-detection/internal/utils"
+	"encoding/hex"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// visitorTokenHeader 访客令牌请求头
+	visitorTokenHeader = "X-Visitor-Token"
+	// VisitorClaimsKey 令牌有效时上下文中访客声明（*utils.VisitorClaims）的键
+	VisitorClaimsKey = "visitor_claims"
+)
+
+// VisitorToken 接入方在本地校验访客令牌的中间件，keys 为 kid 到十六进制密钥的映射
+// （即管理API导出的校验密钥）。令牌有效且与客户端IP一致时设置 VisitorClaimsKey，
+// 接入方据此跳过重新采集；缺失或无效时不做处理。本地校验不检查吊销记录，
+// 需要及时吊销时使用 POST /api/tokens/verify
+func VisitorToken(keys map[string]string) gin.HandlerFunc {
+	secrets := make(map[string][]byte, len(keys))
+	for kid, secret := range keys {
+		if b, err := hex.DecodeString(secret); err == nil {
+			secrets[kid] = b
+		}
+	}
+	lookup := func(kid string) ([]byte, bool) {
+		secret, ok := secrets[kid]
+		return secret, ok
+	}
+
+	return func(c *gin.Context) {
+		if token := c.GetHeader(visitorTokenHeader); token != "" {
+			if claims, err := utils.ParseVisitorToken(token, lookup, c.ClientIP(), time.Now()); err == nil {
+				c.Set(VisitorClaimsKey, claims)
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,115 @@
+// 进程外检测器的gRPC接口：detection.detectors.remote 中 url 为 grpc:// 或 grpcs:// 的检测器按本服务定义调用，
+// 由检测器实现 Detector 服务。原始请求与 FingerprintService/Submit 共用 FingerprintRequest 消息。
+// 新增字段只能追加新的字段编号，不得复用或修改已有编号。
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: detector.proto
+
+package protobuf
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Detector_Evaluate_FullMethodName = "/browserdetection.v1.Detector/Evaluate"
+)
+
+// DetectorClient is the client API for Detector service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type DetectorClient interface {
+	// Evaluate 对一次指纹提交给出附加的检测信号，没有发现时返回空列表
+	Evaluate(ctx context.Context, in *DetectRequest, opts ...grpc.CallOption) (*DetectResponse, error)
+}
+
+type detectorClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDetectorClient(cc grpc.ClientConnInterface) DetectorClient {
+	return &detectorClient{cc}
+}
+
+func (c *detectorClient) Evaluate(ctx context.Context, in *DetectRequest, opts ...grpc.CallOption) (*DetectResponse, error) {
+	out := new(DetectResponse)
+	err := c.cc.Invoke(ctx, Detector_Evaluate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DetectorServer is the server API for Detector service.
+// All implementations must embed UnimplementedDetectorServer
+// for forward compatibility
+type DetectorServer interface {
+	// Evaluate 对一次指纹提交给出附加的检测信号，没有发现时返回空列表
+	Evaluate(context.Context, *DetectRequest) (*DetectResponse, error)
+	mustEmbedUnimplementedDetectorServer()
+}
+
+// UnimplementedDetectorServer must be embedded to have forward compatible implementations.
+type UnimplementedDetectorServer struct {
+}
+
+func (UnimplementedDetectorServer) Evaluate(context.Context, *DetectRequest) (*DetectResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Evaluate not implemented")
+}
+func (UnimplementedDetectorServer) mustEmbedUnimplementedDetectorServer() {}
+
+// UnsafeDetectorServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DetectorServer will
+// result in compilation errors.
+type UnsafeDetectorServer interface {
+	mustEmbedUnimplementedDetectorServer()
+}
+
+func RegisterDetectorServer(s grpc.ServiceRegistrar, srv DetectorServer) {
+	s.RegisterService(&Detector_ServiceDesc, srv)
+}
+
+func _Detector_Evaluate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DetectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DetectorServer).Evaluate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Detector_Evaluate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DetectorServer).Evaluate(ctx, req.(*DetectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Detector_ServiceDesc is the grpc.ServiceDesc for Detector service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Detector_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "browserdetection.v1.Detector",
+	HandlerType: (*DetectorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Evaluate",
+			Handler:    _Detector_Evaluate_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "detector.proto",
+}
@@ -0,0 +1,41 @@
+package protobuf
+
+import (
+	"browser-detection/internal/models"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// edgeActions、edgeSources 处理建议和决策依据的枚举值，未列出的取值编码为0
+var (
+	edgeActions = map[string]EdgeAction{
+		models.ActionAllow:     EdgeAction_EDGE_ACTION_ALLOW,
+		models.ActionChallenge: EdgeAction_EDGE_ACTION_CHALLENGE,
+		models.ActionDeny:      EdgeAction_EDGE_ACTION_DENY,
+	}
+	edgeSources = map[string]EdgeSource{
+		models.EdgeSourceNone:        EdgeSource_EDGE_SOURCE_NONE,
+		models.EdgeSourceFingerprint: EdgeSource_EDGE_SOURCE_FINGERPRINT,
+		models.EdgeSourceVisitor:     EdgeSource_EDGE_SOURCE_VISITOR,
+		models.EdgeSourceJA4:         EdgeSource_EDGE_SOURCE_JA4,
+	}
+)
+
+// MarshalEdgeDecision 按 api/proto/edge.proto 编码边缘决策
+func MarshalEdgeDecision(d *models.EdgeDecision) ([]byte, error) {
+	msg := &EdgeDecision{
+		Action:          edgeActions[d.Action],
+		Source:          edgeSources[d.Source],
+		BotScore:        float32(d.BotScore),
+		IsBot:           d.IsBot,
+		FingerprintHash: d.FingerprintHash,
+	}
+	if d.MaxAge > 0 {
+		msg.MaxAge = uint32(d.MaxAge)
+	}
+	if d.JA4BotRatio != nil {
+		ratio := float32(*d.JA4BotRatio)
+		msg.Ja4BotRatio = &ratio
+	}
+	return proto.Marshal(msg)
+}
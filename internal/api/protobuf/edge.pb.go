@@ -0,0 +1,338 @@
+// 边缘节点决策查询（GET /api/edge/decision，Accept: application/x-protobuf）的响应消息
+// 字段与 internal/models.EdgeDecision 的JSON字段对应，处理建议和决策依据编码为枚举以缩短响应。
+// 新增字段只能追加新的字段编号，不得复用或修改已有编号。
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.31.0
+// 	protoc        (unknown)
+// source: edge.proto
+
+package protobuf
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// EdgeAction 处理建议
+type EdgeAction int32
+
+const (
+	EdgeAction_EDGE_ACTION_ALLOW     EdgeAction = 0
+	EdgeAction_EDGE_ACTION_CHALLENGE EdgeAction = 1
+	EdgeAction_EDGE_ACTION_DENY      EdgeAction = 2
+)
+
+// Enum value maps for EdgeAction.
+var (
+	EdgeAction_name = map[int32]string{
+		0: "EDGE_ACTION_ALLOW",
+		1: "EDGE_ACTION_CHALLENGE",
+		2: "EDGE_ACTION_DENY",
+	}
+	EdgeAction_value = map[string]int32{
+		"EDGE_ACTION_ALLOW":     0,
+		"EDGE_ACTION_CHALLENGE": 1,
+		"EDGE_ACTION_DENY":      2,
+	}
+)
+
+func (x EdgeAction) Enum() *EdgeAction {
+	p := new(EdgeAction)
+	*p = x
+	return p
+}
+
+func (x EdgeAction) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EdgeAction) Descriptor() protoreflect.EnumDescriptor {
+	return file_edge_proto_enumTypes[0].Descriptor()
+}
+
+func (EdgeAction) Type() protoreflect.EnumType {
+	return &file_edge_proto_enumTypes[0]
+}
+
+func (x EdgeAction) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use EdgeAction.Descriptor instead.
+func (EdgeAction) EnumDescriptor() ([]byte, []int) {
+	return file_edge_proto_rawDescGZIP(), []int{0}
+}
+
+// EdgeSource 决策依据
+type EdgeSource int32
+
+const (
+	EdgeSource_EDGE_SOURCE_NONE        EdgeSource = 0
+	EdgeSource_EDGE_SOURCE_FINGERPRINT EdgeSource = 1
+	EdgeSource_EDGE_SOURCE_VISITOR     EdgeSource = 2
+	EdgeSource_EDGE_SOURCE_JA4         EdgeSource = 3
+)
+
+// Enum value maps for EdgeSource.
+var (
+	EdgeSource_name = map[int32]string{
+		0: "EDGE_SOURCE_NONE",
+		1: "EDGE_SOURCE_FINGERPRINT",
+		2: "EDGE_SOURCE_VISITOR",
+		3: "EDGE_SOURCE_JA4",
+	}
+	EdgeSource_value = map[string]int32{
+		"EDGE_SOURCE_NONE":        0,
+		"EDGE_SOURCE_FINGERPRINT": 1,
+		"EDGE_SOURCE_VISITOR":     2,
+		"EDGE_SOURCE_JA4":         3,
+	}
+)
+
+func (x EdgeSource) Enum() *EdgeSource {
+	p := new(EdgeSource)
+	*p = x
+	return p
+}
+
+func (x EdgeSource) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EdgeSource) Descriptor() protoreflect.EnumDescriptor {
+	return file_edge_proto_enumTypes[1].Descriptor()
+}
+
+func (EdgeSource) Type() protoreflect.EnumType {
+	return &file_edge_proto_enumTypes[1]
+}
+
+func (x EdgeSource) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use EdgeSource.Descriptor instead.
+func (EdgeSource) EnumDescriptor() ([]byte, []int) {
+	return file_edge_proto_rawDescGZIP(), []int{1}
+}
+
+// EdgeDecision 边缘节点的处理建议
+type EdgeDecision struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Action   EdgeAction `protobuf:"varint,1,opt,name=action,proto3,enum=browserdetection.v1.EdgeAction" json:"action,omitempty"`
+	Source   EdgeSource `protobuf:"varint,2,opt,name=source,proto3,enum=browserdetection.v1.EdgeSource" json:"source,omitempty"`
+	BotScore float32    `protobuf:"fixed32,3,opt,name=bot_score,json=botScore,proto3" json:"bot_score,omitempty"`
+	IsBot    bool       `protobuf:"varint,4,opt,name=is_bot,json=isBot,proto3" json:"is_bot,omitempty"`
+	// max_age 决策可缓存的秒数
+	MaxAge          uint32 `protobuf:"varint,5,opt,name=max_age,json=maxAge,proto3" json:"max_age,omitempty"`
+	FingerprintHash string `protobuf:"bytes,6,opt,name=fingerprint_hash,json=fingerprintHash,proto3" json:"fingerprint_hash,omitempty"`
+	// ja4_bot_ratio 只在 source 为 EDGE_SOURCE_JA4 时给出
+	Ja4BotRatio *float32 `protobuf:"fixed32,7,opt,name=ja4_bot_ratio,json=ja4BotRatio,proto3,oneof" json:"ja4_bot_ratio,omitempty"`
+}
+
+func (x *EdgeDecision) Reset() {
+	*x = EdgeDecision{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_edge_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *EdgeDecision) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EdgeDecision) ProtoMessage() {}
+
+func (x *EdgeDecision) ProtoReflect() protoreflect.Message {
+	mi := &file_edge_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EdgeDecision.ProtoReflect.Descriptor instead.
+func (*EdgeDecision) Descriptor() ([]byte, []int) {
+	return file_edge_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *EdgeDecision) GetAction() EdgeAction {
+	if x != nil {
+		return x.Action
+	}
+	return EdgeAction_EDGE_ACTION_ALLOW
+}
+
+func (x *EdgeDecision) GetSource() EdgeSource {
+	if x != nil {
+		return x.Source
+	}
+	return EdgeSource_EDGE_SOURCE_NONE
+}
+
+func (x *EdgeDecision) GetBotScore() float32 {
+	if x != nil {
+		return x.BotScore
+	}
+	return 0
+}
+
+func (x *EdgeDecision) GetIsBot() bool {
+	if x != nil {
+		return x.IsBot
+	}
+	return false
+}
+
+func (x *EdgeDecision) GetMaxAge() uint32 {
+	if x != nil {
+		return x.MaxAge
+	}
+	return 0
+}
+
+func (x *EdgeDecision) GetFingerprintHash() string {
+	if x != nil {
+		return x.FingerprintHash
+	}
+	return ""
+}
+
+func (x *EdgeDecision) GetJa4BotRatio() float32 {
+	if x != nil && x.Ja4BotRatio != nil {
+		return *x.Ja4BotRatio
+	}
+	return 0
+}
+
+var File_edge_proto protoreflect.FileDescriptor
+
+var file_edge_proto_rawDesc = []byte{
+	0x0a, 0x0a, 0x65, 0x64, 0x67, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x13, 0x62, 0x72,
+	0x6f, 0x77, 0x73, 0x65, 0x72, 0x64, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x76,
+	0x31, 0x22, 0xb3, 0x02, 0x0a, 0x0c, 0x45, 0x64, 0x67, 0x65, 0x44, 0x65, 0x63, 0x69, 0x73, 0x69,
+	0x6f, 0x6e, 0x12, 0x37, 0x0a, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0e, 0x32, 0x1f, 0x2e, 0x62, 0x72, 0x6f, 0x77, 0x73, 0x65, 0x72, 0x64, 0x65, 0x74, 0x65,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x64, 0x67, 0x65, 0x41, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x52, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x37, 0x0a, 0x06, 0x73,
+	0x6f, 0x75, 0x72, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x1f, 0x2e, 0x62, 0x72,
+	0x6f, 0x77, 0x73, 0x65, 0x72, 0x64, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x76,
+	0x31, 0x2e, 0x45, 0x64, 0x67, 0x65, 0x53, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x52, 0x06, 0x73, 0x6f,
+	0x75, 0x72, 0x63, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x62, 0x6f, 0x74, 0x5f, 0x73, 0x63, 0x6f, 0x72,
+	0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x02, 0x52, 0x08, 0x62, 0x6f, 0x74, 0x53, 0x63, 0x6f, 0x72,
+	0x65, 0x12, 0x15, 0x0a, 0x06, 0x69, 0x73, 0x5f, 0x62, 0x6f, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x05, 0x69, 0x73, 0x42, 0x6f, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x6d, 0x61, 0x78, 0x5f,
+	0x61, 0x67, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x06, 0x6d, 0x61, 0x78, 0x41, 0x67,
+	0x65, 0x12, 0x29, 0x0a, 0x10, 0x66, 0x69, 0x6e, 0x67, 0x65, 0x72, 0x70, 0x72, 0x69, 0x6e, 0x74,
+	0x5f, 0x68, 0x61, 0x73, 0x68, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0f, 0x66, 0x69, 0x6e,
+	0x67, 0x65, 0x72, 0x70, 0x72, 0x69, 0x6e, 0x74, 0x48, 0x61, 0x73, 0x68, 0x12, 0x27, 0x0a, 0x0d,
+	0x6a, 0x61, 0x34, 0x5f, 0x62, 0x6f, 0x74, 0x5f, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x18, 0x07, 0x20,
+	0x01, 0x28, 0x02, 0x48, 0x00, 0x52, 0x0b, 0x6a, 0x61, 0x34, 0x42, 0x6f, 0x74, 0x52, 0x61, 0x74,
+	0x69, 0x6f, 0x88, 0x01, 0x01, 0x42, 0x10, 0x0a, 0x0e, 0x5f, 0x6a, 0x61, 0x34, 0x5f, 0x62, 0x6f,
+	0x74, 0x5f, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x2a, 0x54, 0x0a, 0x0a, 0x45, 0x64, 0x67, 0x65, 0x41,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x15, 0x0a, 0x11, 0x45, 0x44, 0x47, 0x45, 0x5f, 0x41, 0x43,
+	0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x41, 0x4c, 0x4c, 0x4f, 0x57, 0x10, 0x00, 0x12, 0x19, 0x0a, 0x15,
+	0x45, 0x44, 0x47, 0x45, 0x5f, 0x41, 0x43, 0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x43, 0x48, 0x41, 0x4c,
+	0x4c, 0x45, 0x4e, 0x47, 0x45, 0x10, 0x01, 0x12, 0x14, 0x0a, 0x10, 0x45, 0x44, 0x47, 0x45, 0x5f,
+	0x41, 0x43, 0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x44, 0x45, 0x4e, 0x59, 0x10, 0x02, 0x2a, 0x6d, 0x0a,
+	0x0a, 0x45, 0x64, 0x67, 0x65, 0x53, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x12, 0x14, 0x0a, 0x10, 0x45,
+	0x44, 0x47, 0x45, 0x5f, 0x53, 0x4f, 0x55, 0x52, 0x43, 0x45, 0x5f, 0x4e, 0x4f, 0x4e, 0x45, 0x10,
+	0x00, 0x12, 0x1b, 0x0a, 0x17, 0x45, 0x44, 0x47, 0x45, 0x5f, 0x53, 0x4f, 0x55, 0x52, 0x43, 0x45,
+	0x5f, 0x46, 0x49, 0x4e, 0x47, 0x45, 0x52, 0x50, 0x52, 0x49, 0x4e, 0x54, 0x10, 0x01, 0x12, 0x17,
+	0x0a, 0x13, 0x45, 0x44, 0x47, 0x45, 0x5f, 0x53, 0x4f, 0x55, 0x52, 0x43, 0x45, 0x5f, 0x56, 0x49,
+	0x53, 0x49, 0x54, 0x4f, 0x52, 0x10, 0x02, 0x12, 0x13, 0x0a, 0x0f, 0x45, 0x44, 0x47, 0x45, 0x5f,
+	0x53, 0x4f, 0x55, 0x52, 0x43, 0x45, 0x5f, 0x4a, 0x41, 0x34, 0x10, 0x03, 0x42, 0x29, 0x5a, 0x27,
+	0x62, 0x72, 0x6f, 0x77, 0x73, 0x65, 0x72, 0x2d, 0x64, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69, 0x6f,
+	0x6e, 0x2f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_edge_proto_rawDescOnce sync.Once
+	file_edge_proto_rawDescData = file_edge_proto_rawDesc
+)
+
+func file_edge_proto_rawDescGZIP() []byte {
+	file_edge_proto_rawDescOnce.Do(func() {
+		file_edge_proto_rawDescData = protoimpl.X.CompressGZIP(file_edge_proto_rawDescData)
+	})
+	return file_edge_proto_rawDescData
+}
+
+var file_edge_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_edge_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_edge_proto_goTypes = []interface{}{
+	(EdgeAction)(0),      // 0: browserdetection.v1.EdgeAction
+	(EdgeSource)(0),      // 1: browserdetection.v1.EdgeSource
+	(*EdgeDecision)(nil), // 2: browserdetection.v1.EdgeDecision
+}
+var file_edge_proto_depIdxs = []int32{
+	0, // 0: browserdetection.v1.EdgeDecision.action:type_name -> browserdetection.v1.EdgeAction
+	1, // 1: browserdetection.v1.EdgeDecision.source:type_name -> browserdetection.v1.EdgeSource
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_edge_proto_init() }
+func file_edge_proto_init() {
+	if File_edge_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_edge_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*EdgeDecision); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_edge_proto_msgTypes[0].OneofWrappers = []interface{}{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_edge_proto_rawDesc,
+			NumEnums:      2,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_edge_proto_goTypes,
+		DependencyIndexes: file_edge_proto_depIdxs,
+		EnumInfos:         file_edge_proto_enumTypes,
+		MessageInfos:      file_edge_proto_msgTypes,
+	}.Build()
+	File_edge_proto = out.File
+	file_edge_proto_rawDesc = nil
+	file_edge_proto_goTypes = nil
+	file_edge_proto_depIdxs = nil
+}
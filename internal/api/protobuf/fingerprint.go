@@ -0,0 +1,310 @@
+// Package protobuf 由 api/proto 中的定义生成的消息类型和gRPC服务（make proto），
+// 以及消息与 internal/models 结构之间的转换
+package protobuf
+
+import (
+	"browser-detection/internal/models"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// UnmarshalFingerprintRequest 将Protobuf编码的FingerprintRequest解码到模型结构
+func UnmarshalFingerprintRequest(b []byte, req *models.FingerprintRequest) error {
+	var msg FingerprintRequest
+	if err := proto.Unmarshal(b, &msg); err != nil {
+		return err
+	}
+	msg.ToModel(req)
+	return nil
+}
+
+// ToModel 将消息写入模型结构，未出现的嵌套消息保持为 nil
+func (m *FingerprintRequest) ToModel(req *models.FingerprintRequest) {
+	req.FingerprintHash = m.GetFingerprintHash()
+	req.UserAgent = m.GetUserAgent()
+	req.ScreenResolution = m.GetScreenResolution()
+	req.Timezone = m.GetTimezone()
+	req.Language = m.GetLanguage()
+	req.Platform = m.GetPlatform()
+	req.Canvas = m.GetCanvas()
+	req.WebGL = m.GetWebgl()
+	req.Audio = m.GetAudio()
+	req.Fonts = m.GetFonts()
+	req.Plugins = m.GetPlugins()
+	req.TouchSupport = m.GetTouchSupport()
+	req.CookieEnabled = m.GetCookieEnabled()
+	req.DoNotTrack = m.GetDoNotTrack()
+	req.CanvasNoiseDetection = m.GetCanvasNoiseDetection().toModel()
+	req.WebGLNoiseDetection = m.GetWebglNoiseDetection().toModel()
+	req.AudioNoiseDetection = m.GetAudioNoiseDetection().toModel()
+	req.WebRTCLocalIPs = m.GetWebrtcLocalIps()
+	req.WebRTCPublicIPs = m.GetWebrtcPublicIps()
+	req.HardwareConcurrency = int(m.GetHardwareConcurrency())
+	req.DeviceMemory = m.GetDeviceMemory()
+	req.MediaDevices = m.GetMediaDevices().toModel()
+	req.Battery = m.GetBattery().toModel()
+	req.Sensors = m.GetSensors().toModel()
+	req.Screen = m.GetScreen().toModel()
+	req.Math = m.GetMath()
+	req.Features = m.GetFeatures().toModel()
+	req.FontMetrics = nil
+	for _, metric := range m.GetFontMetrics() {
+		req.FontMetrics = append(req.FontMetrics, models.FontMetric{
+			Font:   metric.GetFont(),
+			Width:  metric.GetWidth(),
+			Height: metric.GetHeight(),
+		})
+	}
+	req.ContentBlocking = m.GetContentBlocking().toModel()
+	req.Extensions = m.GetExtensions()
+	req.Brave = m.GetBrave()
+	req.AudioSamples = nil
+	for _, run := range m.GetAudioSamples() {
+		req.AudioSamples = append(req.AudioSamples, run.GetSamples())
+	}
+	req.FingerprintVersion = int(m.GetFingerprintVersion())
+	req.Network = m.GetNetwork().toModel()
+	req.Navigation = m.GetNavigation().toModel()
+	req.Proof = m.GetProof().toModel()
+	req.Timing = m.GetTiming().toModel()
+	req.Interaction = m.GetInteraction().toModel()
+	req.CanvasCheck = m.GetCanvasCheck().toModel()
+	req.WebGLParams = m.GetWebglParams().toModel()
+}
+
+func (m *NoiseDetection) toModel() *models.NoiseDetection {
+	if m == nil {
+		return nil
+	}
+	return &models.NoiseDetection{HasNoise: m.HasNoise, Type: m.Type, Confidence: m.Confidence, Details: m.Details}
+}
+
+func (m *MediaDevices) toModel() *models.MediaDeviceCounts {
+	if m == nil {
+		return nil
+	}
+	return &models.MediaDeviceCounts{
+		AudioInput:  int(m.AudioInput),
+		AudioOutput: int(m.AudioOutput),
+		VideoInput:  int(m.VideoInput),
+	}
+}
+
+func (m *Battery) toModel() *models.BatteryInfo {
+	if m == nil {
+		return nil
+	}
+	return &models.BatteryInfo{Supported: m.Supported, Charging: m.Charging, Level: m.Level}
+}
+
+func (m *Sensors) toModel() *models.SensorInfo {
+	if m == nil {
+		return nil
+	}
+	return &models.SensorInfo{Accelerometer: m.Accelerometer, Gyroscope: m.Gyroscope}
+}
+
+func (m *ScreenMetrics) toModel() *models.ScreenMetrics {
+	if m == nil {
+		return nil
+	}
+	return &models.ScreenMetrics{
+		ColorDepth:       int(m.ColorDepth),
+		DevicePixelRatio: m.DevicePixelRatio,
+		AvailWidth:       int(m.AvailWidth),
+		AvailHeight:      int(m.AvailHeight),
+		OuterWidth:       int(m.OuterWidth),
+		OuterHeight:      int(m.OuterHeight),
+	}
+}
+
+func (m *FeatureProbes) toModel() *models.FeatureProbes {
+	if m == nil {
+		return nil
+	}
+	return &models.FeatureProbes{Bits: m.Bits, Count: int(m.Count)}
+}
+
+func (m *ContentBlocking) toModel() *models.ContentBlocking {
+	if m == nil {
+		return nil
+	}
+	return &models.ContentBlocking{Baits: int(m.Baits), Blocked: int(m.Blocked)}
+}
+
+func (m *NetworkTiming) toModel() *models.NetworkTiming {
+	if m == nil {
+		return nil
+	}
+	return &models.NetworkTiming{PingMS: m.PingMs}
+}
+
+func (m *NavigationContext) toModel() *models.NavigationContext {
+	if m == nil {
+		return nil
+	}
+	return &models.NavigationContext{Referrer: m.Referrer, Page: m.Page}
+}
+
+func (m *ExecutionProof) toModel() *models.ExecutionProof {
+	if m == nil {
+		return nil
+	}
+	return &models.ExecutionProof{Seed: m.Seed, Value: m.Value}
+}
+
+func (m *CollectionTiming) toModel() *models.CollectionTiming {
+	if m == nil {
+		return nil
+	}
+	return &models.CollectionTiming{StartedAt: m.StartedAt, FinishedAt: m.FinishedAt}
+}
+
+func (m *InputSummary) toModel() *models.InputSummary {
+	if m == nil {
+		return nil
+	}
+	return &models.InputSummary{
+		Touches:          int(m.Touches),
+		PressureVariance: m.PressureVariance,
+		SizeVariance:     m.SizeVariance,
+		MotionSamples:    int(m.MotionSamples),
+		MotionEntropy:    m.MotionEntropy,
+	}
+}
+
+func (m *CanvasCheck) toModel() *models.CanvasCheck {
+	if m == nil {
+		return nil
+	}
+	return &models.CanvasCheck{Static: m.Static, Seed: m.Seed, Seeded: m.Seeded}
+}
+
+func (m *WebGLParams) toModel() *models.WebGLParams {
+	if m == nil {
+		return nil
+	}
+	params := &models.WebGLParams{Extensions: m.Extensions}
+	if len(m.Params) > 0 {
+		params.Params = make(map[string]int, len(m.Params))
+		for name, v := range m.Params {
+			params.Params[name] = int(v)
+		}
+	}
+	if len(m.Precisions) > 0 {
+		params.Precisions = make(map[string][3]int, len(m.Precisions))
+		for name, p := range m.Precisions {
+			params.Precisions[name] = [3]int{int(p.GetRangeMin()), int(p.GetRangeMax()), int(p.GetPrecision())}
+		}
+	}
+	return params
+}
+
+// NewFingerprintRequest 将模型结构转换为消息，供调用进程外检测器
+func NewFingerprintRequest(req *models.FingerprintRequest) *FingerprintRequest {
+	m := &FingerprintRequest{
+		FingerprintHash:     req.FingerprintHash,
+		UserAgent:           req.UserAgent,
+		ScreenResolution:    req.ScreenResolution,
+		Timezone:            req.Timezone,
+		Language:            req.Language,
+		Platform:            req.Platform,
+		Canvas:              req.Canvas,
+		Webgl:               req.WebGL,
+		Audio:               req.Audio,
+		Fonts:               req.Fonts,
+		Plugins:             req.Plugins,
+		TouchSupport:        req.TouchSupport,
+		CookieEnabled:       req.CookieEnabled,
+		DoNotTrack:          req.DoNotTrack,
+		WebrtcLocalIps:      req.WebRTCLocalIPs,
+		WebrtcPublicIps:     req.WebRTCPublicIPs,
+		HardwareConcurrency: int32(req.HardwareConcurrency),
+		DeviceMemory:        req.DeviceMemory,
+		Math:                req.Math,
+		Extensions:          req.Extensions,
+		Brave:               req.Brave,
+		FingerprintVersion:  int32(req.FingerprintVersion),
+	}
+	m.CanvasNoiseDetection = newNoiseDetection(req.CanvasNoiseDetection)
+	m.WebglNoiseDetection = newNoiseDetection(req.WebGLNoiseDetection)
+	m.AudioNoiseDetection = newNoiseDetection(req.AudioNoiseDetection)
+	if d := req.MediaDevices; d != nil {
+		m.MediaDevices = &MediaDevices{AudioInput: int32(d.AudioInput), AudioOutput: int32(d.AudioOutput), VideoInput: int32(d.VideoInput)}
+	}
+	if b := req.Battery; b != nil {
+		m.Battery = &Battery{Supported: b.Supported, Charging: b.Charging, Level: b.Level}
+	}
+	if s := req.Sensors; s != nil {
+		m.Sensors = &Sensors{Accelerometer: s.Accelerometer, Gyroscope: s.Gyroscope}
+	}
+	if s := req.Screen; s != nil {
+		m.Screen = &ScreenMetrics{
+			ColorDepth:       int32(s.ColorDepth),
+			DevicePixelRatio: s.DevicePixelRatio,
+			AvailWidth:       int32(s.AvailWidth),
+			AvailHeight:      int32(s.AvailHeight),
+			OuterWidth:       int32(s.OuterWidth),
+			OuterHeight:      int32(s.OuterHeight),
+		}
+	}
+	if f := req.Features; f != nil {
+		m.Features = &FeatureProbes{Bits: f.Bits, Count: int32(f.Count)}
+	}
+	for _, metric := range req.FontMetrics {
+		m.FontMetrics = append(m.FontMetrics, &FontMetric{Font: metric.Font, Width: metric.Width, Height: metric.Height})
+	}
+	if b := req.ContentBlocking; b != nil {
+		m.ContentBlocking = &ContentBlocking{Baits: int32(b.Baits), Blocked: int32(b.Blocked)}
+	}
+	for _, samples := range req.AudioSamples {
+		m.AudioSamples = append(m.AudioSamples, &AudioRun{Samples: samples})
+	}
+	if n := req.Network; n != nil {
+		m.Network = &NetworkTiming{PingMs: n.PingMS}
+	}
+	if n := req.Navigation; n != nil {
+		m.Navigation = &NavigationContext{Referrer: n.Referrer, Page: n.Page}
+	}
+	if p := req.Proof; p != nil {
+		m.Proof = &ExecutionProof{Seed: p.Seed, Value: p.Value}
+	}
+	if t := req.Timing; t != nil {
+		m.Timing = &CollectionTiming{StartedAt: t.StartedAt, FinishedAt: t.FinishedAt}
+	}
+	if i := req.Interaction; i != nil {
+		m.Interaction = &InputSummary{
+			Touches:          int32(i.Touches),
+			PressureVariance: i.PressureVariance,
+			SizeVariance:     i.SizeVariance,
+			MotionSamples:    int32(i.MotionSamples),
+			MotionEntropy:    i.MotionEntropy,
+		}
+	}
+	if c := req.CanvasCheck; c != nil {
+		m.CanvasCheck = &CanvasCheck{Static: c.Static, Seed: c.Seed, Seeded: c.Seeded}
+	}
+	if w := req.WebGLParams; w != nil {
+		m.WebglParams = &WebGLParams{Extensions: w.Extensions}
+		if len(w.Params) > 0 {
+			m.WebglParams.Params = make(map[string]int32, len(w.Params))
+			for name, v := range w.Params {
+				m.WebglParams.Params[name] = int32(v)
+			}
+		}
+		if len(w.Precisions) > 0 {
+			m.WebglParams.Precisions = make(map[string]*ShaderPrecision, len(w.Precisions))
+			for name, p := range w.Precisions {
+				m.WebglParams.Precisions[name] = &ShaderPrecision{RangeMin: int32(p[0]), RangeMax: int32(p[1]), Precision: int32(p[2])}
+			}
+		}
+	}
+	return m
+}
+
+func newNoiseDetection(nd *models.NoiseDetection) *NoiseDetection {
+	if nd == nil {
+		return nil
+	}
+	return &NoiseDetection{HasNoise: nd.HasNoise, Type: nd.Type, Confidence: nd.Confidence, Details: nd.Details}
+}
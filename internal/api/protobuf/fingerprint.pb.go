@@ -0,0 +1,2018 @@
+// 浏览器指纹提交的Protobuf定义
+// 字段与 internal/models.FingerprintRequest 的JSON字段一一对应，
+// application/x-protobuf 提交以及后续的gRPC接口共用本文件中的消息定义。
+// 新增字段只能追加新的字段编号，不得复用或修改已有编号。
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.31.0
+// 	protoc        (unknown)
+// source: fingerprint.proto
+
+package protobuf
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// NoiseDetection 噪点检测结果
+type NoiseDetection struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	HasNoise   bool    `protobuf:"varint,1,opt,name=has_noise,json=hasNoise,proto3" json:"has_noise,omitempty"`
+	Type       string  `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Confidence float64 `protobuf:"fixed64,3,opt,name=confidence,proto3" json:"confidence,omitempty"`
+	Details    string  `protobuf:"bytes,4,opt,name=details,proto3" json:"details,omitempty"`
+}
+
+func (x *NoiseDetection) Reset() {
+	*x = NoiseDetection{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_fingerprint_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *NoiseDetection) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NoiseDetection) ProtoMessage() {}
+
+func (x *NoiseDetection) ProtoReflect() protoreflect.Message {
+	mi := &file_fingerprint_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NoiseDetection.ProtoReflect.Descriptor instead.
+func (*NoiseDetection) Descriptor() ([]byte, []int) {
+	return file_fingerprint_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *NoiseDetection) GetHasNoise() bool {
+	if x != nil {
+		return x.HasNoise
+	}
+	return false
+}
+
+func (x *NoiseDetection) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *NoiseDetection) GetConfidence() float64 {
+	if x != nil {
+		return x.Confidence
+	}
+	return 0
+}
+
+func (x *NoiseDetection) GetDetails() string {
+	if x != nil {
+		return x.Details
+	}
+	return ""
+}
+
+// MediaDevices 媒体设备数量（仅计数，不含设备标签）
+type MediaDevices struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	AudioInput  int32 `protobuf:"varint,1,opt,name=audio_input,json=audioInput,proto3" json:"audio_input,omitempty"`
+	AudioOutput int32 `protobuf:"varint,2,opt,name=audio_output,json=audioOutput,proto3" json:"audio_output,omitempty"`
+	VideoInput  int32 `protobuf:"varint,3,opt,name=video_input,json=videoInput,proto3" json:"video_input,omitempty"`
+}
+
+func (x *MediaDevices) Reset() {
+	*x = MediaDevices{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_fingerprint_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MediaDevices) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MediaDevices) ProtoMessage() {}
+
+func (x *MediaDevices) ProtoReflect() protoreflect.Message {
+	mi := &file_fingerprint_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MediaDevices.ProtoReflect.Descriptor instead.
+func (*MediaDevices) Descriptor() ([]byte, []int) {
+	return file_fingerprint_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *MediaDevices) GetAudioInput() int32 {
+	if x != nil {
+		return x.AudioInput
+	}
+	return 0
+}
+
+func (x *MediaDevices) GetAudioOutput() int32 {
+	if x != nil {
+		return x.AudioOutput
+	}
+	return 0
+}
+
+func (x *MediaDevices) GetVideoInput() int32 {
+	if x != nil {
+		return x.VideoInput
+	}
+	return 0
+}
+
+// Battery Battery Status API 采集结果
+type Battery struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Supported bool    `protobuf:"varint,1,opt,name=supported,proto3" json:"supported,omitempty"`
+	Charging  bool    `protobuf:"varint,2,opt,name=charging,proto3" json:"charging,omitempty"`
+	Level     float64 `protobuf:"fixed64,3,opt,name=level,proto3" json:"level,omitempty"`
+}
+
+func (x *Battery) Reset() {
+	*x = Battery{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_fingerprint_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Battery) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Battery) ProtoMessage() {}
+
+func (x *Battery) ProtoReflect() protoreflect.Message {
+	mi := &file_fingerprint_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Battery.ProtoReflect.Descriptor instead.
+func (*Battery) Descriptor() ([]byte, []int) {
+	return file_fingerprint_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Battery) GetSupported() bool {
+	if x != nil {
+		return x.Supported
+	}
+	return false
+}
+
+func (x *Battery) GetCharging() bool {
+	if x != nil {
+		return x.Charging
+	}
+	return false
+}
+
+func (x *Battery) GetLevel() float64 {
+	if x != nil {
+		return x.Level
+	}
+	return 0
+}
+
+// Sensors 运动传感器可用性
+type Sensors struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Accelerometer bool `protobuf:"varint,1,opt,name=accelerometer,proto3" json:"accelerometer,omitempty"`
+	Gyroscope     bool `protobuf:"varint,2,opt,name=gyroscope,proto3" json:"gyroscope,omitempty"`
+}
+
+func (x *Sensors) Reset() {
+	*x = Sensors{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_fingerprint_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Sensors) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Sensors) ProtoMessage() {}
+
+func (x *Sensors) ProtoReflect() protoreflect.Message {
+	mi := &file_fingerprint_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Sensors.ProtoReflect.Descriptor instead.
+func (*Sensors) Descriptor() ([]byte, []int) {
+	return file_fingerprint_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Sensors) GetAccelerometer() bool {
+	if x != nil {
+		return x.Accelerometer
+	}
+	return false
+}
+
+func (x *Sensors) GetGyroscope() bool {
+	if x != nil {
+		return x.Gyroscope
+	}
+	return false
+}
+
+// ScreenMetrics 扩展屏幕参数
+type ScreenMetrics struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ColorDepth       int32   `protobuf:"varint,1,opt,name=color_depth,json=colorDepth,proto3" json:"color_depth,omitempty"`
+	DevicePixelRatio float64 `protobuf:"fixed64,2,opt,name=device_pixel_ratio,json=devicePixelRatio,proto3" json:"device_pixel_ratio,omitempty"`
+	AvailWidth       int32   `protobuf:"varint,3,opt,name=avail_width,json=availWidth,proto3" json:"avail_width,omitempty"`
+	AvailHeight      int32   `protobuf:"varint,4,opt,name=avail_height,json=availHeight,proto3" json:"avail_height,omitempty"`
+	OuterWidth       int32   `protobuf:"varint,5,opt,name=outer_width,json=outerWidth,proto3" json:"outer_width,omitempty"`
+	OuterHeight      int32   `protobuf:"varint,6,opt,name=outer_height,json=outerHeight,proto3" json:"outer_height,omitempty"`
+}
+
+func (x *ScreenMetrics) Reset() {
+	*x = ScreenMetrics{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_fingerprint_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ScreenMetrics) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScreenMetrics) ProtoMessage() {}
+
+func (x *ScreenMetrics) ProtoReflect() protoreflect.Message {
+	mi := &file_fingerprint_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScreenMetrics.ProtoReflect.Descriptor instead.
+func (*ScreenMetrics) Descriptor() ([]byte, []int) {
+	return file_fingerprint_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ScreenMetrics) GetColorDepth() int32 {
+	if x != nil {
+		return x.ColorDepth
+	}
+	return 0
+}
+
+func (x *ScreenMetrics) GetDevicePixelRatio() float64 {
+	if x != nil {
+		return x.DevicePixelRatio
+	}
+	return 0
+}
+
+func (x *ScreenMetrics) GetAvailWidth() int32 {
+	if x != nil {
+		return x.AvailWidth
+	}
+	return 0
+}
+
+func (x *ScreenMetrics) GetAvailHeight() int32 {
+	if x != nil {
+		return x.AvailHeight
+	}
+	return 0
+}
+
+func (x *ScreenMetrics) GetOuterWidth() int32 {
+	if x != nil {
+		return x.OuterWidth
+	}
+	return 0
+}
+
+func (x *ScreenMetrics) GetOuterHeight() int32 {
+	if x != nil {
+		return x.OuterHeight
+	}
+	return 0
+}
+
+// FeatureProbes 特性探测位图，第 i 位对应第 i 项探测
+type FeatureProbes struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Bits  uint64 `protobuf:"varint,1,opt,name=bits,proto3" json:"bits,omitempty"`
+	Count int32  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+}
+
+func (x *FeatureProbes) Reset() {
+	*x = FeatureProbes{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_fingerprint_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FeatureProbes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FeatureProbes) ProtoMessage() {}
+
+func (x *FeatureProbes) ProtoReflect() protoreflect.Message {
+	mi := &file_fingerprint_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FeatureProbes.ProtoReflect.Descriptor instead.
+func (*FeatureProbes) Descriptor() ([]byte, []int) {
+	return file_fingerprint_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *FeatureProbes) GetBits() uint64 {
+	if x != nil {
+		return x.Bits
+	}
+	return 0
+}
+
+func (x *FeatureProbes) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+// FontMetric 探测字符串在某个字体族下的渲染尺寸
+type FontMetric struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Font   string  `protobuf:"bytes,1,opt,name=font,proto3" json:"font,omitempty"`
+	Width  float64 `protobuf:"fixed64,2,opt,name=width,proto3" json:"width,omitempty"`
+	Height float64 `protobuf:"fixed64,3,opt,name=height,proto3" json:"height,omitempty"`
+}
+
+func (x *FontMetric) Reset() {
+	*x = FontMetric{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_fingerprint_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FontMetric) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FontMetric) ProtoMessage() {}
+
+func (x *FontMetric) ProtoReflect() protoreflect.Message {
+	mi := &file_fingerprint_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FontMetric.ProtoReflect.Descriptor instead.
+func (*FontMetric) Descriptor() ([]byte, []int) {
+	return file_fingerprint_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *FontMetric) GetFont() string {
+	if x != nil {
+		return x.Font
+	}
+	return ""
+}
+
+func (x *FontMetric) GetWidth() float64 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *FontMetric) GetHeight() float64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+// ContentBlocking 广告拦截诱饵元素的检测结果
+type ContentBlocking struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Baits   int32 `protobuf:"varint,1,opt,name=baits,proto3" json:"baits,omitempty"`
+	Blocked int32 `protobuf:"varint,2,opt,name=blocked,proto3" json:"blocked,omitempty"`
+}
+
+func (x *ContentBlocking) Reset() {
+	*x = ContentBlocking{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_fingerprint_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ContentBlocking) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContentBlocking) ProtoMessage() {}
+
+func (x *ContentBlocking) ProtoReflect() protoreflect.Message {
+	mi := &file_fingerprint_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContentBlocking.ProtoReflect.Descriptor instead.
+func (*ContentBlocking) Descriptor() ([]byte, []int) {
+	return file_fingerprint_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ContentBlocking) GetBaits() int32 {
+	if x != nil {
+		return x.Baits
+	}
+	return 0
+}
+
+func (x *ContentBlocking) GetBlocked() int32 {
+	if x != nil {
+		return x.Blocked
+	}
+	return 0
+}
+
+// NetworkTiming 采集端测得的网络时延
+type NetworkTiming struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// 多次请求 /api/health 的往返时延中位数（毫秒）
+	PingMs float64 `protobuf:"fixed64,1,opt,name=ping_ms,json=pingMs,proto3" json:"ping_ms,omitempty"`
+}
+
+func (x *NetworkTiming) Reset() {
+	*x = NetworkTiming{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_fingerprint_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *NetworkTiming) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NetworkTiming) ProtoMessage() {}
+
+func (x *NetworkTiming) ProtoReflect() protoreflect.Message {
+	mi := &file_fingerprint_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NetworkTiming.ProtoReflect.Descriptor instead.
+func (*NetworkTiming) Descriptor() ([]byte, []int) {
+	return file_fingerprint_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *NetworkTiming) GetPingMs() float64 {
+	if x != nil {
+		return x.PingMs
+	}
+	return 0
+}
+
+// NavigationContext 采集页面的导航上下文
+type NavigationContext struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// document.referrer，直接访问时为空
+	Referrer string `protobuf:"bytes,1,opt,name=referrer,proto3" json:"referrer,omitempty"`
+	// location.pathname
+	Page string `protobuf:"bytes,2,opt,name=page,proto3" json:"page,omitempty"`
+}
+
+func (x *NavigationContext) Reset() {
+	*x = NavigationContext{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_fingerprint_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *NavigationContext) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NavigationContext) ProtoMessage() {}
+
+func (x *NavigationContext) ProtoReflect() protoreflect.Message {
+	mi := &file_fingerprint_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NavigationContext.ProtoReflect.Descriptor instead.
+func (*NavigationContext) Descriptor() ([]byte, []int) {
+	return file_fingerprint_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *NavigationContext) GetReferrer() string {
+	if x != nil {
+		return x.Referrer
+	}
+	return ""
+}
+
+func (x *NavigationContext) GetPage() string {
+	if x != nil {
+		return x.Page
+	}
+	return ""
+}
+
+// ExecutionProof 采集脚本按服务端下发的种子计算的执行证明
+type ExecutionProof struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// GET /api/proof/seed 返回的种子
+	Seed string `protobuf:"bytes,1,opt,name=seed,proto3" json:"seed,omitempty"`
+	// 脚本由种子计算的证明值（64个十六进制字符）
+	Value string `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (x *ExecutionProof) Reset() {
+	*x = ExecutionProof{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_fingerprint_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ExecutionProof) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecutionProof) ProtoMessage() {}
+
+func (x *ExecutionProof) ProtoReflect() protoreflect.Message {
+	mi := &file_fingerprint_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecutionProof.ProtoReflect.Descriptor instead.
+func (*ExecutionProof) Descriptor() ([]byte, []int) {
+	return file_fingerprint_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ExecutionProof) GetSeed() string {
+	if x != nil {
+		return x.Seed
+	}
+	return ""
+}
+
+func (x *ExecutionProof) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+// CollectionTiming 采集端时钟记录的采集开始和结束时间（Unix毫秒）
+type CollectionTiming struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	StartedAt  int64 `protobuf:"varint,1,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	FinishedAt int64 `protobuf:"varint,2,opt,name=finished_at,json=finishedAt,proto3" json:"finished_at,omitempty"`
+}
+
+func (x *CollectionTiming) Reset() {
+	*x = CollectionTiming{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_fingerprint_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CollectionTiming) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CollectionTiming) ProtoMessage() {}
+
+func (x *CollectionTiming) ProtoReflect() protoreflect.Message {
+	mi := &file_fingerprint_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CollectionTiming.ProtoReflect.Descriptor instead.
+func (*CollectionTiming) Descriptor() ([]byte, []int) {
+	return file_fingerprint_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *CollectionTiming) GetStartedAt() int64 {
+	if x != nil {
+		return x.StartedAt
+	}
+	return 0
+}
+
+func (x *CollectionTiming) GetFinishedAt() int64 {
+	if x != nil {
+		return x.FinishedAt
+	}
+	return 0
+}
+
+// InputSummary 触摸和运动传感器读数的汇总
+type InputSummary struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Touches          int32   `protobuf:"varint,1,opt,name=touches,proto3" json:"touches,omitempty"`
+	PressureVariance float64 `protobuf:"fixed64,2,opt,name=pressure_variance,json=pressureVariance,proto3" json:"pressure_variance,omitempty"`
+	SizeVariance     float64 `protobuf:"fixed64,3,opt,name=size_variance,json=sizeVariance,proto3" json:"size_variance,omitempty"`
+	MotionSamples    int32   `protobuf:"varint,4,opt,name=motion_samples,json=motionSamples,proto3" json:"motion_samples,omitempty"`
+	// 加速度模长按0.05 m/s²分桶后的香农熵（比特）
+	MotionEntropy float64 `protobuf:"fixed64,5,opt,name=motion_entropy,json=motionEntropy,proto3" json:"motion_entropy,omitempty"`
+}
+
+func (x *InputSummary) Reset() {
+	*x = InputSummary{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_fingerprint_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *InputSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InputSummary) ProtoMessage() {}
+
+func (x *InputSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_fingerprint_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InputSummary.ProtoReflect.Descriptor instead.
+func (*InputSummary) Descriptor() ([]byte, []int) {
+	return file_fingerprint_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *InputSummary) GetTouches() int32 {
+	if x != nil {
+		return x.Touches
+	}
+	return 0
+}
+
+func (x *InputSummary) GetPressureVariance() float64 {
+	if x != nil {
+		return x.PressureVariance
+	}
+	return 0
+}
+
+func (x *InputSummary) GetSizeVariance() float64 {
+	if x != nil {
+		return x.SizeVariance
+	}
+	return 0
+}
+
+func (x *InputSummary) GetMotionSamples() int32 {
+	if x != nil {
+		return x.MotionSamples
+	}
+	return 0
+}
+
+func (x *InputSummary) GetMotionEntropy() float64 {
+	if x != nil {
+		return x.MotionEntropy
+	}
+	return 0
+}
+
+// AudioRun 一次压缩器渲染的原始采样
+type AudioRun struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Samples []float64 `protobuf:"fixed64,1,rep,packed,name=samples,proto3" json:"samples,omitempty"`
+}
+
+func (x *AudioRun) Reset() {
+	*x = AudioRun{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_fingerprint_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AudioRun) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AudioRun) ProtoMessage() {}
+
+func (x *AudioRun) ProtoReflect() protoreflect.Message {
+	mi := &file_fingerprint_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AudioRun.ProtoReflect.Descriptor instead.
+func (*AudioRun) Descriptor() ([]byte, []int) {
+	return file_fingerprint_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *AudioRun) GetSamples() []float64 {
+	if x != nil {
+		return x.Samples
+	}
+	return nil
+}
+
+// CanvasCheck 双Canvas渲染结果，均为 toDataURL 的SHA-256
+type CanvasCheck struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// 固定图案的哈希
+	Static string `protobuf:"bytes,1,opt,name=static,proto3" json:"static,omitempty"`
+	// 绘制种子图案使用的种子，即执行证明的种子
+	Seed string `protobuf:"bytes,2,opt,name=seed,proto3" json:"seed,omitempty"`
+	// 按种子绘制的图案的哈希
+	Seeded string `protobuf:"bytes,3,opt,name=seeded,proto3" json:"seeded,omitempty"`
+}
+
+func (x *CanvasCheck) Reset() {
+	*x = CanvasCheck{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_fingerprint_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CanvasCheck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CanvasCheck) ProtoMessage() {}
+
+func (x *CanvasCheck) ProtoReflect() protoreflect.Message {
+	mi := &file_fingerprint_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CanvasCheck.ProtoReflect.Descriptor instead.
+func (*CanvasCheck) Descriptor() ([]byte, []int) {
+	return file_fingerprint_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *CanvasCheck) GetStatic() string {
+	if x != nil {
+		return x.Static
+	}
+	return ""
+}
+
+func (x *CanvasCheck) GetSeed() string {
+	if x != nil {
+		return x.Seed
+	}
+	return ""
+}
+
+func (x *CanvasCheck) GetSeeded() string {
+	if x != nil {
+		return x.Seeded
+	}
+	return ""
+}
+
+// ShaderPrecision getShaderPrecisionFormat 的结果
+type ShaderPrecision struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	RangeMin  int32 `protobuf:"varint,1,opt,name=range_min,json=rangeMin,proto3" json:"range_min,omitempty"`
+	RangeMax  int32 `protobuf:"varint,2,opt,name=range_max,json=rangeMax,proto3" json:"range_max,omitempty"`
+	Precision int32 `protobuf:"varint,3,opt,name=precision,proto3" json:"precision,omitempty"`
+}
+
+func (x *ShaderPrecision) Reset() {
+	*x = ShaderPrecision{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_fingerprint_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ShaderPrecision) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShaderPrecision) ProtoMessage() {}
+
+func (x *ShaderPrecision) ProtoReflect() protoreflect.Message {
+	mi := &file_fingerprint_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShaderPrecision.ProtoReflect.Descriptor instead.
+func (*ShaderPrecision) Descriptor() ([]byte, []int) {
+	return file_fingerprint_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ShaderPrecision) GetRangeMin() int32 {
+	if x != nil {
+		return x.RangeMin
+	}
+	return 0
+}
+
+func (x *ShaderPrecision) GetRangeMax() int32 {
+	if x != nil {
+		return x.RangeMax
+	}
+	return 0
+}
+
+func (x *ShaderPrecision) GetPrecision() int32 {
+	if x != nil {
+		return x.Precision
+	}
+	return 0
+}
+
+// WebGLParams WebGL的参数、扩展列表和着色器精度格式
+type WebGLParams struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// 整数参数，键为WebGL常量名（如 MAX_TEXTURE_SIZE）
+	Params map[string]int32 `protobuf:"bytes,1,rep,name=params,proto3" json:"params,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	// getSupportedExtensions 的结果
+	Extensions []string `protobuf:"bytes,2,rep,name=extensions,proto3" json:"extensions,omitempty"`
+	// 着色器精度格式，键为 着色器.精度（如 FRAGMENT_SHADER.HIGH_FLOAT）
+	Precisions map[string]*ShaderPrecision `protobuf:"bytes,3,rep,name=precisions,proto3" json:"precisions,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (x *WebGLParams) Reset() {
+	*x = WebGLParams{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_fingerprint_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WebGLParams) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WebGLParams) ProtoMessage() {}
+
+func (x *WebGLParams) ProtoReflect() protoreflect.Message {
+	mi := &file_fingerprint_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WebGLParams.ProtoReflect.Descriptor instead.
+func (*WebGLParams) Descriptor() ([]byte, []int) {
+	return file_fingerprint_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *WebGLParams) GetParams() map[string]int32 {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+func (x *WebGLParams) GetExtensions() []string {
+	if x != nil {
+		return x.Extensions
+	}
+	return nil
+}
+
+func (x *WebGLParams) GetPrecisions() map[string]*ShaderPrecision {
+	if x != nil {
+		return x.Precisions
+	}
+	return nil
+}
+
+// FingerprintRequest 前端提交的指纹数据
+type FingerprintRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	FingerprintHash      string          `protobuf:"bytes,1,opt,name=fingerprint_hash,json=fingerprintHash,proto3" json:"fingerprint_hash,omitempty"`
+	UserAgent            string          `protobuf:"bytes,2,opt,name=user_agent,json=userAgent,proto3" json:"user_agent,omitempty"`
+	ScreenResolution     string          `protobuf:"bytes,3,opt,name=screen_resolution,json=screenResolution,proto3" json:"screen_resolution,omitempty"`
+	Timezone             string          `protobuf:"bytes,4,opt,name=timezone,proto3" json:"timezone,omitempty"`
+	Language             string          `protobuf:"bytes,5,opt,name=language,proto3" json:"language,omitempty"`
+	Platform             string          `protobuf:"bytes,6,opt,name=platform,proto3" json:"platform,omitempty"`
+	Canvas               string          `protobuf:"bytes,7,opt,name=canvas,proto3" json:"canvas,omitempty"`
+	Webgl                string          `protobuf:"bytes,8,opt,name=webgl,proto3" json:"webgl,omitempty"`
+	Audio                string          `protobuf:"bytes,9,opt,name=audio,proto3" json:"audio,omitempty"`
+	Fonts                []string        `protobuf:"bytes,10,rep,name=fonts,proto3" json:"fonts,omitempty"`
+	Plugins              []string        `protobuf:"bytes,11,rep,name=plugins,proto3" json:"plugins,omitempty"`
+	TouchSupport         bool            `protobuf:"varint,12,opt,name=touch_support,json=touchSupport,proto3" json:"touch_support,omitempty"`
+	CookieEnabled        bool            `protobuf:"varint,13,opt,name=cookie_enabled,json=cookieEnabled,proto3" json:"cookie_enabled,omitempty"`
+	DoNotTrack           string          `protobuf:"bytes,14,opt,name=do_not_track,json=doNotTrack,proto3" json:"do_not_track,omitempty"`
+	CanvasNoiseDetection *NoiseDetection `protobuf:"bytes,15,opt,name=canvas_noise_detection,json=canvasNoiseDetection,proto3" json:"canvas_noise_detection,omitempty"`
+	WebglNoiseDetection  *NoiseDetection `protobuf:"bytes,16,opt,name=webgl_noise_detection,json=webglNoiseDetection,proto3" json:"webgl_noise_detection,omitempty"`
+	AudioNoiseDetection  *NoiseDetection `protobuf:"bytes,17,opt,name=audio_noise_detection,json=audioNoiseDetection,proto3" json:"audio_noise_detection,omitempty"`
+	WebrtcLocalIps       []string        `protobuf:"bytes,18,rep,name=webrtc_local_ips,json=webrtcLocalIps,proto3" json:"webrtc_local_ips,omitempty"`
+	WebrtcPublicIps      []string        `protobuf:"bytes,19,rep,name=webrtc_public_ips,json=webrtcPublicIps,proto3" json:"webrtc_public_ips,omitempty"`
+	HardwareConcurrency  int32           `protobuf:"varint,20,opt,name=hardware_concurrency,json=hardwareConcurrency,proto3" json:"hardware_concurrency,omitempty"`
+	DeviceMemory         float64         `protobuf:"fixed64,21,opt,name=device_memory,json=deviceMemory,proto3" json:"device_memory,omitempty"`
+	MediaDevices         *MediaDevices   `protobuf:"bytes,22,opt,name=media_devices,json=mediaDevices,proto3" json:"media_devices,omitempty"`
+	Battery              *Battery        `protobuf:"bytes,23,opt,name=battery,proto3" json:"battery,omitempty"`
+	Sensors              *Sensors        `protobuf:"bytes,24,opt,name=sensors,proto3" json:"sensors,omitempty"`
+	Screen               *ScreenMetrics  `protobuf:"bytes,25,opt,name=screen,proto3" json:"screen,omitempty"`
+	// Math函数边界值与数字格式化结果（String(x)），顺序固定
+	Math            []string         `protobuf:"bytes,26,rep,name=math,proto3" json:"math,omitempty"`
+	Features        *FeatureProbes   `protobuf:"bytes,27,opt,name=features,proto3" json:"features,omitempty"`
+	FontMetrics     []*FontMetric    `protobuf:"bytes,28,rep,name=font_metrics,json=fontMetrics,proto3" json:"font_metrics,omitempty"`
+	ContentBlocking *ContentBlocking `protobuf:"bytes,29,opt,name=content_blocking,json=contentBlocking,proto3" json:"content_blocking,omitempty"`
+	Extensions      []string         `protobuf:"bytes,30,rep,name=extensions,proto3" json:"extensions,omitempty"`
+	Brave           bool             `protobuf:"varint,31,opt,name=brave,proto3" json:"brave,omitempty"`
+	AudioSamples    []*AudioRun      `protobuf:"bytes,32,rep,name=audio_samples,json=audioSamples,proto3" json:"audio_samples,omitempty"`
+	// 采集端的指纹结构版本，未提交时按已提交的信号推断
+	FingerprintVersion int32              `protobuf:"varint,33,opt,name=fingerprint_version,json=fingerprintVersion,proto3" json:"fingerprint_version,omitempty"`
+	Network            *NetworkTiming     `protobuf:"bytes,34,opt,name=network,proto3" json:"network,omitempty"`
+	Navigation         *NavigationContext `protobuf:"bytes,35,opt,name=navigation,proto3" json:"navigation,omitempty"`
+	Proof              *ExecutionProof    `protobuf:"bytes,36,opt,name=proof,proto3" json:"proof,omitempty"`
+	Timing             *CollectionTiming  `protobuf:"bytes,37,opt,name=timing,proto3" json:"timing,omitempty"`
+	Interaction        *InputSummary      `protobuf:"bytes,38,opt,name=interaction,proto3" json:"interaction,omitempty"`
+	CanvasCheck        *CanvasCheck       `protobuf:"bytes,39,opt,name=canvas_check,json=canvasCheck,proto3" json:"canvas_check,omitempty"`
+	WebglParams        *WebGLParams       `protobuf:"bytes,40,opt,name=webgl_params,json=webglParams,proto3" json:"webgl_params,omitempty"`
+}
+
+func (x *FingerprintRequest) Reset() {
+	*x = FingerprintRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_fingerprint_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FingerprintRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FingerprintRequest) ProtoMessage() {}
+
+func (x *FingerprintRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_fingerprint_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FingerprintRequest.ProtoReflect.Descriptor instead.
+func (*FingerprintRequest) Descriptor() ([]byte, []int) {
+	return file_fingerprint_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *FingerprintRequest) GetFingerprintHash() string {
+	if x != nil {
+		return x.FingerprintHash
+	}
+	return ""
+}
+
+func (x *FingerprintRequest) GetUserAgent() string {
+	if x != nil {
+		return x.UserAgent
+	}
+	return ""
+}
+
+func (x *FingerprintRequest) GetScreenResolution() string {
+	if x != nil {
+		return x.ScreenResolution
+	}
+	return ""
+}
+
+func (x *FingerprintRequest) GetTimezone() string {
+	if x != nil {
+		return x.Timezone
+	}
+	return ""
+}
+
+func (x *FingerprintRequest) GetLanguage() string {
+	if x != nil {
+		return x.Language
+	}
+	return ""
+}
+
+func (x *FingerprintRequest) GetPlatform() string {
+	if x != nil {
+		return x.Platform
+	}
+	return ""
+}
+
+func (x *FingerprintRequest) GetCanvas() string {
+	if x != nil {
+		return x.Canvas
+	}
+	return ""
+}
+
+func (x *FingerprintRequest) GetWebgl() string {
+	if x != nil {
+		return x.Webgl
+	}
+	return ""
+}
+
+func (x *FingerprintRequest) GetAudio() string {
+	if x != nil {
+		return x.Audio
+	}
+	return ""
+}
+
+func (x *FingerprintRequest) GetFonts() []string {
+	if x != nil {
+		return x.Fonts
+	}
+	return nil
+}
+
+func (x *FingerprintRequest) GetPlugins() []string {
+	if x != nil {
+		return x.Plugins
+	}
+	return nil
+}
+
+func (x *FingerprintRequest) GetTouchSupport() bool {
+	if x != nil {
+		return x.TouchSupport
+	}
+	return false
+}
+
+func (x *FingerprintRequest) GetCookieEnabled() bool {
+	if x != nil {
+		return x.CookieEnabled
+	}
+	return false
+}
+
+func (x *FingerprintRequest) GetDoNotTrack() string {
+	if x != nil {
+		return x.DoNotTrack
+	}
+	return ""
+}
+
+func (x *FingerprintRequest) GetCanvasNoiseDetection() *NoiseDetection {
+	if x != nil {
+		return x.CanvasNoiseDetection
+	}
+	return nil
+}
+
+func (x *FingerprintRequest) GetWebglNoiseDetection() *NoiseDetection {
+	if x != nil {
+		return x.WebglNoiseDetection
+	}
+	return nil
+}
+
+func (x *FingerprintRequest) GetAudioNoiseDetection() *NoiseDetection {
+	if x != nil {
+		return x.AudioNoiseDetection
+	}
+	return nil
+}
+
+func (x *FingerprintRequest) GetWebrtcLocalIps() []string {
+	if x != nil {
+		return x.WebrtcLocalIps
+	}
+	return nil
+}
+
+func (x *FingerprintRequest) GetWebrtcPublicIps() []string {
+	if x != nil {
+		return x.WebrtcPublicIps
+	}
+	return nil
+}
+
+func (x *FingerprintRequest) GetHardwareConcurrency() int32 {
+	if x != nil {
+		return x.HardwareConcurrency
+	}
+	return 0
+}
+
+func (x *FingerprintRequest) GetDeviceMemory() float64 {
+	if x != nil {
+		return x.DeviceMemory
+	}
+	return 0
+}
+
+func (x *FingerprintRequest) GetMediaDevices() *MediaDevices {
+	if x != nil {
+		return x.MediaDevices
+	}
+	return nil
+}
+
+func (x *FingerprintRequest) GetBattery() *Battery {
+	if x != nil {
+		return x.Battery
+	}
+	return nil
+}
+
+func (x *FingerprintRequest) GetSensors() *Sensors {
+	if x != nil {
+		return x.Sensors
+	}
+	return nil
+}
+
+func (x *FingerprintRequest) GetScreen() *ScreenMetrics {
+	if x != nil {
+		return x.Screen
+	}
+	return nil
+}
+
+func (x *FingerprintRequest) GetMath() []string {
+	if x != nil {
+		return x.Math
+	}
+	return nil
+}
+
+func (x *FingerprintRequest) GetFeatures() *FeatureProbes {
+	if x != nil {
+		return x.Features
+	}
+	return nil
+}
+
+func (x *FingerprintRequest) GetFontMetrics() []*FontMetric {
+	if x != nil {
+		return x.FontMetrics
+	}
+	return nil
+}
+
+func (x *FingerprintRequest) GetContentBlocking() *ContentBlocking {
+	if x != nil {
+		return x.ContentBlocking
+	}
+	return nil
+}
+
+func (x *FingerprintRequest) GetExtensions() []string {
+	if x != nil {
+		return x.Extensions
+	}
+	return nil
+}
+
+func (x *FingerprintRequest) GetBrave() bool {
+	if x != nil {
+		return x.Brave
+	}
+	return false
+}
+
+func (x *FingerprintRequest) GetAudioSamples() []*AudioRun {
+	if x != nil {
+		return x.AudioSamples
+	}
+	return nil
+}
+
+func (x *FingerprintRequest) GetFingerprintVersion() int32 {
+	if x != nil {
+		return x.FingerprintVersion
+	}
+	return 0
+}
+
+func (x *FingerprintRequest) GetNetwork() *NetworkTiming {
+	if x != nil {
+		return x.Network
+	}
+	return nil
+}
+
+func (x *FingerprintRequest) GetNavigation() *NavigationContext {
+	if x != nil {
+		return x.Navigation
+	}
+	return nil
+}
+
+func (x *FingerprintRequest) GetProof() *ExecutionProof {
+	if x != nil {
+		return x.Proof
+	}
+	return nil
+}
+
+func (x *FingerprintRequest) GetTiming() *CollectionTiming {
+	if x != nil {
+		return x.Timing
+	}
+	return nil
+}
+
+func (x *FingerprintRequest) GetInteraction() *InputSummary {
+	if x != nil {
+		return x.Interaction
+	}
+	return nil
+}
+
+func (x *FingerprintRequest) GetCanvasCheck() *CanvasCheck {
+	if x != nil {
+		return x.CanvasCheck
+	}
+	return nil
+}
+
+func (x *FingerprintRequest) GetWebglParams() *WebGLParams {
+	if x != nil {
+		return x.WebglParams
+	}
+	return nil
+}
+
+var File_fingerprint_proto protoreflect.FileDescriptor
+
+var file_fingerprint_proto_rawDesc = []byte{
+	0x0a, 0x11, 0x66, 0x69, 0x6e, 0x67, 0x65, 0x72, 0x70, 0x72, 0x69, 0x6e, 0x74, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x12, 0x13, 0x62, 0x72, 0x6f, 0x77, 0x73, 0x65, 0x72, 0x64, 0x65, 0x74, 0x65,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x76, 0x31, 0x22, 0x7b, 0x0a, 0x0e, 0x4e, 0x6f, 0x69, 0x73,
+	0x65, 0x44, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1b, 0x0a, 0x09, 0x68, 0x61,
+	0x73, 0x5f, 0x6e, 0x6f, 0x69, 0x73, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x68,
+	0x61, 0x73, 0x4e, 0x6f, 0x69, 0x73, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x63,
+	0x6f, 0x6e, 0x66, 0x69, 0x64, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52,
+	0x0a, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x64, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x64,
+	0x65, 0x74, 0x61, 0x69, 0x6c, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x64, 0x65,
+	0x74, 0x61, 0x69, 0x6c, 0x73, 0x22, 0x73, 0x0a, 0x0c, 0x4d, 0x65, 0x64, 0x69, 0x61, 0x44, 0x65,
+	0x76, 0x69, 0x63, 0x65, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x61, 0x75, 0x64, 0x69, 0x6f, 0x5f, 0x69,
+	0x6e, 0x70, 0x75, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x61, 0x75, 0x64, 0x69,
+	0x6f, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x12, 0x21, 0x0a, 0x0c, 0x61, 0x75, 0x64, 0x69, 0x6f, 0x5f,
+	0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0b, 0x61, 0x75,
+	0x64, 0x69, 0x6f, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x12, 0x1f, 0x0a, 0x0b, 0x76, 0x69, 0x64,
+	0x65, 0x6f, 0x5f, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a,
+	0x76, 0x69, 0x64, 0x65, 0x6f, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x22, 0x59, 0x0a, 0x07, 0x42, 0x61,
+	0x74, 0x74, 0x65, 0x72, 0x79, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x75, 0x70, 0x70, 0x6f, 0x72, 0x74,
+	0x65, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x73, 0x75, 0x70, 0x70, 0x6f, 0x72,
+	0x74, 0x65, 0x64, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x68, 0x61, 0x72, 0x67, 0x69, 0x6e, 0x67, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x63, 0x68, 0x61, 0x72, 0x67, 0x69, 0x6e, 0x67, 0x12,
+	0x14, 0x0a, 0x05, 0x6c, 0x65, 0x76, 0x65, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x05,
+	0x6c, 0x65, 0x76, 0x65, 0x6c, 0x22, 0x4d, 0x0a, 0x07, 0x53, 0x65, 0x6e, 0x73, 0x6f, 0x72, 0x73,
+	0x12, 0x24, 0x0a, 0x0d, 0x61, 0x63, 0x63, 0x65, 0x6c, 0x65, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x65,
+	0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0d, 0x61, 0x63, 0x63, 0x65, 0x6c, 0x65, 0x72,
+	0x6f, 0x6d, 0x65, 0x74, 0x65, 0x72, 0x12, 0x1c, 0x0a, 0x09, 0x67, 0x79, 0x72, 0x6f, 0x73, 0x63,
+	0x6f, 0x70, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x67, 0x79, 0x72, 0x6f, 0x73,
+	0x63, 0x6f, 0x70, 0x65, 0x22, 0xe6, 0x01, 0x0a, 0x0d, 0x53, 0x63, 0x72, 0x65, 0x65, 0x6e, 0x4d,
+	0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x5f,
+	0x64, 0x65, 0x70, 0x74, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x63, 0x6f, 0x6c,
+	0x6f, 0x72, 0x44, 0x65, 0x70, 0x74, 0x68, 0x12, 0x2c, 0x0a, 0x12, 0x64, 0x65, 0x76, 0x69, 0x63,
+	0x65, 0x5f, 0x70, 0x69, 0x78, 0x65, 0x6c, 0x5f, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x01, 0x52, 0x10, 0x64, 0x65, 0x76, 0x69, 0x63, 0x65, 0x50, 0x69, 0x78, 0x65, 0x6c,
+	0x52, 0x61, 0x74, 0x69, 0x6f, 0x12, 0x1f, 0x0a, 0x0b, 0x61, 0x76, 0x61, 0x69, 0x6c, 0x5f, 0x77,
+	0x69, 0x64, 0x74, 0x68, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x61, 0x76, 0x61, 0x69,
+	0x6c, 0x57, 0x69, 0x64, 0x74, 0x68, 0x12, 0x21, 0x0a, 0x0c, 0x61, 0x76, 0x61, 0x69, 0x6c, 0x5f,
+	0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0b, 0x61, 0x76,
+	0x61, 0x69, 0x6c, 0x48, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x1f, 0x0a, 0x0b, 0x6f, 0x75, 0x74,
+	0x65, 0x72, 0x5f, 0x77, 0x69, 0x64, 0x74, 0x68, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a,
+	0x6f, 0x75, 0x74, 0x65, 0x72, 0x57, 0x69, 0x64, 0x74, 0x68, 0x12, 0x21, 0x0a, 0x0c, 0x6f, 0x75,
+	0x74, 0x65, 0x72, 0x5f, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x0b, 0x6f, 0x75, 0x74, 0x65, 0x72, 0x48, 0x65, 0x69, 0x67, 0x68, 0x74, 0x22, 0x39, 0x0a,
+	0x0d, 0x46, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x12, 0x12,
+	0x0a, 0x04, 0x62, 0x69, 0x74, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x04, 0x62, 0x69,
+	0x74, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0x4e, 0x0a, 0x0a, 0x46, 0x6f, 0x6e, 0x74,
+	0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x6f, 0x6e, 0x74, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x66, 0x6f, 0x6e, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x77, 0x69,
+	0x64, 0x74, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x05, 0x77, 0x69, 0x64, 0x74, 0x68,
+	0x12, 0x16, 0x0a, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01,
+	0x52, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x22, 0x41, 0x0a, 0x0f, 0x43, 0x6f, 0x6e, 0x74,
+	0x65, 0x6e, 0x74, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x69, 0x6e, 0x67, 0x12, 0x14, 0x0a, 0x05, 0x62,
+	0x61, 0x69, 0x74, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x62, 0x61, 0x69, 0x74,
+	0x73, 0x12, 0x18, 0x0a, 0x07, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x07, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x65, 0x64, 0x22, 0x28, 0x0a, 0x0d, 0x4e,
+	0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x54, 0x69, 0x6d, 0x69, 0x6e, 0x67, 0x12, 0x17, 0x0a, 0x07,
+	0x70, 0x69, 0x6e, 0x67, 0x5f, 0x6d, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x01, 0x52, 0x06, 0x70,
+	0x69, 0x6e, 0x67, 0x4d, 0x73, 0x22, 0x43, 0x0a, 0x11, 0x4e, 0x61, 0x76, 0x69, 0x67, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x78, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65,
+	0x66, 0x65, 0x72, 0x72, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x72, 0x65,
+	0x66, 0x65, 0x72, 0x72, 0x65, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x61, 0x67, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x70, 0x61, 0x67, 0x65, 0x22, 0x3a, 0x0a, 0x0e, 0x45, 0x78,
+	0x65, 0x63, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x50, 0x72, 0x6f, 0x6f, 0x66, 0x12, 0x12, 0x0a, 0x04,
+	0x73, 0x65, 0x65, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x73, 0x65, 0x65, 0x64,
+	0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0x52, 0x0a, 0x10, 0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x54, 0x69, 0x6d, 0x69, 0x6e, 0x67, 0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x74,
+	0x61, 0x72, 0x74, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09,
+	0x73, 0x74, 0x61, 0x72, 0x74, 0x65, 0x64, 0x41, 0x74, 0x12, 0x1f, 0x0a, 0x0b, 0x66, 0x69, 0x6e,
+	0x69, 0x73, 0x68, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a,
+	0x66, 0x69, 0x6e, 0x69, 0x73, 0x68, 0x65, 0x64, 0x41, 0x74, 0x22, 0xc8, 0x01, 0x0a, 0x0c, 0x49,
+	0x6e, 0x70, 0x75, 0x74, 0x53, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79, 0x12, 0x18, 0x0a, 0x07, 0x74,
+	0x6f, 0x75, 0x63, 0x68, 0x65, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x74, 0x6f,
+	0x75, 0x63, 0x68, 0x65, 0x73, 0x12, 0x2b, 0x0a, 0x11, 0x70, 0x72, 0x65, 0x73, 0x73, 0x75, 0x72,
+	0x65, 0x5f, 0x76, 0x61, 0x72, 0x69, 0x61, 0x6e, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01,
+	0x52, 0x10, 0x70, 0x72, 0x65, 0x73, 0x73, 0x75, 0x72, 0x65, 0x56, 0x61, 0x72, 0x69, 0x61, 0x6e,
+	0x63, 0x65, 0x12, 0x23, 0x0a, 0x0d, 0x73, 0x69, 0x7a, 0x65, 0x5f, 0x76, 0x61, 0x72, 0x69, 0x61,
+	0x6e, 0x63, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0c, 0x73, 0x69, 0x7a, 0x65, 0x56,
+	0x61, 0x72, 0x69, 0x61, 0x6e, 0x63, 0x65, 0x12, 0x25, 0x0a, 0x0e, 0x6d, 0x6f, 0x74, 0x69, 0x6f,
+	0x6e, 0x5f, 0x73, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x0d, 0x6d, 0x6f, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x73, 0x12, 0x25,
+	0x0a, 0x0e, 0x6d, 0x6f, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x65, 0x6e, 0x74, 0x72, 0x6f, 0x70, 0x79,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0d, 0x6d, 0x6f, 0x74, 0x69, 0x6f, 0x6e, 0x45, 0x6e,
+	0x74, 0x72, 0x6f, 0x70, 0x79, 0x22, 0x24, 0x0a, 0x08, 0x41, 0x75, 0x64, 0x69, 0x6f, 0x52, 0x75,
+	0x6e, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x01, 0x52, 0x07, 0x73, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x73, 0x22, 0x51, 0x0a, 0x0b, 0x43,
+	0x61, 0x6e, 0x76, 0x61, 0x73, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x74,
+	0x61, 0x74, 0x69, 0x63, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74,
+	0x69, 0x63, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x65, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x73, 0x65, 0x65, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x65, 0x65, 0x64, 0x65, 0x64,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x65, 0x65, 0x64, 0x65, 0x64, 0x22, 0x69,
+	0x0a, 0x0f, 0x53, 0x68, 0x61, 0x64, 0x65, 0x72, 0x50, 0x72, 0x65, 0x63, 0x69, 0x73, 0x69, 0x6f,
+	0x6e, 0x12, 0x1b, 0x0a, 0x09, 0x72, 0x61, 0x6e, 0x67, 0x65, 0x5f, 0x6d, 0x69, 0x6e, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x72, 0x61, 0x6e, 0x67, 0x65, 0x4d, 0x69, 0x6e, 0x12, 0x1b,
+	0x0a, 0x09, 0x72, 0x61, 0x6e, 0x67, 0x65, 0x5f, 0x6d, 0x61, 0x78, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x08, 0x72, 0x61, 0x6e, 0x67, 0x65, 0x4d, 0x61, 0x78, 0x12, 0x1c, 0x0a, 0x09, 0x70,
+	0x72, 0x65, 0x63, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09,
+	0x70, 0x72, 0x65, 0x63, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0xe5, 0x02, 0x0a, 0x0b, 0x57, 0x65,
+	0x62, 0x47, 0x4c, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x12, 0x44, 0x0a, 0x06, 0x70, 0x61, 0x72,
+	0x61, 0x6d, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x2c, 0x2e, 0x62, 0x72, 0x6f, 0x77,
+	0x73, 0x65, 0x72, 0x64, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x76, 0x31, 0x2e,
+	0x57, 0x65, 0x62, 0x47, 0x4c, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x2e, 0x50, 0x61, 0x72, 0x61,
+	0x6d, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x06, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x12,
+	0x1e, 0x0a, 0x0a, 0x65, 0x78, 0x74, 0x65, 0x6e, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x02, 0x20,
+	0x03, 0x28, 0x09, 0x52, 0x0a, 0x65, 0x78, 0x74, 0x65, 0x6e, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x12,
+	0x50, 0x0a, 0x0a, 0x70, 0x72, 0x65, 0x63, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x03, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x30, 0x2e, 0x62, 0x72, 0x6f, 0x77, 0x73, 0x65, 0x72, 0x64, 0x65, 0x74,
+	0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x57, 0x65, 0x62, 0x47, 0x4c, 0x50,
+	0x61, 0x72, 0x61, 0x6d, 0x73, 0x2e, 0x50, 0x72, 0x65, 0x63, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x73,
+	0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0a, 0x70, 0x72, 0x65, 0x63, 0x69, 0x73, 0x69, 0x6f, 0x6e,
+	0x73, 0x1a, 0x39, 0x0a, 0x0b, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79,
+	0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b,
+	0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x63, 0x0a, 0x0f,
+	0x50, 0x72, 0x65, 0x63, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12,
+	0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65,
+	0x79, 0x12, 0x3a, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x24, 0x2e, 0x62, 0x72, 0x6f, 0x77, 0x73, 0x65, 0x72, 0x64, 0x65, 0x74, 0x65, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x68, 0x61, 0x64, 0x65, 0x72, 0x50, 0x72, 0x65,
+	0x63, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38,
+	0x01, 0x22, 0xd3, 0x0f, 0x0a, 0x12, 0x46, 0x69, 0x6e, 0x67, 0x65, 0x72, 0x70, 0x72, 0x69, 0x6e,
+	0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x29, 0x0a, 0x10, 0x66, 0x69, 0x6e, 0x67,
+	0x65, 0x72, 0x70, 0x72, 0x69, 0x6e, 0x74, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0f, 0x66, 0x69, 0x6e, 0x67, 0x65, 0x72, 0x70, 0x72, 0x69, 0x6e, 0x74, 0x48,
+	0x61, 0x73, 0x68, 0x12, 0x1d, 0x0a, 0x0a, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x61, 0x67, 0x65, 0x6e,
+	0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x75, 0x73, 0x65, 0x72, 0x41, 0x67, 0x65,
+	0x6e, 0x74, 0x12, 0x2b, 0x0a, 0x11, 0x73, 0x63, 0x72, 0x65, 0x65, 0x6e, 0x5f, 0x72, 0x65, 0x73,
+	0x6f, 0x6c, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x10, 0x73,
+	0x63, 0x72, 0x65, 0x65, 0x6e, 0x52, 0x65, 0x73, 0x6f, 0x6c, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x12,
+	0x1a, 0x0a, 0x08, 0x74, 0x69, 0x6d, 0x65, 0x7a, 0x6f, 0x6e, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x08, 0x74, 0x69, 0x6d, 0x65, 0x7a, 0x6f, 0x6e, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x6c,
+	0x61, 0x6e, 0x67, 0x75, 0x61, 0x67, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x6c,
+	0x61, 0x6e, 0x67, 0x75, 0x61, 0x67, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x6c, 0x61, 0x74, 0x66,
+	0x6f, 0x72, 0x6d, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x70, 0x6c, 0x61, 0x74, 0x66,
+	0x6f, 0x72, 0x6d, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x61, 0x6e, 0x76, 0x61, 0x73, 0x18, 0x07, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x06, 0x63, 0x61, 0x6e, 0x76, 0x61, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x77,
+	0x65, 0x62, 0x67, 0x6c, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x77, 0x65, 0x62, 0x67,
+	0x6c, 0x12, 0x14, 0x0a, 0x05, 0x61, 0x75, 0x64, 0x69, 0x6f, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x05, 0x61, 0x75, 0x64, 0x69, 0x6f, 0x12, 0x14, 0x0a, 0x05, 0x66, 0x6f, 0x6e, 0x74, 0x73,
+	0x18, 0x0a, 0x20, 0x03, 0x28, 0x09, 0x52, 0x05, 0x66, 0x6f, 0x6e, 0x74, 0x73, 0x12, 0x18, 0x0a,
+	0x07, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x73, 0x18, 0x0b, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07,
+	0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x73, 0x12, 0x23, 0x0a, 0x0d, 0x74, 0x6f, 0x75, 0x63, 0x68,
+	0x5f, 0x73, 0x75, 0x70, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0c,
+	0x74, 0x6f, 0x75, 0x63, 0x68, 0x53, 0x75, 0x70, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x25, 0x0a, 0x0e,
+	0x63, 0x6f, 0x6f, 0x6b, 0x69, 0x65, 0x5f, 0x65, 0x6e, 0x61, 0x62, 0x6c, 0x65, 0x64, 0x18, 0x0d,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x0d, 0x63, 0x6f, 0x6f, 0x6b, 0x69, 0x65, 0x45, 0x6e, 0x61, 0x62,
+	0x6c, 0x65, 0x64, 0x12, 0x20, 0x0a, 0x0c, 0x64, 0x6f, 0x5f, 0x6e, 0x6f, 0x74, 0x5f, 0x74, 0x72,
+	0x61, 0x63, 0x6b, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x64, 0x6f, 0x4e, 0x6f, 0x74,
+	0x54, 0x72, 0x61, 0x63, 0x6b, 0x12, 0x59, 0x0a, 0x16, 0x63, 0x61, 0x6e, 0x76, 0x61, 0x73, 0x5f,
+	0x6e, 0x6f, 0x69, 0x73, 0x65, 0x5f, 0x64, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18,
+	0x0f, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x23, 0x2e, 0x62, 0x72, 0x6f, 0x77, 0x73, 0x65, 0x72, 0x64,
+	0x65, 0x74, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x4e, 0x6f, 0x69, 0x73,
+	0x65, 0x44, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x14, 0x63, 0x61, 0x6e, 0x76,
+	0x61, 0x73, 0x4e, 0x6f, 0x69, 0x73, 0x65, 0x44, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+	0x12, 0x57, 0x0a, 0x15, 0x77, 0x65, 0x62, 0x67, 0x6c, 0x5f, 0x6e, 0x6f, 0x69, 0x73, 0x65, 0x5f,
+	0x64, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x10, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x23, 0x2e, 0x62, 0x72, 0x6f, 0x77, 0x73, 0x65, 0x72, 0x64, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69,
+	0x6f, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x4e, 0x6f, 0x69, 0x73, 0x65, 0x44, 0x65, 0x74, 0x65, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x52, 0x13, 0x77, 0x65, 0x62, 0x67, 0x6c, 0x4e, 0x6f, 0x69, 0x73, 0x65,
+	0x44, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x57, 0x0a, 0x15, 0x61, 0x75, 0x64,
+	0x69, 0x6f, 0x5f, 0x6e, 0x6f, 0x69, 0x73, 0x65, 0x5f, 0x64, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69,
+	0x6f, 0x6e, 0x18, 0x11, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x23, 0x2e, 0x62, 0x72, 0x6f, 0x77, 0x73,
+	0x65, 0x72, 0x64, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x4e,
+	0x6f, 0x69, 0x73, 0x65, 0x44, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x13, 0x61,
+	0x75, 0x64, 0x69, 0x6f, 0x4e, 0x6f, 0x69, 0x73, 0x65, 0x44, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69,
+	0x6f, 0x6e, 0x12, 0x28, 0x0a, 0x10, 0x77, 0x65, 0x62, 0x72, 0x74, 0x63, 0x5f, 0x6c, 0x6f, 0x63,
+	0x61, 0x6c, 0x5f, 0x69, 0x70, 0x73, 0x18, 0x12, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0e, 0x77, 0x65,
+	0x62, 0x72, 0x74, 0x63, 0x4c, 0x6f, 0x63, 0x61, 0x6c, 0x49, 0x70, 0x73, 0x12, 0x2a, 0x0a, 0x11,
+	0x77, 0x65, 0x62, 0x72, 0x74, 0x63, 0x5f, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x5f, 0x69, 0x70,
+	0x73, 0x18, 0x13, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0f, 0x77, 0x65, 0x62, 0x72, 0x74, 0x63, 0x50,
+	0x75, 0x62, 0x6c, 0x69, 0x63, 0x49, 0x70, 0x73, 0x12, 0x31, 0x0a, 0x14, 0x68, 0x61, 0x72, 0x64,
+	0x77, 0x61, 0x72, 0x65, 0x5f, 0x63, 0x6f, 0x6e, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x63, 0x79,
+	0x18, 0x14, 0x20, 0x01, 0x28, 0x05, 0x52, 0x13, 0x68, 0x61, 0x72, 0x64, 0x77, 0x61, 0x72, 0x65,
+	0x43, 0x6f, 0x6e, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x63, 0x79, 0x12, 0x23, 0x0a, 0x0d, 0x64,
+	0x65, 0x76, 0x69, 0x63, 0x65, 0x5f, 0x6d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x18, 0x15, 0x20, 0x01,
+	0x28, 0x01, 0x52, 0x0c, 0x64, 0x65, 0x76, 0x69, 0x63, 0x65, 0x4d, 0x65, 0x6d, 0x6f, 0x72, 0x79,
+	0x12, 0x46, 0x0a, 0x0d, 0x6d, 0x65, 0x64, 0x69, 0x61, 0x5f, 0x64, 0x65, 0x76, 0x69, 0x63, 0x65,
+	0x73, 0x18, 0x16, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x62, 0x72, 0x6f, 0x77, 0x73, 0x65,
+	0x72, 0x64, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x4d, 0x65,
+	0x64, 0x69, 0x61, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x73, 0x52, 0x0c, 0x6d, 0x65, 0x64, 0x69,
+	0x61, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x73, 0x12, 0x36, 0x0a, 0x07, 0x62, 0x61, 0x74, 0x74,
+	0x65, 0x72, 0x79, 0x18, 0x17, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x62, 0x72, 0x6f, 0x77,
+	0x73, 0x65, 0x72, 0x64, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x76, 0x31, 0x2e,
+	0x42, 0x61, 0x74, 0x74, 0x65, 0x72, 0x79, 0x52, 0x07, 0x62, 0x61, 0x74, 0x74, 0x65, 0x72, 0x79,
+	0x12, 0x36, 0x0a, 0x07, 0x73, 0x65, 0x6e, 0x73, 0x6f, 0x72, 0x73, 0x18, 0x18, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x1c, 0x2e, 0x62, 0x72, 0x6f, 0x77, 0x73, 0x65, 0x72, 0x64, 0x65, 0x74, 0x65, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x65, 0x6e, 0x73, 0x6f, 0x72, 0x73, 0x52,
+	0x07, 0x73, 0x65, 0x6e, 0x73, 0x6f, 0x72, 0x73, 0x12, 0x3a, 0x0a, 0x06, 0x73, 0x63, 0x72, 0x65,
+	0x65, 0x6e, 0x18, 0x19, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x22, 0x2e, 0x62, 0x72, 0x6f, 0x77, 0x73,
+	0x65, 0x72, 0x64, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x53,
+	0x63, 0x72, 0x65, 0x65, 0x6e, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x52, 0x06, 0x73, 0x63,
+	0x72, 0x65, 0x65, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x6d, 0x61, 0x74, 0x68, 0x18, 0x1a, 0x20, 0x03,
+	0x28, 0x09, 0x52, 0x04, 0x6d, 0x61, 0x74, 0x68, 0x12, 0x3e, 0x0a, 0x08, 0x66, 0x65, 0x61, 0x74,
+	0x75, 0x72, 0x65, 0x73, 0x18, 0x1b, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x22, 0x2e, 0x62, 0x72, 0x6f,
+	0x77, 0x73, 0x65, 0x72, 0x64, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x76, 0x31,
+	0x2e, 0x46, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x52, 0x08,
+	0x66, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x73, 0x12, 0x42, 0x0a, 0x0c, 0x66, 0x6f, 0x6e, 0x74,
+	0x5f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x18, 0x1c, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1f,
+	0x2e, 0x62, 0x72, 0x6f, 0x77, 0x73, 0x65, 0x72, 0x64, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69, 0x6f,
+	0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x6f, 0x6e, 0x74, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x52,
+	0x0b, 0x66, 0x6f, 0x6e, 0x74, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x12, 0x4f, 0x0a, 0x10,
+	0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x5f, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x69, 0x6e, 0x67,
+	0x18, 0x1d, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x24, 0x2e, 0x62, 0x72, 0x6f, 0x77, 0x73, 0x65, 0x72,
+	0x64, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x6f, 0x6e,
+	0x74, 0x65, 0x6e, 0x74, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x69, 0x6e, 0x67, 0x52, 0x0f, 0x63, 0x6f,
+	0x6e, 0x74, 0x65, 0x6e, 0x74, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x69, 0x6e, 0x67, 0x12, 0x1e, 0x0a,
+	0x0a, 0x65, 0x78, 0x74, 0x65, 0x6e, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x1e, 0x20, 0x03, 0x28,
+	0x09, 0x52, 0x0a, 0x65, 0x78, 0x74, 0x65, 0x6e, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x14, 0x0a,
+	0x05, 0x62, 0x72, 0x61, 0x76, 0x65, 0x18, 0x1f, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x62, 0x72,
+	0x61, 0x76, 0x65, 0x12, 0x42, 0x0a, 0x0d, 0x61, 0x75, 0x64, 0x69, 0x6f, 0x5f, 0x73, 0x61, 0x6d,
+	0x70, 0x6c, 0x65, 0x73, 0x18, 0x20, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x62, 0x72, 0x6f,
+	0x77, 0x73, 0x65, 0x72, 0x64, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x76, 0x31,
+	0x2e, 0x41, 0x75, 0x64, 0x69, 0x6f, 0x52, 0x75, 0x6e, 0x52, 0x0c, 0x61, 0x75, 0x64, 0x69, 0x6f,
+	0x53, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x73, 0x12, 0x2f, 0x0a, 0x13, 0x66, 0x69, 0x6e, 0x67, 0x65,
+	0x72, 0x70, 0x72, 0x69, 0x6e, 0x74, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x21,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x12, 0x66, 0x69, 0x6e, 0x67, 0x65, 0x72, 0x70, 0x72, 0x69, 0x6e,
+	0x74, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x3c, 0x0a, 0x07, 0x6e, 0x65, 0x74, 0x77,
+	0x6f, 0x72, 0x6b, 0x18, 0x22, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x22, 0x2e, 0x62, 0x72, 0x6f, 0x77,
+	0x73, 0x65, 0x72, 0x64, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x76, 0x31, 0x2e,
+	0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x54, 0x69, 0x6d, 0x69, 0x6e, 0x67, 0x52, 0x07, 0x6e,
+	0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x12, 0x46, 0x0a, 0x0a, 0x6e, 0x61, 0x76, 0x69, 0x67, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x18, 0x23, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x26, 0x2e, 0x62, 0x72, 0x6f,
+	0x77, 0x73, 0x65, 0x72, 0x64, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x76, 0x31,
+	0x2e, 0x4e, 0x61, 0x76, 0x69, 0x67, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x43, 0x6f, 0x6e, 0x74, 0x65,
+	0x78, 0x74, 0x52, 0x0a, 0x6e, 0x61, 0x76, 0x69, 0x67, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x39,
+	0x0a, 0x05, 0x70, 0x72, 0x6f, 0x6f, 0x66, 0x18, 0x24, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x23, 0x2e,
+	0x62, 0x72, 0x6f, 0x77, 0x73, 0x65, 0x72, 0x64, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+	0x2e, 0x76, 0x31, 0x2e, 0x45, 0x78, 0x65, 0x63, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x50, 0x72, 0x6f,
+	0x6f, 0x66, 0x52, 0x05, 0x70, 0x72, 0x6f, 0x6f, 0x66, 0x12, 0x3d, 0x0a, 0x06, 0x74, 0x69, 0x6d,
+	0x69, 0x6e, 0x67, 0x18, 0x25, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x25, 0x2e, 0x62, 0x72, 0x6f, 0x77,
+	0x73, 0x65, 0x72, 0x64, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x76, 0x31, 0x2e,
+	0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x54, 0x69, 0x6d, 0x69, 0x6e, 0x67,
+	0x52, 0x06, 0x74, 0x69, 0x6d, 0x69, 0x6e, 0x67, 0x12, 0x43, 0x0a, 0x0b, 0x69, 0x6e, 0x74, 0x65,
+	0x72, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x26, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x21, 0x2e,
+	0x62, 0x72, 0x6f, 0x77, 0x73, 0x65, 0x72, 0x64, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+	0x2e, 0x76, 0x31, 0x2e, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x53, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79,
+	0x52, 0x0b, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x43, 0x0a,
+	0x0c, 0x63, 0x61, 0x6e, 0x76, 0x61, 0x73, 0x5f, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x18, 0x27, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x62, 0x72, 0x6f, 0x77, 0x73, 0x65, 0x72, 0x64, 0x65, 0x74,
+	0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x61, 0x6e, 0x76, 0x61, 0x73,
+	0x43, 0x68, 0x65, 0x63, 0x6b, 0x52, 0x0b, 0x63, 0x61, 0x6e, 0x76, 0x61, 0x73, 0x43, 0x68, 0x65,
+	0x63, 0x6b, 0x12, 0x43, 0x0a, 0x0c, 0x77, 0x65, 0x62, 0x67, 0x6c, 0x5f, 0x70, 0x61, 0x72, 0x61,
+	0x6d, 0x73, 0x18, 0x28, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x62, 0x72, 0x6f, 0x77, 0x73,
+	0x65, 0x72, 0x64, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x57,
+	0x65, 0x62, 0x47, 0x4c, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x52, 0x0b, 0x77, 0x65, 0x62, 0x67,
+	0x6c, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x42, 0x29, 0x5a, 0x27, 0x62, 0x72, 0x6f, 0x77, 0x73,
+	0x65, 0x72, 0x2d, 0x64, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x2f, 0x69, 0x6e, 0x74,
+	0x65, 0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62,
+	0x75, 0x66, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_fingerprint_proto_rawDescOnce sync.Once
+	file_fingerprint_proto_rawDescData = file_fingerprint_proto_rawDesc
+)
+
+func file_fingerprint_proto_rawDescGZIP() []byte {
+	file_fingerprint_proto_rawDescOnce.Do(func() {
+		file_fingerprint_proto_rawDescData = protoimpl.X.CompressGZIP(file_fingerprint_proto_rawDescData)
+	})
+	return file_fingerprint_proto_rawDescData
+}
+
+var file_fingerprint_proto_msgTypes = make([]protoimpl.MessageInfo, 20)
+var file_fingerprint_proto_goTypes = []interface{}{
+	(*NoiseDetection)(nil),     // 0: browserdetection.v1.NoiseDetection
+	(*MediaDevices)(nil),       // 1: browserdetection.v1.MediaDevices
+	(*Battery)(nil),            // 2: browserdetection.v1.Battery
+	(*Sensors)(nil),            // 3: browserdetection.v1.Sensors
+	(*ScreenMetrics)(nil),      // 4: browserdetection.v1.ScreenMetrics
+	(*FeatureProbes)(nil),      // 5: browserdetection.v1.FeatureProbes
+	(*FontMetric)(nil),         // 6: browserdetection.v1.FontMetric
+	(*ContentBlocking)(nil),    // 7: browserdetection.v1.ContentBlocking
+	(*NetworkTiming)(nil),      // 8: browserdetection.v1.NetworkTiming
+	(*NavigationContext)(nil),  // 9: browserdetection.v1.NavigationContext
+	(*ExecutionProof)(nil),     // 10: browserdetection.v1.ExecutionProof
+	(*CollectionTiming)(nil),   // 11: browserdetection.v1.CollectionTiming
+	(*InputSummary)(nil),       // 12: browserdetection.v1.InputSummary
+	(*AudioRun)(nil),           // 13: browserdetection.v1.AudioRun
+	(*CanvasCheck)(nil),        // 14: browserdetection.v1.CanvasCheck
+	(*ShaderPrecision)(nil),    // 15: browserdetection.v1.ShaderPrecision
+	(*WebGLParams)(nil),        // 16: browserdetection.v1.WebGLParams
+	(*FingerprintRequest)(nil), // 17: browserdetection.v1.FingerprintRequest
+	nil,                        // 18: browserdetection.v1.WebGLParams.ParamsEntry
+	nil,                        // 19: browserdetection.v1.WebGLParams.PrecisionsEntry
+}
+var file_fingerprint_proto_depIdxs = []int32{
+	18, // 0: browserdetection.v1.WebGLParams.params:type_name -> browserdetection.v1.WebGLParams.ParamsEntry
+	19, // 1: browserdetection.v1.WebGLParams.precisions:type_name -> browserdetection.v1.WebGLParams.PrecisionsEntry
+	0,  // 2: browserdetection.v1.FingerprintRequest.canvas_noise_detection:type_name -> browserdetection.v1.NoiseDetection
+	0,  // 3: browserdetection.v1.FingerprintRequest.webgl_noise_detection:type_name -> browserdetection.v1.NoiseDetection
+	0,  // 4: browserdetection.v1.FingerprintRequest.audio_noise_detection:type_name -> browserdetection.v1.NoiseDetection
+	1,  // 5: browserdetection.v1.FingerprintRequest.media_devices:type_name -> browserdetection.v1.MediaDevices
+	2,  // 6: browserdetection.v1.FingerprintRequest.battery:type_name -> browserdetection.v1.Battery
+	3,  // 7: browserdetection.v1.FingerprintRequest.sensors:type_name -> browserdetection.v1.Sensors
+	4,  // 8: browserdetection.v1.FingerprintRequest.screen:type_name -> browserdetection.v1.ScreenMetrics
+	5,  // 9: browserdetection.v1.FingerprintRequest.features:type_name -> browserdetection.v1.FeatureProbes
+	6,  // 10: browserdetection.v1.FingerprintRequest.font_metrics:type_name -> browserdetection.v1.FontMetric
+	7,  // 11: browserdetection.v1.FingerprintRequest.content_blocking:type_name -> browserdetection.v1.ContentBlocking
+	13, // 12: browserdetection.v1.FingerprintRequest.audio_samples:type_name -> browserdetection.v1.AudioRun
+	8,  // 13: browserdetection.v1.FingerprintRequest.network:type_name -> browserdetection.v1.NetworkTiming
+	9,  // 14: browserdetection.v1.FingerprintRequest.navigation:type_name -> browserdetection.v1.NavigationContext
+	10, // 15: browserdetection.v1.FingerprintRequest.proof:type_name -> browserdetection.v1.ExecutionProof
+	11, // 16: browserdetection.v1.FingerprintRequest.timing:type_name -> browserdetection.v1.CollectionTiming
+	12, // 17: browserdetection.v1.FingerprintRequest.interaction:type_name -> browserdetection.v1.InputSummary
+	14, // 18: browserdetection.v1.FingerprintRequest.canvas_check:type_name -> browserdetection.v1.CanvasCheck
+	16, // 19: browserdetection.v1.FingerprintRequest.webgl_params:type_name -> browserdetection.v1.WebGLParams
+	15, // 20: browserdetection.v1.WebGLParams.PrecisionsEntry.value:type_name -> browserdetection.v1.ShaderPrecision
+	21, // [21:21] is the sub-list for method output_type
+	21, // [21:21] is the sub-list for method input_type
+	21, // [21:21] is the sub-list for extension type_name
+	21, // [21:21] is the sub-list for extension extendee
+	0,  // [0:21] is the sub-list for field type_name
+}
+
+func init() { file_fingerprint_proto_init() }
+func file_fingerprint_proto_init() {
+	if File_fingerprint_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_fingerprint_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*NoiseDetection); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_fingerprint_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MediaDevices); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_fingerprint_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Battery); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_fingerprint_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Sensors); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_fingerprint_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ScreenMetrics); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_fingerprint_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FeatureProbes); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_fingerprint_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FontMetric); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_fingerprint_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ContentBlocking); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_fingerprint_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*NetworkTiming); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_fingerprint_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*NavigationContext); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_fingerprint_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ExecutionProof); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_fingerprint_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CollectionTiming); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_fingerprint_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*InputSummary); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_fingerprint_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AudioRun); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_fingerprint_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CanvasCheck); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_fingerprint_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ShaderPrecision); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_fingerprint_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WebGLParams); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_fingerprint_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FingerprintRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_fingerprint_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   20,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_fingerprint_proto_goTypes,
+		DependencyIndexes: file_fingerprint_proto_depIdxs,
+		MessageInfos:      file_fingerprint_proto_msgTypes,
+	}.Build()
+	File_fingerprint_proto = out.File
+	file_fingerprint_proto_rawDesc = nil
+	file_fingerprint_proto_goTypes = nil
+	file_fingerprint_proto_depIdxs = nil
+}
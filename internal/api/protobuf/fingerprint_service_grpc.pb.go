@@ -0,0 +1,115 @@
+// 指纹提交的gRPC服务，供接入方的后端代访客提交采集结果
+// 调用须在元数据 x-api-key 中携带站点API密钥；访客的IP和请求头由调用方在 SubmitRequest 中转发。
+// 新增字段只能追加新的字段编号，不得复用或修改已有编号。
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: fingerprint_service.proto
+
+package protobuf
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	FingerprintService_Submit_FullMethodName = "/browserdetection.v1.FingerprintService/Submit"
+)
+
+// FingerprintServiceClient is the client API for FingerprintService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type FingerprintServiceClient interface {
+	// Submit 提交一次采集结果，与 POST /api/fingerprint 走相同的校验和处理流程
+	Submit(ctx context.Context, in *SubmitRequest, opts ...grpc.CallOption) (*SubmitResponse, error)
+}
+
+type fingerprintServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewFingerprintServiceClient(cc grpc.ClientConnInterface) FingerprintServiceClient {
+	return &fingerprintServiceClient{cc}
+}
+
+func (c *fingerprintServiceClient) Submit(ctx context.Context, in *SubmitRequest, opts ...grpc.CallOption) (*SubmitResponse, error) {
+	out := new(SubmitResponse)
+	err := c.cc.Invoke(ctx, FingerprintService_Submit_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// FingerprintServiceServer is the server API for FingerprintService service.
+// All implementations must embed UnimplementedFingerprintServiceServer
+// for forward compatibility
+type FingerprintServiceServer interface {
+	// Submit 提交一次采集结果，与 POST /api/fingerprint 走相同的校验和处理流程
+	Submit(context.Context, *SubmitRequest) (*SubmitResponse, error)
+	mustEmbedUnimplementedFingerprintServiceServer()
+}
+
+// UnimplementedFingerprintServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedFingerprintServiceServer struct {
+}
+
+func (UnimplementedFingerprintServiceServer) Submit(context.Context, *SubmitRequest) (*SubmitResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Submit not implemented")
+}
+func (UnimplementedFingerprintServiceServer) mustEmbedUnimplementedFingerprintServiceServer() {}
+
+// UnsafeFingerprintServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to FingerprintServiceServer will
+// result in compilation errors.
+type UnsafeFingerprintServiceServer interface {
+	mustEmbedUnimplementedFingerprintServiceServer()
+}
+
+func RegisterFingerprintServiceServer(s grpc.ServiceRegistrar, srv FingerprintServiceServer) {
+	s.RegisterService(&FingerprintService_ServiceDesc, srv)
+}
+
+func _FingerprintService_Submit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FingerprintServiceServer).Submit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FingerprintService_Submit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FingerprintServiceServer).Submit(ctx, req.(*SubmitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// FingerprintService_ServiceDesc is the grpc.ServiceDesc for FingerprintService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var FingerprintService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "browserdetection.v1.FingerprintService",
+	HandlerType: (*FingerprintServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Submit",
+			Handler:    _FingerprintService_Submit_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "fingerprint_service.proto",
+}
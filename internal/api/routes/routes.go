@@ -3,12 +3,14 @@ package routes
 import (
 	"browser-detection/internal/api/handlers"
 	"browser-detection/internal/api/middleware"
+	"browser-detection/internal/config"
+	"browser-detection/internal/counters"
 
 	"github.com/gin-gonic/gin"
 )
 
 // SetupRoutes 设置路由
-func SetupRoutes(handler *handlers.FingerprintHandler) *gin.Engine {
+func SetupRoutes(cfg *config.Config, counters *counters.Counters, handler *handlers.FingerprintHandler, collector *handlers.CollectorHandler, admin *handlers.AdminHandler) *gin.Engine {
 	// 设置Gin模式
 	gin.SetMode(gin.ReleaseMode)
 
@@ -17,14 +19,21 @@ func SetupRoutes(handler *handlers.FingerprintHandler) *gin.Engine {
 
 	// 应用中间件
 	r.Use(middleware.Logger())
-	r.Use(middleware.CORS())
+	r.Use(middleware.Gzip())
+	r.Use(middleware.CORS(cfg))
+	r.Use(middleware.APIKey(cfg))
 	r.Use(middleware.Security())
 	r.Use(middleware.ErrorHandler())
+	r.Use(middleware.BodyLimit(cfg.Limits))
+	r.Use(middleware.Timeout(cfg.Server.RequestTimeout.Std()))
 	r.Use(gin.Recovery())
 
+	// 管理令牌校验，管理API和涉及访客明细的查询接口共用
+	adminAuth := middleware.AdminAuth(cfg.Admin.Token)
+
 	// 静态文件服务
 	r.Static("/static", "./static")
-	r.StaticFile("/", "./static/index.html")
+	r.GET("/", collector.Index)
 	r.StaticFile("/favicon.ico", "./static/favicon.ico")
 
 	// API路由组
@@ -33,10 +42,125 @@ func SetupRoutes(handler *handlers.FingerprintHandler) *gin.Engine {
 		// 健康检查
 		api.GET("/health", handler.HealthCheck)
 
+		// 速率限制只作用于之后注册的路由，健康检查不受限制
+		api.Use(middleware.RateLimiter(cfg.Limits.RateLimit, counters))
+
+		// 严格CSP集成辅助
+		api.GET("/csp", collector.CSP)
+
 		// 指纹相关API
-		api.POST("/fingerprint", handler.SubmitFingerprint)
+		api.POST("/fingerprint",
+			middleware.Decompress(cfg.Limits.MaxDecompressedBytes),
+			handler.SubmitFingerprint,
+		)
+		api.POST("/fingerprint/fingerprintjs",
+			middleware.Decompress(cfg.Limits.MaxDecompressedBytes),
+			handler.SubmitFingerprintJS,
+		)
+		api.GET("/proof/seed", handler.GetProofSeed)
+		api.GET("/crawlers/check", handler.CheckCrawler)
+		api.POST("/events", handler.SubmitEvent)
+		api.POST("/access-logs",
+			middleware.Decompress(cfg.Limits.MaxDecompressedBytes),
+			handler.IngestAccessLogs,
+		)
+		api.GET("/decision/:hash", handler.GetDecision)
+		api.GET("/edge/decision", handler.GetEdgeDecision)
+		api.GET("/accounts/:id/risk", handler.GetAccountRisk)
+		// IP画像和信誉包含该地址出现过的指纹，须携带管理令牌
+		api.GET("/ips/:ip", adminAuth, handler.GetIPProfile)
+		api.GET("/ips/:ip/reputation", adminAuth, handler.GetIPReputation)
 		api.GET("/analysis/:hash", handler.GetAnalysis)
+		// 相似指纹查询返回其他设备的指纹哈希，须携带管理令牌
+		api.GET("/similar/:hash", adminAuth, handler.GetSimilar)
+		api.GET("/fingerprints/:hash/anonymity", handler.GetAnonymity)
+		api.GET("/fingerprints/:hash/similar-canvas", adminAuth, handler.GetSimilarCanvas)
+		api.GET("/fingerprints/:hash/neighbors", adminAuth, handler.GetNeighbors)
+		api.GET("/stats/versions", handler.GetVersionStats)
+		api.GET("/stats/quality", handler.GetQualityStats)
+		api.GET("/stats/scores", handler.GetScoreStats)
+		api.GET("/stats/referrers", handler.GetReferrerStats)
+		api.GET("/stats/gpu", handler.GetGPUStats)
+		api.GET("/stats/browsers", handler.GetBrowserStats)
+		api.GET("/stats/crawlers", handler.GetCrawlerStats)
+		api.GET("/stats/robots", handler.GetRobotsReport)
+		api.GET("/stats/access-logs", handler.GetAccessLogStats)
+		api.GET("/export/aggregates", handler.ExportAggregates)
+
+		// 看板：按页面组合的数据，首页一次请求取得；包含各站点的检测明细，须携带管理令牌
+		dashboard := api.Group("/dashboard", adminAuth)
+		dashboard.GET("/overview", handler.GetDashboardOverview)
+		dashboard.GET("/live", handler.GetDashboardLive)
+		dashboard.GET("/detections", handler.GetDetections)
+
+		// 访客的指纹历史可用于跨站点追踪，须携带管理令牌
+		api.GET("/visitors/:id/drift", adminAuth, handler.GetDrift)
+		api.GET("/visitors/:id/fingerprints", adminAuth, handler.GetVisitorFingerprints)
+		api.GET("/farms", adminAuth, handler.GetFarms)
+		api.GET("/outliers", handler.GetOutliers)
+		api.GET("/anomalies", adminAuth, handler.GetAnomalies)
+		api.GET("/credential-stuffing", adminAuth, handler.GetCredentialStuffing)
+		api.GET("/scraping", adminAuth, handler.GetScrapingDetections)
+		api.POST("/tokens/verify", handler.VerifyVisitorToken)
 	}
 
+	// 管理API
+	adminAPI := r.Group("/api/admin", adminAuth)
+	{
+		adminAPI.GET("/sites/:id/policy", admin.GetSitePolicy)
+		adminAPI.PUT("/sites/:id/policy", admin.PutSitePolicy)
+		adminAPI.DELETE("/sites/:id/policy", admin.DeleteSitePolicy)
+		adminAPI.GET("/config/thresholds", admin.GetThresholds)
+		adminAPI.PUT("/config/thresholds", admin.PutThresholds)
+		adminAPI.GET("/rules", admin.GetRules)
+		adminAPI.PUT("/rules", admin.PutRules)
+		adminAPI.POST("/rules/validate", admin.ValidateRules)
+		adminAPI.GET("/rules/versions", admin.GetRuleSetVersions)
+		adminAPI.GET("/rules/versions/:version", admin.GetRuleSetVersion)
+		adminAPI.POST("/rules/rollback", admin.RollbackRules)
+		adminAPI.GET("/features", admin.GetFeatures)
+		adminAPI.PUT("/features/:name", admin.PutFeature)
+		adminAPI.DELETE("/features/:name", admin.DeleteFeature)
+		adminAPI.GET("/webhooks", admin.GetWebhooks)
+		adminAPI.POST("/webhooks", admin.PostWebhook)
+		adminAPI.PUT("/webhooks/:name", admin.PutWebhook)
+		adminAPI.DELETE("/webhooks/:name", admin.DeleteWebhook)
+		adminAPI.POST("/webhooks/:name/test", admin.TestWebhook)
+		adminAPI.GET("/audit", admin.GetAuditLog)
+		adminAPI.GET("/quarantine", admin.GetQuarantine)
+		adminAPI.GET("/blocklist", admin.GetBlocklist)
+		adminAPI.GET("/blocklist/filter", admin.GetBlocklistFilter)
+		adminAPI.POST("/blocklist", admin.PostBlocklistEntry)
+		adminAPI.DELETE("/blocklist", admin.DeleteBlocklistEntry)
+		adminAPI.GET("/auto-block", admin.GetAutoBlock)
+		adminAPI.GET("/detectors", admin.GetDetectors)
+		adminAPI.DELETE("/fingerprints/:hash", admin.DeleteFingerprint)
+		adminAPI.POST("/fingerprints/:hash/restore", admin.RestoreFingerprint)
+		adminAPI.PUT("/fingerprints/:hash/label", admin.PutLabel)
+		adminAPI.DELETE("/fingerprints/:hash/label", admin.DeleteLabel)
+		adminAPI.GET("/tokens/keys", admin.GetTokenKeys)
+		adminAPI.POST("/tokens/rotate", admin.RotateTokenKey)
+		adminAPI.POST("/tokens/revoke", admin.RevokeVisitorTokens)
+		adminAPI.GET("/ua-regexes", admin.GetUARegexes)
+		adminAPI.POST("/ua-regexes/refresh", admin.RefreshUARegexes)
+		adminAPI.GET("/crawlers", admin.GetCrawlers)
+		adminAPI.GET("/baselines", admin.GetBaselines)
+		adminAPI.POST("/baselines/refresh", admin.RefreshBaselines)
+		adminAPI.GET("/baselines/math", admin.GetMathHashes)
+		adminAPI.GET("/ml", admin.GetML)
+		adminAPI.POST("/ml/refresh", admin.RefreshML)
+		adminAPI.POST("/outliers/train", handler.TrainOutliers)
+		adminAPI.POST("/farms/detect", handler.DetectFarms)
+		adminAPI.GET("/browser-releases", admin.GetBrowserReleases)
+		adminAPI.POST("/browser-releases/refresh", admin.RefreshBrowserReleases)
+		adminAPI.GET("/threat-feeds", admin.GetThreatFeeds)
+		adminAPI.PUT("/threat-feeds/:name", admin.PutThreatFeed)
+		adminAPI.POST("/threat-feeds/:name/refresh", admin.RefreshThreatFeed)
+		adminAPI.GET("/replication", admin.GetReplication)
+	}
+
+	// 跨区域同步
+	r.GET("/api/replication/changes", middleware.ReplicationAuth(cfg.Replication), handler.GetReplicationChanges)
+
 	return r
 }
@@ -0,0 +1,263 @@
+package backup
+
+import (
+	"browser-detection/internal/config"
+	"browser-detection/internal/utils"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	// filePrefix 备份文件名前缀，定时备份只清理带该前缀的文件
+	filePrefix = "fingerprints-"
+	// encryptedSuffix 加密备份文件的后缀
+	encryptedSuffix = ".enc"
+	// uploadTimeout 上传一个备份文件的超时
+	uploadTimeout = 30 * time.Minute
+)
+
+// Manager 数据库备份
+type Manager struct {
+	cfg      config.BackupConfig
+	db       *utils.Database
+	uploader *s3Uploader
+}
+
+// New 创建备份管理器，配置了 s3.bucket 时备份后上传
+func New(cfg config.BackupConfig, db *utils.Database) *Manager {
+	m := &Manager{cfg: cfg, db: db}
+	if cfg.S3.Bucket != "" {
+		m.uploader = &s3Uploader{cfg: cfg.S3, client: &http.Client{Timeout: uploadTimeout}}
+	}
+	return m
+}
+
+// Create 用 VACUUM INTO 在服务运行时生成一致的数据库快照，按配置加密并上传，返回备份文件路径；
+// 分片存储时各分片的指纹和分析结果一并复制到快照中，备份总是单个不分片的数据库
+func (m *Manager) Create(ctx context.Context) (string, error) {
+	if err := os.MkdirAll(m.cfg.Dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create backup dir: %w", err)
+	}
+	name := filepath.Join(m.cfg.Dir, filePrefix+time.Now().UTC().Format("20060102T150405Z")+".db")
+	snapshot := name + ".tmp"
+	if _, err := m.db.DB.ExecContext(ctx, "VACUUM INTO ?", snapshot); err != nil {
+		os.Remove(snapshot)
+		return "", fmt.Errorf("failed to snapshot database: %w", err)
+	}
+	if m.db.Shards > 1 {
+		if err := m.db.CopyShards(ctx, snapshot); err != nil {
+			os.Remove(snapshot)
+			return "", fmt.Errorf("failed to snapshot shards: %w", err)
+		}
+	}
+	if err := os.Chmod(snapshot, 0o600); err != nil {
+		os.Remove(snapshot)
+		return "", err
+	}
+
+	if m.cfg.EncryptionKey != "" {
+		encrypted := name + encryptedSuffix
+		if err := encryptFile(encrypted, snapshot, m.cfg.EncryptionKey); err != nil {
+			os.Remove(snapshot)
+			os.Remove(encrypted)
+			return "", fmt.Errorf("failed to encrypt backup: %w", err)
+		}
+		os.Remove(snapshot)
+		name = encrypted
+	} else if err := os.Rename(snapshot, name); err != nil {
+		return "", err
+	}
+
+	if m.uploader != nil {
+		if err := m.uploader.Upload(ctx, name); err != nil {
+			return name, fmt.Errorf("failed to upload backup: %w", err)
+		}
+	}
+	log.Printf("Created backup %s", name)
+	return name, nil
+}
+
+// encryptFile 加密 src 写入 dst
+func encryptFile(dst, src, passphrase string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := encrypt(out, in, passphrase); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// prune 只保留最新的 keep 个备份文件
+func (m *Manager) prune() error {
+	entries, err := os.ReadDir(m.cfg.Dir)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		if !e.IsDir() && strings.HasPrefix(name, filePrefix) && !strings.HasSuffix(name, ".tmp") {
+			names = append(names, name)
+		}
+	}
+	// 文件名中的UTC时间戳按字典序即时间序
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	for i := m.cfg.Keep; i < len(names); i++ {
+		if err := os.Remove(filepath.Join(m.cfg.Dir, names[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run 按配置的间隔定时备份并清理旧备份，直到 ctx 结束；leader 返回假时跳过本次备份
+func (m *Manager) Run(ctx context.Context, leader func() bool) {
+	if m.cfg.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(m.cfg.Interval.Std())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !leader() {
+				continue
+			}
+			if _, err := m.Create(ctx); err != nil {
+				log.Printf("Backup failed: %v", err)
+			}
+			if err := m.prune(); err != nil {
+				log.Printf("Failed to prune backups: %v", err)
+			}
+		}
+	}
+}
+
+// Restore 用备份文件替换 dbPath 处的数据库，必须在服务停止时执行
+// 加密的备份需要 passphrase；写入前先在临时文件上做完整性检查，原数据库连同WAL保留为 .bak
+func Restore(src, dbPath, passphrase string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := dbPath + ".restore"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	if isEncrypted(in) {
+		if passphrase == "" {
+			out.Close()
+			return errors.New("backup is encrypted but backup.encryption_key is not set")
+		}
+		if _, err := in.Seek(0, io.SeekStart); err != nil {
+			out.Close()
+			return err
+		}
+		err = decrypt(out, in, passphrase)
+	} else {
+		if _, err = in.Seek(0, io.SeekStart); err == nil {
+			_, err = io.Copy(out, in)
+		}
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := checkIntegrity(tmp); err != nil {
+		return err
+	}
+
+	if err := keepPrevious(dbPath); err != nil {
+		return err
+	}
+	// 备份是不分片的完整数据库，原有的分片文件同样保留为 .bak，配置了分片时下次启动重新分片
+	for i := 0; i < utils.MaxShards; i++ {
+		if err := keepPrevious(utils.ShardPath(dbPath, i)); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(tmp, dbPath); err != nil {
+		return err
+	}
+	log.Printf("Restored %s from %s", dbPath, src)
+	return nil
+}
+
+// keepPrevious 将 dbPath 处的数据库连同其WAL和共享内存文件重命名为 .bak、.bak-wal、.bak-shm，
+// WAL中尚未检查点的事务随之保留，打开 .bak 时由SQLite重放；数据库不存在时只删除残留的WAL文件
+func keepPrevious(dbPath string) error {
+	suffixes := []string{"-wal", "-shm"}
+	if _, err := os.Stat(dbPath); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		for _, suffix := range suffixes {
+			if err := os.Remove(dbPath + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+		return nil
+	}
+	// 先删除上次恢复留下的WAL，否则会被当作本次 .bak 的WAL重放
+	for _, suffix := range suffixes {
+		if err := os.Remove(dbPath + ".bak" + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.Rename(dbPath, dbPath+".bak"); err != nil {
+		return err
+	}
+	for _, suffix := range suffixes {
+		if err := os.Rename(dbPath+suffix, dbPath+".bak"+suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkIntegrity 对SQLite文件执行完整性检查
+func checkIntegrity(path string) error {
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	var result string
+	if err := db.QueryRow("PRAGMA integrity_check").Scan(&result); err != nil {
+		return fmt.Errorf("backup is not a valid database: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("backup failed integrity check: %s", result)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// encryptedMagic 加密备份文件的文件头，其后是 saltSize 字节的随机盐
+	encryptedMagic = "BDBACKUP\x02"
+	// saltSize 密钥派生的随机盐长度
+	saltSize = 16
+	// chunkSize 加密分块的明文大小，整个文件不必读入内存
+	chunkSize = 64 * 1024
+)
+
+// scrypt 参数：N=2^15、r=8、p=1，派生一次约需32MB内存，使离线猜测密钥的代价与之相当
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// ErrNotEncrypted 备份文件没有加密文件头
+var ErrNotEncrypted = errors.New("backup is not encrypted")
+
+// deriveKey 以 scrypt 由配置的密钥和文件头中的随机盐派生AES-256密钥
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, 32)
+}
+
+// chunkNonce 由随机前缀和分块序号组成每块的nonce，同一文件内不重复
+func chunkNonce(prefix []byte, index uint32) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[8:], index)
+	return nonce
+}
+
+// newAEAD 以派生的密钥创建AES-256-GCM
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encrypt 以分块AES-256-GCM加密：文件头、16字节随机盐、8字节随机nonce前缀，之后每块为4字节长度加密文；
+// 最后一块的附加数据标记为1，截断的文件无法通过校验
+func encrypt(dst io.Writer, src io.Reader, passphrase string) error {
+	header := make([]byte, saltSize+8)
+	if _, err := rand.Read(header); err != nil {
+		return err
+	}
+	salt, prefix := header[:saltSize], header[saltSize:]
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(dst, encryptedMagic); err != nil {
+		return err
+	}
+	if _, err := dst.Write(header); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReaderSize(src, chunkSize)
+	buf := make([]byte, chunkSize)
+	for index := uint32(0); ; index++ {
+		n, err := io.ReadFull(reader, buf)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return err
+		}
+		_, peekErr := reader.Peek(1)
+		final := byte(0)
+		if peekErr == io.EOF {
+			final = 1
+		}
+		sealed := aead.Seal(nil, chunkNonce(prefix, index), buf[:n], []byte{final})
+		var length [4]byte
+		binary.BigEndian.PutUint32(length[:], uint32(len(sealed)))
+		if _, err := dst.Write(length[:]); err != nil {
+			return err
+		}
+		if _, err := dst.Write(sealed); err != nil {
+			return err
+		}
+		if final == 1 {
+			return nil
+		}
+	}
+}
+
+// decrypt 解密 encrypt 写出的文件，文件头不符时返回 ErrNotEncrypted
+func decrypt(dst io.Writer, src io.Reader, passphrase string) error {
+	magic := make([]byte, len(encryptedMagic))
+	if _, err := io.ReadFull(src, magic); err != nil || !bytes.Equal(magic, []byte(encryptedMagic)) {
+		return ErrNotEncrypted
+	}
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(src, salt); err != nil {
+		return fmt.Errorf("truncated backup: %w", err)
+	}
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	prefix := make([]byte, 8)
+	if _, err := io.ReadFull(src, prefix); err != nil {
+		return fmt.Errorf("truncated backup: %w", err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+
+	for index := uint32(0); ; index++ {
+		var length [4]byte
+		if _, err := io.ReadFull(src, length[:]); err != nil {
+			return fmt.Errorf("truncated backup: %w", err)
+		}
+		n := binary.BigEndian.Uint32(length[:])
+		if n > chunkSize+uint32(aead.Overhead()) {
+			return errors.New("corrupt backup chunk")
+		}
+		sealed := make([]byte, n)
+		if _, err := io.ReadFull(src, sealed); err != nil {
+			return fmt.Errorf("truncated backup: %w", err)
+		}
+		// 先按中间块解密，失败时再按最后一块解密
+		for _, final := range []byte{0, 1} {
+			plain, err := aead.Open(nil, chunkNonce(prefix, index), sealed, []byte{final})
+			if err != nil {
+				if final == 1 {
+					return errors.New("failed to decrypt backup: wrong key or corrupt file")
+				}
+				continue
+			}
+			if _, err := dst.Write(plain); err != nil {
+				return err
+			}
+			if final == 1 {
+				return nil
+			}
+			break
+		}
+	}
+}
+
+// isEncrypted 判断文件是否以加密文件头开始
+func isEncrypted(r io.Reader) bool {
+	header := make([]byte, len(encryptedMagic))
+	_, err := io.ReadFull(r, header)
+	return err == nil && string(header) == encryptedMagic
+}
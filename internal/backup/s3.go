@@ -0,0 +1,112 @@
+package backup
+
+import (
+	"browser-detection/internal/config"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// unsignedPayload S3允许在HTTPS上不对请求体签名，上传时不必两次读取大文件
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// s3Uploader 以AWS签名V4（路径方式）上传文件到S3兼容存储
+type s3Uploader struct {
+	cfg    config.S3Config
+	client *http.Client
+}
+
+// objectURL 返回对象的URL
+func (u *s3Uploader) objectURL(key string) (*url.URL, error) {
+	endpoint := u.cfg.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", u.cfg.Region)
+	}
+	base, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	base.Path = "/" + path.Join(strings.Trim(base.Path, "/"), u.cfg.Bucket, u.cfg.Prefix, key)
+	return base, nil
+}
+
+// Upload 上传本地文件，对象名为前缀加文件名
+func (u *s3Uploader) Upload(ctx context.Context, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	target, err := u.objectURL(path.Base(file))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target.String(), f)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	u.sign(req, time.Now().UTC())
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("s3 upload returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// sign 按AWS签名V4为请求加上 Authorization 头
+func (u *s3Uploader) sign(req *http.Request, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host + "\nx-amz-content-sha256:" + unsignedPayload + "\nx-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		unsignedPayload,
+	}, "\n")
+
+	scope := date + "/" + u.cfg.Region + "/s3/aws4_request"
+	digest := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(digest[:])
+
+	key := hmacSHA256([]byte("AWS4"+u.cfg.SecretAccessKey), date)
+	for _, part := range []string{u.cfg.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		u.cfg.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// hmacSHA256 计算 HMAC-SHA256
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,135 @@
+// Package baselines 已知真实浏览器的指纹组件取值：按浏览器、系统和GPU类别的Canvas固定图案哈希、WebGL参数、音频指纹值
+// 和各JS引擎的Math结果向量哈希，
+// 随程序内置一份，可用外部文件替换并在运行时刷新
+package baselines
+
+import (
+	"browser-detection/internal/config"
+	"browser-detection/internal/utils"
+	"bytes"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// embeddedCorpus 内置的基线数据，加载外部文件失败时使用
+//
+//go:embed corpus.json
+var embeddedCorpus []byte
+
+// Corpus 一份基线数据
+type Corpus struct {
+	// Version 数据版本，由维护者在每次更新时修改
+	Version string `json:"version"`
+	// Source 数据来源：文件路径或 embedded
+	Source string                  `json:"-"`
+	Canvas []config.CanvasBaseline `json:"canvas"`
+	WebGL  []WebGLBaseline         `json:"webgl"`
+	Audio  []config.AudioBaseline  `json:"audio"`
+	Math   []MathBaseline          `json:"math"`
+}
+
+// MathBaseline 某个JS引擎上Math结果向量（前端 BrowserUtils.getMathInfo，按采集顺序以 | 连接后的SHA-256）的已知哈希
+type MathBaseline struct {
+	Engine string   `json:"engine"`
+	Hashes []string `json:"hashes"`
+}
+
+// mathEngines Math基线可以声明的JS引擎
+var mathEngines = map[string]bool{
+	utils.EngineV8:           true,
+	utils.EngineSpiderMonkey: true,
+	utils.EngineJSC:          true,
+}
+
+// WebGLBaseline 某个GPU类别（以及浏览器家族、操作系统，为空时适用于所有值）上真实实现的WebGL参数
+type WebGLBaseline struct {
+	GPU     string `json:"gpu"`
+	Browser string `json:"browser"`
+	OS      string `json:"os"`
+	// Params 参数名（如 MAX_TEXTURE_SIZE）到真实实现可能的取值，未列出的参数不校验
+	Params map[string][]int `json:"params"`
+	// Extensions 真实实现总会提供的扩展
+	Extensions []string `json:"extensions"`
+}
+
+// Parse 解析并校验基线数据，source 记录数据来源
+func Parse(data []byte, source string) (*Corpus, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var c Corpus
+	if err := dec.Decode(&c); err != nil {
+		return nil, fmt.Errorf("failed to parse baselines: %w", err)
+	}
+	if c.Version == "" {
+		return nil, fmt.Errorf("invalid baselines: version is required")
+	}
+	for i := range c.Canvas {
+		b := &c.Canvas[i]
+		if len(b.Hashes) == 0 {
+			return nil, fmt.Errorf("invalid baselines canvas[%d]: hashes must not be empty", i)
+		}
+		for j, h := range b.Hashes {
+			h = strings.ToLower(h)
+			if raw, err := hex.DecodeString(h); err != nil || len(raw) != 32 {
+				return nil, fmt.Errorf("invalid baselines canvas[%d]: hash %q is not a SHA-256 hex digest", i, h)
+			}
+			b.Hashes[j] = h
+		}
+	}
+	for i, b := range c.WebGL {
+		if b.GPU == "" || len(b.Params) == 0 && len(b.Extensions) == 0 {
+			return nil, fmt.Errorf("invalid baselines webgl[%d]: gpu and params or extensions are required", i)
+		}
+	}
+	for i, b := range c.Audio {
+		if b.Engine == "" || b.Sum <= 0 {
+			return nil, fmt.Errorf("invalid baselines audio[%d]: engine and a positive sum are required", i)
+		}
+	}
+	for i := range c.Math {
+		b := &c.Math[i]
+		if !mathEngines[b.Engine] || len(b.Hashes) == 0 {
+			return nil, fmt.Errorf("invalid baselines math[%d]: engine must be V8, SpiderMonkey or JavaScriptCore and hashes must not be empty", i)
+		}
+		for j, h := range b.Hashes {
+			h = strings.ToLower(h)
+			if raw, err := hex.DecodeString(h); err != nil || len(raw) != 32 {
+				return nil, fmt.Errorf("invalid baselines math[%d]: hash %q is not a SHA-256 hex digest", i, h)
+			}
+			b.Hashes[j] = h
+		}
+	}
+	c.Source = source
+	return &c, nil
+}
+
+// Load 从文件读取基线数据
+func Load(path string) (*Corpus, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data, path)
+}
+
+var (
+	embeddedOnce   sync.Once
+	embeddedParsed *Corpus
+)
+
+// Embedded 内置的基线数据
+func Embedded() *Corpus {
+	embeddedOnce.Do(func() {
+		c, err := Parse(embeddedCorpus, "embedded")
+		if err != nil {
+			panic(err)
+		}
+		embeddedParsed = c
+	})
+	return embeddedParsed
+}
@@ -0,0 +1,1596 @@
+// Package config 加载服务器配置
+// 配置来源依次为：内置默认值、CONFIG_FILE 指定的JSON文件、环境变量
+package config
+
+import (
+	"browser-detection/internal/expr"
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"os"
+	"regexp"
+	"time"
+)
+
+// Config 服务器配置
+type Config struct {
+	Port         string `json:"port"`
+	DatabasePath string `json:"database_path"`
+	// DatabaseReadPath 只读副本路径，配置后统计和列表接口从副本读取
+	DatabaseReadPath string           `json:"database_read_path"`
+	LogLevel         string           `json:"log_level"`
+	EnableCORS       bool             `json:"enable_cors"`
+	Server           ServerConfig     `json:"server"`
+	Limits           LimitsConfig     `json:"limits"`
+	CORS             CORSConfig       `json:"cors"`
+	Sites            []SiteConfig     `json:"sites"`
+	Detection        DetectionConfig  `json:"detection"`
+	Hashing          HashingConfig    `json:"hashing"`
+	Export           ExportConfig     `json:"export"`
+	Embedding        EmbeddingConfig  `json:"embedding"`
+	Anomaly          AnomalyConfig    `json:"anomaly"`
+	Alerting         AlertingConfig   `json:"alerting"`
+	Admin            AdminConfig      `json:"admin"`
+	Tokens           TokenConfig      `json:"tokens"`
+	Backup           BackupConfig     `json:"backup"`
+	Cluster          ClusterConfig    `json:"cluster"`
+	Storage          StorageConfig    `json:"storage"`
+	Quarantine       QuarantineConfig `json:"quarantine"`
+	Edge             EdgeConfig       `json:"edge"`
+	// Replication 多区域部署时各区域实例之间同步封禁名单、IP信誉和人工标注
+	Replication ReplicationConfig `json:"replication"`
+	// Counters 速率限制和速度窗口的计数存储
+	Counters CountersConfig `json:"counters"`
+	// Blocklist 临时封禁名单的内存过滤器
+	Blocklist BlocklistConfig `json:"blocklist"`
+	// QueryCache 统计接口的查询缓存
+	QueryCache QueryCacheConfig `json:"query_cache"`
+	// AudioBaselines 已知真实设备的音频指纹值（压缩器输出第4500~5000个采样的绝对值之和），补充到基线数据中
+	AudioBaselines []AudioBaseline `json:"audio_baselines"`
+}
+
+// 客户端噪点检测结果的处理模式
+const (
+	// NoiseModeTrust 客户端上报的噪点检测结果直接计入爬虫评分
+	NoiseModeTrust = "trust"
+	// NoiseModeVerify 客户端上报的噪点只有经服务端验证（服务端图像/采样分析、同一访客的跨访问一致性）才计分
+	NoiseModeVerify = "verify"
+)
+
+// DetectionConfig 检测策略
+type DetectionConfig struct {
+	NoiseMode string        `json:"noise_mode"`
+	Farms     FarmConfig    `json:"farms"`
+	Accounts  AccountConfig `json:"accounts"`
+	Cookies   CookieConfig  `json:"cookies"`
+	// ScoreHalfLife 存储的爬虫评分按距上次评分的时间衰减的半衰期，为0时不衰减
+	ScoreHalfLife Duration `json:"score_half_life"`
+	// CredentialStuffing 撞库检测
+	CredentialStuffing CredentialStuffingConfig `json:"credential_stuffing"`
+	// AutoBlock 按检测结果自动封禁的规则，为空时不自动封禁
+	AutoBlock []AutoBlockRule `json:"auto_block"`
+	// UAParser User Agent解析规则
+	UAParser UAParserConfig `json:"ua_parser"`
+	// Baselines 已知真实浏览器指纹组件取值的基线数据
+	Baselines BaselinesConfig `json:"baselines"`
+	// ThreatIntel IP信誉情报源
+	ThreatIntel ThreatIntelConfig `json:"threat_intel"`
+	// IPReputation 按IP累计的历史信誉
+	IPReputation IPReputationConfig `json:"ip_reputation"`
+	// Proxy 住宅代理检测
+	Proxy ProxyConfig `json:"proxy"`
+	// Navigation 采集页面的来源与导航上下文
+	Navigation NavigationConfig `json:"navigation"`
+	// Scraping 按页面浏览事件的访问序列检测采集爬虫
+	Scraping ScrapingConfig `json:"scraping"`
+	// Concurrency 同一身份同时从多个网段或国家活动的检测
+	Concurrency ConcurrencyConfig `json:"concurrency"`
+	// Challenges 人机验证的下发与结果反馈
+	Challenges ChallengeConfig `json:"challenges"`
+	// JSProof 采集脚本的执行证明
+	JSProof JSProofConfig `json:"js_proof"`
+	// CanvasCheck 双Canvas渲染校验
+	CanvasCheck CanvasCheckConfig `json:"canvas_check"`
+	// Timing 采集时间与时钟偏差校验
+	Timing TimingConfig `json:"timing"`
+	// Typing 登录、注册事件的打字节奏
+	Typing TypingConfig `json:"typing"`
+	// Detectors 编译进服务的检测器插件
+	Detectors DetectorsConfig `json:"detectors"`
+	// Expressions 运维编写的评分表达式，为空时不计算
+	Expressions []ScoreExpression `json:"expressions"`
+	// ML 机器学习评分模型，未配置模型文件时不启用
+	ML MLConfig `json:"ml"`
+	// Outliers 基于孤立森林的无监督离群检测
+	Outliers OutlierConfig `json:"outliers"`
+	// BrowserReleases 各浏览器的稳定版本表与版本新旧校验
+	BrowserReleases BrowserReleasesConfig `json:"browser_releases"`
+	// Crawlers 按User Agent识别已知爬虫
+	Crawlers CrawlersConfig `json:"crawlers"`
+	// Robots 已识别爬虫的 robots.txt 遵守情况审计
+	Robots RobotsConfig `json:"robots"`
+	// AccessLogs 接入 Web 服务器的访问日志，对从未执行采集脚本的客户端评分
+	AccessLogs AccessLogsConfig `json:"access_logs"`
+}
+
+// AccessLogsConfig 访问日志接入：接收（POST /api/access-logs）或跟踪 Web 服务器的 JSON 访问日志（nginx、caddy），
+// 也接收 Cloudflare Logpush 和 AWS ALB 的日志批次，按 IP、User Agent 和时间与已采集的指纹关联，对请求了页面却从未执行采集脚本的客户端评分，覆盖采集端看不到的流量
+type AccessLogsConfig struct {
+	// Window 关联指纹的时间窗口：指纹在客户端首次请求前、最后一次请求后该时长内出现过即视为执行了采集脚本；
+	// 客户端最后一次请求超过该时长后才评分。为0时不接入访问日志
+	Window Duration `json:"window"`
+	// Files 跟踪的访问日志文件，从启动时的文件末尾开始读取，文件被轮转或截断后从头读取新文件
+	Files []AccessLogFile `json:"files"`
+	// PollInterval 检查跟踪文件新内容的间隔
+	PollInterval Duration `json:"poll_interval"`
+	// MinPages 客户端一天内请求的页面达到该数量才评分
+	MinPages int `json:"min_pages"`
+	// MaxPageRate 每分钟页面请求数超过该值时记入 high_page_rate
+	MaxPageRate float64 `json:"max_page_rate"`
+	// NoJSWeight 请求页面却未关联到指纹（no_js）的权重
+	NoJSWeight float64 `json:"no_js_weight"`
+	// NoAssetsWeight 只请求页面、从不加载静态资源（no_assets）的权重
+	NoAssetsWeight float64 `json:"no_assets_weight"`
+	// RateWeight 页面请求速率过高（high_page_rate）的权重
+	RateWeight float64 `json:"rate_weight"`
+	// ErrorWeight 半数以上请求返回4xx/5xx（error_rate）的权重
+	ErrorWeight float64 `json:"error_weight"`
+	// CDNBotThreshold CDN 提供的爬虫评分（Cloudflare Bot Management 的 BotScore，1-99）不高于该值时视为自动化程序
+	CDNBotThreshold int `json:"cdn_bot_threshold"`
+	// CDNWeight CDN 评分判定为自动化程序（cdn_bot_score）的权重，同时用于访问日志客户端和同一IP、User Agent的指纹提交
+	CDNWeight float64 `json:"cdn_weight"`
+	// Retention 客户端汇总记录的保留期
+	Retention Duration `json:"retention"`
+}
+
+// AccessLogFile 一个跟踪的访问日志文件
+type AccessLogFile struct {
+	Path string `json:"path"`
+	// Format nginx、caddy、cloudflare 或 alb
+	Format string `json:"format"`
+	// SiteID 日志所属的接入站点
+	SiteID string `json:"site_id"`
+}
+
+// RobotsConfig 已识别爬虫的 robots.txt 遵守情况审计：抓取并缓存各站点的 robots.txt，
+// 检查爬虫访问的页面路径（采集脚本和 page_view 事件上报的页面、爬虫检查接口的 path）是否被禁止
+type RobotsConfig struct {
+	// TTL robots.txt 的缓存时长，为0时不审计
+	TTL Duration `json:"ttl"`
+	// Timeout 抓取 robots.txt 的超时
+	Timeout Duration `json:"timeout"`
+	// Retention 违规路径记录在最后一次出现后的保留时长
+	Retention Duration `json:"retention"`
+}
+
+// CrawlersConfig 已知爬虫的特征库，用于识别AI爬虫、搜索引擎等自报身份的爬虫，按站点策略放行或拦截并统计其流量
+type CrawlersConfig struct {
+	// Path 特征库文件路径，为空时只使用内置的特征库；启动时加载失败则使用内置的特征库
+	Path string `json:"path"`
+	// AIWeight 识别为AI爬虫时的信号权重
+	AIWeight float64 `json:"ai_weight"`
+	// Weight 识别为其他已知爬虫时的信号权重
+	Weight float64 `json:"weight"`
+}
+
+// BrowserReleasesConfig 各浏览器当前稳定版的版本表，用于识别User Agent和Client Hints中不可能过旧或尚未发布的版本，
+// 并统计过时浏览器的流量；未配置 Feed 时使用内置的版本表
+type BrowserReleasesConfig struct {
+	// Feed 版本表的地址（http/https）或本地文件路径
+	Feed string `json:"feed"`
+	// Interval 从 Feed 更新的间隔
+	Interval Duration `json:"interval"`
+	// Timeout 下载 Feed 的超时
+	Timeout Duration `json:"timeout"`
+	// FutureMajors 主版本号比按发布周期推算的稳定版高出超过该值时视为尚未发布，Beta、Dev、Canary 通常高出1~3
+	FutureMajors int `json:"future_majors"`
+	// OutdatedAfter 被下一个版本取代超过该时长的版本在统计中视为过时
+	OutdatedAfter Duration `json:"outdated_after"`
+	// ObsoleteAfter 被下一个版本取代超过该时长的版本视为不可能仍在使用
+	ObsoleteAfter Duration `json:"obsolete_after"`
+	// Weight 版本尚未发布或不可能仍在使用时的信号权重，为0时不校验
+	Weight float64 `json:"weight"`
+}
+
+// OutlierConfig 无监督离群检测：定期用已存储的指纹训练孤立森林，
+// 对规则未覆盖但在统计上罕见的配置组合给出信号
+type OutlierConfig struct {
+	// Interval 重新训练的间隔，为0时只能通过API手动训练
+	Interval Duration `json:"interval"`
+	// Window 训练样本取该时间窗口内更新过的指纹
+	Window Duration `json:"window"`
+	// MaxSamples 每次训练最多读取的指纹数，超出时随机抽样
+	MaxSamples int `json:"max_samples"`
+	// MinSamples 样本少于该数时不训练，已有模型继续使用
+	MinSamples int `json:"min_samples"`
+	// Trees 树的数量
+	Trees int `json:"trees"`
+	// SampleSize 每棵树抽取的样本数
+	SampleSize int `json:"sample_size"`
+	// Threshold 异常分数（0~1）超过该值时给出信号
+	Threshold float64 `json:"threshold"`
+	// Weight 离群信号的权重
+	Weight float64 `json:"weight"`
+}
+
+// MLConfig 机器学习评分：加载离线训练的ONNX模型，按指纹和检测信号的特征向量给出爬虫概率，
+// 与规则评分按 Weight 加权混合
+type MLConfig struct {
+	// Model ONNX模型文件路径，为空时不启用
+	Model string `json:"model"`
+	// Weight 模型概率在最终评分中的占比（0~1），评分 = (1-Weight)·规则评分 + Weight·模型概率
+	Weight float64 `json:"weight"`
+	// Shadow 为 true 时只记录模型概率，不参与评分，用于上线前评估新模型
+	Shadow bool `json:"shadow"`
+}
+
+// ExpressionNamePattern 评分表达式名称的格式，与内置原因代码一致
+var ExpressionNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// ScoreExpression 评分表达式：按指纹字段和附加数据求值，结果为 true 时给出权重为 Weight 的信号，
+// 结果为数字时以该数字为权重（限制在 -1~1，为0时不给出信号）
+type ScoreExpression struct {
+	// Name 表达式名称，作为信号的原因代码，不能与内置原因代码重名
+	Name string `json:"name"`
+	// Expr 表达式源码，语法见 internal/expr
+	Expr string `json:"expr"`
+	// Weight 布尔结果为 true 时的信号权重（-1~1）
+	Weight float64 `json:"weight"`
+	// Reason 信号的原因说明，为空时使用表达式源码
+	Reason string `json:"reason"`
+	// Sites 只对这些站点的提交求值，为空时对所有站点求值
+	Sites []string `json:"sites"`
+}
+
+// DetectorsConfig 检测器插件：已注册的检测器默认全部启用
+type DetectorsConfig struct {
+	// Disabled 停用的检测器名称
+	Disabled []string `json:"disabled"`
+	// Remote 以HTTP调用的进程外检测器
+	Remote []RemoteDetectorConfig `json:"remote"`
+	// Timeout 进程外检测器的默认调用超时
+	Timeout Duration `json:"timeout"`
+	// BreakerFailures 进程外检测器连续失败该次数后熔断，熔断期间不调用，为0时不熔断
+	BreakerFailures int `json:"breaker_failures"`
+	// BreakerCooldown 熔断后等待该时长再试探调用
+	BreakerCooldown Duration `json:"breaker_cooldown"`
+}
+
+// RemoteDetectorConfig 进程外检测器：每次指纹提交以JSON POST或gRPC调用发送指纹和原始请求，返回附加的检测信号，
+// 用于不便合入本服务的自有检测逻辑
+type RemoteDetectorConfig struct {
+	// Name 检测器名称，不能与编译进服务的检测器重名
+	Name string `json:"name"`
+	// URL 检测器的HTTP(S)地址，或 grpc://host:port（明文）、grpcs://host:port（TLS）形式的gRPC地址
+	URL string `json:"url"`
+	// Timeout 调用超时，为0时使用 detection.detectors.timeout
+	Timeout Duration `json:"timeout"`
+	// Sites 只对这些站点的提交调用，为空时对所有站点调用
+	Sites []string `json:"sites"`
+	// MaxWeight 单次调用返回的信号权重之和的上限（按绝对值），为0时为 0.5
+	MaxWeight float64 `json:"max_weight"`
+	// Headers 附加的请求头（gRPC调用时为元数据），如鉴权令牌
+	Headers map[string]string `json:"headers"`
+}
+
+// remoteDetectorSchemes 进程外检测器地址支持的协议
+var remoteDetectorSchemes = map[string]bool{"http": true, "https": true, "grpc": true, "grpcs": true}
+
+// TypingConfig 打字节奏检测：登录、注册事件可附带接入方采集的按键间隔汇总特征（不含原始按键），
+// 节奏过快或过于均匀视为脚本输入，与账号历史节奏差异过大视为他人在使用该账号
+type TypingConfig struct {
+	// MinKeystrokes 按键数少于该值时不分析，为0时禁用
+	MinKeystrokes int `json:"min_keystrokes"`
+	// MinInterval 平均按键间隔低于该值视为机械节奏
+	MinInterval Duration `json:"min_interval"`
+	// MinVariation 按键间隔的变异系数（标准差/均值）低于该值视为机械节奏
+	MinVariation float64 `json:"min_variation"`
+	// ProfileSamples 账号的节奏档案累计该数量的成功事件后才做一致性检查，为0时不建立档案
+	ProfileSamples int `json:"profile_samples"`
+	// MaxIntervalRatio 平均按键间隔与账号档案之比（较大者比较小者）超过该值视为不一致
+	MaxIntervalRatio float64 `json:"max_interval_ratio"`
+}
+
+// TimingConfig 采集时间校验：采集端回传按自身时钟记录的采集开始和结束时间，
+// 与服务端的接收时间及执行证明种子中签名的签发时间对照，识别预先录制后重放的提交
+type TimingConfig struct {
+	// MinDuration 采集耗时的下限，完整运行采集脚本不可能更快
+	MinDuration Duration `json:"min_duration"`
+	// MaxDuration 采集耗时的上限，为0时禁用
+	MaxDuration Duration `json:"max_duration"`
+	// MaxSkew 采集完成时间与服务端接收时间允许的最大偏差（含采集端时钟误差）
+	MaxSkew Duration `json:"max_skew"`
+	// Latency 允许的网络时延，种子须在采集开始前该时长之后签发
+	Latency Duration `json:"latency"`
+	// Required 为 true 时缺少采集时间的提交同样计分；接入的采集端都已升级后再开启
+	Required bool `json:"required"`
+	// Reject 为 true 时直接拒绝采集时间不合理的提交（400），否则只计分
+	Reject bool `json:"reject"`
+	// Weight collection_timing_invalid 计入爬虫评分的权重
+	Weight float64 `json:"weight"`
+}
+
+// JSProofConfig 采集脚本的执行证明：脚本按服务端签发的种子运行一段计算，结果随指纹提交，
+// 没有执行脚本而直接构造的请求无法给出正确的值
+type JSProofConfig struct {
+	// SeedTTL 种子的有效期，为0时不签发种子也不校验
+	SeedTTL Duration `json:"seed_ttl"`
+	// Required 为 true 时缺少执行证明的提交同样计分；接入的采集端都已升级后再开启
+	Required bool `json:"required"`
+	// Weight js_proof_invalid 计入爬虫评分的权重
+	Weight float64 `json:"weight"`
+}
+
+// CanvasCheckConfig 双Canvas渲染校验：采集端绘制一张固定图案和一张按执行证明种子变化的图案，
+// 固定图案须与声明的浏览器、系统和GPU类别的已知哈希一致，种子图案须随种子变化且同一种子的结果可复现
+type CanvasCheckConfig struct {
+	// Weight canvas_static_mismatch、canvas_seed_invalid 计入爬虫评分的权重，为0时不校验
+	Weight float64 `json:"weight"`
+	// Retention 种子图案哈希的保留时长，用于发现重放到其他种子的结果
+	Retention Duration `json:"retention"`
+	// Baselines 固定图案的已知哈希，补充到基线数据中；没有适用于该浏览器、系统和GPU类别的记录时不校验固定图案
+	Baselines []CanvasBaseline `json:"baselines"`
+}
+
+// CanvasBaseline 某个浏览器家族、操作系统和GPU类别上固定图案的已知哈希（toDataURL 的SHA-256），为空的字段适用于所有值
+type CanvasBaseline struct {
+	Browser string   `json:"browser"`
+	OS      string   `json:"os"`
+	GPU     string   `json:"gpu"`
+	Hashes  []string `json:"hashes"`
+}
+
+// ChallengeConfig 人机验证的结果反馈：记录每次下发的验证及其结果，多次失败的指纹或IP段临时封禁
+type ChallengeConfig struct {
+	// AbandonAfter 下发后超过该时间仍未提交结果的验证视为放弃
+	AbandonAfter Duration `json:"abandon_after"`
+	// Window 统计验证失败次数的时间窗口
+	Window Duration `json:"window"`
+	// MaxFailures 同一指纹或IP段在窗口内验证失败达到该次数时临时封禁，为0时不封禁
+	MaxFailures int `json:"max_failures"`
+	// BlockDuration 自动封禁的时长
+	BlockDuration Duration `json:"block_duration"`
+	// Retention 验证记录的保留期，用于检测质量报告
+	Retention Duration `json:"retention"`
+}
+
+// ConcurrencyConfig 会话并发检测：同一指纹或访客Cookie在短时间内同时出现在多个网段或国家，
+// 说明爬虫把一套身份分发给了多个工作节点
+type ConcurrencyConfig struct {
+	// Window 视为同时活动的时间窗口，为0时禁用
+	Window Duration `json:"window"`
+	// MinSubnets 窗口内出现的不同网段（IPv4 /24，IPv6 /64）达到该数量时判定
+	MinSubnets int `json:"min_subnets"`
+	// MinCountries 窗口内出现的不同国家达到该数量时判定
+	MinCountries int `json:"min_countries"`
+	// Weight concurrent_sessions 计入爬虫评分的权重
+	Weight float64 `json:"weight"`
+	// Alert 判定时发送告警，同一身份在窗口内只告警一次
+	Alert bool `json:"alert"`
+}
+
+// ScrapingConfig 采集爬虫的访问序列检测：按ID或字典序依次遍历同一目录下的页面，
+// 或只访问商品、列表页且不加载任何静态资源
+type ScrapingConfig struct {
+	// Window 分析同一指纹页面浏览序列的时间窗口，为0时禁用
+	Window Duration `json:"window"`
+	// MinPages 窗口内的页面浏览达到该数量才进行分析
+	MinPages int `json:"min_pages"`
+	// ContentPattern 匹配商品、列表等内容页路径的正则
+	ContentPattern string `json:"content_pattern"`
+	// Weight scraping_pattern 计入爬虫评分的权重
+	Weight float64 `json:"weight"`
+}
+
+// NavigationConfig 没有来源直接访问深层页面的检测：正常访客大多从首页、搜索引擎或站内链接进入，
+// 爬虫按URL列表直接请求深层页面
+type NavigationConfig struct {
+	// Window 统计直接访问深层页面次数的时间窗口
+	Window Duration `json:"window"`
+	// MinDirectDeep 同一指纹或同一网段在窗口内直接访问的深层页面达到该数量时记入信号
+	MinDirectDeep int `json:"min_direct_deep"`
+	// Weight direct_deep_hits 计入爬虫评分的权重
+	Weight float64 `json:"weight"`
+	// Retention 导航记录的保留期，用于来源统计
+	Retention Duration `json:"retention"`
+}
+
+// ProxyConfig 住宅代理检测：同一指纹短时间内来自多个住宅ASN、传输层特征与声明的系统或测得的时延不符、WebRTC与HTTP来源IP不一致
+type ProxyConfig struct {
+	// ASNDatabase iptoasn.com 格式的IP段到ASN数据（TSV，可为 .gz），未配置时不检测ASN轮换
+	ASNDatabase string `json:"asn_database"`
+	// HostingASNs 额外视为机房（非住宅）的ASN，ASN名称中包含常见云服务商和托管关键词的也视为机房
+	HostingASNs []int `json:"hosting_asns"`
+	// Window 统计同一指纹住宅ASN数量的时间窗口
+	Window Duration `json:"window"`
+	// MinASNs 时间窗口内的住宅ASN数量达到该值时视为代理轮换
+	MinASNs int `json:"min_asns"`
+	// TTLHeader 反向代理提供的来源连接IP TTL请求头（如 nginx 的 $ip_ttl），为空时不检查TTL
+	TTLHeader string `json:"ttl_header"`
+	// RTTHeader 反向代理提供的来源连接TCP往返时延请求头，单位微秒（如 nginx 的 $tcpinfo_rtt），为空时不检查时延
+	RTTHeader string `json:"rtt_header"`
+	// LatencyGap 浏览器测得的请求往返时延超过TCP往返时延该值（且超过两倍）时视为经过中继
+	LatencyGap Duration `json:"latency_gap"`
+	// Weight proxy_suspected 计入爬虫评分的权重
+	Weight float64 `json:"weight"`
+}
+
+// IPReputationConfig 按IP（IPv6按/64）累计爬虫判定、人机验证失败和情报源命中，按半衰期衰减后作为评分输入
+type IPReputationConfig struct {
+	// HalfLife 历史记录的半衰期，为0时不记录
+	HalfLife Duration `json:"half_life"`
+	// MinScore 信誉评分达到该值时计入爬虫评分
+	MinScore float64 `json:"min_score"`
+	// Weight 信誉评分为1时计入爬虫评分的权重，按信誉评分折算
+	Weight float64 `json:"weight"`
+}
+
+// ThreatIntelConfig IP信誉情报源：定期导入本地数据库，评分时来源IP命中的记录作为信号
+type ThreatIntelConfig struct {
+	// Interval 情报源默认的更新间隔
+	Interval Duration `json:"interval"`
+	// StaleAfter 距上次成功更新超过该时长的情报源视为过期
+	StaleAfter Duration `json:"stale_after"`
+	// Timeout 下载单个情报源的超时
+	Timeout Duration           `json:"timeout"`
+	Feeds   []ThreatFeedConfig `json:"feeds"`
+}
+
+// ThreatFeedConfig 单个情报源
+type ThreatFeedConfig struct {
+	// Name 情报源名称，写入命中记录的来源
+	Name string `json:"name"`
+	// Type spamhaus_drop、firehol 或 abuseipdb
+	Type string `json:"type"`
+	// URL 为空时使用该类型的默认地址（Spamhaus DROP、FireHOL level1、AbuseIPDB blacklist）
+	URL string `json:"url"`
+	// APIKey AbuseIPDB 的API密钥
+	APIKey string `json:"api_key"`
+	// MinConfidence AbuseIPDB 导入的最低可信度（confidenceMinimum），为0时使用接口默认值
+	MinConfidence int `json:"min_confidence"`
+	// Interval 覆盖默认的更新间隔
+	Interval Duration `json:"interval"`
+	// Weight 命中时计入爬虫评分的权重，按记录的可信度折算；为0时使用0.4
+	Weight float64 `json:"weight"`
+}
+
+// UAParserConfig User Agent解析规则
+type UAParserConfig struct {
+	// RegexesPath uap-core 格式的 regexes.yaml 路径，为空时只使用内置的家族正则；
+	// 启动时加载失败则使用内置的精简副本
+	RegexesPath string `json:"regexes_path"`
+}
+
+// BaselinesConfig 已知真实浏览器的指纹组件取值（Canvas固定图案哈希、WebGL参数、音频值），用于在服务端校验提交的组件
+type BaselinesConfig struct {
+	// Path 基线数据文件路径，为空时只使用内置的基线；启动时加载失败则使用内置的基线
+	Path string `json:"path"`
+}
+
+// CredentialStuffingConfig 撞库检测：同一设备或IP段在时间窗口内对大量账号登录失败时临时封禁
+type CredentialStuffingConfig struct {
+	// Window 统计登录失败的时间窗口，为0时禁用
+	Window Duration `json:"window"`
+	// MinFailures 窗口内登录失败次数的阈值
+	MinFailures int `json:"min_failures"`
+	// MinAccounts 窗口内登录失败涉及的不同账号数的阈值
+	MinAccounts int `json:"min_accounts"`
+	// BlockDuration 自动封禁的时长
+	BlockDuration Duration `json:"block_duration"`
+}
+
+// 自动封禁规则的计数和封禁对象
+const (
+	AutoBlockScopeIP          = "ip"
+	AutoBlockScopeIPRange     = "ip_range"
+	AutoBlockScopeFingerprint = "fingerprint"
+	AutoBlockScopeVisitor     = "visitor"
+)
+
+// 自动封禁规则计数的检测结果
+const (
+	// AutoBlockMatchHigh 风险等级为 HIGH 的提交
+	AutoBlockMatchHigh = "high"
+	// AutoBlockMatchBot 判定为爬虫的提交
+	AutoBlockMatchBot = "bot"
+)
+
+// AutoBlockRule 自动封禁规则：同一IP、IP段、指纹或访客在时间窗口内的检测结果达到次数时临时封禁，
+// 如"3次HIGH后封禁该IP 1小时"、"10分钟内50次爬虫提交后封禁该网段"
+type AutoBlockRule struct {
+	// Name 规则名，记入封禁原因和审计记录
+	Name string `json:"name"`
+	// Scope 计数和封禁的对象：ip、ip_range（IPv4 /24，IPv6 /48）、fingerprint 或 visitor（访客Cookie）
+	Scope string `json:"scope"`
+	// Match 计数的检测结果：high 或 bot
+	Match string `json:"match"`
+	// Count 窗口内的次数阈值（包括本次提交）
+	Count int `json:"count"`
+	// Window 计数的时间窗口
+	Window Duration `json:"window"`
+	// Duration 封禁时长
+	Duration Duration `json:"duration"`
+}
+
+// AccountConfig 账号与设备绑定的接管（ATO）检测
+type AccountConfig struct {
+	// ImpossibleTravelWindow 同一账号在该时间内从不同国家提交事件时视为不可能的移动
+	ImpossibleTravelWindow Duration `json:"impossible_travel_window"`
+	// SharedDeviceWindow 统计同一设备登录账号数的时间窗口
+	SharedDeviceWindow Duration `json:"shared_device_window"`
+	// MaxAccountsPerDevice 同一设备在窗口内使用的账号数超过该值时给出信号
+	MaxAccountsPerDevice int `json:"max_accounts_per_device"`
+	// RiskWindow 账号风险报告统计信号的时间窗口
+	RiskWindow Duration `json:"risk_window"`
+}
+
+// CookieConfig 访客Cookie与指纹的关联检测
+type CookieConfig struct {
+	// MismatchRatio 同一Cookie前后两次提交中发生变化的组件比例达到该值时视为不同设备，为0时禁用
+	MismatchRatio float64 `json:"mismatch_ratio"`
+	// CyclingWindow 统计同一指纹使用的Cookie数的时间窗口
+	CyclingWindow Duration `json:"cycling_window"`
+	// MaxCookiesPerFingerprint 同一指纹在窗口内使用的Cookie数超过该值时给出信号，为0时禁用
+	MaxCookiesPerFingerprint int `json:"max_cookies_per_fingerprint"`
+}
+
+// FarmConfig 设备农场检测任务
+type FarmConfig struct {
+	// Interval 检测任务的运行间隔，为0时只能通过API手动触发
+	Interval Duration `json:"interval"`
+	// Window 只分析该时间窗口内出现过的指纹
+	Window Duration `json:"window"`
+	// IPRangeMinFingerprints 同一IP段（IPv4 /24、IPv6 /48）内不同指纹数的阈值
+	IPRangeMinFingerprints int `json:"ip_range_min_fingerprints"`
+	// UARotationMinUserAgents 屏幕、时区、字体完全相同的指纹中不同User Agent数的阈值
+	UARotationMinUserAgents int `json:"ua_rotation_min_user_agents"`
+	// CanvasCloneMinFingerprints 同一Canvas哈希下不同指纹数的阈值
+	CanvasCloneMinFingerprints int `json:"canvas_clone_min_fingerprints"`
+	// CanvasCloneMinPlatforms 同一Canvas哈希下声明的不同浏览器/系统组合数的阈值，
+	// 同型号真实设备的Canvas相同但声明的平台一致
+	CanvasCloneMinPlatforms int `json:"canvas_clone_min_platforms"`
+	// AutoBlock 为true时农场成员直接判定为爬虫
+	AutoBlock bool `json:"auto_block"`
+}
+
+// HashingConfig 哈希算法
+type HashingConfig struct {
+	// SubHashAlgorithm Canvas/WebGL/音频子哈希的算法：sha256（默认）、xxhash 或 blake3；
+	// 主指纹哈希始终使用SHA-256
+	SubHashAlgorithm string `json:"sub_hash_algorithm"`
+}
+
+// ExportConfig 对外发布的聚合统计的差分隐私参数
+type ExportConfig struct {
+	// Epsilon 一次导出的总隐私预算，平均分给总数和4个直方图，拉普拉斯噪声的尺度为 5/Epsilon
+	Epsilon float64 `json:"epsilon"`
+	// MinCount 加噪后计数低于该值的分组不发布，避免暴露罕见设备
+	MinCount int `json:"min_count"`
+	// NoiseSecret 噪声种子密钥：同一分组计数不变时重复导出得到相同的噪声，无法通过多次查询取平均消除；
+	// 为空时每次启动随机生成
+	NoiseSecret string `json:"noise_secret"`
+	// BundleKey 实例间导出包的HMAC-SHA256签名密钥，导出和导入的实例须配置相同的值
+	BundleKey string `json:"bundle_key"`
+}
+
+// EmbeddingConfig 指纹特征向量的近邻索引
+type EmbeddingConfig struct {
+	// IndexPath 索引文件路径，为空时不建立索引
+	IndexPath string `json:"index_path"`
+	// SaveInterval 定期保存索引的间隔，关闭服务时也会保存
+	SaveInterval Duration `json:"save_interval"`
+}
+
+// AnomalyConfig 按站点统计提交量和爬虫比例，与滚动基线比较发现流量异常
+type AnomalyConfig struct {
+	// Bucket 统计桶长度，也是检测任务的运行间隔；为0时禁用
+	Bucket Duration `json:"bucket"`
+	// BaselineBuckets 滚动基线包含的桶数
+	BaselineBuckets int `json:"baseline_buckets"`
+	// SubmissionFactor 提交量超过基线的该倍数时告警
+	SubmissionFactor float64 `json:"submission_factor"`
+	// BotRateFactor 爬虫比例超过基线的该倍数时告警
+	BotRateFactor float64 `json:"bot_rate_factor"`
+	// MinSubmissions 当前桶提交量低于该值时不告警，避免低流量站点的噪声
+	MinSubmissions int `json:"min_submissions"`
+	// Scores 按站点监控爬虫评分分布的漂移
+	Scores ScoreDriftConfig `json:"scores"`
+}
+
+// ScoreDriftConfig 按站点和统计桶记录爬虫评分直方图，与滚动基线比较发现评分分布的偏移，
+// 例如浏览器发布新版本后大量正常访客的评分整体升高
+type ScoreDriftConfig struct {
+	// Bucket 统计桶长度，也是检测任务的运行间隔；为0时不记录评分分布
+	Bucket Duration `json:"bucket"`
+	// BaselineBuckets 滚动基线包含的桶数
+	BaselineBuckets int `json:"baseline_buckets"`
+	// MinSubmissions 当前桶提交量低于该值时不告警
+	MinSubmissions int `json:"min_submissions"`
+	// PSI 群体稳定性指数超过该值时告警
+	PSI float64 `json:"psi"`
+}
+
+// AdminConfig 管理API
+type AdminConfig struct {
+	// Token 管理API的Bearer令牌，为空时禁用管理API
+	Token string `json:"token"`
+}
+
+// AlertingConfig 告警通道，告警始终写入日志
+type AlertingConfig struct {
+	// WebhookURL 配置后告警同时以JSON POST到该地址
+	WebhookURL string `json:"webhook_url"`
+	// Timeout 发送webhook的超时
+	Timeout Duration `json:"timeout"`
+}
+
+// TokenConfig 访客令牌：低风险的提交签发绑定指纹哈希和IP的短期JWT
+type TokenConfig struct {
+	// TTL 令牌有效期，为0时不签发
+	TTL Duration `json:"ttl"`
+}
+
+// StorageConfig 数据库调用的重试与熔断
+type StorageConfig struct {
+	// BusyTimeout SQLite 遇到锁时在驱动内等待的时长
+	BusyTimeout Duration `json:"busy_timeout"`
+	// RetryAttempts 写入遇到锁冲突或连接失效时的最多尝试次数
+	RetryAttempts int `json:"retry_attempts"`
+	// RetryBackoff 首次重试前的等待时长，之后每次加倍
+	RetryBackoff Duration `json:"retry_backoff"`
+	// BreakerFailures 连续多少次存储故障后熔断，为0时不熔断
+	BreakerFailures int `json:"breaker_failures"`
+	// BreakerCooldown 熔断后经过该时长再放行一次探测
+	BreakerCooldown Duration `json:"breaker_cooldown"`
+	// ScoreOnly 存储不可用时仍只按本次提交的无状态规则评分并返回结果，关闭时返回503
+	ScoreOnly bool `json:"score_only"`
+	// BufferPath 存储不可用时缓存提交的文件，存储恢复后重放；为空时不缓存
+	BufferPath string `json:"buffer_path"`
+	// JournalPath 预写日志文件，每次提交处理前写入并落盘，崩溃后用 -replay-journal 重放；为空时不记录
+	JournalPath string `json:"journal_path"`
+	// JournalMaxBytes 预写日志超过该大小时轮转，只保留上一代
+	JournalMaxBytes int64 `json:"journal_max_bytes"`
+	// Shards 指纹和分析结果按哈希前缀分片的SQLite文件数（2、4或8），为0时不分片；分片后不能再修改
+	Shards int `json:"shards"`
+}
+
+// QuarantineConfig 被拒绝的提交的隔离存储，用于分析高频的畸形提交
+type QuarantineConfig struct {
+	// Retention 隔离记录在最后一次出现后的保留时长，为0时不记录
+	Retention Duration `json:"retention"`
+	// MaxBodyBytes 每条记录保存的请求体最大字节数，超出部分截断
+	MaxBodyBytes int `json:"max_body_bytes"`
+}
+
+// EdgeConfig 边缘节点（Cloudflare Workers、Fastly Compute）的决策查询接口 GET /api/edge/decision
+type EdgeConfig struct {
+	// CacheTTL 决策在服务端的缓存时长，同时作为响应 Cache-Control 的 max-age，为0时不提供该接口
+	CacheTTL Duration `json:"cache_ttl"`
+	// StaleTTL 响应 Cache-Control 的 stale-while-revalidate 和 stale-if-error，边缘节点在该时长内可先使用过期决策
+	StaleTTL Duration `json:"stale_ttl"`
+	// MaxEntries 服务端缓存的决策数上限，超出时先清除已过期的决策
+	MaxEntries int `json:"max_entries"`
+	// JA4Header 边缘节点转发的 JA4 TLS 指纹请求头（如 X-JA4），指纹提交时按 JA4 汇总爬虫判定，为空时不按 JA4 决策
+	JA4Header string `json:"ja4_header"`
+	// JA4Window 按 JA4 汇总爬虫判定的时间窗口
+	JA4Window Duration `json:"ja4_window"`
+	// JA4MinSubmissions JA4 在窗口内的提交数达到该值才据此决策
+	JA4MinSubmissions int `json:"ja4_min_submissions"`
+	// JA4ChallengeRatio、JA4DenyRatio 窗口内判定为爬虫的提交比例达到该值时按 JA4 要求人机验证或拒绝，为0时不使用
+	JA4ChallengeRatio float64 `json:"ja4_challenge_ratio"`
+	JA4DenyRatio      float64 `json:"ja4_deny_ratio"`
+	// JA4Retention JA4 汇总的保留期
+	JA4Retention Duration `json:"ja4_retention"`
+}
+
+// ReplicationConfig 多区域部署：每个区域有独立的数据库，定期从其他区域拉取本区域没有的变更
+type ReplicationConfig struct {
+	// Region 本区域名称，为空时不记录也不拉取变更
+	Region string `json:"region"`
+	// Token 其他区域拉取本区域变更时携带的 Bearer 令牌
+	Token string `json:"token"`
+	// Peers 其他区域，每个区域都要列出所有其他区域：变更只从产生它的区域拉取，不转发
+	Peers []ReplicationPeer `json:"peers"`
+	// Interval 拉取的间隔
+	Interval Duration `json:"interval"`
+	// Timeout 每次拉取的超时
+	Timeout Duration `json:"timeout"`
+	// BatchSize 每次拉取的最大变更数
+	BatchSize int `json:"batch_size"`
+	// Retention 变更记录和删除标记的保留期，超过该时长未拉取的区域需要先导入数据包
+	Retention Duration `json:"retention"`
+}
+
+// ReplicationPeer 拉取变更的其他区域
+type ReplicationPeer struct {
+	// Region 对方区域名称
+	Region string `json:"region"`
+	// URL 对方实例的地址，如 https://eu.example.com
+	URL string `json:"url"`
+	// Token 对方的 replication.token
+	Token string `json:"token"`
+}
+
+// ClusterConfig 多个实例共享同一数据库时的部署方式
+type ClusterConfig struct {
+	// Enabled 开启后后台任务（设备农场检测、异常检测、保留期清理、定时备份、近邻索引保存、情报源更新）只在持有租约的实例上执行
+	Enabled bool `json:"enabled"`
+	// InstanceID 实例标识，为空时使用主机名和进程号
+	InstanceID string `json:"instance_id"`
+	// LeaseTTL 租约有效期，持有者每隔三分之一有效期续约，宕机后其他实例最多等待该时长接管
+	LeaseTTL Duration `json:"lease_ttl"`
+	// RefreshInterval 从数据库重新加载站点策略、评分阈值、令牌密钥和IP信誉情报的间隔，使其他实例上的修改生效
+	RefreshInterval Duration `json:"refresh_interval"`
+}
+
+// BackupConfig 数据库备份
+type BackupConfig struct {
+	// Dir 备份文件目录
+	Dir string `json:"dir"`
+	// Interval 定时备份的间隔，为0时只能通过 backup 子命令手动备份
+	Interval Duration `json:"interval"`
+	// Keep 目录中保留的定时备份个数
+	Keep int `json:"keep"`
+	// EncryptionKey 配置后备份文件用由该密钥派生的AES-256-GCM加密
+	EncryptionKey string   `json:"encryption_key"`
+	S3            S3Config `json:"s3"`
+}
+
+// S3Config 备份上传的S3兼容存储，未配置 bucket 时不上传
+type S3Config struct {
+	// Endpoint 为空时使用 https://s3.<region>.amazonaws.com，以路径方式访问 bucket
+	Endpoint        string `json:"endpoint"`
+	Region          string `json:"region"`
+	Bucket          string `json:"bucket"`
+	Prefix          string `json:"prefix"`
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+}
+
+// AudioBaseline 某个JS引擎/操作系统上已知的真实音频指纹值，OS为空表示适用于所有系统
+type AudioBaseline struct {
+	Engine string  `json:"engine"`
+	OS     string  `json:"os"`
+	Sum    float64 `json:"sum"`
+}
+
+// CORSConfig 跨域策略
+// AllowedOrigins 支持精确来源（https://example.com）、通配子域名（https://*.example.com）和 "*"；
+// 同源请求始终允许
+type CORSConfig struct {
+	AllowedOrigins   []string `json:"allowed_origins"`
+	AllowedMethods   []string `json:"allowed_methods"`
+	AllowedHeaders   []string `json:"allowed_headers"`
+	AllowCredentials bool     `json:"allow_credentials"`
+	MaxAge           Duration `json:"max_age"`
+}
+
+// SiteConfig 接入站点配置
+type SiteConfig struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// CORS 站点自己的跨域策略，未配置的方法和请求头沿用全局策略
+	CORS CORSConfig `json:"cors"`
+	// Policy 站点的检测策略
+	Policy SitePolicy `json:"policy"`
+	// APIKeys 站点的API密钥：请求头 X-API-Key 匹配时按该站点处理，优先于按来源匹配
+	APIKeys []string `json:"api_keys"`
+	// HashSecret 指纹哈希的HMAC-SHA256密钥，配置后该站点的指纹哈希无法与其他部署关联；
+	// 更换密钥后需执行 -rehash-site 重新计算已存储记录的哈希
+	HashSecret string `json:"hash_secret"`
+	// RobotsURL 站点的 robots.txt 地址，为空时取 cors.allowed_origins 中第一个不含通配符的源
+	RobotsURL string `json:"robots_url"`
+}
+
+// SitePolicy 站点检测策略
+// 广告拦截和浏览器扩展在真实用户中很常见，默认只参与唯一性评分，不计入爬虫评分
+type SitePolicy struct {
+	// ContentBlockingAffectsRisk 检测到广告拦截时计入爬虫评分
+	ContentBlockingAffectsRisk bool `json:"content_blocking_affects_risk"`
+	// ExtensionsAffectRisk 检测到浏览器扩展时计入爬虫评分
+	ExtensionsAffectRisk bool `json:"extensions_affect_risk"`
+}
+
+// ServerConfig HTTP服务器超时与慢客户端防护
+type ServerConfig struct {
+	ReadTimeout       Duration `json:"read_timeout"`
+	ReadHeaderTimeout Duration `json:"read_header_timeout"`
+	WriteTimeout      Duration `json:"write_timeout"`
+	IdleTimeout       Duration `json:"idle_timeout"`
+	MaxHeaderBytes    int      `json:"max_header_bytes"`
+	// RequestTimeout 单个请求的处理期限，会随context传递到数据库调用
+	RequestTimeout Duration `json:"request_timeout"`
+	// ShutdownTimeout 优雅关闭时等待进行中请求的最长时间
+	ShutdownTimeout Duration `json:"shutdown_timeout"`
+	// CountryHeader 反向代理或CDN提供的访客国家代码请求头（如 CF-IPCountry），为空时不记录国家
+	CountryHeader string `json:"country_header"`
+	// GRPCPort 指纹提交gRPC服务（api/proto/fingerprint_service.proto）的监听端口，为空时不启动
+	GRPCPort string `json:"grpc_port"`
+}
+
+// LimitsConfig 请求体与字段大小限制
+type LimitsConfig struct {
+	// MaxBodyBytes 默认请求体上限（压缩状态下的原始字节数）
+	MaxBodyBytes int64 `json:"max_body_bytes"`
+	// RouteMaxBodyBytes 按路由覆盖请求体上限，键为 "METHOD /path"，如 "POST /api/fingerprint"
+	RouteMaxBodyBytes map[string]int64 `json:"route_max_body_bytes"`
+	// MaxDecompressedBytes 解压后的请求体上限
+	MaxDecompressedBytes int64 `json:"max_decompressed_bytes"`
+	// MaxFieldLengths 字符串字段长度上限，键为JSON字段名
+	MaxFieldLengths map[string]int `json:"max_field_lengths"`
+	// MaxArrayLengths 数组字段元素个数上限，键为JSON字段名
+	MaxArrayLengths map[string]int `json:"max_array_lengths"`
+	// MaxArrayItemLength 数组中单个字符串元素的长度上限
+	MaxArrayItemLength int `json:"max_array_item_length"`
+	// RateLimit 按客户端IP限制 /api 下的请求速率
+	RateLimit RateLimitConfig `json:"rate_limit"`
+}
+
+// RateLimitConfig 按客户端IP的请求速率限制，超出时返回429
+type RateLimitConfig struct {
+	// Requests 每个IP在 window 内允许的请求数，为0时不限制
+	Requests int `json:"requests"`
+	// Window 速率限制的滑动窗口
+	Window Duration `json:"window"`
+	// Exempt 不受限制的IP或CIDR，如负载均衡器的健康检查和内部服务
+	Exempt []string `json:"exempt"`
+}
+
+// CountersConfig 速率限制和速度窗口（网段提交量、自动封禁规则）的计数存储
+type CountersConfig struct {
+	// RedisURL 如 redis://:password@10.0.0.5:6379/0（TLS 用 rediss://），配置后多个实例共享计数；为空时各实例在进程内或本地数据库中计数
+	RedisURL string `json:"redis_url"`
+	// Prefix Redis 键的前缀，多个部署共用一个 Redis 时区分
+	Prefix string `json:"prefix"`
+	// Timeout 每次 Redis 操作的超时
+	Timeout Duration `json:"timeout"`
+	// PoolSize Redis 连接数上限
+	PoolSize int `json:"pool_size"`
+	// BreakerFailures Redis 连续失败该次数后在 breaker_cooldown 内改用进程内计数
+	BreakerFailures int `json:"breaker_failures"`
+	// BreakerCooldown 改用进程内计数的时长，结束后重新尝试 Redis
+	BreakerCooldown Duration `json:"breaker_cooldown"`
+}
+
+// BlocklistConfig 封禁名单的内存缓存：未过期的条目全部保存在内存中，前置布隆过滤器，
+// 决策时不查数据库
+type BlocklistConfig struct {
+	// FalsePositiveRate 过滤器的目标误判率，误判的键需要再查一次内存中的名单；越小占用内存越多
+	FalsePositiveRate float64 `json:"false_positive_rate"`
+	// Refresh 从数据库重新加载名单和过滤器的间隔，其他实例写入的封禁最迟在该时间后生效
+	Refresh Duration `json:"refresh"`
+}
+
+// QueryCacheConfig 统计、排行和时间序列接口（/api/stats/*、/api/export/aggregates）的查询结果缓存：
+// 缓存未超过 ttl 时直接返回；超过 ttl 但未超过 ttl+stale 时先返回旧结果并在后台重新查询，
+// 评分统计桶结束、过期数据清理和数据包导入后缓存的结果按过期处理
+type QueryCacheConfig struct {
+	// TTL 结果直接使用的时长，为0时不缓存
+	TTL Duration `json:"ttl"`
+	// Stale 超过 ttl 后仍可先返回旧结果的时长，后台查询失败时也继续返回旧结果
+	Stale Duration `json:"stale"`
+	// MaxEntries 缓存的结果数上限（不同的查询参数分别缓存）
+	MaxEntries int `json:"max_entries"`
+}
+
+// Default 返回默认配置
+func Default() *Config {
+	return &Config{
+		Port:         "8080",
+		DatabasePath: "fingerprints.db",
+		LogLevel:     "info",
+		EnableCORS:   true,
+		Server: ServerConfig{
+			ReadTimeout:       Duration(15 * time.Second),
+			ReadHeaderTimeout: Duration(5 * time.Second),
+			WriteTimeout:      Duration(30 * time.Second),
+			IdleTimeout:       Duration(60 * time.Second),
+			MaxHeaderBytes:    64 << 10,
+			RequestTimeout:    Duration(10 * time.Second),
+			ShutdownTimeout:   Duration(15 * time.Second),
+		},
+		CORS: CORSConfig{
+			AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+			AllowedHeaders: []string{"Content-Type", "Content-Length", "Content-Encoding", "Accept-Encoding", "Authorization", "X-Requested-With", "X-API-Key"},
+			MaxAge:         Duration(10 * time.Minute),
+		},
+		Limits: LimitsConfig{
+			MaxBodyBytes: 1 << 20,
+			RouteMaxBodyBytes: map[string]int64{
+				"POST /api/fingerprint":               2 << 20,
+				"POST /api/fingerprint/fingerprintjs": 2 << 20,
+				"POST /api/access-logs":               8 << 20,
+			},
+			MaxDecompressedBytes: 8 << 20,
+			MaxFieldLengths: map[string]int{
+				"fingerprint_hash":  128,
+				"user_agent":        1024,
+				"screen_resolution": 32,
+				"timezone":          64,
+				"language":          64,
+				"platform":          64,
+				"canvas":            256 << 10,
+				"webgl":             64 << 10,
+				"audio":             32 << 10,
+				"do_not_track":      32,
+			},
+			MaxArrayLengths: map[string]int{
+				"fonts":             500,
+				"plugins":           100,
+				"webrtc_local_ips":  20,
+				"webrtc_public_ips": 20,
+				"math":              64,
+				"font_metrics":      64,
+				"extensions":        32,
+				"webgl_extensions":  128,
+				"webgl_params":      64,
+				"audio_samples":     4,
+				"audio_samples_run": 1000,
+			},
+			MaxArrayItemLength: 256,
+			RateLimit: RateLimitConfig{
+				Window: Duration(time.Minute),
+			},
+		},
+		Detection: DetectionConfig{
+			NoiseMode: NoiseModeTrust,
+			Farms: FarmConfig{
+				Interval:                   Duration(10 * time.Minute),
+				Window:                     Duration(24 * time.Hour),
+				IPRangeMinFingerprints:     20,
+				UARotationMinUserAgents:    5,
+				CanvasCloneMinFingerprints: 100,
+				CanvasCloneMinPlatforms:    3,
+			},
+			Accounts: AccountConfig{
+				ImpossibleTravelWindow: Duration(time.Hour),
+				SharedDeviceWindow:     Duration(24 * time.Hour),
+				MaxAccountsPerDevice:   3,
+				RiskWindow:             Duration(7 * 24 * time.Hour),
+			},
+			ScoreHalfLife: Duration(30 * 24 * time.Hour),
+			IPReputation: IPReputationConfig{
+				HalfLife: Duration(7 * 24 * time.Hour),
+				MinScore: 0.3,
+				Weight:   0.3,
+			},
+			Proxy: ProxyConfig{
+				Window:     Duration(time.Hour),
+				MinASNs:    3,
+				LatencyGap: Duration(80 * time.Millisecond),
+				Weight:     0.4,
+			},
+			Navigation: NavigationConfig{
+				Window:        Duration(time.Hour),
+				MinDirectDeep: 20,
+				Weight:        0.3,
+				Retention:     Duration(30 * 24 * time.Hour),
+			},
+			Scraping: ScrapingConfig{
+				Window:         Duration(time.Hour),
+				MinPages:       20,
+				ContentPattern: `(?i)/(products?|items?|p|dp|goods|listings?|category|categories|catalog|collections?|search|shop)(/|$)`,
+				Weight:         0.5,
+			},
+			Concurrency: ConcurrencyConfig{
+				Window:       Duration(5 * time.Minute),
+				MinSubnets:   3,
+				MinCountries: 2,
+				Weight:       0.7,
+				Alert:        true,
+			},
+			Challenges: ChallengeConfig{
+				AbandonAfter:  Duration(10 * time.Minute),
+				Window:        Duration(time.Hour),
+				MaxFailures:   3,
+				BlockDuration: Duration(time.Hour),
+				Retention:     Duration(30 * 24 * time.Hour),
+			},
+			JSProof: JSProofConfig{
+				SeedTTL: Duration(10 * time.Minute),
+				Weight:  0.9,
+			},
+			CanvasCheck: CanvasCheckConfig{
+				Weight:    0.4,
+				Retention: Duration(24 * time.Hour),
+			},
+			Typing: TypingConfig{
+				MinKeystrokes:    8,
+				MinInterval:      Duration(30 * time.Millisecond),
+				MinVariation:     0.1,
+				ProfileSamples:   3,
+				MaxIntervalRatio: 2,
+			},
+			Timing: TimingConfig{
+				MinDuration: Duration(50 * time.Millisecond),
+				MaxDuration: Duration(time.Minute),
+				MaxSkew:     Duration(5 * time.Minute),
+				Latency:     Duration(10 * time.Second),
+				Weight:      0.8,
+			},
+			ThreatIntel: ThreatIntelConfig{
+				Interval:   Duration(6 * time.Hour),
+				StaleAfter: Duration(48 * time.Hour),
+				Timeout:    Duration(20 * time.Second),
+			},
+			Cookies: CookieConfig{
+				MismatchRatio:            0.6,
+				CyclingWindow:            Duration(24 * time.Hour),
+				MaxCookiesPerFingerprint: 100,
+			},
+			CredentialStuffing: CredentialStuffingConfig{
+				Window:        Duration(10 * time.Minute),
+				MinFailures:   20,
+				MinAccounts:   10,
+				BlockDuration: Duration(time.Hour),
+			},
+			Detectors: DetectorsConfig{
+				Timeout:         Duration(300 * time.Millisecond),
+				BreakerFailures: 5,
+				BreakerCooldown: Duration(30 * time.Second),
+			},
+			ML: MLConfig{
+				Weight: 0.3,
+			},
+			Outliers: OutlierConfig{
+				Interval:   Duration(time.Hour),
+				Window:     Duration(30 * 24 * time.Hour),
+				MaxSamples: 20000,
+				MinSamples: 500,
+				Trees:      100,
+				SampleSize: 256,
+				Threshold:  0.65,
+				Weight:     0.15,
+			},
+			BrowserReleases: BrowserReleasesConfig{
+				Interval:      Duration(24 * time.Hour),
+				Timeout:       Duration(30 * time.Second),
+				FutureMajors:  3,
+				OutdatedAfter: Duration(90 * 24 * time.Hour),
+				ObsoleteAfter: Duration(5 * 365 * 24 * time.Hour),
+				Weight:        0.4,
+			},
+			Crawlers: CrawlersConfig{
+				AIWeight: 0.5,
+				Weight:   0.3,
+			},
+			Robots: RobotsConfig{
+				TTL:       Duration(24 * time.Hour),
+				Timeout:   Duration(10 * time.Second),
+				Retention: Duration(30 * 24 * time.Hour),
+			},
+			AccessLogs: AccessLogsConfig{
+				Window:          Duration(10 * time.Minute),
+				PollInterval:    Duration(time.Second),
+				MinPages:        3,
+				MaxPageRate:     30,
+				NoJSWeight:      0.5,
+				NoAssetsWeight:  0.2,
+				RateWeight:      0.2,
+				ErrorWeight:     0.1,
+				CDNBotThreshold: 29,
+				CDNWeight:       0.3,
+				Retention:       Duration(30 * 24 * time.Hour),
+			},
+		},
+		Hashing: HashingConfig{
+			SubHashAlgorithm: "sha256",
+		},
+		Export: ExportConfig{
+			Epsilon:  1.0,
+			MinCount: 10,
+		},
+		Embedding: EmbeddingConfig{
+			IndexPath:    "fingerprints.hnsw",
+			SaveInterval: Duration(5 * time.Minute),
+		},
+		Anomaly: AnomalyConfig{
+			Bucket:           Duration(5 * time.Minute),
+			BaselineBuckets:  288,
+			SubmissionFactor: 3,
+			BotRateFactor:    2,
+			MinSubmissions:   20,
+			Scores: ScoreDriftConfig{
+				Bucket:          Duration(time.Hour),
+				BaselineBuckets: 168,
+				MinSubmissions:  100,
+				PSI:             0.2,
+			},
+		},
+		Alerting: AlertingConfig{
+			Timeout: Duration(5 * time.Second),
+		},
+		Tokens: TokenConfig{
+			TTL: Duration(15 * time.Minute),
+		},
+		Storage: StorageConfig{
+			BusyTimeout:     Duration(5 * time.Second),
+			RetryAttempts:   3,
+			RetryBackoff:    Duration(50 * time.Millisecond),
+			BreakerFailures: 5,
+			BreakerCooldown: Duration(30 * time.Second),
+			ScoreOnly:       true,
+			BufferPath:      "degraded.jsonl",
+			JournalMaxBytes: 64 << 20,
+		},
+		Quarantine: QuarantineConfig{
+			Retention:    Duration(7 * 24 * time.Hour),
+			MaxBodyBytes: 16 << 10,
+		},
+		Edge: EdgeConfig{
+			CacheTTL:          Duration(30 * time.Second),
+			StaleTTL:          Duration(5 * time.Minute),
+			MaxEntries:        100000,
+			JA4Window:         Duration(24 * time.Hour),
+			JA4MinSubmissions: 20,
+			JA4ChallengeRatio: 0.8,
+			JA4DenyRatio:      0.95,
+			JA4Retention:      Duration(7 * 24 * time.Hour),
+		},
+		Counters: CountersConfig{
+			Prefix:          "bd:",
+			Timeout:         Duration(200 * time.Millisecond),
+			PoolSize:        16,
+			BreakerFailures: 3,
+			BreakerCooldown: Duration(10 * time.Second),
+		},
+		QueryCache: QueryCacheConfig{
+			TTL:        Duration(30 * time.Second),
+			Stale:      Duration(5 * time.Minute),
+			MaxEntries: 1000,
+		},
+		Blocklist: BlocklistConfig{
+			FalsePositiveRate: 0.01,
+			Refresh:           Duration(10 * time.Second),
+		},
+		Replication: ReplicationConfig{
+			Interval:  Duration(10 * time.Second),
+			Timeout:   Duration(10 * time.Second),
+			BatchSize: 1000,
+			Retention: Duration(7 * 24 * time.Hour),
+		},
+		Cluster: ClusterConfig{
+			LeaseTTL:        Duration(30 * time.Second),
+			RefreshInterval: Duration(30 * time.Second),
+		},
+		Backup: BackupConfig{
+			Dir:  "backups",
+			Keep: 7,
+			S3: S3Config{
+				Region: "us-east-1",
+			},
+		},
+	}
+}
+
+// Load 加载配置
+func Load() (*Config, error) {
+	cfg := Default()
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err := json.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		}
+	}
+
+	if port := os.Getenv("PORT"); port != "" {
+		cfg.Port = port
+	}
+	if grpcPort := os.Getenv("GRPC_PORT"); grpcPort != "" {
+		cfg.Server.GRPCPort = grpcPort
+	}
+	if dbPath := os.Getenv("DATABASE_PATH"); dbPath != "" {
+		cfg.DatabasePath = dbPath
+	}
+	if readPath := os.Getenv("DATABASE_READ_PATH"); readPath != "" {
+		cfg.DatabaseReadPath = readPath
+	}
+	if redisURL := os.Getenv("REDIS_URL"); redisURL != "" {
+		cfg.Counters.RedisURL = redisURL
+	}
+	if key := os.Getenv("BUNDLE_KEY"); key != "" {
+		cfg.Export.BundleKey = key
+	}
+	if key := os.Getenv("BACKUP_ENCRYPTION_KEY"); key != "" {
+		cfg.Backup.EncryptionKey = key
+	}
+	if id, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"); id != "" && secret != "" {
+		cfg.Backup.S3.AccessKeyID = id
+		cfg.Backup.S3.SecretAccessKey = secret
+	}
+
+	switch cfg.Detection.NoiseMode {
+	case NoiseModeTrust, NoiseModeVerify:
+	default:
+		return nil, fmt.Errorf("invalid detection.noise_mode %q", cfg.Detection.NoiseMode)
+	}
+
+	switch cfg.Hashing.SubHashAlgorithm {
+	case "sha256", "xxhash", "blake3":
+	default:
+		return nil, fmt.Errorf("invalid hashing.sub_hash_algorithm %q", cfg.Hashing.SubHashAlgorithm)
+	}
+
+	if cfg.Export.Epsilon <= 0 {
+		return nil, fmt.Errorf("invalid export.epsilon %v: must be positive", cfg.Export.Epsilon)
+	}
+
+	apiKeys := make(map[string]string)
+	siteIDs := make(map[string]bool, len(cfg.Sites))
+	for _, site := range cfg.Sites {
+		siteIDs[site.ID] = true
+		for _, key := range site.APIKeys {
+			if owner, ok := apiKeys[key]; ok {
+				return nil, fmt.Errorf("duplicate api key in sites %q and %q", owner, site.ID)
+			}
+			apiKeys[key] = site.ID
+		}
+		if site.RobotsURL != "" {
+			if u, err := url.Parse(site.RobotsURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, fmt.Errorf("invalid sites %q robots_url %q: must be an http(s) URL", site.ID, site.RobotsURL)
+			}
+		}
+	}
+
+	if r := cfg.Detection.Cookies.MismatchRatio; r < 0 || r > 1 {
+		return nil, fmt.Errorf("invalid detection.cookies.mismatch_ratio %v: must be within [0, 1]", r)
+	}
+
+	if stuffing := cfg.Detection.CredentialStuffing; stuffing.Window > 0 {
+		if stuffing.MinFailures < 1 || stuffing.MinAccounts < 1 {
+			return nil, fmt.Errorf("invalid detection.credential_stuffing thresholds: must be positive")
+		}
+		if stuffing.BlockDuration <= 0 {
+			return nil, fmt.Errorf("invalid detection.credential_stuffing.block_duration: must be positive")
+		}
+	}
+
+	detectors := &cfg.Detection.Detectors
+	remoteNames := make(map[string]bool, len(detectors.Remote))
+	for i := range detectors.Remote {
+		remote := &detectors.Remote[i]
+		if remote.Name == "" || remoteNames[remote.Name] {
+			return nil, fmt.Errorf("invalid detection.detectors.remote[%d].name %q: must be unique and non-empty", i, remote.Name)
+		}
+		remoteNames[remote.Name] = true
+		if u, err := url.Parse(remote.URL); err != nil || !remoteDetectorSchemes[u.Scheme] || u.Host == "" {
+			return nil, fmt.Errorf("invalid remote detector %q url %q: must be an http(s) or grpc(s) URL", remote.Name, remote.URL)
+		}
+		if remote.MaxWeight < 0 || remote.MaxWeight > 1 {
+			return nil, fmt.Errorf("invalid remote detector %q max_weight %v: must be within [0, 1]", remote.Name, remote.MaxWeight)
+		}
+		if remote.MaxWeight == 0 {
+			remote.MaxWeight = 0.5
+		}
+		if remote.Timeout <= 0 {
+			remote.Timeout = detectors.Timeout
+		}
+		if remote.Timeout <= 0 {
+			return nil, fmt.Errorf("invalid remote detector %q timeout: must be positive", remote.Name)
+		}
+	}
+
+	builtinCodes := make(map[string]bool, len(models.ReasonCodes))
+	for _, code := range models.ReasonCodes {
+		builtinCodes[code] = true
+	}
+	exprNames := make(map[string]bool, len(cfg.Detection.Expressions))
+	for i, e := range cfg.Detection.Expressions {
+		if !ExpressionNamePattern.MatchString(e.Name) || exprNames[e.Name] || builtinCodes[e.Name] {
+			return nil, fmt.Errorf("invalid detection.expressions[%d].name %q: must match %s, be unique and differ from built-in reason codes", i, e.Name, ExpressionNamePattern)
+		}
+		exprNames[e.Name] = true
+		if _, err := expr.Compile(e.Expr); err != nil {
+			return nil, fmt.Errorf("invalid expression %q: %w", e.Name, err)
+		}
+		if e.Weight < -1 || e.Weight > 1 {
+			return nil, fmt.Errorf("invalid expression %q weight %v: must be within [-1, 1]", e.Name, e.Weight)
+		}
+	}
+
+	if cfg.Detection.ML.Weight < 0 || cfg.Detection.ML.Weight > 1 {
+		return nil, fmt.Errorf("invalid detection.ml.weight %v: must be within [0, 1]", cfg.Detection.ML.Weight)
+	}
+	if outliers := cfg.Detection.Outliers; outliers.Window <= 0 || outliers.Trees < 1 || outliers.SampleSize < 2 ||
+		outliers.MinSamples < 2 || outliers.MaxSamples < outliers.MinSamples {
+		return nil, fmt.Errorf("invalid detection.outliers: window and trees must be positive, sample_size and min_samples at least 2, max_samples at least min_samples")
+	}
+	if outliers := cfg.Detection.Outliers; outliers.Threshold <= 0 || outliers.Threshold >= 1 || outliers.Weight < 0 || outliers.Weight > 1 {
+		return nil, fmt.Errorf("invalid detection.outliers: threshold must be within (0, 1) and weight within [0, 1]")
+	}
+	if r := cfg.Detection.BrowserReleases; r.FutureMajors < 0 || r.OutdatedAfter <= 0 || r.ObsoleteAfter < r.OutdatedAfter ||
+		r.Weight < 0 || r.Weight > 1 || (r.Feed != "" && (r.Interval <= 0 || r.Timeout <= 0)) {
+		return nil, fmt.Errorf("invalid detection.browser_releases: future_majors must not be negative, outdated_after positive, obsolete_after at least outdated_after, weight within [0, 1], and interval and timeout positive when feed is set")
+	}
+	if c := cfg.Detection.Crawlers; c.AIWeight < 0 || c.AIWeight > 1 || c.Weight < 0 || c.Weight > 1 {
+		return nil, fmt.Errorf("invalid detection.crawlers: ai_weight and weight must be within [0, 1]")
+	}
+	if r := cfg.Detection.Robots; r.TTL > 0 && (r.Timeout <= 0 || r.Retention <= 0) {
+		return nil, fmt.Errorf("invalid detection.robots: timeout and retention must be positive when ttl is set")
+	}
+	if a := cfg.Detection.AccessLogs; a.Window > 0 {
+		if a.MinPages < 1 || a.MaxPageRate <= 0 || a.PollInterval <= 0 || a.Retention < a.Window {
+			return nil, fmt.Errorf("invalid detection.access_logs: min_pages must be at least 1, max_page_rate and poll_interval positive and retention at least window")
+		}
+		if a.CDNBotThreshold < 1 || a.CDNBotThreshold > 99 {
+			return nil, fmt.Errorf("invalid detection.access_logs.cdn_bot_threshold %d: must be within [1, 99]", a.CDNBotThreshold)
+		}
+		for _, w := range []float64{a.NoJSWeight, a.NoAssetsWeight, a.RateWeight, a.ErrorWeight, a.CDNWeight} {
+			if w < 0 || w > 1 {
+				return nil, fmt.Errorf("invalid detection.access_logs: weights must be within [0, 1]")
+			}
+		}
+		for i, f := range a.Files {
+			if f.Path == "" || !utils.ValidAccessLogFormat(f.Format) {
+				return nil, fmt.Errorf("invalid detection.access_logs.files[%d]: path is required and format must be nginx, caddy, cloudflare or alb", i)
+			}
+			if !siteIDs[f.SiteID] {
+				return nil, fmt.Errorf("invalid detection.access_logs.files[%d].site_id %q: unknown site", i, f.SiteID)
+			}
+		}
+	}
+
+	ruleNames := make(map[string]bool, len(cfg.Detection.AutoBlock))
+	for i, rule := range cfg.Detection.AutoBlock {
+		if rule.Name == "" || ruleNames[rule.Name] {
+			return nil, fmt.Errorf("invalid detection.auto_block[%d].name %q: must be non-empty and unique", i, rule.Name)
+		}
+		ruleNames[rule.Name] = true
+		switch rule.Scope {
+		case AutoBlockScopeIP, AutoBlockScopeIPRange, AutoBlockScopeFingerprint, AutoBlockScopeVisitor:
+		default:
+			return nil, fmt.Errorf("invalid detection.auto_block[%d].scope %q: must be ip, ip_range, fingerprint or visitor", i, rule.Scope)
+		}
+		if rule.Match != AutoBlockMatchHigh && rule.Match != AutoBlockMatchBot {
+			return nil, fmt.Errorf("invalid detection.auto_block[%d].match %q: must be high or bot", i, rule.Match)
+		}
+		if rule.Count < 1 || rule.Window <= 0 || rule.Duration <= 0 {
+			return nil, fmt.Errorf("invalid detection.auto_block[%d]: count, window and duration must be positive", i)
+		}
+	}
+
+	if cfg.Backup.Interval > 0 && cfg.Backup.Keep < 1 {
+		return nil, fmt.Errorf("invalid backup.keep %d: must be positive", cfg.Backup.Keep)
+	}
+
+	if cfg.Storage.RetryAttempts < 1 {
+		return nil, fmt.Errorf("invalid storage.retry_attempts %d: must be positive", cfg.Storage.RetryAttempts)
+	}
+	if cfg.Storage.BreakerFailures > 0 && cfg.Storage.BreakerCooldown <= 0 {
+		return nil, fmt.Errorf("invalid storage.breaker_cooldown: must be positive")
+	}
+	switch cfg.Storage.Shards {
+	case 0, 2, 4, 8:
+	default:
+		return nil, fmt.Errorf("invalid storage.shards %d: must be 0, 2, 4 or 8", cfg.Storage.Shards)
+	}
+	if cfg.Storage.Shards > 0 && cfg.DatabaseReadPath != "" {
+		return nil, fmt.Errorf("storage.shards cannot be combined with database_read_path")
+	}
+	if cfg.Quarantine.Retention > 0 && cfg.Quarantine.MaxBodyBytes < 0 {
+		return nil, fmt.Errorf("invalid quarantine.max_body_bytes %d: must not be negative", cfg.Quarantine.MaxBodyBytes)
+	}
+
+	if e := cfg.Edge; e.CacheTTL > 0 {
+		if e.StaleTTL < 0 || e.MaxEntries < 1 {
+			return nil, fmt.Errorf("invalid edge: stale_ttl must not be negative and max_entries must be positive")
+		}
+		if e.JA4Header != "" {
+			if e.JA4Window <= 0 || e.JA4MinSubmissions < 1 || e.JA4Retention < e.JA4Window {
+				return nil, fmt.Errorf("invalid edge: ja4_window and ja4_min_submissions must be positive and ja4_retention at least ja4_window")
+			}
+			for _, r := range []float64{e.JA4ChallengeRatio, e.JA4DenyRatio} {
+				if r < 0 || r > 1 {
+					return nil, fmt.Errorf("invalid edge: ja4_challenge_ratio and ja4_deny_ratio must be within [0, 1]")
+				}
+			}
+		}
+	}
+
+	if rl := cfg.Limits.RateLimit; rl.Requests > 0 {
+		if rl.Window <= 0 {
+			return nil, fmt.Errorf("invalid limits.rate_limit.window: must be positive")
+		}
+		for _, entry := range rl.Exempt {
+			if _, err := netip.ParseAddr(entry); err == nil {
+				continue
+			}
+			if _, err := netip.ParsePrefix(entry); err != nil {
+				return nil, fmt.Errorf("invalid limits.rate_limit.exempt entry %q: must be an IP or CIDR", entry)
+			}
+		}
+	}
+	if c := cfg.Counters; c.RedisURL != "" {
+		if u, err := url.Parse(c.RedisURL); err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") || u.Hostname() == "" {
+			return nil, fmt.Errorf("invalid counters.redis_url: must be redis://host:port or rediss://host:port")
+		}
+		if c.Timeout <= 0 || c.PoolSize < 1 || c.BreakerFailures < 1 || c.BreakerCooldown <= 0 {
+			return nil, fmt.Errorf("invalid counters: timeout, pool_size, breaker_failures and breaker_cooldown must be positive")
+		}
+	}
+
+	if b := cfg.Blocklist; b.FalsePositiveRate <= 0 || b.FalsePositiveRate >= 1 || b.Refresh <= 0 {
+		return nil, fmt.Errorf("invalid blocklist: false_positive_rate must be between 0 and 1 and refresh must be positive")
+	}
+
+	if q := cfg.QueryCache; q.TTL < 0 || q.Stale < 0 || (q.TTL > 0 && q.MaxEntries < 1) {
+		return nil, fmt.Errorf("invalid query_cache: ttl and stale must not be negative and max_entries must be positive")
+	}
+
+	if r := cfg.Replication; r.Region != "" {
+		if r.Token == "" || r.Interval <= 0 || r.Timeout <= 0 || r.BatchSize < 1 || r.Retention <= 0 {
+			return nil, fmt.Errorf("invalid replication: token must be set and interval, timeout, batch_size and retention must be positive")
+		}
+		seen := map[string]bool{r.Region: true}
+		for _, peer := range r.Peers {
+			if peer.Region == "" || seen[peer.Region] {
+				return nil, fmt.Errorf("invalid replication peer %q: region must be set and unique", peer.Region)
+			}
+			seen[peer.Region] = true
+			if u, err := url.Parse(peer.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, fmt.Errorf("invalid replication peer %q url %q", peer.Region, peer.URL)
+			}
+			if peer.Token == "" {
+				return nil, fmt.Errorf("invalid replication peer %q: token must be set", peer.Region)
+			}
+		}
+	}
+
+	if cfg.Cluster.Enabled && (cfg.Cluster.LeaseTTL <= 0 || cfg.Cluster.RefreshInterval <= 0) {
+		return nil, fmt.Errorf("invalid cluster.lease_ttl or cluster.refresh_interval: must be positive")
+	}
+
+	if rep := cfg.Detection.IPReputation; rep.HalfLife > 0 {
+		if rep.MinScore < 0 || rep.MinScore > 1 || rep.Weight < 0 || rep.Weight > 1 {
+			return nil, fmt.Errorf("invalid detection.ip_reputation min_score or weight: must be within [0, 1]")
+		}
+	}
+
+	if p := cfg.Detection.Proxy; p.Window <= 0 || p.MinASNs < 2 || p.Weight < 0 || p.Weight > 1 {
+		return nil, fmt.Errorf("invalid detection.proxy: window must be positive, min_asns at least 2 and weight within [0, 1]")
+	}
+
+	if n := cfg.Detection.Navigation; n.Window <= 0 || n.Retention < n.Window || n.MinDirectDeep < 1 || n.Weight < 0 || n.Weight > 1 {
+		return nil, fmt.Errorf("invalid detection.navigation: window must be positive, retention at least window, min_direct_deep at least 1 and weight within [0, 1]")
+	}
+
+	if s := cfg.Detection.Scraping; s.Window > 0 {
+		if s.MinPages < 3 || s.Weight < 0 || s.Weight > 1 {
+			return nil, fmt.Errorf("invalid detection.scraping: min_pages must be at least 3 and weight within [0, 1]")
+		}
+		if s.ContentPattern == "" {
+			return nil, fmt.Errorf("invalid detection.scraping.content_pattern: must not be empty")
+		}
+	}
+	if c := cfg.Detection.Concurrency; c.Window > 0 && (c.MinSubnets < 2 || c.MinCountries < 2 || c.Weight < 0 || c.Weight > 1) {
+		return nil, fmt.Errorf("invalid detection.concurrency: min_subnets and min_countries must be at least 2 and weight within [0, 1]")
+	}
+
+	if ch := cfg.Detection.Challenges; ch.AbandonAfter <= 0 || ch.Window <= 0 || ch.Retention < ch.AbandonAfter || ch.MaxFailures < 0 {
+		return nil, fmt.Errorf("invalid detection.challenges: abandon_after and window must be positive, retention at least abandon_after and max_failures not negative")
+	} else if ch.MaxFailures > 0 && ch.BlockDuration <= 0 {
+		return nil, fmt.Errorf("invalid detection.challenges.block_duration: must be positive")
+	}
+
+	if p := cfg.Detection.JSProof; p.SeedTTL < 0 || p.Weight < 0 || p.Weight > 1 {
+		return nil, fmt.Errorf("invalid detection.js_proof: seed_ttl must not be negative and weight within [0, 1]")
+	}
+
+	if cc := cfg.Detection.CanvasCheck; cc.Weight < 0 || cc.Weight > 1 || (cc.Weight > 0 && cc.Retention <= 0) {
+		return nil, fmt.Errorf("invalid detection.canvas_check: weight must be within [0, 1] and retention positive")
+	}
+	for i, b := range cfg.Detection.CanvasCheck.Baselines {
+		if len(b.Hashes) == 0 {
+			return nil, fmt.Errorf("invalid detection.canvas_check.baselines[%d]: hashes must not be empty", i)
+		}
+	}
+
+	if t := cfg.Detection.Timing; t.MinDuration < 0 || t.MaxDuration < 0 || t.MaxSkew < 0 || t.Latency < 0 || t.Weight < 0 || t.Weight > 1 {
+		return nil, fmt.Errorf("invalid detection.timing: durations must not be negative and weight within [0, 1]")
+	}
+	if t := cfg.Detection.Timing; t.MaxDuration > 0 && (t.MinDuration >= t.MaxDuration || t.MaxSkew <= 0) {
+		return nil, fmt.Errorf("invalid detection.timing: min_duration must be below max_duration and max_skew positive")
+	}
+
+	if t := cfg.Detection.Typing; t.MinKeystrokes < 0 || t.MinInterval < 0 || t.MinVariation < 0 || t.ProfileSamples < 0 ||
+		t.ProfileSamples > 0 && t.MaxIntervalRatio <= 1 {
+		return nil, fmt.Errorf("invalid detection.typing: values must not be negative and max_interval_ratio above 1")
+	}
+
+	if _, err := regexp.Compile(cfg.Detection.Scraping.ContentPattern); err != nil {
+		return nil, fmt.Errorf("invalid detection.scraping.content_pattern: %w", err)
+	}
+
+	feedNames := make(map[string]bool)
+	for i := range cfg.Detection.ThreatIntel.Feeds {
+		feed := &cfg.Detection.ThreatIntel.Feeds[i]
+		if feed.Name == "" || feedNames[feed.Name] {
+			return nil, fmt.Errorf("invalid detection.threat_intel.feeds[%d].name %q: must be unique and non-empty", i, feed.Name)
+		}
+		feedNames[feed.Name] = true
+		switch feed.Type {
+		case "spamhaus_drop", "firehol":
+		case "abuseipdb":
+			if feed.APIKey == "" {
+				return nil, fmt.Errorf("invalid threat feed %q: api_key is required for abuseipdb", feed.Name)
+			}
+		default:
+			return nil, fmt.Errorf("invalid threat feed %q type %q", feed.Name, feed.Type)
+		}
+		if feed.Weight < 0 || feed.Weight > 1 {
+			return nil, fmt.Errorf("invalid threat feed %q weight %v: must be within [0, 1]", feed.Name, feed.Weight)
+		}
+		if feed.Weight == 0 {
+			feed.Weight = 0.4
+		}
+		if feed.Interval <= 0 {
+			feed.Interval = cfg.Detection.ThreatIntel.Interval
+		}
+	}
+	if len(feedNames) > 0 && cfg.Detection.ThreatIntel.Interval <= 0 {
+		return nil, fmt.Errorf("invalid detection.threat_intel.interval: must be positive")
+	}
+
+	if cfg.Anomaly.Bucket > 0 {
+		if cfg.Anomaly.BaselineBuckets < 1 {
+			return nil, fmt.Errorf("invalid anomaly.baseline_buckets %d: must be positive", cfg.Anomaly.BaselineBuckets)
+		}
+		if cfg.Anomaly.SubmissionFactor <= 1 || cfg.Anomaly.BotRateFactor <= 1 {
+			return nil, fmt.Errorf("invalid anomaly factors: must be greater than 1")
+		}
+	}
+	if scores := cfg.Anomaly.Scores; scores.Bucket > 0 && (scores.BaselineBuckets < 1 || scores.PSI <= 0) {
+		return nil, fmt.Errorf("invalid anomaly.scores: baseline_buckets and psi must be positive")
+	}
+
+	return cfg, nil
+}
+
+// MaxBodyFor 返回指定路由的请求体上限
+func (l LimitsConfig) MaxBodyFor(method, path string) int64 {
+	if max, ok := l.RouteMaxBodyBytes[method+" "+path]; ok {
+		return max
+	}
+	return l.MaxBodyBytes
+}
+
+// Duration 支持在JSON中以 "10s"、"1m30s" 形式书写的时长
+type Duration time.Duration
+
+// UnmarshalJSON 解析字符串时长，数字按秒处理
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch value := v.(type) {
+	case float64:
+		*d = Duration(time.Duration(value * float64(time.Second)))
+	case string:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q: %w", value, err)
+		}
+		*d = Duration(parsed)
+	default:
+		return fmt.Errorf("invalid duration: %s", data)
+	}
+	return nil
+}
+
+// MarshalJSON 以字符串形式输出时长
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Std 转换为 time.Duration
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
@@ -0,0 +1,186 @@
+// Package counters 速率限制和速度窗口的计数：配置 Redis 时多个实例共享计数，
+// Redis 不可用时退回到进程内计数，恢复后继续使用 Redis
+package counters
+
+import (
+	"browser-detection/internal/config"
+	"browser-detection/internal/utils"
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 计数存储的状态
+const (
+	// BackendLocal 未配置 Redis，各实例在进程内计数
+	BackendLocal = "local"
+	// BackendRedis 计数保存在 Redis 中
+	BackendRedis = "redis"
+	// BackendFallback Redis 不可用，暂时在进程内计数
+	BackendFallback = "local_fallback"
+)
+
+// Store 带过期时间的计数
+type Store interface {
+	// IncrBy 将键的计数加 n 并返回新值，键不存在时创建并在 ttl 后过期
+	IncrBy(ctx context.Context, key string, n int64, ttl time.Duration) (int64, error)
+	// Get 返回各键的当前值，不存在或已过期的键为0
+	Get(ctx context.Context, keys ...string) ([]int64, error)
+}
+
+// localSweepInterval 进程内计数清除过期键的最小间隔
+const localSweepInterval = time.Minute
+
+// localEntry 进程内的一个计数
+type localEntry struct {
+	value   int64
+	expires time.Time
+}
+
+// Local 进程内计数，只统计本实例看到的请求
+type Local struct {
+	mu      sync.Mutex
+	entries map[string]*localEntry
+	swept   time.Time
+}
+
+// NewLocal 创建进程内计数
+func NewLocal() *Local {
+	return &Local{entries: make(map[string]*localEntry)}
+}
+
+// IncrBy 实现 Store
+func (l *Local) IncrBy(_ context.Context, key string, n int64, ttl time.Duration) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if now.Sub(l.swept) >= localSweepInterval {
+		for k, e := range l.entries {
+			if !now.Before(e.expires) {
+				delete(l.entries, k)
+			}
+		}
+		l.swept = now
+	}
+	e, ok := l.entries[key]
+	if !ok || !now.Before(e.expires) {
+		e = &localEntry{expires: now.Add(ttl)}
+		l.entries[key] = e
+	}
+	e.value += n
+	return e.value, nil
+}
+
+// Get 实现 Store
+func (l *Local) Get(_ context.Context, keys ...string) ([]int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	values := make([]int64, len(keys))
+	for i, k := range keys {
+		if e, ok := l.entries[k]; ok && now.Before(e.expires) {
+			values[i] = e.value
+		}
+	}
+	return values, nil
+}
+
+// Counters 按配置选择 Redis 或进程内计数：Redis 连续失败达到 breaker_failures 次后，
+// 在 breaker_cooldown 内直接使用进程内计数，冷却结束后重新尝试 Redis
+type Counters struct {
+	redis   *redisStore
+	local   *Local
+	breaker *utils.CircuitBreaker
+}
+
+// New 按配置创建计数，redis_url 在加载配置时已校验
+func New(cfg config.CountersConfig) (*Counters, error) {
+	c := &Counters{local: NewLocal()}
+	if cfg.RedisURL == "" {
+		return c, nil
+	}
+	client, err := newRedisClient(cfg.RedisURL, cfg.Timeout.Std(), cfg.PoolSize)
+	if err != nil {
+		return nil, fmt.Errorf("invalid counters.redis_url: %w", err)
+	}
+	c.redis = &redisStore{client: client, prefix: cfg.Prefix}
+	c.breaker = utils.NewCircuitBreaker(cfg.BreakerFailures, cfg.BreakerCooldown.Std())
+	return c, nil
+}
+
+// Shared 报告是否配置了 Redis，即计数是否在多个实例之间共享
+func (c *Counters) Shared() bool {
+	return c.redis != nil
+}
+
+// Backend 返回当前使用的计数存储：local、redis 或 local_fallback
+func (c *Counters) Backend() string {
+	switch {
+	case c.redis == nil:
+		return BackendLocal
+	case c.breaker.State() == utils.BreakerOpen:
+		return BackendFallback
+	default:
+		return BackendRedis
+	}
+}
+
+// useRedis 报告本次是否应使用 Redis
+func (c *Counters) useRedis() bool {
+	return c.redis != nil && c.breaker.Allow()
+}
+
+// redisResult 记录一次 Redis 调用的结果，失败使熔断器断开时写日志
+func (c *Counters) redisResult(err error) {
+	if err == nil {
+		c.breaker.Success()
+		return
+	}
+	if c.breaker.Failure() {
+		log.Printf("Redis counters unavailable, counting locally for %s: %v", c.breaker.RetryAfter(), err)
+	}
+}
+
+// IncrBy 实现 Store，Redis 失败时改用进程内计数
+func (c *Counters) IncrBy(ctx context.Context, key string, n int64, ttl time.Duration) (int64, error) {
+	if c.useRedis() {
+		v, err := c.redis.IncrBy(ctx, key, n, ttl)
+		c.redisResult(err)
+		if err == nil {
+			return v, nil
+		}
+	}
+	return c.local.IncrBy(ctx, key, n, ttl)
+}
+
+// Get 实现 Store，Redis 失败时改用进程内计数
+func (c *Counters) Get(ctx context.Context, keys ...string) ([]int64, error) {
+	if c.useRedis() {
+		values, err := c.redis.Get(ctx, keys...)
+		c.redisResult(err)
+		if err == nil {
+			return values, nil
+		}
+	}
+	return c.local.Get(ctx, keys...)
+}
+
+// Window 将键计入滑动窗口并返回窗口内的近似次数（包括本次）和距离当前固定窗口结束的时长：
+// 按 window 分段计数，窗口内的次数为当前分段的计数加上一分段按未过去的比例折算的计数
+func (c *Counters) Window(ctx context.Context, key string, window time.Duration, now time.Time) (float64, time.Duration, error) {
+	bucket := now.UnixNano() / int64(window)
+	current, err := c.IncrBy(ctx, key+":"+strconv.FormatInt(bucket, 10), 1, 2*window)
+	if err != nil {
+		return 0, 0, err
+	}
+	previous, err := c.Get(ctx, key+":"+strconv.FormatInt(bucket-1, 10))
+	if err != nil {
+		return 0, 0, err
+	}
+	elapsed := time.Duration(now.UnixNano() - bucket*int64(window))
+	weight := 1 - float64(elapsed)/float64(window)
+	return float64(current) + float64(previous[0])*weight, window - elapsed, nil
+}
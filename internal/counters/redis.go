@@ -0,0 +1,269 @@
+package counters
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// incrScript 原子地累加计数，键首次创建时设置过期时间，之后的累加不延长过期时间
+const incrScript = `local v = redis.call('INCRBY', KEYS[1], ARGV[1])
+if v == tonumber(ARGV[1]) then redis.call('PEXPIRE', KEYS[1], ARGV[2]) end
+return v`
+
+// redisError Redis 返回的错误回复，连接本身仍然可用
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// redisConn 一个 Redis 连接
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// redisClient 只实现计数所需命令的 RESP 客户端，连接数不超过 pool 的容量
+type redisClient struct {
+	addr     string
+	password string
+	db       int
+	tls      *tls.Config
+	timeout  time.Duration
+	idle     chan *redisConn
+	slots    chan struct{}
+}
+
+// newRedisClient 按 redis://[:password@]host:port[/db] 或 rediss://（TLS）创建客户端，不立即连接
+func newRedisClient(rawURL string, timeout time.Duration, poolSize int) (*redisClient, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	c := &redisClient{
+		addr:    u.Host,
+		timeout: timeout,
+		idle:    make(chan *redisConn, poolSize),
+		slots:   make(chan struct{}, poolSize),
+	}
+	switch u.Scheme {
+	case "redis":
+	case "rediss":
+		c.tls = &tls.Config{ServerName: u.Hostname()}
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if u.Port() == "" {
+		c.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		c.password, _ = u.User.Password()
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		if c.db, err = strconv.Atoi(db); err != nil || c.db < 0 {
+			return nil, fmt.Errorf("invalid database %q", db)
+		}
+	}
+	return c, nil
+}
+
+// do 执行一条命令并返回回复：整数为 int64，字符串为 string，空回复为 nil，数组为 []interface{}
+func (c *redisClient) do(ctx context.Context, args ...string) (interface{}, error) {
+	select {
+	case c.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-c.slots }()
+
+	var conn *redisConn
+	select {
+	case conn = <-c.idle:
+	default:
+		var err error
+		if conn, err = c.dial(ctx); err != nil {
+			return nil, err
+		}
+	}
+	reply, err := conn.do(ctx, c.timeout, args)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		conn.conn.Close()
+		return nil, err
+	}
+	c.idle <- conn
+	return reply, err
+}
+
+// dial 建立连接并按需认证和选择数据库
+func (c *redisClient) dial(ctx context.Context) (*redisConn, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	var d net.Dialer
+	nc, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, err
+	}
+	if c.tls != nil {
+		tc := tls.Client(nc, c.tls)
+		if err := tc.HandshakeContext(ctx); err != nil {
+			nc.Close()
+			return nil, err
+		}
+		nc = tc
+	}
+	conn := &redisConn{conn: nc, r: bufio.NewReader(nc)}
+	if c.password != "" {
+		if _, err := conn.do(ctx, c.timeout, []string{"AUTH", c.password}); err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+	if c.db != 0 {
+		if _, err := conn.do(ctx, c.timeout, []string{"SELECT", strconv.Itoa(c.db)}); err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// do 在连接上发送命令并读取回复，期限取 timeout 和 ctx 中较早的一个
+func (conn *redisConn) do(ctx context.Context, timeout time.Duration, args []string) (interface{}, error) {
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := io.WriteString(conn.conn, b.String()); err != nil {
+		return nil, err
+	}
+	return readReply(conn.r)
+}
+
+// readReply 读取一个 RESP 回复
+// 数组中的元素是错误回复时仍读完其余元素再返回第一个错误，连接上不会残留未读的回复；
+// 其他读取或格式错误后连接状态未知，调用方须关闭连接
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, redisError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		var replyErr error
+		for i := range items {
+			item, err := readReply(r)
+			var elemErr redisError
+			if errors.As(err, &elemErr) {
+				if replyErr == nil {
+					replyErr = err
+				}
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		if replyErr != nil {
+			return nil, replyErr
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+}
+
+// redisStore 保存在 Redis 中的计数，多个实例共享
+type redisStore struct {
+	client *redisClient
+	prefix string
+}
+
+// IncrBy 实现 Store
+func (s *redisStore) IncrBy(ctx context.Context, key string, n int64, ttl time.Duration) (int64, error) {
+	reply, err := s.client.do(ctx, "EVAL", incrScript, "1", s.prefix+key,
+		strconv.FormatInt(n, 10), strconv.FormatInt(ttl.Milliseconds(), 10))
+	if err != nil {
+		return 0, err
+	}
+	v, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: unexpected reply %T to EVAL", reply)
+	}
+	return v, nil
+}
+
+// Get 实现 Store
+func (s *redisStore) Get(ctx context.Context, keys ...string) ([]int64, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	args := make([]string, 0, len(keys)+1)
+	args = append(args, "MGET")
+	for _, k := range keys {
+		args = append(args, s.prefix+k)
+	}
+	reply, err := s.client.do(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := reply.([]interface{})
+	if !ok || len(items) != len(keys) {
+		return nil, fmt.Errorf("redis: unexpected reply to MGET")
+	}
+	values := make([]int64, len(keys))
+	for i, item := range items {
+		if s, ok := item.(string); ok {
+			if values[i], err = strconv.ParseInt(s, 10, 64); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,284 @@
+package counters
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadReply(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  interface{}
+	}{
+		{"simple string", "+OK\r\n", "OK"},
+		{"integer", ":42\r\n", int64(42)},
+		{"negative integer", ":-7\r\n", int64(-7)},
+		{"bulk string", "$5\r\nhello\r\n", "hello"},
+		{"bulk string with CRLF", "$4\r\na\r\nb\r\n", "a\r\nb"},
+		{"empty bulk string", "$0\r\n\r\n", ""},
+		{"null bulk string", "$-1\r\n", nil},
+		{"null array", "*-1\r\n", nil},
+		{"empty array", "*0\r\n", []interface{}{}},
+		{"array", "*3\r\n$1\r\n5\r\n$-1\r\n:3\r\n", []interface{}{"5", nil, int64(3)}},
+		{"nested array", "*2\r\n*1\r\n+a\r\n:1\r\n", []interface{}{[]interface{}{"a"}, int64(1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readReply(bufio.NewReader(strings.NewReader(tt.input)))
+			if err != nil {
+				t.Fatalf("readReply(%q) error: %v", tt.input, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("readReply(%q) = %#v, want %#v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadReplyErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		replyErr bool
+	}{
+		{"error reply", "-ERR unknown command\r\n", true},
+		{"missing CR", "+OK\n", false},
+		{"short line", "+\n", false},
+		{"unknown type", "!3\r\nbad\r\n", false},
+		{"bad integer", ":abc\r\n", false},
+		{"bad bulk length", "$x\r\n", false},
+		{"truncated bulk string", "$10\r\nshort\r\n", false},
+		{"truncated array", "*2\r\n:1\r\n", false},
+		{"EOF", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readReply(bufio.NewReader(strings.NewReader(tt.input)))
+			if err == nil {
+				t.Fatalf("readReply(%q) succeeded, want error", tt.input)
+			}
+			var replyErr redisError
+			if errors.As(err, &replyErr) != tt.replyErr {
+				t.Fatalf("readReply(%q) error %v: redis error reply = %v, want %v", tt.input, err, !tt.replyErr, tt.replyErr)
+			}
+		})
+	}
+}
+
+// 数组中的错误元素之后的元素须被读完，下一条回复从正确的位置开始
+func TestReadReplyDrainsArrayAfterError(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("*4\r\n:1\r\n-ERR first\r\n*2\r\n-ERR nested\r\n+x\r\n$3\r\nabc\r\n:99\r\n"))
+	_, err := readReply(r)
+	var replyErr redisError
+	if !errors.As(err, &replyErr) || string(replyErr) != "ERR first" {
+		t.Fatalf("readReply error = %v, want the first element error", err)
+	}
+	next, err := readReply(r)
+	if err != nil || next != int64(99) {
+		t.Fatalf("next reply = %#v, %v, want 99", next, err)
+	}
+}
+
+// fakeRedis 按顺序回应命令的测试服务器，记录收到的命令
+type fakeRedis struct {
+	listener net.Listener
+	replies  chan string
+	commands chan []string
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeRedis{listener: l, replies: make(chan string, 16), commands: make(chan []string, 16)}
+	t.Cleanup(func() { l.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeRedis) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go func(conn net.Conn) {
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+			for {
+				cmd, err := readReply(r)
+				if err != nil {
+					return
+				}
+				var args []string
+				for _, a := range cmd.([]interface{}) {
+					args = append(args, a.(string))
+				}
+				s.commands <- args
+				if _, err := conn.Write([]byte(<-s.replies)); err != nil {
+					return
+				}
+			}
+		}(conn)
+	}
+}
+
+func (s *fakeRedis) url(suffix string) string {
+	return "redis://" + s.listener.Addr().String() + suffix
+}
+
+func TestRedisClientAuthAndSelect(t *testing.T) {
+	s := newFakeRedis(t)
+	c, err := newRedisClient("redis://:secret@"+s.listener.Addr().String()+"/2", time.Second, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.replies <- "+OK\r\n"
+	s.replies <- "+OK\r\n"
+	s.replies <- ":1\r\n"
+	reply, err := c.do(context.Background(), "INCR", "k")
+	if err != nil || reply != int64(1) {
+		t.Fatalf("do = %#v, %v, want 1", reply, err)
+	}
+	for _, want := range [][]string{{"AUTH", "secret"}, {"SELECT", "2"}, {"INCR", "k"}} {
+		if got := <-s.commands; !reflect.DeepEqual(got, want) {
+			t.Fatalf("command = %q, want %q", got, want)
+		}
+	}
+}
+
+// 错误回复后连接仍然可用并放回连接池，下一条命令读到的是自己的回复
+func TestRedisClientReusesConnectionAfterErrorReply(t *testing.T) {
+	s := newFakeRedis(t)
+	c, err := newRedisClient(s.url(""), time.Second, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &redisStore{client: c, prefix: "p:"}
+
+	s.replies <- "*3\r\n$1\r\n4\r\n-WRONGTYPE bad\r\n$1\r\n6\r\n"
+	if _, err := store.Get(context.Background(), "a", "b", "c"); err == nil || !strings.Contains(err.Error(), "WRONGTYPE") {
+		t.Fatalf("Get error = %v, want WRONGTYPE", err)
+	}
+	if got := <-s.commands; !reflect.DeepEqual(got, []string{"MGET", "p:a", "p:b", "p:c"}) {
+		t.Fatalf("command = %q", got)
+	}
+
+	s.replies <- "*2\r\n$1\r\n7\r\n$-1\r\n"
+	values, err := store.Get(context.Background(), "a", "b")
+	if err != nil || !reflect.DeepEqual(values, []int64{7, 0}) {
+		t.Fatalf("Get = %v, %v, want [7 0]", values, err)
+	}
+	<-s.commands
+	if len(c.idle) != 1 {
+		t.Fatalf("idle connections = %d, want 1", len(c.idle))
+	}
+}
+
+// 格式错误的回复之后连接状态未知，须关闭而不是放回连接池
+func TestRedisClientClosesConnectionAfterMalformedReply(t *testing.T) {
+	s := newFakeRedis(t)
+	c, err := newRedisClient(s.url(""), time.Second, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.replies <- ":not-a-number\r\n"
+	if _, err := c.do(context.Background(), "INCR", "k"); err == nil {
+		t.Fatal("do succeeded, want parse error")
+	}
+	<-s.commands
+	if len(c.idle) != 0 {
+		t.Fatalf("idle connections = %d, want 0", len(c.idle))
+	}
+
+	s.replies <- ":5\r\n"
+	if reply, err := c.do(context.Background(), "INCR", "k"); err != nil || reply != int64(5) {
+		t.Fatalf("do on a new connection = %#v, %v, want 5", reply, err)
+	}
+}
+
+func TestRedisStoreIncrBy(t *testing.T) {
+	s := newFakeRedis(t)
+	c, err := newRedisClient(s.url(""), time.Second, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &redisStore{client: c, prefix: "p:"}
+
+	s.replies <- ":3\r\n"
+	v, err := store.IncrBy(context.Background(), "k", 3, 1500*time.Millisecond)
+	if err != nil || v != 3 {
+		t.Fatalf("IncrBy = %d, %v, want 3", v, err)
+	}
+	want := []string{"EVAL", incrScript, "1", "p:k", "3", strconv.Itoa(1500)}
+	if got := <-s.commands; !reflect.DeepEqual(got, want) {
+		t.Fatalf("command = %q, want %q", got, want)
+	}
+
+	s.replies <- "+OK\r\n"
+	if _, err := store.IncrBy(context.Background(), "k", 1, time.Second); err == nil {
+		t.Fatal("IncrBy with a string reply succeeded, want error")
+	}
+	<-s.commands
+}
+
+func TestRedisClientHonorsContext(t *testing.T) {
+	s := newFakeRedis(t)
+	c, err := newRedisClient(s.url(""), 5*time.Second, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := c.do(ctx, "INCR", "k"); err == nil {
+		t.Fatal("do without a reply succeeded, want timeout")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("do returned after %v, want the context deadline", elapsed)
+	}
+	<-s.commands
+}
+
+func TestNewRedisClient(t *testing.T) {
+	tests := []struct {
+		url     string
+		addr    string
+		db      int
+		tls     bool
+		wantErr bool
+	}{
+		{url: "redis://localhost", addr: "localhost:6379"},
+		{url: "redis://:pw@10.0.0.1:6380/3", addr: "10.0.0.1:6380", db: 3},
+		{url: "rediss://cache.example.com", addr: "cache.example.com:6379", tls: true},
+		{url: "http://localhost", wantErr: true},
+		{url: "redis://localhost/x", wantErr: true},
+		{url: "redis://localhost/-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		c, err := newRedisClient(tt.url, time.Second, 1)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("newRedisClient(%q) succeeded, want error", tt.url)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("newRedisClient(%q) error: %v", tt.url, err)
+			continue
+		}
+		if c.addr != tt.addr || c.db != tt.db || (c.tls != nil) != tt.tls {
+			t.Errorf("newRedisClient(%q) = addr %q db %d tls %v", tt.url, c.addr, c.db, c.tls != nil)
+		}
+	}
+}
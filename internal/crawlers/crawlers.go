@@ -0,0 +1,161 @@
+// Package crawlers 已知爬虫的特征库：按User Agent中的标识识别AI爬虫、搜索引擎、SEO工具、社交预览和监控服务，
+// 随程序内置一份，可用外部文件替换
+package crawlers
+
+import (
+	"browser-detection/internal/models"
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// embeddedTable 内置的特征库，加载外部文件失败时使用
+//
+//go:embed crawlers.json
+var embeddedTable []byte
+
+// Table 一份爬虫特征库
+type Table struct {
+	// Version 特征库的版本，由维护者在每次更新时修改
+	Version string `json:"version"`
+	// Source 特征库来源：文件路径或 embedded
+	Source   string      `json:"-"`
+	Crawlers []Signature `json:"crawlers"`
+}
+
+// Signature 一个爬虫的特征
+type Signature struct {
+	models.Crawler
+	// Tokens User Agent中的标识，不区分大小写，包含任意一个即为该爬虫；按特征库中的顺序匹配第一个
+	Tokens []string `json:"tokens"`
+	// Robots 爬虫遵循的 robots.txt User-agent 名称，为空时为 Name
+	Robots []string `json:"robots,omitempty"`
+
+	lowered []string
+}
+
+// Parse 解析并校验特征库，source 记录来源
+func Parse(data []byte, source string) (*Table, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var t Table
+	if err := dec.Decode(&t); err != nil {
+		return nil, fmt.Errorf("failed to parse crawlers: %w", err)
+	}
+	if t.Version == "" || len(t.Crawlers) == 0 {
+		return nil, fmt.Errorf("invalid crawlers: version and crawlers are required")
+	}
+	seen := make(map[string]bool, len(t.Crawlers))
+	for i := range t.Crawlers {
+		s := &t.Crawlers[i]
+		if s.Name == "" || seen[s.Name] {
+			return nil, fmt.Errorf("invalid crawlers[%d]: name is empty or duplicated", i)
+		}
+		seen[s.Name] = true
+		if !ValidCategory(s.Category) {
+			return nil, fmt.Errorf("invalid crawlers %s: unknown category %q", s.Name, s.Category)
+		}
+		if s.Purpose != "" && (s.Category != models.CrawlerAI || !ValidPurpose(s.Purpose)) {
+			return nil, fmt.Errorf("invalid crawlers %s: purpose %q is only allowed for ai crawlers", s.Name, s.Purpose)
+		}
+		if len(s.Tokens) == 0 {
+			return nil, fmt.Errorf("invalid crawlers %s: tokens must not be empty", s.Name)
+		}
+		for _, token := range s.Tokens {
+			if strings.TrimSpace(token) == "" {
+				return nil, fmt.Errorf("invalid crawlers %s: token must not be blank", s.Name)
+			}
+			s.lowered = append(s.lowered, strings.ToLower(token))
+		}
+	}
+	t.Source = source
+	return &t, nil
+}
+
+// ValidCategory 判断是否为已知的爬虫类别
+func ValidCategory(category string) bool {
+	for _, c := range models.CrawlerCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidPurpose 判断是否为已知的AI爬虫用途
+func ValidPurpose(purpose string) bool {
+	for _, p := range models.CrawlerPurposes {
+		if p == purpose {
+			return true
+		}
+	}
+	return false
+}
+
+// Load 从文件读取特征库
+func Load(path string) (*Table, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data, path)
+}
+
+var (
+	embeddedOnce   sync.Once
+	embeddedParsed *Table
+)
+
+// Embedded 内置的特征库
+func Embedded() *Table {
+	embeddedOnce.Do(func() {
+		t, err := Parse(embeddedTable, "embedded")
+		if err != nil {
+			panic(err)
+		}
+		embeddedParsed = t
+	})
+	return embeddedParsed
+}
+
+// Match 按User Agent识别爬虫，未识别时返回 nil
+func (t *Table) Match(userAgent string) *models.Crawler {
+	if userAgent == "" {
+		return nil
+	}
+	ua := strings.ToLower(userAgent)
+	for i := range t.Crawlers {
+		for _, token := range t.Crawlers[i].lowered {
+			if strings.Contains(ua, token) {
+				c := t.Crawlers[i].Crawler
+				return &c
+			}
+		}
+	}
+	return nil
+}
+
+// RobotsAgents 返回爬虫遵循的 robots.txt User-agent 名称，不在特征库中时为名称本身
+func (t *Table) RobotsAgents(name string) []string {
+	for i := range t.Crawlers {
+		if s := &t.Crawlers[i]; s.Name == name && len(s.Robots) > 0 {
+			return s.Robots
+		}
+	}
+	return []string{name}
+}
+
+// Lookup 按名称查找爬虫
+func (t *Table) Lookup(name string) (*models.Crawler, bool) {
+	for i := range t.Crawlers {
+		if t.Crawlers[i].Name == name {
+			c := t.Crawlers[i].Crawler
+			return &c, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,78 @@
+// Package detector 检测器插件：在服务内置检查之外增加检测模块（地理位置、TLS、行为或各公司自有的检查），
+// 检测器实现 Detector 接口并在 init 中调用 Register 注册，由 cmd/server 以空白导入编译进服务，不需要修改指纹服务
+package detector
+
+import (
+	"browser-detection/internal/models"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Signal 检测器给出的信号：Weight 计入爬虫评分（-1~1，负值降低评分），Code 与 Reason 写入分析结果
+type Signal struct {
+	Code   string  `json:"code"`
+	Weight float64 `json:"weight"`
+	Reason string  `json:"reason"`
+}
+
+// Detector 检测器：按本次提交的指纹和原始请求给出检测信号，没有发现时返回空
+// Evaluate 在每次指纹提交的分析中同步调用，须遵守 ctx 的期限，且可能被多个请求并发调用
+type Detector interface {
+	// Name 检测器名称，在注册表中唯一，用于配置中启用、停用和日志
+	Name() string
+	Evaluate(ctx context.Context, fp *models.Fingerprint, req *models.FingerprintRequest) []Signal
+}
+
+// Func 将函数包装为检测器
+func Func(name string, evaluate func(ctx context.Context, fp *models.Fingerprint, req *models.FingerprintRequest) []Signal) Detector {
+	return funcDetector{name: name, evaluate: evaluate}
+}
+
+// funcDetector 由函数实现的检测器
+type funcDetector struct {
+	name     string
+	evaluate func(ctx context.Context, fp *models.Fingerprint, req *models.FingerprintRequest) []Signal
+}
+
+func (d funcDetector) Name() string { return d.name }
+
+func (d funcDetector) Evaluate(ctx context.Context, fp *models.Fingerprint, req *models.FingerprintRequest) []Signal {
+	return d.evaluate(ctx, fp, req)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Detector)
+)
+
+// Register 注册检测器，名称为空或重复时 panic；通常在检测器所在包的 init 中调用
+func Register(d Detector) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	name := d.Name()
+	if name == "" {
+		panic("detector: Register detector with empty name")
+	}
+	if _, dup := registry[name]; dup {
+		panic(fmt.Sprintf("detector: Register called twice for detector %q", name))
+	}
+	registry[name] = d
+}
+
+// Registered 按名称顺序返回已注册的检测器
+func Registered() []Detector {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	detectors := make([]Detector, len(names))
+	for i, name := range names {
+		detectors[i] = registry[name]
+	}
+	return detectors
+}
@@ -0,0 +1,198 @@
+package detector
+
+import (
+	"browser-detection/internal/api/protobuf"
+	"browser-detection/internal/config"
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// maxRemoteResponseBytes 进程外检测器响应体的大小上限
+const maxRemoteResponseBytes = 64 << 10
+
+// remoteRequest 发送给进程外检测器的请求体
+type remoteRequest struct {
+	Detector    string                     `json:"detector"`
+	Fingerprint *models.Fingerprint        `json:"fingerprint"`
+	Request     *models.FingerprintRequest `json:"request"`
+}
+
+// remoteResponse 进程外检测器的响应体
+type remoteResponse struct {
+	Signals []Signal `json:"signals"`
+}
+
+// Remote 进程外检测器：http(s) 地址以HTTP POST JSON（detector、fingerprint、request），响应 {"signals": [...]}；
+// grpc(s) 地址调用 api/proto/detector.proto 中的 Detector/Evaluate。
+// 调用超时、失败或响应无效时不给出信号；连续失败达到阈值后熔断，熔断期间直接跳过
+type Remote struct {
+	cfg      config.RemoteDetectorConfig
+	client   *http.Client
+	grpc     protobuf.DetectorClient
+	sites    map[string]bool
+	breaker  *utils.CircuitBreaker
+	cooldown time.Duration
+}
+
+// NewRemote 按配置创建进程外检测器，配置在加载时已校验并补齐默认值；
+// gRPC检测器的连接在首次调用时建立，断开后自动重连
+func NewRemote(cfg config.RemoteDetectorConfig, breakerFailures int, breakerCooldown config.Duration) (*Remote, error) {
+	sites := make(map[string]bool, len(cfg.Sites))
+	for _, site := range cfg.Sites {
+		sites[site] = true
+	}
+	r := &Remote{
+		cfg:      cfg,
+		sites:    sites,
+		breaker:  utils.NewCircuitBreaker(breakerFailures, breakerCooldown.Std()),
+		cooldown: breakerCooldown.Std(),
+	}
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "grpc", "grpcs":
+		creds := insecure.NewCredentials()
+		if u.Scheme == "grpcs" {
+			creds = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+		}
+		conn, err := grpc.Dial(u.Host,
+			grpc.WithTransportCredentials(creds),
+			grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxRemoteResponseBytes)))
+		if err != nil {
+			return nil, err
+		}
+		r.grpc = protobuf.NewDetectorClient(conn)
+	default:
+		r.client = &http.Client{Timeout: cfg.Timeout.Std()}
+	}
+	return r, nil
+}
+
+// Name 检测器名称
+func (r *Remote) Name() string { return r.cfg.Name }
+
+// State 返回熔断器状态
+func (r *Remote) State() string { return r.breaker.State() }
+
+// Evaluate 调用进程外检测器，失败时记录日志并返回空
+func (r *Remote) Evaluate(ctx context.Context, fp *models.Fingerprint, req *models.FingerprintRequest) []Signal {
+	if len(r.sites) > 0 && !r.sites[fp.SiteID] {
+		return nil
+	}
+	if !r.breaker.Allow() {
+		return nil
+	}
+	signals, err := r.call(ctx, fp, req)
+	if err != nil {
+		if r.breaker.Failure() {
+			log.Printf("Remote detector %s disabled for %s after repeated failures: %v", r.cfg.Name, r.cooldown, err)
+		} else {
+			log.Printf("Remote detector %s failed: %v", r.cfg.Name, err)
+		}
+		return nil
+	}
+	r.breaker.Success()
+	return capWeights(signals, r.cfg.MaxWeight)
+}
+
+// call 发送请求并解析响应
+func (r *Remote) call(ctx context.Context, fp *models.Fingerprint, req *models.FingerprintRequest) ([]Signal, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Timeout.Std())
+	defer cancel()
+	if r.grpc != nil {
+		return r.callGRPC(ctx, fp, req)
+	}
+
+	body, err := json.Marshal(remoteRequest{Detector: r.cfg.Name, Fingerprint: fp, Request: req})
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, r.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	for k, v := range r.cfg.Headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	resp, err := r.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("remote detector returned status %d", resp.StatusCode)
+	}
+	var out remoteResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxRemoteResponseBytes)).Decode(&out); err != nil {
+		return nil, fmt.Errorf("invalid remote detector response: %w", err)
+	}
+	return out.Signals, nil
+}
+
+// callGRPC 调用 Detector/Evaluate，配置的 headers 作为元数据发送
+func (r *Remote) callGRPC(ctx context.Context, fp *models.Fingerprint, req *models.FingerprintRequest) ([]Signal, error) {
+	for k, v := range r.cfg.Headers {
+		ctx = metadata.AppendToOutgoingContext(ctx, strings.ToLower(k), v)
+	}
+	resp, err := r.grpc.Evaluate(ctx, &protobuf.DetectRequest{
+		Detector: r.cfg.Name,
+		Request:  protobuf.NewFingerprintRequest(req),
+		Submission: &protobuf.SubmissionContext{
+			FingerprintHash: fp.FingerprintHash,
+			SiteId:          fp.SiteID,
+			IpAddress:       fp.IPAddress,
+			Country:         fp.Country,
+			VisitorId:       fp.VisitorID,
+			AcceptLanguage:  fp.AcceptLanguage,
+			PageUrl:         fp.PageURL,
+			ClientHintsUa:   fp.ClientHintsUA,
+			MathHash:        fp.MathHash,
+			CanvasPhash:     fp.CanvasPHash,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	signals := make([]Signal, len(resp.GetSignals()))
+	for i, s := range resp.GetSignals() {
+		signals[i] = Signal{Code: s.GetCode(), Weight: s.GetWeight(), Reason: s.GetReason()}
+	}
+	return signals, nil
+}
+
+// capWeights 按比例缩小信号权重，使权重绝对值之和不超过 max
+func capWeights(signals []Signal, max float64) []Signal {
+	total := 0.0
+	for _, s := range signals {
+		total += math.Abs(s.Weight)
+	}
+	if total <= max || total == 0 || math.IsNaN(total) {
+		return signals
+	}
+	scale := max / total
+	for i := range signals {
+		signals[i].Weight *= scale
+	}
+	return signals
+}
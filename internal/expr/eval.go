@@ -0,0 +1,349 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// MaxStringLength 求值过程中拼接出的字符串的最大长度
+const MaxStringLength = 64 << 10
+
+// Env 求值环境：变量名到取值，取值为 float64、string、bool 或 []interface{}；整数按 float64 传入
+type Env map[string]interface{}
+
+// Eval 按环境求值，变量未定义、类型不匹配或除以0时返回错误
+func (p *Program) Eval(env Env) (interface{}, error) {
+	return p.root.eval(env)
+}
+
+// node 语法树节点
+type node interface {
+	eval(env Env) (interface{}, error)
+}
+
+type literalNode struct{ value interface{} }
+
+func (n *literalNode) eval(Env) (interface{}, error) { return n.value, nil }
+
+type varNode struct{ name string }
+
+func (n *varNode) eval(env Env) (interface{}, error) {
+	v, ok := env[n.name]
+	if !ok {
+		return nil, fmt.Errorf("undefined variable %q", n.name)
+	}
+	return v, nil
+}
+
+type listNode struct{ items []node }
+
+func (n *listNode) eval(env Env) (interface{}, error) {
+	list := make([]interface{}, len(n.items))
+	for i, item := range n.items {
+		v, err := item.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = v
+	}
+	return list, nil
+}
+
+type condNode struct{ cond, then, otherwise node }
+
+func (n *condNode) eval(env Env) (interface{}, error) {
+	c, err := evalBool(n.cond, env)
+	if err != nil {
+		return nil, err
+	}
+	if c {
+		return n.then.eval(env)
+	}
+	return n.otherwise.eval(env)
+}
+
+type unaryNode struct {
+	op      string
+	operand node
+}
+
+func (n *unaryNode) eval(env Env) (interface{}, error) {
+	if n.op == "!" {
+		b, err := evalBool(n.operand, env)
+		return !b, err
+	}
+	v, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	f, ok := v.(float64)
+	if !ok {
+		return nil, fmt.Errorf("operator - needs a number, got %s", typeName(v))
+	}
+	return -f, nil
+}
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+func (n *binaryNode) eval(env Env) (interface{}, error) {
+	// 逻辑运算短路求值
+	switch n.op {
+	case "&&", "||":
+		l, err := evalBool(n.left, env)
+		if err != nil {
+			return nil, err
+		}
+		if l == (n.op == "||") {
+			return l, nil
+		}
+		return evalBool(n.right, env)
+	}
+
+	l, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	r, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return equal(l, r), nil
+	case "!=":
+		return !equal(l, r), nil
+	case "in":
+		return contains(r, l)
+	case "+":
+		if ls, ok := l.(string); ok {
+			rs, ok := r.(string)
+			if !ok {
+				return nil, fmt.Errorf("operator + cannot join string and %s", typeName(r))
+			}
+			if len(ls)+len(rs) > MaxStringLength {
+				return nil, fmt.Errorf("string longer than %d bytes", MaxStringLength)
+			}
+			return ls + rs, nil
+		}
+	}
+
+	switch n.op {
+	case "<", "<=", ">", ">=":
+		if ls, ok := l.(string); ok {
+			if rs, ok := r.(string); ok {
+				return compare(n.op, strings.Compare(ls, rs)), nil
+			}
+		}
+	}
+	lf, lok := l.(float64)
+	rf, rok := r.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("operator %s needs numbers, got %s and %s", n.op, typeName(l), typeName(r))
+	}
+	switch n.op {
+	case "<", "<=", ">", ">=":
+		c := 0
+		if lf < rf {
+			c = -1
+		} else if lf > rf {
+			c = 1
+		}
+		return compare(n.op, c), nil
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		if rf == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return lf / rf, nil
+	case "%":
+		if rf == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return math.Mod(lf, rf), nil
+	}
+	return nil, fmt.Errorf("unknown operator %s", n.op)
+}
+
+type callNode struct {
+	name string
+	fn   func(args []interface{}) (interface{}, error)
+	args []node
+}
+
+func (n *callNode) eval(env Env) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	v, err := n.fn(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", n.name, err)
+	}
+	return v, nil
+}
+
+// evalBool 求值并要求结果为布尔值
+func evalBool(n node, env Env) (bool, error) {
+	v, err := n.eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected a boolean, got %s", typeName(v))
+	}
+	return b, nil
+}
+
+// compare 按比较结果（-1、0、1）计算比较运算符
+func compare(op string, c int) bool {
+	switch op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+// equal 比较两个值，类型不同时不相等
+func equal(a, b interface{}) bool {
+	la, aok := a.([]interface{})
+	lb, bok := b.([]interface{})
+	if aok || bok {
+		if !aok || !bok || len(la) != len(lb) {
+			return false
+		}
+		for i := range la {
+			if !equal(la[i], lb[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+// contains 判断列表是否含有该值，或字符串是否含有子串
+func contains(container, item interface{}) (bool, error) {
+	switch c := container.(type) {
+	case []interface{}:
+		for _, v := range c {
+			if equal(v, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case string:
+		s, ok := item.(string)
+		if !ok {
+			return false, fmt.Errorf("cannot search %s in a string", typeName(item))
+		}
+		return strings.Contains(c, s), nil
+	}
+	return false, fmt.Errorf("cannot search in %s", typeName(container))
+}
+
+// typeName 返回值的类型名，用于错误信息
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "list"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// function 内置函数及其参数个数
+type function struct {
+	arity int
+	call  func(args []interface{}) (interface{}, error)
+}
+
+// functions 内置函数
+var functions = map[string]function{
+	"len": {1, func(args []interface{}) (interface{}, error) {
+		switch v := args[0].(type) {
+		case string:
+			return float64(len(v)), nil
+		case []interface{}:
+			return float64(len(v)), nil
+		}
+		return nil, fmt.Errorf("needs a string or list, got %s", typeName(args[0]))
+	}},
+	"lower": {1, stringFunc(strings.ToLower)},
+	"upper": {1, stringFunc(strings.ToUpper)},
+	"contains": {2, func(args []interface{}) (interface{}, error) {
+		return contains(args[0], args[1])
+	}},
+	"startsWith": {2, stringPredicate(strings.HasPrefix)},
+	"endsWith":   {2, stringPredicate(strings.HasSuffix)},
+	"abs":        {1, numberFunc(math.Abs)},
+	"min":        {2, numberFunc2(math.Min)},
+	"max":        {2, numberFunc2(math.Max)},
+}
+
+func stringFunc(f func(string) string) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("needs a string, got %s", typeName(args[0]))
+		}
+		return f(s), nil
+	}
+}
+
+func stringPredicate(f func(string, string) bool) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		s, ok1 := args[0].(string)
+		t, ok2 := args[1].(string)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("needs strings, got %s and %s", typeName(args[0]), typeName(args[1]))
+		}
+		return f(s, t), nil
+	}
+}
+
+func numberFunc(f func(float64) float64) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		x, ok := args[0].(float64)
+		if !ok {
+			return nil, fmt.Errorf("needs a number, got %s", typeName(args[0]))
+		}
+		return f(x), nil
+	}
+}
+
+func numberFunc2(f func(float64, float64) float64) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		x, ok1 := args[0].(float64)
+		y, ok2 := args[1].(float64)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("needs numbers, got %s and %s", typeName(args[0]), typeName(args[1]))
+		}
+		return f(x, y), nil
+	}
+}
@@ -0,0 +1,374 @@
+// Package expr 评分表达式：运维在配置中编写的小型表达式语言，按指纹字段和附加数据计算自定义检测信号，
+// 不需要修改Go代码。只有字面量、变量、运算符和少量内置函数，没有循环和赋值，
+// 编译时限制长度、节点数和嵌套深度，求值时间与节点数成正比
+//
+// 支持的语法：数字、"字符串"、true/false、[列表]、变量名（ASCII字母、数字和下划线）、函数调用 f(a, b)；
+// 运算符按优先级从低到高为 ?:、||、&&、== !=、< <= > >= in、+ -、* / %、一元 ! -
+//
+// 没有使用 cel-go 或 expr-lang/expr：规则由运维在配置和管理API中编写，每次提交都要对全部规则求值，
+// 需要的只是字段比较和少量字符串函数。cel-go 依赖 ANTLR 运行时和 CEL 的 protobuf 定义，
+// 求值成本靠运行时的代价估算限制；expr-lang/expr 提供闭包、map/filter 等集合函数和区间运算符（1..n），
+// 求值时间取决于数据而不是表达式大小，须逐项关闭并跟随其版本重新审查。本语言没有循环、闭包和成员访问，
+// 节点数上限同时限制了求值的步数和递归深度，变量只能取自求值环境，字符串字面量不会被当作代码解析
+package expr
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// 编译限制
+const (
+	// MaxLength 表达式源码的最大长度
+	MaxLength = 2048
+	// MaxNodes 语法树的最大节点数
+	MaxNodes = 256
+	// MaxDepth 语法树的最大嵌套深度
+	MaxDepth = 32
+)
+
+// Program 编译后的表达式
+type Program struct {
+	source string
+	root   node
+	vars   []string
+}
+
+// Source 返回表达式源码
+func (p *Program) Source() string { return p.source }
+
+// Variables 按名称顺序返回表达式引用的变量
+func (p *Program) Variables() []string { return p.vars }
+
+// Compile 解析表达式，语法错误、未知函数或超出限制时返回错误
+func Compile(source string) (*Program, error) {
+	if len(source) > MaxLength {
+		return nil, fmt.Errorf("expression longer than %d bytes", MaxLength)
+	}
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, vars: make(map[string]bool)}
+	root, err := p.parseExpr(0)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at offset %d", t.text, t.pos)
+	}
+	vars := make([]string, 0, len(p.vars))
+	for v := range p.vars {
+		vars = append(vars, v)
+	}
+	sort.Strings(vars)
+	return &Program{source: source, root: root, vars: vars}, nil
+}
+
+// 词法单元类型
+const (
+	tokEOF = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOp
+)
+
+// token 词法单元，pos 为在源码中的字节偏移
+type token struct {
+	kind int
+	text string
+	num  float64
+	pos  int
+}
+
+// operators 运算符和分隔符，较长的在前以便优先匹配
+var operators = []string{"||", "&&", "==", "!=", "<=", ">=", "<", ">", "+", "-", "*", "/", "%", "!", "?", ":", "(", ")", "[", "]", ","}
+
+// lex 将源码切分为词法单元
+func lex(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(src) {
+		c := rune(src[i])
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			start := i
+			for i < len(src) && (src[i] >= '0' && src[i] <= '9' || src[i] == '.' || src[i] == 'e' || src[i] == 'E' ||
+				(src[i] == '-' || src[i] == '+') && (src[i-1] == 'e' || src[i-1] == 'E')) {
+				i++
+			}
+			v, err := strconv.ParseFloat(src[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at offset %d", src[start:i], start)
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: src[start:i], num: v, pos: start})
+		case c == '"' || c == '\'':
+			start := i
+			i++
+			var b strings.Builder
+			for {
+				if i >= len(src) {
+					return nil, fmt.Errorf("unterminated string at offset %d", start)
+				}
+				if rune(src[i]) == c {
+					i++
+					break
+				}
+				if src[i] == '\\' && i+1 < len(src) {
+					i++
+				}
+				b.WriteByte(src[i])
+				i++
+			}
+			tokens = append(tokens, token{kind: tokString, text: b.String(), pos: start})
+		case isIdentStart(src[i]):
+			start := i
+			for i < len(src) && (isIdentStart(src[i]) || src[i] >= '0' && src[i] <= '9') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: src[start:i], pos: start})
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(src[i:], op) {
+					tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				r, _ := utf8.DecodeRuneInString(src[i:])
+				return nil, fmt.Errorf("unexpected character %q at offset %d", r, i)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+// isIdentStart 判断字节能否作为标识符的开头：ASCII字母或下划线
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// parser 递归下降解析器
+type parser struct {
+	tokens []token
+	pos    int
+	nodes  int
+	vars   map[string]bool
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept 下一个单元是该运算符时消费并返回 true
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokOp && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// expect 消费指定的运算符，否则返回错误
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		t := p.peek()
+		return fmt.Errorf("expected %q at offset %d", op, t.pos)
+	}
+	return nil
+}
+
+// add 计数新节点并检查限制
+func (p *parser) add(n node, depth int) (node, error) {
+	p.nodes++
+	if p.nodes > MaxNodes {
+		return nil, fmt.Errorf("expression has more than %d nodes", MaxNodes)
+	}
+	if depth > MaxDepth {
+		return nil, fmt.Errorf("expression nested deeper than %d", MaxDepth)
+	}
+	return n, nil
+}
+
+// binaryLevels 二元运算符的优先级，从低到高
+var binaryLevels = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">=", "in"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+// parseExpr 解析条件表达式 cond ? a : b
+// 进入下一层前先检查嵌套深度，避免 ((((… 这样的输入在报错前递归过深
+func (p *parser) parseExpr(depth int) (node, error) {
+	if depth > MaxDepth {
+		return nil, fmt.Errorf("expression nested deeper than %d", MaxDepth)
+	}
+	cond, err := p.parseBinary(0, depth)
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept("?") {
+		return cond, nil
+	}
+	then, err := p.parseExpr(depth + 1)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	otherwise, err := p.parseExpr(depth + 1)
+	if err != nil {
+		return nil, err
+	}
+	return p.add(&condNode{cond: cond, then: then, otherwise: otherwise}, depth)
+}
+
+// matchBinary 下一个单元是该优先级的运算符时消费并返回运算符
+func (p *parser) matchBinary(level int) (string, bool) {
+	t := p.peek()
+	for _, op := range binaryLevels[level] {
+		if (t.kind == tokOp || t.kind == tokIdent) && t.text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+// parseBinary 按优先级解析左结合的二元运算
+func (p *parser) parseBinary(level, depth int) (node, error) {
+	if level == len(binaryLevels) {
+		return p.parseUnary(depth)
+	}
+	left, err := p.parseBinary(level+1, depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.matchBinary(level)
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseBinary(level+1, depth)
+		if err != nil {
+			return nil, err
+		}
+		if left, err = p.add(&binaryNode{op: op, left: left, right: right}, depth); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseUnary 解析一元运算 ! 和 -
+func (p *parser) parseUnary(depth int) (node, error) {
+	if depth > MaxDepth {
+		return nil, fmt.Errorf("expression nested deeper than %d", MaxDepth)
+	}
+	for _, op := range []string{"!", "-"} {
+		if p.accept(op) {
+			operand, err := p.parseUnary(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			return p.add(&unaryNode{op: op, operand: operand}, depth)
+		}
+	}
+	return p.parsePrimary(depth)
+}
+
+// parsePrimary 解析字面量、变量、函数调用、列表和括号
+func (p *parser) parsePrimary(depth int) (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		return p.add(&literalNode{value: t.num}, depth)
+	case tokString:
+		return p.add(&literalNode{value: t.text}, depth)
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return p.add(&literalNode{value: true}, depth)
+		case "false":
+			return p.add(&literalNode{value: false}, depth)
+		case "in":
+			return nil, fmt.Errorf("unexpected %q at offset %d", t.text, t.pos)
+		}
+		if !p.accept("(") {
+			p.vars[t.text] = true
+			return p.add(&varNode{name: t.text}, depth)
+		}
+		fn, ok := functions[t.text]
+		if !ok {
+			return nil, fmt.Errorf("unknown function %q at offset %d", t.text, t.pos)
+		}
+		args, err := p.parseList(")", depth)
+		if err != nil {
+			return nil, err
+		}
+		if len(args) != fn.arity {
+			return nil, fmt.Errorf("function %s takes %d arguments, got %d", t.text, fn.arity, len(args))
+		}
+		return p.add(&callNode{name: t.text, fn: fn.call, args: args}, depth)
+	case tokOp:
+		switch t.text {
+		case "(":
+			inner, err := p.parseExpr(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return inner, nil
+		case "[":
+			items, err := p.parseList("]", depth)
+			if err != nil {
+				return nil, err
+			}
+			return p.add(&listNode{items: items}, depth)
+		}
+	case tokEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q at offset %d", t.text, t.pos)
+}
+
+// parseList 解析以逗号分隔、以 end 结束的表达式列表
+func (p *parser) parseList(end string, depth int) ([]node, error) {
+	var items []node
+	if p.accept(end) {
+		return items, nil
+	}
+	for {
+		item, err := p.parseExpr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if p.accept(end) {
+			return items, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package expr
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	env := Env{
+		"timezone":  "UTC",
+		"cores":     float64(8),
+		"webdriver": false,
+		"fonts":     []interface{}{"Arial", "Helvetica"},
+	}
+	tests := []struct {
+		src  string
+		want interface{}
+	}{
+		{`1 + 2 * 3 == 7`, true},
+		{`(1 + 2) * 3`, float64(9)},
+		{`-2 * 3`, float64(-6)},
+		{`10 % 3`, float64(1)},
+		{`.5 + 1.5e-3 + 1e3`, 1000.5015},
+		{`7 - 2 - 1`, float64(4)},
+		{`!false && true`, true},
+		{`true ? 1 : 0`, float64(1)},
+		{`false ? 1 : true ? 2 : 3`, float64(2)},
+		{`"x" in ["a", "x"]`, true},
+		{`"ell" in "hello"`, true},
+		{`[1, "a"] == [1, "a"]`, true},
+		{`[1] == 1`, false},
+		{`1 == "1"`, false},
+		{`"b" > "a"`, true},
+		{`'single' == "single"`, true},
+		{`"a\"b"`, `a"b`},
+		{`timezone == "UTC" && cores >= 4`, true},
+		{`!webdriver && "Arial" in fonts`, true},
+		{`len(fonts) + len("abc")`, float64(5)},
+		{`lower("AbC") + upper("d")`, "abcD"},
+		{`contains(fonts, "Helvetica") && contains("abc", "b")`, true},
+		{`startsWith(timezone, "U") && endsWith(timezone, "C")`, true},
+		{`abs(-2) + min(1, 2) + max(1, 2)`, float64(5)},
+		{`[]`, []interface{}{}},
+	}
+	for _, tt := range tests {
+		p, err := Compile(tt.src)
+		if err != nil {
+			t.Errorf("Compile(%q) error: %v", tt.src, err)
+			continue
+		}
+		got, err := p.Eval(env)
+		if err != nil {
+			t.Errorf("Eval(%q) error: %v", tt.src, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Eval(%q) = %#v, want %#v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestVariables(t *testing.T) {
+	p, err := Compile(`b + a > b ? len(c) : 0`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := p.Variables(), []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Variables() = %q, want %q", got, want)
+	}
+	if p.Source() != `b + a > b ? len(c) : 0` {
+		t.Fatalf("Source() = %q", p.Source())
+	}
+}
+
+// 逻辑运算短路求值，未求值的一侧即使引用了未定义的变量也不报错
+func TestShortCircuit(t *testing.T) {
+	for src, want := range map[string]bool{
+		`false && missing`: false,
+		`true || missing`:  true,
+	} {
+		p, err := Compile(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := p.Eval(Env{})
+		if err != nil || got != want {
+			t.Errorf("Eval(%q) = %v, %v, want %v", src, got, err, want)
+		}
+	}
+	p, err := Compile(`missing && false`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Eval(Env{}); err == nil || !strings.Contains(err.Error(), `undefined variable "missing"`) {
+		t.Fatalf("Eval error = %v, want undefined variable", err)
+	}
+}
+
+func TestMaxLength(t *testing.T) {
+	src := `"` + strings.Repeat("a", MaxLength-2) + `"`
+	if _, err := Compile(src); err != nil {
+		t.Fatalf("Compile of %d bytes error: %v", len(src), err)
+	}
+	if _, err := Compile(src + " "); err == nil || !strings.Contains(err.Error(), "longer than") {
+		t.Fatalf("Compile of %d bytes error = %v, want length error", len(src)+1, err)
+	}
+}
+
+// n 个字面量相加有 2n-1 个节点
+func TestMaxNodes(t *testing.T) {
+	sum := func(n int) string { return strings.TrimSuffix(strings.Repeat("1+", n), "+") }
+	within := (MaxNodes + 1) / 2
+	p, err := Compile(sum(within))
+	if err != nil {
+		t.Fatalf("Compile with %d nodes error: %v", 2*within-1, err)
+	}
+	if got, err := p.Eval(Env{}); err != nil || got != float64(within) {
+		t.Fatalf("Eval = %v, %v, want %d", got, err, within)
+	}
+	if _, err := Compile(sum(within + 1)); err == nil || !strings.Contains(err.Error(), "nodes") {
+		t.Fatalf("Compile with %d nodes error = %v, want node limit error", 2*within+1, err)
+	}
+
+	// 函数参数和列表元素同样计入节点数
+	list := "[" + strings.TrimSuffix(strings.Repeat("1,", MaxNodes), ",") + "]"
+	if _, err := Compile(list); err == nil || !strings.Contains(err.Error(), "nodes") {
+		t.Fatalf("Compile of a %d-item list error = %v, want node limit error", MaxNodes, err)
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	nest := func(open, inner, close string, n int) string {
+		return strings.Repeat(open, n) + inner + strings.Repeat(close, n)
+	}
+	tests := []struct {
+		name string
+		src  func(n int) string
+		max  int
+	}{
+		{"parentheses", func(n int) string { return nest("(", "1", ")", n) }, MaxDepth},
+		{"unary minus", func(n int) string { return nest("-", "1", "", n) }, MaxDepth},
+		{"negation", func(n int) string { return nest("!", "true", "", n) }, MaxDepth},
+		{"lists", func(n int) string { return nest("[", "", "]", n) }, MaxDepth + 1},
+		{"function calls", func(n int) string { return nest("abs(", "1", ")", n) }, MaxDepth},
+		{"conditionals", func(n int) string { return nest("true ? ", "1", " : 0", n) }, MaxDepth},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Compile(tt.src(tt.max)); err != nil {
+				t.Fatalf("Compile at depth %d error: %v", tt.max, err)
+			}
+			if _, err := Compile(tt.src(tt.max + 1)); err == nil || !strings.Contains(err.Error(), "nested deeper") {
+				t.Fatalf("Compile at depth %d error = %v, want depth error", tt.max+1, err)
+			}
+		})
+	}
+
+	// 超出长度以内最深的嵌套在进入下一层前就被拒绝
+	src := nest("(", "1", ")", MaxLength/2-1)
+	if _, err := Compile(src); err == nil || !strings.Contains(err.Error(), "nested deeper") {
+		t.Fatalf("Compile of %d nested parentheses error = %v, want depth error", MaxLength/2-1, err)
+	}
+}
+
+func TestTypeErrors(t *testing.T) {
+	env := Env{"s": "abc", "n": float64(1), "l": []interface{}{"a"}, "i": 3}
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`1 + "a"`, "operator + needs numbers, got number and string"},
+		{`s + 1`, "operator + cannot join string and number"},
+		{`s - 1`, "operator - needs numbers, got string and number"},
+		{`s < 1`, "operator < needs numbers, got string and number"},
+		{`l < l`, "operator < needs numbers, got list and list"},
+		{`-s`, "operator - needs a number, got string"},
+		{`!n`, "expected a boolean, got number"},
+		{`n && true`, "expected a boolean, got number"},
+		{`n ? 1 : 2`, "expected a boolean, got number"},
+		{`1 in 2`, "cannot search in number"},
+		{`1 in s`, "cannot search number in a string"},
+		{`len(n)`, "len: needs a string or list, got number"},
+		{`lower(l)`, "lower: needs a string, got list"},
+		{`startsWith(s, 1)`, "startsWith: needs strings, got string and number"},
+		{`abs(s)`, "abs: needs a number, got string"},
+		{`max(n, s)`, "max: needs numbers, got number and string"},
+		{`n / 0`, "division by zero"},
+		{`n % 0`, "division by zero"},
+		{`i + 1`, "operator + needs numbers, got int and number"},
+		{`undefined_field == 1`, `undefined variable "undefined_field"`},
+	}
+	for _, tt := range tests {
+		p, err := Compile(tt.src)
+		if err != nil {
+			t.Errorf("Compile(%q) error: %v", tt.src, err)
+			continue
+		}
+		if _, err := p.Eval(env); err == nil || err.Error() != tt.want {
+			t.Errorf("Eval(%q) error = %v, want %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestStringLengthLimit(t *testing.T) {
+	p, err := Compile(`s + s`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Eval(Env{"s": strings.Repeat("a", MaxStringLength/2)}); err != nil {
+		t.Fatalf("Eval at the limit error: %v", err)
+	}
+	if _, err := p.Eval(Env{"s": strings.Repeat("a", MaxStringLength/2+1)}); err == nil || !strings.Contains(err.Error(), "string longer than") {
+		t.Fatalf("Eval over the limit error = %v, want length error", err)
+	}
+}
+
+// 形似其他语言代码的输入要么无法编译，要么只是普通的字符串和变量
+func TestInjectionInputs(t *testing.T) {
+	rejected := []struct {
+		src  string
+		want string
+	}{
+		{`timezone == "UTC"; os.Exit(1)`, `unexpected character ';'`},
+		{`exec("rm -rf /")`, `unknown function "exec"`},
+		{`system(timezone)`, `unknown function "system"`},
+		{`fp.timezone`, `unexpected character '.'`},
+		{`fonts[0]`, `unexpected "["`},
+		{`${timezone}`, `unexpected character '$'`},
+		{"timezone == \"UTC\" // comment", `unexpected "/"`},
+		{"timezone == \"UTC\" # comment", `unexpected character '#'`},
+		{"timezone\x00 == 1", `unexpected character '\x00'`},
+		{"tïmezone == 1", `unexpected character 'ï'`},
+		{"timezone\u00a0== 1", `unexpected character '\u00a0'`},
+		{`"unterminated`, "unterminated string"},
+		{`"a" + "`, "unterminated string"},
+		{`1..10`, `invalid number "1..10"`},
+		{`0x10`, `unexpected "x10"`},
+		{`len(1, 2)`, "function len takes 1 arguments, got 2"},
+		{`a = 1`, `unexpected character '='`},
+		{`(1`, `expected ")"`},
+		{`1)`, `unexpected ")"`},
+		{`true ? 1`, `expected ":"`},
+		{`in`, `unexpected "in"`},
+		{``, "unexpected end of expression"},
+	}
+	for _, tt := range rejected {
+		if _, err := Compile(tt.src); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Compile(%q) error = %v, want %q", tt.src, err, tt.want)
+		}
+	}
+
+	env := Env{"timezone": `"); drop table fingerprints; --`}
+	evaluated := []struct {
+		src  string
+		want interface{}
+	}{
+		{`timezone == "\"); drop table fingerprints; --"`, true},
+		{`"exec(\"rm\")"`, `exec("rm")`},
+		{`"${timezone}" == timezone`, false},
+		{`"a\nb"`, "anb"},
+	}
+	for _, tt := range evaluated {
+		p, err := Compile(tt.src)
+		if err != nil {
+			t.Errorf("Compile(%q) error: %v", tt.src, err)
+			continue
+		}
+		if got, err := p.Eval(env); err != nil || got != tt.want {
+			t.Errorf("Eval(%q) = %#v, %v, want %#v", tt.src, got, err, tt.want)
+		}
+	}
+
+	// 变量只取自求值环境，不会解析到Go或JavaScript中的对象
+	for _, name := range []string{"__proto__", "constructor", "os", "env", "len"} {
+		p, err := Compile(name)
+		if err != nil {
+			t.Errorf("Compile(%q) error: %v", name, err)
+			continue
+		}
+		if _, err := p.Eval(Env{}); err == nil || !strings.Contains(err.Error(), "undefined variable") {
+			t.Errorf("Eval(%q) error = %v, want undefined variable", name, err)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// previousSuffix 轮转后上一代日志文件的后缀
+const previousSuffix = ".1"
+
+// Entry 日志中尚未完成的记录
+type Entry struct {
+	ID         string          `json:"id"`
+	ReceivedAt time.Time       `json:"received_at"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// line 日志文件的一行：记录本身，或 Done 非空的完成标记
+type line struct {
+	Entry
+	Done string `json:"done,omitempty"`
+}
+
+// doneLine 完成标记
+type doneLine struct {
+	Done string `json:"done"`
+}
+
+// Journal 追加写入的预写日志：处理前写入并落盘，处理完成后追加完成标记
+// 进程崩溃时没有完成标记的记录可以重放；文件超过上限时轮转，只保留上一代
+type Journal struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	file     *os.File
+	size     int64
+}
+
+// Open 打开（或创建）日志文件，maxBytes 不大于0时不轮转
+func Open(path string, maxBytes int64) (*Journal, error) {
+	j := &Journal{path: path, maxBytes: maxBytes}
+	if err := j.open(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// open 以追加方式打开当前日志文件
+func (j *Journal) open() error {
+	f, err := os.OpenFile(j.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	j.file, j.size = f, info.Size()
+	// 上次写入时崩溃留下不完整的末行，补上换行使之后的记录另起一行
+	if j.size > 0 {
+		last := make([]byte, 1)
+		if r, err := os.Open(j.path); err == nil {
+			_, err = r.ReadAt(last, j.size-1)
+			r.Close()
+			if err == nil && last[0] != '\n' {
+				n, err := f.Write([]byte{'\n'})
+				j.size += int64(n)
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Append 写入一条记录并落盘，返回记录ID
+func (j *Journal) Append(payload interface{}) (string, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	entry := Entry{ID: hex.EncodeToString(id), ReceivedAt: time.Now(), Payload: raw}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.rotateIfFull(); err != nil {
+		return "", err
+	}
+	if err := j.write(entry); err != nil {
+		return "", err
+	}
+	if err := j.file.Sync(); err != nil {
+		return "", err
+	}
+	return entry.ID, nil
+}
+
+// Done 追加记录的完成标记；标记不落盘，崩溃时最多导致重复重放
+func (j *Journal) Done(id string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.write(doneLine{Done: id})
+}
+
+// write 写入一行
+func (j *Journal) write(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	n, err := j.file.Write(append(data, '\n'))
+	j.size += int64(n)
+	return err
+}
+
+// rotateIfFull 当前文件超过上限时改名为上一代并新建文件
+func (j *Journal) rotateIfFull() error {
+	if j.maxBytes <= 0 || j.size < j.maxBytes {
+		return nil
+	}
+	if err := j.file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(j.path, j.path+previousSuffix); err != nil {
+		return err
+	}
+	return j.open()
+}
+
+// Close 关闭日志文件
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.file.Close()
+}
+
+// Pending 读取 path 及其上一代文件中没有完成标记的记录，按写入顺序返回
+// 不完整的末行（写入时崩溃）被忽略
+func Pending(path string) ([]Entry, error) {
+	var entries []Entry
+	done := make(map[string]bool)
+	for _, p := range []string{path + previousSuffix, path} {
+		data, err := os.ReadFile(p)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(nil, len(data)+1)
+		for scanner.Scan() {
+			var l line
+			if err := json.Unmarshal(scanner.Bytes(), &l); err != nil {
+				continue
+			}
+			if l.Done != "" {
+				done[l.Done] = true
+			} else if l.ID != "" {
+				entries = append(entries, l.Entry)
+			}
+		}
+	}
+
+	pending := entries[:0]
+	for _, e := range entries {
+		if !done[e.ID] {
+			pending = append(pending, e)
+		}
+	}
+	return pending, nil
+}
@@ -0,0 +1,583 @@
+// Package ml 机器学习评分模型：加载离线用标注数据训练并导出为ONNX格式的模型，按特征向量给出爬虫概率。
+// 不依赖ONNX Runtime，以纯Go解码模型并解释执行计算图，只支持小型逻辑回归和多层感知机常用的算子
+// （见 operators），使用其他算子的模型在加载时报错
+package ml
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ONNX ModelProto 字段编号
+const (
+	fieldModelProducerName protowire.Number = 2
+	fieldModelVersion      protowire.Number = 5
+	fieldModelGraph        protowire.Number = 7
+	fieldModelMetadata     protowire.Number = 14
+)
+
+// GraphProto 字段编号
+const (
+	fieldGraphNode        protowire.Number = 1
+	fieldGraphInitializer protowire.Number = 5
+	fieldGraphInput       protowire.Number = 11
+	fieldGraphOutput      protowire.Number = 12
+)
+
+// NodeProto 字段编号
+const (
+	fieldNodeInput     protowire.Number = 1
+	fieldNodeOutput    protowire.Number = 2
+	fieldNodeOpType    protowire.Number = 4
+	fieldNodeAttribute protowire.Number = 5
+	fieldNodeDomain    protowire.Number = 7
+)
+
+// AttributeProto 字段编号
+const (
+	fieldAttrName   protowire.Number = 1
+	fieldAttrFloat  protowire.Number = 2
+	fieldAttrInt    protowire.Number = 3
+	fieldAttrTensor protowire.Number = 5
+	fieldAttrFloats protowire.Number = 7
+	fieldAttrInts   protowire.Number = 8
+)
+
+// TensorProto 字段编号
+const (
+	fieldTensorDims       protowire.Number = 1
+	fieldTensorDataType   protowire.Number = 2
+	fieldTensorFloatData  protowire.Number = 4
+	fieldTensorInt64Data  protowire.Number = 7
+	fieldTensorName       protowire.Number = 8
+	fieldTensorRawData    protowire.Number = 9
+	fieldTensorDoubleData protowire.Number = 10
+)
+
+// ValueInfoProto、TypeProto、TensorShapeProto 及 StringStringEntryProto 字段编号
+const (
+	fieldValueName       protowire.Number = 1
+	fieldValueType       protowire.Number = 2
+	fieldTypeTensor      protowire.Number = 1
+	fieldTensorTypeShape protowire.Number = 2
+	fieldShapeDim        protowire.Number = 1
+	fieldDimValue        protowire.Number = 1
+	fieldEntryKey        protowire.Number = 1
+	fieldEntryValue      protowire.Number = 2
+)
+
+// 支持的张量元素类型
+const (
+	dataTypeFloat  = 1
+	dataTypeInt64  = 7
+	dataTypeDouble = 11
+)
+
+// maxTensorElements 单个张量的最大元素数，防止异常模型耗尽内存
+const maxTensorElements = 1 << 24
+
+// Model 已加载的模型
+type Model struct {
+	// Version 模型版本：元数据 version，没有时为 model_version，都没有时为文件哈希
+	Version string
+	// Hash 模型文件 SHA-256 的前12位十六进制
+	Hash string
+	// Producer 导出模型的工具
+	Producer string
+	// Metadata 模型的 metadata_props
+	Metadata map[string]string
+	// InputSize 输入特征数，模型未声明时为0
+	InputSize int
+
+	input        string
+	output       string
+	initializers map[string]*tensor
+	nodes        []graphNode
+}
+
+// graphNode 计算图中的节点
+type graphNode struct {
+	op      string
+	inputs  []string
+	outputs []string
+	attrs   map[string]attribute
+}
+
+// attribute 节点属性，按类型只使用其中一个字段
+type attribute struct {
+	f      float64
+	i      int64
+	t      *tensor
+	floats []float64
+	ints   []int64
+}
+
+// Load 读取并校验ONNX模型文件
+func Load(path string) (*Model, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid model %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Decode 解码并校验ONNX模型：计算图须只有一个输入、所有算子都受支持，且能对全0输入给出0~1的概率
+func Decode(data []byte) (*Model, error) {
+	sum := sha256.Sum256(data)
+	m := &Model{
+		Hash:         hex.EncodeToString(sum[:])[:12],
+		Metadata:     make(map[string]string),
+		initializers: make(map[string]*tensor),
+	}
+	var modelVersion int64
+	var graph []byte
+	err := walk(data, func(num protowire.Number, typ protowire.Type, v uint64, b []byte) error {
+		switch num {
+		case fieldModelProducerName:
+			m.Producer = string(b)
+		case fieldModelVersion:
+			modelVersion = int64(v)
+		case fieldModelGraph:
+			graph = b
+		case fieldModelMetadata:
+			var key, value string
+			if err := walk(b, func(num protowire.Number, _ protowire.Type, _ uint64, b []byte) error {
+				switch num {
+				case fieldEntryKey:
+					key = string(b)
+				case fieldEntryValue:
+					value = string(b)
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			m.Metadata[key] = value
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if graph == nil {
+		return nil, errors.New("model has no graph")
+	}
+	if err := m.decodeGraph(graph); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case m.Metadata["version"] != "":
+		m.Version = m.Metadata["version"]
+	case modelVersion > 0:
+		m.Version = strconv.FormatInt(modelVersion, 10)
+	default:
+		m.Version = m.Hash
+	}
+
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// decodeGraph 解码计算图的节点、常量、输入和输出
+func (m *Model) decodeGraph(b []byte) error {
+	var inputs []string
+	inputSizes := make(map[string]int)
+	err := walk(b, func(num protowire.Number, typ protowire.Type, v uint64, b []byte) error {
+		switch num {
+		case fieldGraphNode:
+			n, err := decodeNode(b)
+			if err != nil {
+				return err
+			}
+			m.nodes = append(m.nodes, n)
+		case fieldGraphInitializer:
+			name, t, err := decodeTensor(b)
+			if err != nil {
+				return err
+			}
+			m.initializers[name] = t
+		case fieldGraphInput:
+			name, size, err := decodeValueInfo(b)
+			if err != nil {
+				return err
+			}
+			inputs = append(inputs, name)
+			inputSizes[name] = size
+		case fieldGraphOutput:
+			if m.output == "" {
+				name, _, err := decodeValueInfo(b)
+				if err != nil {
+					return err
+				}
+				m.output = name
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// 旧版导出工具把常量也列在输入中
+	for _, name := range inputs {
+		if _, ok := m.initializers[name]; ok {
+			continue
+		}
+		if m.input != "" {
+			return errors.New("model must have exactly one input")
+		}
+		m.input = name
+		m.InputSize = inputSizes[name]
+	}
+	if m.input == "" {
+		return errors.New("model has no input")
+	}
+	if m.output == "" {
+		return errors.New("model has no output")
+	}
+	return nil
+}
+
+// check 校验算子和数据流，并以全0输入试运行
+func (m *Model) check() error {
+	defined := map[string]bool{m.input: true, "": true}
+	for name := range m.initializers {
+		defined[name] = true
+	}
+	for _, n := range m.nodes {
+		if _, ok := operators[n.op]; !ok {
+			return fmt.Errorf("unsupported operator %s", n.op)
+		}
+		for _, in := range n.inputs {
+			if !defined[in] {
+				return fmt.Errorf("operator %s uses %q before it is computed", n.op, in)
+			}
+		}
+		// 算子只计算第一个输出，其余输出（如可选的掩码）不能被引用
+		if len(n.outputs) > 0 {
+			defined[n.outputs[0]] = true
+		}
+	}
+	if !defined[m.output] {
+		return fmt.Errorf("model output %q is never computed", m.output)
+	}
+	if m.InputSize > 0 {
+		if _, err := m.Predict(make([]float64, m.InputSize)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Predict 按特征向量给出爬虫概率。模型输出一个值时即为概率，
+// 输出两个值时（[人类, 爬虫] 两类的概率）取第二个；输出不在0~1时返回错误
+func (m *Model) Predict(features []float64) (float64, error) {
+	if m.InputSize > 0 && len(features) != m.InputSize {
+		return 0, fmt.Errorf("model expects %d features, got %d", m.InputSize, len(features))
+	}
+	values := make(map[string]*tensor, len(m.initializers)+len(m.nodes)+1)
+	for name, t := range m.initializers {
+		values[name] = t
+	}
+	values[m.input] = &tensor{shape: []int{1, len(features)}, data: append([]float64(nil), features...)}
+
+	for _, n := range m.nodes {
+		args := make([]*tensor, len(n.inputs))
+		for i, in := range n.inputs {
+			args[i] = values[in]
+		}
+		out, err := operators[n.op](args, n.attrs)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", n.op, err)
+		}
+		if out.size() > maxTensorElements {
+			return 0, fmt.Errorf("%s: tensor larger than %d elements", n.op, maxTensorElements)
+		}
+		if len(n.outputs) > 0 {
+			values[n.outputs[0]] = out
+		}
+	}
+
+	out := values[m.output].data
+	var p float64
+	switch len(out) {
+	case 1:
+		p = out[0]
+	case 2:
+		p = out[1]
+	default:
+		return 0, fmt.Errorf("model output has %d values, want 1 or 2", len(out))
+	}
+	if math.IsNaN(p) || p < 0 || p > 1 {
+		return 0, fmt.Errorf("model output %v is not a probability", p)
+	}
+	return p, nil
+}
+
+// walk 依次回调消息的每个字段：varint、fixed32 和 fixed64 类型的值在 v 中，bytes 类型的值在 b 中
+func walk(msg []byte, fn func(num protowire.Number, typ protowire.Type, v uint64, b []byte) error) error {
+	for len(msg) > 0 {
+		num, typ, n := protowire.ConsumeTag(msg)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		msg = msg[n:]
+		var v uint64
+		var b []byte
+		switch typ {
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(msg)
+		case protowire.Fixed32Type:
+			var x uint32
+			x, n = protowire.ConsumeFixed32(msg)
+			v = uint64(x)
+		case protowire.Fixed64Type:
+			v, n = protowire.ConsumeFixed64(msg)
+		case protowire.BytesType:
+			b, n = protowire.ConsumeBytes(msg)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, msg)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		msg = msg[n:]
+		if err := fn(num, typ, v, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeNode 解码计算图节点
+func decodeNode(b []byte) (graphNode, error) {
+	n := graphNode{attrs: make(map[string]attribute)}
+	var domain string
+	err := walk(b, func(num protowire.Number, typ protowire.Type, v uint64, b []byte) error {
+		switch num {
+		case fieldNodeInput:
+			n.inputs = append(n.inputs, string(b))
+		case fieldNodeOutput:
+			n.outputs = append(n.outputs, string(b))
+		case fieldNodeOpType:
+			n.op = string(b)
+		case fieldNodeDomain:
+			domain = string(b)
+		case fieldNodeAttribute:
+			name, attr, err := decodeAttribute(b)
+			if err != nil {
+				return err
+			}
+			n.attrs[name] = attr
+		}
+		return nil
+	})
+	if err == nil && domain != "" && domain != "ai.onnx" {
+		err = fmt.Errorf("unsupported operator %s:%s", domain, n.op)
+	}
+	return n, err
+}
+
+// decodeAttribute 解码节点属性
+func decodeAttribute(b []byte) (string, attribute, error) {
+	var name string
+	var a attribute
+	err := walk(b, func(num protowire.Number, typ protowire.Type, v uint64, b []byte) error {
+		switch num {
+		case fieldAttrName:
+			name = string(b)
+		case fieldAttrFloat:
+			a.f = float64(math.Float32frombits(uint32(v)))
+		case fieldAttrInt:
+			a.i = int64(v)
+		case fieldAttrTensor:
+			_, t, err := decodeTensor(b)
+			if err != nil {
+				return err
+			}
+			a.t = t
+		case fieldAttrFloats:
+			floats, err := decodeFloats(typ, v, b)
+			if err != nil {
+				return err
+			}
+			a.floats = append(a.floats, floats...)
+		case fieldAttrInts:
+			ints, err := decodeInts(typ, v, b)
+			if err != nil {
+				return err
+			}
+			a.ints = append(a.ints, ints...)
+		}
+		return nil
+	})
+	return name, a, err
+}
+
+// decodeTensor 解码常量张量，支持 float、double 和 int64 元素，int64 也转换为 float64 保存
+func decodeTensor(b []byte) (string, *tensor, error) {
+	var name string
+	var dataType uint64
+	var raw []byte
+	t := &tensor{}
+	err := walk(b, func(num protowire.Number, typ protowire.Type, v uint64, b []byte) error {
+		switch num {
+		case fieldTensorDims:
+			dims, err := decodeInts(typ, v, b)
+			if err != nil {
+				return err
+			}
+			for _, d := range dims {
+				if d < 0 || d > maxTensorElements {
+					return fmt.Errorf("invalid tensor dimension %d", d)
+				}
+				t.shape = append(t.shape, int(d))
+			}
+		case fieldTensorDataType:
+			dataType = v
+		case fieldTensorName:
+			name = string(b)
+		case fieldTensorRawData:
+			raw = b
+		case fieldTensorFloatData:
+			floats, err := decodeFloats(typ, v, b)
+			if err != nil {
+				return err
+			}
+			t.data = append(t.data, floats...)
+		case fieldTensorInt64Data:
+			ints, err := decodeInts(typ, v, b)
+			if err != nil {
+				return err
+			}
+			for _, x := range ints {
+				t.data = append(t.data, float64(x))
+			}
+		case fieldTensorDoubleData:
+			if typ == protowire.BytesType {
+				for i := 0; i+8 <= len(b); i += 8 {
+					t.data = append(t.data, math.Float64frombits(binary.LittleEndian.Uint64(b[i:])))
+				}
+			} else {
+				t.data = append(t.data, math.Float64frombits(v))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	if raw != nil {
+		switch dataType {
+		case dataTypeFloat:
+			for i := 0; i+4 <= len(raw); i += 4 {
+				t.data = append(t.data, float64(math.Float32frombits(binary.LittleEndian.Uint32(raw[i:]))))
+			}
+		case dataTypeDouble:
+			for i := 0; i+8 <= len(raw); i += 8 {
+				t.data = append(t.data, math.Float64frombits(binary.LittleEndian.Uint64(raw[i:])))
+			}
+		case dataTypeInt64:
+			for i := 0; i+8 <= len(raw); i += 8 {
+				t.data = append(t.data, float64(int64(binary.LittleEndian.Uint64(raw[i:]))))
+			}
+		}
+	}
+	if dataType != dataTypeFloat && dataType != dataTypeDouble && dataType != dataTypeInt64 {
+		return "", nil, fmt.Errorf("tensor %q has unsupported data type %d", name, dataType)
+	}
+	if t.size() != len(t.data) {
+		return "", nil, fmt.Errorf("tensor %q has %d values for shape %v", name, len(t.data), t.shape)
+	}
+	return name, t, nil
+}
+
+// decodeValueInfo 解码计算图输入或输出的名称和最后一维的大小（未声明时为0）
+func decodeValueInfo(b []byte) (string, int, error) {
+	var name string
+	var dims []int
+	err := walk(b, func(num protowire.Number, _ protowire.Type, _ uint64, b []byte) error {
+		switch num {
+		case fieldValueName:
+			name = string(b)
+		case fieldValueType:
+			return walk(b, func(num protowire.Number, _ protowire.Type, _ uint64, b []byte) error {
+				if num != fieldTypeTensor {
+					return nil
+				}
+				return walk(b, func(num protowire.Number, _ protowire.Type, _ uint64, b []byte) error {
+					if num != fieldTensorTypeShape {
+						return nil
+					}
+					return walk(b, func(num protowire.Number, _ protowire.Type, _ uint64, b []byte) error {
+						if num != fieldShapeDim {
+							return nil
+						}
+						size := 0
+						err := walk(b, func(num protowire.Number, _ protowire.Type, v uint64, _ []byte) error {
+							if num == fieldDimValue {
+								size = int(v)
+							}
+							return nil
+						})
+						dims = append(dims, size)
+						return err
+					})
+				})
+			})
+		}
+		return nil
+	})
+	if len(dims) == 0 {
+		return name, 0, err
+	}
+	return name, dims[len(dims)-1], err
+}
+
+// decodeFloats 解码打包或未打包的 repeated float 字段
+func decodeFloats(typ protowire.Type, v uint64, b []byte) ([]float64, error) {
+	if typ != protowire.BytesType {
+		return []float64{float64(math.Float32frombits(uint32(v)))}, nil
+	}
+	if len(b)%4 != 0 {
+		return nil, errors.New("invalid packed floats")
+	}
+	floats := make([]float64, 0, len(b)/4)
+	for i := 0; i < len(b); i += 4 {
+		floats = append(floats, float64(math.Float32frombits(binary.LittleEndian.Uint32(b[i:]))))
+	}
+	return floats, nil
+}
+
+// decodeInts 解码打包或未打包的 repeated int64 字段
+func decodeInts(typ protowire.Type, v uint64, b []byte) ([]int64, error) {
+	if typ != protowire.BytesType {
+		return []int64{int64(v)}, nil
+	}
+	var ints []int64
+	for len(b) > 0 {
+		x, n := protowire.ConsumeVarint(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		ints = append(ints, int64(x))
+		b = b[n:]
+	}
+	return ints, nil
+}
@@ -0,0 +1,414 @@
+package ml
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// 以下函数按 ONNX 的 protobuf 定义编码测试模型，字段编号与 onnx.go 中的常量一致
+
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	return appendMessage(b, num, []byte(s))
+}
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func packedInts(values []int64) []byte {
+	var b []byte
+	for _, v := range values {
+		b = protowire.AppendVarint(b, uint64(v))
+	}
+	return b
+}
+
+// floatTensor float 张量，raw 为 true 时写入 raw_data，否则写入打包的 float_data
+func floatTensor(name string, dims []int64, values []float32, raw bool) []byte {
+	b := appendMessage(nil, fieldTensorDims, packedInts(dims))
+	b = appendVarint(b, fieldTensorDataType, dataTypeFloat)
+	b = appendString(b, fieldTensorName, name)
+	data := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(v))
+	}
+	if raw {
+		return appendMessage(b, fieldTensorRawData, data)
+	}
+	return appendMessage(b, fieldTensorFloatData, data)
+}
+
+// doubleTensor double 张量，raw 为 true 时写入 raw_data，否则写入打包的 double_data
+func doubleTensor(name string, dims []int64, values []float64, raw bool) []byte {
+	b := appendMessage(nil, fieldTensorDims, packedInts(dims))
+	b = appendVarint(b, fieldTensorDataType, dataTypeDouble)
+	b = appendString(b, fieldTensorName, name)
+	data := make([]byte, 8*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint64(data[8*i:], math.Float64bits(v))
+	}
+	if raw {
+		return appendMessage(b, fieldTensorRawData, data)
+	}
+	return appendMessage(b, fieldTensorDoubleData, data)
+}
+
+// int64Tensor int64 张量，写入打包的 int64_data
+func int64Tensor(name string, dims []int64, values []int64) []byte {
+	b := appendMessage(nil, fieldTensorDims, packedInts(dims))
+	b = appendVarint(b, fieldTensorDataType, dataTypeInt64)
+	b = appendString(b, fieldTensorName, name)
+	return appendMessage(b, fieldTensorInt64Data, packedInts(values))
+}
+
+func attrFloat(name string, v float32) []byte {
+	b := appendString(nil, fieldAttrName, name)
+	b = protowire.AppendTag(b, fieldAttrFloat, protowire.Fixed32Type)
+	return protowire.AppendFixed32(b, math.Float32bits(v))
+}
+
+func attrInt(name string, v int64) []byte {
+	b := appendString(nil, fieldAttrName, name)
+	return appendVarint(b, fieldAttrInt, uint64(v))
+}
+
+func attrTensor(name string, t []byte) []byte {
+	b := appendString(nil, fieldAttrName, name)
+	return appendMessage(b, fieldAttrTensor, t)
+}
+
+func nodeProto(op string, inputs, outputs []string, attrs ...[]byte) []byte {
+	var b []byte
+	for _, in := range inputs {
+		b = appendString(b, fieldNodeInput, in)
+	}
+	for _, out := range outputs {
+		b = appendString(b, fieldNodeOutput, out)
+	}
+	b = appendString(b, fieldNodeOpType, op)
+	for _, a := range attrs {
+		b = appendMessage(b, fieldNodeAttribute, a)
+	}
+	return b
+}
+
+// valueInfo 计算图输入或输出，dims 为0时写入没有 dim_value 的维度
+func valueInfo(name string, dims ...int64) []byte {
+	var shape []byte
+	for _, d := range dims {
+		var dim []byte
+		if d > 0 {
+			dim = appendVarint(nil, fieldDimValue, uint64(d))
+		}
+		shape = appendMessage(shape, fieldShapeDim, dim)
+	}
+	tensorType := appendVarint(nil, 1, dataTypeFloat)
+	tensorType = appendMessage(tensorType, fieldTensorTypeShape, shape)
+	typ := appendMessage(nil, fieldTypeTensor, tensorType)
+	b := appendString(nil, fieldValueName, name)
+	return appendMessage(b, fieldValueType, typ)
+}
+
+// testGraph 测试模型的计算图
+type testGraph struct {
+	nodes        [][]byte
+	initializers [][]byte
+	inputs       [][]byte
+	outputs      [][]byte
+}
+
+func (g testGraph) encode() []byte {
+	var b []byte
+	for _, n := range g.nodes {
+		b = appendMessage(b, fieldGraphNode, n)
+	}
+	for _, t := range g.initializers {
+		b = appendMessage(b, fieldGraphInitializer, t)
+	}
+	for _, in := range g.inputs {
+		b = appendMessage(b, fieldGraphInput, in)
+	}
+	for _, out := range g.outputs {
+		b = appendMessage(b, fieldGraphOutput, out)
+	}
+	return b
+}
+
+// encodeModel 编码 ModelProto，metadata 按键值对顺序写入
+func encodeModel(producer string, version int64, graph testGraph, metadata ...string) []byte {
+	b := appendString(nil, fieldModelProducerName, producer)
+	if version > 0 {
+		b = appendVarint(b, fieldModelVersion, uint64(version))
+	}
+	b = appendMessage(b, fieldModelGraph, graph.encode())
+	for i := 0; i+1 < len(metadata); i += 2 {
+		entry := appendString(nil, fieldEntryKey, metadata[i])
+		entry = appendString(entry, fieldEntryValue, metadata[i+1])
+		b = appendMessage(b, fieldModelMetadata, entry)
+	}
+	return b
+}
+
+// logisticRegression 逻辑回归：sigmoid(x·W + B)，W 为 raw_data，B 为 float_data
+func logisticRegression() testGraph {
+	return testGraph{
+		nodes: [][]byte{
+			nodeProto("MatMul", []string{"x", "W"}, []string{"xw"}),
+			nodeProto("Add", []string{"xw", "B"}, []string{"logit"}),
+			nodeProto("Sigmoid", []string{"logit"}, []string{"p"}),
+		},
+		initializers: [][]byte{
+			floatTensor("W", []int64{3, 1}, []float32{0.75, -0.5, 1.25}, true),
+			floatTensor("B", []int64{1}, []float32{-0.5}, false),
+		},
+		inputs:  [][]byte{valueInfo("x", 0, 3)},
+		outputs: [][]byte{valueInfo("p", 0, 1)},
+	}
+}
+
+// multilayerPerceptron 标准化后的两层感知机：
+// z = (x - mean) / scale，h = LeakyRelu(z·W1ᵀ + b1, 0.5)，输出 Softmax(h·W2ᵀ + b2) 两类的概率；
+// 常量分别以 double_data、double raw_data、float raw_data、float_data 和 Constant 节点给出，
+// 并经过 Reshape、Flatten 和 Identity；旧版导出工具的写法，常量也列在计算图的输入中
+func multilayerPerceptron() testGraph {
+	return testGraph{
+		nodes: [][]byte{
+			nodeProto("Sub", []string{"x", "mean"}, []string{"centered"}),
+			nodeProto("Div", []string{"centered", "scale"}, []string{"z"}),
+			nodeProto("Constant", nil, []string{"shape"}, attrTensor("value", int64Tensor("", []int64{2}, []int64{1, -1}))),
+			nodeProto("Reshape", []string{"z", "shape"}, []string{"z2"}),
+			nodeProto("Flatten", []string{"z2"}, []string{"z3"}, attrInt("axis", 1)),
+			nodeProto("Identity", []string{"z3"}, []string{"z4"}),
+			nodeProto("Gemm", []string{"z4", "W1", "b1"}, []string{"g1"}, attrInt("transB", 1)),
+			nodeProto("LeakyRelu", []string{"g1"}, []string{"h"}, attrFloat("alpha", 0.5)),
+			nodeProto("Gemm", []string{"h", "W2", "b2"}, []string{"logits"}, attrInt("transB", 1), attrFloat("alpha", 1), attrFloat("beta", 1)),
+			nodeProto("Softmax", []string{"logits"}, []string{"probabilities"}, attrInt("axis", -1)),
+		},
+		initializers: [][]byte{
+			doubleTensor("mean", []int64{3}, []float64{1, 2, 0.5}, false),
+			doubleTensor("scale", []int64{1, 3}, []float64{2, 4, 0.25}, true),
+			floatTensor("W1", []int64{4, 3}, []float32{
+				0.5, -1, 0.25,
+				1, 1, -0.5,
+				-0.75, 0.5, 1,
+				0.25, 0.25, 0.25,
+			}, true),
+			floatTensor("b1", []int64{4}, []float32{0.5, -0.25, 0, 1}, false),
+			floatTensor("W2", []int64{2, 4}, []float32{
+				0.5, -1, 0.25, 1,
+				-0.5, 1.5, -0.25, 0.75,
+			}, true),
+			floatTensor("b2", []int64{2}, []float32{0.25, -0.25}, true),
+		},
+		inputs: [][]byte{
+			valueInfo("mean", 3),
+			valueInfo("x", 1, 3),
+			valueInfo("W1", 4, 3),
+		},
+		outputs: [][]byte{valueInfo("probabilities", 1, 2)},
+	}
+}
+
+// 期望值由独立的参考实现按同样的权重以双精度计算
+func TestPredictGolden(t *testing.T) {
+	tests := []struct {
+		name  string
+		graph testGraph
+		cases map[[3]float64]float64
+	}{
+		{"logistic regression", logisticRegression(), map[[3]float64]float64{
+			{0, 0, 0}:     0.3775406687981454,
+			{1, 2, 0.5}:   0.46879062662624377,
+			{3, -2, 1}:    0.9820137900379085,
+			{-4, 1, 0.25}: 0.02442309005410721,
+		}},
+		{"multilayer perceptron", multilayerPerceptron(), map[[3]float64]float64{
+			{0, 0, 0}:    0.341582499438317,
+			{1, 2, 0.5}:  0.17328820592932656,
+			{3, -2, 1}:   0.004905405705722035,
+			{10, 10, 10}: 2.2774057196530822e-21,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Decode(encodeModel("test", 0, tt.graph))
+			if err != nil {
+				t.Fatalf("Decode error: %v", err)
+			}
+			if m.InputSize != 3 {
+				t.Fatalf("InputSize = %d, want 3", m.InputSize)
+			}
+			for x, want := range tt.cases {
+				got, err := m.Predict(x[:])
+				if err != nil {
+					t.Fatalf("Predict(%v) error: %v", x, err)
+				}
+				if math.Abs(got-want) > 1e-12*want {
+					t.Errorf("Predict(%v) = %v, want %v", x, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestModelVersion(t *testing.T) {
+	graph := logisticRegression()
+	tests := []struct {
+		name     string
+		data     []byte
+		version  string
+		producer string
+	}{
+		{"metadata version", encodeModel("skl2onnx", 3, graph, "version", "lr-2026.10", "trained_on", "labels"), "lr-2026.10", "skl2onnx"},
+		{"model_version", encodeModel("tf2onnx", 7, graph), "7", "tf2onnx"},
+	}
+	for _, tt := range tests {
+		m, err := Decode(tt.data)
+		if err != nil {
+			t.Fatalf("%s: Decode error: %v", tt.name, err)
+		}
+		if m.Version != tt.version || m.Producer != tt.producer {
+			t.Errorf("%s: version %q producer %q, want %q %q", tt.name, m.Version, m.Producer, tt.version, tt.producer)
+		}
+		if len(m.Hash) != 12 {
+			t.Errorf("%s: hash %q, want 12 hex digits", tt.name, m.Hash)
+		}
+	}
+
+	m, err := Decode(encodeModel("test", 0, graph))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Version != m.Hash {
+		t.Fatalf("version %q, want the file hash %q", m.Version, m.Hash)
+	}
+	if m.Metadata["trained_on"] != "" {
+		t.Fatalf("metadata = %v, want empty", m.Metadata)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.onnx")
+	if err := os.WriteFile(path, encodeModel("test", 1, logisticRegression()), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if p, err := m.Predict([]float64{3, -2, 1}); err != nil || math.Abs(p-0.9820137900379085) > 1e-12 {
+		t.Fatalf("Predict = %v, %v", p, err)
+	}
+
+	if err := os.WriteFile(path, []byte("not a model"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "invalid model") {
+		t.Fatalf("Load of a corrupt file error = %v, want invalid model", err)
+	}
+}
+
+func TestPredictErrors(t *testing.T) {
+	m, err := Decode(encodeModel("test", 0, logisticRegression()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Predict([]float64{1, 2}); err == nil || !strings.Contains(err.Error(), "expects 3 features, got 2") {
+		t.Fatalf("Predict with 2 features error = %v", err)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	lr := logisticRegression()
+	withNodes := func(nodes ...[]byte) testGraph {
+		g := lr
+		g.nodes = nodes
+		return g
+	}
+	withOutputs := func(g testGraph, outputs ...[]byte) testGraph {
+		g.outputs = outputs
+		return g
+	}
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"truncated", encodeModel("test", 0, lr)[:20], "unexpected EOF"},
+		{"no graph", appendString(nil, fieldModelProducerName, "test"), "model has no graph"},
+		{"unsupported operator", encodeModel("test", 0, withNodes(
+			nodeProto("MatMul", []string{"x", "W"}, []string{"xw"}),
+			nodeProto("Erf", []string{"xw"}, []string{"p"}),
+		)), "unsupported operator Erf"},
+		{"custom domain", encodeModel("test", 0, withNodes(
+			appendString(nodeProto("Sigmoid", []string{"x"}, []string{"p"}), fieldNodeDomain, "ai.onnx.ml"),
+		)), "unsupported operator ai.onnx.ml:Sigmoid"},
+		{"use before compute", encodeModel("test", 0, withNodes(
+			nodeProto("Sigmoid", []string{"logit"}, []string{"p"}),
+			nodeProto("MatMul", []string{"x", "W"}, []string{"logit"}),
+		)), `uses "logit" before it is computed`},
+		{"second output", encodeModel("test", 0, withNodes(
+			nodeProto("MatMul", []string{"x", "W"}, []string{"xw", "mask"}),
+			nodeProto("Sigmoid", []string{"mask"}, []string{"p"}),
+		)), `uses "mask" before it is computed`},
+		{"output never computed", encodeModel("test", 0, withOutputs(lr, valueInfo("missing", 1))), `model output "missing" is never computed`},
+		{"two inputs", encodeModel("test", 0, testGraph{
+			nodes:        lr.nodes,
+			initializers: lr.initializers,
+			inputs:       [][]byte{valueInfo("x", 0, 3), valueInfo("y", 0, 3)},
+			outputs:      lr.outputs,
+		}), "exactly one input"},
+		{"no input", encodeModel("test", 0, testGraph{nodes: lr.nodes, initializers: lr.initializers, outputs: lr.outputs}), "model has no input"},
+		{"no output", encodeModel("test", 0, withOutputs(lr)), "model has no output"},
+		{"not a probability", encodeModel("test", 0, withNodes(
+			nodeProto("MatMul", []string{"x", "W"}, []string{"xw"}),
+			nodeProto("Add", []string{"xw", "B"}, []string{"p"}),
+		)), "is not a probability"},
+		{"too many output values", encodeModel("test", 0, withNodes(
+			nodeProto("Identity", []string{"x"}, []string{"p"}),
+		)), "model output has 3 values"},
+		{"shape mismatch", encodeModel("test", 0, testGraph{
+			nodes:        lr.nodes,
+			initializers: [][]byte{floatTensor("W", []int64{3, 2}, []float32{1, 2, 3}, true), lr.initializers[1]},
+			inputs:       lr.inputs,
+			outputs:      lr.outputs,
+		}), `tensor "W" has 3 values for shape [3 2]`},
+		{"unsupported data type", encodeModel("test", 0, testGraph{
+			nodes:        lr.nodes,
+			initializers: [][]byte{appendVarint(appendString(nil, fieldTensorName, "W"), fieldTensorDataType, 8), lr.initializers[1]},
+			inputs:       lr.inputs,
+			outputs:      lr.outputs,
+		}), `tensor "W" has unsupported data type 8`},
+		{"huge dimension", encodeModel("test", 0, testGraph{
+			nodes:        lr.nodes,
+			initializers: [][]byte{floatTensor("W", []int64{1 << 30}, nil, true), lr.initializers[1]},
+			inputs:       lr.inputs,
+			outputs:      lr.outputs,
+		}), "invalid tensor dimension"},
+		{"inner product mismatch", encodeModel("test", 0, testGraph{
+			nodes:        lr.nodes,
+			initializers: lr.initializers,
+			inputs:       [][]byte{valueInfo("x", 0, 4)},
+			outputs:      lr.outputs,
+		}), "MatMul: cannot multiply shapes [1 4] and [3 1]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode(tt.data); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Decode error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
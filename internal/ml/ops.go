@@ -0,0 +1,353 @@
+package ml
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// tensor 行优先存储的张量，形状为空时是标量
+type tensor struct {
+	shape []int
+	data  []float64
+}
+
+// size 按形状计算元素数
+func (t *tensor) size() int {
+	n := 1
+	for _, d := range t.shape {
+		n *= d
+		if n > maxTensorElements {
+			return maxTensorElements + 1
+		}
+	}
+	return n
+}
+
+// operator 算子：按输入张量和属性计算输出张量，只支持单输出
+type operator func(args []*tensor, attrs map[string]attribute) (*tensor, error)
+
+// operators 支持的算子，覆盖常见逻辑回归和多层感知机导出后的计算图
+var operators = map[string]operator{
+	"Identity":  unary(func(x float64) float64 { return x }),
+	"Relu":      unary(func(x float64) float64 { return math.Max(x, 0) }),
+	"Sigmoid":   unary(sigmoid),
+	"Tanh":      unary(math.Tanh),
+	"LeakyRelu": leakyRelu,
+	"Softmax":   softmax,
+	"Add":       elementwise(func(a, b float64) float64 { return a + b }),
+	"Sub":       elementwise(func(a, b float64) float64 { return a - b }),
+	"Mul":       elementwise(func(a, b float64) float64 { return a * b }),
+	"Div":       elementwise(func(a, b float64) float64 { return a / b }),
+	"MatMul":    matMul,
+	"Gemm":      gemm,
+	"Flatten":   flatten,
+	"Reshape":   reshape,
+	"Constant":  constant,
+}
+
+// sigmoid 逻辑函数
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
+
+// need 检查输入个数
+func need(args []*tensor, n int) error {
+	if len(args) < n {
+		return fmt.Errorf("needs %d inputs, got %d", n, len(args))
+	}
+	for _, a := range args[:n] {
+		if a == nil {
+			return errors.New("missing input")
+		}
+	}
+	return nil
+}
+
+// unary 逐元素的一元算子
+func unary(f func(float64) float64) operator {
+	return func(args []*tensor, _ map[string]attribute) (*tensor, error) {
+		if err := need(args, 1); err != nil {
+			return nil, err
+		}
+		out := &tensor{shape: args[0].shape, data: make([]float64, len(args[0].data))}
+		for i, x := range args[0].data {
+			out.data[i] = f(x)
+		}
+		return out, nil
+	}
+}
+
+// leakyRelu 负半轴乘以 alpha（默认0.01）
+func leakyRelu(args []*tensor, attrs map[string]attribute) (*tensor, error) {
+	alpha := 0.01
+	if a, ok := attrs["alpha"]; ok {
+		alpha = a.f
+	}
+	return unary(func(x float64) float64 {
+		if x < 0 {
+			return alpha * x
+		}
+		return x
+	})(args, attrs)
+}
+
+// softmax 沿最后一维归一化
+func softmax(args []*tensor, attrs map[string]attribute) (*tensor, error) {
+	if err := need(args, 1); err != nil {
+		return nil, err
+	}
+	x := args[0]
+	if len(x.shape) == 0 {
+		return nil, errors.New("needs a tensor with at least one dimension")
+	}
+	if a, ok := attrs["axis"]; ok && a.i != -1 && int(a.i) != len(x.shape)-1 {
+		return nil, errors.New("only the last axis is supported")
+	}
+	n := x.shape[len(x.shape)-1]
+	out := &tensor{shape: x.shape, data: make([]float64, len(x.data))}
+	for start := 0; start+n <= len(x.data) && n > 0; start += n {
+		row := x.data[start : start+n]
+		peak := math.Inf(-1)
+		for _, v := range row {
+			peak = math.Max(peak, v)
+		}
+		sum := 0.0
+		for i, v := range row {
+			out.data[start+i] = math.Exp(v - peak)
+			sum += out.data[start+i]
+		}
+		for i := range row {
+			out.data[start+i] /= sum
+		}
+	}
+	return out, nil
+}
+
+// elementwise 支持NumPy式广播的逐元素二元算子
+func elementwise(f func(a, b float64) float64) operator {
+	return func(args []*tensor, _ map[string]attribute) (*tensor, error) {
+		if err := need(args, 2); err != nil {
+			return nil, err
+		}
+		a, b := args[0], args[1]
+		rank := max(len(a.shape), len(b.shape))
+		shape := make([]int, rank)
+		for i := range shape {
+			da, db := dim(a, i, rank), dim(b, i, rank)
+			switch {
+			case da == db || db == 1:
+				shape[i] = da
+			case da == 1:
+				shape[i] = db
+			default:
+				return nil, fmt.Errorf("cannot broadcast shapes %v and %v", a.shape, b.shape)
+			}
+		}
+		out := &tensor{shape: shape}
+		if out.size() > maxTensorElements {
+			return nil, fmt.Errorf("tensor larger than %d elements", maxTensorElements)
+		}
+		out.data = make([]float64, out.size())
+		index := make([]int, rank)
+		for i := range out.data {
+			out.data[i] = f(a.data[offset(a, index, rank)], b.data[offset(b, index, rank)])
+			for d := rank - 1; d >= 0; d-- {
+				index[d]++
+				if index[d] < shape[d] {
+					break
+				}
+				index[d] = 0
+			}
+		}
+		return out, nil
+	}
+}
+
+// dim 返回按 rank 右对齐后第 i 维的大小，缺少的维视为1
+func dim(t *tensor, i, rank int) int {
+	if j := i - (rank - len(t.shape)); j >= 0 {
+		return t.shape[j]
+	}
+	return 1
+}
+
+// offset 返回广播下标在张量中的位置，大小为1的维固定取0
+func offset(t *tensor, index []int, rank int) int {
+	pos := 0
+	for i := rank - len(t.shape); i < rank; i++ {
+		d := t.shape[i-(rank-len(t.shape))]
+		pos *= d
+		if d > 1 {
+			pos += index[i]
+		}
+	}
+	return pos
+}
+
+// matrix 将一维或二维张量视为矩阵，一维张量为 asRow 时视为行向量，否则视为列向量
+func matrix(t *tensor, asRow bool) (rows, cols int, err error) {
+	switch len(t.shape) {
+	case 1:
+		if asRow {
+			return 1, t.shape[0], nil
+		}
+		return t.shape[0], 1, nil
+	case 2:
+		return t.shape[0], t.shape[1], nil
+	}
+	return 0, 0, fmt.Errorf("needs a 1-D or 2-D tensor, got shape %v", t.shape)
+}
+
+// multiply 计算 op(A)·op(B)，transA、transB 表示先转置
+func multiply(a, b *tensor, transA, transB bool) (*tensor, error) {
+	ar, ac, err := matrix(a, true)
+	if err != nil {
+		return nil, err
+	}
+	br, bc, err := matrix(b, false)
+	if err != nil {
+		return nil, err
+	}
+	if transA {
+		ar, ac = ac, ar
+	}
+	if transB {
+		br, bc = bc, br
+	}
+	if ac != br {
+		return nil, fmt.Errorf("cannot multiply shapes %v and %v", a.shape, b.shape)
+	}
+	if ar*bc > maxTensorElements {
+		return nil, fmt.Errorf("tensor larger than %d elements", maxTensorElements)
+	}
+	at := func(i, k int) float64 {
+		if transA {
+			return a.data[k*ar+i]
+		}
+		return a.data[i*ac+k]
+	}
+	bt := func(k, j int) float64 {
+		if transB {
+			return b.data[j*br+k]
+		}
+		return b.data[k*bc+j]
+	}
+	out := &tensor{shape: []int{ar, bc}, data: make([]float64, ar*bc)}
+	for i := 0; i < ar; i++ {
+		for j := 0; j < bc; j++ {
+			sum := 0.0
+			for k := 0; k < ac; k++ {
+				sum += at(i, k) * bt(k, j)
+			}
+			out.data[i*bc+j] = sum
+		}
+	}
+	return out, nil
+}
+
+// matMul 矩阵乘法，只支持一维和二维
+func matMul(args []*tensor, _ map[string]attribute) (*tensor, error) {
+	if err := need(args, 2); err != nil {
+		return nil, err
+	}
+	return multiply(args[0], args[1], false, false)
+}
+
+// gemm alpha·op(A)·op(B) + beta·C
+func gemm(args []*tensor, attrs map[string]attribute) (*tensor, error) {
+	if err := need(args, 2); err != nil {
+		return nil, err
+	}
+	alpha, beta := 1.0, 1.0
+	if a, ok := attrs["alpha"]; ok {
+		alpha = a.f
+	}
+	if a, ok := attrs["beta"]; ok {
+		beta = a.f
+	}
+	out, err := multiply(args[0], args[1], attrs["transA"].i != 0, attrs["transB"].i != 0)
+	if err != nil {
+		return nil, err
+	}
+	for i := range out.data {
+		out.data[i] *= alpha
+	}
+	if len(args) < 3 || args[2] == nil {
+		return out, nil
+	}
+	c := &tensor{shape: args[2].shape, data: make([]float64, len(args[2].data))}
+	for i, v := range args[2].data {
+		c.data[i] = beta * v
+	}
+	return elementwise(func(a, b float64) float64 { return a + b })([]*tensor{out, c}, nil)
+}
+
+// flatten 以 axis（默认1）为界展平为二维
+func flatten(args []*tensor, attrs map[string]attribute) (*tensor, error) {
+	if err := need(args, 1); err != nil {
+		return nil, err
+	}
+	x := args[0]
+	axis := 1
+	if a, ok := attrs["axis"]; ok {
+		axis = int(a.i)
+	}
+	if axis < 0 {
+		axis += len(x.shape)
+	}
+	if axis < 0 || axis > len(x.shape) {
+		return nil, fmt.Errorf("invalid axis %d for shape %v", axis, x.shape)
+	}
+	rows := (&tensor{shape: x.shape[:axis]}).size()
+	return &tensor{shape: []int{rows, len(x.data) / max(rows, 1)}, data: x.data}, nil
+}
+
+// reshape 按第二个输入给出的形状重排，0 表示沿用原维度，-1 表示由其余维度推算
+func reshape(args []*tensor, _ map[string]attribute) (*tensor, error) {
+	if err := need(args, 2); err != nil {
+		return nil, err
+	}
+	x := args[0]
+	shape := make([]int, len(args[1].data))
+	known, infer := 1, -1
+	for i, v := range args[1].data {
+		d := int(v)
+		switch {
+		case d == 0 && i < len(x.shape):
+			d = x.shape[i]
+		case d == -1 && infer < 0:
+			infer = i
+			continue
+		case d <= 0:
+			return nil, fmt.Errorf("invalid shape %v", args[1].data)
+		}
+		shape[i] = d
+		known *= d
+	}
+	if infer >= 0 {
+		if known == 0 || len(x.data)%known != 0 {
+			return nil, fmt.Errorf("cannot reshape %v to %v", x.shape, args[1].data)
+		}
+		shape[infer] = len(x.data) / known
+	}
+	out := &tensor{shape: shape, data: x.data}
+	if out.size() != len(x.data) {
+		return nil, fmt.Errorf("cannot reshape %v to %v", x.shape, args[1].data)
+	}
+	return out, nil
+}
+
+// constant 输出 value 属性中的常量张量
+func constant(_ []*tensor, attrs map[string]attribute) (*tensor, error) {
+	if a, ok := attrs["value"]; ok && a.t != nil {
+		return a.t, nil
+	}
+	if a, ok := attrs["value_float"]; ok {
+		return &tensor{data: []float64{a.f}}, nil
+	}
+	if a, ok := attrs["value_floats"]; ok {
+		return &tensor{shape: []int{len(a.floats)}, data: a.floats}, nil
+	}
+	return nil, errors.New("needs a value attribute")
+}
@@ -0,0 +1,152 @@
+package ml
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTensor(shape []int, data ...float64) *tensor {
+	return &tensor{shape: shape, data: data}
+}
+
+func run(t *testing.T, op string, attrs map[string]attribute, args ...*tensor) *tensor {
+	t.Helper()
+	out, err := operators[op](args, attrs)
+	if err != nil {
+		t.Fatalf("%s error: %v", op, err)
+	}
+	return out
+}
+
+func assertTensor(t *testing.T, got *tensor, shape []int, data ...float64) {
+	t.Helper()
+	if !reflect.DeepEqual(got.shape, shape) {
+		t.Fatalf("shape = %v, want %v", got.shape, shape)
+	}
+	if len(got.data) != len(data) {
+		t.Fatalf("data = %v, want %v", got.data, data)
+	}
+	for i := range data {
+		if math.Abs(got.data[i]-data[i]) > 1e-12 {
+			t.Fatalf("data = %v, want %v", got.data, data)
+		}
+	}
+}
+
+func TestUnaryOperators(t *testing.T) {
+	x := newTensor([]int{1, 4}, -2, -0.5, 0, 3)
+	assertTensor(t, run(t, "Identity", nil, x), []int{1, 4}, -2, -0.5, 0, 3)
+	assertTensor(t, run(t, "Relu", nil, x), []int{1, 4}, 0, 0, 0, 3)
+	assertTensor(t, run(t, "LeakyRelu", nil, x), []int{1, 4}, -0.02, -0.005, 0, 3)
+	assertTensor(t, run(t, "LeakyRelu", map[string]attribute{"alpha": {f: 0.25}}, x), []int{1, 4}, -0.5, -0.125, 0, 3)
+	assertTensor(t, run(t, "Sigmoid", nil, x), []int{1, 4},
+		0.11920292202211755, 0.3775406687981454, 0.5, 0.9525741268224334)
+	assertTensor(t, run(t, "Tanh", nil, x), []int{1, 4},
+		-0.9640275800758169, -0.46211715726000974, 0, 0.9950547536867305)
+}
+
+func TestSoftmax(t *testing.T) {
+	x := newTensor([]int{2, 2}, 0, math.Log(3), 1000, 1000)
+	assertTensor(t, run(t, "Softmax", nil, x), []int{2, 2}, 0.25, 0.75, 0.5, 0.5)
+	assertTensor(t, run(t, "Softmax", map[string]attribute{"axis": {i: 1}}, x), []int{2, 2}, 0.25, 0.75, 0.5, 0.5)
+	if _, err := softmax([]*tensor{x}, map[string]attribute{"axis": {i: 0}}); err == nil || !strings.Contains(err.Error(), "last axis") {
+		t.Fatalf("Softmax over axis 0 error = %v, want last axis error", err)
+	}
+	if _, err := softmax([]*tensor{newTensor(nil, 1)}, nil); err == nil {
+		t.Fatal("Softmax of a scalar succeeded, want error")
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+	a := newTensor([]int{2, 3}, 1, 2, 3, 4, 5, 6)
+	assertTensor(t, run(t, "Add", nil, a, newTensor([]int{3}, 10, 20, 30)), []int{2, 3}, 11, 22, 33, 14, 25, 36)
+	assertTensor(t, run(t, "Sub", nil, a, newTensor([]int{2, 1}, 1, 2)), []int{2, 3}, 0, 1, 2, 2, 3, 4)
+	assertTensor(t, run(t, "Mul", nil, newTensor(nil, 2), a), []int{2, 3}, 2, 4, 6, 8, 10, 12)
+	assertTensor(t, run(t, "Div", nil, a, newTensor([]int{1, 1}, 2)), []int{2, 3}, 0.5, 1, 1.5, 2, 2.5, 3)
+	assertTensor(t, run(t, "Add", nil, newTensor([]int{2, 1}, 1, 2), newTensor([]int{1, 3}, 10, 20, 30)),
+		[]int{2, 3}, 11, 21, 31, 12, 22, 32)
+
+	if _, err := operators["Add"]([]*tensor{a, newTensor([]int{2}, 1, 2)}, nil); err == nil || !strings.Contains(err.Error(), "cannot broadcast") {
+		t.Fatalf("Add of [2 3] and [2] error = %v, want broadcast error", err)
+	}
+	big := newTensor([]int{1 << 13, 1}, make([]float64, 1<<13)...)
+	wide := newTensor([]int{1, 1 << 12}, make([]float64, 1<<12)...)
+	if _, err := operators["Mul"]([]*tensor{big, wide}, nil); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Fatalf("Mul broadcasting to %d elements error = %v, want size error", 1<<25, err)
+	}
+}
+
+func TestMatMulAndGemm(t *testing.T) {
+	a := newTensor([]int{2, 3}, 1, 2, 3, 4, 5, 6)
+	b := newTensor([]int{3, 2}, 7, 8, 9, 10, 11, 12)
+	assertTensor(t, run(t, "MatMul", nil, a, b), []int{2, 2}, 58, 64, 139, 154)
+	// 一维的左操作数视为行向量，右操作数视为列向量
+	assertTensor(t, run(t, "MatMul", nil, newTensor([]int{3}, 1, 2, 3), b), []int{1, 2}, 58, 64)
+	assertTensor(t, run(t, "MatMul", nil, a, newTensor([]int{3}, 1, 0, -1)), []int{2, 1}, -2, -2)
+
+	bt := newTensor([]int{2, 3}, 7, 9, 11, 8, 10, 12)
+	at := newTensor([]int{3, 2}, 1, 4, 2, 5, 3, 6)
+	c := newTensor([]int{2}, 1, -1)
+	assertTensor(t, run(t, "Gemm", map[string]attribute{"transB": {i: 1}}, a, bt), []int{2, 2}, 58, 64, 139, 154)
+	assertTensor(t, run(t, "Gemm", map[string]attribute{"transA": {i: 1}}, at, b), []int{2, 2}, 58, 64, 139, 154)
+	assertTensor(t, run(t, "Gemm", map[string]attribute{"transA": {i: 1}, "transB": {i: 1}}, at, bt), []int{2, 2}, 58, 64, 139, 154)
+	assertTensor(t, run(t, "Gemm", map[string]attribute{"alpha": {f: 0.5}, "beta": {f: 2}}, a, b, c), []int{2, 2}, 31, 30, 71.5, 75)
+	assertTensor(t, run(t, "Gemm", nil, a, b, nil), []int{2, 2}, 58, 64, 139, 154)
+
+	if _, err := matMul([]*tensor{a, a}, nil); err == nil || !strings.Contains(err.Error(), "cannot multiply shapes [2 3] and [2 3]") {
+		t.Fatalf("MatMul of [2 3] and [2 3] error = %v", err)
+	}
+	if _, err := matMul([]*tensor{newTensor([]int{1, 1, 1}, 1), b}, nil); err == nil || !strings.Contains(err.Error(), "1-D or 2-D") {
+		t.Fatalf("MatMul of a 3-D tensor error = %v", err)
+	}
+	if _, err := gemm([]*tensor{a}, nil); err == nil || !strings.Contains(err.Error(), "needs 2 inputs, got 1") {
+		t.Fatalf("Gemm with one input error = %v", err)
+	}
+	if _, err := matMul([]*tensor{a, nil}, nil); err == nil || !strings.Contains(err.Error(), "missing input") {
+		t.Fatalf("MatMul with a missing input error = %v", err)
+	}
+}
+
+func TestFlattenAndReshape(t *testing.T) {
+	x := newTensor([]int{2, 3, 2}, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12)
+	assertTensor(t, run(t, "Flatten", nil, x), []int{2, 6}, x.data...)
+	assertTensor(t, run(t, "Flatten", map[string]attribute{"axis": {i: 0}}, x), []int{1, 12}, x.data...)
+	assertTensor(t, run(t, "Flatten", map[string]attribute{"axis": {i: -1}}, x), []int{6, 2}, x.data...)
+	assertTensor(t, run(t, "Flatten", map[string]attribute{"axis": {i: 3}}, x), []int{12, 1}, x.data...)
+	if _, err := flatten([]*tensor{x}, map[string]attribute{"axis": {i: 4}}); err == nil || !strings.Contains(err.Error(), "invalid axis") {
+		t.Fatalf("Flatten with axis 4 error = %v", err)
+	}
+
+	assertTensor(t, run(t, "Reshape", nil, x, newTensor([]int{2}, 3, 4)), []int{3, 4}, x.data...)
+	assertTensor(t, run(t, "Reshape", nil, x, newTensor([]int{2}, 0, -1)), []int{2, 6}, x.data...)
+	assertTensor(t, run(t, "Reshape", nil, x, newTensor([]int{3}, -1, 3, 2)), []int{2, 3, 2}, x.data...)
+	for _, shape := range [][]float64{{5, -1}, {-1, -1}, {5, 5}, {-2, 6}, {0, 0, 0, 0}} {
+		if _, err := reshape([]*tensor{x, newTensor([]int{len(shape)}, shape...)}, nil); err == nil {
+			t.Errorf("Reshape to %v succeeded, want error", shape)
+		}
+	}
+}
+
+func TestConstant(t *testing.T) {
+	value := newTensor([]int{2}, 1, -1)
+	assertTensor(t, run(t, "Constant", map[string]attribute{"value": {t: value}}), []int{2}, 1, -1)
+	assertTensor(t, run(t, "Constant", map[string]attribute{"value_float": {f: 0.5}}), nil, 0.5)
+	assertTensor(t, run(t, "Constant", map[string]attribute{"value_floats": {floats: []float64{1, 2, 3}}}), []int{3}, 1, 2, 3)
+	if _, err := constant(nil, nil); err == nil || !strings.Contains(err.Error(), "value attribute") {
+		t.Fatalf("Constant without a value error = %v", err)
+	}
+}
+
+func TestTensorSize(t *testing.T) {
+	if got := newTensor(nil).size(); got != 1 {
+		t.Fatalf("scalar size = %d, want 1", got)
+	}
+	if got := newTensor([]int{2, 0, 3}).size(); got != 0 {
+		t.Fatalf("size of [2 0 3] = %d, want 0", got)
+	}
+	if got := newTensor([]int{1 << 20, 1 << 20, 1 << 20}).size(); got != maxTensorElements+1 {
+		t.Fatalf("size of an oversized tensor = %d, want %d", got, maxTensorElements+1)
+	}
+}
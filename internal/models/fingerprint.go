@@ -1,32 +1,78 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
 // Fingerprint 表示浏览器指纹数据
 type Fingerprint struct {
-	ID               int       `json:"id" db:"id"`
-	FingerprintHash  string    `json:"fingerprint_hash" db:"fingerprint_hash"`
-	UserAgent        string    `json:"user_agent" db:"user_agent"`
-	ScreenResolution string    `json:"screen_resolution" db:"screen_resolution"`
-	Timezone         string    `json:"timezone" db:"timezone"`
-	Language         string    `json:"language" db:"language"`
-	Platform         string    `json:"platform" db:"platform"`
-	Canvas           string    `json:"canvas" db:"canvas"`
-	CanvasHash       string    `json:"canvas_hash" db:"canvas_hash"`
-	WebGL            string    `json:"webgl" db:"webgl"`
-	WebGLHash        string    `json:"webgl_hash" db:"webgl_hash"`
-	Audio            string    `json:"audio" db:"audio"`
-	AudioHash        string    `json:"audio_hash" db:"audio_hash"`
-	Fonts            string    `json:"fonts" db:"fonts"` // JSON数组字符串
-	Plugins          string    `json:"plugins" db:"plugins"` // JSON数组字符串
-	TouchSupport     bool      `json:"touch_support" db:"touch_support"`
-	CookieEnabled    bool      `json:"cookie_enabled" db:"cookie_enabled"`
-	DoNotTrack       string    `json:"do_not_track" db:"do_not_track"`
-	IPAddress        string    `json:"ip_address" db:"ip_address"`
-	CreatedAt        time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+	ID                  int       `json:"id" db:"id"`
+	FingerprintHash     string    `json:"fingerprint_hash" db:"fingerprint_hash"`
+	UserAgent           string    `json:"user_agent" db:"user_agent"`
+	ScreenResolution    string    `json:"screen_resolution" db:"screen_resolution"`
+	Timezone            string    `json:"timezone" db:"timezone"`
+	Language            string    `json:"language" db:"language"`
+	Platform            string    `json:"platform" db:"platform"`
+	Canvas              string    `json:"canvas" db:"canvas"`
+	CanvasHash          string    `json:"canvas_hash" db:"canvas_hash"`
+	WebGL               string    `json:"webgl" db:"webgl"`
+	WebGLHash           string    `json:"webgl_hash" db:"webgl_hash"`
+	Audio               string    `json:"audio" db:"audio"`
+	AudioHash           string    `json:"audio_hash" db:"audio_hash"`
+	Fonts               string    `json:"fonts" db:"fonts"`     // JSON数组字符串
+	Plugins             string    `json:"plugins" db:"plugins"` // JSON数组字符串
+	TouchSupport        bool      `json:"touch_support" db:"touch_support"`
+	CookieEnabled       bool      `json:"cookie_enabled" db:"cookie_enabled"`
+	DoNotTrack          string    `json:"do_not_track" db:"do_not_track"`
+	IPAddress           string    `json:"ip_address" db:"ip_address"`
+	WebRTCLocalIPs      string    `json:"webrtc_local_ips" db:"webrtc_local_ips"`   // JSON数组字符串
+	WebRTCPublicIPs     string    `json:"webrtc_public_ips" db:"webrtc_public_ips"` // JSON数组字符串
+	HardwareConcurrency int       `json:"hardware_concurrency" db:"hardware_concurrency"`
+	DeviceMemory        float64   `json:"device_memory" db:"device_memory"`           // GB
+	MediaAudioInputs    int       `json:"media_audio_inputs" db:"media_audio_inputs"` // -1 表示未采集
+	MediaAudioOutputs   int       `json:"media_audio_outputs" db:"media_audio_outputs"`
+	MediaVideoInputs    int       `json:"media_video_inputs" db:"media_video_inputs"`
+	BatterySupported    bool      `json:"battery_supported" db:"battery_supported"`
+	BatteryCharging     bool      `json:"battery_charging" db:"battery_charging"`
+	BatteryLevel        float64   `json:"battery_level" db:"battery_level"` // 0-1，-1 表示未知
+	SensorsCollected    bool      `json:"sensors_collected" db:"sensors_collected"`
+	HasAccelerometer    bool      `json:"has_accelerometer" db:"has_accelerometer"`
+	HasGyroscope        bool      `json:"has_gyroscope" db:"has_gyroscope"`
+	ColorDepth          int       `json:"color_depth" db:"color_depth"`
+	DevicePixelRatio    float64   `json:"device_pixel_ratio" db:"device_pixel_ratio"`
+	AvailWidth          int       `json:"avail_width" db:"avail_width"`
+	AvailHeight         int       `json:"avail_height" db:"avail_height"`
+	OuterWidth          int       `json:"outer_width" db:"outer_width"` // -1 表示未采集
+	OuterHeight         int       `json:"outer_height" db:"outer_height"`
+	MathHash            string    `json:"math_hash" db:"math_hash"` // Math函数结果向量的哈希，标识JS引擎
+	FeatureBits         int64     `json:"feature_bits" db:"feature_bits"`
+	FeatureCount        int       `json:"feature_count" db:"feature_count"` // 0 表示未采集
+	FontMetricsHash     string    `json:"font_metrics_hash" db:"font_metrics_hash"`
+	AdBlockBaits        int       `json:"adblock_baits" db:"adblock_baits"`
+	AdBlockBlocked      int       `json:"adblock_blocked" db:"adblock_blocked"` // -1 表示未采集
+	Extensions          string    `json:"extensions" db:"extensions"`           // JSON数组字符串
+	SiteID              string    `json:"site_id" db:"site_id"`
+	CanvasPHash         string    `json:"canvas_phash" db:"canvas_phash"` // Canvas图像的感知哈希
+	CanvasNoise         float64   `json:"canvas_noise" db:"canvas_noise"` // 服务端计算的孤立噪点比例，-1 表示无法解码
+	AudioSum            float64   `json:"audio_sum" db:"audio_sum"`
+	AudioNoise          float64   `json:"audio_noise" db:"audio_noise"` // 服务端计算的音频噪点置信度，-1 表示未上报采样
+	HashV2              string    `json:"hash_v2" db:"hash_v2"`         // 归一化组件哈希组合而成的指纹哈希
+	FingerprintVersion  int       `json:"fingerprint_version" db:"fingerprint_version"`
+	VisitorID           string    `json:"visitor_id" db:"visitor_id"`           // 最近一次提交该指纹的访客Cookie
+	Country             string    `json:"country" db:"country"`                 // ISO 3166-1 二位国家代码，未配置国家请求头时为空
+	CanvasSimHash       int64     `json:"canvas_simhash" db:"canvas_simhash"`   // Canvas图像的64位SimHash，0 表示无法解码
+	AcceptLanguage      string    `json:"accept_language" db:"accept_language"` // 提交请求的 Accept-Language 请求头
+	IPTTL               int       `json:"-" db:"-"`                             // 反向代理测得的来源连接IP TTL，只用于本次评分，0 表示未知
+	TCPRTT              float64   `json:"-" db:"-"`                             // 反向代理测得的TCP往返时延（毫秒），只用于本次评分，0 表示未知
+	PageURL             string    `json:"-" db:"-"`                             // 提交请求的 Referer（没有时为 Origin）请求头，即采集页面
+	ClientHintsUA       string    `json:"-" db:"-"`                             // 提交请求的 Sec-CH-UA 请求头，只用于本次评分
+	ReceivedAt          time.Time `json:"-" db:"-"`                             // 服务端收到本次提交的时间，只用于本次评分
+	MissingComponents   string    `json:"missing_components" db:"missing_components"`
+	WebGLParams         string    `json:"webgl_params" db:"webgl_params"` // WebGLParams 的JSON，未采集时为空
+	GPUFamily           string    `json:"gpu_family" db:"gpu_family"`     // 由WebGL渲染器判断的GPU家族，无法判断时为空
+	CreatedAt           time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at" db:"updated_at"`
 }
 
 // Analysis 表示指纹分析结果
@@ -34,14 +80,28 @@ type Analysis struct {
 	ID              int       `json:"id" db:"id"`
 	FingerprintHash string    `json:"fingerprint_hash" db:"fingerprint_hash"`
 	UniquenessScore float64   `json:"uniqueness_score" db:"uniqueness_score"` // 唯一性评分 0-1
-	BotScore        float64   `json:"bot_score" db:"bot_score"`        // 爬虫评分 0-1
-	RiskLevel       string    `json:"risk_level" db:"risk_level"`      // LOW, MEDIUM, HIGH
+	BotScore        float64   `json:"bot_score" db:"bot_score"`               // 爬虫评分 0-1
+	RiskLevel       string    `json:"risk_level" db:"risk_level"`             // LOW, MEDIUM, HIGH
 	IsBot           bool      `json:"is_bot" db:"is_bot"`
-	Reasons         string    `json:"reasons" db:"reasons"`            // JSON数组字符串，检测原因
+	Reasons         string    `json:"reasons" db:"reasons"`           // JSON数组字符串，检测原因
+	ReasonCodes     string    `json:"reason_codes" db:"reason_codes"` // JSON数组字符串，稳定的原因代码
+	PrivacyMode     bool      `json:"privacy_mode" db:"privacy_mode"` // 隐私浏览器的反指纹模式
 	VisitCount      int       `json:"visit_count" db:"visit_count"`
 	LastSeen        time.Time `json:"last_seen" db:"last_seen"`
 	CreatedAt       time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+	// EmulatorSuspected 声明为移动设备的请求疑似来自Android模拟器或iOS模拟器
+	EmulatorSuspected bool `json:"emulator_suspected" db:"emulator_suspected"`
+	// RawBotScore 衰减前的爬虫评分，只在读取时评分发生了衰减才返回
+	RawBotScore float64 `json:"raw_bot_score,omitempty" db:"-"`
+	// MLScore 机器学习模型给出的爬虫概率，未启用模型或预测失败时为空
+	MLScore *float64 `json:"ml_score,omitempty" db:"ml_score"`
+	// MLModel 给出 MLScore 的模型版本
+	MLModel string `json:"ml_model,omitempty" db:"ml_model"`
+	// OutlierScore 孤立森林给出的异常分数（0~1），未训练模型时为空
+	OutlierScore *float64 `json:"outlier_score,omitempty" db:"outlier_score"`
+	// OutlierFeatures 对异常分数贡献最大的特征，只在给出离群信号时记录
+	OutlierFeatures string `json:"outlier_features,omitempty" db:"outlier_features"`
 }
 
 // NoiseDetection 表示噪点检测结果
@@ -52,33 +112,1594 @@ type NoiseDetection struct {
 	Details    string  `json:"details,omitempty"`
 }
 
+// MediaDeviceCounts 媒体设备数量（仅计数，不含设备标签）
+type MediaDeviceCounts struct {
+	AudioInput  int `json:"audio_input"`
+	AudioOutput int `json:"audio_output"`
+	VideoInput  int `json:"video_input"`
+}
+
+// BatteryInfo Battery Status API 采集结果
+type BatteryInfo struct {
+	Supported bool    `json:"supported"`
+	Charging  bool    `json:"charging"`
+	Level     float64 `json:"level"`
+}
+
+// SensorInfo 运动传感器可用性（devicemotion 事件中是否带有对应数据）
+type SensorInfo struct {
+	Accelerometer bool `json:"accelerometer"`
+	Gyroscope     bool `json:"gyroscope"`
+}
+
+// InputSummary 采集脚本记录的触摸和运动传感器读数的汇总，不包含原始读数
+type InputSummary struct {
+	// Touches 记录的触点数
+	Touches int `json:"touches"`
+	// PressureVariance 触点压力（Touch.force）的方差
+	PressureVariance float64 `json:"pressure_variance"`
+	// SizeVariance 触点接触半径（radiusX、radiusY 的均值）的方差
+	SizeVariance float64 `json:"size_variance"`
+	// MotionSamples 记录的 devicemotion 读数数
+	MotionSamples int `json:"motion_samples"`
+	// MotionEntropy 加速度模长按0.05 m/s²分桶后的香农熵（比特）
+	MotionEntropy float64 `json:"motion_entropy"`
+}
+
+// ScreenMetrics 扩展屏幕参数
+type ScreenMetrics struct {
+	ColorDepth       int     `json:"color_depth"`
+	DevicePixelRatio float64 `json:"device_pixel_ratio"`
+	AvailWidth       int     `json:"avail_width"`
+	AvailHeight      int     `json:"avail_height"`
+	OuterWidth       int     `json:"outer_width"`
+	OuterHeight      int     `json:"outer_height"`
+}
+
+// FeatureProbes 特性探测位图
+// 第 i 位表示第 i 项探测（顺序见 services.featureProbes）是否支持，Count 为客户端执行的探测项数
+type FeatureProbes struct {
+	Bits  uint64 `json:"bits"`
+	Count int    `json:"count"`
+}
+
+// FontMetric 探测字符串在某个字体族下的渲染尺寸（像素，保留小数）
+type FontMetric struct {
+	Font   string  `json:"font"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// ContentBlocking 广告拦截诱饵元素的检测结果
+type ContentBlocking struct {
+	Baits   int `json:"baits"`   // 插入的诱饵元素个数
+	Blocked int `json:"blocked"` // 被隐藏或移除的个数
+}
+
+// RequestMeta 指纹提交请求的上下文信息
+type RequestMeta struct {
+	IPAddress string `json:"ip_address"`
+	// SiteID 按来源匹配到的接入站点，未匹配时为空
+	SiteID string `json:"site_id,omitempty"`
+	// VisitorID 访客Cookie，用于跟踪同一访客的指纹漂移
+	VisitorID string `json:"visitor_id,omitempty"`
+	// Country 反向代理提供的访客国家代码
+	Country string `json:"country,omitempty"`
+	// AcceptLanguage 提交请求的 Accept-Language 请求头
+	AcceptLanguage string `json:"accept_language,omitempty"`
+	// PageURL 提交请求的 Referer（没有时为 Origin）请求头，即采集页面；跨域提交时浏览器通常只发送源
+	PageURL string `json:"page_url,omitempty"`
+	// ClientHintsUA 提交请求的 Sec-CH-UA 请求头（Chromium系浏览器的品牌和主版本）
+	ClientHintsUA string `json:"client_hints_ua,omitempty"`
+	// IPTTL 反向代理测得的来源连接IP TTL，0 表示未知
+	IPTTL int `json:"ip_ttl,omitempty"`
+	// TCPRTT 反向代理测得的TCP往返时延（毫秒），0 表示未知
+	TCPRTT float64 `json:"tcp_rtt,omitempty"`
+	// JA4 边缘节点转发的 JA4 TLS 指纹，未配置 edge.ja4_header 时为空
+	JA4 string `json:"ja4,omitempty"`
+	// ReceivedAt 服务端收到提交的时间，重放缓存和预写日志时按该时间校验采集时间
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// NavigationContext 采集页面的导航上下文
+type NavigationContext struct {
+	// Referrer document.referrer，直接访问时为空
+	Referrer string `json:"referrer"`
+	// Page 采集页面的路径（location.pathname）
+	Page string `json:"page"`
+}
+
+// WebGLParams WebGL的参数、扩展列表和着色器精度格式
+type WebGLParams struct {
+	// Params 整数参数，键为WebGL常量名（如 MAX_TEXTURE_SIZE）
+	Params map[string]int `json:"params"`
+	// Extensions getSupportedExtensions 的结果
+	Extensions []string `json:"extensions"`
+	// Precisions 着色器精度格式，键为 着色器.精度（如 FRAGMENT_SHADER.HIGH_FLOAT），值为 rangeMin、rangeMax、precision
+	Precisions map[string][3]int `json:"precisions,omitempty"`
+}
+
+// CanvasCheck 双Canvas渲染结果，均为 toDataURL 的SHA-256
+type CanvasCheck struct {
+	// Static 固定图案的哈希
+	Static string `json:"static"`
+	// Seed 绘制种子图案使用的种子，即执行证明的种子
+	Seed string `json:"seed"`
+	// Seeded 按种子绘制的图案的哈希
+	Seeded string `json:"seeded"`
+}
+
+// ExecutionProof 采集脚本的执行证明：按服务端下发的种子运行脚本中的计算得到的值，
+// 没有执行脚本而直接构造的请求无法给出正确的值
+type ExecutionProof struct {
+	// Seed GET /api/proof/seed 返回的种子
+	Seed string `json:"seed"`
+	// Value 由种子计算的证明值
+	Value string `json:"value"`
+}
+
+// CollectionTiming 采集端时钟记录的采集开始和结束时间（Unix毫秒）
+type CollectionTiming struct {
+	// StartedAt 开始采集的时间
+	StartedAt int64 `json:"started_at"`
+	// FinishedAt 采集完成的时间
+	FinishedAt int64 `json:"finished_at"`
+}
+
+// NetworkTiming 采集端测得的网络时延
+type NetworkTiming struct {
+	// PingMS 多次请求 /api/health 的往返时延中位数（毫秒）
+	PingMS float64 `json:"ping_ms"`
+}
+
 // FingerprintRequest 接收前端提交的指纹数据
 type FingerprintRequest struct {
-	FingerprintHash         string           `json:"fingerprint_hash,omitempty"` // 前端预计算的指纹哈希（可选）
-	UserAgent               string           `json:"user_agent" binding:"required"`
-	ScreenResolution        string           `json:"screen_resolution" binding:"required"`
-	Timezone                string           `json:"timezone" binding:"required"`
-	Language                string           `json:"language" binding:"required"`
-	Platform                string           `json:"platform" binding:"required"`
-	Canvas                  string           `json:"canvas" binding:"required"`
-	WebGL                   string           `json:"webgl" binding:"required"`
-	Audio                   string           `json:"audio" binding:"required"`
-	Fonts                   []string         `json:"fonts" binding:"required"`
-	Plugins                 []string         `json:"plugins" binding:"required"`
-	TouchSupport            bool             `json:"touch_support"`
-	CookieEnabled           bool             `json:"cookie_enabled"`
-	DoNotTrack              string           `json:"do_not_track"`
-	CanvasNoiseDetection    *NoiseDetection  `json:"canvasNoiseDetection,omitempty"`
-	WebGLNoiseDetection     *NoiseDetection  `json:"webglNoiseDetection,omitempty"`
-	AudioNoiseDetection     *NoiseDetection  `json:"audioNoiseDetection,omitempty"`
+	FingerprintHash      string             `json:"fingerprint_hash,omitempty"` // 前端预计算的指纹哈希（可选）
+	UserAgent            string             `json:"user_agent" binding:"required"`
+	ScreenResolution     string             `json:"screen_resolution" binding:"required"`
+	Timezone             string             `json:"timezone" binding:"required"`
+	Language             string             `json:"language" binding:"required"`
+	Platform             string             `json:"platform" binding:"required"`
+	Canvas               string             `json:"canvas"` // 指纹组件可缺省（隐私加固的浏览器会拦截读取），缺少的组件计入 components_missing 信号
+	WebGL                string             `json:"webgl"`
+	Audio                string             `json:"audio"`
+	Fonts                []string           `json:"fonts"`
+	Plugins              []string           `json:"plugins"`
+	TouchSupport         bool               `json:"touch_support"`
+	CookieEnabled        bool               `json:"cookie_enabled"`
+	DoNotTrack           string             `json:"do_not_track"`
+	CanvasNoiseDetection *NoiseDetection    `json:"canvasNoiseDetection,omitempty"`
+	WebGLNoiseDetection  *NoiseDetection    `json:"webglNoiseDetection,omitempty"`
+	AudioNoiseDetection  *NoiseDetection    `json:"audioNoiseDetection,omitempty"`
+	WebRTCLocalIPs       []string           `json:"webrtc_local_ips,omitempty"`     // WebRTC ICE候选中的内网地址
+	WebRTCPublicIPs      []string           `json:"webrtc_public_ips,omitempty"`    // WebRTC STUN探测到的公网地址
+	HardwareConcurrency  int                `json:"hardware_concurrency,omitempty"` // navigator.hardwareConcurrency
+	DeviceMemory         float64            `json:"device_memory,omitempty"`        // navigator.deviceMemory（GB）
+	MediaDevices         *MediaDeviceCounts `json:"media_devices,omitempty"`        // enumerateDevices 各类设备数量
+	Battery              *BatteryInfo       `json:"battery,omitempty"`
+	Sensors              *SensorInfo        `json:"sensors,omitempty"` // 需要授权才能读取时（iOS）不提交
+	Screen               *ScreenMetrics     `json:"screen,omitempty"`
+	Math                 []string           `json:"math,omitempty"` // Math函数边界值与数字格式化结果，按采集顺序排列
+	Features             *FeatureProbes     `json:"features,omitempty"`
+	FontMetrics          []FontMetric       `json:"font_metrics,omitempty"` // 字体渲染尺寸，不受字体列表伪造影响
+	ContentBlocking      *ContentBlocking   `json:"content_blocking,omitempty"`
+	Extensions           []string           `json:"extensions,omitempty"`          // 页面上可观察到的扩展痕迹
+	Brave                bool               `json:"brave,omitempty"`               // navigator.brave 存在
+	AudioSamples         [][]float64        `json:"audio_samples,omitempty"`       // 每次压缩器渲染的第4500~5000个采样
+	FingerprintVersion   int                `json:"fingerprint_version,omitempty"` // 采集端的指纹结构版本，未提交时按已提交的信号推断
+	Network              *NetworkTiming     `json:"network,omitempty"`
+	Navigation           *NavigationContext `json:"navigation,omitempty"`
+	Proof                *ExecutionProof    `json:"proof,omitempty"`
+	CanvasCheck          *CanvasCheck       `json:"canvas_check,omitempty"`
+	WebGLParams          *WebGLParams       `json:"webgl_params,omitempty"`
+	Timing               *CollectionTiming  `json:"timing,omitempty"`
+	Interaction          *InputSummary      `json:"interaction,omitempty"`
+}
+
+// CurrentFingerprintVersion 当前的指纹结构版本
+//   - 1：基础信号（User Agent、屏幕、时区、语言、平台、Canvas、WebGL、音频、字体、插件、硬件）
+//   - 2：增加扩展屏幕参数、字体渲染尺寸、Math结果向量和特性探测
+const CurrentFingerprintVersion = 2
+
+// VersionCount 某个指纹结构版本的记录数
+type VersionCount struct {
+	Version int `json:"version"`
+	Count   int `json:"count"`
+}
+
+// ComponentAnonymity 单个组件在已存储指纹中的分布
+type ComponentAnonymity struct {
+	Component string `json:"component"`
+	// Shared 共享该组件哈希的其他指纹数
+	Shared int `json:"shared"`
+	// Frequency 该组件哈希在全部指纹中的出现比例
+	Frequency float64 `json:"frequency"`
+	// Bits 该组件提供的识别信息量（-log2 Frequency）
+	Bits float64 `json:"bits"`
+}
+
+// AnonymityReport 指纹的k-匿名报告
+type AnonymityReport struct {
+	FingerprintHash   string `json:"fingerprint_hash"`
+	TotalFingerprints int    `json:"total_fingerprints"`
+	// AnonymitySetSize 全部组件都相同的指纹数（含自身），即k-匿名中的k
+	AnonymitySetSize int                  `json:"anonymity_set_size"`
+	Components       []ComponentAnonymity `json:"components"`
+	// UniquenessScore 1/k，指纹唯一时为1
+	UniquenessScore float64 `json:"uniqueness_score"`
+}
+
+// 设备农场类型
+const (
+	// FarmIPRange 同一IP段内出现大量不同指纹
+	FarmIPRange = "ip_range"
+	// FarmUARotation 屏幕、时区、字体完全相同而User Agent轮换
+	FarmUARotation = "ua_rotation"
+	// FarmCanvasClone 大量声明为不同平台的设备共享同一Canvas哈希
+	FarmCanvasClone = "canvas_clone"
+)
+
+// Farm 设备农场检测报告
+type Farm struct {
+	ID   int    `json:"id"`
+	Kind string `json:"kind"`
+	// Key 分组键：IP段、配置哈希或Canvas哈希
+	Key           string    `json:"key"`
+	Fingerprints  int       `json:"fingerprints"`
+	UserAgents    int       `json:"user_agents"`
+	Samples       []string  `json:"samples"` // 部分成员指纹哈希
+	Blocked       bool      `json:"blocked"`
+	FirstDetected time.Time `json:"first_detected"`
+	LastDetected  time.Time `json:"last_detected"`
+}
+
+// Thresholds 全局评分阈值，可通过管理API在运行时调整
+type Thresholds struct {
+	// BotScore 爬虫评分超过该值判定为爬虫，站点可单独覆盖
+	BotScore float64 `json:"bot_score"`
+	// RiskHigh 爬虫评分超过该值风险等级为HIGH
+	RiskHigh float64 `json:"risk_high"`
+	// RiskMedium 爬虫评分超过该值风险等级为MEDIUM
+	RiskMedium float64 `json:"risk_medium"`
+	// EventBotScore 按业务事件类型覆盖的爬虫判定阈值，如登录比浏览更严格
+	EventBotScore map[string]float64 `json:"event_bot_score,omitempty"`
+}
+
+// 常用业务事件类型，其他类型只需符合命名规则
+const (
+	EventPageView = "page_view"
+	EventLogin    = "login"
+	EventSignup   = "signup"
+	EventCheckout = "checkout"
+	// EventChallenge 人机验证结果，metadata.type 为验证方式（如 pow、captcha），
+	// outcome 为 failure 时计入来源IP的信誉记录
+	EventChallenge = "challenge"
+)
+
+// 业务事件结果
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// 业务事件的处理建议
+const (
+	ActionAllow     = "allow"
+	ActionChallenge = "challenge"
+	ActionDeny      = "deny"
+)
+
+// EventRequest 接入方提交的业务事件
+type EventRequest struct {
+	FingerprintHash string `json:"fingerprint_hash" binding:"required"`
+	EventType       string `json:"event_type" binding:"required"`
+	// AccountID 接入方的不透明账号标识（可选），用于跟踪账号使用的设备
+	AccountID string `json:"account_id"`
+	// Outcome success、failure 或为空
+	Outcome  string            `json:"outcome"`
+	Metadata map[string]string `json:"metadata"`
+	// Typing 登录、注册表单的打字节奏汇总（可选）
+	Typing *TypingFeatures `json:"typing,omitempty"`
+	// Action 按哪个受保护操作的策略给出处理建议，为空时使用事件类型
+	Action string `json:"action,omitempty"`
+}
+
+// TypingFeatures 接入方在表单中采集的按键节奏汇总特征，不包含原始按键和时间序列
+type TypingFeatures struct {
+	// Keystrokes 参与统计的按键数
+	Keystrokes int `json:"keystrokes"`
+	// MeanInterval 相邻按键的平均间隔（毫秒）
+	MeanInterval float64 `json:"mean_interval"`
+	// IntervalVariance 相邻按键间隔的方差（毫秒²）
+	IntervalVariance float64 `json:"interval_variance"`
+	// BackspaceRate 退格键占全部按键的比例
+	BackspaceRate float64 `json:"backspace_rate"`
+}
+
+// EventDecision 按事件类型的策略对指纹分析结果给出的处理建议
+type EventDecision struct {
+	BotScore  float64 `json:"bot_score"`
+	Threshold float64 `json:"threshold"`
+	IsBot     bool    `json:"is_bot"`
+	RiskLevel string  `json:"risk_level"`
+	Action    string  `json:"action"`
+	// AccountSignals 账号接管相关的原因代码
+	AccountSignals []string `json:"account_signals,omitempty"`
+	// Blocklisted 指纹或IP段在封禁名单中，Action 为 deny
+	Blocklisted bool `json:"blocklisted,omitempty"`
+	// CountryPolicy 站点国家策略给出的处理，未命中时为空
+	CountryPolicy string `json:"country_policy,omitempty"`
+	// TypingSignals 打字节奏相关的原因代码
+	TypingSignals []string `json:"typing_signals,omitempty"`
+	// CrawlerPolicy 站点爬虫策略按指纹的User Agent给出的处理，未命中时为空
+	CrawlerPolicy string `json:"crawler_policy,omitempty"`
+	// Policy 生效的操作策略名，没有匹配的操作策略时为空
+	Policy string `json:"policy,omitempty"`
+}
+
+// 边缘决策的依据
+const (
+	// EdgeSourceFingerprint 按请求给出的指纹哈希（如边缘节点校验访客令牌后取得的哈希）
+	EdgeSourceFingerprint = "fingerprint"
+	// EdgeSourceVisitor 按访客Cookie最近一次提交的指纹
+	EdgeSourceVisitor = "visitor"
+	// EdgeSourceJA4 没有可用的指纹，按 JA4 在窗口内的爬虫比例
+	EdgeSourceJA4 = "ja4"
+	// EdgeSourceNone 没有可用的依据，只按封禁名单和国家策略决策
+	EdgeSourceNone = "none"
+)
+
+// EdgeRequest 边缘节点的决策查询
+type EdgeRequest struct {
+	SiteID          string
+	FingerprintHash string
+	VisitorID       string
+	JA4             string
+	// IPAddress 边缘节点看到的访客IP
+	IPAddress string
+	Country   string
+	// Action 受保护操作，为空时使用站点的默认策略
+	Action string
+}
+
+// EdgeDecision 边缘节点的决策查询结果，只保留边缘节点执行处理所需的字段
+type EdgeDecision struct {
+	Action   string  `json:"action"`
+	Source   string  `json:"source"`
+	BotScore float64 `json:"bot_score"`
+	IsBot    bool    `json:"is_bot"`
+	// MaxAge 决策可缓存的秒数
+	MaxAge          int    `json:"max_age"`
+	FingerprintHash string `json:"fingerprint_hash,omitempty"`
+	// JA4BotRatio JA4 在窗口内判定为爬虫的提交比例，只在按 JA4 决策时给出
+	JA4BotRatio *float64 `json:"ja4_bot_ratio,omitempty"`
+}
+
+// 封禁名单条目类型
+const (
+	BlockFingerprint = "fingerprint"
+	BlockIPRange     = "ip_range"
+	BlockIP          = "ip"
+	BlockVisitor     = "visitor"
+)
+
+// BlockEntry 临时封禁名单条目
+type BlockEntry struct {
+	Kind      string    `json:"kind"`
+	Key       string    `json:"key"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// BlocklistFilterStatus 封禁名单内存过滤器的大小、误判率和启动以来的查询统计
+type BlocklistFilterStatus struct {
+	// Entries 内存中未过期的封禁条目数，Keys 过滤器中的键数（含重建前已解除的键）
+	Entries                    int       `json:"entries"`
+	Keys                       int       `json:"keys"`
+	Bits                       uint64    `json:"bits"`
+	Bytes                      int       `json:"bytes"`
+	Hashes                     int       `json:"hashes"`
+	TargetFalsePositiveRate    float64   `json:"target_false_positive_rate"`
+	EstimatedFalsePositiveRate float64   `json:"estimated_false_positive_rate"`
+	RebuiltAt                  time.Time `json:"rebuilt_at"`
+	// Lookups 查询的键数，FilterRejected 其中过滤器直接判定不在名单中的键数
+	Lookups        uint64 `json:"lookups"`
+	FilterRejected uint64 `json:"filter_rejected"`
+	// FilterPassed 过滤器判定可能在名单中、再查内存名单的键数，FalsePositives 其中实际不在名单中（或已过期）的键数
+	FilterPassed   uint64 `json:"filter_passed"`
+	FalsePositives uint64 `json:"false_positives"`
+}
+
+// 跨区域同步的变更类型
+const (
+	ReplicationBlock      = "block"
+	ReplicationUnblock    = "unblock"
+	ReplicationReputation = "reputation"
+	ReplicationLabel      = "label"
+	ReplicationUnlabel    = "unlabel"
+)
+
+// ReplicationChange 本区域产生的一条变更，Data 按 Kind 为 BlockEntry、ReplicationDelete、ReputationDelta 或 Label
+type ReplicationChange struct {
+	Seq       int64           `json:"seq"`
+	Kind      string          `json:"kind"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// ReplicationDelete 解除封禁或删除标注：封禁名单条目由 Kind 和 Key 指定，标注的 Key 为指纹哈希
+type ReplicationDelete struct {
+	Kind      string    `json:"kind,omitempty"`
+	Key       string    `json:"key"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// ReputationDelta 同步的IP信誉增量：At 时新增的爬虫判定和人机验证失败次数
+type ReputationDelta struct {
+	IP                string    `json:"ip"`
+	BotDetections     float64   `json:"bot_detections"`
+	ChallengeFailures float64   `json:"challenge_failures"`
+	At                time.Time `json:"at"`
+}
+
+// ReplicationBatch 其他区域拉取的一批变更
+type ReplicationBatch struct {
+	Region  string              `json:"region"`
+	Changes []ReplicationChange `json:"changes"`
+	// Next 下次拉取的游标
+	Next int64 `json:"next"`
+	// More 为 true 时还有变更未返回，应立即继续拉取
+	More bool `json:"more"`
+	// Truncated 为 true 时游标之后的部分变更已超过保留期被删除，拉取方需要导入数据包补齐
+	Truncated bool `json:"truncated"`
+}
+
+// ReplicationPeerStatus 从一个区域拉取变更的进度
+type ReplicationPeerStatus struct {
+	Region        string     `json:"region"`
+	URL           string     `json:"url"`
+	Cursor        int64      `json:"cursor"`
+	Applied       int64      `json:"applied"`
+	LastPullAt    *time.Time `json:"last_pull_at,omitempty"`
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+}
+
+// ReplicationStatus 跨区域同步的状态
+type ReplicationStatus struct {
+	Region string `json:"region"`
+	// LastSeq 本区域最新一条变更的序号
+	LastSeq int64                   `json:"last_seq"`
+	Peers   []ReplicationPeerStatus `json:"peers"`
+}
+
+// DetectorStatus 检测器插件的状态
+type DetectorStatus struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	// Remote 为 true 时是以HTTP调用的进程外检测器
+	Remote bool `json:"remote,omitempty"`
+	// State 进程外检测器的熔断器状态：closed、open、half_open
+	State string `json:"state,omitempty"`
+}
+
+// OutlierModelStatus 离群检测模型的训练情况
+type OutlierModelStatus struct {
+	// Interval 定期训练的间隔，0s 表示只能手动训练
+	Interval string `json:"interval"`
+	// TrainedAt 最近一次训练的时间，尚未训练时为空
+	TrainedAt *time.Time `json:"trained_at,omitempty"`
+	// Samples 训练样本数
+	Samples   int     `json:"samples"`
+	Trees     int     `json:"trees"`
+	Features  int     `json:"features"`
+	Threshold float64 `json:"threshold"`
+	Weight    float64 `json:"weight"`
+	// SampleFlagRate 训练样本中异常分数超过阈值的比例
+	SampleFlagRate float64 `json:"sample_flag_rate"`
+	// Scored、Flagged 本实例自训练以来评分和给出离群信号的次数
+	Scored  int64 `json:"scored"`
+	Flagged int64 `json:"flagged"`
+	// Error 最近一次训练失败或跳过的原因
+	Error string `json:"error,omitempty"`
+}
+
+// MLStatus 机器学习评分模型的配置和各模型版本的统计
+type MLStatus struct {
+	// Path 模型文件路径
+	Path string `json:"path"`
+	// Version 当前模型版本，模型未加载时为空
+	Version string `json:"version,omitempty"`
+	// Hash 当前模型文件 SHA-256 的前12位
+	Hash     string `json:"hash,omitempty"`
+	Producer string `json:"producer,omitempty"`
+	// FeatureVersion 本服务特征向量的版本，Features 为特征数
+	FeatureVersion int     `json:"feature_version"`
+	Features       int     `json:"features"`
+	Weight         float64 `json:"weight"`
+	Shadow         bool    `json:"shadow"`
+	// LoadedAt 当前模型的加载时间
+	LoadedAt *time.Time `json:"loaded_at,omitempty"`
+	// Error 最近一次加载失败的原因
+	Error string `json:"error,omitempty"`
+	// Models 本实例启动以来使用过的和分析结果中记录的各模型版本
+	Models []MLModelStats `json:"models"`
+}
+
+// MLModelStats 单个模型版本的统计：运行统计只包括本实例自启动以来的预测，
+// 标注统计以人工标注为真实值、概率 0.5 为界评估存储的分析结果
+type MLModelStats struct {
+	Version         string  `json:"version"`
+	Active          bool    `json:"active"`
+	Predictions     int64   `json:"predictions"`
+	Errors          int64   `json:"errors"`
+	MeanLatencyMs   float64 `json:"mean_latency_ms"`
+	MeanProbability float64 `json:"mean_probability"`
+	// Labeled 带有该模型概率的标注样本数
+	Labeled   int     `json:"labeled"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	Accuracy  float64 `json:"accuracy"`
+	// Brier 概率与标注（爬虫为1）之差的平方的均值，越小越好
+	Brier float64 `json:"brier"`
+}
+
+// AutoBlockRuleStatus 自动封禁规则的配置和执行情况
+type AutoBlockRuleStatus struct {
+	Name     string `json:"name"`
+	Scope    string `json:"scope"`
+	Match    string `json:"match"`
+	Count    int    `json:"count"`
+	Window   string `json:"window"`
+	Duration string `json:"duration"`
+	// Triggered 本实例自启动以来触发的封禁次数
+	Triggered int64 `json:"triggered"`
+	// Active 该规则产生的未过期封禁数
+	Active int `json:"active"`
+}
+
+// ThreatFeedStatus IP信誉情报源的状态
+type ThreatFeedStatus struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Enabled bool   `json:"enabled"`
+	// Entries 最近一次成功更新导入的记录数
+	Entries     int        `json:"entries"`
+	LastAttempt *time.Time `json:"last_attempt,omitempty"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	// AgeSeconds 距上次成功更新的秒数，从未成功时为 -1
+	AgeSeconds int64 `json:"age_seconds"`
+	// Stale 已启用且从未成功更新或距上次成功更新超过 stale_after
+	Stale bool `json:"stale"`
+}
+
+// ThreatIntelHit 命中的情报记录及其来源
+type ThreatIntelHit struct {
+	Feed       string `json:"feed"`
+	Prefix     string `json:"prefix"`
+	Reference  string `json:"reference,omitempty"`
+	Confidence int    `json:"confidence"`
+}
+
+// IPReputation 来源IP的历史信誉，计数均已衰减到查询时刻
+type IPReputation struct {
+	// IP 记录的键：IPv4的/24或IPv6的/64网段
+	IP string `json:"ip"`
+	// Score 综合信誉评分（0~1），越高越可疑
+	Score                  float64          `json:"score"`
+	BotDetections          float64          `json:"bot_detections"`
+	ChallengeFailures      float64          `json:"challenge_failures"`
+	FeedHits               float64          `json:"feed_hits"`
+	Submissions            int              `json:"submissions"`
+	FirstSeen              *time.Time       `json:"first_seen,omitempty"`
+	LastSeen               *time.Time       `json:"last_seen,omitempty"`
+	LastBotAt              *time.Time       `json:"last_bot_at,omitempty"`
+	LastChallengeFailureAt *time.Time       `json:"last_challenge_failure_at,omitempty"`
+	LastFeedHitAt          *time.Time       `json:"last_feed_hit_at,omitempty"`
+	ThreatIntel            []ThreatIntelHit `json:"threat_intel"`
+}
+
+// SubnetStats 聚合网段（IPv4 /24，IPv6 /64）的近期活动
+type SubnetStats struct {
+	Submissions1h     int        `json:"submissions_1h"`
+	Submissions24h    int        `json:"submissions_24h"`
+	BotSubmissions24h int        `json:"bot_submissions_24h"`
+	Addresses24h      int        `json:"addresses_24h"`
+	Fingerprints24h   int        `json:"fingerprints_24h"`
+	LastSeen          *time.Time `json:"last_seen,omitempty"`
+}
+
+// ReferrerCount 一个来源域名的访问次数
+type ReferrerCount struct {
+	Host  string `json:"host"`
+	Count int    `json:"count"`
+}
+
+// ReferrerStats 采集页面的来源统计
+type ReferrerStats struct {
+	Total int `json:"total"`
+	// Direct 没有来源（document.referrer 为空）的访问次数
+	Direct int `json:"direct"`
+	// DirectDeep 没有来源且直接访问深层页面（非首页）的次数
+	DirectDeep int `json:"direct_deep"`
+	// Internal 来源为同一站点的访问次数
+	Internal int `json:"internal"`
+	// Referrers 站外来源域名，按次数降序
+	Referrers []ReferrerCount `json:"referrers"`
+}
+
+// GPUFamilyCount 某个GPU家族的指纹数和其中判定为爬虫的数量
+type GPUFamilyCount struct {
+	// Family GPU家族，渲染器被隐藏或无法识别时为空
+	Family  string  `json:"family"`
+	Count   int     `json:"count"`
+	Bots    int     `json:"bots"`
+	BotRate float64 `json:"bot_rate"`
+}
+
+// BrowserRelease 版本表中某个浏览器家族的稳定版
+type BrowserRelease struct {
+	Family      string `json:"family"`
+	Stable      int    `json:"stable"`
+	ReleasedAt  string `json:"released_at"`
+	CadenceDays int    `json:"cadence_days"`
+	// Expected 按发布周期推算的当前稳定版
+	Expected int `json:"expected"`
+}
+
+// BrowserReleasesStatus 当前使用的浏览器版本表
+type BrowserReleasesStatus struct {
+	Version string `json:"version"`
+	// Source 版本表来源：地址、文件路径或 embedded
+	Source    string           `json:"source"`
+	Feed      string           `json:"feed,omitempty"`
+	UpdatedAt *time.Time       `json:"updated_at,omitempty"`
+	Browsers  []BrowserRelease `json:"browsers"`
+	// Error 最近一次更新失败的原因
+	Error string `json:"error,omitempty"`
+}
+
+// VersionTraffic 某类版本的指纹数和其中判定为爬虫的数量
+type VersionTraffic struct {
+	Count int `json:"count"`
+	Bots  int `json:"bots"`
+}
+
+// BrowserVersionStats 某个浏览器家族按版本新旧的指纹数
+type BrowserVersionStats struct {
+	Family string `json:"family"`
+	// Expected 按发布周期推算的当前稳定版
+	Expected int `json:"expected"`
+	Count    int `json:"count"`
+	// Current 当前稳定版或被取代未超过 outdated_after 的版本
+	Current VersionTraffic `json:"current"`
+	// Outdated 被取代超过 outdated_after 但未超过 obsolete_after
+	Outdated VersionTraffic `json:"outdated"`
+	// Obsolete 被取代超过 obsolete_after
+	Obsolete VersionTraffic `json:"obsolete"`
+	// Unreleased 比推算的稳定版高出超过 future_majors
+	Unreleased VersionTraffic `json:"unreleased"`
+	// OutdatedRate 过时、不可能仍在使用的版本占比
+	OutdatedRate float64 `json:"outdated_rate"`
+}
+
+// CrawlerCheck 按User Agent识别爬虫并按站点爬虫策略给出的处理
+type CrawlerCheck struct {
+	// Crawler 未识别为已知爬虫时为空
+	Crawler *Crawler `json:"crawler"`
+	// Action allow、challenge 或 deny，未识别或策略未覆盖时为 allow
+	Action string `json:"action"`
+	// RobotsDisallowed 站点的 robots.txt 禁止该爬虫访问请求的路径，未提供路径或 robots.txt 尚未获取时为空
+	RobotsDisallowed *bool `json:"robots_disallowed,omitempty"`
+	// RobotsRule 生效的 robots.txt 规则，如 Disallow: /private/
+	RobotsRule string `json:"robots_rule,omitempty"`
+}
+
+// RobotsStatus 站点 robots.txt 的缓存状态
+type RobotsStatus struct {
+	SiteID string `json:"site_id"`
+	URL    string `json:"url"`
+	// Groups robots.txt 中的 User-agent 分组数，不存在（4xx）时为0，视为允许所有路径
+	Groups    int        `json:"groups"`
+	FetchedAt *time.Time `json:"fetched_at,omitempty"`
+	// Error 最近一次抓取失败的原因，失败时继续使用之前的内容，从未成功时不审计
+	Error string `json:"error,omitempty"`
+}
+
+// RobotsViolation 爬虫访问的被 robots.txt 禁止的路径
+type RobotsViolation struct {
+	Path     string    `json:"path"`
+	Rule     string    `json:"rule"`
+	Count    int       `json:"count"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// RobotsCrawlerStats 爬虫访问的路径中被 robots.txt 禁止的比例
+type RobotsCrawlerStats struct {
+	Name     string `json:"name"`
+	Operator string `json:"operator"`
+	Category string `json:"category"`
+	Purpose  string `json:"purpose,omitempty"`
+	// Requests 审计的请求数
+	Requests   int `json:"requests"`
+	Disallowed int `json:"disallowed"`
+	// ComplianceRate 未访问禁止路径的请求占比
+	ComplianceRate float64 `json:"compliance_rate"`
+	// Violations 最近访问的禁止路径，按次数排序
+	Violations []RobotsViolation `json:"violations,omitempty"`
+}
+
+// RobotsReport 已识别爬虫的 robots.txt 遵守情况，访问过禁止路径的爬虫单独列出
+type RobotsReport struct {
+	SiteID       string               `json:"site_id,omitempty"`
+	From         time.Time            `json:"from"`
+	To           time.Time            `json:"to"`
+	NonCompliant []RobotsCrawlerStats `json:"non_compliant"`
+	Compliant    []RobotsCrawlerStats `json:"compliant"`
+	Robots       []RobotsStatus       `json:"robots"`
+}
+
+// 访问日志客户端的评分原因
+const (
+	// AccessLogNoJS 请求了页面却未关联到执行过采集脚本的指纹
+	AccessLogNoJS = "no_js"
+	// AccessLogNoAssets 只请求页面，从不加载样式、脚本和图片等静态资源
+	AccessLogNoAssets = "no_assets"
+	// AccessLogHighPageRate 每分钟页面请求数超过 max_page_rate
+	AccessLogHighPageRate = "high_page_rate"
+	// AccessLogErrorRate 半数以上请求返回4xx/5xx，常见于路径探测
+	AccessLogErrorRate = "error_rate"
+)
+
+// AccessLogIngest 一批访问日志的接入结果
+type AccessLogIngest struct {
+	Lines    int `json:"lines"`
+	Ingested int `json:"ingested"`
+	// Invalid 无法解析的行数，Errors 给出前几行的原因
+	Invalid int      `json:"invalid"`
+	Errors  []string `json:"errors,omitempty"`
+	// Clients 涉及的客户端（IP + User Agent）数
+	Clients int `json:"clients"`
+	// Crawlers 按User Agent识别为已知爬虫的请求数
+	Crawlers int `json:"crawlers"`
+}
+
+// AccessLogClient 访问日志中的一个客户端（IP + User Agent）一天内的请求汇总及评分
+type AccessLogClient struct {
+	SiteID    string    `json:"site_id"`
+	Day       time.Time `json:"day"`
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent"`
+	Requests  int       `json:"requests"`
+	Pages     int       `json:"pages"`
+	Assets    int       `json:"assets"`
+	Errors    int       `json:"errors"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	// Crawler 按User Agent识别出的已知爬虫
+	Crawler string `json:"crawler,omitempty"`
+	// FingerprintHash 关联到的指纹，为空表示从未执行采集脚本
+	FingerprintHash string `json:"fingerprint_hash,omitempty"`
+	// CDNBotScore CDN 日志给出的最低爬虫评分（越低越可能是自动化程序），日志不含评分时为空
+	CDNBotScore *int `json:"cdn_bot_score,omitempty"`
+	// BotScore 尚未评分时为空
+	BotScore *float64 `json:"bot_score"`
+	IsBot    bool     `json:"is_bot"`
+	Reasons  []string `json:"reasons"`
+}
+
+// AccessLogStats 访问日志中客户端的汇总：关联到指纹的比例、从未执行采集脚本的客户端和判定为爬虫的客户端
+type AccessLogStats struct {
+	SiteID   string    `json:"site_id,omitempty"`
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Clients  int       `json:"clients"`
+	Requests int       `json:"requests"`
+	Pages    int       `json:"pages"`
+	// Correlated 关联到指纹的客户端数
+	Correlated int `json:"correlated"`
+	// NoJS 请求页面达到 min_pages 却未关联到指纹的客户端数
+	NoJS int `json:"no_js"`
+	// CDNBots CDN 爬虫评分不高于 cdn_bot_threshold 的客户端数
+	CDNBots int `json:"cdn_bots"`
+	Bots    int `json:"bots"`
+	// Pending 尚未评分的客户端数
+	Pending         int     `json:"pending"`
+	CorrelationRate float64 `json:"correlation_rate"`
+	// Top 评分最高的客户端
+	Top []AccessLogClient `json:"top"`
+}
+
+// CrawlerTraffic 爬虫的请求数及其中按站点爬虫策略要求验证和拒绝的数量
+type CrawlerTraffic struct {
+	Requests   int `json:"requests"`
+	Challenged int `json:"challenged"`
+	Denied     int `json:"denied"`
+}
+
+// CrawlerTrafficStats 某个爬虫、类别或AI爬虫用途的流量
+type CrawlerTrafficStats struct {
+	// Name 爬虫名称、类别或用途
+	Name     string `json:"name"`
+	Operator string `json:"operator,omitempty"`
+	Category string `json:"category,omitempty"`
+	Purpose  string `json:"purpose,omitempty"`
+	CrawlerTraffic
+	// Share 占已识别爬虫请求的比例
+	Share float64 `json:"share"`
+}
+
+// CrawlerDayStats 某一天（UTC）各类别的爬虫请求数
+type CrawlerDayStats struct {
+	Day        time.Time      `json:"day"`
+	Categories map[string]int `json:"categories"`
+}
+
+// CrawlerStats 已识别爬虫的流量：按类别、AI爬虫用途、爬虫和天
+type CrawlerStats struct {
+	SiteID string    `json:"site_id,omitempty"`
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+	CrawlerTraffic
+	// AIShare AI爬虫占已识别爬虫请求的比例
+	AIShare    float64               `json:"ai_share"`
+	Categories []CrawlerTrafficStats `json:"categories"`
+	Purposes   []CrawlerTrafficStats `json:"purposes"`
+	Crawlers   []CrawlerTrafficStats `json:"crawlers"`
+	Series     []CrawlerDayStats     `json:"series"`
+}
+
+// IPProfile IP地址及其所在网段的概况
+type IPProfile struct {
+	// IP 规范化后的地址
+	IP     string `json:"ip"`
+	Subnet string `json:"subnet"`
+	// AddressFingerprints24h 该地址本身24小时内出现的指纹数
+	AddressFingerprints24h int         `json:"address_fingerprints_24h"`
+	AddressLastSeen        *time.Time  `json:"address_last_seen,omitempty"`
+	SubnetStats            SubnetStats `json:"subnet_stats"`
+	// Reputation 网段的历史信誉和该地址命中的情报记录，均没有时为空
+	Reputation *IPReputation `json:"reputation,omitempty"`
+}
+
+// StuffingDetection 撞库检测记录
+type StuffingDetection struct {
+	ID     int    `json:"id"`
+	SiteID string `json:"site_id"`
+	// Kind 聚合维度：fingerprint 或 ip_range
+	Kind      string    `json:"kind"`
+	Key       string    `json:"key"`
+	Failures  int       `json:"failures"`
+	Accounts  int       `json:"accounts"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// 采集爬虫的访问序列模式
+const (
+	// ScrapingOrderedTraversal 按ID或字典序依次遍历同一目录下的页面
+	ScrapingOrderedTraversal = "ordered_traversal"
+	// ScrapingContentOnly 只访问商品、列表等内容页且不加载任何静态资源
+	ScrapingContentOnly = "content_only"
+)
+
+// ScrapingDetection 采集爬虫检测记录
+type ScrapingDetection struct {
+	ID              int    `json:"id"`
+	SiteID          string `json:"site_id"`
+	FingerprintHash string `json:"fingerprint_hash"`
+	// Pattern ordered_traversal 或 content_only
+	Pattern string `json:"pattern"`
+	// Pages 检测时窗口内的页面浏览数
+	Pages int `json:"pages"`
+	// Samples 部分页面路径，按访问顺序
+	Samples   []string  `json:"samples"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// QuarantineEntry 被拒绝的提交
+type QuarantineEntry struct {
+	ID        int    `json:"id"`
+	SiteID    string `json:"site_id"`
+	IPAddress string `json:"ip_address"`
+	// Reason 拒绝时返回的错误代码
+	Reason string `json:"reason"`
+	Detail string `json:"detail,omitempty"`
+	// Headers 白名单内的请求头，不包含Cookie、Authorization等凭据
+	Headers map[string]string `json:"headers"`
+	// Body 请求体，超过 max_body_bytes 的部分被截断；不是合法UTF-8时为base64编码
+	Body         string `json:"body"`
+	BodyEncoding string `json:"body_encoding,omitempty"`
+	// BodySize 原始请求体的字节数
+	BodySize int    `json:"body_size"`
+	BodyHash string `json:"body_hash"`
+	// Count 同一IP以相同原因提交相同请求体的次数
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// AccountDevice 账号使用过的设备
+type AccountDevice struct {
+	FingerprintHash string    `json:"fingerprint_hash"`
+	FirstSeen       time.Time `json:"first_seen"`
+	LastSeen        time.Time `json:"last_seen"`
+	LastIP          string    `json:"last_ip"`
+	LastCountry     string    `json:"last_country,omitempty"`
+}
+
+// AccountSignal 账号接管信号
+type AccountSignal struct {
+	Code            string    `json:"code"`
+	FingerprintHash string    `json:"fingerprint_hash"`
+	Detail          string    `json:"detail"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// AccountRisk 账号风险报告
+type AccountRisk struct {
+	AccountID string          `json:"account_id"`
+	SiteID    string          `json:"site_id"`
+	Devices   []AccountDevice `json:"devices"`
+	Signals   []AccountSignal `json:"signals"`
+	// RiskScore 时间窗口内账号接管信号的权重之和（最大为1）
+	RiskScore float64 `json:"risk_score"`
+	RiskLevel string  `json:"risk_level"`
+}
+
+// Event 已存储的业务事件
+type Event struct {
+	ID              int64             `json:"id"`
+	FingerprintHash string            `json:"fingerprint_hash"`
+	SiteID          string            `json:"site_id"`
+	EventType       string            `json:"event_type"`
+	AccountID       string            `json:"account_id,omitempty"`
+	Outcome         string            `json:"outcome"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
+	IPAddress       string            `json:"ip_address"`
+	Country         string            `json:"country,omitempty"`
+	Decision        EventDecision     `json:"decision"`
+	CreatedAt       time.Time         `json:"created_at"`
+}
+
+// AuditEntry 管理操作的审计记录
+type AuditEntry struct {
+	ID        int             `json:"id"`
+	Actor     string          `json:"actor"`
+	Action    string          `json:"action"`
+	Target    string          `json:"target"`
+	Before    json.RawMessage `json:"before"`
+	After     json.RawMessage `json:"after"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// ScoreRule 评分表达式规则，字段含义与配置中的 detection.expressions 相同
+type ScoreRule struct {
+	Name   string   `json:"name"`
+	Expr   string   `json:"expr"`
+	Weight float64  `json:"weight"`
+	Reason string   `json:"reason,omitempty"`
+	Sites  []string `json:"sites,omitempty"`
+}
+
+// RuleSet 评分表达式规则集的一个版本；版本0为配置文件中的表达式，未通过管理API修改过规则时生效
+type RuleSet struct {
+	Version   int64       `json:"version"`
+	Rules     []ScoreRule `json:"rules"`
+	Comment   string      `json:"comment"`
+	Actor     string      `json:"actor"`
+	CreatedAt *time.Time  `json:"created_at"`
+	Active    bool        `json:"active"`
+}
+
+// Webhook 通过管理API配置的告警webhook
+type Webhook struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Kinds 只发送这些类型的告警，为空时发送所有告警
+	Kinds     []string  `json:"kinds"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FeatureFlag 可在运行时开关的检测功能
+type FeatureFlag struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	// Default 未通过管理API修改时是否启用，检测器插件按 detection.detectors.disabled
+	Default bool `json:"default"`
+	// Overridden 为 true 时开关已通过管理API修改
+	Overridden bool `json:"overridden"`
+}
+
+// 站点挑战策略：风险等级达到该级别时要求接入方进行人机验证
+const (
+	ChallengeOff    = "off"
+	ChallengeHigh   = "high"
+	ChallengeMedium = "medium"
+)
+
+// SitePolicyOverride 站点的评分与策略覆盖，存储在数据库中，可通过管理API修改
+type SitePolicyOverride struct {
+	SiteID string `json:"site_id"`
+	// BotThreshold 爬虫评分超过该值判定为爬虫，为空时使用全局阈值
+	BotThreshold *float64 `json:"bot_threshold,omitempty"`
+	// RuleWeights 按原因代码覆盖扩展检测信号的权重
+	RuleWeights map[string]float64 `json:"rule_weights,omitempty"`
+	// EventBotScore 按业务事件类型覆盖的爬虫判定阈值，优先于全局的事件阈值
+	EventBotScore map[string]float64 `json:"event_bot_score,omitempty"`
+	// ChallengePolicy off（默认）、high 或 medium
+	ChallengePolicy string `json:"challenge_policy,omitempty"`
+	// RetentionDays 该站点的指纹超过该天数未出现时自动删除，0 表示不清理
+	RetentionDays int `json:"retention_days"`
+	// CountryPolicy 按访客国家的处理策略，为空表示不限制
+	CountryPolicy *CountryPolicy `json:"country_policy,omitempty"`
+	// CrawlerPolicy 对已识别爬虫的处理策略，为空表示不限制
+	CrawlerPolicy *CrawlerPolicy `json:"crawler_policy,omitempty"`
+	// Actions 按受保护操作（如 login、api_read、checkout）的决策策略，键为操作名
+	Actions   map[string]ActionPolicy `json:"actions,omitempty"`
+	UpdatedAt time.Time               `json:"updated_at"`
+}
+
+// ActionPolicy 受保护操作的决策策略，未设置的项沿用站点策略
+type ActionPolicy struct {
+	// BotScore 爬虫评分超过该值时拒绝，优先于所有事件阈值
+	BotScore *float64 `json:"bot_score,omitempty"`
+	// ChallengePolicy off、high 或 medium，覆盖站点的挑战策略
+	ChallengePolicy string `json:"challenge_policy,omitempty"`
+}
+
+// 国家策略的匹配方式
+const (
+	// CountryModeServed Countries 为站点服务的国家，其余国家执行 Action
+	CountryModeServed = "served"
+	// CountryModeListed Countries 中的国家执行 Action
+	CountryModeListed = "listed"
+)
+
+// CountryPolicy 按访客国家（来自国家请求头）对提交和业务事件给出的处理
+type CountryPolicy struct {
+	Mode      string   `json:"mode"`
+	Countries []string `json:"countries"`
+	// Action challenge 或 deny
+	Action string `json:"action"`
+	// IncludeUnknown 国家未知时也执行 Action
+	IncludeUnknown bool `json:"include_unknown,omitempty"`
+	// Allowlist 不受国家策略限制的IP、CIDR或指纹哈希
+	Allowlist []string `json:"allowlist,omitempty"`
+}
+
+// 已知爬虫的类别
+const (
+	// CrawlerAI 采集内容用于模型训练、AI搜索或替用户抓取页面的AI爬虫
+	CrawlerAI         = "ai"
+	CrawlerSearch     = "search"
+	CrawlerSEO        = "seo"
+	CrawlerSocial     = "social"
+	CrawlerMonitoring = "monitoring"
+)
+
+// CrawlerCategories 全部爬虫类别
+var CrawlerCategories = []string{CrawlerAI, CrawlerSearch, CrawlerSEO, CrawlerSocial, CrawlerMonitoring}
+
+// AI爬虫的用途
+const (
+	// CrawlerTraining 采集训练数据
+	CrawlerTraining = "training"
+	// CrawlerAISearch 为AI搜索建立索引
+	CrawlerAISearch = "search"
+	// CrawlerAssistant 用户在AI助手中提问时实时抓取页面
+	CrawlerAssistant = "assistant"
+)
+
+// CrawlerPurposes 全部AI爬虫用途
+var CrawlerPurposes = []string{CrawlerTraining, CrawlerAISearch, CrawlerAssistant}
+
+// Crawler 按User Agent识别出的已知爬虫
+type Crawler struct {
+	Name     string `json:"name"`
+	Operator string `json:"operator"`
+	Category string `json:"category"`
+	// Purpose AI爬虫的用途：training、search 或 assistant，其他类别为空
+	Purpose string `json:"purpose,omitempty"`
+}
+
+// CrawlerPolicy 按类别、AI爬虫用途或名称对已识别爬虫给出的处理（allow、challenge 或 deny），
+// 名称优先于用途，用途优先于类别；User Agent可以伪造，allow 不会放宽其他策略的结果
+type CrawlerPolicy struct {
+	Categories map[string]string `json:"categories,omitempty"`
+	Purposes   map[string]string `json:"purposes,omitempty"`
+	// Crawlers 键为特征库中的爬虫名称
+	Crawlers map[string]string `json:"crawlers,omitempty"`
+}
+
+// 流量异常类型
+const (
+	// AnomalySubmissionSpike 提交量超过基线
+	AnomalySubmissionSpike = "submission_spike"
+	// AnomalyBotRateSpike 爬虫比例超过基线
+	AnomalyBotRateSpike = "bot_rate_spike"
+	// AnomalyScoreDrift 爬虫评分分布相对基线发生偏移，Observed 为群体稳定性指数（PSI），Baseline 为配置的告警阈值
+	AnomalyScoreDrift = "score_drift"
+)
+
+// Anomaly 流量异常记录
+type Anomaly struct {
+	ID     int    `json:"id"`
+	SiteID string `json:"site_id"`
+	Kind   string `json:"kind"`
+	// BucketStart 发生异常的统计桶起始时间
+	BucketStart time.Time `json:"bucket_start"`
+	Observed    float64   `json:"observed"`
+	Baseline    float64   `json:"baseline"`
+	Factor      float64   `json:"factor"` // Observed / Baseline
+	Message     string    `json:"message"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ScoreBin 评分分布的一个区间 [Min, Max)，最后一个区间包含1.0
+type ScoreBin struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Count int     `json:"count"`
+	Share float64 `json:"share"`
+	// AtLeast 评分不低于 Min 的提交比例，即阈值取 Min 时判定为爬虫的比例
+	AtLeast float64 `json:"at_least"`
+}
+
+// ScoreBucketStats 一个统计桶内的评分分布概要
+type ScoreBucketStats struct {
+	Start       time.Time `json:"start"`
+	Submissions int       `json:"submissions"`
+	Mean        float64   `json:"mean"`
+	P50         float64   `json:"p50"`
+	P90         float64   `json:"p90"`
+}
+
+// ScoreDrift 最后一个完整统计桶与之前滚动基线的评分分布比较
+type ScoreDrift struct {
+	BucketStart         time.Time `json:"bucket_start"`
+	Submissions         int       `json:"submissions"`
+	BaselineSubmissions int       `json:"baseline_submissions"`
+	Mean                float64   `json:"mean"`
+	BaselineMean        float64   `json:"baseline_mean"`
+	// PSI 群体稳定性指数，0.1以下基本稳定，超过 anomaly.scores.psi 时记录 score_drift 异常
+	PSI     float64 `json:"psi"`
+	Drifted bool    `json:"drifted"`
+}
+
+// ScoreStats 时间范围内线上提交的爬虫评分分布，用于观察评分随时间的变化和调整阈值
+type ScoreStats struct {
+	SiteID       string             `json:"site_id,omitempty"`
+	From         time.Time          `json:"from"`
+	To           time.Time          `json:"to"`
+	Bucket       string             `json:"bucket"`
+	BotThreshold float64            `json:"bot_threshold"`
+	Submissions  int                `json:"submissions"`
+	Mean         float64            `json:"mean"`
+	P50          float64            `json:"p50"`
+	P90          float64            `json:"p90"`
+	P99          float64            `json:"p99"`
+	Histogram    []ScoreBin         `json:"histogram"`
+	Series       []ScoreBucketStats `json:"series"`
+	// Drift 历史不足基线窗口的四分之一时为空
+	Drift *ScoreDrift `json:"drift,omitempty"`
+}
+
+// DashboardOverview 看板首页一次取得的数据：时间范围内的汇总、图表和告警，以及实时计数和最近的检测
+type DashboardOverview struct {
+	SiteID  string           `json:"site_id,omitempty"`
+	From    time.Time        `json:"from"`
+	To      time.Time        `json:"to"`
+	Summary DashboardSummary `json:"summary"`
+	Charts  DashboardCharts  `json:"charts"`
+	Alerts  DashboardAlerts  `json:"alerts"`
+	Live    DashboardLive    `json:"live"`
+	Recent  []Detection      `json:"recent"`
+}
+
+// DashboardSummary 时间范围内的汇总：指纹数按首次出现时间统计，提交次数含同一指纹的重复提交
+type DashboardSummary struct {
+	Fingerprints   int     `json:"fingerprints"`
+	Bots           int     `json:"bots"`
+	BotRate        float64 `json:"bot_rate"`
+	HighRisk       int     `json:"high_risk"`
+	Visitors       int     `json:"visitors"`
+	IPs            int     `json:"ips"`
+	Submissions    int     `json:"submissions"`
+	BotSubmissions int     `json:"bot_submissions"`
+}
+
+// DashboardPoint 时间序列图中的一个点
+type DashboardPoint struct {
+	Time        time.Time `json:"t"`
+	Submissions int       `json:"submissions"`
+	Bots        int       `json:"bots"`
+}
+
+// DashboardCount 分布图或排行中的一项
+type DashboardCount struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+	Bots  int    `json:"bots"`
+}
+
+// DashboardCharts 看板首页的图表数据
+type DashboardCharts struct {
+	// Traffic 按 anomaly.bucket 统计桶的提交量和爬虫数，没有提交的桶为0
+	Traffic []DashboardPoint `json:"traffic"`
+	// Scores 爬虫评分直方图
+	Scores []ScoreBin `json:"scores"`
+	// Risk 各风险等级的指纹数
+	Risk []DashboardCount `json:"risk"`
+	// Reasons 出现最多的检测原因代码
+	Reasons []DashboardCount `json:"reasons"`
+	// Countries 指纹数最多的国家
+	Countries []DashboardCount `json:"countries"`
+}
+
+// DashboardAlerts 最近的流量异常、撞库和采集爬虫检测
+type DashboardAlerts struct {
+	Anomalies          []Anomaly           `json:"anomalies"`
+	CredentialStuffing []StuffingDetection `json:"credential_stuffing"`
+	Scraping           []ScrapingDetection `json:"scraping"`
+}
+
+// DashboardLive 实时计数：按分钟累计的提交量和爬虫数，配置 Redis 计数时为所有实例的合计
+type DashboardLive struct {
+	At            time.Time `json:"at"`
+	Submissions1m int64     `json:"submissions_1m"`
+	Bots1m        int64     `json:"bots_1m"`
+	Submissions5m int64     `json:"submissions_5m"`
+	Bots5m        int64     `json:"bots_5m"`
+	Submissions1h int64     `json:"submissions_1h"`
+	Bots1h        int64     `json:"bots_1h"`
+	// PerMinute 最近60分钟（含当前分钟）每分钟的提交量和爬虫数，最早的在前
+	PerMinute    []DashboardPoint `json:"per_minute"`
+	ActiveBlocks int              `json:"active_blocks"`
+	// Counters 计数存储：local、redis 或 local_fallback
+	Counters string `json:"counters"`
+}
+
+// Detection 检测列表中的一条：指纹最近一次分析的结果
+type Detection struct {
+	FingerprintHash string    `json:"fingerprint_hash"`
+	SiteID          string    `json:"site_id"`
+	IPAddress       string    `json:"ip_address"`
+	Country         string    `json:"country,omitempty"`
+	UserAgent       string    `json:"user_agent"`
+	BotScore        float64   `json:"bot_score"`
+	RiskLevel       string    `json:"risk_level"`
+	IsBot           bool      `json:"is_bot"`
+	ReasonCodes     []string  `json:"reason_codes"`
+	VisitCount      int       `json:"visit_count"`
+	LastSeen        time.Time `json:"last_seen"`
+}
+
+// DetectionQuery 检测列表的过滤条件：Since 不为空时返回其后的检测（轮询），Before 不为空时返回其前的检测（翻页）
+type DetectionQuery struct {
+	SiteID   string
+	BotsOnly bool
+	Since    *time.Time
+	Before   *time.Time
+	Limit    int
+}
+
+// CanvasMatch Canvas输出相近的指纹
+type CanvasMatch struct {
+	FingerprintHash string `json:"fingerprint_hash"`
+	// Distance 两个Canvas SimHash的汉明距离
+	Distance int `json:"distance"`
+}
+
+// AggregateBucket 聚合统计的一个分组
+type AggregateBucket struct {
+	Key   string  `json:"key"`
+	Count int     `json:"count"`
+	Share float64 `json:"share"`
+}
+
+// AggregateExport 加入差分隐私噪声的聚合统计
+type AggregateExport struct {
+	// Epsilon 一次导出的总隐私预算
+	Epsilon float64 `json:"epsilon"`
+	// ReleaseEpsilon 总数和每个直方图各自分得的隐私预算
+	ReleaseEpsilon   float64           `json:"release_epsilon"`
+	MinCount         int               `json:"min_count"`
+	Total            int               `json:"total"`
+	Countries        []AggregateBucket `json:"countries"`
+	Browsers         []AggregateBucket `json:"browsers"`
+	OperatingSystems []AggregateBucket `json:"operating_systems"`
+	GPUFamilies      []AggregateBucket `json:"gpu_families"`
+	GeneratedAt      time.Time         `json:"generated_at"`
+}
+
+// ComponentChange 访客的某个组件哈希的一次变化
+type ComponentChange struct {
+	Component       string    `json:"component"`
+	PreviousHash    string    `json:"previous_hash"`
+	Hash            string    `json:"hash"`
+	FingerprintHash string    `json:"fingerprint_hash"`
+	ChangedAt       time.Time `json:"changed_at"`
+}
+
+// DriftReport 访客的指纹漂移报告
+type DriftReport struct {
+	VisitorID string `json:"visitor_id"`
+	// Changes 按时间排列的组件变化
+	Changes []ComponentChange `json:"changes"`
+	// ChangeCounts 各组件的变化次数
+	ChangeCounts map[string]int `json:"change_counts"`
+}
+
+// VisitorFingerprint 访客Cookie关联过的指纹
+type VisitorFingerprint struct {
+	FingerprintHash string    `json:"fingerprint_hash"`
+	FirstSeen       time.Time `json:"first_seen"`
+	LastSeen        time.Time `json:"last_seen"`
+	Submissions     int       `json:"submissions"`
 }
 
 // FingerprintResponse 返回给前端的响应
 type FingerprintResponse struct {
 	FingerprintHash string    `json:"fingerprint_hash"`
 	Analysis        *Analysis `json:"analysis,omitempty"`
-	Success         bool      `json:"success"`
-	Message         string    `json:"message,omitempty"`
+	// Challenge 按站点的挑战策略，接入方应对该访客进行人机验证
+	Challenge bool `json:"challenge"`
+	// CountryPolicy 站点国家策略给出的处理（challenge 或 deny），未命中时为空
+	CountryPolicy string `json:"country_policy,omitempty"`
+	// Crawler 按User Agent识别出的已知爬虫
+	Crawler *Crawler `json:"crawler,omitempty"`
+	// CrawlerPolicy 站点爬虫策略给出的处理，未识别为爬虫或策略未覆盖时为空
+	CrawlerPolicy string `json:"crawler_policy,omitempty"`
+	// VisitorToken 低风险的提交签发的访客令牌，接入方可在有效期内跳过重新采集
+	VisitorToken string `json:"visitor_token,omitempty"`
+	// Degraded 存储不可用时只返回指纹哈希，分析推迟
+	Degraded bool `json:"degraded,omitempty"`
+	Success  bool `json:"success"`
+	// Code 失败时的错误代码，见 internal/api/apierror
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// TokenKey 访客令牌的签名密钥，Secret 为十六进制
+type TokenKey struct {
+	ID        string     `json:"id"`
+	Secret    string     `json:"secret"`
+	CreatedAt time.Time  `json:"created_at"`
+	RetiredAt *time.Time `json:"retired_at,omitempty"`
+}
+
+// TokenRevocation 访客令牌的吊销请求，按令牌ID或指纹哈希吊销
+type TokenRevocation struct {
+	JTI             string `json:"jti"`
+	FingerprintHash string `json:"fingerprint_hash"`
+}
+
+// BundleImportStats 导入包中一张表的处理结果
+type BundleImportStats struct {
+	Inserted int `json:"inserted"`
+	Updated  int `json:"updated"`
+	Skipped  int `json:"skipped"`
+}
+
+// CandidateRules 待评估的评分规则，未给出的部分沿用当前规则
+type CandidateRules struct {
+	// Thresholds 替换全局评分阈值
+	Thresholds *Thresholds `json:"thresholds,omitempty"`
+	// RuleWeights 按原因代码覆盖所有站点的检测信号权重，站点自己的规则权重优先
+	RuleWeights map[string]float64 `json:"rule_weights,omitempty"`
+	// Sites 按站点替换策略覆盖中的爬虫阈值和规则权重
+	Sites map[string]SitePolicyOverride `json:"sites,omitempty"`
+}
+
+// ScoreDistribution 一组爬虫评分的分布
+type ScoreDistribution struct {
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P99  float64 `json:"p99"`
+	// Histogram 按0.1分桶的数量，最后一桶包含1.0
+	Histogram  [10]int        `json:"histogram"`
+	Bots       int            `json:"bots"`
+	RiskLevels map[string]int `json:"risk_levels"`
+}
+
+// ReplayChange 判定或风险等级发生变化的指纹
+type ReplayChange struct {
+	FingerprintHash string   `json:"fingerprint_hash"`
+	SiteID          string   `json:"site_id,omitempty"`
+	BaselineScore   float64  `json:"baseline_score"`
+	CandidateScore  float64  `json:"candidate_score"`
+	BaselineBot     bool     `json:"baseline_bot"`
+	CandidateBot    bool     `json:"candidate_bot"`
+	BaselineRisk    string   `json:"baseline_risk"`
+	CandidateRisk   string   `json:"candidate_risk"`
+	ReasonCodes     []string `json:"reason_codes"`
+}
+
+// ReplayReport 候选规则与当前规则对已存储指纹的评分差异
+type ReplayReport struct {
+	Fingerprints int               `json:"fingerprints"`
+	Baseline     ScoreDistribution `json:"baseline"`
+	Candidate    ScoreDistribution `json:"candidate"`
+	BecameBot    int               `json:"became_bot"`
+	BecameHuman  int               `json:"became_human"`
+	// RiskTransitions 风险等级变化的计数，键为 "LOW->HIGH" 形式
+	RiskTransitions map[string]int `json:"risk_transitions"`
+	// Changes 判定或风险等级发生变化的指纹，按评分变化幅度降序，最多保留 limit 条
+	Changes []ReplayChange `json:"changes"`
+}
+
+// 人工标注的真实类别
+const (
+	LabelBot   = "bot"
+	LabelHuman = "human"
+)
+
+// Label 指纹的人工标注，作为评估检测质量的真实值
+type Label struct {
+	FingerprintHash string    `json:"fingerprint_hash"`
+	Label           string    `json:"label"`
+	Note            string    `json:"note,omitempty"`
+	Actor           string    `json:"actor"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// LabelRequest 标注请求
+type LabelRequest struct {
+	Label string `json:"label" binding:"required"`
+	Note  string `json:"note"`
+}
+
+// QualityMetrics 以标注为真实值、爬虫为正类的混淆矩阵及指标
+type QualityMetrics struct {
+	TruePositives  int     `json:"true_positives"`
+	FalsePositives int     `json:"false_positives"`
+	TrueNegatives  int     `json:"true_negatives"`
+	FalseNegatives int     `json:"false_negatives"`
+	Precision      float64 `json:"precision"`
+	Recall         float64 `json:"recall"`
+	F1             float64 `json:"f1"`
+	Accuracy       float64 `json:"accuracy"`
+}
+
+// CalibrationBucket 校准曲线的一个评分区间 [Min, Max)，最后一个区间包含1.0
+type CalibrationBucket struct {
+	Min       float64 `json:"min"`
+	Max       float64 `json:"max"`
+	Count     int     `json:"count"`
+	MeanScore float64 `json:"mean_score"`
+	// BotRate 区间内标注为爬虫的比例，评分校准良好时接近 MeanScore
+	BotRate float64 `json:"bot_rate"`
+}
+
+// RuleQuality 单条检测规则（原因代码）在标注样本上的表现
+type RuleQuality struct {
+	Code string `json:"code"`
+	// Fired 触发该规则的标注样本数
+	Fired          int `json:"fired"`
+	TruePositives  int `json:"true_positives"`
+	FalsePositives int `json:"false_positives"`
+	// Precision 触发时确为爬虫的比例；Recall 标注为爬虫的样本中触发该规则的比例
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+}
+
+// 人机验证的结果
+const (
+	ChallengePending   = "pending"
+	ChallengePassed    = "passed"
+	ChallengeFailed    = "failed"
+	ChallengeAbandoned = "abandoned"
+)
+
+// ChallengeStats 人机验证的下发次数和结果
+type ChallengeStats struct {
+	// Code 下发时触发的原因代码，汇总全部验证时为空
+	Code      string `json:"code,omitempty"`
+	Issued    int    `json:"issued"`
+	Passed    int    `json:"passed"`
+	Failed    int    `json:"failed"`
+	Abandoned int    `json:"abandoned"`
+	Pending   int    `json:"pending"`
+	// PassRate 已有结果（通过、失败或放弃）的验证中通过的比例，规则的通过率高说明它拦下了较多真实用户
+	PassRate float64 `json:"pass_rate"`
+}
+
+// QualityReport 检测质量报告：按存储的分析结果与人工标注比较
+type QualityReport struct {
+	From        *time.Time          `json:"from,omitempty"`
+	To          *time.Time          `json:"to,omitempty"`
+	SiteID      string              `json:"site_id,omitempty"`
+	Labeled     int                 `json:"labeled"`
+	Bots        int                 `json:"bots"`
+	Humans      int                 `json:"humans"`
+	Overall     QualityMetrics      `json:"overall"`
+	Calibration []CalibrationBucket `json:"calibration"`
+	// Brier 评分与标注（爬虫为1）之差的平方的平均值，越小越好
+	Brier float64 `json:"brier"`
+	// CalibrationError 各区间平均评分与爬虫比例之差按样本数加权的平均值（ECE）
+	CalibrationError float64       `json:"calibration_error"`
+	Rules            []RuleQuality `json:"rules"`
+	// Challenges 时间范围内下发的人机验证及其结果，ChallengeRules 按下发时的原因代码拆分
+	Challenges     ChallengeStats   `json:"challenges"`
+	ChallengeRules []ChallengeStats `json:"challenge_rules"`
+}
+
+// SimilarFingerprint 与指定指纹共享部分组件哈希的指纹
+type SimilarFingerprint struct {
+	FingerprintHash  string  `json:"fingerprint_hash"`
+	SharedComponents int     `json:"shared_components"`
+	TotalComponents  int     `json:"total_components"`
+	Similarity       float64 `json:"similarity"`
+}
+
+// User Agent解析规则的来源
+const (
+	// UARegexesBuiltin 只使用内置的家族正则
+	UARegexesBuiltin = "builtin"
+	// UARegexesEmbedded 内置的 uap-core 精简副本
+	UARegexesEmbedded = "embedded"
+)
+
+// UARegexesStatus 当前使用的User Agent解析规则
+type UARegexesStatus struct {
+	// Source 规则来源：regexes.yaml 路径、embedded 或 builtin
+	Source         string `json:"source"`
+	BrowserParsers int    `json:"browser_parsers"`
+	OSParsers      int    `json:"os_parsers"`
+	// Skipped Go正则不支持而跳过的规则数
+	Skipped  int        `json:"skipped"`
+	LoadedAt *time.Time `json:"loaded_at,omitempty"`
+	// Error 最近一次加载 regexes_path 失败的原因
+	Error string `json:"error,omitempty"`
+}
+
+// MathHashStats 一个Math结果向量哈希的观测统计
+type MathHashStats struct {
+	MathHash string `json:"math_hash"`
+	Samples  int    `json:"samples"`
+	// Engines 各记录的User Agent所声明的JS引擎及其记录数，无法识别的记为 Unknown
+	Engines map[string]int `json:"engines"`
+	// BaselineEngine 基线数据中收录该哈希的JS引擎，未收录时为空
+	BaselineEngine string `json:"baseline_engine,omitempty"`
+}
+
+// BaselinesStatus 当前使用的基线数据
+type BaselinesStatus struct {
+	// Source 数据来源：文件路径或 embedded
+	Source  string `json:"source"`
+	Version string `json:"version"`
+	Canvas  int    `json:"canvas"`
+	WebGL   int    `json:"webgl"`
+	Audio   int    `json:"audio"`
+	Math    int    `json:"math"`
+	// LoadedAt 加载时间，未加载外部文件时为空
+	LoadedAt *time.Time `json:"loaded_at,omitempty"`
+	// Error 最近一次加载 path 失败的原因
+	Error string `json:"error,omitempty"`
+}
+
+// CrawlersStatus 当前使用的爬虫特征库
+type CrawlersStatus struct {
+	// Source 特征库来源：文件路径或 embedded
+	Source   string    `json:"source"`
+	Version  string    `json:"version"`
+	Crawlers []Crawler `json:"crawlers"`
+	// Error 启动时加载 path 失败的原因
+	Error string `json:"error,omitempty"`
+}
+
+// FieldError 字段级校验错误
+type FieldError struct {
+	Field      string      `json:"field"`
+	Constraint string      `json:"constraint"`
+	Limit      int         `json:"limit,omitempty"`
+	Got        interface{} `json:"got,omitempty"`
 }
 
 // AnalysisResponse 分析结果响应
@@ -0,0 +1,128 @@
+package models
+
+// 原因代码：写入 Analysis.ReasonCodes 的稳定标识，客户端可据此分支处理
+const (
+	// ReasonWebRTCIPMismatch WebRTC探测到的公网IP与HTTP来源IP不一致（代理/VPN迹象）
+	ReasonWebRTCIPMismatch = "webrtc_ip_mismatch"
+	// ReasonHardwareImpossible CPU核数与内存的组合在真实设备上不可能出现
+	ReasonHardwareImpossible = "hardware_impossible"
+	// ReasonHardwareInvalid deviceMemory 不是浏览器会报告的取值（应为2的幂）
+	ReasonHardwareInvalid = "hardware_invalid"
+	// ReasonHardwareCIProfile 桌面浏览器报告单核CPU，常见于CI容器中的无头浏览器
+	ReasonHardwareCIProfile = "hardware_ci_profile"
+	// ReasonMediaDevicesNone 桌面Chrome未枚举到任何媒体设备，无头浏览器的典型特征
+	ReasonMediaDevicesNone = "media_devices_none"
+	// ReasonMobileNoSensors 声明为移动设备却没有任何运动传感器
+	ReasonMobileNoSensors = "mobile_no_sensors"
+	// ReasonScreenOuterZero 窗口外部尺寸为0，无头浏览器的经典特征
+	ReasonScreenOuterZero = "screen_outer_zero"
+	// ReasonScreenMetricsInvalid 像素比、色深或可用区域超出真实设备的取值范围
+	ReasonScreenMetricsInvalid = "screen_metrics_invalid"
+	// ReasonMathEngineMismatch Math结果向量所属的JS引擎与User Agent声明的浏览器不一致
+	ReasonMathEngineMismatch = "math_engine_mismatch"
+	// ReasonFeatureVersionMismatch 缺少User Agent声明的浏览器版本早已支持的特性
+	ReasonFeatureVersionMismatch = "feature_version_mismatch"
+	// ReasonFontPlatformMismatch 检测不到声明操作系统的任何默认字体
+	ReasonFontPlatformMismatch = "font_platform_mismatch"
+	// ReasonPluginProfileMismatch 插件列表不符合声明浏览器的预期插件列表
+	ReasonPluginProfileMismatch = "plugin_profile_mismatch"
+	// ReasonContentBlocking 检测到广告拦截（仅在站点策略开启时计分）
+	ReasonContentBlocking = "content_blocking"
+	// ReasonExtensionsDetected 检测到浏览器扩展（仅在站点策略开启时计分）
+	ReasonExtensionsDetected = "extensions_detected"
+	// ReasonPrivacyBrowser 识别为隐私浏览器（Brave、Firefox RFP），随机化特征不计分
+	ReasonPrivacyBrowser = "privacy_browser"
+	// ReasonCanvasNoiseInjected 服务端在Canvas图像的平坦区域检测到孤立的低位噪点
+	ReasonCanvasNoiseInjected = "canvas_noise_injected"
+	// ReasonCanvasRandomized 同一访客多次提交的Canvas感知相同但像素不同（逐次随机化）
+	ReasonCanvasRandomized = "canvas_randomized"
+	// ReasonAudioNoiseInjected 服务端分析音频原始采样发现注入的抖动
+	ReasonAudioNoiseInjected = "audio_noise_injected"
+	// ReasonRenderDrift 同一访客Cookie的Canvas、WebGL和音频指纹同时改变（切换了伪造的设备配置）
+	ReasonRenderDrift = "render_drift"
+	// ReasonFarmMember 指纹属于检测任务发现的设备农场
+	ReasonFarmMember = "farm_member"
+	// ReasonBlocklisted 指纹或IP段在临时封禁名单中
+	ReasonBlocklisted = "blocklisted"
+	// ReasonCookieMismatch 同一访客Cookie的指纹大部分组件都变了（Cookie被转移到其他设备或伪造配置）
+	ReasonCookieMismatch = "cookie_fingerprint_mismatch"
+	// ReasonCookieCycling 同一指纹在短时间内使用了大量不同的访客Cookie
+	ReasonCookieCycling = "cookie_cycling"
+	// ReasonCredentialStuffing 同一设备或IP段对大量账号登录失败（撞库）
+	ReasonCredentialStuffing = "credential_stuffing"
+	// ReasonChallengeFailures 同一设备或IP段多次未通过人机验证，用作封禁原因
+	ReasonChallengeFailures = "challenge_failures"
+	// ReasonAccountNewDevice 账号在之前未使用过的设备上提交事件
+	ReasonAccountNewDevice = "account_new_device"
+	// ReasonImpossibleTravel 同一账号在短时间内从不同国家提交事件
+	ReasonImpossibleTravel = "impossible_travel"
+	// ReasonDeviceManyAccounts 同一设备短时间内使用了大量账号
+	ReasonDeviceManyAccounts = "device_many_accounts"
+	// ReasonRoboticTyping 登录、注册表单的按键节奏过快或过于均匀，输入由脚本产生
+	ReasonRoboticTyping = "robotic_typing"
+	// ReasonTypingInconsistent 按键节奏与账号以往的节奏差异过大
+	ReasonTypingInconsistent = "typing_inconsistent"
+	// ReasonThreatIntel 来源IP被IP信誉情报源列出
+	ReasonThreatIntel = "threat_intel_listed"
+	// ReasonIPReputation 来源IP近期有爬虫判定、人机验证失败或情报源命中的记录
+	ReasonIPReputation = "ip_reputation"
+	// ReasonProxySuspected 疑似住宅代理：同一指纹轮换住宅ASN，或TTL、时延、WebRTC地址中有多项与直连不符
+	ReasonProxySuspected = "proxy_suspected"
+	// ReasonLanguageMismatch navigator.language 的主语言不在提交请求的 Accept-Language 中
+	ReasonLanguageMismatch = "accept_language_mismatch"
+	// ReasonLanguageCountryMismatch 浏览器语言都不是访客国家的通行语言
+	ReasonLanguageCountryMismatch = "language_country_mismatch"
+	// ReasonDirectDeepHits 同一指纹或网段短时间内没有来源直接访问大量深层页面
+	ReasonDirectDeepHits = "direct_deep_hits"
+	// ReasonScrapingPattern 页面浏览序列呈现采集爬虫的模式：按ID或字典序遍历，或只访问内容页且不加载静态资源
+	ReasonScrapingPattern = "scraping_pattern"
+	// ReasonConcurrentSessions 同一指纹或访客Cookie同时从多个网段或国家活动
+	ReasonConcurrentSessions = "concurrent_sessions"
+	// ReasonJSProofInvalid 采集脚本的执行证明缺失或不正确，请求不是由运行采集脚本产生的
+	ReasonJSProofInvalid = "js_proof_invalid"
+	// ReasonCollectionTimingInvalid 采集耗时不可能或采集时间与服务端时钟偏差过大，提交可能是预先录制后重放的
+	ReasonCollectionTimingInvalid = "collection_timing_invalid"
+	// ReasonEmulatorSuspected 声明为移动设备的请求来自Android模拟器或iOS模拟器
+	ReasonEmulatorSuspected = "emulator_suspected"
+	// ReasonMobileScriptedInput 移动设备的触摸压力和接触面积、或运动传感器读数完全没有变化，输入由脚本产生
+	ReasonMobileScriptedInput = "mobile_scripted_input"
+	// ReasonComponentsMissing 提交缺少Canvas、WebGL、音频、字体或插件等指纹组件（被拦截或采集失败）
+	ReasonComponentsMissing = "components_missing"
+	// ReasonCanvasStaticMismatch 固定Canvas图案与声明的浏览器、系统和GPU类别的已知哈希都不一致，Canvas被伪造或随机化
+	ReasonCanvasStaticMismatch = "canvas_static_mismatch"
+	// ReasonCanvasSeedInvalid 种子Canvas图案没有随种子变化、同一种子的结果不可复现，或结果被重放到其他种子
+	ReasonCanvasSeedInvalid = "canvas_seed_invalid"
+	// ReasonWebGLSpoof WebGL参数、扩展或着色器精度自相矛盾，或与渲染器所属GPU类别的真实取值不符
+	ReasonWebGLSpoof = "webgl_spoof"
+	// ReasonGPUPlatformMismatch WebGL渲染器所属的GPU家族不会出现在User Agent声明的操作系统上
+	ReasonGPUPlatformMismatch = "gpu_platform_mismatch"
+	// ReasonStatisticalOutlier 孤立森林判定指纹的配置组合在已存储的指纹中是统计上的离群点
+	ReasonStatisticalOutlier = "statistical_outlier"
+	// ReasonBrowserVersionUnreleased User Agent或Client Hints声明的主版本比当前稳定版高出太多，尚未发布
+	ReasonBrowserVersionUnreleased = "browser_version_unreleased"
+	// ReasonBrowserVersionObsolete User Agent或Client Hints声明的主版本早已被取代，不可能仍在使用
+	ReasonBrowserVersionObsolete = "browser_version_obsolete"
+	// ReasonAICrawler User Agent属于特征库中的AI爬虫（训练数据采集、AI搜索或AI助手抓取）
+	ReasonAICrawler = "ai_crawler"
+	// ReasonKnownCrawler User Agent属于特征库中的其他已知爬虫（搜索引擎、SEO、社交预览、监控）
+	ReasonKnownCrawler = "known_crawler"
+	// ReasonCDNBotScore 同一IP和User Agent在CDN日志中的爬虫评分（如 Cloudflare BotScore）低于阈值
+	ReasonCDNBotScore = "cdn_bot_score"
+)
+
+// ReasonCodes 所有原因代码，用于校验站点的规则权重覆盖
+var ReasonCodes = []string{
+	ReasonWebRTCIPMismatch, ReasonHardwareImpossible, ReasonHardwareInvalid, ReasonHardwareCIProfile,
+	ReasonMediaDevicesNone, ReasonMobileNoSensors, ReasonScreenOuterZero, ReasonScreenMetricsInvalid,
+	ReasonMathEngineMismatch, ReasonFeatureVersionMismatch, ReasonFontPlatformMismatch,
+	ReasonPluginProfileMismatch, ReasonContentBlocking, ReasonExtensionsDetected, ReasonPrivacyBrowser,
+	ReasonCanvasNoiseInjected, ReasonCanvasRandomized, ReasonAudioNoiseInjected, ReasonRenderDrift,
+	ReasonFarmMember, ReasonBlocklisted, ReasonCookieMismatch, ReasonCookieCycling, ReasonThreatIntel,
+	ReasonIPReputation, ReasonProxySuspected, ReasonLanguageMismatch, ReasonLanguageCountryMismatch,
+	ReasonDirectDeepHits, ReasonScrapingPattern, ReasonConcurrentSessions,
+	ReasonJSProofInvalid, ReasonCollectionTimingInvalid, ReasonEmulatorSuspected,
+	ReasonMobileScriptedInput, ReasonComponentsMissing, ReasonCanvasStaticMismatch, ReasonCanvasSeedInvalid,
+	ReasonWebGLSpoof, ReasonGPUPlatformMismatch, ReasonStatisticalOutlier,
+	ReasonBrowserVersionUnreleased, ReasonBrowserVersionObsolete, ReasonAICrawler, ReasonKnownCrawler,
+	ReasonCDNBotScore,
+}
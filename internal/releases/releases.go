@@ -0,0 +1,216 @@
+// Package releases 各浏览器当前稳定版的版本表：主版本号、发布日期和发布周期，
+// 用于判断User Agent和Client Hints中的版本是否过时、不可能仍在使用或尚未发布。
+// 随程序内置一份，可从外部源定期更新
+package releases
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dateLayout 版本表中日期的格式
+const dateLayout = "2006-01-02"
+
+// maxFeedBytes 版本表下载内容的上限
+const maxFeedBytes = 1 << 20
+
+// embeddedTable 内置的版本表，从未成功更新过时使用
+//
+//go:embed table.json
+var embeddedTable []byte
+
+// Table 一份版本表
+type Table struct {
+	// Version 版本表的版本，由维护者在每次更新时修改
+	Version string `json:"version"`
+	// Source 版本表来源：地址、文件路径或 embedded
+	Source   string    `json:"source,omitempty"`
+	Browsers []Release `json:"browsers"`
+}
+
+// Release 某个浏览器家族的稳定版
+type Release struct {
+	// Family 浏览器家族，与User Agent解析结果的家族名一致
+	Family string `json:"family"`
+	// Stable 当前稳定版的主版本号
+	Stable int `json:"stable"`
+	// ReleasedAt 当前稳定版的发布日期（YYYY-MM-DD）
+	ReleasedAt string `json:"released_at"`
+	// CadenceDays 发布周期（天），用于推算版本表更新之后发布的版本和较早版本的发布日期
+	CadenceDays int `json:"cadence_days"`
+	// History 较早主版本的发布日期，主版本号不连续（如 Safari 18 之后为 26）时需要给出
+	History map[string]string `json:"history,omitempty"`
+
+	released time.Time
+	history  map[int]time.Time
+}
+
+// Parse 解析并校验版本表，source 记录来源
+func Parse(data []byte, source string) (*Table, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var t Table
+	if err := dec.Decode(&t); err != nil {
+		return nil, fmt.Errorf("failed to parse browser releases: %w", err)
+	}
+	if t.Version == "" || len(t.Browsers) == 0 {
+		return nil, fmt.Errorf("invalid browser releases: version and browsers are required")
+	}
+	seen := make(map[string]bool, len(t.Browsers))
+	for i := range t.Browsers {
+		r := &t.Browsers[i]
+		if r.Family == "" || seen[r.Family] {
+			return nil, fmt.Errorf("invalid browser releases browsers[%d]: family is empty or duplicated", i)
+		}
+		seen[r.Family] = true
+		if r.Stable < 1 || r.CadenceDays < 1 {
+			return nil, fmt.Errorf("invalid browser releases %s: stable and cadence_days must be positive", r.Family)
+		}
+		var err error
+		if r.released, err = time.Parse(dateLayout, r.ReleasedAt); err != nil {
+			return nil, fmt.Errorf("invalid browser releases %s: released_at: %w", r.Family, err)
+		}
+		r.history = make(map[int]time.Time, len(r.History))
+		for major, date := range r.History {
+			m, err := strconv.Atoi(major)
+			if err != nil || m < 1 || m >= r.Stable {
+				return nil, fmt.Errorf("invalid browser releases %s: history major %q must be below stable", r.Family, major)
+			}
+			if r.history[m], err = time.Parse(dateLayout, date); err != nil {
+				return nil, fmt.Errorf("invalid browser releases %s: history %s: %w", r.Family, major, err)
+			}
+		}
+	}
+	sort.Slice(t.Browsers, func(i, j int) bool { return t.Browsers[i].Family < t.Browsers[j].Family })
+	t.Source = source
+	return &t, nil
+}
+
+// Fetch 从 http(s) 地址或本地文件读取版本表
+func Fetch(ctx context.Context, source string, timeout time.Duration) (*Table, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return nil, err
+		}
+		return Parse(data, source)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download browser releases: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("browser releases feed returned status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read browser releases: %w", err)
+	}
+	if len(data) > maxFeedBytes {
+		return nil, fmt.Errorf("browser releases feed exceeds %d bytes", maxFeedBytes)
+	}
+	return Parse(data, source)
+}
+
+var (
+	embeddedOnce   sync.Once
+	embeddedParsed *Table
+)
+
+// Embedded 内置的版本表
+func Embedded() *Table {
+	embeddedOnce.Do(func() {
+		t, err := Parse(embeddedTable, "embedded")
+		if err != nil {
+			panic(err)
+		}
+		embeddedParsed = t
+	})
+	return embeddedParsed
+}
+
+// Lookup 查找浏览器家族的稳定版
+func (t *Table) Lookup(family string) (*Release, bool) {
+	for i := range t.Browsers {
+		if t.Browsers[i].Family == family {
+			return &t.Browsers[i], true
+		}
+	}
+	return nil, false
+}
+
+// cadence 发布周期
+func (r *Release) cadence() time.Duration {
+	return time.Duration(r.CadenceDays) * 24 * time.Hour
+}
+
+// Expected 按发布周期推算 now 时的稳定版主版本号，版本表未及时更新时也不会把新发布的版本当作尚未发布
+func (r *Release) Expected(now time.Time) int {
+	if now.Before(r.released) {
+		return r.Stable
+	}
+	return r.Stable + int(now.Sub(r.released)/r.cadence())
+}
+
+// releaseDate 主版本的发布日期：版本表给出的日期，没有时按发布周期从下一个已知版本向前推算
+func (r *Release) releaseDate(major int) time.Time {
+	if major >= r.Stable {
+		return r.released.Add(time.Duration(major-r.Stable) * r.cadence())
+	}
+	if date, ok := r.history[major]; ok {
+		return date
+	}
+	next, nextDate := r.Stable, r.released
+	for m, date := range r.history {
+		if m > major && m < next {
+			next, nextDate = m, date
+		}
+	}
+	return nextDate.Add(-time.Duration(next-major) * r.cadence())
+}
+
+// Superseded 主版本被下一个版本取代的时长，仍是当前稳定版或更新的版本时为0。
+// 主版本号不连续时（如 Safari 19~25）取其后第一个已发布的版本
+func (r *Release) Superseded(major int, now time.Time) time.Duration {
+	if major >= r.Expected(now) {
+		return 0
+	}
+	next := major + 1
+	if _, known := r.history[next]; !known && next < r.Stable && r.earliest() < next {
+		next = r.Stable
+		for m := range r.history {
+			if m > major && m < next {
+				next = m
+			}
+		}
+	}
+	return max(0, now.Sub(r.releaseDate(next)))
+}
+
+// earliest 版本表给出发布日期的最早主版本
+func (r *Release) earliest() int {
+	earliest := r.Stable
+	for m := range r.history {
+		earliest = min(earliest, m)
+	}
+	return earliest
+}
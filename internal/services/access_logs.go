@@ -0,0 +1,563 @@
+package services
+
+import (
+	"browser-detection/internal/config"
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// accessLogScoreInterval 对访问日志客户端评分的间隔
+	accessLogScoreInterval = time.Minute
+	// accessLogScoreBatch 每次评分处理的客户端数上限
+	accessLogScoreBatch = 1000
+	// maxAccessLogLine 单行访问日志的长度上限
+	maxAccessLogLine = 64 << 10
+	// maxAccessLogErrors 接入结果中列出的解析错误数
+	maxAccessLogErrors = 10
+	// maxAccessLogRead 跟踪文件时每次读取的字节数上限，其余留到下次
+	maxAccessLogRead = 8 << 20
+	// accessLogTopClients 统计中列出的客户端数
+	accessLogTopClients = 20
+)
+
+// ErrAccessLogsDisabled 未启用访问日志接入
+var ErrAccessLogsDisabled = errors.New("access log ingestion is disabled")
+
+// accessLogAssetExts 视为静态资源的扩展名
+var accessLogAssetExts = map[string]bool{
+	".css": true, ".js": true, ".mjs": true, ".map": true,
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".webp": true, ".avif": true, ".ico": true,
+	".woff": true, ".woff2": true, ".ttf": true, ".otf": true, ".eot": true,
+	".mp4": true, ".webm": true, ".mp3": true,
+}
+
+// accessLogPageExts 视为页面的扩展名，没有扩展名的路径也视为页面
+var accessLogPageExts = map[string]bool{"": true, ".html": true, ".htm": true, ".php": true, ".asp": true, ".aspx": true, ".jsp": true}
+
+// accessLogKey 一个客户端一天的汇总键
+type accessLogKey struct {
+	ip        string
+	userAgent string
+	day       int64
+}
+
+// accessLogTotals 一批日志中一个客户端的请求汇总
+type accessLogTotals struct {
+	requests, pages, assets, errors int
+	firstSeen, lastSeen             time.Time
+	// cdnBotScore 各请求中 CDN 给出的最低爬虫评分，0 表示日志不含评分
+	cdnBotScore int
+}
+
+// accessLogResource 按请求方法和路径的扩展名区分页面和静态资源，其他请求（接口、下载、robots.txt 等）两者都不是
+func accessLogResource(method, p string) (page, asset bool) {
+	if i := strings.IndexAny(p, "?#"); i >= 0 {
+		p = p[:i]
+	}
+	ext := strings.ToLower(path.Ext(p))
+	if accessLogAssetExts[ext] {
+		return false, true
+	}
+	return (method == "" || method == http.MethodGet || method == http.MethodHead) && accessLogPageExts[ext], false
+}
+
+// IngestAccessLogs 读取一批访问日志（每行一条）并按客户端（IP + User Agent）和天汇总，
+// 识别出的已知爬虫计入爬虫流量，其页面请求计入 robots.txt 审计。评分在客户端最后一次请求超过关联窗口后进行
+func (fs *FingerprintService) IngestAccessLogs(ctx context.Context, siteID, format string, r io.Reader) (*models.AccessLogIngest, error) {
+	if fs.accessLogs.Window <= 0 {
+		return nil, ErrAccessLogsDisabled
+	}
+	result := &models.AccessLogIngest{}
+	batch := make(map[accessLogKey]*accessLogTotals)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxAccessLogLine)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		result.Lines++
+		entry, err := utils.ParseAccessLog(line, format)
+		if err != nil {
+			result.Invalid++
+			if len(result.Errors) < maxAccessLogErrors {
+				result.Errors = append(result.Errors, fmt.Sprintf("line %d: %v", result.Lines, err))
+			}
+			continue
+		}
+		result.Ingested++
+
+		page, asset := accessLogResource(entry.Method, entry.Path)
+		seen := entry.Time.Local()
+		key := accessLogKey{ip: entry.IP, userAgent: entry.UserAgent, day: seen.UTC().Truncate(crawlerDay).Unix()}
+		t := batch[key]
+		if t == nil {
+			t = &accessLogTotals{firstSeen: seen, lastSeen: seen}
+			batch[key] = t
+		}
+		t.requests++
+		if page {
+			t.pages++
+		}
+		if asset {
+			t.assets++
+		}
+		if entry.Status >= 400 {
+			t.errors++
+		}
+		if entry.BotScore > 0 && (t.cdnBotScore == 0 || entry.BotScore < t.cdnBotScore) {
+			t.cdnBotScore = entry.BotScore
+		}
+		if seen.Before(t.firstSeen) {
+			t.firstSeen = seen
+		}
+		if seen.After(t.lastSeen) {
+			t.lastSeen = seen
+		}
+
+		if c := fs.crawlerTable.Match(entry.UserAgent); c != nil {
+			result.Crawlers++
+			if err := fs.recordCrawler(ctx, siteID, c, ""); err != nil {
+				return nil, err
+			}
+			if page {
+				if _, _, err := fs.auditRobots(ctx, siteID, c, entry.Path); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	// 读取中断时仍保存已解析的部分
+	readErr := scanner.Err()
+	if err := fs.saveAccessLogTotals(ctx, siteID, batch); err != nil {
+		return nil, err
+	}
+	if readErr != nil {
+		return nil, fmt.Errorf("failed to read access log: %w", readErr)
+	}
+	result.Clients = len(batch)
+	return result, nil
+}
+
+// saveAccessLogTotals 在一个事务中累加各客户端的请求汇总，并标记为待评分
+func (fs *FingerprintService) saveAccessLogTotals(ctx context.Context, siteID string, batch map[accessLogKey]*accessLogTotals) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO access_log_clients (site_id, day, ip_address, user_agent, requests, pages, assets, errors, first_seen, last_seen, cdn_bot_score)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (site_id, day, ip_address, user_agent) DO UPDATE SET
+			requests = requests + excluded.requests,
+			pages = pages + excluded.pages,
+			assets = assets + excluded.assets,
+			errors = errors + excluded.errors,
+			first_seen = MIN(first_seen, excluded.first_seen),
+			last_seen = MAX(last_seen, excluded.last_seen),
+			cdn_bot_score = MIN(COALESCE(cdn_bot_score, excluded.cdn_bot_score), COALESCE(excluded.cdn_bot_score, cdn_bot_score)),
+			pending = 1`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for key, t := range batch {
+		var cdnBotScore interface{}
+		if t.cdnBotScore > 0 {
+			cdnBotScore = t.cdnBotScore
+		}
+		if _, err := stmt.ExecContext(ctx, siteID, key.day, key.ip, key.userAgent,
+			t.requests, t.pages, t.assets, t.errors, t.firstSeen, t.lastSeen, cdnBotScore); err != nil {
+			return fmt.Errorf("failed to save access log client: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// RunAccessLogs 跟踪配置的访问日志文件并定期对客户端评分，直到 ctx 结束；
+// 每个实例跟踪本机的文件，评分只由持有租约的实例进行
+func (fs *FingerprintService) RunAccessLogs(ctx context.Context) {
+	if fs.accessLogs.Window <= 0 {
+		return
+	}
+	var wg sync.WaitGroup
+	for _, f := range fs.accessLogs.Files {
+		wg.Add(1)
+		go func(f config.AccessLogFile) {
+			defer wg.Done()
+			fs.tailAccessLog(ctx, f)
+		}(f)
+	}
+	defer wg.Wait()
+
+	ticker := time.NewTicker(accessLogScoreInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !fs.IsLeader() {
+				continue
+			}
+			if _, err := fs.ScoreAccessLogClients(ctx); err != nil {
+				log.Printf("Access log scoring failed: %v", err)
+			}
+		}
+	}
+}
+
+// tailAccessLog 按 poll_interval 读取文件新增的完整行并接入；启动时从文件末尾开始，
+// 文件被轮转（路径指向新文件）时读完旧文件后从头读取新文件，被截断时从头读取
+func (fs *FingerprintService) tailAccessLog(ctx context.Context, f config.AccessLogFile) {
+	var file *os.File
+	var partial []byte
+	defer func() {
+		if file != nil {
+			file.Close()
+		}
+	}()
+	// 只有启动时已存在的文件从末尾开始，之后出现的文件从头读取
+	start := io.SeekEnd
+	lastErr := ""
+	report := func(err error) {
+		// 同一错误只记录一次，避免文件不存在时每次轮询都写日志
+		if msg := err.Error(); msg != lastErr {
+			log.Printf("Failed to tail access log %s: %v", f.Path, err)
+			lastErr = msg
+		}
+	}
+
+	ticker := time.NewTicker(fs.accessLogs.PollInterval.Std())
+	defer ticker.Stop()
+	for {
+		if file == nil {
+			opened, err := os.Open(f.Path)
+			if err == nil {
+				_, err = opened.Seek(0, start)
+			}
+			if err != nil {
+				if opened != nil {
+					opened.Close()
+				}
+				report(err)
+			} else {
+				file, partial, lastErr = opened, nil, ""
+			}
+			start = io.SeekStart
+		}
+		if file != nil {
+			rotated, err := fs.readAccessLog(ctx, f, file, &partial)
+			if err != nil {
+				report(err)
+			}
+			if rotated {
+				file.Close()
+				file = nil
+				continue
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// readAccessLog 读取并接入文件新增的完整行，不完整的最后一行留在 partial 中；返回文件是否已被轮转
+func (fs *FingerprintService) readAccessLog(ctx context.Context, f config.AccessLogFile, file *os.File, partial *[]byte) (bool, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return true, err
+	}
+	offset, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return true, err
+	}
+	if info.Size() < offset {
+		// 文件被截断（如 copytruncate），从头读取
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return true, err
+		}
+		*partial = nil
+	}
+
+	chunk, err := io.ReadAll(io.LimitReader(file, maxAccessLogRead))
+	if err != nil {
+		return true, err
+	}
+	data := append(*partial, chunk...)
+	end := bytes.LastIndexByte(data, '\n') + 1
+	*partial = append([]byte(nil), data[end:]...)
+	if len(*partial) > maxAccessLogLine {
+		*partial = nil
+	}
+	if end > 0 {
+		if _, err := fs.IngestAccessLogs(ctx, f.SiteID, f.Format, bytes.NewReader(data[:end])); err != nil {
+			return false, err
+		}
+	}
+	if len(chunk) == maxAccessLogRead {
+		// 还有未读完的内容，下次轮询继续
+		return false, nil
+	}
+
+	// 已读到文件末尾：路径指向了另一个文件说明已被轮转
+	current, err := os.Stat(f.Path)
+	if err != nil {
+		return false, nil
+	}
+	return !os.SameFile(info, current), nil
+}
+
+// ScoreAccessLogClients 对有新请求、最后一次请求已超过关联窗口的客户端关联指纹并评分，返回评分的客户端数
+func (fs *FingerprintService) ScoreAccessLogClients(ctx context.Context) (int, error) {
+	if fs.accessLogs.Window <= 0 {
+		return 0, nil
+	}
+	rows, err := fs.db.DB.QueryContext(ctx, `
+		SELECT site_id, day, ip_address, user_agent, requests, pages, assets, errors, first_seen, last_seen, fingerprint_hash, cdn_bot_score
+		FROM access_log_clients WHERE pending = 1 AND last_seen <= ? LIMIT ?`,
+		time.Now().Add(-fs.accessLogs.Window.Std()), accessLogScoreBatch)
+	if err != nil {
+		return 0, err
+	}
+	var clients []models.AccessLogClient
+	for rows.Next() {
+		var c models.AccessLogClient
+		var day int64
+		if err := rows.Scan(&c.SiteID, &day, &c.IPAddress, &c.UserAgent, &c.Requests, &c.Pages, &c.Assets, &c.Errors,
+			&c.FirstSeen, &c.LastSeen, &c.FingerprintHash, &c.CDNBotScore); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		c.Day = time.Unix(day, 0).UTC()
+		clients = append(clients, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for i := range clients {
+		c := &clients[i]
+		if c.FingerprintHash == "" {
+			if c.FingerprintHash, err = fs.correlateAccessLogClient(ctx, c); err != nil {
+				return i, err
+			}
+		}
+		score, reasons := fs.scoreAccessLogClient(c)
+		reasonsJSON, _ := json.Marshal(reasons)
+		// 评分期间接入了新请求时 requests 已变化，保留 pending 留到下次评分
+		if _, err := fs.db.DB.ExecContext(ctx, `
+			UPDATE access_log_clients SET crawler = ?, fingerprint_hash = ?, bot_score = ?, reasons = ?, pending = 0
+			WHERE site_id = ? AND day = ? AND ip_address = ? AND user_agent = ? AND requests = ?`,
+			c.Crawler, c.FingerprintHash, score, string(reasonsJSON),
+			c.SiteID, c.Day.Unix(), c.IPAddress, c.UserAgent, c.Requests); err != nil {
+			return i, err
+		}
+	}
+	return len(clients), nil
+}
+
+// correlateAccessLogClient 查找同一IP和User Agent、在客户端首次请求前到最后一次请求后的关联窗口内出现过的指纹，没有时返回空
+func (fs *FingerprintService) correlateAccessLogClient(ctx context.Context, c *models.AccessLogClient) (string, error) {
+	window := fs.accessLogs.Window.Std()
+	var hash string
+	err := fs.db.DB.QueryRowContext(ctx, `
+		SELECT fingerprint_hash FROM fingerprints
+		WHERE ip_address = ? AND user_agent = ? AND deleted_at IS NULL AND created_at <= ? AND updated_at >= ?
+		ORDER BY updated_at DESC LIMIT 1`,
+		c.IPAddress, c.UserAgent, c.LastSeen.Add(window), c.FirstSeen.Add(-window)).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return hash, err
+}
+
+// scoreAccessLogClient 按客户端的请求汇总评分并记录识别出的爬虫，自报为已知爬虫的按爬虫特征库的信号权重计分
+func (fs *FingerprintService) scoreAccessLogClient(c *models.AccessLogClient) (float64, []string) {
+	cfg := fs.accessLogs
+	var score float64
+	reasons := []string{}
+	add := func(code string, weight float64) {
+		if weight > 0 {
+			score += weight
+			reasons = append(reasons, code)
+		}
+	}
+
+	c.Crawler = ""
+	if crawler := fs.crawlerTable.Match(c.UserAgent); crawler != nil {
+		c.Crawler = crawler.Name
+		if crawler.Category == models.CrawlerAI {
+			add(models.ReasonAICrawler, fs.crawlerConfig.AIWeight)
+		} else {
+			add(models.ReasonKnownCrawler, fs.crawlerConfig.Weight)
+		}
+	}
+	if c.Pages >= cfg.MinPages {
+		if c.FingerprintHash == "" {
+			add(models.AccessLogNoJS, cfg.NoJSWeight)
+		}
+		if c.Assets == 0 {
+			add(models.AccessLogNoAssets, cfg.NoAssetsWeight)
+		}
+		minutes := math.Max(c.LastSeen.Sub(c.FirstSeen).Minutes(), 1)
+		if float64(c.Pages)/minutes > cfg.MaxPageRate {
+			add(models.AccessLogHighPageRate, cfg.RateWeight)
+		}
+	}
+	if c.Requests >= cfg.MinPages && c.Errors*2 > c.Requests {
+		add(models.AccessLogErrorRate, cfg.ErrorWeight)
+	}
+	if c.CDNBotScore != nil && *c.CDNBotScore <= cfg.CDNBotThreshold {
+		add(models.ReasonCDNBotScore, cfg.CDNWeight)
+	}
+	return math.Round(math.Min(score, 1)*1e4) / 1e4, reasons
+}
+
+// checkCDNBotScore 同一IP和User Agent最近两天（UTC）在CDN日志中的最低爬虫评分不高于 cdn_bot_threshold 时计分。
+// CDN 日志批次通常晚于指纹提交到达，只有之前的请求已接入时才能命中
+func (fs *FingerprintService) checkCDNBotScore(ctx context.Context, fp *models.Fingerprint) []signal {
+	if fs.accessLogs.Window <= 0 || fs.accessLogs.CDNWeight <= 0 || fp.IPAddress == "" {
+		return nil
+	}
+	since := time.Now().UTC().Truncate(crawlerDay).Add(-crawlerDay).Unix()
+	var score sql.NullInt64
+	if err := fs.db.DB.QueryRowContext(ctx, `
+		SELECT MIN(cdn_bot_score) FROM access_log_clients
+		WHERE ip_address = ? AND user_agent = ? AND day >= ? AND cdn_bot_score IS NOT NULL`,
+		fp.IPAddress, fp.UserAgent, since).Scan(&score); err != nil {
+		log.Printf("Failed to query CDN bot score: %v", err)
+		return nil
+	}
+	if !score.Valid || int(score.Int64) > fs.accessLogs.CDNBotThreshold {
+		return nil
+	}
+	return []signal{{
+		Code:   models.ReasonCDNBotScore,
+		Weight: fs.accessLogs.CDNWeight,
+		Reason: fmt.Sprintf("CDN logs give this IP and User Agent a bot score of %d (at most %d indicates automation)",
+			score.Int64, fs.accessLogs.CDNBotThreshold),
+	}}
+}
+
+// purgeAccessLogClients 删除超过保留期的访问日志客户端汇总
+func (fs *FingerprintService) purgeAccessLogClients(ctx context.Context) error {
+	if fs.accessLogs.Window <= 0 {
+		return nil
+	}
+	_, err := fs.db.DB.ExecContext(ctx,
+		"DELETE FROM access_log_clients WHERE last_seen < ?", time.Now().Add(-fs.accessLogs.Retention.Std()))
+	return err
+}
+
+// AccessLogStats 返回 [from, to] 内（按天，UTC）访问日志客户端的汇总及评分最高的客户端，按站点的爬虫判定阈值判定爬虫；
+// from、to 为空时取最近30天，siteID 为空时合并所有站点；结果按 query_cache 缓存
+func (fs *FingerprintService) AccessLogStats(ctx context.Context, from, to *time.Time, siteID string) (*models.AccessLogStats, error) {
+	return cachedQuery(ctx, fs, queryKey("access_logs", from, to, siteID), func(ctx context.Context) (*models.AccessLogStats, error) {
+		return fs.accessLogStats(ctx, from, to, siteID)
+	})
+}
+
+// accessLogStats 查询访问日志统计，不使用缓存
+func (fs *FingerprintService) accessLogStats(ctx context.Context, from, to *time.Time, siteID string) (*models.AccessLogStats, error) {
+	end := time.Now()
+	if to != nil {
+		end = *to
+	}
+	start := end.Add(-30 * crawlerDay)
+	if from != nil {
+		start = *from
+	}
+	stats := &models.AccessLogStats{SiteID: siteID, From: start, To: end, Top: []models.AccessLogClient{}}
+	startDay, endDay := start.UTC().Truncate(crawlerDay).Unix(), end.UTC().Truncate(crawlerDay).Unix()
+
+	// 各站点的爬虫判定阈值不同，按站点分别汇总
+	var siteIDs []string
+	if siteID != "" {
+		siteIDs = []string{siteID}
+	} else {
+		for id := range fs.sites {
+			siteIDs = append(siteIDs, id)
+		}
+		sort.Strings(siteIDs)
+	}
+	for _, id := range siteIDs {
+		var clients, requests, pages, correlated, noJS, cdnBots, bots, pending sql.NullInt64
+		if err := fs.db.Read.QueryRowContext(ctx, `
+			SELECT COUNT(*), SUM(requests), SUM(pages), SUM(fingerprint_hash != ''),
+				SUM(pending = 0 AND fingerprint_hash = '' AND pages >= ?), SUM(cdn_bot_score <= ?),
+				SUM(pending = 0 AND bot_score >= ?), SUM(pending)
+			FROM access_log_clients WHERE site_id = ? AND day >= ? AND day <= ?`,
+			fs.accessLogs.MinPages, fs.accessLogs.CDNBotThreshold, fs.botThreshold(fs.siteOverride(id)), id, startDay, endDay).
+			Scan(&clients, &requests, &pages, &correlated, &noJS, &cdnBots, &bots, &pending); err != nil {
+			return nil, err
+		}
+		stats.Clients += int(clients.Int64)
+		stats.Requests += int(requests.Int64)
+		stats.Pages += int(pages.Int64)
+		stats.Correlated += int(correlated.Int64)
+		stats.NoJS += int(noJS.Int64)
+		stats.CDNBots += int(cdnBots.Int64)
+		stats.Bots += int(bots.Int64)
+		stats.Pending += int(pending.Int64)
+	}
+	stats.CorrelationRate = ratio(stats.Correlated, stats.Clients)
+
+	query := `
+		SELECT site_id, day, ip_address, user_agent, requests, pages, assets, errors, first_seen, last_seen,
+			crawler, fingerprint_hash, cdn_bot_score, bot_score, reasons
+		FROM access_log_clients WHERE bot_score IS NOT NULL AND day >= ? AND day <= ?`
+	args := []interface{}{startDay, endDay}
+	if siteID != "" {
+		query += " AND site_id = ?"
+		args = append(args, siteID)
+	}
+	rows, err := fs.db.Read.QueryContext(ctx, query+" ORDER BY bot_score DESC, pages DESC LIMIT ?", append(args, accessLogTopClients)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c models.AccessLogClient
+		var day int64
+		var score float64
+		var reasons string
+		if err := rows.Scan(&c.SiteID, &day, &c.IPAddress, &c.UserAgent, &c.Requests, &c.Pages, &c.Assets, &c.Errors,
+			&c.FirstSeen, &c.LastSeen, &c.Crawler, &c.FingerprintHash, &c.CDNBotScore, &score, &reasons); err != nil {
+			return nil, err
+		}
+		c.Day = time.Unix(day, 0).UTC()
+		c.BotScore = &score
+		c.IsBot = score >= fs.botThreshold(fs.siteOverride(c.SiteID))
+		c.Reasons = utils.JSONToStringSlice(reasons)
+		stats.Top = append(stats.Top, c)
+	}
+	return stats, rows.Err()
+}
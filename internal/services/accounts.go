@@ -0,0 +1,153 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// maxAccountIDLength 账号标识的最大长度
+const maxAccountIDLength = 128
+
+// accountSignalWeights 账号接管信号计入账号风险评分的权重
+var accountSignalWeights = map[string]float64{
+	models.ReasonAccountNewDevice:   0.2,
+	models.ReasonImpossibleTravel:   0.6,
+	models.ReasonDeviceManyAccounts: 0.4,
+	models.ReasonTypingInconsistent: 0.3,
+}
+
+// evaluateAccount 计算事件的账号接管信号，并记录账号与设备的绑定
+// 账号第一次出现时的设备不算新设备；国家代码依赖 server.country_header
+func (fs *FingerprintService) evaluateAccount(ctx context.Context, event *models.Event) ([]models.AccountSignal, error) {
+	cfg := fs.accounts
+	now := event.CreatedAt
+	var signals []models.AccountSignal
+
+	var known, total int
+	if err := fs.db.DB.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(fingerprint_hash = ?), 0), COUNT(*) FROM account_devices
+		WHERE site_id = ? AND account_id = ?`,
+		event.FingerprintHash, event.SiteID, event.AccountID).Scan(&known, &total); err != nil {
+		return nil, err
+	}
+	if total > 0 && known == 0 {
+		signals = append(signals, models.AccountSignal{
+			Code:   models.ReasonAccountNewDevice,
+			Detail: fmt.Sprintf("Account used a new device (%d known devices)", total),
+		})
+	}
+
+	if event.Country != "" && cfg.ImpossibleTravelWindow > 0 {
+		var prevCountry string
+		var prevAt time.Time
+		err := fs.db.DB.QueryRowContext(ctx, `
+			SELECT country, created_at FROM events
+			WHERE site_id = ? AND account_id = ? AND country != '' AND created_at >= ?
+			ORDER BY id DESC LIMIT 1`,
+			event.SiteID, event.AccountID, now.Add(-cfg.ImpossibleTravelWindow.Std())).Scan(&prevCountry, &prevAt)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		if err == nil && prevCountry != event.Country {
+			signals = append(signals, models.AccountSignal{
+				Code: models.ReasonImpossibleTravel,
+				Detail: fmt.Sprintf("Account seen in %s and %s within %s",
+					prevCountry, event.Country, now.Sub(prevAt).Round(time.Second)),
+			})
+		}
+	}
+
+	if _, err := fs.db.DB.ExecContext(ctx, `
+		INSERT INTO account_devices (site_id, account_id, fingerprint_hash, first_seen, last_seen, last_ip, last_country)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (site_id, account_id, fingerprint_hash) DO UPDATE SET
+			last_seen = excluded.last_seen,
+			last_ip = excluded.last_ip,
+			last_country = excluded.last_country`,
+		event.SiteID, event.AccountID, event.FingerprintHash, now, now, event.IPAddress, event.Country); err != nil {
+		return nil, fmt.Errorf("failed to bind account device: %w", err)
+	}
+
+	if cfg.MaxAccountsPerDevice > 0 {
+		var accounts int
+		if err := fs.db.DB.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM account_devices
+			WHERE site_id = ? AND fingerprint_hash = ? AND last_seen >= ?`,
+			event.SiteID, event.FingerprintHash, now.Add(-cfg.SharedDeviceWindow.Std())).Scan(&accounts); err != nil {
+			return nil, err
+		}
+		if accounts > cfg.MaxAccountsPerDevice {
+			signals = append(signals, models.AccountSignal{
+				Code:   models.ReasonDeviceManyAccounts,
+				Detail: fmt.Sprintf("Device used %d accounts within %s", accounts, cfg.SharedDeviceWindow.Std()),
+			})
+		}
+	}
+
+	for i := range signals {
+		signals[i].FingerprintHash = event.FingerprintHash
+		signals[i].CreatedAt = now
+		if _, err := fs.db.DB.ExecContext(ctx, `
+			INSERT INTO account_signals (site_id, account_id, fingerprint_hash, code, detail, created_at)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			event.SiteID, event.AccountID, event.FingerprintHash, signals[i].Code, signals[i].Detail, now); err != nil {
+			return nil, fmt.Errorf("failed to save account signal: %w", err)
+		}
+	}
+	return signals, nil
+}
+
+// GetAccountRisk 返回账号使用过的设备和时间窗口内的账号接管信号
+// 账号在该站点没有任何记录时返回 sql.ErrNoRows
+func (fs *FingerprintService) GetAccountRisk(ctx context.Context, siteID, accountID string) (*models.AccountRisk, error) {
+	rows, err := fs.db.DB.QueryContext(ctx, `
+		SELECT fingerprint_hash, first_seen, last_seen, last_ip, last_country FROM account_devices
+		WHERE site_id = ? AND account_id = ? ORDER BY last_seen DESC`, siteID, accountID)
+	if err != nil {
+		return nil, err
+	}
+	report := &models.AccountRisk{AccountID: accountID, SiteID: siteID, Devices: []models.AccountDevice{}, Signals: []models.AccountSignal{}}
+	for rows.Next() {
+		var d models.AccountDevice
+		if err := rows.Scan(&d.FingerprintHash, &d.FirstSeen, &d.LastSeen, &d.LastIP, &d.LastCountry); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		report.Devices = append(report.Devices, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(report.Devices) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	rows, err = fs.db.DB.QueryContext(ctx, `
+		SELECT code, fingerprint_hash, detail, created_at FROM account_signals
+		WHERE site_id = ? AND account_id = ? AND created_at >= ? ORDER BY id DESC`,
+		siteID, accountID, time.Now().Add(-fs.accounts.RiskWindow.Std()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var s models.AccountSignal
+		if err := rows.Scan(&s.Code, &s.FingerprintHash, &s.Detail, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		report.Signals = append(report.Signals, s)
+		report.RiskScore += accountSignalWeights[s.Code]
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	report.RiskScore = min(report.RiskScore, 1)
+	report.RiskLevel = fs.calculateRiskLevel(0, report.RiskScore)
+	return report, nil
+}
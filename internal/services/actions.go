@@ -0,0 +1,113 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"context"
+	"sort"
+)
+
+// validateActionPolicies 校验站点按受保护操作的决策策略，操作名的命名规则与事件类型相同
+func validateActionPolicies(actions map[string]models.ActionPolicy) []models.FieldError {
+	var errs []models.FieldError
+	names := make([]string, 0, len(actions))
+	for name := range actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field := "actions." + name
+		if !eventTypePattern.MatchString(name) {
+			errs = append(errs, models.FieldError{Field: field, Constraint: "pattern " + eventTypePattern.String()})
+			continue
+		}
+		p := actions[name]
+		if p.BotScore != nil && (*p.BotScore <= 0 || *p.BotScore > 1) {
+			errs = append(errs, models.FieldError{Field: field + ".bot_score", Constraint: "range (0, 1]", Got: *p.BotScore})
+		}
+		switch p.ChallengePolicy {
+		case "", models.ChallengeOff, models.ChallengeHigh, models.ChallengeMedium:
+		default:
+			errs = append(errs, models.FieldError{Field: field + ".challenge_policy", Constraint: "one of off, high, medium", Got: p.ChallengePolicy})
+		}
+	}
+	return errs
+}
+
+// decide 按受保护操作的策略对指纹的分析结果给出处理建议：
+// 阈值优先取操作策略的 bot_score，否则按操作名取事件阈值；挑战策略优先取操作策略的 challenge_policy
+func (fs *FingerprintService) decide(override models.SitePolicyOverride, analysis *models.Analysis, action string) models.EventDecision {
+	threshold := fs.eventThreshold(override, action)
+	policy, ok := override.Actions[action]
+	if ok {
+		if policy.BotScore != nil {
+			threshold = *policy.BotScore
+		}
+		if policy.ChallengePolicy != "" {
+			override.ChallengePolicy = policy.ChallengePolicy
+		}
+	}
+	decision := models.EventDecision{
+		BotScore:  analysis.BotScore,
+		Threshold: threshold,
+		IsBot:     analysis.BotScore > threshold,
+		RiskLevel: analysis.RiskLevel,
+		Action:    models.ActionAllow,
+	}
+	if ok {
+		decision.Policy = action
+	}
+	if decision.IsBot {
+		decision.Action = models.ActionDeny
+	} else if needsChallenge(override, analysis.RiskLevel) {
+		decision.Action = models.ActionChallenge
+	}
+	return decision
+}
+
+// applyCountryPolicy 按站点国家策略收紧处理建议：deny 覆盖其他结果，challenge 只替换 allow
+func applyCountryPolicy(decision *models.EventDecision, override models.SitePolicyOverride, meta models.RequestMeta, fingerprintHash string) {
+	decision.CountryPolicy = countryAction(override.CountryPolicy, meta.Country, meta.IPAddress, fingerprintHash)
+	switch {
+	case decision.CountryPolicy == models.ActionDeny:
+		decision.Action = models.ActionDeny
+	case decision.CountryPolicy == models.ActionChallenge && decision.Action == models.ActionAllow:
+		decision.Action = models.ActionChallenge
+	}
+}
+
+// Decide 按受保护操作的策略返回指纹当前的处理建议（含封禁名单、国家策略和爬虫策略），不记录事件
+// action 为空时使用站点的默认策略；指纹尚未提交过时返回 sql.ErrNoRows
+func (fs *FingerprintService) Decide(ctx context.Context, fingerprintHash, action string, meta models.RequestMeta) (*models.EventDecision, error) {
+	analysis, err := fs.GetAnalysis(ctx, fingerprintHash)
+	if err != nil {
+		return nil, err
+	}
+	override := fs.siteOverride(meta.SiteID)
+	decision := fs.decide(override, analysis, action)
+	visitorID, err := fs.requestVisitorID(ctx, fingerprintHash, meta)
+	if err != nil {
+		return nil, err
+	}
+	blocked, err := fs.isBlocklisted(ctx, fingerprintHash, meta.IPAddress, visitorID)
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		decision.Blocklisted = true
+		decision.Action = models.ActionDeny
+	}
+	applyCountryPolicy(&decision, override, meta, fingerprintHash)
+	if override.CrawlerPolicy != nil {
+		crawler, err := fs.fingerprintCrawler(ctx, fingerprintHash)
+		if err != nil {
+			return nil, err
+		}
+		applyCrawlerPolicy(&decision, crawlerAction(override.CrawlerPolicy, crawler))
+	}
+	return &decision, nil
+}
+
+// ValidAction 判断操作名是否符合命名规则
+func ValidAction(action string) bool {
+	return eventTypePattern.MatchString(action)
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"browser-detection/internal/alerting"
+	"browser-detection/internal/models"
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// minBaselineRate 爬虫比例基线的下限，避免基线为0时任何爬虫都触发告警
+const minBaselineRate = 0.01
+
+// trafficBucket 返回时间所在统计桶的起始时间（Unix秒）
+func (fs *FingerprintService) trafficBucket(t time.Time) int64 {
+	return t.Truncate(fs.anomaly.Bucket.Std()).Unix()
+}
+
+// recordTraffic 累计站点当前统计桶的提交量和爬虫数
+func (fs *FingerprintService) recordTraffic(ctx context.Context, siteID string, isBot bool) error {
+	if fs.anomaly.Bucket <= 0 {
+		return nil
+	}
+	bots := 0
+	if isBot {
+		bots = 1
+	}
+	_, err := fs.db.DB.ExecContext(ctx, `
+		INSERT INTO traffic_stats (site_id, bucket, submissions, bots) VALUES (?, ?, 1, ?)
+		ON CONFLICT (site_id, bucket) DO UPDATE SET
+			submissions = submissions + 1,
+			bots = bots + excluded.bots`,
+		siteID, fs.trafficBucket(time.Now()), bots)
+	return err
+}
+
+// trafficBaseline 站点在 bucket 之前的滚动基线：平均每桶提交量和爬虫比例
+// 历史不足基线窗口的四分之一时 ok 为false，刚接入的站点不告警
+func (fs *FingerprintService) trafficBaseline(ctx context.Context, siteID string, bucket int64) (submissions, botRate float64, ok bool, err error) {
+	step := int64(fs.anomaly.Bucket.Std() / time.Second)
+
+	var first int64
+	if err := fs.db.DB.QueryRowContext(ctx,
+		"SELECT COALESCE(MIN(bucket), ?) FROM traffic_stats WHERE site_id = ?", bucket, siteID).Scan(&first); err != nil {
+		return 0, 0, false, err
+	}
+	span := (bucket - first) / step
+	if span < int64(max(1, fs.anomaly.BaselineBuckets/4)) {
+		return 0, 0, false, nil
+	}
+	span = min(span, int64(fs.anomaly.BaselineBuckets))
+
+	var total, bots int64
+	if err := fs.db.DB.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(submissions), 0), COALESCE(SUM(bots), 0) FROM traffic_stats
+		WHERE site_id = ? AND bucket >= ? AND bucket < ?`,
+		siteID, bucket-span*step, bucket).Scan(&total, &bots); err != nil {
+		return 0, 0, false, err
+	}
+	// 没有提交的桶按0计入平均值
+	submissions = float64(total) / float64(span)
+	if total > 0 {
+		botRate = float64(bots) / float64(total)
+	}
+	return submissions, botRate, true, nil
+}
+
+// CheckAnomalies 检查 now 之前最后一个完整统计桶，提交量或爬虫比例超过基线的配置倍数时记录异常并告警
+func (fs *FingerprintService) CheckAnomalies(ctx context.Context, now time.Time) ([]models.Anomaly, error) {
+	if fs.anomaly.Bucket <= 0 {
+		return nil, nil
+	}
+	bucket := fs.trafficBucket(now) - int64(fs.anomaly.Bucket.Std()/time.Second)
+
+	type siteTraffic struct {
+		siteID            string
+		submissions, bots int
+	}
+	rows, err := fs.db.DB.QueryContext(ctx,
+		"SELECT site_id, submissions, bots FROM traffic_stats WHERE bucket = ?", bucket)
+	if err != nil {
+		return nil, err
+	}
+	var current []siteTraffic
+	for rows.Next() {
+		var t siteTraffic
+		if err := rows.Scan(&t.siteID, &t.submissions, &t.bots); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		current = append(current, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var anomalies []models.Anomaly
+	for _, t := range current {
+		if t.submissions < fs.anomaly.MinSubmissions {
+			continue
+		}
+		baseline, baselineRate, ok, err := fs.trafficBaseline(ctx, t.siteID, bucket)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		candidates := []models.Anomaly{{
+			Kind:     models.AnomalySubmissionSpike,
+			Observed: float64(t.submissions),
+			Baseline: max(baseline, 1),
+		}, {
+			Kind:     models.AnomalyBotRateSpike,
+			Observed: float64(t.bots) / float64(t.submissions),
+			Baseline: max(baselineRate, minBaselineRate),
+		}}
+		for _, a := range candidates {
+			a.SiteID = t.siteID
+			a.BucketStart = time.Unix(bucket, 0)
+			a.Factor = a.Observed / a.Baseline
+			threshold := fs.anomaly.SubmissionFactor
+			if a.Kind == models.AnomalyBotRateSpike {
+				threshold = fs.anomaly.BotRateFactor
+			}
+			if a.Factor < threshold {
+				continue
+			}
+			a.Message = fmt.Sprintf("%s for site %q: observed %.3g vs baseline %.3g (%.1fx)",
+				a.Kind, a.SiteID, a.Observed, a.Baseline, a.Factor)
+
+			recorded, err := fs.saveAnomaly(ctx, &a)
+			if err != nil {
+				return nil, err
+			}
+			if !recorded {
+				continue
+			}
+			anomalies = append(anomalies, a)
+			if err := fs.alerts.Notify(ctx, alerting.Alert{
+				Kind:    a.Kind,
+				SiteID:  a.SiteID,
+				Message: a.Message,
+				Details: map[string]interface{}{
+					"bucket_start": a.BucketStart,
+					"observed":     a.Observed,
+					"baseline":     a.Baseline,
+					"factor":       a.Factor,
+				},
+				Time: a.CreatedAt,
+			}); err != nil {
+				log.Printf("Failed to send anomaly alert: %v", err)
+			}
+		}
+	}
+	return anomalies, nil
+}
+
+// saveAnomaly 记录异常，同一统计桶已记录过时返回false
+func (fs *FingerprintService) saveAnomaly(ctx context.Context, a *models.Anomaly) (bool, error) {
+	a.CreatedAt = time.Now()
+	result, err := fs.db.DB.ExecContext(ctx, `
+		INSERT OR IGNORE INTO anomalies (site_id, kind, bucket_start, observed, baseline, factor, message, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		a.SiteID, a.Kind, a.BucketStart, a.Observed, a.Baseline, a.Factor, a.Message, a.CreatedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to save anomaly: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil || n == 0 {
+		return false, err
+	}
+	id, err := result.LastInsertId()
+	a.ID = int(id)
+	return true, err
+}
+
+// GetAnomalies 按统计桶时间倒序返回流量异常，siteID 为空时返回所有站点
+func (fs *FingerprintService) GetAnomalies(ctx context.Context, siteID string, limit int) ([]models.Anomaly, error) {
+	query := "SELECT id, site_id, kind, bucket_start, observed, baseline, factor, message, created_at FROM anomalies"
+	var args []interface{}
+	if siteID != "" {
+		query += " WHERE site_id = ?"
+		args = append(args, siteID)
+	}
+	query += " ORDER BY bucket_start DESC, id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := fs.db.Read.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	anomalies := []models.Anomaly{}
+	for rows.Next() {
+		var a models.Anomaly
+		if err := rows.Scan(&a.ID, &a.SiteID, &a.Kind, &a.BucketStart, &a.Observed, &a.Baseline,
+			&a.Factor, &a.Message, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		anomalies = append(anomalies, a)
+	}
+	return anomalies, rows.Err()
+}
+
+// RunAnomalyDetection 每个统计桶结束后检查流量异常，直到 ctx 结束
+func (fs *FingerprintService) RunAnomalyDetection(ctx context.Context) {
+	if fs.anomaly.Bucket <= 0 {
+		return
+	}
+	ticker := time.NewTicker(fs.anomaly.Bucket.Std())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if !fs.IsLeader() {
+				continue
+			}
+			if _, err := fs.CheckAnomalies(ctx, now); err != nil {
+				log.Printf("Anomaly detection failed: %v", err)
+			}
+		}
+	}
+}
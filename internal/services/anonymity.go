@@ -0,0 +1,76 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"context"
+	"database/sql"
+	"math"
+	"sort"
+)
+
+// GetAnonymity 计算指纹的k-匿名报告
+// 按组件统计共享同一组件哈希的指纹数，并统计全部组件都相同的指纹数作为匿名集大小；
+// 只比较组件哈希，加盐站点的哈希只会与同一站点的记录匹配
+func (fs *FingerprintService) GetAnonymity(ctx context.Context, fingerprintHash string) (*models.AnonymityReport, error) {
+	rows, err := fs.db.DB.QueryContext(ctx,
+		"SELECT component, hash FROM fingerprint_components WHERE fingerprint_hash = ?", fingerprintHash)
+	if err != nil {
+		return nil, err
+	}
+	own := make(map[string]string)
+	for rows.Next() {
+		var component, hash string
+		if err := rows.Scan(&component, &hash); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		own[component] = hash
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(own) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	report := &models.AnonymityReport{FingerprintHash: fingerprintHash}
+	if err := fs.db.DB.QueryRowContext(ctx,
+		"SELECT COUNT(DISTINCT fingerprint_hash) FROM fingerprint_components").Scan(&report.TotalFingerprints); err != nil {
+		return nil, err
+	}
+
+	for component, hash := range own {
+		entry := models.ComponentAnonymity{Component: component}
+		if err := fs.db.DB.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM fingerprint_components WHERE component = ? AND hash = ? AND fingerprint_hash != ?",
+			component, hash, fingerprintHash).Scan(&entry.Shared); err != nil {
+			return nil, err
+		}
+		entry.Frequency = float64(entry.Shared+1) / float64(report.TotalFingerprints)
+		entry.Bits = math.Log2(1 / entry.Frequency)
+		report.Components = append(report.Components, entry)
+	}
+	sort.Slice(report.Components, func(i, j int) bool {
+		a, b := report.Components[i], report.Components[j]
+		if a.Bits != b.Bits {
+			return a.Bits > b.Bits
+		}
+		return a.Component < b.Component
+	})
+
+	if err := fs.db.DB.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM (
+			SELECT other.fingerprint_hash FROM fingerprint_components self
+			JOIN fingerprint_components other
+				ON other.component = self.component AND other.hash = self.hash
+			WHERE self.fingerprint_hash = ?
+			GROUP BY other.fingerprint_hash
+			HAVING COUNT(*) = ?
+		)`, fingerprintHash, len(own)).Scan(&report.AnonymitySetSize); err != nil {
+		return nil, err
+	}
+	report.UniquenessScore = 1 / float64(report.AnonymitySetSize)
+
+	return report, nil
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// archiveTables 归档到冷库的表，均以 fingerprint_hash 关联
+var archiveTables = []string{"fingerprints", "analysis"}
+
+// archiveDerivedTables 归档时直接删除的派生索引，可由指纹记录重新计算
+var archiveDerivedTables = []string{"fingerprint_components", "canvas_lsh"}
+
+// notDeleted 返回排除软删除指纹的条件，column 为指纹哈希列
+func notDeleted(column string) string {
+	return column + " NOT IN (SELECT fingerprint_hash FROM fingerprints WHERE deleted_at IS NOT NULL)"
+}
+
+// SoftDeleteFingerprint 软删除指纹及其分析结果并写入审计记录，指纹不存在或已删除时返回 sql.ErrNoRows
+// 软删除的指纹不再出现在查询和近邻索引中，同一设备再次提交时恢复
+func (fs *FingerprintService) SoftDeleteFingerprint(ctx context.Context, fingerprintHash, actor string) error {
+	if err := fs.setDeleted(ctx, fingerprintHash, actor, true); err != nil {
+		return err
+	}
+	if fs.vectors != nil {
+		fs.vectors.Remove(fingerprintHash)
+	}
+	return nil
+}
+
+// RestoreFingerprint 恢复软删除的指纹并写入审计记录，指纹不存在或未删除时返回 sql.ErrNoRows
+func (fs *FingerprintService) RestoreFingerprint(ctx context.Context, fingerprintHash, actor string) error {
+	if err := fs.setDeleted(ctx, fingerprintHash, actor, false); err != nil {
+		return err
+	}
+	if fs.vectors != nil {
+		stored, err := fs.loadFingerprints(ctx, "fingerprint_hash = ?", fingerprintHash)
+		if err != nil {
+			return err
+		}
+		for _, s := range stored {
+			fs.vectors.Add(s.fp.FingerprintHash, fingerprintVector(&s.fp))
+		}
+	}
+	return nil
+}
+
+// setDeleted 在事务中设置或清除指纹和分析结果的 deleted_at
+func (fs *FingerprintService) setDeleted(ctx context.Context, fingerprintHash, actor string, deleted bool) error {
+	var deletedAt interface{}
+	action, current := "restore_fingerprint", "deleted_at IS NOT NULL"
+	if deleted {
+		deletedAt = time.Now()
+		action, current = "delete_fingerprint", "deleted_at IS NULL"
+	}
+
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// 分片存储时 fingerprints 是视图，更新的影响行数总为0，先查询是否存在
+	var found bool
+	if err := tx.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM fingerprints WHERE fingerprint_hash = ? AND "+current+")", fingerprintHash).Scan(&found); err != nil {
+		return err
+	}
+	if !found {
+		return sql.ErrNoRows
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE fingerprints SET deleted_at = ? WHERE fingerprint_hash = ?", deletedAt, fingerprintHash); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE analysis SET deleted_at = ? WHERE fingerprint_hash = ?", deletedAt, fingerprintHash); err != nil {
+		return err
+	}
+	if err := recordAudit(ctx, tx, actor, action, fingerprintHash, nil, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ArchiveFingerprints 将 cutoff 之前最后出现的指纹和所有软删除的指纹连同分析结果移到 path 指定的SQLite冷库，
+// 并删除其组件哈希和Canvas索引，返回归档的指纹数。冷库的表结构随热库的新增列补齐
+func (fs *FingerprintService) ArchiveFingerprints(ctx context.Context, path string, cutoff time.Time) (int, error) {
+	// ATTACH 只对当前连接生效，整个归档过程使用同一个连接
+	conn, err := fs.db.DB.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS archive", path); err != nil {
+		return 0, fmt.Errorf("failed to attach archive: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "DETACH DATABASE archive")
+
+	// 热库的表不带库名，依次在临时库和主库中查找，先于同名的冷库表；分片存储时指纹和分析结果是合并各分片的临时视图
+	columns := make(map[string][]string, len(archiveTables))
+	for _, table := range archiveTables {
+		if columns[table], err = prepareArchiveTable(ctx, conn, table); err != nil {
+			return 0, fmt.Errorf("failed to prepare archive table %s: %w", table, err)
+		}
+	}
+
+	var hashes []string
+	rows, err := conn.QueryContext(ctx,
+		"SELECT fingerprint_hash FROM fingerprints WHERE updated_at < ? OR deleted_at IS NOT NULL", cutoff)
+	if err != nil {
+		return 0, err
+	}
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		hashes = append(hashes, hash)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(hashes) == 0 {
+		return 0, err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	for _, hash := range hashes {
+		for _, table := range archiveTables {
+			list := strings.Join(columns[table], ", ")
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf(
+				"INSERT INTO archive.%[1]s (%[2]s) SELECT %[2]s FROM %[1]s WHERE fingerprint_hash = ?", table, list),
+				hash); err != nil {
+				return 0, fmt.Errorf("failed to archive %s: %w", table, err)
+			}
+		}
+		for _, table := range append(archiveDerivedTables, archiveTables...) {
+			if _, err := tx.ExecContext(ctx,
+				"DELETE FROM "+table+" WHERE fingerprint_hash = ?", hash); err != nil {
+				return 0, fmt.Errorf("failed to delete from %s: %w", table, err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	fs.invalidateVectorIndex()
+	log.Printf("Archived %d fingerprints to %s", len(hashes), path)
+	return len(hashes), nil
+}
+
+// prepareArchiveTable 在冷库中创建与热库相同列的表，已存在时补齐缺少的列，返回热库的列名
+func prepareArchiveTable(ctx context.Context, conn *sql.Conn, table string) ([]string, error) {
+	hotColumns, err := tableColumns(ctx, conn, "", table)
+	if err != nil {
+		return nil, err
+	}
+	archiveColumns, err := tableColumns(ctx, conn, "archive", table)
+	if err != nil {
+		return nil, err
+	}
+	if len(archiveColumns) == 0 {
+		_, err := conn.ExecContext(ctx, fmt.Sprintf("CREATE TABLE archive.%[1]s AS SELECT * FROM %[1]s WHERE 0", table))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		existing := make(map[string]bool, len(archiveColumns))
+		for _, c := range archiveColumns {
+			existing[c] = true
+		}
+		for _, c := range hotColumns {
+			if existing[c] {
+				continue
+			}
+			if _, err := conn.ExecContext(ctx, fmt.Sprintf("ALTER TABLE archive.%s ADD COLUMN %s", table, c)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return hotColumns, nil
+}
+
+// tableColumns 返回 schema 中表的列名，schema 为空时按不带库名的表名查找，表不存在时为空
+func tableColumns(ctx context.Context, conn *sql.Conn, schema, table string) ([]string, error) {
+	query := fmt.Sprintf("PRAGMA table_info(%s)", table)
+	if schema != "" {
+		query = fmt.Sprintf("PRAGMA %s.table_info(%s)", schema, table)
+	}
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var (
+			cid        int
+			name, kind string
+			notNull    int
+			defaultVal sql.NullString
+			pk         int
+		)
+		if err := rows.Scan(&cid, &name, &kind, &notNull, &defaultVal, &pk); err != nil {
+			return nil, err
+		}
+		columns = append(columns, name)
+	}
+	return columns, rows.Err()
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"browser-detection/internal/config"
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// autoBlockReasonPrefix 自动封禁条目的封禁原因前缀，后接规则名
+const autoBlockReasonPrefix = "auto_block:"
+
+// autoBlockRule 自动封禁规则及其自启动以来的触发次数
+type autoBlockRule struct {
+	config.AutoBlockRule
+	triggered atomic.Int64
+}
+
+// newAutoBlockRules 按配置创建自动封禁规则，配置在加载时已校验
+func newAutoBlockRules(rules []config.AutoBlockRule) []*autoBlockRule {
+	out := make([]*autoBlockRule, len(rules))
+	for i, r := range rules {
+		out[i] = &autoBlockRule{AutoBlockRule: r}
+	}
+	return out
+}
+
+// autoBlockKey 返回规则计数和封禁的键及 detection_hits 中对应的列，IP或访客为空时返回空键
+func autoBlockKey(scope string, fp *models.Fingerprint) (kind, column, key string) {
+	switch scope {
+	case config.AutoBlockScopeIP:
+		if fp.IPAddress != "" {
+			key = utils.NormalizeIP(fp.IPAddress)
+		}
+		return models.BlockIP, "ip_address", key
+	case config.AutoBlockScopeIPRange:
+		if fp.IPAddress != "" {
+			key = utils.IPRange(fp.IPAddress)
+		}
+		return models.BlockIPRange, "ip_range", key
+	case config.AutoBlockScopeVisitor:
+		return models.BlockVisitor, "visitor_id", fp.VisitorID
+	default:
+		return models.BlockFingerprint, "fingerprint_hash", fp.FingerprintHash
+	}
+}
+
+// autoBlockMatches 判断分析结果是否计入规则
+func autoBlockMatches(match string, analysis *models.Analysis) bool {
+	if match == config.AutoBlockMatchHigh {
+		return analysis.RiskLevel == "HIGH"
+	}
+	return analysis.IsBot
+}
+
+// applyAutoBlock 记录风险等级为 HIGH 或判定为爬虫的提交，并按自动封禁规则统计窗口内的次数（包括本次），
+// 达到阈值时临时封禁对应的IP、IP段、指纹或访客并写入审计记录；已在封禁中的键不重复封禁，功能停用时不记录
+func (fs *FingerprintService) applyAutoBlock(ctx context.Context, fp *models.Fingerprint, analysis *models.Analysis) error {
+	if len(fs.autoBlock) == 0 || analysis == nil || (analysis.RiskLevel != "HIGH" && !analysis.IsBot) || !fs.featureEnabled(FeatureAutoBlock) {
+		return nil
+	}
+	now := time.Now()
+	ip := utils.NormalizeIP(fp.IPAddress)
+	// 配置 Redis 时各规则的次数在多个实例之间合计，不写入 detection_hits
+	if !fs.counters.Shared() {
+		if _, err := fs.db.DB.ExecContext(ctx, `
+			INSERT INTO detection_hits (fingerprint_hash, ip_address, ip_range, visitor_id, risk_level, is_bot, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			fp.FingerprintHash, ip, utils.IPRange(ip), fp.VisitorID, analysis.RiskLevel, analysis.IsBot, now); err != nil {
+			return fmt.Errorf("failed to record detection hit: %w", err)
+		}
+	}
+
+	for _, rule := range fs.autoBlock {
+		if !autoBlockMatches(rule.Match, analysis) {
+			continue
+		}
+		kind, column, key := autoBlockKey(rule.Scope, fp)
+		if key == "" {
+			continue
+		}
+		hits, err := fs.autoBlockHits(ctx, rule, column, key, now)
+		if err != nil {
+			return err
+		}
+		if hits < rule.Count {
+			continue
+		}
+		active, err := fs.isBlocked(ctx, kind, key)
+		if err != nil {
+			return err
+		}
+		if active {
+			continue
+		}
+		if err := fs.autoBlockEntry(ctx, rule, kind, key, hits); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// autoBlockHits 返回规则窗口内该键的次数（包括本次）：配置 Redis 时计入共享计数的滑动窗口，否则统计 detection_hits
+func (fs *FingerprintService) autoBlockHits(ctx context.Context, rule *autoBlockRule, column, key string, now time.Time) (int, error) {
+	if fs.counters.Shared() {
+		hits, _, err := fs.counters.Window(ctx, "auto_block:"+rule.Name+":"+key, rule.Window.Std(), now)
+		return int(math.Round(hits)), err
+	}
+	condition := "is_bot = 1"
+	if rule.Match == config.AutoBlockMatchHigh {
+		condition = "risk_level = 'HIGH'"
+	}
+	var hits int
+	err := fs.db.DB.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM detection_hits WHERE "+column+" = ? AND "+condition+" AND created_at >= ?",
+		key, now.Add(-rule.Window.Std())).Scan(&hits)
+	return hits, err
+}
+
+// autoBlockEntry 按规则封禁一个键并写入审计记录，操作者记为 system
+func (fs *FingerprintService) autoBlockEntry(ctx context.Context, rule *autoBlockRule, kind, key string, hits int) error {
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	entry, err := saveBlock(ctx, tx, kind, key, autoBlockReasonPrefix+rule.Name, rule.Duration.Std())
+	if err != nil {
+		return err
+	}
+	if err := fs.logReplication(ctx, tx, models.ReplicationBlock, entry); err != nil {
+		return err
+	}
+	after := map[string]interface{}{
+		"rule":       rule.Name,
+		"hits":       hits,
+		"window":     rule.Window,
+		"expires_at": entry.ExpiresAt,
+	}
+	if err := recordAudit(ctx, tx, "system", "auto_block", kind+":"+key, nil, after); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	fs.blocks.put(entry.Kind, entry.Key, entry.ExpiresAt)
+	rule.triggered.Add(1)
+	log.Printf("Auto-block rule %q blocked %s %s for %s after %d hits", rule.Name, kind, key, rule.Duration.Std(), hits)
+	return nil
+}
+
+// purgeDetectionHits 删除超出所有自动封禁规则时间窗口的检测记录
+func (fs *FingerprintService) purgeDetectionHits(ctx context.Context) error {
+	var window time.Duration
+	for _, rule := range fs.autoBlock {
+		if w := rule.Window.Std(); w > window {
+			window = w
+		}
+	}
+	_, err := fs.db.DB.ExecContext(ctx, "DELETE FROM detection_hits WHERE created_at < ?", time.Now().Add(-window))
+	return err
+}
+
+// AutoBlockStatus 返回各自动封禁规则的配置、自启动以来的触发次数和当前未过期的封禁数
+func (fs *FingerprintService) AutoBlockStatus(ctx context.Context) ([]models.AutoBlockRuleStatus, error) {
+	rules := make([]models.AutoBlockRuleStatus, 0, len(fs.autoBlock))
+	for _, rule := range fs.autoBlock {
+		status := models.AutoBlockRuleStatus{
+			Name:      rule.Name,
+			Scope:     rule.Scope,
+			Match:     rule.Match,
+			Count:     rule.Count,
+			Window:    rule.Window.Std().String(),
+			Duration:  rule.Duration.Std().String(),
+			Triggered: rule.triggered.Load(),
+		}
+		if err := fs.db.Read.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM blocklist WHERE reason = ? AND expires_at > ?",
+			autoBlockReasonPrefix+rule.Name, time.Now()).Scan(&status.Active); err != nil {
+			return nil, err
+		}
+		rules = append(rules, status)
+	}
+	return rules, nil
+}
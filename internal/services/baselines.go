@@ -0,0 +1,174 @@
+package services
+
+import (
+	"browser-detection/internal/baselines"
+	"browser-detection/internal/config"
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"time"
+)
+
+// ErrBaselinesNotConfigured 未配置 detection.baselines.path
+var ErrBaselinesNotConfigured = errors.New("detection.baselines.path is not configured")
+
+// LoadBaselines 启动时加载基线数据文件，未配置路径或文件不可用时使用内置的基线
+func (fs *FingerprintService) LoadBaselines() {
+	if fs.baselinesPath == "" {
+		return
+	}
+	fs.baselinesMu.Lock()
+	defer fs.baselinesMu.Unlock()
+
+	c, err := baselines.Load(fs.baselinesPath)
+	if err != nil {
+		log.Printf("Failed to load baselines from %s, using embedded copy: %v", fs.baselinesPath, err)
+		c = baselines.Embedded()
+	}
+	fs.baselinesStatus = fs.activateBaselines(c)
+	if err != nil {
+		fs.baselinesStatus.Error = err.Error()
+	}
+	log.Printf("Loaded baselines %s from %s: %d canvas, %d WebGL, %d audio, %d math",
+		c.Version, c.Source, len(c.Canvas), len(c.WebGL), len(c.Audio), len(c.Math))
+}
+
+// RefreshBaselines 重新读取基线数据文件；读取或校验失败时保留当前数据并返回错误
+func (fs *FingerprintService) RefreshBaselines(ctx context.Context, actor string) (models.BaselinesStatus, error) {
+	if fs.baselinesPath == "" {
+		return fs.Baselines(), ErrBaselinesNotConfigured
+	}
+	fs.baselinesMu.Lock()
+	defer fs.baselinesMu.Unlock()
+
+	c, err := baselines.Load(fs.baselinesPath)
+	if err != nil {
+		fs.baselinesStatus.Error = err.Error()
+		return fs.baselinesStatus, err
+	}
+
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fs.baselinesStatus, err
+	}
+	defer tx.Rollback()
+	before := fs.baselinesStatus
+	after := baselinesStatus(c)
+	if err := recordAudit(ctx, tx, actor, "refresh_baselines", c.Source, before, after); err != nil {
+		return fs.baselinesStatus, err
+	}
+	if err := tx.Commit(); err != nil {
+		return fs.baselinesStatus, err
+	}
+
+	fs.baselinesStatus = fs.activateBaselines(c)
+	return fs.baselinesStatus, nil
+}
+
+// Baselines 返回当前使用的基线数据
+func (fs *FingerprintService) Baselines() models.BaselinesStatus {
+	fs.baselinesMu.Lock()
+	defer fs.baselinesMu.Unlock()
+	return fs.baselinesStatus
+}
+
+// activateBaselines 让检测规则使用基线数据并返回其状态
+func (fs *FingerprintService) activateBaselines(c *baselines.Corpus) models.BaselinesStatus {
+	fs.corpus.Store(c)
+	return baselinesStatus(c)
+}
+
+// baselinesStatus 基线数据的状态
+func baselinesStatus(c *baselines.Corpus) models.BaselinesStatus {
+	status := models.BaselinesStatus{
+		Source:  c.Source,
+		Version: c.Version,
+		Canvas:  len(c.Canvas),
+		WebGL:   len(c.WebGL),
+		Audio:   len(c.Audio),
+		Math:    len(c.Math),
+	}
+	if c.Source != "embedded" {
+		now := time.Now()
+		status.LoadedAt = &now
+	}
+	return status
+}
+
+// canvasBaselines 当前基线数据与配置中的Canvas固定图案已知哈希
+func (fs *FingerprintService) canvasBaselines() [][]config.CanvasBaseline {
+	return [][]config.CanvasBaseline{fs.corpus.Load().Canvas, fs.canvasCheck.Baselines}
+}
+
+// mathEngine 返回基线数据中Math结果向量哈希对应的JS引擎；未收录或被多个引擎收录时返回空
+func (fs *FingerprintService) mathEngine(mathHash string) string {
+	engine := ""
+	for _, b := range fs.corpus.Load().Math {
+		for _, h := range b.Hashes {
+			if h != mathHash {
+				continue
+			}
+			if engine != "" && engine != b.Engine {
+				return ""
+			}
+			engine = b.Engine
+		}
+	}
+	return engine
+}
+
+// audioBaselineSets 当前基线数据与配置中的已知真实音频指纹值
+func (fs *FingerprintService) audioBaselineSets() [][]config.AudioBaseline {
+	return [][]config.AudioBaseline{fs.corpus.Load().Audio, fs.audioBaselines}
+}
+
+// maxMathHashes 观测到的Math结果向量哈希一次返回的最大数量
+const maxMathHashes = 100
+
+// MathHashes 统计最近 samples 条指纹记录中各Math结果向量哈希对应的User Agent所声明的JS引擎，按出现次数降序返回前100个；
+// 只供维护者整理基线数据的 math，检测不读取这些统计
+func (fs *FingerprintService) MathHashes(ctx context.Context, samples int) ([]models.MathHashStats, error) {
+	rows, err := fs.db.DB.QueryContext(ctx,
+		"SELECT math_hash, user_agent FROM fingerprints WHERE math_hash != '' ORDER BY updated_at DESC LIMIT ?", samples)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byHash := make(map[string]*models.MathHashStats)
+	for rows.Next() {
+		var mathHash, userAgent string
+		if err := rows.Scan(&mathHash, &userAgent); err != nil {
+			return nil, err
+		}
+		stats := byHash[mathHash]
+		if stats == nil {
+			stats = &models.MathHashStats{MathHash: mathHash, Engines: make(map[string]int)}
+			byHash[mathHash] = stats
+		}
+		stats.Samples++
+		stats.Engines[utils.ParseUserAgent(userAgent).Engine]++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]models.MathHashStats, 0, len(byHash))
+	for _, stats := range byHash {
+		stats.BaselineEngine = fs.mathEngine(stats.MathHash)
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Samples != result[j].Samples {
+			return result[i].Samples > result[j].Samples
+		}
+		return result[i].MathHash < result[j].MathHash
+	})
+	if len(result) > maxMathHashes {
+		result = result[:maxMathHashes]
+	}
+	return result, nil
+}
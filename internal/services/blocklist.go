@@ -0,0 +1,420 @@
+package services
+
+import (
+	"browser-detection/internal/config"
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// blockFilterMinKeys 过滤器按当前条目数的两倍预留容量，至少预留该数量，两次重建之间新增的封禁不会使误判率明显上升
+const blockFilterMinKeys = 1024
+
+// blockKey 封禁名单条目的类型和键
+type blockKey struct {
+	kind string
+	key  string
+}
+
+// filterKey 条目在过滤器中的键
+func (k blockKey) filterKey() string {
+	return k.kind + ":" + k.key
+}
+
+// blockChange 重建期间本实例写入的封禁（expiresAt 非零）或解除，重建完成后重新应用
+type blockChange struct {
+	key       blockKey
+	expiresAt time.Time
+}
+
+// blockStore 封禁名单的内存缓存：保存所有未过期条目的过期时间，每次决策只查内存，不查数据库；
+// 名单前置布隆过滤器，过滤器判定不在名单中的键（绝大多数请求）不再查找名单。
+// 本实例的封禁和解除立即生效，其他实例（或导入）写入的条目在下次重建后生效
+type blockStore struct {
+	mu             sync.RWMutex
+	filter         *utils.BloomFilter
+	expires        map[blockKey]time.Time
+	loadedAt       time.Time
+	loading        sync.Mutex
+	pending        []blockChange
+	rebuild        bool
+	interval       time.Duration
+	fpRate         float64
+	lookups        atomic.Uint64
+	rejected       atomic.Uint64
+	passed         atomic.Uint64
+	falsePositives atomic.Uint64
+}
+
+// newBlockStore 创建空的封禁名单缓存，首次查询时从数据库加载
+func newBlockStore(cfg config.BlocklistConfig) *blockStore {
+	return &blockStore{
+		expires:  make(map[blockKey]time.Time),
+		interval: cfg.Refresh.Std(),
+		fpRate:   cfg.FalsePositiveRate,
+	}
+}
+
+// apply 在持有写锁时更新条目：expiresAt 为零值时删除，否则保留较晚的过期时间并加入过滤器
+func (s *blockStore) apply(c blockChange) {
+	if c.expiresAt.IsZero() {
+		delete(s.expires, c.key)
+		return
+	}
+	if s.filter != nil {
+		s.filter.Add(c.key.filterKey())
+	}
+	if c.expiresAt.After(s.expires[c.key]) {
+		s.expires[c.key] = c.expiresAt
+	}
+}
+
+// change 更新条目，重建进行中时同时记下，重建完成后重新应用
+func (s *blockStore) change(c blockChange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.apply(c)
+	if s.rebuild {
+		s.pending = append(s.pending, c)
+	}
+}
+
+// put 记录条目的过期时间，已有更晚的过期时间时保留原值
+func (s *blockStore) put(kind, key string, expiresAt time.Time) {
+	s.change(blockChange{key: blockKey{kind, key}, expiresAt: expiresAt})
+}
+
+// remove 删除缓存中的条目；过滤器不支持删除，键在下次重建前仍会通过过滤器，但名单中已没有该条目
+func (s *blockStore) remove(kind, key string) {
+	s.change(blockChange{key: blockKey{kind, key}})
+}
+
+// invalidate 使缓存在下次查询时重新加载
+func (s *blockStore) invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadedAt = time.Time{}
+}
+
+// refresh 缓存超过重建间隔未加载时从数据库读取未过期的条目，重建名单和过滤器，同一时间只有一个请求重建；
+// 已过期的条目随重建移出内存
+func (s *blockStore) refresh(ctx context.Context, db *sql.DB) error {
+	s.mu.RLock()
+	fresh := time.Since(s.loadedAt) < s.interval
+	s.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+	s.loading.Lock()
+	defer s.loading.Unlock()
+	s.mu.Lock()
+	fresh = time.Since(s.loadedAt) < s.interval
+	s.rebuild = !fresh
+	s.pending = nil
+	s.mu.Unlock()
+	if fresh {
+		return nil
+	}
+	defer func() {
+		s.mu.Lock()
+		s.rebuild = false
+		s.pending = nil
+		s.mu.Unlock()
+	}()
+
+	now := time.Now()
+	rows, err := db.QueryContext(ctx, "SELECT kind, key, expires_at FROM blocklist WHERE expires_at > ?", now)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	expires := make(map[blockKey]time.Time)
+	for rows.Next() {
+		var k blockKey
+		var expiresAt time.Time
+		if err := rows.Scan(&k.kind, &k.key, &expiresAt); err != nil {
+			return err
+		}
+		expires[k] = expiresAt
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	capacity := 2 * len(expires)
+	if capacity < blockFilterMinKeys {
+		capacity = blockFilterMinKeys
+	}
+	filter := utils.NewBloomFilter(capacity, s.fpRate)
+	for k := range expires {
+		filter.Add(k.filterKey())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.filter = filter
+	s.expires = expires
+	for _, c := range s.pending {
+		s.apply(c)
+	}
+	s.loadedAt = now
+	return nil
+}
+
+// blocked 判断任一条目是否未过期，keys 为类型到键的映射；过滤器判定可能封禁的键再查内存中的名单
+func (s *blockStore) blocked(keys map[string]string, now time.Time) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for kind, key := range keys {
+		if key == "" {
+			continue
+		}
+		s.lookups.Add(1)
+		k := blockKey{kind, key}
+		if s.filter != nil && !s.filter.Test(k.filterKey()) {
+			s.rejected.Add(1)
+			continue
+		}
+		s.passed.Add(1)
+		if s.expires[k].After(now) {
+			return true
+		}
+		s.falsePositives.Add(1)
+	}
+	return false
+}
+
+// status 返回过滤器的统计
+func (s *blockStore) status() models.BlocklistFilterStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	status := models.BlocklistFilterStatus{
+		Entries:                 len(s.expires),
+		TargetFalsePositiveRate: s.fpRate,
+		RebuiltAt:               s.loadedAt,
+		Lookups:                 s.lookups.Load(),
+		FilterRejected:          s.rejected.Load(),
+		FilterPassed:            s.passed.Load(),
+		FalsePositives:          s.falsePositives.Load(),
+	}
+	if s.filter != nil {
+		stats := s.filter.Stats()
+		status.Keys = stats.Keys
+		status.Bits = stats.Bits
+		status.Bytes = stats.Bytes
+		status.Hashes = stats.Hashes
+		status.EstimatedFalsePositiveRate = stats.FalsePositive
+	}
+	return status
+}
+
+// blockKeys 返回指纹、IP和访客对应的封禁名单键，IP或访客为空时不包括对应的键
+func blockKeys(fingerprintHash, ip, visitorID string) map[string]string {
+	keys := map[string]string{models.BlockFingerprint: fingerprintHash}
+	if ip != "" {
+		keys[models.BlockIP] = utils.NormalizeIP(ip)
+		keys[models.BlockIPRange] = utils.IPRange(ip)
+	}
+	if visitorID != "" {
+		keys[models.BlockVisitor] = visitorID
+	}
+	return keys
+}
+
+// isBlocklisted 判断指纹、IP、IP所在的IP段或访客是否在未过期的封禁名单中
+func (fs *FingerprintService) isBlocklisted(ctx context.Context, fingerprintHash, ip, visitorID string) (bool, error) {
+	if err := fs.blocks.refresh(ctx, fs.db.DB); err != nil {
+		return false, err
+	}
+	return fs.blocks.blocked(blockKeys(fingerprintHash, ip, visitorID), time.Now()), nil
+}
+
+// requestVisitorID 返回判断封禁名单时使用的访客Cookie：请求未携带时（业务后端调用事件和决策接口）
+// 取最近一次提交该指纹的访客，指纹不存在时为空
+func (fs *FingerprintService) requestVisitorID(ctx context.Context, fingerprintHash string, meta models.RequestMeta) (string, error) {
+	if meta.VisitorID != "" {
+		return meta.VisitorID, nil
+	}
+	var visitorID string
+	err := fs.db.DB.QueryRowContext(ctx,
+		"SELECT visitor_id FROM fingerprints WHERE fingerprint_hash = ? AND deleted_at IS NULL", fingerprintHash).Scan(&visitorID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return visitorID, err
+}
+
+// isBlocked 判断单个条目是否在未过期的封禁名单中
+func (fs *FingerprintService) isBlocked(ctx context.Context, kind, key string) (bool, error) {
+	if err := fs.blocks.refresh(ctx, fs.db.DB); err != nil {
+		return false, err
+	}
+	return fs.blocks.blocked(map[string]string{kind: key}, time.Now()), nil
+}
+
+// checkBlocklist 指纹、IP、IP段或访客被临时封禁时直接判定为爬虫
+func (fs *FingerprintService) checkBlocklist(ctx context.Context, fp *models.Fingerprint) []signal {
+	blocked, err := fs.isBlocklisted(ctx, fp.FingerprintHash, fp.IPAddress, fp.VisitorID)
+	if err != nil {
+		log.Printf("Failed to query blocklist: %v", err)
+		return nil
+	}
+	if !blocked {
+		return nil
+	}
+	return []signal{{
+		Code:   models.ReasonBlocklisted,
+		Weight: 1.0,
+		Reason: "Fingerprint, IP, IP range or visitor is temporarily blocklisted",
+	}}
+}
+
+// Block 将指纹、IP、IP段或访客加入临时封禁名单，已存在时延长到新的过期时间
+func (fs *FingerprintService) Block(ctx context.Context, kind, key, reason string, duration time.Duration) (*models.BlockEntry, error) {
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	entry, err := saveBlock(ctx, tx, kind, key, reason, duration)
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.logReplication(ctx, tx, models.ReplicationBlock, entry); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	fs.blocks.put(entry.Kind, entry.Key, entry.ExpiresAt)
+	return entry, nil
+}
+
+// AddBlock 通过管理API临时封禁条目并写入审计记录，已在封禁中时延长到新的过期时间；
+// 调用方负责校验类型并规范化键（IP和IP段与 blockKeys 的格式一致）
+func (fs *FingerprintService) AddBlock(ctx context.Context, kind, key, reason string, duration time.Duration, actor string) (*models.BlockEntry, error) {
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	entry, err := saveBlock(ctx, tx, kind, key, reason, duration)
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.logReplication(ctx, tx, models.ReplicationBlock, entry); err != nil {
+		return nil, err
+	}
+	if err := recordAudit(ctx, tx, actor, "block", kind+":"+key, nil, entry); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	fs.blocks.put(entry.Kind, entry.Key, entry.ExpiresAt)
+	return entry, nil
+}
+
+// execer *sql.DB 和 *sql.Tx 共有的执行方法
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// saveBlock 在数据库或事务中写入封禁名单条目，已存在时延长到新的过期时间；调用方负责在写入成功后更新缓存
+func saveBlock(ctx context.Context, db execer, kind, key, reason string, duration time.Duration) (*models.BlockEntry, error) {
+	now := time.Now()
+	entry := &models.BlockEntry{Kind: kind, Key: key, Reason: reason, CreatedAt: now, ExpiresAt: now.Add(duration)}
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO blocklist (kind, key, reason, created_at, expires_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (kind, key) DO UPDATE SET
+			reason = excluded.reason,
+			created_at = excluded.created_at,
+			expires_at = MAX(expires_at, excluded.expires_at)`,
+		entry.Kind, entry.Key, entry.Reason, entry.CreatedAt, entry.ExpiresAt); err != nil {
+		return nil, fmt.Errorf("failed to save blocklist entry: %w", err)
+	}
+	return entry, nil
+}
+
+// Unblock 将条目移出封禁名单并写入审计记录，条目不存在或已过期时返回 sql.ErrNoRows
+func (fs *FingerprintService) Unblock(ctx context.Context, kind, key, actor string) error {
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var before models.BlockEntry
+	if err := tx.QueryRowContext(ctx, `
+		SELECT kind, key, reason, created_at, expires_at FROM blocklist
+		WHERE kind = ? AND key = ? AND expires_at > ?`, kind, key, time.Now()).
+		Scan(&before.Kind, &before.Key, &before.Reason, &before.CreatedAt, &before.ExpiresAt); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM blocklist WHERE kind = ? AND key = ?", kind, key); err != nil {
+		return err
+	}
+	deleted := models.ReplicationDelete{Kind: kind, Key: key, DeletedAt: time.Now()}
+	if err := fs.saveTombstone(ctx, tx, tombstoneBlock, kind+":"+key, deleted.DeletedAt); err != nil {
+		return err
+	}
+	if err := fs.logReplication(ctx, tx, models.ReplicationUnblock, deleted); err != nil {
+		return err
+	}
+	if err := recordAudit(ctx, tx, actor, "unblock", kind+":"+key, before, nil); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	fs.blocks.remove(kind, key)
+	return nil
+}
+
+// GetBlocklist 按过期时间返回未过期的封禁名单，kind 不为空时只返回该类型的条目
+func (fs *FingerprintService) GetBlocklist(ctx context.Context, kind string) ([]models.BlockEntry, error) {
+	query := "SELECT kind, key, reason, created_at, expires_at FROM blocklist WHERE expires_at > ?"
+	args := []interface{}{time.Now()}
+	if kind != "" {
+		query += " AND kind = ?"
+		args = append(args, kind)
+	}
+	rows, err := fs.db.Read.QueryContext(ctx, query+" ORDER BY expires_at DESC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.BlockEntry{}
+	for rows.Next() {
+		var e models.BlockEntry
+		if err := rows.Scan(&e.Kind, &e.Key, &e.Reason, &e.CreatedAt, &e.ExpiresAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// BlocklistFilter 返回封禁名单内存过滤器的大小、误判率和查询统计，过滤器尚未构建时先构建
+func (fs *FingerprintService) BlocklistFilter(ctx context.Context) (*models.BlocklistFilterStatus, error) {
+	if err := fs.blocks.refresh(ctx, fs.db.DB); err != nil {
+		return nil, err
+	}
+	status := fs.blocks.status()
+	return &status, nil
+}
+
+// purgeBlocklist 删除已过期的封禁名单条目
+func (fs *FingerprintService) purgeBlocklist(ctx context.Context) error {
+	_, err := fs.db.DB.ExecContext(ctx, "DELETE FROM blocklist WHERE expires_at <= ?", time.Now())
+	return err
+}
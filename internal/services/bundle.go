@@ -0,0 +1,395 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// bundleMagic 导出包首行的格式标识，其后是对压缩内容的HMAC签名
+const bundleMagic = "BDBUNDLE1"
+
+// 导入时的冲突处理方式
+const (
+	// BundleConflictNewer 保留时间较新的一方（指纹、分析和标注按 updated_at，封禁按 expires_at）
+	BundleConflictNewer = "newer"
+	// BundleConflictSkip 保留本地记录
+	BundleConflictSkip = "skip"
+	// BundleConflictOverwrite 用导入的记录覆盖本地记录
+	BundleConflictOverwrite = "overwrite"
+)
+
+var (
+	// ErrBundleKeyMissing 未配置导出包的签名密钥
+	ErrBundleKeyMissing = errors.New("export.bundle_key is not set")
+	// ErrBundleSignature 导出包格式不符或签名校验失败
+	ErrBundleSignature = errors.New("bundle signature is invalid")
+)
+
+// bundleTable 导出包中的一张表
+type bundleTable struct {
+	name string
+	// keys 判断记录是否已存在的列
+	keys []string
+	// compare 冲突时按该时间列比较新旧
+	compare string
+	// where 导出条件及其参数，site 非空时 siteWhere 追加站点过滤
+	where     string
+	whereArgs func() []interface{}
+	siteWhere string
+}
+
+// bundleTables 导出包包含的表，按导入顺序排列
+var bundleTables = []bundleTable{
+	{
+		name:      "fingerprints",
+		keys:      []string{"fingerprint_hash"},
+		compare:   "updated_at",
+		where:     "deleted_at IS NULL",
+		siteWhere: "site_id = ?",
+	},
+	{
+		name:      "analysis",
+		keys:      []string{"fingerprint_hash"},
+		compare:   "updated_at",
+		where:     notDeleted("fingerprint_hash"),
+		siteWhere: "fingerprint_hash IN (SELECT fingerprint_hash FROM fingerprints WHERE site_id = ?)",
+	},
+	{
+		name:      "labels",
+		keys:      []string{"fingerprint_hash"},
+		compare:   "updated_at",
+		where:     notDeleted("fingerprint_hash"),
+		siteWhere: "fingerprint_hash IN (SELECT fingerprint_hash FROM fingerprints WHERE site_id = ?)",
+	},
+	{
+		name:    "blocklist",
+		keys:    []string{"kind", "key"},
+		compare: "expires_at",
+		where:   "expires_at > ?",
+		whereArgs: func() []interface{} {
+			return []interface{}{time.Now()}
+		},
+	},
+}
+
+// bundle 导出包内容，每行记录为列名到值的映射，导入时只写入本地存在的列
+type bundle struct {
+	Version   int                                 `json:"version"`
+	CreatedAt time.Time                           `json:"created_at"`
+	SiteID    string                              `json:"site_id,omitempty"`
+	Tables    map[string][]map[string]interface{} `json:"tables"`
+}
+
+// ExportBundle 将未删除的指纹、分析结果、标注和有效的封禁写入签名的导出包，siteID 非空时只导出该站点的指纹
+// 文件首行为格式标识和对其后gzip压缩的JSON内容的HMAC-SHA256签名
+func (fs *FingerprintService) ExportBundle(ctx context.Context, path, siteID string) error {
+	if fs.export.BundleKey == "" {
+		return ErrBundleKeyMissing
+	}
+	b := bundle{Version: 1, CreatedAt: time.Now().UTC(), SiteID: siteID, Tables: make(map[string][]map[string]interface{})}
+	for _, table := range bundleTables {
+		rows, err := fs.exportTable(ctx, table, siteID)
+		if err != nil {
+			return fmt.Errorf("failed to export %s: %w", table.name, err)
+		}
+		b.Tables[table.name] = rows
+	}
+
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	if err := json.NewEncoder(zw).Encode(b); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(out, "%s %s\n", bundleMagic, fs.bundleSignature(body.Bytes())); err == nil {
+		_, err = out.Write(body.Bytes())
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("Exported %d fingerprints, %d analyses and %d blocklist entries to %s",
+		len(b.Tables["fingerprints"]), len(b.Tables["analysis"]), len(b.Tables["blocklist"]), path)
+	return nil
+}
+
+// bundleSignature 计算导出包内容的签名
+func (fs *FingerprintService) bundleSignature(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(fs.export.BundleKey))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// exportTable 读取表中满足导出条件的记录，自增ID不导出
+func (fs *FingerprintService) exportTable(ctx context.Context, table bundleTable, siteID string) ([]map[string]interface{}, error) {
+	query := "SELECT * FROM " + table.name + " WHERE " + table.where
+	var args []interface{}
+	if table.whereArgs != nil {
+		args = table.whereArgs()
+	}
+	if siteID != "" && table.siteWhere != "" {
+		query += " AND " + table.siteWhere
+		args = append(args, siteID)
+	}
+	rows, err := fs.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	records := []map[string]interface{}{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		record := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			if column == "id" {
+				continue
+			}
+			if b, ok := values[i].([]byte); ok {
+				values[i] = string(b)
+			}
+			record[column] = values[i]
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// ImportBundle 校验签名后导入导出包，按 conflict 处理本地已存在的记录，返回各表的处理结果
+// 导入的指纹按本实例的站点密钥重新计算组件哈希和Canvas索引；两侧哈希密钥不同时需再执行 -rehash-site
+func (fs *FingerprintService) ImportBundle(ctx context.Context, path, conflict, actor string) (map[string]models.BundleImportStats, error) {
+	switch conflict {
+	case BundleConflictNewer, BundleConflictSkip, BundleConflictOverwrite:
+	default:
+		return nil, fmt.Errorf("invalid conflict mode %q", conflict)
+	}
+	b, err := fs.readBundle(path)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stats := make(map[string]models.BundleImportStats, len(bundleTables))
+	var imported []string
+	for _, table := range bundleTables {
+		s, hashes, err := importTable(ctx, tx, table, b.Tables[table.name], conflict)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import %s: %w", table.name, err)
+		}
+		stats[table.name] = s
+		if table.name == "fingerprints" {
+			imported = hashes
+		}
+	}
+	if err := recordAudit(ctx, tx, actor, "import_bundle", path, nil, stats); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	fs.blocks.invalidate()
+	fs.queryCache.invalidate()
+
+	for _, hash := range imported {
+		stored, err := fs.loadFingerprints(ctx, "fingerprint_hash = ?", hash)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range stored {
+			if err := fs.saveComponents(ctx, hash, hashComponents(&s.fp, fs.hashSecret(s.fp.SiteID))); err != nil {
+				return nil, err
+			}
+			if err := fs.saveCanvasIndex(ctx, hash, s.fp.CanvasSimHash); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(imported) > 0 {
+		fs.invalidateVectorIndex()
+	}
+	return stats, nil
+}
+
+// readBundle 读取导出包并校验签名
+func (fs *FingerprintService) readBundle(path string) (*bundle, error) {
+	if fs.export.BundleKey == "" {
+		return nil, ErrBundleKeyMissing
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	header, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, ErrBundleSignature
+	}
+	magic, signature, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || magic != bundleMagic {
+		return nil, ErrBundleSignature
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(signature), []byte(fs.bundleSignature(body))) {
+		return nil, ErrBundleSignature
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(zr)
+	// 64位的SimHash等整数不能经过float64
+	decoder.UseNumber()
+	var b bundle
+	if err := decoder.Decode(&b); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	if b.Version != 1 {
+		return nil, fmt.Errorf("unsupported bundle version %d", b.Version)
+	}
+	return &b, nil
+}
+
+// importTable 在事务中导入一张表的记录，返回处理结果和写入的记录的首个键值
+func importTable(ctx context.Context, tx *sql.Tx, table bundleTable, records []map[string]interface{}, conflict string) (models.BundleImportStats, []string, error) {
+	var stats models.BundleImportStats
+	types, err := columnTypes(ctx, tx, table.name)
+	if err != nil {
+		return stats, nil, err
+	}
+	where := strings.Join(table.keys, " = ? AND ") + " = ?"
+
+	var written []string
+	for _, record := range records {
+		keyArgs := make([]interface{}, len(table.keys))
+		for i, key := range table.keys {
+			if keyArgs[i] = record[key]; keyArgs[i] == nil {
+				return stats, nil, fmt.Errorf("record without %s", key)
+			}
+		}
+
+		var local sql.NullTime
+		err := tx.QueryRowContext(ctx,
+			"SELECT "+table.compare+" FROM "+table.name+" WHERE "+where, keyArgs...).Scan(&local)
+		exists := err == nil
+		if err != nil && err != sql.ErrNoRows {
+			return stats, nil, err
+		}
+		if exists {
+			incoming, _ := bundleTime(record[table.compare])
+			if conflict == BundleConflictSkip ||
+				conflict == BundleConflictNewer && local.Valid && !incoming.After(local.Time) {
+				stats.Skipped++
+				continue
+			}
+		}
+
+		var columns, placeholders []string
+		var args []interface{}
+		for column, value := range record {
+			kind, ok := types[column]
+			if !ok || column == "id" {
+				continue
+			}
+			if kind == "DATETIME" {
+				if t, ok := bundleTime(value); ok {
+					value = t
+				}
+			}
+			columns = append(columns, column)
+			placeholders = append(placeholders, "?")
+			args = append(args, value)
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("INSERT OR REPLACE INTO %s (%s) VALUES (%s)",
+			table.name, strings.Join(columns, ", "), strings.Join(placeholders, ", ")), args...); err != nil {
+			return stats, nil, err
+		}
+		if exists {
+			stats.Updated++
+		} else {
+			stats.Inserted++
+		}
+		written = append(written, fmt.Sprint(keyArgs[0]))
+	}
+	return stats, written, nil
+}
+
+// bundleTime 解析导出包中的时间值
+func bundleTime(value interface{}) (time.Time, bool) {
+	s, ok := value.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	return t, err == nil
+}
+
+// columnTypes 返回本地表的列名及其声明类型
+func columnTypes(ctx context.Context, tx *sql.Tx, table string) (map[string]string, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	types := make(map[string]string)
+	for rows.Next() {
+		var (
+			cid        int
+			name, kind string
+			notNull    int
+			defaultVal sql.NullString
+			pk         int
+		)
+		if err := rows.Scan(&cid, &name, &kind, &notNull, &defaultVal, &pk); err != nil {
+			return nil, err
+		}
+		types[name] = strings.ToUpper(kind)
+	}
+	return types, rows.Err()
+}
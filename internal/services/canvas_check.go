@@ -0,0 +1,141 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// canvasClass 指纹声明的浏览器家族、操作系统和GPU类别
+func canvasClass(fp *models.Fingerprint) (browser, os, gpu string) {
+	ua := utils.ParseUserAgent(fp.UserAgent)
+	return ua.Family, ua.OS, fp.GPUFamily
+}
+
+// canvasBaselineHashes 返回基线数据和配置中适用于该浏览器、系统和GPU类别的固定图案已知哈希，没有适用的记录时返回 nil
+func (fs *FingerprintService) canvasBaselineHashes(browser, os, gpu string) map[string]bool {
+	var hashes map[string]bool
+	for _, set := range fs.canvasBaselines() {
+		for _, b := range set {
+			if (b.Browser != "" && !strings.EqualFold(b.Browser, browser)) ||
+				(b.OS != "" && !strings.EqualFold(b.OS, os)) ||
+				(b.GPU != "" && !strings.EqualFold(b.GPU, gpu)) {
+				continue
+			}
+			if hashes == nil {
+				hashes = make(map[string]bool)
+			}
+			for _, h := range b.Hashes {
+				hashes[strings.ToLower(h)] = true
+			}
+		}
+	}
+	return hashes
+}
+
+// checkCanvasDualRender 校验双Canvas渲染结果：固定图案须与声明类别的已知哈希一致，种子图案须随种子变化、
+// 同一种子的结果可复现且没有被重放到其他种子；只校验本次提交，重新分析和规则重放（没有接收时间）不校验
+func (fs *FingerprintService) checkCanvasDualRender(ctx context.Context, fp *models.Fingerprint, req *models.FingerprintRequest) []signal {
+	cfg := fs.canvasCheck
+	if cfg.Weight <= 0 || req == nil || fp.ReceivedAt.IsZero() {
+		return nil
+	}
+	var check models.CanvasCheck
+	if req.CanvasCheck != nil {
+		check = *req.CanvasCheck
+	}
+	check.Static, check.Seeded = strings.ToLower(check.Static), strings.ToLower(check.Seeded)
+
+	var signals []signal
+	browser, os, gpu := canvasClass(fp)
+	if hashes := fs.canvasBaselineHashes(browser, os, gpu); hashes != nil && check.Static != "" && !hashes[check.Static] {
+		signals = append(signals, signal{
+			Code:   models.ReasonCanvasStaticMismatch,
+			Weight: cfg.Weight,
+			Reason: fmt.Sprintf("Static canvas matches no known rendering for %s on %s with %s GPU: the canvas is spoofed or randomized", browser, os, gpuOrUnknown(gpu)),
+		})
+	}
+	if problem := fs.canvasSeedProblem(ctx, fp, check); problem != "" {
+		signals = append(signals, signal{
+			Code:   models.ReasonCanvasSeedInvalid,
+			Weight: cfg.Weight,
+			Reason: "Seeded canvas " + problem,
+		})
+	}
+	return signals
+}
+
+// gpuOrUnknown 返回GPU类别，无法判断时为 unknown
+func gpuOrUnknown(gpu string) string {
+	if gpu == "" {
+		return "unknown"
+	}
+	return gpu
+}
+
+// canvasSeedProblem 返回种子图案的问题，没有问题时返回空字符串
+// 签发执行证明种子时采集脚本总会按种子绘制，缺少种子图案说明跳过了校验（Canvas组件本身缺失时已由 components_missing 计分）；
+// 种子图案与固定图案相同说明没有按种子绘制；同一种子先前得到过其他结果说明每次绘制都被随机化；
+// 相同结果先前出现在其他种子下说明结果是录制后重放的
+func (fs *FingerprintService) canvasSeedProblem(ctx context.Context, fp *models.Fingerprint, check models.CanvasCheck) string {
+	if check.Seed == "" || check.Seeded == "" {
+		if fs.jsProof.SeedTTL > 0 && !componentMissing(fp)["canvas"] {
+			return "is missing: the canvas check was skipped"
+		}
+		return ""
+	}
+	if fs.jsProof.SeedTTL > 0 {
+		if _, err := fs.verifyProofSeed(check.Seed, fp.ReceivedAt); err != nil {
+			return "uses an invalid seed: " + err.Error()
+		}
+	}
+	if check.Seeded == check.Static {
+		return "is identical to the static canvas: it was not drawn with the seed"
+	}
+
+	rows, err := fs.db.DB.QueryContext(ctx,
+		"SELECT seed, seeded_hash FROM canvas_renders WHERE seed = ? OR seeded_hash = ?", check.Seed, check.Seeded)
+	if err != nil {
+		log.Printf("Failed to query canvas renders: %v", err)
+		return ""
+	}
+	var problem string
+	for rows.Next() {
+		var seed, seeded string
+		if err := rows.Scan(&seed, &seeded); err != nil {
+			log.Printf("Failed to scan canvas render: %v", err)
+			break
+		}
+		switch {
+		case seed == check.Seed && seeded != check.Seeded:
+			problem = "differs from an earlier rendering with the same seed: the canvas is randomized"
+		case seed != check.Seed && seeded == check.Seeded:
+			problem = "was already submitted with another seed: the canvas data is replayed"
+		}
+		if problem != "" {
+			break
+		}
+	}
+	rows.Close()
+
+	if _, err := fs.db.DB.ExecContext(ctx,
+		"INSERT OR IGNORE INTO canvas_renders (seed, seeded_hash, first_seen) VALUES (?, ?, ?)",
+		check.Seed, check.Seeded, time.Now()); err != nil {
+		log.Printf("Failed to record canvas render: %v", err)
+	}
+	return problem
+}
+
+// purgeCanvasRenders 删除超过保留期的种子图案记录
+func (fs *FingerprintService) purgeCanvasRenders(ctx context.Context) error {
+	if fs.canvasCheck.Weight <= 0 {
+		return nil
+	}
+	_, err := fs.db.DB.ExecContext(ctx,
+		"DELETE FROM canvas_renders WHERE first_seen < ?", time.Now().Add(-fs.canvasCheck.Retention.Std()))
+	return err
+}
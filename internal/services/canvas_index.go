@@ -0,0 +1,116 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"math/bits"
+	"sort"
+	"strings"
+)
+
+const (
+	// canvasLSHBands SimHash切分的段数，每段16位
+	canvasLSHBands = 4
+	// MaxCanvasDistance 分段索引能保证召回的最大汉明距离（段数-1）
+	MaxCanvasDistance = canvasLSHBands - 1
+	// canvasMatchLimit 相近Canvas查询返回的最大条数
+	canvasMatchLimit = 200
+)
+
+// canvasBands 把SimHash切成 canvasLSHBands 段
+func canvasBands(simhash int64) [canvasLSHBands]int64 {
+	var bands [canvasLSHBands]int64
+	for i := range bands {
+		bands[i] = int64(uint64(simhash) >> (16 * uint(i)) & 0xffff)
+	}
+	return bands
+}
+
+// saveCanvasIndex 替换指纹的Canvas SimHash分段索引，simhash 为0（无法解码）时只删除
+func (fs *FingerprintService) saveCanvasIndex(ctx context.Context, fingerprintHash string, simhash int64) error {
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM canvas_lsh WHERE fingerprint_hash = ?", fingerprintHash); err != nil {
+		return err
+	}
+	if simhash != 0 {
+		for band, value := range canvasBands(simhash) {
+			if _, err := tx.ExecContext(ctx,
+				"INSERT INTO canvas_lsh (band, value, fingerprint_hash) VALUES (?, ?, ?)",
+				band, value, fingerprintHash); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// FindSimilarCanvas 查找Canvas SimHash与指定指纹的汉明距离不超过 maxDistance 的其他指纹
+// 先用分段索引取出至少一段相同的候选，再逐个计算汉明距离，按距离从近到远排序
+func (fs *FingerprintService) FindSimilarCanvas(ctx context.Context, fingerprintHash string, maxDistance int) ([]models.CanvasMatch, error) {
+	if maxDistance < 0 || maxDistance > MaxCanvasDistance {
+		return nil, fmt.Errorf("max distance must be between 0 and %d", MaxCanvasDistance)
+	}
+
+	var simhash int64
+	err := fs.db.DB.QueryRowContext(ctx,
+		"SELECT canvas_simhash FROM fingerprints WHERE fingerprint_hash = ? AND deleted_at IS NULL", fingerprintHash).Scan(&simhash)
+	if err != nil {
+		return nil, err
+	}
+	if simhash == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	bands := canvasBands(simhash)
+	conditions := make([]string, len(bands))
+	args := make([]interface{}, 0, 2*len(bands)+1)
+	for i, value := range bands {
+		conditions[i] = "(l.band = ? AND l.value = ?)"
+		args = append(args, i, value)
+	}
+	args = append(args, fingerprintHash)
+
+	query := fmt.Sprintf(`
+		SELECT DISTINCT f.fingerprint_hash, f.canvas_simhash FROM canvas_lsh l
+		JOIN fingerprints f ON f.fingerprint_hash = l.fingerprint_hash
+		WHERE (%s) AND l.fingerprint_hash != ? AND f.deleted_at IS NULL`, strings.Join(conditions, " OR "))
+	rows, err := fs.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := []models.CanvasMatch{}
+	for rows.Next() {
+		var match models.CanvasMatch
+		var other int64
+		if err := rows.Scan(&match.FingerprintHash, &other); err != nil {
+			return nil, err
+		}
+		match.Distance = bits.OnesCount64(uint64(simhash ^ other))
+		if match.Distance <= maxDistance {
+			matches = append(matches, match)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Distance != matches[j].Distance {
+			return matches[i].Distance < matches[j].Distance
+		}
+		return matches[i].FingerprintHash < matches[j].FingerprintHash
+	})
+	if len(matches) > canvasMatchLimit {
+		matches = matches[:canvasMatchLimit]
+	}
+	return matches, nil
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// maxChallengeTypeLength 验证方式的最大长度
+const maxChallengeTypeLength = 32
+
+// recordChallengeIssued 记录下发的人机验证及触发时的原因代码
+// 同一指纹已有未过期的待完成验证时不重复记录，避免访客在验证页反复提交时重复计数
+func (fs *FingerprintService) recordChallengeIssued(ctx context.Context, siteID, fingerprintHash, ip string, reasonCodes []string) error {
+	now := time.Now()
+	var pending int
+	if err := fs.db.DB.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM challenges WHERE fingerprint_hash = ? AND outcome = '' AND issued_at >= ?`,
+		fingerprintHash, now.Add(-fs.challenges.AbandonAfter.Std())).Scan(&pending); err != nil {
+		return err
+	}
+	if pending > 0 {
+		return nil
+	}
+	var ipRange string
+	if ip != "" {
+		ipRange = utils.IPRange(ip)
+	}
+	_, err := fs.db.DB.ExecContext(ctx, `
+		INSERT INTO challenges (site_id, fingerprint_hash, ip_range, reason_codes, type, outcome, issued_at)
+		VALUES (?, ?, ?, ?, '', '', ?)`,
+		siteID, fingerprintHash, ipRange, utils.StringSliceToJSON(reasonCodes), now)
+	return err
+}
+
+// recordChallengeOutcome 按 challenge 事件的结果完成该指纹最近一次待完成的验证，没有待完成的验证时单独记录一条；
+// 随后统计窗口内的失败次数，同一指纹或IP段达到 max_failures 时临时封禁
+func (fs *FingerprintService) recordChallengeOutcome(ctx context.Context, event *models.Event, ipRange string) error {
+	var outcome string
+	switch event.Outcome {
+	case models.OutcomeSuccess:
+		outcome = models.ChallengePassed
+	case models.OutcomeFailure:
+		outcome = models.ChallengeFailed
+	default:
+		return nil
+	}
+	challengeType := event.Metadata["type"]
+	if len(challengeType) > maxChallengeTypeLength {
+		challengeType = challengeType[:maxChallengeTypeLength]
+	}
+
+	var id int64
+	err := fs.db.DB.QueryRowContext(ctx, `
+		SELECT id FROM challenges WHERE fingerprint_hash = ? AND outcome = '' AND issued_at >= ?
+		ORDER BY id DESC LIMIT 1`,
+		event.FingerprintHash, event.CreatedAt.Add(-fs.challenges.AbandonAfter.Std())).Scan(&id)
+	switch {
+	case err == nil:
+		_, err = fs.db.DB.ExecContext(ctx,
+			"UPDATE challenges SET type = ?, outcome = ?, resolved_at = ? WHERE id = ?",
+			challengeType, outcome, event.CreatedAt, id)
+	case errors.Is(err, sql.ErrNoRows):
+		_, err = fs.db.DB.ExecContext(ctx, `
+			INSERT INTO challenges (site_id, fingerprint_hash, ip_range, reason_codes, type, outcome, issued_at, resolved_at)
+			VALUES (?, ?, ?, '[]', ?, ?, ?, ?)`,
+			event.SiteID, event.FingerprintHash, ipRange, challengeType, outcome, event.CreatedAt, event.CreatedAt)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save challenge outcome: %w", err)
+	}
+	if outcome != models.ChallengeFailed || fs.challenges.MaxFailures <= 0 {
+		return nil
+	}
+
+	since := event.CreatedAt.Add(-fs.challenges.Window.Std())
+	dimensions := []struct{ kind, column, key string }{
+		{models.BlockFingerprint, "fingerprint_hash", event.FingerprintHash},
+		{models.BlockIPRange, "ip_range", ipRange},
+	}
+	for _, d := range dimensions {
+		if d.key == "" {
+			continue
+		}
+		var failures int
+		if err := fs.db.DB.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM challenges WHERE `+d.column+` = ? AND outcome = ? AND resolved_at >= ?`,
+			d.key, models.ChallengeFailed, since).Scan(&failures); err != nil {
+			return err
+		}
+		if failures < fs.challenges.MaxFailures {
+			continue
+		}
+		if _, err := fs.Block(ctx, d.kind, d.key, models.ReasonChallengeFailures, fs.challenges.BlockDuration.Std()); err != nil {
+			return err
+		}
+		log.Printf("Blocked %s %s after %d challenge failures within %s", d.kind, d.key, failures, fs.challenges.Window.Std())
+	}
+	return nil
+}
+
+// challengeQuality 按下发时间和站点统计人机验证的结果，以及按下发时原因代码拆分的通过率
+// 超过 abandon_after 仍未提交结果的验证计为放弃
+func (fs *FingerprintService) challengeQuality(ctx context.Context, report *models.QualityReport) error {
+	query := "SELECT reason_codes, outcome, issued_at FROM challenges WHERE 1 = 1"
+	var args []interface{}
+	if report.From != nil {
+		query += " AND issued_at >= ?"
+		args = append(args, *report.From)
+	}
+	if report.To != nil {
+		query += " AND issued_at < ?"
+		args = append(args, *report.To)
+	}
+	if report.SiteID != "" {
+		query += " AND site_id = ?"
+		args = append(args, report.SiteID)
+	}
+	rows, err := fs.db.Read.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	abandonedBefore := time.Now().Add(-fs.challenges.AbandonAfter.Std())
+	rules := make(map[string]*models.ChallengeStats)
+	for rows.Next() {
+		var reasonCodes, outcome string
+		var issuedAt time.Time
+		if err := rows.Scan(&reasonCodes, &outcome, &issuedAt); err != nil {
+			return err
+		}
+		if outcome == "" {
+			outcome = models.ChallengePending
+			if issuedAt.Before(abandonedBefore) {
+				outcome = models.ChallengeAbandoned
+			}
+		}
+		countChallenge(&report.Challenges, outcome)
+		for _, code := range utils.JSONToStringSlice(reasonCodes) {
+			r := rules[code]
+			if r == nil {
+				r = &models.ChallengeStats{Code: code}
+				rules[code] = r
+			}
+			countChallenge(r, outcome)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	finishChallengeStats(&report.Challenges)
+	report.ChallengeRules = []models.ChallengeStats{}
+	for _, r := range rules {
+		finishChallengeStats(r)
+		report.ChallengeRules = append(report.ChallengeRules, *r)
+	}
+	sort.Slice(report.ChallengeRules, func(i, j int) bool {
+		if report.ChallengeRules[i].Issued != report.ChallengeRules[j].Issued {
+			return report.ChallengeRules[i].Issued > report.ChallengeRules[j].Issued
+		}
+		return report.ChallengeRules[i].Code < report.ChallengeRules[j].Code
+	})
+	return nil
+}
+
+// countChallenge 将一次验证计入统计
+func countChallenge(s *models.ChallengeStats, outcome string) {
+	s.Issued++
+	switch outcome {
+	case models.ChallengePassed:
+		s.Passed++
+	case models.ChallengeFailed:
+		s.Failed++
+	case models.ChallengeAbandoned:
+		s.Abandoned++
+	default:
+		s.Pending++
+	}
+}
+
+// finishChallengeStats 计算通过率
+func finishChallengeStats(s *models.ChallengeStats) {
+	s.PassRate = ratio(s.Passed, s.Passed+s.Failed+s.Abandoned)
+}
+
+// purgeChallenges 删除超过保留期的验证记录
+func (fs *FingerprintService) purgeChallenges(ctx context.Context) error {
+	_, err := fs.db.DB.ExecContext(ctx,
+		"DELETE FROM challenges WHERE issued_at < ?", time.Now().Add(-fs.challenges.Retention.Std()))
+	return err
+}
@@ -0,0 +1,339 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// screenRoundStep 屏幕尺寸归一化的取整步长，吸收缩放和系统栏带来的细微差异
+const screenRoundStep = 10
+
+// componentSince 各组件开始参与哈希的指纹结构版本，未列出的组件从版本1开始
+// 新增组件时必须递增 models.CurrentFingerprintVersion 并在此登记，旧版本记录的哈希保持不变
+var componentSince = map[string]int{
+	"screen_metrics": 2,
+	"font_metrics":   2,
+	"math":           2,
+	"features":       2,
+}
+
+// inferFingerprintVersion 按已采集的信号推断未声明版本的指纹结构版本
+func inferFingerprintVersion(fp *models.Fingerprint) int {
+	if fp.ColorDepth > 0 || fp.FontMetricsHash != "" || fp.MathHash != "" || fp.FeatureCount > 0 {
+		return 2
+	}
+	return 1
+}
+
+// hashComponents 对指纹的各项信号归一化后分别哈希，secret 非空时使用HMAC-SHA256
+// 归一化去掉了易漂移的部分（浏览器版本、屏幕方向、尺寸零头、列表顺序和大小写），
+// 未采集的组件和晚于指纹结构版本的组件不出现在结果中，使新旧客户端的组件可以逐项比较
+func hashComponents(fp *models.Fingerprint, secret string) map[string]string {
+	values := make(map[string]string)
+
+	ua := utils.ParseUserAgent(fp.UserAgent)
+	if ua.Family != utils.BrowserUnknown || ua.OS != "Unknown" {
+		values["browser"] = fmt.Sprintf("%s|%s|%t", ua.Family, ua.OS, ua.Mobile)
+	}
+	if screen := normalizeResolution(fp.ScreenResolution); screen != "" {
+		values["screen"] = screen
+	}
+	if fp.ColorDepth > 0 {
+		values["screen_metrics"] = fmt.Sprintf("%d|%.2f", fp.ColorDepth, fp.DevicePixelRatio)
+	}
+	if fp.Timezone != "" {
+		values["timezone"] = fp.Timezone
+	}
+	if fp.Language != "" {
+		values["language"] = strings.ToLower(fp.Language)
+	}
+	if fp.Platform != "" {
+		values["platform"] = strings.ToLower(fp.Platform)
+	}
+
+	// Canvas优先使用感知哈希，对噪点注入不敏感
+	if fp.CanvasPHash != "" {
+		values["canvas"] = "p:" + fp.CanvasPHash
+	} else if fp.Canvas != "" {
+		values["canvas"] = fp.CanvasHash
+	}
+	if fp.WebGL != "" {
+		values["webgl"] = fp.WebGLHash
+	}
+	// 音频优先使用服务端从原始采样计算的和值，保留与抖动容差相当的精度
+	if fp.AudioNoise >= 0 {
+		values["audio"] = "s:" + strconv.FormatFloat(fp.AudioSum, 'f', 4, 64)
+	} else if fp.Audio != "" {
+		values["audio"] = fp.AudioHash
+	}
+
+	if fonts := normalizeList(utils.JSONToStringSlice(fp.Fonts)); fonts != "" {
+		values["fonts"] = fonts
+	}
+	if plugins := normalizeList(utils.JSONToStringSlice(fp.Plugins)); plugins != "" {
+		values["plugins"] = plugins
+	}
+	if fp.FontMetricsHash != "" {
+		values["font_metrics"] = fp.FontMetricsHash
+	}
+	if fp.HardwareConcurrency > 0 || fp.DeviceMemory > 0 {
+		values["hardware"] = fmt.Sprintf("%d|%g", fp.HardwareConcurrency, fp.DeviceMemory)
+	}
+	if fp.MathHash != "" {
+		values["math"] = fp.MathHash
+	}
+	if fp.FeatureCount > 0 {
+		values["features"] = fmt.Sprintf("%d|%x", fp.FeatureCount, uint64(fp.FeatureBits))
+	}
+
+	version := fp.FingerprintVersion
+	if version <= 0 {
+		version = inferFingerprintVersion(fp)
+	}
+	components := make(map[string]string, len(values))
+	for name, value := range values {
+		if componentSince[name] > version {
+			continue
+		}
+		if secret != "" {
+			components[name] = utils.GenerateKeyedHash(secret, name+"|"+value)
+		} else {
+			components[name] = utils.GenerateListHash([]string{name, value})
+		}
+	}
+	return components
+}
+
+// combineComponents 按组件名排序后组合各组件哈希，得到确定性的整体哈希
+func combineComponents(components map[string]string) string {
+	data := make(map[string]interface{}, len(components))
+	for name, hash := range components {
+		data[name] = hash
+	}
+	return utils.GenerateFingerprintHash(data)
+}
+
+// normalizeResolution 将 "WxH" 归一化为长边x短边并按步长取整，消除屏幕旋转和零头差异
+func normalizeResolution(resolution string) string {
+	parts := strings.Split(resolution, "x")
+	if len(parts) != 2 {
+		return ""
+	}
+	w, errW := strconv.Atoi(strings.TrimSpace(parts[0]))
+	h, errH := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if errW != nil || errH != nil || w <= 0 || h <= 0 {
+		return ""
+	}
+	if w < h {
+		w, h = h, w
+	}
+	round := func(v int) int {
+		return int(math.Round(float64(v)/screenRoundStep)) * screenRoundStep
+	}
+	return fmt.Sprintf("%dx%d", round(w), round(h))
+}
+
+// normalizeList 列表去重、转小写并排序后拼接，消除枚举顺序差异
+func normalizeList(items []string) string {
+	seen := make(map[string]bool, len(items))
+	var out []string
+	for _, item := range items {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" || seen[item] {
+			continue
+		}
+		seen[item] = true
+		out = append(out, item)
+	}
+	sort.Strings(out)
+	return strings.Join(out, ",")
+}
+
+// saveComponents 替换指纹的组件哈希
+func (fs *FingerprintService) saveComponents(ctx context.Context, fingerprintHash string, components map[string]string) error {
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceComponents(ctx, tx, "", fingerprintHash, components); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// replaceComponents 在事务中替换指纹的组件哈希
+// oldHash 非空时同时删除该哈希下的组件，用于指纹哈希变更
+func replaceComponents(ctx context.Context, tx *sql.Tx, oldHash, fingerprintHash string, components map[string]string) error {
+	for _, hash := range []string{oldHash, fingerprintHash} {
+		if hash == "" {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM fingerprint_components WHERE fingerprint_hash = ?", hash); err != nil {
+			return err
+		}
+	}
+	for name, hash := range components {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO fingerprint_components (fingerprint_hash, component, hash) VALUES (?, ?, ?)",
+			fingerprintHash, name, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const (
+	// similarLimit 相似指纹查询返回的最大条数
+	similarLimit = 50
+	// similarCandidatesPerValue 每个组件取值最多取多少个共享该取值的指纹作为候选；
+	// 常见取值（如时区、语言）对应大量指纹，逐项限制候选数可使查询量与指纹总数无关，
+	// 真正相似的指纹还共享Canvas、WebGL等罕见取值，不会因此遗漏
+	similarCandidatesPerValue = 500
+	// similarBatchSize 读取候选指纹组件时每次查询的指纹数
+	similarBatchSize = 500
+)
+
+// FindSimilar 查找与指定指纹最多有 maxDiff 个组件不同的其他指纹
+// 候选为至少共享一个组件取值的指纹（按 (component, hash) 索引查找，每个取值最多 similarCandidatesPerValue 个）；
+// 两侧都采集到的组件才参与比较，按共享组件数从多到少排序
+func (fs *FingerprintService) FindSimilar(ctx context.Context, fingerprintHash string, maxDiff int) ([]models.SimilarFingerprint, error) {
+	self, err := fs.liveComponentHashes(ctx, fingerprintHash)
+	if err != nil {
+		return nil, err
+	}
+	if len(self) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	candidates := make(map[string]bool)
+	for component, hash := range self {
+		rows, err := fs.db.DB.QueryContext(ctx,
+			"SELECT fingerprint_hash FROM fingerprint_components WHERE component = ? AND hash = ? AND fingerprint_hash != ? LIMIT ?",
+			component, hash, fingerprintHash, similarCandidatesPerValue)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var candidate string
+			if err := rows.Scan(&candidate); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			candidates[candidate] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	hashes := make([]string, 0, len(candidates))
+	for candidate := range candidates {
+		hashes = append(hashes, candidate)
+	}
+	sort.Strings(hashes)
+
+	similar := []models.SimilarFingerprint{}
+	for start := 0; start < len(hashes); start += similarBatchSize {
+		batch := hashes[start:min(start+similarBatchSize, len(hashes))]
+		found, err := fs.compareComponents(ctx, self, batch, maxDiff)
+		if err != nil {
+			return nil, err
+		}
+		similar = append(similar, found...)
+	}
+	sort.Slice(similar, func(i, j int) bool {
+		a, b := similar[i], similar[j]
+		if a.SharedComponents != b.SharedComponents {
+			return a.SharedComponents > b.SharedComponents
+		}
+		if da, db := a.TotalComponents-a.SharedComponents, b.TotalComponents-b.SharedComponents; da != db {
+			return da < db
+		}
+		return a.FingerprintHash < b.FingerprintHash
+	})
+	if len(similar) > similarLimit {
+		similar = similar[:similarLimit]
+	}
+	return similar, nil
+}
+
+// liveComponentHashes 返回未删除指纹的各组件哈希，指纹已软删除时返回空
+func (fs *FingerprintService) liveComponentHashes(ctx context.Context, fingerprintHash string) (map[string]string, error) {
+	rows, err := fs.db.DB.QueryContext(ctx,
+		"SELECT component, hash FROM fingerprint_components WHERE fingerprint_hash = ? AND "+notDeleted("fingerprint_hash"),
+		fingerprintHash)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	components := make(map[string]string)
+	for rows.Next() {
+		var component, hash string
+		if err := rows.Scan(&component, &hash); err != nil {
+			return nil, err
+		}
+		components[component] = hash
+	}
+	return components, rows.Err()
+}
+
+// compareComponents 将一批候选指纹的组件与 self 比较，返回共享至少一个组件且不同组件不超过 maxDiff 个的未删除指纹
+func (fs *FingerprintService) compareComponents(ctx context.Context, self map[string]string, candidates []string, maxDiff int) ([]models.SimilarFingerprint, error) {
+	placeholders := make([]string, len(candidates))
+	args := make([]any, len(candidates))
+	for i, candidate := range candidates {
+		placeholders[i] = "?"
+		args[i] = candidate
+	}
+	rows, err := fs.db.DB.QueryContext(ctx,
+		"SELECT fingerprint_hash, component, hash FROM fingerprint_components WHERE fingerprint_hash IN ("+
+			strings.Join(placeholders, ", ")+") AND "+notDeleted("fingerprint_hash"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byHash := make(map[string]*models.SimilarFingerprint)
+	for rows.Next() {
+		var candidate, component, hash string
+		if err := rows.Scan(&candidate, &component, &hash); err != nil {
+			return nil, err
+		}
+		own, ok := self[component]
+		if !ok {
+			continue
+		}
+		s := byHash[candidate]
+		if s == nil {
+			s = &models.SimilarFingerprint{FingerprintHash: candidate}
+			byHash[candidate] = s
+		}
+		s.TotalComponents++
+		if own == hash {
+			s.SharedComponents++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var similar []models.SimilarFingerprint
+	for _, s := range byHash {
+		if s.SharedComponents == 0 || s.TotalComponents-s.SharedComponents > maxDiff {
+			continue
+		}
+		s.Similarity = float64(s.SharedComponents) / float64(s.TotalComponents)
+		similar = append(similar, *s)
+	}
+	return similar, nil
+}
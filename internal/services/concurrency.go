@@ -0,0 +1,134 @@
+package services
+
+import (
+	"browser-detection/internal/alerting"
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// 会话并发检测的身份类型
+const (
+	sessionFingerprint = "fingerprint"
+	sessionVisitor     = "visitor"
+)
+
+// sessionIdentity 参与会话并发检测的一个身份
+type sessionIdentity struct{ kind, key string }
+
+// sessionIdentities 返回指纹哈希和访客Cookie对应的身份，为空的不参与
+func sessionIdentities(fingerprintHash, visitorID string) []sessionIdentity {
+	var ids []sessionIdentity
+	if fingerprintHash != "" {
+		ids = append(ids, sessionIdentity{sessionFingerprint, fingerprintHash})
+	}
+	if visitorID != "" {
+		ids = append(ids, sessionIdentity{sessionVisitor, visitorID})
+	}
+	return ids
+}
+
+// recordSessionActivity 记录指纹和访客Cookie本次出现的网段和国家
+func (fs *FingerprintService) recordSessionActivity(ctx context.Context, fingerprintHash, visitorID, ip, country string) error {
+	if fs.concurrency.Window <= 0 {
+		return nil
+	}
+	subnet, ok := utils.IPSubnet(ip)
+	if !ok {
+		return nil
+	}
+	now := time.Now()
+	for _, id := range sessionIdentities(fingerprintHash, visitorID) {
+		if _, err := fs.db.DB.ExecContext(ctx, `
+			INSERT INTO session_activity (kind, key, subnet, country, last_seen) VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT (kind, key, subnet) DO UPDATE SET country = excluded.country, last_seen = excluded.last_seen`,
+			id.kind, id.key, subnet.String(), country, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// concurrentSessions 统计身份在窗口内出现的网段和国家，达到阈值时给出信号并按告警规则告警
+func (fs *FingerprintService) concurrentSessions(ctx context.Context, siteID, fingerprintHash, visitorID string) []signal {
+	cfg := fs.concurrency
+	if cfg.Window <= 0 {
+		return nil
+	}
+	since := time.Now().Add(-cfg.Window.Std())
+	for _, id := range sessionIdentities(fingerprintHash, visitorID) {
+		var subnets, countries int
+		if err := fs.db.DB.QueryRowContext(ctx, `
+			SELECT COUNT(*), COUNT(DISTINCT NULLIF(country, '')) FROM session_activity
+			WHERE kind = ? AND key = ? AND last_seen >= ?`,
+			id.kind, id.key, since).Scan(&subnets, &countries); err != nil {
+			log.Printf("Failed to query session activity: %v", err)
+			return nil
+		}
+		if subnets < cfg.MinSubnets && countries < cfg.MinCountries {
+			continue
+		}
+		message := fmt.Sprintf("%s %s active from %d subnets in %d countries within %s",
+			id.kind, id.key, subnets, countries, cfg.Window.Std())
+		if cfg.Alert {
+			fs.alertConcurrentSession(ctx, siteID, id, subnets, countries, message)
+		}
+		return []signal{{
+			Code:   models.ReasonConcurrentSessions,
+			Weight: cfg.Weight,
+			Reason: "Concurrent sessions: " + message,
+		}}
+	}
+	return nil
+}
+
+// alertConcurrentSession 发送会话并发告警，同一身份在窗口内只告警一次
+func (fs *FingerprintService) alertConcurrentSession(ctx context.Context, siteID string, id sessionIdentity, subnets, countries int, message string) {
+	now := time.Now()
+	result, err := fs.db.DB.ExecContext(ctx, `
+		INSERT INTO session_alerts (kind, key, alerted_at) VALUES (?, ?, ?)
+		ON CONFLICT (kind, key) DO UPDATE SET alerted_at = excluded.alerted_at WHERE alerted_at < ?`,
+		id.kind, id.key, now, now.Add(-fs.concurrency.Window.Std()))
+	if err != nil {
+		log.Printf("Failed to record session alert: %v", err)
+		return
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return
+	}
+	if err := fs.alerts.Notify(ctx, alerting.Alert{
+		Kind:    models.ReasonConcurrentSessions,
+		SiteID:  siteID,
+		Message: fmt.Sprintf("concurrent_sessions for site %q: %s", siteID, message),
+		Details: map[string]interface{}{
+			"kind":      id.kind,
+			"key":       id.key,
+			"subnets":   subnets,
+			"countries": countries,
+		},
+		Time: now,
+	}); err != nil {
+		log.Printf("Failed to send concurrent sessions alert: %v", err)
+	}
+}
+
+// checkConcurrentSessions 指纹或访客Cookie同时从多个网段或国家活动时给出信号
+func (fs *FingerprintService) checkConcurrentSessions(ctx context.Context, fp *models.Fingerprint) []signal {
+	return fs.concurrentSessions(ctx, fp.SiteID, fp.FingerprintHash, fp.VisitorID)
+}
+
+// purgeSessionActivity 删除超出窗口的会话活动和告警记录
+func (fs *FingerprintService) purgeSessionActivity(ctx context.Context) error {
+	if fs.concurrency.Window <= 0 {
+		return nil
+	}
+	cutoff := time.Now().Add(-fs.concurrency.Window.Std())
+	if _, err := fs.db.DB.ExecContext(ctx, "DELETE FROM session_activity WHERE last_seen < ?", cutoff); err != nil {
+		return err
+	}
+	_, err := fs.db.DB.ExecContext(ctx, "DELETE FROM session_alerts WHERE alerted_at < ?", cutoff)
+	return err
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// minMismatchComponents 计算Cookie与指纹不一致时至少需要比较的组件数
+const minMismatchComponents = 4
+
+// recordVisitorFingerprint 记录访客Cookie与指纹的关联
+func (fs *FingerprintService) recordVisitorFingerprint(ctx context.Context, fp *models.Fingerprint) error {
+	if fp.VisitorID == "" {
+		return nil
+	}
+	now := time.Now()
+	_, err := fs.db.DB.ExecContext(ctx, `
+		INSERT INTO visitor_fingerprints (visitor_id, fingerprint_hash, first_seen, last_seen) VALUES (?, ?, ?, ?)
+		ON CONFLICT (visitor_id, fingerprint_hash) DO UPDATE SET
+			last_seen = excluded.last_seen,
+			submissions = submissions + 1`,
+		fp.VisitorID, fp.FingerprintHash, now, now)
+	return err
+}
+
+// checkCookieMismatch 同一访客Cookie上一次记录的组件与本次相比大部分都变了
+// 浏览器升级只改变少数组件，大部分组件同时改变说明Cookie被复制到了其他设备，
+// 或反检测浏览器保留Cookie的同时换了整套设备配置
+func (fs *FingerprintService) checkCookieMismatch(ctx context.Context, fp *models.Fingerprint) []signal {
+	if fp.VisitorID == "" || fs.cookies.MismatchRatio <= 0 {
+		return nil
+	}
+	latest, err := fs.latestComponentHashes(ctx, fp.VisitorID)
+	if err != nil {
+		log.Printf("Failed to query component history: %v", err)
+		return nil
+	}
+	current, err := fs.componentHashes(ctx, fp.FingerprintHash)
+	if err != nil {
+		log.Printf("Failed to query fingerprint components: %v", err)
+		return nil
+	}
+
+	compared, changed := 0, 0
+	for name, hash := range current {
+		prev, ok := latest[name]
+		if !ok {
+			continue
+		}
+		compared++
+		if prev != hash {
+			changed++
+		}
+	}
+	if compared < minMismatchComponents || float64(changed)/float64(compared) < fs.cookies.MismatchRatio {
+		return nil
+	}
+	return []signal{{
+		Code:   models.ReasonCookieMismatch,
+		Weight: 0.3,
+		Reason: fmt.Sprintf("Visitor cookie seen with a different device (%d of %d components changed)", changed, compared),
+	}}
+}
+
+// checkCookieCycling 同一指纹在窗口内使用的访客Cookie数超过配置值
+// 不保存Cookie的爬虫每次访问都会拿到新的Cookie
+func (fs *FingerprintService) checkCookieCycling(ctx context.Context, fp *models.Fingerprint) []signal {
+	if fs.cookies.MaxCookiesPerFingerprint <= 0 {
+		return nil
+	}
+	var cookies int
+	if err := fs.db.DB.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM visitor_fingerprints WHERE fingerprint_hash = ? AND last_seen >= ?",
+		fp.FingerprintHash, time.Now().Add(-fs.cookies.CyclingWindow.Std())).Scan(&cookies); err != nil {
+		log.Printf("Failed to count visitor cookies: %v", err)
+		return nil
+	}
+	if cookies <= fs.cookies.MaxCookiesPerFingerprint {
+		return nil
+	}
+	return []signal{{
+		Code:   models.ReasonCookieCycling,
+		Weight: 0.4,
+		Reason: fmt.Sprintf("Fingerprint used %d visitor cookies within %s", cookies, fs.cookies.CyclingWindow.Std()),
+	}}
+}
+
+// GetVisitorFingerprints 按最后出现时间倒序返回访客Cookie关联过的指纹，访客不存在时返回 sql.ErrNoRows
+func (fs *FingerprintService) GetVisitorFingerprints(ctx context.Context, visitorID string) ([]models.VisitorFingerprint, error) {
+	rows, err := fs.db.Read.QueryContext(ctx, `
+		SELECT fingerprint_hash, first_seen, last_seen, submissions FROM visitor_fingerprints
+		WHERE visitor_id = ? ORDER BY last_seen DESC`, visitorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fingerprints []models.VisitorFingerprint
+	for rows.Next() {
+		var v models.VisitorFingerprint
+		if err := rows.Scan(&v.FingerprintHash, &v.FirstSeen, &v.LastSeen, &v.Submissions); err != nil {
+			return nil, err
+		}
+		fingerprints = append(fingerprints, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(fingerprints) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return fingerprints, nil
+}
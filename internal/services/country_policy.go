@@ -0,0 +1,101 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// validateCountryPolicy 校验并规范化站点的国家策略，国家代码统一为大写
+func validateCountryPolicy(p *models.CountryPolicy) []models.FieldError {
+	var errs []models.FieldError
+	switch p.Mode {
+	case models.CountryModeServed, models.CountryModeListed:
+	default:
+		errs = append(errs, models.FieldError{Field: "country_policy.mode", Constraint: "one of served, listed", Got: p.Mode})
+	}
+	switch p.Action {
+	case models.ActionChallenge, models.ActionDeny:
+	default:
+		errs = append(errs, models.FieldError{Field: "country_policy.action", Constraint: "one of challenge, deny", Got: p.Action})
+	}
+	if len(p.Countries) == 0 {
+		errs = append(errs, models.FieldError{Field: "country_policy.countries", Constraint: "non-empty"})
+	}
+	for i, code := range p.Countries {
+		normalized := utils.NormalizeCountry(code)
+		if normalized == "" {
+			errs = append(errs, models.FieldError{Field: fmt.Sprintf("country_policy.countries[%d]", i), Constraint: "ISO 3166-1 alpha-2 code", Got: code})
+			continue
+		}
+		p.Countries[i] = normalized
+	}
+	for i, entry := range p.Allowlist {
+		// 不是IP或CIDR的条目按指纹哈希匹配
+		entry = strings.TrimSpace(entry)
+		p.Allowlist[i] = entry
+		invalid := entry == "" || strings.ContainsAny(entry, " \t")
+		if strings.Contains(entry, "/") {
+			_, err := netip.ParsePrefix(entry)
+			invalid = err != nil
+		}
+		if invalid {
+			errs = append(errs, models.FieldError{Field: fmt.Sprintf("country_policy.allowlist[%d]", i), Constraint: "IP, CIDR or fingerprint hash", Got: entry})
+		}
+	}
+	return errs
+}
+
+// countryAction 按站点的国家策略返回对访客的处理，未命中或在放行名单中时返回空
+func countryAction(p *models.CountryPolicy, country, ip, fingerprintHash string) string {
+	if p == nil {
+		return ""
+	}
+	if country == "" {
+		if !p.IncludeUnknown {
+			return ""
+		}
+	} else {
+		listed := false
+		for _, code := range p.Countries {
+			if code == country {
+				listed = true
+				break
+			}
+		}
+		if listed == (p.Mode == models.CountryModeServed) {
+			return ""
+		}
+	}
+	if countryAllowlisted(p.Allowlist, ip, fingerprintHash) {
+		return ""
+	}
+	return p.Action
+}
+
+// countryAllowlisted 判断IP或指纹哈希是否在国家策略的放行名单中
+func countryAllowlisted(allowlist []string, ip, fingerprintHash string) bool {
+	addr, err := netip.ParseAddr(ip)
+	hasAddr := err == nil
+	if hasAddr {
+		addr = addr.Unmap()
+	}
+	for _, entry := range allowlist {
+		if fingerprintHash != "" && strings.EqualFold(entry, fingerprintHash) {
+			return true
+		}
+		if !hasAddr {
+			continue
+		}
+		if a, err := netip.ParseAddr(entry); err == nil {
+			if a.Unmap() == addr {
+				return true
+			}
+		} else if prefix, err := netip.ParsePrefix(entry); err == nil && prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
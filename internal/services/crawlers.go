@@ -0,0 +1,303 @@
+package services
+
+import (
+	"browser-detection/internal/crawlers"
+	"browser-detection/internal/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// crawlerDay 爬虫流量统计的粒度
+const crawlerDay = 24 * time.Hour
+
+// LoadCrawlers 启动时加载爬虫特征库文件，未配置路径或文件不可用时使用内置的特征库
+func (fs *FingerprintService) LoadCrawlers() {
+	if fs.crawlerConfig.Path == "" {
+		return
+	}
+	t, err := crawlers.Load(fs.crawlerConfig.Path)
+	if err != nil {
+		log.Printf("Failed to load crawlers from %s, using embedded copy: %v", fs.crawlerConfig.Path, err)
+		fs.crawlersError = err.Error()
+		return
+	}
+	fs.crawlerTable = t
+	log.Printf("Loaded crawlers %s from %s: %d signatures", t.Version, t.Source, len(t.Crawlers))
+}
+
+// Crawlers 返回当前使用的爬虫特征库
+func (fs *FingerprintService) Crawlers() models.CrawlersStatus {
+	t := fs.crawlerTable
+	status := models.CrawlersStatus{
+		Source:   t.Source,
+		Version:  t.Version,
+		Crawlers: make([]models.Crawler, len(t.Crawlers)),
+		Error:    fs.crawlersError,
+	}
+	for i, s := range t.Crawlers {
+		status.Crawlers[i] = s.Crawler
+	}
+	return status
+}
+
+// validateCrawlerPolicy 校验站点的爬虫策略，爬虫名称须在当前特征库中
+func (fs *FingerprintService) validateCrawlerPolicy(p *models.CrawlerPolicy) []models.FieldError {
+	var errs []models.FieldError
+	check := func(field string, keys map[string]string, known func(string) bool, constraint string) {
+		names := make([]string, 0, len(keys))
+		for name := range keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if !known(name) {
+				errs = append(errs, models.FieldError{Field: field + "." + name, Constraint: constraint})
+				continue
+			}
+			switch action := keys[name]; action {
+			case models.ActionAllow, models.ActionChallenge, models.ActionDeny:
+			default:
+				errs = append(errs, models.FieldError{Field: field + "." + name, Constraint: "one of allow, challenge, deny", Got: action})
+			}
+		}
+	}
+	check("crawler_policy.categories", p.Categories, crawlers.ValidCategory, "one of "+strings.Join(models.CrawlerCategories, ", "))
+	check("crawler_policy.purposes", p.Purposes, crawlers.ValidPurpose, "one of "+strings.Join(models.CrawlerPurposes, ", "))
+	check("crawler_policy.crawlers", p.Crawlers, func(name string) bool {
+		_, ok := fs.crawlerTable.Lookup(name)
+		return ok
+	}, "known crawler name")
+	return errs
+}
+
+// crawlerAction 按站点的爬虫策略返回对爬虫的处理，未识别为爬虫或策略未覆盖时返回空
+func crawlerAction(p *models.CrawlerPolicy, c *models.Crawler) string {
+	if p == nil || c == nil {
+		return ""
+	}
+	if action, ok := p.Crawlers[c.Name]; ok {
+		return action
+	}
+	if c.Purpose != "" {
+		if action, ok := p.Purposes[c.Purpose]; ok {
+			return action
+		}
+	}
+	return p.Categories[c.Category]
+}
+
+// applyCrawlerPolicy 按站点爬虫策略收紧处理建议：deny 覆盖其他结果，challenge 只替换 allow，allow 不放宽
+func applyCrawlerPolicy(decision *models.EventDecision, action string) {
+	decision.CrawlerPolicy = action
+	switch {
+	case action == models.ActionDeny:
+		decision.Action = models.ActionDeny
+	case action == models.ActionChallenge && decision.Action == models.ActionAllow:
+		decision.Action = models.ActionChallenge
+	}
+}
+
+// checkCrawler 检查User Agent是否属于特征库中的已知爬虫，AI爬虫与其他爬虫分别计分
+func (fs *FingerprintService) checkCrawler(fp *models.Fingerprint) []signal {
+	c := fs.crawlerTable.Match(fp.UserAgent)
+	if c == nil {
+		return nil
+	}
+	if c.Category == models.CrawlerAI {
+		if fs.crawlerConfig.AIWeight <= 0 {
+			return nil
+		}
+		return []signal{{
+			Code:   models.ReasonAICrawler,
+			Weight: fs.crawlerConfig.AIWeight,
+			Reason: fmt.Sprintf("User Agent identifies AI crawler %s (%s, %s)", c.Name, c.Operator, c.Purpose),
+		}}
+	}
+	if fs.crawlerConfig.Weight <= 0 {
+		return nil
+	}
+	return []signal{{
+		Code:   models.ReasonKnownCrawler,
+		Weight: fs.crawlerConfig.Weight,
+		Reason: fmt.Sprintf("User Agent identifies %s crawler %s (%s)", c.Category, c.Name, c.Operator),
+	}}
+}
+
+// fingerprintCrawler 按指纹保存的User Agent识别爬虫，指纹不存在或未识别时返回 nil
+func (fs *FingerprintService) fingerprintCrawler(ctx context.Context, fingerprintHash string) (*models.Crawler, error) {
+	var userAgent string
+	err := fs.db.DB.QueryRowContext(ctx,
+		"SELECT user_agent FROM fingerprints WHERE fingerprint_hash = ? AND deleted_at IS NULL", fingerprintHash).Scan(&userAgent)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fs.crawlerTable.Match(userAgent), nil
+}
+
+// recordCrawler 累计站点当天该爬虫的请求数及按爬虫策略要求验证、拒绝的数量
+func (fs *FingerprintService) recordCrawler(ctx context.Context, siteID string, c *models.Crawler, action string) error {
+	if c == nil {
+		return nil
+	}
+	var challenged, denied int
+	switch action {
+	case models.ActionChallenge:
+		challenged = 1
+	case models.ActionDeny:
+		denied = 1
+	}
+	_, err := fs.db.DB.ExecContext(ctx, `
+		INSERT INTO crawler_stats (site_id, day, crawler, operator, category, purpose, requests, challenged, denied)
+		VALUES (?, ?, ?, ?, ?, ?, 1, ?, ?)
+		ON CONFLICT (site_id, day, crawler) DO UPDATE SET
+			requests = requests + 1,
+			challenged = challenged + excluded.challenged,
+			denied = denied + excluded.denied,
+			operator = excluded.operator,
+			category = excluded.category,
+			purpose = excluded.purpose`,
+		siteID, time.Now().UTC().Truncate(crawlerDay).Unix(), c.Name, c.Operator, c.Category, c.Purpose, challenged, denied)
+	return err
+}
+
+// CheckCrawler 按User Agent识别爬虫并按站点的爬虫策略给出处理，识别出的爬虫计入流量统计，
+// path 不为空时审计该路径是否被站点的 robots.txt 禁止。
+// 供不执行采集脚本的请求在服务端或边缘节点调用，AI爬虫通常不会运行JS
+func (fs *FingerprintService) CheckCrawler(ctx context.Context, siteID, userAgent, path string) (*models.CrawlerCheck, error) {
+	check := &models.CrawlerCheck{
+		Crawler: fs.crawlerTable.Match(userAgent),
+		Action:  models.ActionAllow,
+	}
+	if check.Crawler == nil {
+		return check, nil
+	}
+	action := crawlerAction(fs.siteOverride(siteID).CrawlerPolicy, check.Crawler)
+	if action != "" {
+		check.Action = action
+	}
+	if err := fs.recordCrawler(ctx, siteID, check.Crawler, action); err != nil {
+		return nil, err
+	}
+	disallowed, rule, err := fs.auditRobots(ctx, siteID, check.Crawler, path)
+	if err != nil {
+		return nil, err
+	}
+	check.RobotsDisallowed, check.RobotsRule = disallowed, rule
+	return check, nil
+}
+
+// CrawlerStats 返回 [from, to] 内已识别爬虫的请求数，按类别、AI爬虫用途、爬虫和天（UTC）汇总，
+// from、to 为空时取最近30天，siteID 为空时合并所有站点，category 不为空时爬虫列表只包含该类别；结果按 query_cache 缓存
+func (fs *FingerprintService) CrawlerStats(ctx context.Context, from, to *time.Time, siteID, category string) (*models.CrawlerStats, error) {
+	return cachedQuery(ctx, fs, queryKey("crawlers", from, to, siteID, category), func(ctx context.Context) (*models.CrawlerStats, error) {
+		return fs.crawlerStats(ctx, from, to, siteID, category)
+	})
+}
+
+// crawlerStats 查询已知爬虫统计，不使用缓存
+func (fs *FingerprintService) crawlerStats(ctx context.Context, from, to *time.Time, siteID, category string) (*models.CrawlerStats, error) {
+	end := time.Now()
+	if to != nil {
+		end = *to
+	}
+	start := end.Add(-30 * crawlerDay)
+	if from != nil {
+		start = *from
+	}
+	stats := &models.CrawlerStats{
+		SiteID:     siteID,
+		From:       start,
+		To:         end,
+		Categories: []models.CrawlerTrafficStats{},
+		Purposes:   []models.CrawlerTrafficStats{},
+		Crawlers:   []models.CrawlerTrafficStats{},
+		Series:     []models.CrawlerDayStats{},
+	}
+
+	query := `
+		SELECT day, crawler, operator, category, purpose, requests, challenged, denied
+		FROM crawler_stats WHERE day >= ? AND day <= ?`
+	args := []interface{}{start.UTC().Truncate(crawlerDay).Unix(), end.UTC().Truncate(crawlerDay).Unix()}
+	if siteID != "" {
+		query += " AND site_id = ?"
+		args = append(args, siteID)
+	}
+	rows, err := fs.db.Read.QueryContext(ctx, query+" ORDER BY day", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := make(map[string]*models.CrawlerTrafficStats)
+	purposes := make(map[string]*models.CrawlerTrafficStats)
+	byName := make(map[string]*models.CrawlerTrafficStats)
+	add := func(m map[string]*models.CrawlerTrafficStats, key string, t models.CrawlerTraffic, init models.CrawlerTrafficStats) {
+		s := m[key]
+		if s == nil {
+			s = &init
+			m[key] = s
+		}
+		s.Requests += t.Requests
+		s.Challenged += t.Challenged
+		s.Denied += t.Denied
+	}
+	var ai int
+	for rows.Next() {
+		var day int64
+		var c models.Crawler
+		var t models.CrawlerTraffic
+		if err := rows.Scan(&day, &c.Name, &c.Operator, &c.Category, &c.Purpose, &t.Requests, &t.Challenged, &t.Denied); err != nil {
+			return nil, err
+		}
+		stats.Requests += t.Requests
+		stats.Challenged += t.Challenged
+		stats.Denied += t.Denied
+		add(categories, c.Category, t, models.CrawlerTrafficStats{Name: c.Category})
+		if c.Category == models.CrawlerAI {
+			ai += t.Requests
+			if c.Purpose != "" {
+				add(purposes, c.Purpose, t, models.CrawlerTrafficStats{Name: c.Purpose})
+			}
+		}
+		if category == "" || c.Category == category {
+			add(byName, c.Name, t, models.CrawlerTrafficStats{Name: c.Name, Operator: c.Operator, Category: c.Category, Purpose: c.Purpose})
+		}
+
+		start := time.Unix(day, 0).UTC()
+		if n := len(stats.Series); n == 0 || !stats.Series[n-1].Day.Equal(start) {
+			stats.Series = append(stats.Series, models.CrawlerDayStats{Day: start, Categories: map[string]int{}})
+		}
+		stats.Series[len(stats.Series)-1].Categories[c.Category] += t.Requests
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	stats.AIShare = ratio(ai, stats.Requests)
+	flatten := func(m map[string]*models.CrawlerTrafficStats, total int) []models.CrawlerTrafficStats {
+		list := make([]models.CrawlerTrafficStats, 0, len(m))
+		for _, s := range m {
+			s.Share = ratio(s.Requests, total)
+			list = append(list, *s)
+		}
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Requests != list[j].Requests {
+				return list[i].Requests > list[j].Requests
+			}
+			return list[i].Name < list[j].Name
+		})
+		return list
+	}
+	stats.Categories = flatten(categories, stats.Requests)
+	stats.Purposes = flatten(purposes, stats.Requests)
+	stats.Crawlers = flatten(byName, stats.Requests)
+	return stats, nil
+}
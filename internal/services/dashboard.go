@@ -0,0 +1,298 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// dashboardRecent 看板首页最近检测的条数
+	dashboardRecent = 20
+	// dashboardTop 看板排行图的条数
+	dashboardTop = 10
+	// dashboardAlerts 看板首页每类告警的条数
+	dashboardAlerts = 5
+	// liveMinutes 实时计数保留的分钟数
+	liveMinutes = 60
+)
+
+// liveKey 站点某一分钟实时计数的键，kind 为 submissions 或 bots
+func liveKey(siteID string, minute int64, kind string) string {
+	return "live:" + siteID + ":" + strconv.FormatInt(minute, 10) + ":" + kind
+}
+
+// recordLive 累计站点当前分钟的提交量和爬虫数，供看板的实时计数使用
+func (fs *FingerprintService) recordLive(ctx context.Context, siteID string, isBot bool) error {
+	minute := time.Now().Unix() / 60
+	ttl := (liveMinutes + 1) * time.Minute
+	if _, err := fs.counters.IncrBy(ctx, liveKey(siteID, minute, "submissions"), 1, ttl); err != nil {
+		return err
+	}
+	if isBot {
+		if _, err := fs.counters.IncrBy(ctx, liveKey(siteID, minute, "bots"), 1, ttl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DashboardLive 返回最近一小时按分钟的提交量和爬虫数以及未过期的封禁数，siteID 为空时合并所有站点
+func (fs *FingerprintService) DashboardLive(ctx context.Context, siteID string) (*models.DashboardLive, error) {
+	now := time.Now()
+	// 未指定站点时合并空站点（未携带站点的提交）和所有配置的站点
+	sites := []string{siteID}
+	if siteID == "" {
+		for id := range fs.sites {
+			sites = append(sites, id)
+		}
+	}
+	current := now.Unix() / 60
+	keys := make([]string, 0, len(sites)*liveMinutes*2)
+	for _, site := range sites {
+		for i := int64(0); i < liveMinutes; i++ {
+			minute := current - liveMinutes + 1 + i
+			keys = append(keys, liveKey(site, minute, "submissions"), liveKey(site, minute, "bots"))
+		}
+	}
+	values, err := fs.counters.Get(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	live := &models.DashboardLive{At: now, PerMinute: make([]models.DashboardPoint, liveMinutes), Counters: fs.counters.Backend()}
+	for i := range live.PerMinute {
+		live.PerMinute[i].Time = time.Unix((current-liveMinutes+1+int64(i))*60, 0).UTC()
+	}
+	for j := 0; j < len(values); j += 2 {
+		p := &live.PerMinute[(j/2)%liveMinutes]
+		p.Submissions += int(values[j])
+		p.Bots += int(values[j+1])
+	}
+	for i, p := range live.PerMinute {
+		age := liveMinutes - 1 - i
+		submissions, bots := int64(p.Submissions), int64(p.Bots)
+		if age < 1 {
+			live.Submissions1m += submissions
+			live.Bots1m += bots
+		}
+		if age < 5 {
+			live.Submissions5m += submissions
+			live.Bots5m += bots
+		}
+		live.Submissions1h += submissions
+		live.Bots1h += bots
+	}
+
+	if err := fs.db.Read.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM blocklist WHERE expires_at > ?", now).Scan(&live.ActiveBlocks); err != nil {
+		return nil, err
+	}
+	return live, nil
+}
+
+// Detections 按最近一次分析的时间倒序返回检测列表
+func (fs *FingerprintService) Detections(ctx context.Context, q models.DetectionQuery) ([]models.Detection, error) {
+	where := []string{"a.deleted_at IS NULL"}
+	var args []interface{}
+	if q.SiteID != "" {
+		where = append(where, "f.site_id = ?")
+		args = append(args, q.SiteID)
+	}
+	if q.BotsOnly {
+		where = append(where, "a.is_bot")
+	}
+	if q.Since != nil {
+		where = append(where, "a.last_seen > ?")
+		args = append(args, *q.Since)
+	}
+	if q.Before != nil {
+		where = append(where, "a.last_seen < ?")
+		args = append(args, *q.Before)
+	}
+	args = append(args, q.Limit)
+
+	rows, err := fs.db.Read.QueryContext(ctx, `
+		SELECT a.fingerprint_hash, f.site_id, f.ip_address, f.country, f.user_agent,
+		       a.bot_score, a.risk_level, a.is_bot, a.reason_codes, a.visit_count, a.last_seen
+		FROM analysis a JOIN fingerprints f ON f.fingerprint_hash = a.fingerprint_hash
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY a.last_seen DESC LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	detections := []models.Detection{}
+	for rows.Next() {
+		var d models.Detection
+		var reasonCodes string
+		if err := rows.Scan(&d.FingerprintHash, &d.SiteID, &d.IPAddress, &d.Country, &d.UserAgent,
+			&d.BotScore, &d.RiskLevel, &d.IsBot, &reasonCodes, &d.VisitCount, &d.LastSeen); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(reasonCodes), &d.ReasonCodes); err != nil || d.ReasonCodes == nil {
+			d.ReasonCodes = []string{}
+		}
+		detections = append(detections, d)
+	}
+	return detections, rows.Err()
+}
+
+// DashboardOverview 返回看板首页的数据：[from, to) 内的汇总、图表和告警，以及实时计数和最近的检测；
+// from、to 为空时取最近24小时，siteID 为空时合并所有站点。汇总、图表和告警按 query_cache 缓存，实时计数和最近的检测每次查询
+func (fs *FingerprintService) DashboardOverview(ctx context.Context, from, to *time.Time, siteID string) (*models.DashboardOverview, error) {
+	cached, err := cachedQuery(ctx, fs, queryKey("dashboard", from, to, siteID), func(ctx context.Context) (*models.DashboardOverview, error) {
+		return fs.dashboardOverview(ctx, from, to, siteID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	overview := *cached
+	live, err := fs.DashboardLive(ctx, siteID)
+	if err != nil {
+		return nil, err
+	}
+	overview.Live = *live
+	if overview.Recent, err = fs.Detections(ctx, models.DetectionQuery{SiteID: siteID, Limit: dashboardRecent}); err != nil {
+		return nil, err
+	}
+	return &overview, nil
+}
+
+// dashboardOverview 查询看板首页的汇总、图表和告警，不使用缓存
+func (fs *FingerprintService) dashboardOverview(ctx context.Context, from, to *time.Time, siteID string) (*models.DashboardOverview, error) {
+	end := time.Now()
+	if to != nil {
+		end = *to
+	}
+	start := end.Add(-24 * time.Hour)
+	if from != nil {
+		start = *from
+	}
+	overview := &models.DashboardOverview{SiteID: siteID, From: start, To: end}
+
+	where := "f.deleted_at IS NULL AND f.created_at >= ? AND f.created_at < ?"
+	args := []interface{}{start, end}
+	if siteID != "" {
+		where += " AND f.site_id = ?"
+		args = append(args, siteID)
+	}
+	const joined = `
+		FROM fingerprints f JOIN analysis a ON a.fingerprint_hash = f.fingerprint_hash`
+
+	s := &overview.Summary
+	if err := fs.db.Read.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(a.is_bot), 0), COALESCE(SUM(a.risk_level = 'HIGH'), 0),
+		       COUNT(DISTINCT NULLIF(f.visitor_id, '')), COUNT(DISTINCT f.ip_address)`+joined+" WHERE "+where, args...).
+		Scan(&s.Fingerprints, &s.Bots, &s.HighRisk, &s.Visitors, &s.IPs); err != nil {
+		return nil, err
+	}
+	if s.Fingerprints > 0 {
+		s.BotRate = float64(s.Bots) / float64(s.Fingerprints)
+	}
+
+	var err error
+	c := &overview.Charts
+	top := " LIMIT " + strconv.Itoa(dashboardTop)
+	if c.Risk, err = fs.dashboardCounts(ctx, "SELECT a.risk_level AS k, COUNT(*) AS n, COALESCE(SUM(a.is_bot), 0)"+joined+
+		" WHERE "+where+" GROUP BY k ORDER BY n DESC", args); err != nil {
+		return nil, err
+	}
+	if c.Countries, err = fs.dashboardCounts(ctx, "SELECT f.country AS k, COUNT(*) AS n, COALESCE(SUM(a.is_bot), 0)"+joined+
+		" WHERE "+where+" AND f.country != '' GROUP BY k ORDER BY n DESC, k"+top, args); err != nil {
+		return nil, err
+	}
+	// 原因代码保存为JSON数组，按数组元素展开后计数
+	if c.Reasons, err = fs.dashboardCounts(ctx, "SELECT r.value AS k, COUNT(*) AS n, COALESCE(SUM(a.is_bot), 0)"+joined+
+		", json_each(a.reason_codes) r WHERE "+where+" GROUP BY k ORDER BY n DESC, k"+top, args); err != nil {
+		return nil, err
+	}
+	if c.Traffic, err = fs.dashboardTraffic(ctx, start, end, siteID, s); err != nil {
+		return nil, err
+	}
+	scores, err := fs.ScoreStats(ctx, &start, &end, siteID)
+	if err != nil {
+		return nil, err
+	}
+	c.Scores = scores.Histogram
+
+	a := &overview.Alerts
+	if a.Anomalies, err = fs.GetAnomalies(ctx, siteID, dashboardAlerts); err != nil {
+		return nil, err
+	}
+	if a.CredentialStuffing, err = fs.GetStuffingDetections(ctx, siteID, dashboardAlerts); err != nil {
+		return nil, err
+	}
+	if a.Scraping, err = fs.GetScrapingDetections(ctx, siteID, dashboardAlerts); err != nil {
+		return nil, err
+	}
+	return overview, nil
+}
+
+// dashboardCounts 执行返回键、数量和爬虫数三列的分组查询
+func (fs *FingerprintService) dashboardCounts(ctx context.Context, query string, args []interface{}) ([]models.DashboardCount, error) {
+	rows, err := fs.db.Read.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := []models.DashboardCount{}
+	for rows.Next() {
+		var c models.DashboardCount
+		if err := rows.Scan(&c.Key, &c.Count, &c.Bots); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// dashboardTraffic 从 traffic_stats 读取 [start, end) 内各统计桶的提交量和爬虫数，补齐没有提交的桶，并累计到汇总中
+func (fs *FingerprintService) dashboardTraffic(ctx context.Context, start, end time.Time, siteID string, summary *models.DashboardSummary) ([]models.DashboardPoint, error) {
+	points := []models.DashboardPoint{}
+	if fs.anomaly.Bucket <= 0 {
+		return points, nil
+	}
+	step := int64(fs.anomaly.Bucket.Std() / time.Second)
+	first, last := fs.trafficBucket(start), fs.trafficBucket(end)
+	if !end.After(time.Unix(last, 0)) {
+		last -= step
+	}
+
+	query := "SELECT bucket, SUM(submissions), SUM(bots) FROM traffic_stats WHERE bucket >= ? AND bucket <= ?"
+	args := []interface{}{first, last}
+	if siteID != "" {
+		query += " AND site_id = ?"
+		args = append(args, siteID)
+	}
+	rows, err := fs.db.Read.QueryContext(ctx, query+" GROUP BY bucket", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	byBucket := make(map[int64]models.DashboardPoint)
+	for rows.Next() {
+		var bucket int64
+		var p models.DashboardPoint
+		if err := rows.Scan(&bucket, &p.Submissions, &p.Bots); err != nil {
+			return nil, err
+		}
+		byBucket[bucket] = p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for bucket := first; bucket <= last; bucket += step {
+		p := byBucket[bucket]
+		p.Time = time.Unix(bucket, 0).UTC()
+		summary.Submissions += p.Submissions
+		summary.BotSubmissions += p.Bots
+		points = append(points, p)
+	}
+	return points, nil
+}
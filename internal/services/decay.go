@@ -0,0 +1,63 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"math"
+	"time"
+)
+
+// velocityReasonCodes 依据一段时间内的访问量或访问速度得出的原因代码：证据只在各自的统计窗口内成立，
+// 评分衰减过一个半衰期后不再作为当前原因返回；设备特征自相矛盾一类的原因描述的是设备本身，随评分一起衰减但保留原因
+var velocityReasonCodes = map[string]bool{
+	models.ReasonCookieCycling:      true,
+	models.ReasonIPReputation:       true,
+	models.ReasonDirectDeepHits:     true,
+	models.ReasonScrapingPattern:    true,
+	models.ReasonConcurrentSessions: true,
+}
+
+// decayFactor 返回距上次评分 age 之后评分保留的比例，按半衰期指数衰减
+func decayFactor(age, halfLife time.Duration) float64 {
+	if halfLife <= 0 || age <= 0 {
+		return 1
+	}
+	return math.Pow(0.5, float64(age)/float64(halfLife))
+}
+
+// decayAnalysis 读取时按距上次评分的时间衰减爬虫评分，并重新判定风险等级和是否为爬虫
+// 旧的检测结果不应永久认定一台设备，设备再次提交时按新的数据重新评分；
+// 评分衰减到无证据（0），访问量和访问速度得出的原因代码在衰减过一个半衰期后移除，存储的评分和原因不变
+func (fs *FingerprintService) decayAnalysis(a *models.Analysis, siteID string, now time.Time) {
+	factor := decayFactor(now.Sub(a.UpdatedAt), fs.scoreHalfLife)
+	if factor <= 0.5 {
+		a.ReasonCodes = dropVelocityReasons(a.ReasonCodes)
+	}
+	// 保留4位小数，刚评分不久的记录不因微小的衰减改变
+	decayed := math.Round(a.BotScore*factor*1e4) / 1e4
+	if decayed >= a.BotScore {
+		return
+	}
+	a.RawBotScore = a.BotScore
+	a.BotScore = decayed
+	a.RiskLevel = fs.calculateRiskLevel(a.UniquenessScore, a.BotScore)
+	a.IsBot = a.BotScore > fs.botThreshold(fs.siteOverride(siteID))
+}
+
+// dropVelocityReasons 从JSON数组形式的原因代码中移除访问量和访问速度得出的代码
+func dropVelocityReasons(reasonCodes string) string {
+	if reasonCodes == "" {
+		return reasonCodes
+	}
+	codes := utils.JSONToStringSlice(reasonCodes)
+	kept := codes[:0]
+	for _, code := range codes {
+		if !velocityReasonCodes[code] {
+			kept = append(kept, code)
+		}
+	}
+	if len(kept) == len(codes) {
+		return reasonCodes
+	}
+	return utils.StringSliceToJSON(kept)
+}
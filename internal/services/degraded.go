@@ -0,0 +1,245 @@
+package services
+
+import (
+	"browser-detection/internal/journal"
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"time"
+)
+
+// submission 写入缓存文件和预写日志的一次原始提交
+type submission struct {
+	ReceivedAt time.Time                 `json:"received_at"`
+	Meta       models.RequestMeta        `json:"meta"`
+	Request    models.FingerprintRequest `json:"request"`
+}
+
+// requestMeta 返回提交的请求元数据，旧版本写入的记录没有单独保存接收时间时取记录的接收时间
+func (s *submission) requestMeta() models.RequestMeta {
+	meta := s.Meta
+	if meta.ReceivedAt.IsZero() {
+		meta.ReceivedAt = s.ReceivedAt
+	}
+	return meta
+}
+
+// processDegraded 存储不可用时的处理：缓存提交，并在开启 score_only 时只按无状态规则评分
+// 未开启时只返回指纹哈希和 err，由调用方返回503
+func (fs *FingerprintService) processDegraded(fp *models.Fingerprint, req *models.FingerprintRequest, meta models.RequestMeta, err error) (*models.FingerprintResponse, error) {
+	if bufErr := fs.bufferSubmission(req, meta); bufErr != nil {
+		log.Printf("Failed to buffer submission: %v", bufErr)
+	}
+	if !fs.storage.ScoreOnly {
+		return &models.FingerprintResponse{
+			FingerprintHash: fp.FingerprintHash,
+			Degraded:        true,
+			Message:         "Storage unavailable, analysis deferred",
+		}, err
+	}
+
+	// 无法查询历史记录，验证模式下没有服务端证据的噪点结论一律不采信
+	analysis := fs.scoreFingerprint(fp, req, fs.statelessSignals(fp, req), fs.rulesFor(fp.SiteID), func(string, string) int { return 0 })
+	override := fs.siteOverride(meta.SiteID)
+	action := countryAction(override.CountryPolicy, meta.Country, meta.IPAddress, fp.FingerprintHash)
+	crawler := fs.crawlerTable.Match(fp.UserAgent)
+	crawlerPolicy := crawlerAction(override.CrawlerPolicy, crawler)
+	return &models.FingerprintResponse{
+		FingerprintHash: fp.FingerprintHash,
+		Analysis:        analysis,
+		Challenge:       needsChallenge(override, analysis.RiskLevel) || action == models.ActionChallenge || crawlerPolicy == models.ActionChallenge,
+		CountryPolicy:   action,
+		Crawler:         crawler,
+		CrawlerPolicy:   crawlerPolicy,
+		Degraded:        true,
+		Success:         true,
+		Message:         "Storage unavailable, scored with stateless rules only",
+	}, nil
+}
+
+// bufferSubmission 将提交追加到缓存文件
+func (fs *FingerprintService) bufferSubmission(req *models.FingerprintRequest, meta models.RequestMeta) error {
+	if fs.storage.BufferPath == "" {
+		return nil
+	}
+	line, err := json.Marshal(submission{ReceivedAt: meta.ReceivedAt, Meta: meta, Request: *req})
+	if err != nil {
+		return err
+	}
+	fs.bufferMu.Lock()
+	defer fs.bufferMu.Unlock()
+	return appendLines(fs.storage.BufferPath, [][]byte{line})
+}
+
+// appendLines 以追加方式写入若干行
+func appendLines(path string, lines [][]byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, line := range lines {
+		w.Write(line)
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReplayBuffered 将缓存的提交重新写入数据库，返回重放的条数
+// 重放中存储再次不可用时，当前提交已重新缓存，其余提交写回缓存文件等待下次重放
+func (fs *FingerprintService) ReplayBuffered(ctx context.Context) (int, error) {
+	if fs.storage.BufferPath == "" {
+		return 0, nil
+	}
+	// 先移走缓存文件，重放期间的新提交写入新的缓存文件
+	replaying := fs.storage.BufferPath + ".replay"
+	fs.bufferMu.Lock()
+	if _, err := os.Stat(replaying); errors.Is(err, os.ErrNotExist) {
+		if err := os.Rename(fs.storage.BufferPath, replaying); err != nil {
+			fs.bufferMu.Unlock()
+			if errors.Is(err, os.ErrNotExist) {
+				return 0, nil
+			}
+			return 0, err
+		}
+	}
+	fs.bufferMu.Unlock()
+
+	data, err := os.ReadFile(replaying)
+	if err != nil {
+		return 0, err
+	}
+	var lines [][]byte
+	for _, line := range bytes.Split(data, []byte{'\n'}) {
+		if len(line) > 0 {
+			lines = append(lines, line)
+		}
+	}
+
+	replayed := 0
+	for i, line := range lines {
+		var s submission
+		if err := json.Unmarshal(line, &s); err != nil {
+			log.Printf("Skipping invalid buffered submission: %v", err)
+			continue
+		}
+		resp, err := fs.processFingerprint(ctx, &s.Request, s.requestMeta())
+		if err == nil && !resp.Degraded {
+			replayed++
+			continue
+		}
+		if err != nil && !errors.Is(err, ErrStorageUnavailable) {
+			log.Printf("Skipping buffered submission that failed to process: %v", err)
+			continue
+		}
+		// 存储再次不可用：当前提交已重新缓存，其余写回
+		fs.bufferMu.Lock()
+		err = appendLines(fs.storage.BufferPath, lines[i+1:])
+		fs.bufferMu.Unlock()
+		if err != nil {
+			return replayed, err
+		}
+		break
+	}
+	if err := os.Remove(replaying); err != nil {
+		return replayed, err
+	}
+	if replayed > 0 {
+		log.Printf("Replayed %d buffered submissions", replayed)
+	}
+	return replayed, nil
+}
+
+// RunBufferReplay 启动时及之后每个熔断冷却期检查缓存文件，存储可用时重放，直到 ctx 结束
+func (fs *FingerprintService) RunBufferReplay(ctx context.Context) {
+	if fs.storage.BufferPath == "" {
+		return
+	}
+	interval := fs.storage.BreakerCooldown.Std()
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if fs.breaker.State() != utils.BreakerOpen {
+			if _, err := fs.ReplayBuffered(ctx); err != nil {
+				log.Printf("Failed to replay buffered submissions: %v", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// OpenJournal 按配置打开预写日志，未配置路径时不记录
+func (fs *FingerprintService) OpenJournal() error {
+	if fs.storage.JournalPath == "" {
+		return nil
+	}
+	j, err := journal.Open(fs.storage.JournalPath, fs.storage.JournalMaxBytes)
+	if err != nil {
+		return err
+	}
+	fs.journal = j
+	return nil
+}
+
+// CloseJournal 关闭预写日志
+func (fs *FingerprintService) CloseJournal() error {
+	if fs.journal == nil {
+		return nil
+	}
+	return fs.journal.Close()
+}
+
+// ReplayJournal 重放预写日志中没有完成标记的提交（崩溃时正在处理的请求），返回重放的条数，必须在服务停止时执行
+// 崩溃前已写入数据库但未来得及标记的提交会再处理一次，访问次数可能多计一次
+func (fs *FingerprintService) ReplayJournal(ctx context.Context) (int, error) {
+	if fs.journal == nil {
+		return 0, errors.New("storage.journal_path is not set")
+	}
+	pending, err := journal.Pending(fs.storage.JournalPath)
+	if err != nil {
+		return 0, err
+	}
+	replayed := 0
+	for _, entry := range pending {
+		var s submission
+		if err := json.Unmarshal(entry.Payload, &s); err != nil {
+			log.Printf("Skipping invalid journal entry %s: %v", entry.ID, err)
+		} else {
+			resp, err := fs.processFingerprint(ctx, &s.Request, s.requestMeta())
+			switch {
+			case errors.Is(err, ErrStorageUnavailable) || err == nil && resp.Degraded:
+				// 该提交已写入缓存文件，由存储恢复后的重放处理
+				if err := fs.journal.Done(entry.ID); err != nil {
+					return replayed, err
+				}
+				return replayed, ErrStorageUnavailable
+			case err != nil:
+				log.Printf("Skipping journal entry %s that failed to process: %v", entry.ID, err)
+			default:
+				replayed++
+			}
+		}
+		if err := fs.journal.Done(entry.ID); err != nil {
+			return replayed, err
+		}
+	}
+	log.Printf("Replayed %d of %d pending journal entries", replayed, len(pending))
+	return replayed, nil
+}
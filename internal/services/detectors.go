@@ -0,0 +1,100 @@
+package services
+
+import (
+	"browser-detection/internal/config"
+	"browser-detection/internal/detector"
+	"browser-detection/internal/models"
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+)
+
+// newDetectors 返回已注册的检测器插件以及配置的进程外检测器，是否运行由功能开关 detector.<名称> 决定，
+// detection.detectors.disabled 中的检测器默认停用；与已注册检测器重名的进程外检测器不启用
+func newDetectors(cfg config.DetectorsConfig) []detector.Detector {
+	detectors := detector.Registered()
+	registered := make(map[string]bool)
+	for _, d := range detectors {
+		registered[d.Name()] = true
+	}
+	for _, name := range cfg.Disabled {
+		if !registered[name] {
+			log.Printf("Disabled detector %q is not registered", name)
+		}
+	}
+	for _, remote := range cfg.Remote {
+		if registered[remote.Name] {
+			log.Printf("Remote detector %q conflicts with a registered detector, skipped", remote.Name)
+			continue
+		}
+		d, err := detector.NewRemote(remote, cfg.BreakerFailures, cfg.BreakerCooldown)
+		if err != nil {
+			log.Printf("Remote detector %q skipped: %v", remote.Name, err)
+			continue
+		}
+		detectors = append(detectors, d)
+	}
+	return detectors
+}
+
+// detectorSignals 并发运行检测器插件，按检测器顺序转换为检测信号；
+// 单个检测器 panic 或给出无效信号（代码为空、权重不在 -1~1）时记录日志并忽略，不影响其他检测
+func (fs *FingerprintService) detectorSignals(ctx context.Context, fp *models.Fingerprint, req *models.FingerprintRequest) []signal {
+	var active []detector.Detector
+	for _, d := range fs.detectors {
+		if fs.featureEnabled(detectorFeature(d.Name())) {
+			active = append(active, d)
+		}
+	}
+	results := make([][]detector.Signal, len(active))
+	var wg sync.WaitGroup
+	for i, d := range active {
+		wg.Add(1)
+		go func(i int, d detector.Detector) {
+			defer wg.Done()
+			found, err := runDetector(ctx, d, fp, req)
+			if err != nil {
+				log.Printf("Detector %s failed: %v", d.Name(), err)
+				return
+			}
+			results[i] = found
+		}(i, d)
+	}
+	wg.Wait()
+
+	var signals []signal
+	for i, d := range active {
+		for _, s := range results[i] {
+			if s.Code == "" || math.IsNaN(s.Weight) || s.Weight < -1 || s.Weight > 1 {
+				log.Printf("Detector %s returned invalid signal %q with weight %v", d.Name(), s.Code, s.Weight)
+				continue
+			}
+			signals = append(signals, signal{Code: s.Code, Weight: s.Weight, Reason: s.Reason})
+		}
+	}
+	return signals
+}
+
+// runDetector 运行单个检测器，将 panic 转为错误
+func runDetector(ctx context.Context, d detector.Detector, fp *models.Fingerprint, req *models.FingerprintRequest) (signals []detector.Signal, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return d.Evaluate(ctx, fp, req), nil
+}
+
+// Detectors 返回已注册的检测器插件和进程外检测器及其是否启用，以及进程外检测器的熔断状态
+func (fs *FingerprintService) Detectors() []models.DetectorStatus {
+	statuses := make([]models.DetectorStatus, len(fs.detectors))
+	for i, d := range fs.detectors {
+		statuses[i] = models.DetectorStatus{Name: d.Name(), Enabled: fs.featureEnabled(detectorFeature(d.Name()))}
+		if remote, ok := d.(*detector.Remote); ok {
+			statuses[i].Remote, statuses[i].State = true, remote.State()
+		}
+	}
+	return statuses
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"context"
+	"database/sql"
+	"time"
+)
+
+// latestComponentHashes 返回访客各组件最近一次记录的哈希
+func (fs *FingerprintService) latestComponentHashes(ctx context.Context, visitorID string) (map[string]string, error) {
+	rows, err := fs.db.DB.QueryContext(ctx, `
+		SELECT h.component, h.hash FROM component_history h
+		JOIN (
+			SELECT component, MAX(id) AS id FROM component_history
+			WHERE visitor_id = ? GROUP BY component
+		) latest ON latest.id = h.id`, visitorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	latest := make(map[string]string)
+	for rows.Next() {
+		var component, hash string
+		if err := rows.Scan(&component, &hash); err != nil {
+			return nil, err
+		}
+		latest[component] = hash
+	}
+	return latest, rows.Err()
+}
+
+// componentHashes 返回指纹已保存的各组件哈希
+func (fs *FingerprintService) componentHashes(ctx context.Context, fingerprintHash string) (map[string]string, error) {
+	rows, err := fs.db.DB.QueryContext(ctx,
+		"SELECT component, hash FROM fingerprint_components WHERE fingerprint_hash = ?", fingerprintHash)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hashes := make(map[string]string)
+	for rows.Next() {
+		var component, hash string
+		if err := rows.Scan(&component, &hash); err != nil {
+			return nil, err
+		}
+		hashes[component] = hash
+	}
+	return hashes, rows.Err()
+}
+
+// recordComponentHistory 记录访客本次提交中首次出现或发生变化的组件哈希
+func (fs *FingerprintService) recordComponentHistory(ctx context.Context, fp *models.Fingerprint, components map[string]string) error {
+	if fp.VisitorID == "" {
+		return nil
+	}
+	latest, err := fs.latestComponentHashes(ctx, fp.VisitorID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	for name, hash := range components {
+		if latest[name] == hash {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO component_history (visitor_id, component, hash, fingerprint_hash, observed_at) VALUES (?, ?, ?, ?, ?)",
+			fp.VisitorID, name, hash, fp.FingerprintHash, now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetDriftReport 生成访客的指纹漂移报告：每个组件相邻两次记录之间的变化
+func (fs *FingerprintService) GetDriftReport(ctx context.Context, visitorID string) (*models.DriftReport, error) {
+	rows, err := fs.db.Read.QueryContext(ctx,
+		"SELECT component, hash, fingerprint_hash, observed_at FROM component_history WHERE visitor_id = ? ORDER BY id",
+		visitorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := &models.DriftReport{
+		VisitorID:    visitorID,
+		Changes:      []models.ComponentChange{},
+		ChangeCounts: make(map[string]int),
+	}
+	previous := make(map[string]string)
+	seen := false
+	for rows.Next() {
+		var component, hash, fingerprintHash string
+		var observedAt time.Time
+		if err := rows.Scan(&component, &hash, &fingerprintHash, &observedAt); err != nil {
+			return nil, err
+		}
+		seen = true
+		if prev, ok := previous[component]; ok {
+			report.Changes = append(report.Changes, models.ComponentChange{
+				Component:       component,
+				PreviousHash:    prev,
+				Hash:            hash,
+				FingerprintHash: fingerprintHash,
+				ChangedAt:       observedAt,
+			})
+			report.ChangeCounts[component]++
+		}
+		previous[component] = hash
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if !seen {
+		return nil, sql.ErrNoRows
+	}
+	return report, nil
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrEdgeDisabled 未配置 edge.cache_ttl 时边缘决策接口不可用
+var ErrEdgeDisabled = errors.New("edge decisions are disabled")
+
+// ja4Pattern JA4 TLS 客户端指纹的格式，如 t13d1516h2_8daaf6152771_b0da82dd1658
+var ja4Pattern = regexp.MustCompile(`^[tqd][0-9a-z]{2}[di][0-9]{4}[0-9a-z]{2}_[0-9a-f]{12}_[0-9a-f]{12}$`)
+
+// ValidJA4 判断是否为格式正确的 JA4 指纹
+func ValidJA4(ja4 string) bool {
+	return ja4Pattern.MatchString(ja4)
+}
+
+// edgeEntry 服务端缓存的一条边缘决策
+type edgeEntry struct {
+	decision models.EdgeDecision
+	expires  time.Time
+}
+
+// edgeCache 边缘决策的内存缓存，按查询条件缓存 cache_ttl
+type edgeCache struct {
+	mu      sync.Mutex
+	entries map[string]edgeEntry
+}
+
+// edgeCacheKey 查询条件组成的缓存键
+func edgeCacheKey(req models.EdgeRequest) string {
+	return strings.Join([]string{req.SiteID, req.FingerprintHash, req.VisitorID, req.JA4, req.IPAddress, req.Country, req.Action}, "\x00")
+}
+
+// get 返回未过期的缓存决策
+func (c *edgeCache) get(key string, now time.Time) (models.EdgeDecision, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || !now.Before(e.expires) {
+		return models.EdgeDecision{}, false
+	}
+	return e.decision, true
+}
+
+// put 缓存决策 ttl，达到上限时先清除已过期的决策，仍然已满时清空缓存
+func (c *edgeCache) put(key string, decision models.EdgeDecision, now time.Time, ttl time.Duration, maxEntries int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= maxEntries {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxEntries {
+			c.entries = make(map[string]edgeEntry)
+		}
+	}
+	c.entries[key] = edgeEntry{decision: decision, expires: now.Add(ttl)}
+}
+
+// EdgeDecision 按指纹哈希、访客Cookie、JA4 的顺序查找边缘节点的处理建议，决策在服务端缓存 cache_ttl，
+// 第二个返回值表示是否命中缓存。都查不到时按 allow 处理，封禁名单和国家策略仍然生效
+func (fs *FingerprintService) EdgeDecision(ctx context.Context, req models.EdgeRequest) (*models.EdgeDecision, bool, error) {
+	if fs.edge.CacheTTL <= 0 {
+		return nil, false, ErrEdgeDisabled
+	}
+	now := time.Now()
+	key := edgeCacheKey(req)
+	if decision, ok := fs.edgeCache.get(key, now); ok {
+		return &decision, true, nil
+	}
+
+	decision, err := fs.edgeDecision(ctx, req)
+	if err != nil {
+		return nil, false, err
+	}
+	decision.MaxAge = int(fs.edge.CacheTTL.Std().Seconds())
+	fs.edgeCache.put(key, *decision, now, fs.edge.CacheTTL.Std(), fs.edge.MaxEntries)
+	return decision, false, nil
+}
+
+// edgeDecision 查询并组合边缘决策，不使用缓存
+func (fs *FingerprintService) edgeDecision(ctx context.Context, req models.EdgeRequest) (*models.EdgeDecision, error) {
+	meta := models.RequestMeta{IPAddress: req.IPAddress, SiteID: req.SiteID, VisitorID: req.VisitorID, Country: req.Country}
+	hash, source := req.FingerprintHash, models.EdgeSourceFingerprint
+	if hash == "" && req.VisitorID != "" {
+		var err error
+		if hash, err = fs.visitorFingerprint(ctx, req.VisitorID); err != nil {
+			return nil, err
+		}
+		source = models.EdgeSourceVisitor
+	}
+	if hash != "" {
+		decision, err := fs.Decide(ctx, hash, req.Action, meta)
+		if err == nil {
+			return &models.EdgeDecision{
+				Action:          decision.Action,
+				Source:          source,
+				BotScore:        decision.BotScore,
+				IsBot:           decision.IsBot,
+				FingerprintHash: hash,
+			}, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+	}
+
+	// 没有可用的指纹时按 JA4 汇总、封禁名单和国家策略决策
+	decision := models.EventDecision{Action: models.ActionAllow}
+	edge := &models.EdgeDecision{Source: models.EdgeSourceNone}
+	if req.JA4 != "" {
+		botRatio, ok, err := fs.ja4BotRatio(ctx, req.SiteID, req.JA4)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			edge.Source = models.EdgeSourceJA4
+			edge.JA4BotRatio = &botRatio
+			switch {
+			case fs.edge.JA4DenyRatio > 0 && botRatio >= fs.edge.JA4DenyRatio:
+				decision.Action = models.ActionDeny
+			case fs.edge.JA4ChallengeRatio > 0 && botRatio >= fs.edge.JA4ChallengeRatio:
+				decision.Action = models.ActionChallenge
+			}
+		}
+	}
+	blocked, err := fs.isBlocklisted(ctx, "", req.IPAddress, req.VisitorID)
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		decision.Action = models.ActionDeny
+	}
+	applyCountryPolicy(&decision, fs.siteOverride(req.SiteID), meta, "")
+	edge.Action = decision.Action
+	return edge, nil
+}
+
+// visitorFingerprint 返回访客Cookie最近一次提交的指纹，没有时返回空
+func (fs *FingerprintService) visitorFingerprint(ctx context.Context, visitorID string) (string, error) {
+	var hash string
+	err := fs.db.DB.QueryRowContext(ctx,
+		"SELECT fingerprint_hash FROM visitor_fingerprints WHERE visitor_id = ? ORDER BY last_seen DESC LIMIT 1",
+		visitorID).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return hash, err
+}
+
+// ja4Hour JA4 汇总按小时分桶
+func ja4Hour(t time.Time) int64 {
+	return t.Truncate(time.Hour).Unix()
+}
+
+// recordJA4 将指纹提交的爬虫判定计入其 JA4 的小时汇总
+func (fs *FingerprintService) recordJA4(ctx context.Context, siteID, ja4 string, bot bool) error {
+	if fs.edge.CacheTTL <= 0 || ja4 == "" {
+		return nil
+	}
+	_, err := fs.db.DB.ExecContext(ctx, `
+		INSERT INTO ja4_stats (site_id, ja4, hour, submissions, bots) VALUES (?, ?, ?, 1, ?)
+		ON CONFLICT (site_id, ja4, hour) DO UPDATE SET
+			submissions = submissions + 1,
+			bots = bots + excluded.bots`,
+		siteID, ja4, ja4Hour(time.Now()), bot)
+	return err
+}
+
+// ja4BotRatio 返回 JA4 在 ja4_window 内判定为爬虫的提交比例，提交数不足 ja4_min_submissions 时 ok 为 false
+func (fs *FingerprintService) ja4BotRatio(ctx context.Context, siteID, ja4 string) (float64, bool, error) {
+	var submissions, bots sql.NullInt64
+	if err := fs.db.DB.QueryRowContext(ctx, `
+		SELECT SUM(submissions), SUM(bots) FROM ja4_stats WHERE site_id = ? AND ja4 = ? AND hour >= ?`,
+		siteID, ja4, ja4Hour(time.Now().Add(-fs.edge.JA4Window.Std()))).Scan(&submissions, &bots); err != nil {
+		return 0, false, err
+	}
+	if int(submissions.Int64) < fs.edge.JA4MinSubmissions {
+		return 0, false, nil
+	}
+	return ratio(int(bots.Int64), int(submissions.Int64)), true, nil
+}
+
+// purgeJA4Stats 删除超过保留期的 JA4 汇总
+func (fs *FingerprintService) purgeJA4Stats(ctx context.Context) error {
+	if fs.edge.CacheTTL <= 0 {
+		return nil
+	}
+	_, err := fs.db.DB.ExecContext(ctx, "DELETE FROM ja4_stats WHERE hour < ?", ja4Hour(time.Now().Add(-fs.edge.JA4Retention.Std())))
+	return err
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"context"
+	"database/sql"
+	"errors"
+	"hash/fnv"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// embeddingBuckets 类别特征散列到的维数
+	embeddingBuckets = 32
+	// embeddingFeatureBits 特性探测位图参与向量的位数
+	embeddingFeatureBits = 20
+	// 近邻索引参数
+	hnswM              = 16
+	hnswEfConstruction = 200
+	hnswEfSearch       = 64
+)
+
+// fingerprintVector 把指纹编码为定长数值向量
+// 数值特征缩放到 0~1；User Agent家族、系统、时区、语言和平台散列到固定的桶中；
+// Canvas SimHash逐位展开，使输出相近的Canvas在向量空间中也相近。
+// 哈希不同但配置几乎相同的自动化浏览器会落在彼此附近
+func fingerprintVector(fp *models.Fingerprint) []float32 {
+	vec := make([]float32, 0, 10+embeddingBuckets+64+embeddingFeatureBits)
+
+	w, h := 0, 0
+	if parts := strings.Split(normalizeResolution(fp.ScreenResolution), "x"); len(parts) == 2 {
+		w, _ = strconv.Atoi(parts[0])
+		h, _ = strconv.Atoi(parts[1])
+	}
+	scale := func(v, max float64) float32 {
+		return float32(math.Min(math.Max(v, 0), max) / max)
+	}
+	flag := func(b bool) float32 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+	vec = append(vec,
+		scale(float64(w), 8000),
+		scale(float64(h), 8000),
+		scale(fp.DevicePixelRatio, 4),
+		scale(float64(fp.ColorDepth), 48),
+		scale(float64(fp.HardwareConcurrency), 64),
+		scale(fp.DeviceMemory, 32),
+		scale(float64(len(utils.JSONToStringSlice(fp.Fonts))), 300),
+		scale(float64(len(utils.JSONToStringSlice(fp.Plugins))), 10),
+		flag(fp.TouchSupport),
+		flag(fp.CookieEnabled),
+	)
+
+	// 类别特征散列：每个取值在对应的桶上加1，再整体归一化
+	buckets := make([]float32, embeddingBuckets)
+	ua := utils.ParseUserAgent(fp.UserAgent)
+	for _, category := range []string{
+		"family:" + ua.Family,
+		"os:" + ua.OS,
+		"tz:" + fp.Timezone,
+		"lang:" + strings.ToLower(fp.Language),
+		"platform:" + strings.ToLower(fp.Platform),
+	} {
+		h := fnv.New32a()
+		h.Write([]byte(category))
+		buckets[h.Sum32()%embeddingBuckets]++
+	}
+	for _, b := range buckets {
+		vec = append(vec, b/5)
+	}
+
+	// Canvas SimHash 的每一位，无法解码时为0.5
+	for bit := 0; bit < 64; bit++ {
+		v := float32(0.5)
+		if fp.CanvasSimHash != 0 {
+			v = float32(uint64(fp.CanvasSimHash) >> uint(bit) & 1)
+		}
+		vec = append(vec, v)
+	}
+
+	for bit := 0; bit < embeddingFeatureBits; bit++ {
+		vec = append(vec, float32(uint64(fp.FeatureBits)>>uint(bit)&1))
+	}
+	return vec
+}
+
+// LoadVectorIndex 加载磁盘上的近邻索引，文件不存在或无法解码时从数据库重建
+func (fs *FingerprintService) LoadVectorIndex(ctx context.Context) error {
+	if fs.embedding.IndexPath == "" {
+		return nil
+	}
+
+	index, err := utils.LoadHNSW(fs.embedding.IndexPath)
+	if err == nil {
+		fs.vectors = index
+		log.Printf("Loaded vector index with %d fingerprints", index.Len())
+		return nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Rebuilding vector index: %v", err)
+	}
+
+	stored, err := fs.loadFingerprints(ctx, "deleted_at IS NULL")
+	if err != nil {
+		return err
+	}
+	index = utils.NewHNSW(hnswM, hnswEfConstruction)
+	for _, s := range stored {
+		index.Add(s.fp.FingerprintHash, fingerprintVector(&s.fp))
+	}
+	fs.vectors = index
+	log.Printf("Built vector index with %d fingerprints", index.Len())
+	return fs.SaveVectorIndex()
+}
+
+// SaveVectorIndex 将近邻索引写入磁盘，多实例部署时只由持有租约的实例写入
+func (fs *FingerprintService) SaveVectorIndex() error {
+	if fs.vectors == nil || !fs.IsLeader() {
+		return nil
+	}
+	return fs.vectors.Save(fs.embedding.IndexPath)
+}
+
+// RunVectorIndexSaver 按配置的间隔保存近邻索引，直到 ctx 结束
+func (fs *FingerprintService) RunVectorIndexSaver(ctx context.Context) {
+	if fs.vectors == nil || fs.embedding.SaveInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(fs.embedding.SaveInterval.Std())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := fs.SaveVectorIndex(); err != nil {
+				log.Printf("Failed to save vector index: %v", err)
+			}
+		}
+	}
+}
+
+// invalidateVectorIndex 指纹哈希被批量改写后删除磁盘上的索引，下次启动时重建
+func (fs *FingerprintService) invalidateVectorIndex() {
+	if fs.embedding.IndexPath == "" {
+		return
+	}
+	fs.vectors = nil
+	if err := os.Remove(fs.embedding.IndexPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Failed to remove vector index: %v", err)
+	}
+}
+
+// FindNeighbors 查找特征向量与指定指纹最接近的 k 个其他指纹
+func (fs *FingerprintService) FindNeighbors(ctx context.Context, fingerprintHash string, k int) ([]utils.VectorMatch, error) {
+	if fs.vectors == nil {
+		return nil, errors.New("vector index is disabled")
+	}
+	vector, ok := fs.vectors.Vector(fingerprintHash)
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	matches := []utils.VectorMatch{}
+	for _, m := range fs.vectors.Search(vector, k+1, hnswEfSearch) {
+		if m.ID != fingerprintHash && len(matches) < k {
+			matches = append(matches, m)
+		}
+	}
+	return matches, nil
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"time"
+)
+
+const (
+	// maxEventMetadata 业务事件元数据的最大条目数
+	maxEventMetadata = 20
+	// maxEventMetadataLength 元数据键和值的最大长度
+	maxEventMetadataLength = 256
+)
+
+// eventTypePattern 业务事件类型的命名规则
+var eventTypePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+// validateEventThresholds 校验按事件类型的爬虫判定阈值
+func validateEventThresholds(thresholds map[string]float64) []models.FieldError {
+	var errs []models.FieldError
+	eventTypes := make([]string, 0, len(thresholds))
+	for eventType := range thresholds {
+		eventTypes = append(eventTypes, eventType)
+	}
+	sort.Strings(eventTypes)
+	for _, eventType := range eventTypes {
+		field := "event_bot_score." + eventType
+		if !eventTypePattern.MatchString(eventType) {
+			errs = append(errs, models.FieldError{Field: field, Constraint: "pattern " + eventTypePattern.String()})
+		} else if v := thresholds[eventType]; v <= 0 || v > 1 {
+			errs = append(errs, models.FieldError{Field: field, Constraint: "range (0, 1]", Got: v})
+		}
+	}
+	return errs
+}
+
+// validateEvent 校验业务事件，返回字段级错误
+func validateEvent(req *models.EventRequest) []models.FieldError {
+	var errs []models.FieldError
+	if !eventTypePattern.MatchString(req.EventType) {
+		errs = append(errs, models.FieldError{Field: "event_type", Constraint: "pattern " + eventTypePattern.String(), Got: req.EventType})
+	}
+	if req.Action != "" && !eventTypePattern.MatchString(req.Action) {
+		errs = append(errs, models.FieldError{Field: "action", Constraint: "pattern " + eventTypePattern.String(), Got: req.Action})
+	}
+	if len(req.AccountID) > maxAccountIDLength {
+		errs = append(errs, models.FieldError{Field: "account_id", Constraint: "max_length", Limit: maxAccountIDLength, Got: len(req.AccountID)})
+	}
+	switch req.Outcome {
+	case "", models.OutcomeSuccess, models.OutcomeFailure:
+	default:
+		errs = append(errs, models.FieldError{Field: "outcome", Constraint: "one of success, failure", Got: req.Outcome})
+	}
+	if len(req.Metadata) > maxEventMetadata {
+		errs = append(errs, models.FieldError{Field: "metadata", Constraint: "max_items", Limit: maxEventMetadata, Got: len(req.Metadata)})
+	}
+	keys := make([]string, 0, len(req.Metadata))
+	for key := range req.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if len(key) > maxEventMetadataLength || len(req.Metadata[key]) > maxEventMetadataLength {
+			errs = append(errs, models.FieldError{Field: "metadata." + key, Constraint: "max_length", Limit: maxEventMetadataLength})
+		}
+	}
+	if req.EventType == models.EventPageView {
+		errs = append(errs, validatePageViewAssets(req.Metadata)...)
+	}
+	errs = append(errs, validateTyping(req.EventType, req.Typing)...)
+	return errs
+}
+
+// eventThreshold 返回事件类型的爬虫判定阈值
+// 优先级：站点的事件阈值 > 全局的事件阈值 > 站点阈值 > 全局阈值
+func (fs *FingerprintService) eventThreshold(override models.SitePolicyOverride, eventType string) float64 {
+	if v, ok := override.EventBotScore[eventType]; ok {
+		return v
+	}
+	if v, ok := fs.Thresholds().EventBotScore[eventType]; ok {
+		return v
+	}
+	return fs.botThreshold(override)
+}
+
+// RecordEvent 记录接入方提交的业务事件，按受保护操作（未指定时为事件类型）的策略对指纹的分析结果给出处理建议
+// 指纹尚未提交过时返回 sql.ErrNoRows，字段不合法时返回字段级错误
+func (fs *FingerprintService) RecordEvent(ctx context.Context, req *models.EventRequest, meta models.RequestMeta) (*models.Event, []models.FieldError, error) {
+	if errs := validateEvent(req); len(errs) > 0 {
+		return nil, errs, nil
+	}
+
+	analysis, err := fs.GetAnalysis(ctx, req.FingerprintHash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	override := fs.siteOverride(meta.SiteID)
+	action := req.Action
+	if action == "" {
+		action = req.EventType
+	}
+	decision := fs.decide(override, analysis, action)
+
+	event := &models.Event{
+		FingerprintHash: req.FingerprintHash,
+		SiteID:          meta.SiteID,
+		EventType:       req.EventType,
+		AccountID:       req.AccountID,
+		Outcome:         req.Outcome,
+		Metadata:        req.Metadata,
+		IPAddress:       meta.IPAddress,
+		Country:         meta.Country,
+		CreatedAt:       time.Now(),
+	}
+
+	// 撞库检测可能封禁本次事件的指纹或IP段，需在判断封禁名单之前运行
+	var ipRange string
+	if meta.IPAddress != "" {
+		ipRange = utils.IPRange(meta.IPAddress)
+	}
+	if _, err := fs.detectStuffing(ctx, event, ipRange); err != nil {
+		return nil, nil, err
+	}
+	// 验证失败达到阈值时同样会封禁，也需在判断封禁名单之前记录
+	if req.EventType == models.EventChallenge {
+		if err := fs.recordChallengeOutcome(ctx, event, ipRange); err != nil {
+			return nil, nil, err
+		}
+	}
+	// 事件同样计入会话活动，工作节点只调用业务接口时也能发现并发；信号在指纹下次提交时计分，这里只告警
+	if err := fs.recordSessionActivity(ctx, req.FingerprintHash, "", meta.IPAddress, meta.Country); err != nil {
+		log.Printf("Failed to record session activity: %v", err)
+	}
+	fs.concurrentSessions(ctx, meta.SiteID, req.FingerprintHash, "")
+	visitorID, err := fs.requestVisitorID(ctx, req.FingerprintHash, meta)
+	if err != nil {
+		return nil, nil, err
+	}
+	blocked, err := fs.isBlocklisted(ctx, req.FingerprintHash, meta.IPAddress, visitorID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if blocked {
+		decision.Blocklisted = true
+		decision.Action = models.ActionDeny
+	}
+
+	// 账号接管信号：不可能的移动和多账号设备要求人机验证，新设备只作提示
+	if req.AccountID != "" {
+		signals, err := fs.evaluateAccount(ctx, event)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, s := range signals {
+			decision.AccountSignals = append(decision.AccountSignals, s.Code)
+			if decision.Action == models.ActionAllow && s.Code != models.ReasonAccountNewDevice {
+				decision.Action = models.ActionChallenge
+			}
+		}
+	}
+
+	// 打字节奏：机械的节奏和与账号档案不一致的节奏都要求人机验证
+	if req.Typing != nil {
+		signals, err := fs.evaluateTyping(ctx, event, req.Typing)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, s := range signals {
+			decision.TypingSignals = append(decision.TypingSignals, s.Code)
+			if decision.Action == models.ActionAllow {
+				decision.Action = models.ActionChallenge
+			}
+		}
+	}
+
+	applyCountryPolicy(&decision, override, meta, req.FingerprintHash)
+	if override.CrawlerPolicy != nil {
+		crawler, err := fs.fingerprintCrawler(ctx, req.FingerprintHash)
+		if err != nil {
+			return nil, nil, err
+		}
+		applyCrawlerPolicy(&decision, crawlerAction(override.CrawlerPolicy, crawler))
+	}
+	event.Decision = decision
+
+	if decision.Action == models.ActionChallenge && req.EventType != models.EventChallenge {
+		reasonCodes := append(utils.JSONToStringSlice(analysis.ReasonCodes), decision.AccountSignals...)
+		reasonCodes = append(reasonCodes, decision.TypingSignals...)
+		if err := fs.recordChallengeIssued(ctx, meta.SiteID, req.FingerprintHash, meta.IPAddress, reasonCodes); err != nil {
+			log.Printf("Failed to record challenge: %v", err)
+		}
+	}
+
+	if req.EventType == models.EventChallenge && req.Outcome == models.OutcomeFailure {
+		if err := fs.recordIPReputation(ctx, meta.IPAddress, ipReputationUpdate{challengeFailure: true}); err != nil {
+			log.Printf("Failed to record IP reputation: %v", err)
+		}
+	}
+
+	metadata, err := json.Marshal(req.Metadata)
+	if err != nil {
+		return nil, nil, err
+	}
+	if req.Metadata == nil {
+		metadata = []byte("{}")
+	}
+
+	result, err := fs.db.DB.ExecContext(ctx, `
+		INSERT INTO events (fingerprint_hash, site_id, event_type, account_id, outcome, metadata, ip_address, ip_range, country,
+			bot_score, threshold, is_bot, risk_level, action, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		event.FingerprintHash, event.SiteID, event.EventType, event.AccountID, event.Outcome, string(metadata),
+		event.IPAddress, ipRange, event.Country, decision.BotScore, decision.Threshold, decision.IsBot, decision.RiskLevel, decision.Action, event.CreatedAt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to save event: %w", err)
+	}
+	if event.ID, err = result.LastInsertId(); err != nil {
+		return nil, nil, err
+	}
+	if err := fs.recordPageView(ctx, event); err != nil {
+		log.Printf("Failed to record page view: %v", err)
+	}
+	if err := fs.auditPageView(ctx, event); err != nil {
+		log.Printf("Failed to audit robots.txt compliance: %v", err)
+	}
+	return event, nil, nil
+}
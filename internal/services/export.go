@@ -0,0 +1,100 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// exportReleases 一次导出发布的统计数：总数和国家、浏览器、操作系统、GPU四个直方图
+const exportReleases = 5
+
+// ExportAggregates 生成可对外发布的聚合统计
+// 每台设备（指纹记录）在总数和每个直方图中各只计入一个分组，各项统计的敏感度为1；
+// 按顺序组合定理，一台设备在一次导出中消耗的隐私预算是各项统计之和，因此 epsilon 作为一次导出的总预算平均分给 exportReleases 项统计，
+// 每个计数加入尺度为 exportReleases/epsilon 的拉普拉斯噪声；加噪后低于 min_count 的分组不发布；结果按 query_cache 缓存
+func (fs *FingerprintService) ExportAggregates(ctx context.Context) (*models.AggregateExport, error) {
+	return cachedQuery(ctx, fs, queryKey("export_aggregates", nil, nil), func(ctx context.Context) (*models.AggregateExport, error) {
+		return fs.exportAggregates(ctx)
+	})
+}
+
+// exportAggregates 查询聚合统计，不使用缓存
+func (fs *FingerprintService) exportAggregates(ctx context.Context) (*models.AggregateExport, error) {
+	rows, err := fs.db.Read.QueryContext(ctx, "SELECT user_agent, country, gpu_family FROM fingerprints WHERE deleted_at IS NULL")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	countries := make(map[string]int)
+	browsers := make(map[string]int)
+	systems := make(map[string]int)
+	gpus := make(map[string]int)
+	total := 0
+	for rows.Next() {
+		var userAgent, country, gpu string
+		if err := rows.Scan(&userAgent, &country, &gpu); err != nil {
+			return nil, err
+		}
+		total++
+		if country != "" {
+			countries[country]++
+		}
+		ua := utils.ParseUserAgent(userAgent)
+		browsers[ua.Family]++
+		systems[ua.OS]++
+		if gpu != "" {
+			gpus[gpu]++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &models.AggregateExport{
+		Epsilon:          fs.export.Epsilon,
+		ReleaseEpsilon:   fs.export.Epsilon / exportReleases,
+		MinCount:         fs.export.MinCount,
+		Total:            fs.noisyCount("total", "", total),
+		Countries:        fs.noisyHistogram("country", countries),
+		Browsers:         fs.noisyHistogram("browser", browsers),
+		OperatingSystems: fs.noisyHistogram("os", systems),
+		GPUFamilies:      fs.noisyHistogram("gpu", gpus),
+		GeneratedAt:      time.Now(),
+	}, nil
+}
+
+// noisyCount 返回加噪并取整后的计数，不小于0
+func (fs *FingerprintService) noisyCount(dimension, key string, count int) int {
+	noise := fs.noise.Laplace(exportReleases/fs.export.Epsilon, fmt.Sprintf("%s|%s|%d", dimension, key, count))
+	return int(math.Max(0, math.Round(float64(count)+noise)))
+}
+
+// noisyHistogram 对直方图逐组加噪，丢弃低于阈值的分组，按计数从多到少排列
+func (fs *FingerprintService) noisyHistogram(dimension string, counts map[string]int) []models.AggregateBucket {
+	buckets := []models.AggregateBucket{}
+	published := 0
+	for key, count := range counts {
+		noisy := fs.noisyCount(dimension, key, count)
+		if noisy < fs.export.MinCount {
+			continue
+		}
+		buckets = append(buckets, models.AggregateBucket{Key: key, Count: noisy})
+		published += noisy
+	}
+	for i := range buckets {
+		buckets[i].Share = float64(buckets[i].Count) / float64(published)
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		if buckets[i].Count != buckets[j].Count {
+			return buckets[i].Count > buckets[j].Count
+		}
+		return buckets[i].Key < buckets[j].Key
+	})
+	return buckets
+}
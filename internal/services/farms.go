@@ -0,0 +1,272 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// farmSampleSize 农场报告中保留的成员指纹数
+const farmSampleSize = 10
+
+// farmGroup 按某一分组键聚合的指纹
+type farmGroup struct {
+	fingerprints map[string]bool
+	userAgents   map[string]bool
+	// platforms 声明的浏览器家族与系统组合
+	platforms map[string]bool
+}
+
+func newFarmGroup() *farmGroup {
+	return &farmGroup{
+		fingerprints: make(map[string]bool),
+		userAgents:   make(map[string]bool),
+		platforms:    make(map[string]bool),
+	}
+}
+
+func (g *farmGroup) add(fp *models.Fingerprint) {
+	ua := utils.ParseUserAgent(fp.UserAgent)
+	g.fingerprints[fp.FingerprintHash] = true
+	g.userAgents[fp.UserAgent] = true
+	g.platforms[ua.Family+"/"+ua.OS] = true
+}
+
+// farmProfileKey 屏幕、时区和字体组成的配置键，UA轮换的农场在这些属性上完全相同
+func farmProfileKey(fp *models.Fingerprint) string {
+	return utils.GenerateListHash([]string{
+		normalizeResolution(fp.ScreenResolution),
+		fp.Timezone,
+		normalizeList(utils.JSONToStringSlice(fp.Fonts)),
+	})
+}
+
+// farmKeys 返回指纹在各类农场中的分组键
+// 没有字体列表的指纹不参与UA轮换分组，否则常见分辨率和时区会把大量真实用户归为一组
+func farmKeys(fp *models.Fingerprint) map[string]string {
+	keys := make(map[string]string, 3)
+	if fp.IPAddress != "" {
+		keys[models.FarmIPRange] = utils.IPRange(fp.IPAddress)
+	}
+	if len(utils.JSONToStringSlice(fp.Fonts)) > 0 {
+		keys[models.FarmUARotation] = farmProfileKey(fp)
+	}
+	if fp.CanvasHash != "" {
+		keys[models.FarmCanvasClone] = fp.CanvasHash
+	}
+	return keys
+}
+
+// DetectFarms 分析时间窗口内的指纹，找出设备农场并更新农场报告
+// 三类农场：同一IP段内大量不同指纹；屏幕、时区、字体完全相同而UA轮换；
+// 大量声明为不同平台的设备共享同一Canvas哈希。启用 auto_block 时新发现的农场标记为封禁。
+// 检测进行中时再次调用等待并共享本次结果，不会并发检测；检测不随调用方的请求取消而中断
+func (fs *FingerprintService) DetectFarms(ctx context.Context) ([]models.Farm, error) {
+	farms, err, _ := fs.jobs.Do("farms", func() (interface{}, error) {
+		return fs.detectFarms(context.WithoutCancel(ctx))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return farms.([]models.Farm), nil
+}
+
+// detectFarms 运行一次设备农场检测，只由 DetectFarms 调用
+func (fs *FingerprintService) detectFarms(ctx context.Context) ([]models.Farm, error) {
+	cfg := fs.farms
+	stored, err := fs.loadFingerprints(ctx, "updated_at >= ?", time.Now().Add(-cfg.Window.Std()))
+	if err != nil {
+		return nil, err
+	}
+
+	groups := map[string]map[string]*farmGroup{
+		models.FarmIPRange:     {},
+		models.FarmUARotation:  {},
+		models.FarmCanvasClone: {},
+	}
+	for i := range stored {
+		fp := &stored[i].fp
+		for kind, key := range farmKeys(fp) {
+			g, ok := groups[kind][key]
+			if !ok {
+				g = newFarmGroup()
+				groups[kind][key] = g
+			}
+			g.add(fp)
+		}
+	}
+
+	now := time.Now()
+	farms := []models.Farm{}
+	for kind, byKey := range groups {
+		for key, g := range byKey {
+			var flagged bool
+			switch kind {
+			case models.FarmIPRange:
+				flagged = len(g.fingerprints) >= cfg.IPRangeMinFingerprints
+			case models.FarmUARotation:
+				flagged = len(g.userAgents) >= cfg.UARotationMinUserAgents
+			case models.FarmCanvasClone:
+				flagged = len(g.fingerprints) >= cfg.CanvasCloneMinFingerprints &&
+					len(g.platforms) >= cfg.CanvasCloneMinPlatforms
+			}
+			if !flagged {
+				continue
+			}
+
+			samples := make([]string, 0, len(g.fingerprints))
+			for hash := range g.fingerprints {
+				samples = append(samples, hash)
+			}
+			sort.Strings(samples)
+			if len(samples) > farmSampleSize {
+				samples = samples[:farmSampleSize]
+			}
+			farms = append(farms, models.Farm{
+				Kind:          kind,
+				Key:           key,
+				Fingerprints:  len(g.fingerprints),
+				UserAgents:    len(g.userAgents),
+				Samples:       samples,
+				Blocked:       cfg.AutoBlock,
+				FirstDetected: now,
+				LastDetected:  now,
+			})
+		}
+	}
+	sort.Slice(farms, func(i, j int) bool {
+		if farms[i].Fingerprints != farms[j].Fingerprints {
+			return farms[i].Fingerprints > farms[j].Fingerprints
+		}
+		return farms[i].Kind+farms[i].Key < farms[j].Kind+farms[j].Key
+	})
+
+	if err := fs.saveFarms(ctx, farms); err != nil {
+		return nil, err
+	}
+	log.Printf("Farm detection checked %d fingerprints, found %d farms", len(stored), len(farms))
+	return farms, nil
+}
+
+// saveFarms 写入农场报告，已存在的报告更新统计和最后检测时间，封禁标记只增不减
+// 写入后回填报告的ID、首次检测时间和封禁标记
+func (fs *FingerprintService) saveFarms(ctx context.Context, farms []models.Farm) error {
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i := range farms {
+		f := &farms[i]
+		samples, err := json.Marshal(f.Samples)
+		if err != nil {
+			return err
+		}
+		if err := tx.QueryRowContext(ctx, `
+			INSERT INTO farms (kind, key, fingerprints, user_agents, samples, blocked, first_detected, last_detected)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (kind, key) DO UPDATE SET
+				fingerprints = excluded.fingerprints,
+				user_agents = excluded.user_agents,
+				samples = excluded.samples,
+				blocked = farms.blocked OR excluded.blocked,
+				last_detected = excluded.last_detected
+			RETURNING id, blocked, first_detected`,
+			f.Kind, f.Key, f.Fingerprints, f.UserAgents, string(samples), f.Blocked, f.FirstDetected, f.LastDetected,
+		).Scan(&f.ID, &f.Blocked, &f.FirstDetected); err != nil {
+			return fmt.Errorf("failed to save %s farm %s: %w", f.Kind, f.Key, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetFarms 按最后检测时间倒序返回农场报告
+func (fs *FingerprintService) GetFarms(ctx context.Context, limit int) ([]models.Farm, error) {
+	rows, err := fs.db.Read.QueryContext(ctx, `
+		SELECT id, kind, key, fingerprints, user_agents, samples, blocked, first_detected, last_detected
+		FROM farms ORDER BY last_detected DESC, fingerprints DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	farms := []models.Farm{}
+	for rows.Next() {
+		var f models.Farm
+		var samples string
+		if err := rows.Scan(&f.ID, &f.Kind, &f.Key, &f.Fingerprints, &f.UserAgents, &samples,
+			&f.Blocked, &f.FirstDetected, &f.LastDetected); err != nil {
+			return nil, err
+		}
+		f.Samples = utils.JSONToStringSlice(samples)
+		farms = append(farms, f)
+	}
+	return farms, rows.Err()
+}
+
+// RunFarmDetection 按配置的间隔运行农场检测任务，直到 ctx 结束
+func (fs *FingerprintService) RunFarmDetection(ctx context.Context) {
+	if fs.farms.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(fs.farms.Interval.Std())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !fs.IsLeader() {
+				continue
+			}
+			if _, err := fs.DetectFarms(ctx); err != nil {
+				log.Printf("Farm detection failed: %v", err)
+			}
+		}
+	}
+}
+
+// checkFarmMember 指纹属于已发现的设备农场时给出信号
+// 被封禁的农场直接判定为爬虫，否则只作为加权信号
+func (fs *FingerprintService) checkFarmMember(ctx context.Context, fp *models.Fingerprint) []signal {
+	var best *signal
+	keys := farmKeys(fp)
+	for _, kind := range []string{models.FarmIPRange, models.FarmUARotation, models.FarmCanvasClone} {
+		key, ok := keys[kind]
+		if !ok {
+			continue
+		}
+		var blocked bool
+		err := fs.db.DB.QueryRowContext(ctx,
+			"SELECT blocked FROM farms WHERE kind = ? AND key = ?", kind, key).Scan(&blocked)
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				log.Printf("Failed to query farms: %v", err)
+			}
+			continue
+		}
+		weight := 0.3
+		if blocked {
+			weight = 1.0
+		}
+		if best == nil || weight > best.Weight {
+			best = &signal{
+				Code:   models.ReasonFarmMember,
+				Weight: weight,
+				Reason: fmt.Sprintf("Fingerprint belongs to a detected %s farm", kind),
+			}
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return []signal{*best}
+}
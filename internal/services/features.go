@@ -0,0 +1,164 @@
+package services
+
+import (
+	"browser-detection/internal/config"
+	"browser-detection/internal/detector"
+	"browser-detection/internal/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// featuresSetting settings 表中功能开关的键，值为通过管理API修改过的开关
+const featuresSetting = "features"
+
+// 可在运行时开关的检测功能，默认全部启用；检测器插件的开关为 detector.<名称>
+const (
+	FeatureExpressions = "expressions"
+	FeatureML          = "ml"
+	FeatureOutliers    = "outliers"
+	FeatureThreatIntel = "threat_intel"
+	FeatureAutoBlock   = "auto_block"
+)
+
+// builtinFeatures 检测功能的开关，按此顺序列出
+var builtinFeatures = []string{FeatureExpressions, FeatureML, FeatureOutliers, FeatureThreatIntel, FeatureAutoBlock}
+
+// detectorFeature 检测器插件的开关名称
+func detectorFeature(name string) string {
+	return "detector." + name
+}
+
+// newFeatureDefaults 返回各开关未修改时是否启用：检测功能默认启用，
+// 检测器插件在 detection.detectors.disabled 中时默认停用
+func newFeatureDefaults(cfg config.DetectorsConfig, detectors []detector.Detector) map[string]bool {
+	defaults := make(map[string]bool, len(builtinFeatures)+len(detectors))
+	for _, name := range builtinFeatures {
+		defaults[name] = true
+	}
+	disabled := make(map[string]bool, len(cfg.Disabled))
+	for _, name := range cfg.Disabled {
+		disabled[name] = true
+	}
+	for _, d := range detectors {
+		defaults[detectorFeature(d.Name())] = !disabled[d.Name()]
+	}
+	return defaults
+}
+
+// featureEnabled 判断功能是否启用，未通过管理API修改时使用默认值
+func (fs *FingerprintService) featureEnabled(name string) bool {
+	if overrides := fs.features.Load(); overrides != nil {
+		if enabled, ok := (*overrides)[name]; ok {
+			return enabled
+		}
+	}
+	return fs.featureDefaults[name]
+}
+
+// LoadFeatures 加载持久化的功能开关，忽略已不存在的开关（如移除的检测器）
+func (fs *FingerprintService) LoadFeatures(ctx context.Context) error {
+	var value string
+	err := fs.db.DB.QueryRowContext(ctx,
+		"SELECT value FROM settings WHERE key = ?", featuresSetting).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var stored map[string]bool
+	if err := json.Unmarshal([]byte(value), &stored); err != nil {
+		return fmt.Errorf("invalid stored features: %w", err)
+	}
+	overrides := make(map[string]bool, len(stored))
+	for name, enabled := range stored {
+		if _, ok := fs.featureDefaults[name]; !ok {
+			log.Printf("Ignoring stored feature %q: not available", name)
+			continue
+		}
+		overrides[name] = enabled
+	}
+	fs.features.Store(&overrides)
+	return nil
+}
+
+// Features 返回所有功能开关：检测功能在前，其后是已注册的检测器插件和进程外检测器
+func (fs *FingerprintService) Features() []models.FeatureFlag {
+	names := append([]string{}, builtinFeatures...)
+	for _, d := range fs.detectors {
+		names = append(names, detectorFeature(d.Name()))
+	}
+	flags := make([]models.FeatureFlag, len(names))
+	for i, name := range names {
+		flags[i] = fs.feature(name)
+	}
+	return flags
+}
+
+// feature 返回一个功能开关的状态
+func (fs *FingerprintService) feature(name string) models.FeatureFlag {
+	flag := models.FeatureFlag{Name: name, Default: fs.featureDefaults[name], Enabled: fs.featureEnabled(name)}
+	if overrides := fs.features.Load(); overrides != nil {
+		_, flag.Overridden = (*overrides)[name]
+	}
+	return flag
+}
+
+// SetFeature 启用或停用功能并写入审计记录，立即对之后的提交生效；enabled 为 nil 时恢复默认值。
+// 开关不存在时返回 sql.ErrNoRows
+func (fs *FingerprintService) SetFeature(ctx context.Context, name string, enabled *bool, actor string) (*models.FeatureFlag, error) {
+	if _, ok := fs.featureDefaults[name]; !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	fs.featureMu.Lock()
+	defer fs.featureMu.Unlock()
+	before := fs.feature(name)
+
+	overrides := make(map[string]bool)
+	if current := fs.features.Load(); current != nil {
+		for k, v := range *current {
+			overrides[k] = v
+		}
+	}
+	if enabled == nil {
+		delete(overrides, name)
+	} else {
+		overrides[name] = *enabled
+	}
+
+	value, err := json.Marshal(overrides)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT OR REPLACE INTO settings (key, value, updated_at) VALUES (?, ?, ?)",
+		featuresSetting, string(value), time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to save features: %w", err)
+	}
+	after := models.FeatureFlag{Name: name, Default: before.Default, Enabled: before.Default, Overridden: enabled != nil}
+	if enabled != nil {
+		after.Enabled = *enabled
+	}
+	if err := recordAudit(ctx, tx, actor, "set_feature", name,
+		map[string]bool{"enabled": before.Enabled}, map[string]bool{"enabled": after.Enabled}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	fs.features.Store(&overrides)
+	return &after, nil
+}
@@ -1,177 +1,707 @@
 package services
 
 import (
+	"browser-detection/internal/alerting"
+	"browser-detection/internal/baselines"
+	"browser-detection/internal/config"
+	"browser-detection/internal/counters"
+	"browser-detection/internal/crawlers"
+	"browser-detection/internal/detector"
+	"browser-detection/internal/journal"
+	"browser-detection/internal/ml"
 	"browser-detection/internal/models"
+	"browser-detection/internal/releases"
+	"browser-detection/internal/threatintel"
 	"browser-detection/internal/utils"
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"golang.org/x/sync/singleflight"
 )
 
 // FingerprintService 指纹服务
 type FingerprintService struct {
-	db *utils.Database
+	db               *utils.Database
+	sites            map[string]config.SiteConfig
+	audioBaselines   []config.AudioBaseline
+	noiseMode        string
+	subHasher        *utils.SubHasher
+	export           config.ExportConfig
+	noise            *utils.NoiseSource
+	embedding        config.EmbeddingConfig
+	vectors          *utils.HNSW
+	farms            config.FarmConfig
+	accounts         config.AccountConfig
+	cookies          config.CookieConfig
+	scoreHalfLife    time.Duration
+	stuffing         config.CredentialStuffingConfig
+	autoBlock        []*autoBlockRule
+	blocks           *blockStore
+	tokens           config.TokenConfig
+	anomaly          config.AnomalyConfig
+	alerts           alerting.Notifier
+	cluster          config.ClusterConfig
+	storage          config.StorageConfig
+	quarantine       config.QuarantineConfig
+	breaker          *utils.CircuitBreaker
+	instanceID       string
+	leader           atomic.Bool
+	policyMu         sync.RWMutex
+	policies         map[string]models.SitePolicyOverride
+	thresholdMu      sync.RWMutex
+	thresholds       models.Thresholds
+	tokenMu          sync.RWMutex
+	tokenKeys        []models.TokenKey
+	bufferMu         sync.Mutex
+	journal          *journal.Journal
+	uaRegexesPath    string
+	uaRegexesMu      sync.Mutex
+	uaRegexes        models.UARegexesStatus
+	threatFeeds      []threatFeed
+	threatStaleAfter time.Duration
+	threatMu         sync.Mutex
+	threatIndex      atomic.Pointer[threatintel.Index]
+	ipReputation     config.IPReputationConfig
+	proxy            config.ProxyConfig
+	asnDB            *utils.ASNDatabase
+	hostingASNs      map[int]bool
+	navigation       config.NavigationConfig
+	scraping         config.ScrapingConfig
+	contentPages     *regexp.Regexp
+	concurrency      config.ConcurrencyConfig
+	challenges       config.ChallengeConfig
+	jsProof          config.JSProofConfig
+	canvasCheck      config.CanvasCheckConfig
+	baselinesPath    string
+	baselinesMu      sync.Mutex
+	baselinesStatus  models.BaselinesStatus
+	corpus           atomic.Pointer[baselines.Corpus]
+	timing           config.TimingConfig
+	typing           config.TypingConfig
+	detectors        []detector.Detector
+	configRules      []models.ScoreRule
+	ruleSet          atomic.Pointer[activeRuleSet]
+	rulesMu          sync.Mutex
+	featureDefaults  map[string]bool
+	features         atomic.Pointer[map[string]bool]
+	featureMu        sync.Mutex
+	webhooks         *alerting.Webhooks
+	mlConfig         config.MLConfig
+	mlModel          atomic.Pointer[ml.Model]
+	mlMu             sync.Mutex
+	mlLoadedAt       time.Time
+	mlError          string
+	mlStats          map[string]*mlModelStats
+	outliers         config.OutlierConfig
+	outlierModel     atomic.Pointer[outlierModel]
+	outlierMu        sync.Mutex
+	outlierError     string
+	outlierScored    atomic.Int64
+	outlierFlagged   atomic.Int64
+	releaseConfig    config.BrowserReleasesConfig
+	releases         atomic.Pointer[releases.Table]
+	releasesMu       sync.Mutex
+	releasesUpdated  *time.Time
+	releasesAttempt  time.Time
+	releasesError    string
+	crawlerConfig    config.CrawlersConfig
+	crawlerTable     *crawlers.Table
+	crawlersError    string
+	robotsConfig     config.RobotsConfig
+	robotsMu         sync.Mutex
+	robotsCache      map[string]*robotsEntry
+	accessLogs       config.AccessLogsConfig
+	edge             config.EdgeConfig
+	edgeCache        edgeCache
+	queryCacheCfg    config.QueryCacheConfig
+	queryCache       queryCache
+	replication      config.ReplicationConfig
+	counters         *counters.Counters
+	jobs             singleflight.Group // 离群模型训练、设备农场检测等批处理任务，同一任务同时只运行一次
 }
 
 // NewFingerprintService 创建新的指纹服务
-func NewFingerprintService(db *utils.Database) *FingerprintService {
-	return &FingerprintService{db: db}
+// 子哈希算法在加载配置时已校验
+func NewFingerprintService(db *utils.Database, cfg *config.Config) *FingerprintService {
+	subHasher, err := utils.NewSubHasher(cfg.Hashing.SubHashAlgorithm)
+	if err != nil {
+		log.Printf("Falling back to SHA-256 sub-hashes: %v", err)
+		subHasher, _ = utils.NewSubHasher(utils.HashSHA256)
+	}
+	counterStore, err := counters.New(cfg.Counters)
+	if err != nil {
+		log.Printf("Falling back to local counters: %v", err)
+		counterStore, _ = counters.New(config.CountersConfig{})
+	}
+	webhooks := alerting.NewWebhooks(cfg.Alerting.Timeout.Std())
+	siteMap := make(map[string]config.SiteConfig, len(cfg.Sites))
+	for _, site := range cfg.Sites {
+		siteMap[site.ID] = site
+	}
+	fs := &FingerprintService{
+		db:               db,
+		sites:            siteMap,
+		audioBaselines:   cfg.AudioBaselines,
+		noiseMode:        cfg.Detection.NoiseMode,
+		subHasher:        subHasher,
+		export:           cfg.Export,
+		noise:            utils.NewNoiseSource(cfg.Export.NoiseSecret),
+		embedding:        cfg.Embedding,
+		farms:            cfg.Detection.Farms,
+		accounts:         cfg.Detection.Accounts,
+		cookies:          cfg.Detection.Cookies,
+		scoreHalfLife:    cfg.Detection.ScoreHalfLife.Std(),
+		stuffing:         cfg.Detection.CredentialStuffing,
+		autoBlock:        newAutoBlockRules(cfg.Detection.AutoBlock),
+		blocks:           newBlockStore(cfg.Blocklist),
+		tokens:           cfg.Tokens,
+		anomaly:          cfg.Anomaly,
+		alerts:           alerting.New(cfg.Alerting, webhooks),
+		webhooks:         webhooks,
+		cluster:          cfg.Cluster,
+		storage:          cfg.Storage,
+		quarantine:       cfg.Quarantine,
+		breaker:          utils.NewCircuitBreaker(cfg.Storage.BreakerFailures, cfg.Storage.BreakerCooldown.Std()),
+		instanceID:       instanceID(cfg.Cluster),
+		policies:         make(map[string]models.SitePolicyOverride),
+		thresholds:       DefaultThresholds,
+		uaRegexesPath:    cfg.Detection.UAParser.RegexesPath,
+		uaRegexes:        models.UARegexesStatus{Source: models.UARegexesBuiltin},
+		threatFeeds:      newThreatFeeds(cfg.Detection.ThreatIntel),
+		threatStaleAfter: cfg.Detection.ThreatIntel.StaleAfter.Std(),
+		ipReputation:     cfg.Detection.IPReputation,
+		proxy:            cfg.Detection.Proxy,
+		hostingASNs:      newHostingASNs(cfg.Detection.Proxy.HostingASNs),
+		navigation:       cfg.Detection.Navigation,
+		scraping:         cfg.Detection.Scraping,
+		contentPages:     regexp.MustCompile(cfg.Detection.Scraping.ContentPattern),
+		concurrency:      cfg.Detection.Concurrency,
+		challenges:       cfg.Detection.Challenges,
+		jsProof:          cfg.Detection.JSProof,
+		canvasCheck:      cfg.Detection.CanvasCheck,
+		baselinesPath:    cfg.Detection.Baselines.Path,
+		timing:           cfg.Detection.Timing,
+		typing:           cfg.Detection.Typing,
+		detectors:        newDetectors(cfg.Detection.Detectors),
+		configRules:      configRules(cfg.Detection.Expressions),
+		mlConfig:         cfg.Detection.ML,
+		mlStats:          make(map[string]*mlModelStats),
+		outliers:         cfg.Detection.Outliers,
+		releaseConfig:    cfg.Detection.BrowserReleases,
+		crawlerConfig:    cfg.Detection.Crawlers,
+		crawlerTable:     crawlers.Embedded(),
+		robotsConfig:     cfg.Detection.Robots,
+		robotsCache:      make(map[string]*robotsEntry),
+		accessLogs:       cfg.Detection.AccessLogs,
+		edge:             cfg.Edge,
+		edgeCache:        edgeCache{entries: make(map[string]edgeEntry)},
+		queryCacheCfg:    cfg.QueryCache,
+		queryCache:       queryCache{entries: make(map[string]*queryEntry)},
+		replication:      cfg.Replication,
+		counters:         counterStore,
+	}
+	fs.ruleSet.Store(&activeRuleSet{rules: newExprRules(fs.configRules)})
+	fs.featureDefaults = newFeatureDefaults(cfg.Detection.Detectors, fs.detectors)
+	fs.baselinesStatus = fs.activateBaselines(baselines.Embedded())
+	return fs
 }
 
-// ProcessFingerprint 处理指纹数据
-func (fs *FingerprintService) ProcessFingerprint(req *models.FingerprintRequest, ipAddress string) (*models.FingerprintResponse, error) {
-	// 使用前端提交的指纹哈希，如果没有则生成
-	var fingerprintHash string
-	if req.FingerprintHash != "" {
-		// 使用前端预计算的指纹哈希
-		fingerprintHash = req.FingerprintHash
-		log.Printf("使用前端预计算的指纹哈希: %s", fingerprintHash)
-	} else {
-		// 后端计算指纹哈希（兼容旧版本）
-		fingerprintData := map[string]interface{}{
-			"user_agent":        req.UserAgent,
-			"screen_resolution": req.ScreenResolution,
-			"timezone":          req.Timezone,
-			"language":          req.Language,
-			"platform":          req.Platform,
-			"canvas":            req.Canvas,
-			"webgl":             req.WebGL,
-			"audio":             req.Audio,
-			"fonts":             req.Fonts,
-			"plugins":           req.Plugins,
-			"touch_support":     req.TouchSupport,
-			"cookie_enabled":    req.CookieEnabled,
-			"do_not_track":      req.DoNotTrack,
+// audioBaselinesFor 返回适用于该User Agent的已知真实音频指纹值
+func (fs *FingerprintService) audioBaselinesFor(userAgent string) []float64 {
+	ua := utils.ParseUserAgent(userAgent)
+	var sums []float64
+	for _, set := range fs.audioBaselineSets() {
+		for _, b := range set {
+			if b.Engine == ua.Engine && (b.OS == "" || b.OS == ua.OS) {
+				sums = append(sums, b.Sum)
+			}
+		}
+	}
+	return sums
+}
+
+// sitePolicy 返回站点的检测策略，未知站点使用默认策略
+func (fs *FingerprintService) sitePolicy(siteID string) config.SitePolicy {
+	return fs.sites[siteID].Policy
+}
+
+// hashSecret 返回站点的指纹哈希密钥，未配置时为空
+func (fs *FingerprintService) hashSecret(siteID string) string {
+	return fs.sites[siteID].HashSecret
+}
+
+// ProcessFingerprint 处理指纹数据，开启预写日志时先写入日志，处理返回后标记完成
+// ctx 携带请求期限，数据库调用会在期限到达时中止
+func (fs *FingerprintService) ProcessFingerprint(ctx context.Context, req *models.FingerprintRequest, meta models.RequestMeta) (*models.FingerprintResponse, error) {
+	if meta.ReceivedAt.IsZero() {
+		meta.ReceivedAt = time.Now()
+	}
+	if fs.journal == nil {
+		return fs.processFingerprint(ctx, req, meta)
+	}
+	id, err := fs.journal.Append(submission{ReceivedAt: meta.ReceivedAt, Meta: meta, Request: *req})
+	if err != nil {
+		log.Printf("Failed to write journal: %v", err)
+		return fs.processFingerprint(ctx, req, meta)
+	}
+	defer func() {
+		if err := fs.journal.Done(id); err != nil {
+			log.Printf("Failed to mark journal entry %s done: %v", id, err)
+		}
+	}()
+	return fs.processFingerprint(ctx, req, meta)
+}
+
+// processFingerprint 处理指纹数据，不写预写日志
+// 开启 detection.timing.reject 时，采集时间不合理的提交在写入前以 ErrInvalidTiming 拒绝
+func (fs *FingerprintService) processFingerprint(ctx context.Context, req *models.FingerprintRequest, meta models.RequestMeta) (*models.FingerprintResponse, error) {
+	if meta.ReceivedAt.IsZero() {
+		meta.ReceivedAt = time.Now()
+	}
+	if fs.timing.Reject {
+		if problem := fs.collectionTimingProblem(req, meta.ReceivedAt); problem != "" {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidTiming, problem)
 		}
-		fingerprintHash = utils.GenerateFingerprintHash(fingerprintData)
-		log.Printf("后端计算的指纹哈希: %s", fingerprintHash)
 	}
 
 	// 计算其他哈希值
-	canvasHash := utils.GenerateCanvasHash(req.Canvas)
-	webglHash := utils.GenerateFingerprintHash(map[string]interface{}{"webgl": req.WebGL})
-	audioHash := utils.GenerateFingerprintHash(map[string]interface{}{"audio": req.Audio})
+	canvasHash := fs.subHasher.Canvas(req.Canvas)
+	webglHash := fs.subHasher.Field("webgl", req.WebGL)
+	audioHash := fs.subHasher.Field("audio", req.Audio)
 
 	// 创建指纹记录
 	fingerprint := &models.Fingerprint{
-		FingerprintHash:  fingerprintHash,
-		UserAgent:        req.UserAgent,
-		ScreenResolution: req.ScreenResolution,
-		Timezone:         req.Timezone,
-		Language:         req.Language,
-		Platform:         req.Platform,
-		Canvas:           req.Canvas,
-		CanvasHash:       canvasHash,
-		WebGL:            req.WebGL,
-		WebGLHash:        webglHash,
-		Audio:            req.Audio,
-		AudioHash:        audioHash,
-		Fonts:            utils.StringSliceToJSON(req.Fonts),
-		Plugins:          utils.StringSliceToJSON(req.Plugins),
-		TouchSupport:     req.TouchSupport,
-		CookieEnabled:    req.CookieEnabled,
-		DoNotTrack:       req.DoNotTrack,
-		IPAddress:        ipAddress,
-		CreatedAt:        time.Now(),
-		UpdatedAt:        time.Now(),
-	}
-
-	// 保存或更新指纹
-	if err := fs.saveFingerprint(fingerprint); err != nil {
-		return nil, fmt.Errorf("failed to save fingerprint: %w", err)
+		FingerprintHash:     req.FingerprintHash,
+		UserAgent:           req.UserAgent,
+		ScreenResolution:    req.ScreenResolution,
+		Timezone:            req.Timezone,
+		Language:            req.Language,
+		Platform:            req.Platform,
+		Canvas:              req.Canvas,
+		CanvasHash:          canvasHash,
+		WebGL:               req.WebGL,
+		WebGLHash:           webglHash,
+		Audio:               req.Audio,
+		AudioHash:           audioHash,
+		Fonts:               utils.StringSliceToJSON(req.Fonts),
+		Plugins:             utils.StringSliceToJSON(req.Plugins),
+		TouchSupport:        req.TouchSupport,
+		CookieEnabled:       req.CookieEnabled,
+		DoNotTrack:          req.DoNotTrack,
+		IPAddress:           meta.IPAddress,
+		SiteID:              meta.SiteID,
+		VisitorID:           meta.VisitorID,
+		Country:             meta.Country,
+		AcceptLanguage:      meta.AcceptLanguage,
+		IPTTL:               meta.IPTTL,
+		TCPRTT:              meta.TCPRTT,
+		PageURL:             meta.PageURL,
+		ClientHintsUA:       meta.ClientHintsUA,
+		ReceivedAt:          meta.ReceivedAt,
+		WebRTCLocalIPs:      utils.StringSliceToJSON(req.WebRTCLocalIPs),
+		WebRTCPublicIPs:     utils.StringSliceToJSON(req.WebRTCPublicIPs),
+		HardwareConcurrency: req.HardwareConcurrency,
+		DeviceMemory:        req.DeviceMemory,
+		MathHash:            utils.GenerateListHash(req.Math),
+		FontMetricsHash:     fontMetricsHash(req.FontMetrics),
+		Extensions:          utils.StringSliceToJSON(req.Extensions),
+		MissingComponents:   utils.StringSliceToJSON(missingComponents(req)),
+		WebGLParams:         webglParamsJSON(req.WebGLParams),
+		GPUFamily:           utils.GPUFamily(webglRenderer(req.WebGL)),
+		CreatedAt:           time.Now(),
+		UpdatedAt:           time.Now(),
+	}
+
+	// 媒体设备数量，未采集时记为 -1
+	fingerprint.MediaAudioInputs, fingerprint.MediaAudioOutputs, fingerprint.MediaVideoInputs = -1, -1, -1
+	if req.MediaDevices != nil {
+		fingerprint.MediaAudioInputs = req.MediaDevices.AudioInput
+		fingerprint.MediaAudioOutputs = req.MediaDevices.AudioOutput
+		fingerprint.MediaVideoInputs = req.MediaDevices.VideoInput
+	}
+
+	// 电池与传感器
+	fingerprint.BatteryLevel = -1
+	if req.Battery != nil {
+		fingerprint.BatterySupported = req.Battery.Supported
+		fingerprint.BatteryCharging = req.Battery.Charging
+		if req.Battery.Supported {
+			fingerprint.BatteryLevel = req.Battery.Level
+		}
+	}
+	if req.Sensors != nil {
+		fingerprint.SensorsCollected = true
+		fingerprint.HasAccelerometer = req.Sensors.Accelerometer
+		fingerprint.HasGyroscope = req.Sensors.Gyroscope
+	}
+
+	// 扩展屏幕参数
+	fingerprint.OuterWidth, fingerprint.OuterHeight = -1, -1
+	if req.Screen != nil {
+		fingerprint.ColorDepth = req.Screen.ColorDepth
+		fingerprint.DevicePixelRatio = req.Screen.DevicePixelRatio
+		fingerprint.AvailWidth = req.Screen.AvailWidth
+		fingerprint.AvailHeight = req.Screen.AvailHeight
+		fingerprint.OuterWidth = req.Screen.OuterWidth
+		fingerprint.OuterHeight = req.Screen.OuterHeight
+	}
+
+	// 服务端Canvas图像分析，不依赖客户端上报的噪点检测结果
+	fingerprint.CanvasNoise = -1
+	if stats, err := utils.AnalyzeCanvasImage(req.Canvas); err != nil {
+		log.Printf("Canvas image analysis skipped: %v", err)
+	} else {
+		fingerprint.CanvasPHash = stats.PHash
+		fingerprint.CanvasSimHash = int64(stats.SimHash)
+		fingerprint.CanvasNoise = stats.MaxNoiseRatio()
+	}
+
+	// 服务端音频采样分析，取代客户端上报的音频噪点检测结果
+	fingerprint.AudioNoise = -1
+	if stats := utils.AnalyzeAudioSamples(req.AudioSamples, fs.audioBaselinesFor(req.UserAgent)); stats != nil {
+		fingerprint.AudioSum = stats.Sum
+		fingerprint.AudioNoise = stats.Confidence
+		if len(stats.Findings) > 0 {
+			log.Printf("Audio sample analysis: %s", strings.Join(stats.Findings, "; "))
+		}
+	}
+
+	// 广告拦截诱饵检测
+	fingerprint.AdBlockBlocked = -1
+	if req.ContentBlocking != nil {
+		fingerprint.AdBlockBaits = req.ContentBlocking.Baits
+		fingerprint.AdBlockBlocked = req.ContentBlocking.Blocked
+	}
+
+	// 特性探测位图
+	if req.Features != nil {
+		fingerprint.FeatureBits = int64(req.Features.Bits)
+		fingerprint.FeatureCount = req.Features.Count
+	}
+
+	// 归一化组件哈希（v2），前端未提交指纹哈希时作为主哈希；
+	// 前端哈希未加盐，可被跨部署关联，配置了密钥的站点始终使用服务端的加盐哈希
+	fingerprint.FingerprintVersion = req.FingerprintVersion
+	if fingerprint.FingerprintVersion <= 0 {
+		fingerprint.FingerprintVersion = inferFingerprintVersion(fingerprint)
+	}
+	secret := fs.hashSecret(meta.SiteID)
+	components := hashComponents(fingerprint, secret)
+	fingerprint.HashV2 = combineComponents(components)
+	if fingerprint.FingerprintHash != "" && secret == "" {
+		log.Printf("使用前端预计算的指纹哈希: %s", fingerprint.FingerprintHash)
+	} else {
+		fingerprint.FingerprintHash = fingerprint.HashV2
+		log.Printf("后端计算的指纹哈希: %s", fingerprint.FingerprintHash)
+	}
+
+	// 保存或更新指纹；存储不可用时只返回指纹哈希，分析推迟到存储恢复后再次提交
+	err := fs.withStorage(ctx, func() error {
+		if err := fs.saveFingerprint(ctx, fingerprint); err != nil {
+			return fmt.Errorf("failed to save fingerprint: %w", err)
+		}
+		if err := fs.saveComponents(ctx, fingerprint.FingerprintHash, components); err != nil {
+			return fmt.Errorf("failed to save fingerprint components: %w", err)
+		}
+		if err := fs.saveCanvasIndex(ctx, fingerprint.FingerprintHash, fingerprint.CanvasSimHash); err != nil {
+			return fmt.Errorf("failed to index canvas simhash: %w", err)
+		}
+		return nil
+	})
+	if errors.Is(err, ErrStorageUnavailable) {
+		return fs.processDegraded(fingerprint, req, meta, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if fs.vectors != nil {
+		fs.vectors.Add(fingerprint.FingerprintHash, fingerprintVector(fingerprint))
+	}
+	if err := fs.recordVisitorFingerprint(ctx, fingerprint); err != nil {
+		log.Printf("Failed to record visitor fingerprint: %v", err)
+	}
+	if err := fs.recordFingerprintASN(ctx, fingerprint); err != nil {
+		log.Printf("Failed to record fingerprint ASN: %v", err)
+	}
+	if err := fs.recordNavigation(ctx, fingerprint, req); err != nil {
+		log.Printf("Failed to record navigation: %v", err)
+	}
+	if err := fs.recordSessionActivity(ctx, fingerprint.FingerprintHash, fingerprint.VisitorID, fingerprint.IPAddress, fingerprint.Country); err != nil {
+		log.Printf("Failed to record session activity: %v", err)
 	}
 
 	// 进行分析（传入原始请求以获取噪点检测信息）
-	analysis, err := fs.analyzeFingerprintWithNoise(fingerprint, req)
+	analysis, err := fs.analyzeFingerprintWithNoise(ctx, fingerprint, req)
 	if err != nil {
 		log.Printf("Failed to analyze fingerprint: %v", err)
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("failed to analyze fingerprint: %w", ctx.Err())
+		}
 	}
 
-	return &models.FingerprintResponse{
-		FingerprintHash: fingerprintHash,
+	if err := fs.recordTraffic(ctx, meta.SiteID, analysis != nil && analysis.IsBot); err != nil {
+		log.Printf("Failed to record traffic stats: %v", err)
+	}
+	if err := fs.recordLive(ctx, meta.SiteID, analysis != nil && analysis.IsBot); err != nil {
+		log.Printf("Failed to record live counters: %v", err)
+	}
+	if analysis != nil {
+		if err := fs.recordScore(ctx, meta.SiteID, analysis.BotScore); err != nil {
+			log.Printf("Failed to record score stats: %v", err)
+		}
+	}
+	if err := fs.recordSubmissionReputation(ctx, fingerprint, analysis); err != nil {
+		log.Printf("Failed to record IP reputation: %v", err)
+	}
+	if err := fs.recordSubnetActivity(ctx, fingerprint, analysis); err != nil {
+		log.Printf("Failed to record subnet activity: %v", err)
+	}
+	if err := fs.recordJA4(ctx, meta.SiteID, meta.JA4, analysis != nil && analysis.IsBot); err != nil {
+		log.Printf("Failed to record JA4 stats: %v", err)
+	}
+	if err := fs.applyAutoBlock(ctx, fingerprint, analysis); err != nil {
+		log.Printf("Failed to apply auto-block rules: %v", err)
+	}
+
+	// 分析之后再记录访客的组件历史，漂移检测需要与之前的记录比较
+	if err := fs.recordComponentHistory(ctx, fingerprint, components); err != nil {
+		log.Printf("Failed to record component history: %v", err)
+	}
+
+	override := fs.siteOverride(meta.SiteID)
+	crawler := fs.crawlerTable.Match(fingerprint.UserAgent)
+	resp := &models.FingerprintResponse{
+		FingerprintHash: fingerprint.FingerprintHash,
 		Analysis:        analysis,
+		Challenge:       analysis != nil && needsChallenge(override, analysis.RiskLevel),
+		CountryPolicy:   countryAction(override.CountryPolicy, meta.Country, meta.IPAddress, fingerprint.FingerprintHash),
+		Crawler:         crawler,
+		CrawlerPolicy:   crawlerAction(override.CrawlerPolicy, crawler),
 		Success:         true,
-	}, nil
+	}
+	if resp.CountryPolicy == models.ActionChallenge || resp.CrawlerPolicy == models.ActionChallenge {
+		resp.Challenge = true
+	}
+	if err := fs.recordCrawler(ctx, meta.SiteID, crawler, resp.CrawlerPolicy); err != nil {
+		log.Printf("Failed to record crawler stats: %v", err)
+	}
+	if _, _, err := fs.auditRobots(ctx, meta.SiteID, crawler, fingerprintPage(fingerprint, req)); err != nil {
+		log.Printf("Failed to audit robots.txt compliance: %v", err)
+	}
+	if resp.Challenge {
+		var reasonCodes []string
+		if analysis != nil {
+			reasonCodes = utils.JSONToStringSlice(analysis.ReasonCodes)
+		}
+		if err := fs.recordChallengeIssued(ctx, meta.SiteID, fingerprint.FingerprintHash, meta.IPAddress, reasonCodes); err != nil {
+			log.Printf("Failed to record challenge: %v", err)
+		}
+	}
+	if analysis != nil && !analysis.IsBot && analysis.RiskLevel == "LOW" && !resp.Challenge && resp.CountryPolicy == "" &&
+		resp.CrawlerPolicy != models.ActionDeny {
+		if resp.VisitorToken, err = fs.issueVisitorToken(fingerprint.FingerprintHash, meta); err != nil {
+			log.Printf("Failed to issue visitor token: %v", err)
+		}
+	}
+	return resp, nil
 }
 
-// saveFingerprint 保存指纹到数据库
-func (fs *FingerprintService) saveFingerprint(fp *models.Fingerprint) error {
-	query := `
-		INSERT OR REPLACE INTO fingerprints (
-			fingerprint_hash, user_agent, screen_resolution, timezone, language, platform,
-			canvas, canvas_hash, webgl, webgl_hash, audio, audio_hash, fonts, plugins,
-			touch_support, cookie_enabled, do_not_track, ip_address, created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-
-	_, err := fs.db.DB.Exec(query,
-		fp.FingerprintHash, fp.UserAgent, fp.ScreenResolution, fp.Timezone, fp.Language, fp.Platform,
-		fp.Canvas, fp.CanvasHash, fp.WebGL, fp.WebGLHash, fp.Audio, fp.AudioHash, fp.Fonts, fp.Plugins,
-		fp.TouchSupport, fp.CookieEnabled, fp.DoNotTrack, fp.IPAddress, fp.CreatedAt, fp.UpdatedAt,
-	)
+// fontMetricsHash 生成字体渲染尺寸的哈希
+// 尺寸保留两位小数，消除不同缩放下的浮点误差
+func fontMetricsHash(metrics []models.FontMetric) string {
+	parts := make([]string, len(metrics))
+	for i, m := range metrics {
+		parts[i] = fmt.Sprintf("%s:%.2f:%.2f", m.Font, m.Width, m.Height)
+	}
+	return utils.GenerateListHash(parts)
+}
 
-	return err
+// fingerprintField 指纹表的列名及对应的结构体字段指针，写入和读取共用同一份列表
+type fingerprintField struct {
+	column string
+	ptr    interface{}
 }
 
-// analyzeFingerprintWithNoise 分析指纹并生成分析结果（包含噪点检测）
-func (fs *FingerprintService) analyzeFingerprintWithNoise(fp *models.Fingerprint, req *models.FingerprintRequest) (*models.Analysis, error) {
-	// 计算唯一性评分
-	uniquenessScore := fs.calculateUniquenessScore(fp)
+// fingerprintFields 返回指纹记录的全部列（不含自增ID）
+func fingerprintFields(fp *models.Fingerprint) []fingerprintField {
+	return []fingerprintField{
+		{"fingerprint_hash", &fp.FingerprintHash},
+		{"user_agent", &fp.UserAgent},
+		{"screen_resolution", &fp.ScreenResolution},
+		{"timezone", &fp.Timezone},
+		{"language", &fp.Language},
+		{"platform", &fp.Platform},
+		{"canvas", &fp.Canvas},
+		{"canvas_hash", &fp.CanvasHash},
+		{"webgl", &fp.WebGL},
+		{"webgl_hash", &fp.WebGLHash},
+		{"audio", &fp.Audio},
+		{"audio_hash", &fp.AudioHash},
+		{"fonts", &fp.Fonts},
+		{"plugins", &fp.Plugins},
+		{"touch_support", &fp.TouchSupport},
+		{"cookie_enabled", &fp.CookieEnabled},
+		{"do_not_track", &fp.DoNotTrack},
+		{"ip_address", &fp.IPAddress},
+		{"webrtc_local_ips", &fp.WebRTCLocalIPs},
+		{"webrtc_public_ips", &fp.WebRTCPublicIPs},
+		{"hardware_concurrency", &fp.HardwareConcurrency},
+		{"device_memory", &fp.DeviceMemory},
+		{"media_audio_inputs", &fp.MediaAudioInputs},
+		{"media_audio_outputs", &fp.MediaAudioOutputs},
+		{"media_video_inputs", &fp.MediaVideoInputs},
+		{"battery_supported", &fp.BatterySupported},
+		{"battery_charging", &fp.BatteryCharging},
+		{"battery_level", &fp.BatteryLevel},
+		{"sensors_collected", &fp.SensorsCollected},
+		{"has_accelerometer", &fp.HasAccelerometer},
+		{"has_gyroscope", &fp.HasGyroscope},
+		{"color_depth", &fp.ColorDepth},
+		{"device_pixel_ratio", &fp.DevicePixelRatio},
+		{"avail_width", &fp.AvailWidth},
+		{"avail_height", &fp.AvailHeight},
+		{"outer_width", &fp.OuterWidth},
+		{"outer_height", &fp.OuterHeight},
+		{"math_hash", &fp.MathHash},
+		{"feature_bits", &fp.FeatureBits},
+		{"feature_count", &fp.FeatureCount},
+		{"font_metrics_hash", &fp.FontMetricsHash},
+		{"adblock_baits", &fp.AdBlockBaits},
+		{"adblock_blocked", &fp.AdBlockBlocked},
+		{"extensions", &fp.Extensions},
+		{"site_id", &fp.SiteID},
+		{"canvas_phash", &fp.CanvasPHash},
+		{"canvas_noise", &fp.CanvasNoise},
+		{"audio_sum", &fp.AudioSum},
+		{"audio_noise", &fp.AudioNoise},
+		{"hash_v2", &fp.HashV2},
+		{"fingerprint_version", &fp.FingerprintVersion},
+		{"visitor_id", &fp.VisitorID},
+		{"country", &fp.Country},
+		{"canvas_simhash", &fp.CanvasSimHash},
+		{"accept_language", &fp.AcceptLanguage},
+		{"missing_components", &fp.MissingComponents},
+		{"webgl_params", &fp.WebGLParams},
+		{"gpu_family", &fp.GPUFamily},
+		{"created_at", &fp.CreatedAt},
+		{"updated_at", &fp.UpdatedAt},
+	}
+}
 
-	// 计算爬虫评分（包含噪点检测）
-	botScore := fs.calculateBotScoreWithNoise(fp, req)
+// saveFingerprint 保存指纹到数据库
+func (fs *FingerprintService) saveFingerprint(ctx context.Context, fp *models.Fingerprint) error {
+	fields := fingerprintFields(fp)
+	columns := make([]string, len(fields))
+	placeholders := make([]string, len(fields))
+	args := make([]interface{}, len(fields))
+	for i, f := range fields {
+		columns[i] = f.column
+		placeholders[i] = "?"
+		args[i] = f.ptr
+	}
 
-	// 确定风险等级
-	riskLevel := fs.calculateRiskLevel(uniquenessScore, botScore)
+	query := fmt.Sprintf("INSERT OR REPLACE INTO fingerprints (%s) VALUES (%s)",
+		strings.Join(columns, ", "), strings.Join(placeholders, ", "))
 
-	// 判断是否为爬虫
-	isBot := botScore > 0.7
+	_, err := fs.db.DB.ExecContext(ctx, query, args...)
+	return err
+}
 
-	// 生成检测原因（包含噪点检测）
-	reasons := fs.generateReasonsWithNoise(fp, req, botScore, uniquenessScore)
+// analyzeFingerprintWithNoise 分析指纹并生成分析结果（包含噪点检测）
+func (fs *FingerprintService) analyzeFingerprintWithNoise(ctx context.Context, fp *models.Fingerprint, req *models.FingerprintRequest) (*models.Analysis, error) {
+	analysis := fs.scoreFingerprint(fp, req, fs.evaluateSignals(ctx, fp, req), fs.rulesFor(fp.SiteID), func(column, value string) int {
+		return fs.componentVariants(ctx, fp, column, value)
+	})
 
 	// 检查是否已存在分析记录
-	var visitCount int
 	var lastSeen time.Time
-	err := fs.db.DB.QueryRow("SELECT visit_count, last_seen FROM analysis WHERE fingerprint_hash = ?", fp.FingerprintHash).Scan(&visitCount, &lastSeen)
+	err := fs.db.DB.QueryRowContext(ctx, "SELECT visit_count, last_seen FROM analysis WHERE fingerprint_hash = ?", fp.FingerprintHash).Scan(&analysis.VisitCount, &lastSeen)
 	if err != nil && err != sql.ErrNoRows {
 		return nil, err
 	}
-
-	if err == sql.ErrNoRows {
-		// 新记录
-		visitCount = 1
-		lastSeen = time.Now()
-	} else {
+	if err == nil {
 		// 更新访问次数
-		visitCount++
-		lastSeen = time.Now()
-	}
-
-	analysis := &models.Analysis{
-		FingerprintHash: fp.FingerprintHash,
-		UniquenessScore: uniquenessScore,
-		BotScore:        botScore,
-		RiskLevel:       riskLevel,
-		IsBot:           isBot,
-		Reasons:         utils.StringSliceToJSON(reasons),
-		VisitCount:      visitCount,
-		LastSeen:        lastSeen,
-		CreatedAt:       time.Now(),
-		UpdatedAt:       time.Now(),
+		analysis.VisitCount++
 	}
 
 	// 保存分析结果
-	if err := fs.saveAnalysis(analysis); err != nil {
+	if err := fs.saveAnalysis(ctx, analysis); err != nil {
 		return nil, err
 	}
 
 	return analysis, nil
 }
 
+// scoreFingerprint 按检测信号和评分规则计算评分并生成本次访问的分析结果，不访问数据库
+// variants 用于验证模式下核对客户端的噪点结论
+func (fs *FingerprintService) scoreFingerprint(fp *models.Fingerprint, req *models.FingerprintRequest, signals []signal, rules scoringRules, variants func(column, value string) int) *models.Analysis {
+	// 计算唯一性评分
+	uniquenessScore := fs.calculateUniquenessScore(fp)
+
+	// 识别隐私浏览器，其随机化特征不按爬虫计分
+	privacyBrowser := detectPrivacyBrowser(fp, req, variants)
+	privacyMode := privacyBrowser != ""
+
+	// 无监督离群检测，分数和解释单独记录
+	outlierScore, outlierSignals, outlierFeatures := fs.scoreOutlier(fp)
+	signals = append(signals, outlierSignals...)
+
+	// 按站点的规则权重覆盖调整检测信号
+	signals = applyPrivacyMode(signals, privacyBrowser)
+	signals = applyRuleWeights(signals, rules.override.RuleWeights)
+
+	// 验证模式下，未经服务端确认的客户端噪点结论不参与评分
+	if fs.noiseMode == config.NoiseModeVerify {
+		req = verifyNoiseClaims(fp, req, variants)
+	}
+
+	// 计算爬虫评分（包含噪点检测）
+	botScore := fs.calculateBotScoreWithNoise(fp, req, signals, privacyMode)
+
+	// 与机器学习模型的概率混合
+	botScore, mlScore, mlModel := fs.blendMLScore(fp, signals, botScore)
+
+	// 确定风险等级
+	riskLevel := riskLevelFor(rules.thresholds, botScore)
+
+	// 判断是否为爬虫
+	isBot := botScore > rules.botThreshold()
+
+	// 生成检测原因（包含噪点检测）
+	reasons := fs.generateReasonsWithNoise(fp, req, signals, privacyMode, botScore, uniquenessScore)
+
+	now := time.Now()
+	return &models.Analysis{
+		FingerprintHash:   fp.FingerprintHash,
+		UniquenessScore:   uniquenessScore,
+		BotScore:          botScore,
+		RiskLevel:         riskLevel,
+		IsBot:             isBot,
+		Reasons:           utils.StringSliceToJSON(reasons),
+		ReasonCodes:       utils.StringSliceToJSON(signalCodes(signals)),
+		PrivacyMode:       privacyMode,
+		EmulatorSuspected: emulatorSuspected(signals),
+		MLScore:           mlScore,
+		MLModel:           mlModel,
+		OutlierScore:      outlierScore,
+		OutlierFeatures:   outlierFeaturesJSON(outlierFeatures),
+		VisitCount:        1,
+		LastSeen:          now,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+}
+
 // analyzeFingerprint 分析指纹并生成分析结果
-func (fs *FingerprintService) analyzeFingerprint(fp *models.Fingerprint) (*models.Analysis, error) {
+func (fs *FingerprintService) analyzeFingerprint(ctx context.Context, fp *models.Fingerprint) (*models.Analysis, error) {
 	// 计算唯一性评分
 	uniquenessScore := fs.calculateUniquenessScore(fp)
 
@@ -182,7 +712,7 @@ func (fs *FingerprintService) analyzeFingerprint(fp *models.Fingerprint) (*model
 	riskLevel := fs.calculateRiskLevel(uniquenessScore, botScore)
 
 	// 判断是否为爬虫
-	isBot := botScore > 0.7
+	isBot := botScore > fs.Thresholds().BotScore
 
 	// 生成检测原因
 	reasons := fs.generateReasons(fp, botScore, uniquenessScore)
@@ -190,7 +720,7 @@ func (fs *FingerprintService) analyzeFingerprint(fp *models.Fingerprint) (*model
 	// 检查是否已存在分析记录
 	var visitCount int
 	var lastSeen time.Time
-	err := fs.db.DB.QueryRow("SELECT visit_count, last_seen FROM analysis WHERE fingerprint_hash = ?", fp.FingerprintHash).Scan(&visitCount, &lastSeen)
+	err := fs.db.DB.QueryRowContext(ctx, "SELECT visit_count, last_seen FROM analysis WHERE fingerprint_hash = ?", fp.FingerprintHash).Scan(&visitCount, &lastSeen)
 	if err != nil && err != sql.ErrNoRows {
 		return nil, err
 	}
@@ -212,6 +742,7 @@ func (fs *FingerprintService) analyzeFingerprint(fp *models.Fingerprint) (*model
 		RiskLevel:       riskLevel,
 		IsBot:           isBot,
 		Reasons:         utils.StringSliceToJSON(reasons),
+		ReasonCodes:     "[]",
 		VisitCount:      visitCount,
 		LastSeen:        lastSeen,
 		CreatedAt:       time.Now(),
@@ -219,7 +750,7 @@ func (fs *FingerprintService) analyzeFingerprint(fp *models.Fingerprint) (*model
 	}
 
 	// 保存分析结果
-	if err := fs.saveAnalysis(analysis); err != nil {
+	if err := fs.saveAnalysis(ctx, analysis); err != nil {
 		return nil, err
 	}
 
@@ -263,6 +794,23 @@ func (fs *FingerprintService) calculateUniquenessScore(fp *models.Fingerprint) f
 		score += 0.1
 	}
 
+	// 广告拦截与扩展痕迹 各 0.05
+	if fp.AdBlockBlocked > 0 {
+		score += 0.05
+	}
+	if len(utils.JSONToStringSlice(fp.Extensions)) > 0 {
+		score += 0.05
+	}
+
+	// 媒体设备数量 权重 0.05：麦克风、扬声器和摄像头的数量组合因设备而异
+	if fp.MediaAudioInputs >= 0 {
+		score += 0.05
+	}
+
+	if score > 1.0 {
+		score = 1.0
+	}
+
 	return score
 }
 
@@ -285,25 +833,22 @@ func (fs *FingerprintService) calculateBotScore(fp *models.Fingerprint) float64
 		score += 0.1
 	}
 
+	// 未提交的组件由 components_missing 信号计分，这里不重复计入
+	missing := componentMissing(fp)
+
 	// 检查Canvas指纹异常
-	if len(fp.Canvas) < 100 || len(fp.Canvas) > 10000 {
+	if !missing["canvas"] && (len(fp.Canvas) < 100 || len(fp.Canvas) > 10000) {
 		score += 0.2
 	}
 
 	// 检查WebGL支持
-	if fp.WebGL == "" || fp.WebGL == "undefined" {
+	if !missing["webgl"] && (fp.WebGL == "" || fp.WebGL == "undefined") {
 		score += 0.15
 	}
 
 	// 检查字体数量异常
 	fonts := utils.JSONToStringSlice(fp.Fonts)
-	if len(fonts) < 5 || len(fonts) > 200 {
-		score += 0.1
-	}
-
-	// 检查插件数量异常
-	plugins := utils.JSONToStringSlice(fp.Plugins)
-	if len(plugins) == 0 || len(plugins) > 50 {
+	if !missing["fonts"] && (len(fonts) < 5 || len(fonts) > 200) {
 		score += 0.1
 	}
 
@@ -321,9 +866,25 @@ func (fs *FingerprintService) calculateBotScore(fp *models.Fingerprint) float64
 }
 
 // calculateBotScoreWithNoise 计算爬虫评分（包含噪点检测）
-func (fs *FingerprintService) calculateBotScoreWithNoise(fp *models.Fingerprint, req *models.FingerprintRequest) float64 {
+// 隐私浏览器的噪点来自浏览器自身的随机化，不计分
+func (fs *FingerprintService) calculateBotScoreWithNoise(fp *models.Fingerprint, req *models.FingerprintRequest, signals []signal, privacyMode bool) float64 {
 	score := fs.calculateBotScore(fp)
 
+	// 扩展检测信号
+	for _, sig := range signals {
+		score += sig.Weight
+	}
+
+	if privacyMode {
+		if score > 1.0 {
+			score = 1.0
+		}
+		if score < 0 {
+			score = 0
+		}
+		return score
+	}
+
 	// 检查Canvas噪点
 	if req.CanvasNoiseDetection != nil && req.CanvasNoiseDetection.HasNoise {
 		switch req.CanvasNoiseDetection.Type {
@@ -346,27 +907,35 @@ func (fs *FingerprintService) calculateBotScoreWithNoise(fp *models.Fingerprint,
 		}
 	}
 
-	// 检查Audio噪点
-	if req.AudioNoiseDetection != nil && req.AudioNoiseDetection.HasNoise {
+	// 检查Audio噪点（服务端已分析原始采样时不采信客户端结果）
+	if fp.AudioNoise < 0 && req.AudioNoiseDetection != nil && req.AudioNoiseDetection.HasNoise {
 		switch req.AudioNoiseDetection.Type {
 		case "audio_anomaly":
 			score += 0.2 * req.AudioNoiseDetection.Confidence
 		}
 	}
 
-	// 限制评分范围
+	// 限制评分范围，检测器插件和表达式规则的负权重信号不会使评分低于0
 	if score > 1.0 {
 		score = 1.0
 	}
+	if score < 0 {
+		score = 0
+	}
 
 	return score
 }
 
 // calculateRiskLevel 计算风险等级
 func (fs *FingerprintService) calculateRiskLevel(uniquenessScore, botScore float64) string {
-	if botScore > 0.7 {
+	return riskLevelFor(fs.Thresholds(), botScore)
+}
+
+// riskLevelFor 按给定阈值计算风险等级
+func riskLevelFor(thresholds models.Thresholds, botScore float64) string {
+	if botScore > thresholds.RiskHigh {
 		return "HIGH"
-	} else if botScore > 0.4 {
+	} else if botScore > thresholds.RiskMedium {
 		return "MEDIUM"
 	} else {
 		return "LOW"
@@ -374,9 +943,18 @@ func (fs *FingerprintService) calculateRiskLevel(uniquenessScore, botScore float
 }
 
 // generateReasonsWithNoise 生成检测原因（包含噪点检测）
-func (fs *FingerprintService) generateReasonsWithNoise(fp *models.Fingerprint, req *models.FingerprintRequest, botScore, uniquenessScore float64) []string {
+func (fs *FingerprintService) generateReasonsWithNoise(fp *models.Fingerprint, req *models.FingerprintRequest, signals []signal, privacyMode bool, botScore, uniquenessScore float64) []string {
 	reasons := fs.generateReasons(fp, botScore, uniquenessScore)
 
+	// 扩展检测信号的原因
+	for _, sig := range signals {
+		reasons = append(reasons, sig.Reason)
+	}
+
+	if privacyMode {
+		return reasons
+	}
+
 	// 添加噪点检测相关的原因
 	if req.CanvasNoiseDetection != nil && req.CanvasNoiseDetection.HasNoise {
 		switch req.CanvasNoiseDetection.Type {
@@ -402,7 +980,7 @@ func (fs *FingerprintService) generateReasonsWithNoise(fp *models.Fingerprint, r
 		}
 	}
 
-	if req.AudioNoiseDetection != nil && req.AudioNoiseDetection.HasNoise {
+	if fp.AudioNoise < 0 && req.AudioNoiseDetection != nil && req.AudioNoiseDetection.HasNoise {
 		switch req.AudioNoiseDetection.Type {
 		case "audio_anomaly":
 			reasons = append(reasons, "Audio fingerprint anomaly detected")
@@ -427,7 +1005,9 @@ func (fs *FingerprintService) generateReasons(fp *models.Fingerprint, botScore,
 		}
 	}
 
-	if len(fp.Canvas) < 100 {
+	missing := componentMissing(fp)
+
+	if !missing["canvas"] && len(fp.Canvas) < 100 {
 		reasons = append(reasons, "Canvas fingerprint too short")
 	}
 
@@ -435,12 +1015,12 @@ func (fs *FingerprintService) generateReasons(fp *models.Fingerprint, botScore,
 		reasons = append(reasons, "Canvas fingerprint too long (possible noise injection)")
 	}
 
-	if fp.WebGL == "" || fp.WebGL == "undefined" {
+	if !missing["webgl"] && (fp.WebGL == "" || fp.WebGL == "undefined") {
 		reasons = append(reasons, "WebGL not supported or disabled")
 	}
 
 	fonts := utils.JSONToStringSlice(fp.Fonts)
-	if len(fonts) < 5 {
+	if !missing["fonts"] && len(fonts) < 5 {
 		reasons = append(reasons, "Too few fonts detected")
 	}
 
@@ -448,11 +1028,6 @@ func (fs *FingerprintService) generateReasons(fp *models.Fingerprint, botScore,
 		reasons = append(reasons, "Too many fonts detected")
 	}
 
-	plugins := utils.JSONToStringSlice(fp.Plugins)
-	if len(plugins) == 0 {
-		reasons = append(reasons, "No plugins detected")
-	}
-
 	if fp.ScreenResolution == "0x0" || fp.ScreenResolution == "" {
 		reasons = append(reasons, "Invalid screen resolution")
 	}
@@ -465,39 +1040,46 @@ func (fs *FingerprintService) generateReasons(fp *models.Fingerprint, botScore,
 }
 
 // saveAnalysis 保存分析结果
-func (fs *FingerprintService) saveAnalysis(analysis *models.Analysis) error {
+func (fs *FingerprintService) saveAnalysis(ctx context.Context, analysis *models.Analysis) error {
 	query := `
 		INSERT OR REPLACE INTO analysis (
-			fingerprint_hash, uniqueness_score, bot_score, risk_level, is_bot, reasons,
-			visit_count, last_seen, created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+			fingerprint_hash, uniqueness_score, bot_score, risk_level, is_bot, reasons, reason_codes,
+			privacy_mode, emulator_suspected, visit_count, last_seen, created_at, updated_at, ml_score, ml_model,
+			outlier_score, outlier_features
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
-	_, err := fs.db.DB.Exec(query,
+	_, err := fs.db.DB.ExecContext(ctx, query,
 		analysis.FingerprintHash, analysis.UniquenessScore, analysis.BotScore, analysis.RiskLevel,
-		analysis.IsBot, analysis.Reasons, analysis.VisitCount, analysis.LastSeen,
-		analysis.CreatedAt, analysis.UpdatedAt,
+		analysis.IsBot, analysis.Reasons, analysis.ReasonCodes, analysis.PrivacyMode, analysis.EmulatorSuspected, analysis.VisitCount, analysis.LastSeen,
+		analysis.CreatedAt, analysis.UpdatedAt, analysis.MLScore, analysis.MLModel,
+		analysis.OutlierScore, analysis.OutlierFeatures,
 	)
 
 	return err
 }
 
 // GetAnalysis 获取分析结果
-func (fs *FingerprintService) GetAnalysis(fingerprintHash string) (*models.Analysis, error) {
+func (fs *FingerprintService) GetAnalysis(ctx context.Context, fingerprintHash string) (*models.Analysis, error) {
 	query := `
-		SELECT fingerprint_hash, uniqueness_score, bot_score, risk_level, is_bot, reasons,
-		       visit_count, last_seen, created_at, updated_at
-		FROM analysis WHERE fingerprint_hash = ?`
+		SELECT fingerprint_hash, uniqueness_score, bot_score, risk_level, is_bot, reasons, reason_codes,
+		       privacy_mode, emulator_suspected, visit_count, last_seen, created_at, updated_at, ml_score, ml_model,
+		       outlier_score, outlier_features,
+		       COALESCE((SELECT site_id FROM fingerprints f WHERE f.fingerprint_hash = analysis.fingerprint_hash), '')
+		FROM analysis WHERE fingerprint_hash = ? AND deleted_at IS NULL`
 
 	analysis := &models.Analysis{}
-	err := fs.db.DB.QueryRow(query, fingerprintHash).Scan(
+	var siteID string
+	err := fs.db.DB.QueryRowContext(ctx, query, fingerprintHash).Scan(
 		&analysis.FingerprintHash, &analysis.UniquenessScore, &analysis.BotScore,
-		&analysis.RiskLevel, &analysis.IsBot, &analysis.Reasons,
-		&analysis.VisitCount, &analysis.LastSeen, &analysis.CreatedAt, &analysis.UpdatedAt,
+		&analysis.RiskLevel, &analysis.IsBot, &analysis.Reasons, &analysis.ReasonCodes,
+		&analysis.PrivacyMode, &analysis.EmulatorSuspected, &analysis.VisitCount, &analysis.LastSeen, &analysis.CreatedAt, &analysis.UpdatedAt,
+		&analysis.MLScore, &analysis.MLModel, &analysis.OutlierScore, &analysis.OutlierFeatures, &siteID,
 	)
 
 	if err != nil {
 		return nil, err
 	}
 
+	fs.decayAnalysis(analysis, siteID, time.Now())
 	return analysis, nil
 }
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// subnetStatsWindow 网段活动统计的时间窗口，更早的记录在保留期清理时删除
+const subnetStatsWindow = 24 * time.Hour
+
+// recordSubnetActivity 将本次提交计入来源IP所在网段的每小时提交量，并记录网段内出现的地址和指纹
+func (fs *FingerprintService) recordSubnetActivity(ctx context.Context, fp *models.Fingerprint, analysis *models.Analysis) error {
+	subnet, ok := utils.IPSubnet(fp.IPAddress)
+	if !ok {
+		return nil
+	}
+	now := time.Now()
+	bot := 0
+	if analysis != nil && analysis.IsBot {
+		bot = 1
+	}
+
+	// 配置 Redis 时提交量在多个实例之间合计，否则计入本地数据库
+	if fs.counters.Shared() {
+		key := subnetVelocityKey(subnet.String(), now.Truncate(time.Hour))
+		if _, err := fs.counters.IncrBy(ctx, key+":submissions", 1, subnetStatsWindow+time.Hour); err != nil {
+			return err
+		}
+		if _, err := fs.counters.IncrBy(ctx, key+":bots", int64(bot), subnetStatsWindow+time.Hour); err != nil {
+			return err
+		}
+	}
+
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if !fs.counters.Shared() {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO subnet_velocity (subnet, bucket, submissions, bot_submissions) VALUES (?, ?, 1, ?)
+			ON CONFLICT (subnet, bucket) DO UPDATE SET
+				submissions = submissions + 1,
+				bot_submissions = bot_submissions + excluded.bot_submissions`,
+			subnet.String(), now.Truncate(time.Hour), bot); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO subnet_addresses (subnet, ip, fingerprint_hash, last_seen) VALUES (?, ?, ?, ?)
+		ON CONFLICT (subnet, ip, fingerprint_hash) DO UPDATE SET last_seen = excluded.last_seen`,
+		subnet.String(), utils.NormalizeIP(fp.IPAddress), fp.FingerprintHash, now); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// subnetVelocityKey 网段每小时提交量在计数中的键
+func subnetVelocityKey(subnet string, bucket time.Time) string {
+	return "velocity:" + subnet + ":" + strconv.FormatInt(bucket.Unix(), 10)
+}
+
+// subnetVelocity 返回网段最近1小时和24小时的提交量及24小时内判定为爬虫的提交量，
+// 配置 Redis 时从共享计数读取，否则从本地数据库读取
+func (fs *FingerprintService) subnetVelocity(ctx context.Context, subnet string, now time.Time, stats *models.SubnetStats) error {
+	hour := now.Truncate(time.Hour)
+	if !fs.counters.Shared() {
+		return fs.db.Read.QueryRowContext(ctx, `
+			SELECT COALESCE(SUM(CASE WHEN bucket >= ? THEN submissions ELSE 0 END), 0),
+				COALESCE(SUM(submissions), 0), COALESCE(SUM(bot_submissions), 0)
+			FROM subnet_velocity WHERE subnet = ? AND bucket >= ?`,
+			hour.Add(-time.Hour), subnet, now.Add(-subnetStatsWindow).Truncate(time.Hour)).
+			Scan(&stats.Submissions1h, &stats.Submissions24h, &stats.BotSubmissions24h)
+	}
+
+	// 与数据库查询相同，包括当前小时和之前的24个整点分段
+	buckets := int(subnetStatsWindow/time.Hour) + 1
+	keys := make([]string, 0, 2*buckets)
+	for i := 0; i < buckets; i++ {
+		key := subnetVelocityKey(subnet, hour.Add(-time.Duration(i)*time.Hour))
+		keys = append(keys, key+":submissions", key+":bots")
+	}
+	values, err := fs.counters.Get(ctx, keys...)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < buckets; i++ {
+		submissions, bots := int(values[2*i]), int(values[2*i+1])
+		if i < 2 {
+			stats.Submissions1h += submissions
+		}
+		stats.Submissions24h += submissions
+		stats.BotSubmissions24h += bots
+	}
+	return nil
+}
+
+// purgeSubnetActivity 删除超出统计窗口的网段活动记录
+func (fs *FingerprintService) purgeSubnetActivity(ctx context.Context) error {
+	cutoff := time.Now().Add(-subnetStatsWindow)
+	if _, err := fs.db.DB.ExecContext(ctx,
+		"DELETE FROM subnet_velocity WHERE bucket < ?", cutoff.Truncate(time.Hour)); err != nil {
+		return err
+	}
+	_, err := fs.db.DB.ExecContext(ctx, "DELETE FROM subnet_addresses WHERE last_seen < ?", cutoff)
+	return err
+}
+
+// GetIPProfile 返回IP所在网段的近期活动、该地址本身出现的指纹和网段的信誉
+// IP不合法时返回 ErrInvalidIP，网段没有任何记录时返回 sql.ErrNoRows
+func (fs *FingerprintService) GetIPProfile(ctx context.Context, ip string) (*models.IPProfile, error) {
+	ip = utils.NormalizeIP(ip)
+	subnet, ok := utils.IPSubnet(ip)
+	if !ok {
+		return nil, ErrInvalidIP
+	}
+	profile := &models.IPProfile{IP: ip, Subnet: subnet.String()}
+
+	now := time.Now()
+	stats := &profile.SubnetStats
+	if err := fs.subnetVelocity(ctx, profile.Subnet, now, stats); err != nil {
+		return nil, err
+	}
+
+	since := now.Add(-subnetStatsWindow)
+	if err := fs.db.Read.QueryRowContext(ctx, `
+		SELECT COUNT(DISTINCT ip), COUNT(DISTINCT fingerprint_hash),
+			COUNT(DISTINCT CASE WHEN ip = ? THEN fingerprint_hash END)
+		FROM subnet_addresses WHERE subnet = ? AND last_seen >= ?`,
+		ip, profile.Subnet, since).
+		Scan(&stats.Addresses24h, &stats.Fingerprints24h, &profile.AddressFingerprints24h); err != nil {
+		return nil, err
+	}
+	if stats.Addresses24h > 0 {
+		var lastSeen time.Time
+		if err := fs.db.Read.QueryRowContext(ctx,
+			"SELECT last_seen FROM subnet_addresses WHERE subnet = ? ORDER BY last_seen DESC LIMIT 1",
+			profile.Subnet).Scan(&lastSeen); err != nil {
+			return nil, err
+		}
+		stats.LastSeen = &lastSeen
+	}
+	if profile.AddressFingerprints24h > 0 {
+		var lastSeen time.Time
+		if err := fs.db.Read.QueryRowContext(ctx,
+			"SELECT last_seen FROM subnet_addresses WHERE subnet = ? AND ip = ? ORDER BY last_seen DESC LIMIT 1",
+			profile.Subnet, ip).Scan(&lastSeen); err != nil {
+			return nil, err
+		}
+		profile.AddressLastSeen = &lastSeen
+	}
+
+	rep, err := fs.GetIPReputation(ctx, ip)
+	switch {
+	case err == nil:
+		profile.Reputation = rep
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, err
+	}
+
+	if stats.Submissions24h == 0 && stats.Addresses24h == 0 && profile.Reputation == nil {
+		return nil, sql.ErrNoRows
+	}
+	return profile, nil
+}
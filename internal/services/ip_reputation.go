@@ -0,0 +1,274 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/netip"
+	"time"
+)
+
+// ErrInvalidIP 不是合法的IP地址
+var ErrInvalidIP = errors.New("invalid IP address")
+
+// 各类记录计入IP信誉的证据量：信誉评分 = 1 - e^(-证据量)
+// 一次爬虫判定约0.3，一次人机验证失败约0.45，情报源命中约0.4
+const (
+	reputationBotEvidence       = 0.35
+	reputationChallengeEvidence = 0.6
+	reputationFeedEvidence      = 0.5
+)
+
+// ipReputationUpdate 一次提交或事件对IP信誉记录的更新
+type ipReputationUpdate struct {
+	submission       bool
+	bot              bool
+	challengeFailure bool
+	feedHit          bool
+}
+
+// reputationKey 返回IP信誉记录的键，即 utils.IPSubnet 的聚合网段（IPv4为/24，IPv6为/64）；
+// 回环和内网地址不记录
+func reputationKey(ip string) (string, bool) {
+	subnet, ok := utils.IPSubnet(ip)
+	if !ok {
+		return "", false
+	}
+	if addr := subnet.Addr(); addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() {
+		return "", false
+	}
+	return subnet.String(), true
+}
+
+// reputationScore 按衰减后的计数计算信誉评分
+func reputationScore(rep *models.IPReputation) float64 {
+	evidence := rep.BotDetections*reputationBotEvidence +
+		rep.ChallengeFailures*reputationChallengeEvidence +
+		rep.FeedHits*reputationFeedEvidence
+	return math.Round((1-math.Exp(-evidence))*1e4) / 1e4
+}
+
+// loadIPReputation 读取IP信誉记录并将计数衰减到 now，不存在时返回 sql.ErrNoRows
+func (fs *FingerprintService) loadIPReputation(ctx context.Context, key string, now time.Time) (*models.IPReputation, time.Time, error) {
+	rep := &models.IPReputation{IP: key, ThreatIntel: []models.ThreatIntelHit{}}
+	var firstSeen, lastSeen, updatedAt time.Time
+	var lastBot, lastChallenge, lastFeed sql.NullTime
+	err := fs.db.DB.QueryRowContext(ctx, `
+		SELECT bot_detections, challenge_failures, feed_hits, submissions, first_seen, last_seen,
+			last_bot_at, last_challenge_failure_at, last_feed_hit_at, updated_at
+		FROM ip_reputation WHERE ip = ?`, key).
+		Scan(&rep.BotDetections, &rep.ChallengeFailures, &rep.FeedHits, &rep.Submissions, &firstSeen, &lastSeen,
+			&lastBot, &lastChallenge, &lastFeed, &updatedAt)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	factor := decayFactor(now.Sub(updatedAt), fs.ipReputation.HalfLife.Std())
+	rep.BotDetections = math.Round(rep.BotDetections*factor*1e4) / 1e4
+	rep.ChallengeFailures = math.Round(rep.ChallengeFailures*factor*1e4) / 1e4
+	rep.FeedHits = math.Round(rep.FeedHits*factor*1e4) / 1e4
+	rep.FirstSeen, rep.LastSeen = &firstSeen, &lastSeen
+	if lastBot.Valid {
+		rep.LastBotAt = &lastBot.Time
+	}
+	if lastChallenge.Valid {
+		rep.LastChallengeFailureAt = &lastChallenge.Time
+	}
+	if lastFeed.Valid {
+		rep.LastFeedHitAt = &lastFeed.Time
+	}
+	rep.Score = reputationScore(rep)
+	return rep, updatedAt, nil
+}
+
+// reputationWeight 信誉评分对应的信号权重，未达到 min_score 时为0
+func (fs *FingerprintService) reputationWeight(score float64) float64 {
+	if score < fs.ipReputation.MinScore {
+		return 0
+	}
+	return math.Round(score*fs.ipReputation.Weight*1e4) / 1e4
+}
+
+// checkIPReputation 来源IP的历史信誉达到 min_score 时给出信号，本次提交在评分之后才计入记录
+func (fs *FingerprintService) checkIPReputation(ctx context.Context, fp *models.Fingerprint) []signal {
+	if fs.ipReputation.HalfLife <= 0 {
+		return nil
+	}
+	key, ok := reputationKey(fp.IPAddress)
+	if !ok {
+		return nil
+	}
+	rep, _, err := fs.loadIPReputation(ctx, key, time.Now())
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Failed to query IP reputation: %v", err)
+		}
+		return nil
+	}
+	weight := fs.reputationWeight(rep.Score)
+	if weight == 0 {
+		return nil
+	}
+	return []signal{{
+		Code:   models.ReasonIPReputation,
+		Weight: weight,
+		Reason: fmt.Sprintf("IP %s has a poor reputation (score %.2f: %.1f bot detections, %.1f challenge failures, %.1f feed hits)",
+			key, rep.Score, rep.BotDetections, rep.ChallengeFailures, rep.FeedHits),
+	}}
+}
+
+// recordSubmissionReputation 将本次提交的评分结果计入来源IP的信誉记录
+// 只因IP信誉信号才判定为爬虫的提交不计为爬虫判定，避免信誉评分自我强化
+func (fs *FingerprintService) recordSubmissionReputation(ctx context.Context, fp *models.Fingerprint, analysis *models.Analysis) error {
+	if fs.ipReputation.HalfLife <= 0 || analysis == nil {
+		return nil
+	}
+	codes := utils.JSONToStringSlice(analysis.ReasonCodes)
+	update := ipReputationUpdate{submission: true, bot: analysis.IsBot}
+	for _, code := range codes {
+		switch code {
+		case models.ReasonThreatIntel:
+			update.feedHit = true
+		case models.ReasonIPReputation:
+			if !update.bot {
+				continue
+			}
+			override := fs.siteOverride(fp.SiteID)
+			weight, ok := override.RuleWeights[models.ReasonIPReputation]
+			if !ok {
+				if key, valid := reputationKey(fp.IPAddress); valid {
+					if rep, _, err := fs.loadIPReputation(ctx, key, time.Now()); err == nil {
+						weight = fs.reputationWeight(rep.Score)
+					}
+				}
+			}
+			update.bot = analysis.BotScore-weight > fs.botThreshold(override)
+		}
+	}
+	return fs.recordIPReputation(ctx, fp.IPAddress, update)
+}
+
+// recordIPReputation 衰减并更新IP信誉记录
+// 情报源命中只表示IP当前被列出，计数重置为1而不是累加，避免按流量放大
+func (fs *FingerprintService) recordIPReputation(ctx context.Context, ip string, update ipReputationUpdate) error {
+	if fs.ipReputation.HalfLife <= 0 {
+		return nil
+	}
+	key, ok := reputationKey(ip)
+	if !ok {
+		return nil
+	}
+
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	var bots, challenges, feeds float64
+	var updatedAt time.Time
+	err = tx.QueryRowContext(ctx,
+		"SELECT bot_detections, challenge_failures, feed_hits, updated_at FROM ip_reputation WHERE ip = ?", key).
+		Scan(&bots, &challenges, &feeds, &updatedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	factor := decayFactor(now.Sub(updatedAt), fs.ipReputation.HalfLife.Std())
+	bots, challenges, feeds = bots*factor, challenges*factor, feeds*factor
+
+	var submissions int
+	var lastBot, lastChallenge, lastFeed *time.Time
+	if update.submission {
+		submissions = 1
+	}
+	if update.bot {
+		bots++
+		lastBot = &now
+	}
+	if update.challengeFailure {
+		challenges++
+		lastChallenge = &now
+	}
+	if update.feedHit {
+		feeds = 1
+		lastFeed = &now
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO ip_reputation (ip, bot_detections, challenge_failures, feed_hits, submissions, first_seen, last_seen,
+			last_bot_at, last_challenge_failure_at, last_feed_hit_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (ip) DO UPDATE SET
+			bot_detections = excluded.bot_detections,
+			challenge_failures = excluded.challenge_failures,
+			feed_hits = excluded.feed_hits,
+			submissions = submissions + excluded.submissions,
+			last_seen = excluded.last_seen,
+			last_bot_at = COALESCE(excluded.last_bot_at, last_bot_at),
+			last_challenge_failure_at = COALESCE(excluded.last_challenge_failure_at, last_challenge_failure_at),
+			last_feed_hit_at = COALESCE(excluded.last_feed_hit_at, last_feed_hit_at),
+			updated_at = excluded.updated_at`,
+		key, bots, challenges, feeds, submissions, now, now, lastBot, lastChallenge, lastFeed, now); err != nil {
+		return fmt.Errorf("failed to save IP reputation: %w", err)
+	}
+	// 情报源命中不同步，各区域各自拉取情报源
+	if update.bot || update.challengeFailure {
+		delta := models.ReputationDelta{IP: key, At: now}
+		if update.bot {
+			delta.BotDetections = 1
+		}
+		if update.challengeFailure {
+			delta.ChallengeFailures = 1
+		}
+		if err := fs.logReplication(ctx, tx, models.ReplicationReputation, delta); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// reputationPurgeHalfLives 超过该数量的半衰期没有更新的IP信誉记录已衰减到千分之一以下，可以删除
+const reputationPurgeHalfLives = 10
+
+// purgeIPReputation 删除长期没有更新的IP信誉记录
+func (fs *FingerprintService) purgeIPReputation(ctx context.Context) error {
+	if fs.ipReputation.HalfLife <= 0 {
+		return nil
+	}
+	cutoff := time.Now().Add(-reputationPurgeHalfLives * fs.ipReputation.HalfLife.Std())
+	_, err := fs.db.DB.ExecContext(ctx, "DELETE FROM ip_reputation WHERE updated_at < ?", cutoff)
+	return err
+}
+
+// GetIPReputation 返回IP的历史信誉和当前命中的情报记录
+// IP不合法时返回 ErrInvalidIP，既无历史记录也未被情报源列出时返回 sql.ErrNoRows
+func (fs *FingerprintService) GetIPReputation(ctx context.Context, ip string) (*models.IPReputation, error) {
+	ip = utils.NormalizeIP(ip)
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return nil, ErrInvalidIP
+	}
+	hits := fs.threatIntelHits(addr)
+
+	key, ok := reputationKey(ip)
+	if !ok {
+		key = addr.Unmap().String()
+	}
+	rep, _, err := fs.loadIPReputation(ctx, key, time.Now())
+	if errors.Is(err, sql.ErrNoRows) {
+		if len(hits) == 0 {
+			return nil, sql.ErrNoRows
+		}
+		rep, err = &models.IPReputation{IP: key}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rep.ThreatIntel = hits
+	return rep, nil
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// calibrationBuckets 校准曲线的评分区间数
+const calibrationBuckets = 10
+
+// ErrInvalidLabel 标注值不是 bot 或 human
+var ErrInvalidLabel = errors.New("label must be bot or human")
+
+// SetLabel 标注指纹的真实类别并写入审计记录，指纹不存在或已删除时返回 sql.ErrNoRows
+func (fs *FingerprintService) SetLabel(ctx context.Context, fingerprintHash string, req models.LabelRequest, actor string) (*models.Label, error) {
+	if req.Label != models.LabelBot && req.Label != models.LabelHuman {
+		return nil, ErrInvalidLabel
+	}
+
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRowContext(ctx,
+		"SELECT 1 FROM fingerprints WHERE fingerprint_hash = ? AND deleted_at IS NULL", fingerprintHash).Scan(&exists); err != nil {
+		return nil, err
+	}
+	before, err := getLabel(ctx, tx, fingerprintHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	now := time.Now()
+	label := &models.Label{
+		FingerprintHash: fingerprintHash,
+		Label:           req.Label,
+		Note:            req.Note,
+		Actor:           actor,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if before != nil {
+		label.CreatedAt = before.CreatedAt
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT OR REPLACE INTO labels (fingerprint_hash, label, note, actor, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		label.FingerprintHash, label.Label, label.Note, label.Actor, label.CreatedAt, label.UpdatedAt); err != nil {
+		return nil, fmt.Errorf("failed to save label: %w", err)
+	}
+	if err := recordAudit(ctx, tx, actor, "set_label", fingerprintHash, before, label); err != nil {
+		return nil, err
+	}
+	if err := fs.logReplication(ctx, tx, models.ReplicationLabel, label); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return label, nil
+}
+
+// DeleteLabel 删除指纹的标注并写入审计记录，没有标注时返回 sql.ErrNoRows
+func (fs *FingerprintService) DeleteLabel(ctx context.Context, fingerprintHash, actor string) error {
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getLabel(ctx, tx, fingerprintHash)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM labels WHERE fingerprint_hash = ?", fingerprintHash); err != nil {
+		return err
+	}
+	if err := recordAudit(ctx, tx, actor, "delete_label", fingerprintHash, before, nil); err != nil {
+		return err
+	}
+	deleted := models.ReplicationDelete{Key: fingerprintHash, DeletedAt: time.Now()}
+	if err := fs.saveTombstone(ctx, tx, tombstoneLabel, fingerprintHash, deleted.DeletedAt); err != nil {
+		return err
+	}
+	if err := fs.logReplication(ctx, tx, models.ReplicationUnlabel, deleted); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// getLabel 在事务中读取指纹的标注
+func getLabel(ctx context.Context, tx *sql.Tx, fingerprintHash string) (*models.Label, error) {
+	var l models.Label
+	err := tx.QueryRowContext(ctx,
+		"SELECT fingerprint_hash, label, note, actor, created_at, updated_at FROM labels WHERE fingerprint_hash = ?",
+		fingerprintHash).Scan(&l.FingerprintHash, &l.Label, &l.Note, &l.Actor, &l.CreatedAt, &l.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// QualityReport 以人工标注为真实值评估存储的分析结果：混淆矩阵、评分校准曲线和各规则的精确率/召回率
+// from、to 按分析结果的评分时间过滤，为空时不限；siteID 为空时包含全部站点；结果按 query_cache 缓存
+func (fs *FingerprintService) QualityReport(ctx context.Context, from, to *time.Time, siteID string) (*models.QualityReport, error) {
+	return cachedQuery(ctx, fs, queryKey("quality", from, to, siteID), func(ctx context.Context) (*models.QualityReport, error) {
+		return fs.qualityReport(ctx, from, to, siteID)
+	})
+}
+
+// qualityReport 查询检测质量报告，不使用缓存
+func (fs *FingerprintService) qualityReport(ctx context.Context, from, to *time.Time, siteID string) (*models.QualityReport, error) {
+	query := `
+		SELECT l.label, a.bot_score, a.is_bot, a.reason_codes
+		FROM labels l
+		JOIN analysis a ON a.fingerprint_hash = l.fingerprint_hash
+		JOIN fingerprints f ON f.fingerprint_hash = l.fingerprint_hash
+		WHERE f.deleted_at IS NULL`
+	var args []interface{}
+	if from != nil {
+		query += " AND a.updated_at >= ?"
+		args = append(args, *from)
+	}
+	if to != nil {
+		query += " AND a.updated_at < ?"
+		args = append(args, *to)
+	}
+	if siteID != "" {
+		query += " AND f.site_id = ?"
+		args = append(args, siteID)
+	}
+
+	rows, err := fs.db.Read.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := &models.QualityReport{From: from, To: to, SiteID: siteID, Rules: []models.RuleQuality{}}
+	var buckets [calibrationBuckets]struct {
+		count, bots int
+		sum         float64
+	}
+	rules := make(map[string]*models.RuleQuality)
+	m := &report.Overall
+	squaredError := 0.0
+	for rows.Next() {
+		var label, reasonCodes string
+		var score float64
+		var isBot bool
+		if err := rows.Scan(&label, &score, &isBot, &reasonCodes); err != nil {
+			return nil, err
+		}
+		bot := label == models.LabelBot
+		report.Labeled++
+		if bot {
+			report.Bots++
+		} else {
+			report.Humans++
+		}
+
+		switch {
+		case isBot && bot:
+			m.TruePositives++
+		case isBot && !bot:
+			m.FalsePositives++
+		case !isBot && bot:
+			m.FalseNegatives++
+		default:
+			m.TrueNegatives++
+		}
+
+		b := &buckets[min(int(score*calibrationBuckets), calibrationBuckets-1)]
+		b.count++
+		b.sum += score
+		if bot {
+			b.bots++
+			squaredError += (1 - score) * (1 - score)
+		} else {
+			squaredError += score * score
+		}
+
+		for _, code := range utils.JSONToStringSlice(reasonCodes) {
+			r := rules[code]
+			if r == nil {
+				r = &models.RuleQuality{Code: code}
+				rules[code] = r
+			}
+			r.Fired++
+			if bot {
+				r.TruePositives++
+			} else {
+				r.FalsePositives++
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	m.Precision = ratio(m.TruePositives, m.TruePositives+m.FalsePositives)
+	m.Recall = ratio(m.TruePositives, m.TruePositives+m.FalseNegatives)
+	if m.Precision+m.Recall > 0 {
+		m.F1 = 2 * m.Precision * m.Recall / (m.Precision + m.Recall)
+	}
+	m.Accuracy = ratio(m.TruePositives+m.TrueNegatives, report.Labeled)
+
+	for i, b := range buckets {
+		bucket := models.CalibrationBucket{
+			Min:     float64(i) / calibrationBuckets,
+			Max:     float64(i+1) / calibrationBuckets,
+			Count:   b.count,
+			BotRate: ratio(b.bots, b.count),
+		}
+		if b.count > 0 {
+			bucket.MeanScore = b.sum / float64(b.count)
+			report.CalibrationError += math.Abs(bucket.MeanScore-bucket.BotRate) * float64(b.count) / float64(report.Labeled)
+		}
+		report.Calibration = append(report.Calibration, bucket)
+	}
+	if report.Labeled > 0 {
+		report.Brier = squaredError / float64(report.Labeled)
+	}
+
+	for _, r := range rules {
+		r.Precision = ratio(r.TruePositives, r.Fired)
+		r.Recall = ratio(r.TruePositives, report.Bots)
+		report.Rules = append(report.Rules, *r)
+	}
+	sort.Slice(report.Rules, func(i, j int) bool {
+		if report.Rules[i].Fired != report.Rules[j].Fired {
+			return report.Rules[i].Fired > report.Rules[j].Fired
+		}
+		return report.Rules[i].Code < report.Rules[j].Code
+	})
+
+	if err := fs.challengeQuality(ctx, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// ratio 返回 n/d，d 为0时返回0
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"browser-detection/internal/config"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// jobsLease 后台任务的租约名
+const jobsLease = "background_jobs"
+
+// instanceID 返回配置的实例标识，未配置时使用主机名和进程号
+func instanceID(cfg config.ClusterConfig) string {
+	if cfg.InstanceID != "" {
+		return cfg.InstanceID
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// IsLeader 报告本实例是否应执行后台任务，未开启多实例部署时始终为真
+func (fs *FingerprintService) IsLeader() bool {
+	return !fs.cluster.Enabled || fs.leader.Load()
+}
+
+// acquireLease 取得或续约租约：租约不存在、已过期或本实例持有时成功
+func (fs *FingerprintService) acquireLease(ctx context.Context, name string, now time.Time) (bool, error) {
+	result, err := fs.db.DB.ExecContext(ctx, `
+		INSERT INTO leases (name, holder, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET holder = excluded.holder, expires_at = excluded.expires_at
+		WHERE leases.holder = excluded.holder OR leases.expires_at < ?`,
+		name, fs.instanceID, now.Add(fs.cluster.LeaseTTL.Std()), now)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// releaseLease 释放本实例持有的租约，其他实例无需等待过期即可接管
+func (fs *FingerprintService) releaseLease(ctx context.Context, name string) error {
+	_, err := fs.db.DB.ExecContext(ctx, "DELETE FROM leases WHERE name = ? AND holder = ?", name, fs.instanceID)
+	return err
+}
+
+// RunLeaderElection 开启多实例部署时定期争取并续约后台任务的租约，同时重新加载共享的配置状态，直到 ctx 结束
+func (fs *FingerprintService) RunLeaderElection(ctx context.Context) {
+	if !fs.cluster.Enabled {
+		return
+	}
+	elect := func() {
+		held, err := fs.acquireLease(ctx, jobsLease, time.Now())
+		if err != nil {
+			// 无法续约时按失去租约处理，避免与接管的实例同时执行
+			log.Printf("Failed to acquire lease: %v", err)
+			held = false
+		}
+		if was := fs.leader.Swap(held); was != held {
+			if held {
+				log.Printf("Instance %s is now running background jobs", fs.instanceID)
+			} else {
+				log.Printf("Instance %s stopped running background jobs", fs.instanceID)
+			}
+		}
+	}
+	elect()
+
+	renew := time.NewTicker(fs.cluster.LeaseTTL.Std() / 3)
+	defer renew.Stop()
+	refresh := time.NewTicker(fs.cluster.RefreshInterval.Std())
+	defer refresh.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-renew.C:
+			elect()
+		case <-refresh.C:
+			fs.refreshSharedState(ctx)
+		}
+	}
+}
+
+// StepDown 停止执行后台任务并释放租约，退出前调用使其他实例无需等待租约过期即可接管
+func (fs *FingerprintService) StepDown(ctx context.Context) error {
+	if !fs.leader.Swap(false) {
+		return nil
+	}
+	return fs.releaseLease(ctx, jobsLease)
+}
+
+// refreshSharedState 从数据库重新加载可能被其他实例修改的站点策略、评分阈值、令牌密钥和IP信誉情报
+func (fs *FingerprintService) refreshSharedState(ctx context.Context) {
+	if err := fs.LoadSitePolicies(ctx); err != nil {
+		log.Printf("Failed to reload site policies: %v", err)
+	}
+	if err := fs.LoadThresholds(ctx); err != nil {
+		log.Printf("Failed to reload thresholds: %v", err)
+	}
+	if err := fs.LoadTokenKeys(ctx); err != nil {
+		log.Printf("Failed to reload token keys: %v", err)
+	}
+	if err := fs.LoadThreatIntel(ctx); err != nil {
+		log.Printf("Failed to reload threat intel: %v", err)
+	}
+}
@@ -0,0 +1,358 @@
+package services
+
+import (
+	"browser-detection/internal/ml"
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// mlFeatureVersion 特征向量的版本，特征的含义、顺序或数量变化时递增；
+	// 模型元数据中的 feature_version 须与之一致，避免新旧特征混用
+	mlFeatureVersion = 1
+	// mlSignalBuckets 检测信号代码散列到的维数，新增原因代码不改变特征数
+	mlSignalBuckets = 16
+	// mlSignalCountScale 检测信号个数的缩放上限
+	mlSignalCountScale = 20
+)
+
+// ErrMLNotConfigured 未配置 detection.ml.model
+var ErrMLNotConfigured = errors.New("detection.ml.model is not configured")
+
+// mlModelStats 模型版本在本实例的运行统计
+type mlModelStats struct {
+	predictions int64
+	errors      int64
+	latency     time.Duration
+	probSum     float64
+}
+
+// mlQuality 按标注评估模型概率的计数，概率不低于0.5视为判定为爬虫
+type mlQuality struct {
+	n, tp, fp, tn, fn int
+	brier             float64
+}
+
+// add 计入一个标注样本
+func (q *mlQuality) add(p float64, bot bool) {
+	q.n++
+	truth := 0.0
+	if bot {
+		truth = 1
+	}
+	q.brier += (p - truth) * (p - truth)
+	switch flagged := p >= 0.5; {
+	case flagged && bot:
+		q.tp++
+	case flagged:
+		q.fp++
+	case bot:
+		q.fn++
+	default:
+		q.tn++
+	}
+}
+
+// mlFeatureNames 按顺序返回特征名称，与 mlFeatures 一一对应，用作训练数据的表头
+func mlFeatureNames() []string {
+	names := []string{
+		"screen_width", "screen_height", "device_pixel_ratio", "color_depth", "hardware_concurrency",
+		"device_memory", "font_count", "plugin_count", "touch_support", "cookie_enabled",
+	}
+	for i := 0; i < embeddingBuckets; i++ {
+		names = append(names, "category_"+strconv.Itoa(i))
+	}
+	for bit := 0; bit < 64; bit++ {
+		names = append(names, "canvas_simhash_"+strconv.Itoa(bit))
+	}
+	for bit := 0; bit < embeddingFeatureBits; bit++ {
+		names = append(names, "feature_bit_"+strconv.Itoa(bit))
+	}
+	names = append(names, "signal_count")
+	for i := 0; i < mlSignalBuckets; i++ {
+		names = append(names, "signal_"+strconv.Itoa(i))
+	}
+	return names
+}
+
+// mlFeatures 把指纹和本次的检测信号代码编码为模型的特征向量：
+// 指纹部分与近邻索引的向量相同，信号部分为信号个数和各代码散列桶是否出现。
+// 只使用存储的指纹记录和分析结果中的原因代码，训练数据可由已存储的记录重建
+func mlFeatures(fp *models.Fingerprint, codes []string) []float64 {
+	vec := fingerprintVector(fp)
+	features := make([]float64, 0, len(vec)+1+mlSignalBuckets)
+	for _, v := range vec {
+		features = append(features, float64(v))
+	}
+	features = append(features, float64(min(len(codes), mlSignalCountScale))/mlSignalCountScale)
+
+	buckets := make([]float64, mlSignalBuckets)
+	for _, code := range codes {
+		h := fnv.New32a()
+		h.Write([]byte(code))
+		buckets[h.Sum32()%mlSignalBuckets] = 1
+	}
+	return append(features, buckets...)
+}
+
+// loadMLModel 读取模型文件并检查特征版本和特征数
+func loadMLModel(path string) (*ml.Model, error) {
+	model, err := ml.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if v := model.Metadata["feature_version"]; v != "" && v != strconv.Itoa(mlFeatureVersion) {
+		return nil, fmt.Errorf("model %s was trained on feature version %s, this build extracts version %d", model.Version, v, mlFeatureVersion)
+	}
+	if n := len(mlFeatureNames()); model.InputSize != 0 && model.InputSize != n {
+		return nil, fmt.Errorf("model %s expects %d features, this build extracts %d", model.Version, model.InputSize, n)
+	}
+	return model, nil
+}
+
+// LoadMLModel 启动时加载机器学习评分模型，未配置时不启用
+func (fs *FingerprintService) LoadMLModel() error {
+	if fs.mlConfig.Model == "" {
+		return nil
+	}
+	model, err := loadMLModel(fs.mlConfig.Model)
+	if err != nil {
+		return fmt.Errorf("failed to load ML model %s: %w", fs.mlConfig.Model, err)
+	}
+	fs.activateMLModel(model)
+	log.Printf("Loaded ML model %s from %s (%s, %d features)", model.Version, fs.mlConfig.Model, model.Producer, len(mlFeatureNames()))
+	return nil
+}
+
+// RefreshMLModel 重新读取模型文件，用于上线新版本模型；读取或校验失败时继续使用当前模型并返回错误
+func (fs *FingerprintService) RefreshMLModel(ctx context.Context, actor string) (*models.MLStatus, error) {
+	if fs.mlConfig.Model == "" {
+		return nil, ErrMLNotConfigured
+	}
+	model, err := loadMLModel(fs.mlConfig.Model)
+	if err != nil {
+		fs.mlMu.Lock()
+		fs.mlError = err.Error()
+		fs.mlMu.Unlock()
+		return nil, err
+	}
+
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	var before interface{}
+	if current := fs.mlModel.Load(); current != nil {
+		before = map[string]string{"version": current.Version, "hash": current.Hash}
+	}
+	after := map[string]string{"version": model.Version, "hash": model.Hash}
+	if err := recordAudit(ctx, tx, actor, "refresh_ml_model", fs.mlConfig.Model, before, after); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	fs.activateMLModel(model)
+	return fs.MLStatus(ctx)
+}
+
+// activateMLModel 切换当前模型
+func (fs *FingerprintService) activateMLModel(model *ml.Model) {
+	fs.mlMu.Lock()
+	defer fs.mlMu.Unlock()
+	fs.mlModel.Store(model)
+	fs.mlLoadedAt = time.Now()
+	fs.mlError = ""
+	if fs.mlStats[model.Version] == nil {
+		fs.mlStats[model.Version] = &mlModelStats{}
+	}
+}
+
+// blendMLScore 按当前模型计算爬虫概率，并按 detection.ml.weight 与规则评分混合；
+// 影子模式下只返回概率，评分不变。未加载模型、功能停用或预测失败时返回规则评分，概率为空
+func (fs *FingerprintService) blendMLScore(fp *models.Fingerprint, signals []signal, ruleScore float64) (float64, *float64, string) {
+	model := fs.mlModel.Load()
+	if model == nil || !fs.featureEnabled(FeatureML) {
+		return ruleScore, nil, ""
+	}
+
+	start := time.Now()
+	p, err := model.Predict(mlFeatures(fp, signalCodes(signals)))
+	elapsed := time.Since(start)
+
+	fs.mlMu.Lock()
+	stats := fs.mlStats[model.Version]
+	stats.predictions++
+	stats.latency += elapsed
+	if err != nil {
+		stats.errors++
+	} else {
+		stats.probSum += p
+	}
+	fs.mlMu.Unlock()
+
+	if err != nil {
+		log.Printf("ML model %s failed: %v", model.Version, err)
+		return ruleScore, nil, ""
+	}
+	if fs.mlConfig.Shadow {
+		return ruleScore, &p, model.Version
+	}
+	w := fs.mlConfig.Weight
+	return (1-w)*ruleScore + w*p, &p, model.Version
+}
+
+// MLStatus 返回模型配置、本实例各模型版本的运行统计和按人工标注评估的准确率
+func (fs *FingerprintService) MLStatus(ctx context.Context) (*models.MLStatus, error) {
+	status := &models.MLStatus{
+		Path:           fs.mlConfig.Model,
+		FeatureVersion: mlFeatureVersion,
+		Features:       len(mlFeatureNames()),
+		Weight:         fs.mlConfig.Weight,
+		Shadow:         fs.mlConfig.Shadow,
+		Models:         []models.MLModelStats{},
+	}
+
+	versions := make(map[string]*models.MLModelStats)
+	entry := func(version string) *models.MLModelStats {
+		if versions[version] == nil {
+			versions[version] = &models.MLModelStats{Version: version}
+		}
+		return versions[version]
+	}
+
+	fs.mlMu.Lock()
+	current := fs.mlModel.Load()
+	if current != nil {
+		status.Version = current.Version
+		status.Hash = current.Hash
+		status.Producer = current.Producer
+		loadedAt := fs.mlLoadedAt
+		status.LoadedAt = &loadedAt
+		entry(current.Version).Active = true
+	}
+	status.Error = fs.mlError
+	for version, s := range fs.mlStats {
+		e := entry(version)
+		e.Predictions = s.predictions
+		e.Errors = s.errors
+		if s.predictions > 0 {
+			e.MeanLatencyMs = float64(s.latency) / float64(time.Millisecond) / float64(s.predictions)
+		}
+		if ok := s.predictions - s.errors; ok > 0 {
+			e.MeanProbability = s.probSum / float64(ok)
+		}
+	}
+	fs.mlMu.Unlock()
+
+	rows, err := fs.db.Read.QueryContext(ctx, `
+		SELECT a.ml_model, a.ml_score, l.label
+		FROM labels l
+		JOIN analysis a ON a.fingerprint_hash = l.fingerprint_hash
+		JOIN fingerprints f ON f.fingerprint_hash = l.fingerprint_hash
+		WHERE f.deleted_at IS NULL AND a.ml_score IS NOT NULL AND a.ml_model != ''`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	quality := make(map[string]*mlQuality)
+	for rows.Next() {
+		var version, label string
+		var p float64
+		if err := rows.Scan(&version, &p, &label); err != nil {
+			return nil, err
+		}
+		q := quality[version]
+		if q == nil {
+			q = &mlQuality{}
+			quality[version] = q
+		}
+		q.add(p, label == models.LabelBot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for version, q := range quality {
+		e := entry(version)
+		e.Labeled = q.n
+		e.Precision = ratio(q.tp, q.tp+q.fp)
+		e.Recall = ratio(q.tp, q.tp+q.fn)
+		e.Accuracy = ratio(q.tp+q.tn, q.n)
+		e.Brier = q.brier / float64(q.n)
+	}
+
+	for _, e := range versions {
+		status.Models = append(status.Models, *e)
+	}
+	sort.Slice(status.Models, func(i, j int) bool {
+		if status.Models[i].Active != status.Models[j].Active {
+			return status.Models[i].Active
+		}
+		return status.Models[i].Version < status.Models[j].Version
+	})
+	return status, nil
+}
+
+// ExportMLFeatures 以CSV写出已标注指纹的特征向量作为训练数据：fingerprint_hash、label（爬虫为1）和各特征列，
+// 特征与在线评分时的计算方式相同，返回写出的行数
+func (fs *FingerprintService) ExportMLFeatures(ctx context.Context, w io.Writer) (int, error) {
+	rows, err := fs.db.DB.QueryContext(ctx, `
+		SELECT l.fingerprint_hash, l.label, COALESCE(a.reason_codes, '[]')
+		FROM labels l LEFT JOIN analysis a ON a.fingerprint_hash = l.fingerprint_hash`)
+	if err != nil {
+		return 0, err
+	}
+	labels := make(map[string]string)
+	codes := make(map[string][]string)
+	for rows.Next() {
+		var hash, label, reasonCodes string
+		if err := rows.Scan(&hash, &label, &reasonCodes); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		labels[hash] = label
+		codes[hash] = utils.JSONToStringSlice(reasonCodes)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	stored, err := fs.loadFingerprints(ctx, "deleted_at IS NULL AND fingerprint_hash IN (SELECT fingerprint_hash FROM labels) ORDER BY fingerprint_hash")
+	if err != nil {
+		return 0, err
+	}
+
+	out := csv.NewWriter(w)
+	if err := out.Write(append([]string{"fingerprint_hash", "label"}, mlFeatureNames()...)); err != nil {
+		return 0, err
+	}
+	for _, s := range stored {
+		label := "0"
+		if labels[s.fp.FingerprintHash] == models.LabelBot {
+			label = "1"
+		}
+		record := []string{s.fp.FingerprintHash, label}
+		for _, v := range mlFeatures(&s.fp, codes[s.fp.FingerprintHash]) {
+			record = append(record, strconv.FormatFloat(v, 'g', -1, 64))
+		}
+		if err := out.Write(record); err != nil {
+			return 0, err
+		}
+	}
+	out.Flush()
+	return len(stored), out.Error()
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxNavigationPathLength 保存的页面路径最大长度
+const maxNavigationPathLength = 256
+
+// maxReferrerHosts 来源统计返回的站外来源域名数
+const maxReferrerHosts = 20
+
+// navigationEntry 一次提交的导航上下文
+type navigationEntry struct {
+	pageHost     string
+	pagePath     string
+	referrerHost string
+	// direct document.referrer 为空
+	direct bool
+	// deep 采集页面不是首页
+	deep bool
+}
+
+// navigationFor 由采集端上报的导航上下文和采集页面的请求头得到导航记录，采集端未上报时返回 false
+// 跨域提交时 Referer 通常只有源，页面路径以采集端上报的 location.pathname 为准
+func navigationFor(fp *models.Fingerprint, req *models.FingerprintRequest) (navigationEntry, bool) {
+	if req == nil || req.Navigation == nil {
+		return navigationEntry{}, false
+	}
+	var entry navigationEntry
+	if u, err := url.Parse(fp.PageURL); err == nil {
+		entry.pageHost = strings.ToLower(u.Hostname())
+		entry.pagePath = u.Path
+	}
+	if page := req.Navigation.Page; page != "" {
+		if u, err := url.Parse(page); err == nil {
+			entry.pagePath = u.Path
+		}
+	}
+	if len(entry.pagePath) > maxNavigationPathLength {
+		entry.pagePath = entry.pagePath[:maxNavigationPathLength]
+	}
+	if u, err := url.Parse(req.Navigation.Referrer); err == nil {
+		entry.referrerHost = strings.ToLower(u.Hostname())
+	}
+	entry.direct = entry.referrerHost == ""
+	switch entry.pagePath {
+	case "", "/", "/index.html", "/index.htm", "/index.php":
+	default:
+		entry.deep = true
+	}
+	return entry, true
+}
+
+// recordNavigation 记录本次提交的导航上下文，用于直接访问深层页面的检测和来源统计
+func (fs *FingerprintService) recordNavigation(ctx context.Context, fp *models.Fingerprint, req *models.FingerprintRequest) error {
+	entry, ok := navigationFor(fp, req)
+	if !ok {
+		return nil
+	}
+	var subnet string
+	if prefix, ok := utils.IPSubnet(fp.IPAddress); ok {
+		subnet = prefix.String()
+	}
+	_, err := fs.db.DB.ExecContext(ctx, `
+		INSERT INTO navigation_log (site_id, fingerprint_hash, subnet, page_host, page_path, referrer_host, direct, deep, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		fp.SiteID, fp.FingerprintHash, subnet, entry.pageHost, entry.pagePath, entry.referrerHost,
+		entry.direct, entry.deep, time.Now())
+	return err
+}
+
+// checkDirectDeepHits 本次提交没有来源且采集页面是深层页面时，统计同一指纹和同一网段在窗口内
+// 直接访问的不同深层页面数，任一达到 min_direct_deep 时给出信号；反复刷新同一页面只计一次
+func (fs *FingerprintService) checkDirectDeepHits(ctx context.Context, fp *models.Fingerprint, req *models.FingerprintRequest) []signal {
+	entry, ok := navigationFor(fp, req)
+	if !ok || !entry.direct || !entry.deep {
+		return nil
+	}
+	cfg := fs.navigation
+	since := time.Now().Add(-cfg.Window.Std())
+
+	var byFingerprint, bySubnet int
+	if err := fs.db.DB.QueryRowContext(ctx, `
+		SELECT COUNT(DISTINCT page_path) FROM navigation_log
+		WHERE fingerprint_hash = ? AND direct AND deep AND created_at >= ?`,
+		fp.FingerprintHash, since).Scan(&byFingerprint); err != nil {
+		log.Printf("Failed to query navigation log: %v", err)
+		return nil
+	}
+	if prefix, ok := utils.IPSubnet(fp.IPAddress); ok {
+		if err := fs.db.DB.QueryRowContext(ctx, `
+			SELECT COUNT(DISTINCT page_path) FROM navigation_log
+			WHERE subnet = ? AND direct AND deep AND created_at >= ?`,
+			prefix.String(), since).Scan(&bySubnet); err != nil {
+			log.Printf("Failed to query navigation log: %v", err)
+			return nil
+		}
+	}
+	if byFingerprint < cfg.MinDirectDeep && bySubnet < cfg.MinDirectDeep {
+		return nil
+	}
+	return []signal{{
+		Code:   models.ReasonDirectDeepHits,
+		Weight: cfg.Weight,
+		Reason: fmt.Sprintf("Direct hits to %d deep pages from this fingerprint and %d from its subnet within %s, without a referrer",
+			byFingerprint, bySubnet, cfg.Window.Std()),
+	}}
+}
+
+// purgeNavigation 删除超过保留期的导航记录
+func (fs *FingerprintService) purgeNavigation(ctx context.Context) error {
+	_, err := fs.db.DB.ExecContext(ctx,
+		"DELETE FROM navigation_log WHERE created_at < ?", time.Now().Add(-fs.navigation.Retention.Std()))
+	return err
+}
+
+// ReferrerStats 按时间范围和站点统计采集页面的来源，from、to 为空时不限制；结果按 query_cache 缓存
+func (fs *FingerprintService) ReferrerStats(ctx context.Context, from, to *time.Time, siteID string) (*models.ReferrerStats, error) {
+	return cachedQuery(ctx, fs, queryKey("referrers", from, to, siteID), func(ctx context.Context) (*models.ReferrerStats, error) {
+		return fs.referrerStats(ctx, from, to, siteID)
+	})
+}
+
+// referrerStats 查询来源统计，不使用缓存
+func (fs *FingerprintService) referrerStats(ctx context.Context, from, to *time.Time, siteID string) (*models.ReferrerStats, error) {
+	where := []string{"1 = 1"}
+	var args []interface{}
+	if from != nil {
+		where = append(where, "created_at >= ?")
+		args = append(args, *from)
+	}
+	if to != nil {
+		where = append(where, "created_at < ?")
+		args = append(args, *to)
+	}
+	if siteID != "" {
+		where = append(where, "site_id = ?")
+		args = append(args, siteID)
+	}
+	cond := strings.Join(where, " AND ")
+
+	stats := &models.ReferrerStats{Referrers: []models.ReferrerCount{}}
+	if err := fs.db.Read.QueryRowContext(ctx, `
+		SELECT COUNT(*),
+			COALESCE(SUM(CASE WHEN direct THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN direct AND deep THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN NOT direct AND referrer_host = page_host THEN 1 ELSE 0 END), 0)
+		FROM navigation_log WHERE `+cond, args...).
+		Scan(&stats.Total, &stats.Direct, &stats.DirectDeep, &stats.Internal); err != nil {
+		return nil, err
+	}
+
+	rows, err := fs.db.Read.QueryContext(ctx, `
+		SELECT referrer_host, COUNT(*) AS n FROM navigation_log
+		WHERE `+cond+` AND NOT direct AND referrer_host != page_host
+		GROUP BY referrer_host ORDER BY n DESC, referrer_host LIMIT ?`, append(args, maxReferrerHosts)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r models.ReferrerCount
+		if err := rows.Scan(&r.Host, &r.Count); err != nil {
+			return nil, err
+		}
+		stats.Referrers = append(stats.Referrers, r)
+	}
+	return stats, rows.Err()
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"context"
+	"fmt"
+	"log"
+)
+
+// verifyNoiseClaims 用服务端可验证的证据核对客户端上报的噪点检测结论
+// 返回请求的副本，无法确认的结论被移除；客户端省略结论不影响服务端自身的检测信号
+//   - Canvas：服务端图像分析的噪点比例超过阈值，或同一访客提交过其他Canvas
+//   - WebGL：同一访客提交过其他WebGL结果
+//   - Audio：服务端已分析原始采样时以服务端置信度为准，否则看同一访客是否提交过其他音频结果
+//
+// variants 返回同一访客的其他提交中该组件出现过的不同取值数
+func verifyNoiseClaims(fp *models.Fingerprint, req *models.FingerprintRequest, variants func(column, value string) int) *models.FingerprintRequest {
+	verified := *req
+
+	if claim := req.CanvasNoiseDetection; claim != nil && claim.HasNoise {
+		if fp.CanvasNoise < canvasNoiseThreshold && variants("canvas_hash", fp.CanvasHash) == 0 {
+			log.Printf("Ignoring unverified canvas noise claim for %s", fp.FingerprintHash)
+			verified.CanvasNoiseDetection = nil
+		}
+	}
+
+	if claim := req.WebGLNoiseDetection; claim != nil && claim.HasNoise {
+		if variants("webgl_hash", fp.WebGLHash) == 0 {
+			log.Printf("Ignoring unverified WebGL noise claim for %s", fp.FingerprintHash)
+			verified.WebGLNoiseDetection = nil
+		}
+	}
+
+	if claim := req.AudioNoiseDetection; claim != nil && claim.HasNoise && fp.AudioNoise < 0 {
+		if variants("audio_hash", fp.AudioHash) == 0 {
+			log.Printf("Ignoring unverified audio noise claim for %s", fp.FingerprintHash)
+			verified.AudioNoiseDetection = nil
+		}
+	}
+
+	return &verified
+}
+
+// variantColumns 允许做跨访问一致性比较的列
+var variantColumns = map[string]bool{"canvas_hash": true, "webgl_hash": true, "audio_hash": true}
+
+// componentVariants 统计同一访客（相同IP与User Agent）的其他提交中，该组件出现过多少个不同取值
+// 真实设备的渲染结果是稳定的，存在不同取值说明组件被逐次随机化
+func (fs *FingerprintService) componentVariants(ctx context.Context, fp *models.Fingerprint, column, value string) int {
+	if !variantColumns[column] {
+		return 0
+	}
+
+	query := fmt.Sprintf(`
+		SELECT COUNT(DISTINCT %[1]s) FROM fingerprints
+		WHERE ip_address = ? AND user_agent = ? AND %[1]s != ? AND fingerprint_hash != ?`, column)
+
+	var variants int
+	if err := fs.db.DB.QueryRowContext(ctx, query, fp.IPAddress, fp.UserAgent, value, fp.FingerprintHash).Scan(&variants); err != nil {
+		log.Printf("Failed to query %s variants: %v", column, err)
+		return 0
+	}
+	return variants
+}
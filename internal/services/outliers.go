@@ -0,0 +1,239 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrTooFewOutlierSamples 时间窗口内的指纹不足 min_samples，未训练离群检测模型
+var ErrTooFewOutlierSamples = errors.New("not enough fingerprints to train the outlier model")
+
+// outlierTopFeatures 离群解释中列出的贡献最大的特征数
+const outlierTopFeatures = 3
+
+// outlierNumericFeatures 离群检测的数值特征，顺序与 outlierNumeric 一致
+var outlierNumericFeatures = []string{
+	"screen_width", "screen_height", "device_pixel_ratio", "color_depth", "hardware_concurrency",
+	"device_memory", "font_count", "plugin_count", "extension_count", "media_devices",
+	"feature_count", "touch_support", "cookie_enabled",
+}
+
+// outlierCategoricalFeatures 离群检测的类别特征，按取值在训练样本中的频率编码，顺序与 outlierCategories 一致
+var outlierCategoricalFeatures = []string{"ua_family", "ua_os", "timezone", "language", "platform", "gpu_family"}
+
+// outlierModel 训练好的离群检测模型
+type outlierModel struct {
+	forest *utils.IsolationForest
+	// frequencies 各类别特征的取值在训练样本中的比例，未出现的取值为0
+	frequencies []map[string]float64
+	trainedAt   time.Time
+	samples     int
+	flagRate    float64
+}
+
+// outlierNumeric 提取数值特征，布尔值为0或1
+func outlierNumeric(fp *models.Fingerprint) []float64 {
+	w, h := 0, 0
+	if parts := strings.Split(normalizeResolution(fp.ScreenResolution), "x"); len(parts) == 2 {
+		w, _ = strconv.Atoi(parts[0])
+		h, _ = strconv.Atoi(parts[1])
+	}
+	flag := func(b bool) float64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+	media := -1
+	if fp.MediaAudioInputs >= 0 {
+		media = fp.MediaAudioInputs + fp.MediaAudioOutputs + fp.MediaVideoInputs
+	}
+	return []float64{
+		float64(w), float64(h), fp.DevicePixelRatio, float64(fp.ColorDepth), float64(fp.HardwareConcurrency),
+		fp.DeviceMemory, float64(len(utils.JSONToStringSlice(fp.Fonts))), float64(len(utils.JSONToStringSlice(fp.Plugins))),
+		float64(len(utils.JSONToStringSlice(fp.Extensions))), float64(media),
+		float64(fp.FeatureCount), flag(fp.TouchSupport), flag(fp.CookieEnabled),
+	}
+}
+
+// outlierCategories 提取类别特征的取值
+func outlierCategories(fp *models.Fingerprint) []string {
+	ua := utils.ParseUserAgent(fp.UserAgent)
+	return []string{ua.Family, ua.OS, fp.Timezone, strings.ToLower(fp.Language), strings.ToLower(fp.Platform), fp.GPUFamily}
+}
+
+// vector 将指纹编码为模型的输入：数值特征后接各类别取值的频率
+func (m *outlierModel) vector(fp *models.Fingerprint) []float64 {
+	vec := outlierNumeric(fp)
+	for i, value := range outlierCategories(fp) {
+		vec = append(vec, m.frequencies[i][value])
+	}
+	return vec
+}
+
+// trainOutlierModel 用样本训练离群检测模型
+func trainOutlierModel(samples []models.Fingerprint, trees, sampleSize int, threshold float64, rng *rand.Rand) *outlierModel {
+	m := &outlierModel{
+		frequencies: make([]map[string]float64, len(outlierCategoricalFeatures)),
+		trainedAt:   time.Now(),
+		samples:     len(samples),
+	}
+	for i := range m.frequencies {
+		m.frequencies[i] = make(map[string]float64)
+	}
+	for i := range samples {
+		for j, value := range outlierCategories(&samples[i]) {
+			m.frequencies[j][value] += 1 / float64(len(samples))
+		}
+	}
+
+	vectors := make([][]float64, len(samples))
+	for i := range samples {
+		vectors[i] = m.vector(&samples[i])
+	}
+	m.forest = utils.NewIsolationForest(vectors, trees, sampleSize, rng)
+
+	flagged := 0
+	for _, v := range vectors {
+		if score, _ := m.forest.Score(v); score > threshold {
+			flagged++
+		}
+	}
+	m.flagRate = ratio(flagged, len(vectors))
+	return m
+}
+
+// TrainOutlierModel 用时间窗口内更新过的指纹（不含标注为爬虫的）重新训练离群检测模型，
+// 样本不足 min_samples 时保留已有模型并返回错误。训练进行中时再次调用等待并共享本次结果，
+// 不会并发训练；训练不随调用方的请求取消而中断
+func (fs *FingerprintService) TrainOutlierModel(ctx context.Context) (models.OutlierModelStatus, error) {
+	status, err, _ := fs.jobs.Do("outliers", func() (interface{}, error) {
+		return fs.trainOutliers(context.WithoutCancel(ctx))
+	})
+	return status.(models.OutlierModelStatus), err
+}
+
+// trainOutliers 训练离群检测模型，只由 TrainOutlierModel 调用
+func (fs *FingerprintService) trainOutliers(ctx context.Context) (models.OutlierModelStatus, error) {
+	samples, err := fs.loadFingerprints(ctx, `deleted_at IS NULL AND updated_at >= ?
+		AND fingerprint_hash NOT IN (SELECT fingerprint_hash FROM labels WHERE label = ?)
+		ORDER BY RANDOM() LIMIT ?`,
+		time.Now().Add(-fs.outliers.Window.Std()), models.LabelBot, fs.outliers.MaxSamples)
+	if err == nil && len(samples) < fs.outliers.MinSamples {
+		err = fmt.Errorf("%w: %d in the last %s, need %d", ErrTooFewOutlierSamples, len(samples), fs.outliers.Window.Std(), fs.outliers.MinSamples)
+	}
+	if err != nil {
+		fs.outlierMu.Lock()
+		fs.outlierError = err.Error()
+		fs.outlierMu.Unlock()
+		return fs.OutlierStatus(), err
+	}
+
+	fps := make([]models.Fingerprint, len(samples))
+	for i, s := range samples {
+		fps[i] = s.fp
+	}
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	model := trainOutlierModel(fps, fs.outliers.Trees, fs.outliers.SampleSize, fs.outliers.Threshold, rng)
+
+	fs.outlierMu.Lock()
+	fs.outlierModel.Store(model)
+	fs.outlierError = ""
+	fs.outlierScored.Store(0)
+	fs.outlierFlagged.Store(0)
+	fs.outlierMu.Unlock()
+	log.Printf("Trained outlier model on %d fingerprints, %.1f%% above threshold", model.samples, model.flagRate*100)
+	return fs.OutlierStatus(), nil
+}
+
+// RunOutlierTraining 启动时训练一次，之后按间隔重新训练；模型只保存在内存中，每个实例各自训练
+func (fs *FingerprintService) RunOutlierTraining(ctx context.Context) {
+	if fs.outliers.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(fs.outliers.Interval.Std())
+	defer ticker.Stop()
+	for {
+		if _, err := fs.TrainOutlierModel(ctx); err != nil {
+			log.Printf("Outlier model not trained: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// OutlierStatus 返回离群检测模型的训练情况
+func (fs *FingerprintService) OutlierStatus() models.OutlierModelStatus {
+	status := models.OutlierModelStatus{
+		Interval:  fs.outliers.Interval.Std().String(),
+		Features:  len(outlierNumericFeatures) + len(outlierCategoricalFeatures),
+		Threshold: fs.outliers.Threshold,
+		Weight:    fs.outliers.Weight,
+	}
+	fs.outlierMu.Lock()
+	defer fs.outlierMu.Unlock()
+	if m := fs.outlierModel.Load(); m != nil {
+		trainedAt := m.trainedAt
+		status.TrainedAt = &trainedAt
+		status.Samples = m.samples
+		status.Trees = m.forest.Trees()
+		status.SampleFlagRate = m.flagRate
+		status.Scored = fs.outlierScored.Load()
+		status.Flagged = fs.outlierFlagged.Load()
+	}
+	status.Error = fs.outlierError
+	return status
+}
+
+// outlierFeaturesJSON 将离群解释的特征编码为JSON数组，没有时为空字符串
+func outlierFeaturesJSON(features []string) string {
+	if len(features) == 0 {
+		return ""
+	}
+	return utils.StringSliceToJSON(features)
+}
+
+// scoreOutlier 按当前模型计算异常分数，超过阈值时给出离群信号并返回贡献最大的特征；未训练模型或功能停用时分数为空
+func (fs *FingerprintService) scoreOutlier(fp *models.Fingerprint) (*float64, []signal, []string) {
+	m := fs.outlierModel.Load()
+	if m == nil || !fs.featureEnabled(FeatureOutliers) {
+		return nil, nil, nil
+	}
+	score, contributions := m.forest.Score(m.vector(fp))
+	fs.outlierScored.Add(1)
+	if score <= fs.outliers.Threshold {
+		return &score, nil, nil
+	}
+	fs.outlierFlagged.Add(1)
+
+	names := append(append([]string{}, outlierNumericFeatures...), outlierCategoricalFeatures...)
+	order := make([]int, len(contributions))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return contributions[order[a]] > contributions[order[b]] })
+	features := make([]string, 0, outlierTopFeatures)
+	for _, i := range order[:min(outlierTopFeatures, len(order))] {
+		if contributions[i] > 0 {
+			features = append(features, names[i])
+		}
+	}
+
+	return &score, []signal{{
+		Code:   models.ReasonStatisticalOutlier,
+		Weight: fs.outliers.Weight,
+		Reason: fmt.Sprintf("Statistical outlier among stored fingerprints (score %.2f), unusual %s", score, strings.Join(features, ", ")),
+	}}, features
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrProofDisabled 未启用执行证明
+var ErrProofDisabled = errors.New("execution proof is disabled")
+
+// signProofSeed 用签发密钥对种子内容签名，取HMAC-SHA256的前16字节
+func signProofSeed(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("proof:" + payload))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// IssueProofSeed 签发执行证明的种子：密钥ID、签发时间（Unix毫秒）、随机数和签名，服务端无需保存即可校验
+// 签发时间同时用于采集时间校验，返回的过期时间为签发时间加 seed_ttl
+func (fs *FingerprintService) IssueProofSeed() (string, time.Time, error) {
+	if fs.jsProof.SeedTTL <= 0 {
+		return "", time.Time{}, ErrProofDisabled
+	}
+	kid, secret, err := fs.activeTokenKey()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	nonce, err := randomHex(16)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	issuedAt := time.Now().Truncate(time.Millisecond)
+	payload := kid + "." + strconv.FormatInt(issuedAt.UnixMilli(), 10) + "." + nonce
+	return payload + "." + signProofSeed(secret, payload), issuedAt.Add(fs.jsProof.SeedTTL.Std()), nil
+}
+
+// verifyProofSeed 校验种子的签名和有效期，返回签发时间
+func (fs *FingerprintService) verifyProofSeed(seed string, now time.Time) (time.Time, error) {
+	parts := strings.Split(seed, ".")
+	if len(parts) != 4 {
+		return time.Time{}, errors.New("malformed seed")
+	}
+	secret, ok := fs.tokenSecret(parts[0])
+	if !ok {
+		return time.Time{}, errors.New("unknown seed key")
+	}
+	payload := strings.Join(parts[:3], ".")
+	if subtle.ConstantTimeCompare([]byte(signProofSeed(secret, payload)), []byte(parts[3])) != 1 {
+		return time.Time{}, errors.New("invalid seed signature")
+	}
+	issued, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return time.Time{}, errors.New("malformed seed")
+	}
+	issuedAt := time.UnixMilli(issued)
+	if now.After(issuedAt.Add(fs.jsProof.SeedTTL.Std())) {
+		return time.Time{}, errors.New("seed expired")
+	}
+	return issuedAt, nil
+}
+
+// checkExecutionProof 校验采集脚本的执行证明：种子须由本服务签发且未过期、只被一个指纹使用，证明值须与服务端的计算一致
+// 缺少执行证明时只在 required 开启后计分；只校验本次提交，重新分析和规则重放（没有接收时间）不校验
+func (fs *FingerprintService) checkExecutionProof(ctx context.Context, fp *models.Fingerprint, req *models.FingerprintRequest) []signal {
+	cfg := fs.jsProof
+	if cfg.SeedTTL <= 0 || req == nil || fp.ReceivedAt.IsZero() {
+		return nil
+	}
+	var problem string
+	switch proof := req.Proof; {
+	case proof == nil || proof.Seed == "" || proof.Value == "":
+		if !cfg.Required {
+			return nil
+		}
+		problem = "missing"
+	default:
+		if _, err := fs.verifyProofSeed(proof.Seed, fp.ReceivedAt); err != nil {
+			problem = err.Error()
+		} else if subtle.ConstantTimeCompare([]byte(utils.ExecutionProof(proof.Seed)), []byte(strings.ToLower(proof.Value))) != 1 {
+			problem = "incorrect value"
+		} else if reused, err := fs.proofSeedReused(ctx, proof.Seed, fp.FingerprintHash); err != nil {
+			log.Printf("Failed to record proof seed: %v", err)
+		} else if reused {
+			problem = "seed already used by another fingerprint"
+		}
+	}
+	if problem == "" {
+		return nil
+	}
+	return []signal{{
+		Code:   models.ReasonJSProofInvalid,
+		Weight: cfg.Weight,
+		Reason: fmt.Sprintf("Collector execution proof %s: the payload was not produced by running the collector script", problem),
+	}}
+}
+
+// proofSeedReused 记录种子的首次使用，种子已被其他指纹使用过时返回 true
+// 同一访客重新采集时会复用种子，只有不同指纹共用同一种子才说明证明被复制到了伪造的请求中
+func (fs *FingerprintService) proofSeedReused(ctx context.Context, seed, fingerprintHash string) (bool, error) {
+	if _, err := fs.db.DB.ExecContext(ctx,
+		"INSERT OR IGNORE INTO proof_seeds (seed, fingerprint_hash, first_used) VALUES (?, ?, ?)",
+		seed, fingerprintHash, time.Now()); err != nil {
+		return false, err
+	}
+	var owner string
+	if err := fs.db.DB.QueryRowContext(ctx,
+		"SELECT fingerprint_hash FROM proof_seeds WHERE seed = ?", seed).Scan(&owner); err != nil {
+		return false, err
+	}
+	return owner != fingerprintHash, nil
+}
+
+// purgeProofSeeds 删除已过期种子的使用记录，过期的种子本身已无法通过校验
+func (fs *FingerprintService) purgeProofSeeds(ctx context.Context) error {
+	if fs.jsProof.SeedTTL <= 0 {
+		return nil
+	}
+	_, err := fs.db.DB.ExecContext(ctx,
+		"DELETE FROM proof_seeds WHERE first_used < ?", time.Now().Add(-fs.jsProof.SeedTTL.Std()))
+	return err
+}
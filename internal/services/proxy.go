@@ -0,0 +1,144 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"context"
+	"fmt"
+	"log"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// initialTTLs 常见系统的初始TTL，Windows 为128，Linux、Android、macOS、iOS 和 ChromeOS 为64
+var initialTTLs = map[string]int{
+	"Windows":  128,
+	"Linux":    64,
+	"Android":  64,
+	"macOS":    64,
+	"iOS":      64,
+	"ChromeOS": 64,
+}
+
+// newHostingASNs 将配置的机房ASN列表转为集合
+func newHostingASNs(asns []int) map[int]bool {
+	set := make(map[int]bool, len(asns))
+	for _, asn := range asns {
+		set[asn] = true
+	}
+	return set
+}
+
+// LoadASNDatabase 启动时加载 detection.proxy.asn_database，未配置时不检测ASN轮换
+func (fs *FingerprintService) LoadASNDatabase() error {
+	if fs.proxy.ASNDatabase == "" {
+		return nil
+	}
+	db, err := utils.LoadASNDatabase(fs.proxy.ASNDatabase)
+	if err != nil {
+		return fmt.Errorf("failed to load ASN database %s: %w", fs.proxy.ASNDatabase, err)
+	}
+	fs.asnDB = db
+	log.Printf("Loaded ASN database from %s: %d ranges", fs.proxy.ASNDatabase, db.Len())
+	return nil
+}
+
+// residentialASN 返回来源IP所属的住宅ASN，机房ASN、内网地址或未收录的地址返回 false
+func (fs *FingerprintService) residentialASN(ip string) (utils.ASN, bool) {
+	if fs.asnDB == nil {
+		return utils.ASN{}, false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || addr.IsLoopback() || addr.IsPrivate() {
+		return utils.ASN{}, false
+	}
+	asn, ok := fs.asnDB.Lookup(addr)
+	if !ok || fs.hostingASNs[asn.Number] || utils.IsHostingASN(asn) {
+		return utils.ASN{}, false
+	}
+	return asn, true
+}
+
+// recordFingerprintASN 记录指纹本次来源的住宅ASN
+func (fs *FingerprintService) recordFingerprintASN(ctx context.Context, fp *models.Fingerprint) error {
+	asn, ok := fs.residentialASN(fp.IPAddress)
+	if !ok {
+		return nil
+	}
+	_, err := fs.db.DB.ExecContext(ctx, `
+		INSERT INTO fingerprint_asns (fingerprint_hash, asn, last_seen) VALUES (?, ?, ?)
+		ON CONFLICT (fingerprint_hash, asn) DO UPDATE SET last_seen = excluded.last_seen`,
+		fp.FingerprintHash, asn.Number, time.Now())
+	return err
+}
+
+// purgeFingerprintASNs 删除超出统计窗口的住宅ASN记录
+func (fs *FingerprintService) purgeFingerprintASNs(ctx context.Context) error {
+	_, err := fs.db.DB.ExecContext(ctx,
+		"DELETE FROM fingerprint_asns WHERE last_seen < ?", time.Now().Add(-fs.proxy.Window.Std()))
+	return err
+}
+
+// checkResidentialProxy 汇总住宅代理的迹象：
+// 同一指纹在窗口内来自多个住宅ASN单独即可判定；TTL与声明的系统不符、浏览器测得的时延明显高于TCP时延、
+// WebRTC与HTTP来源IP不一致各算半项，至少两项同时出现才判定，避免单项误报
+func (fs *FingerprintService) checkResidentialProxy(ctx context.Context, fp *models.Fingerprint, req *models.FingerprintRequest) []signal {
+	var evidence []string
+	strength := 0.0
+
+	if _, ok := fs.residentialASN(fp.IPAddress); ok {
+		var n int
+		err := fs.db.DB.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM fingerprint_asns WHERE fingerprint_hash = ? AND last_seen >= ?",
+			fp.FingerprintHash, time.Now().Add(-fs.proxy.Window.Std())).Scan(&n)
+		if err != nil {
+			log.Printf("Failed to query fingerprint ASNs: %v", err)
+		} else if n >= fs.proxy.MinASNs {
+			strength += 1
+			evidence = append(evidence, fmt.Sprintf("%d residential ASNs within %s", n, fs.proxy.Window.Std()))
+		}
+	}
+
+	if fp.IPTTL > 0 {
+		osName := utils.ParseUserAgent(fp.UserAgent).OS
+		if expected, ok := initialTTLs[osName]; ok && initialTTL(fp.IPTTL) != expected {
+			strength += 0.5
+			evidence = append(evidence, fmt.Sprintf("TTL %d does not match the %s network stack (initial TTL %d)", fp.IPTTL, osName, expected))
+		}
+	}
+
+	if fp.TCPRTT > 0 && req != nil && req.Network != nil && req.Network.PingMS > 0 {
+		gap := float64(fs.proxy.LatencyGap.Std()) / float64(time.Millisecond)
+		if ping := req.Network.PingMS; ping > fp.TCPRTT+gap && ping > 2*fp.TCPRTT {
+			strength += 0.5
+			evidence = append(evidence, fmt.Sprintf("browser round trip %.0fms vs TCP round trip %.0fms", ping, fp.TCPRTT))
+		}
+	}
+
+	if req != nil && len(checkWebRTCLeak(fp, req)) > 0 {
+		strength += 0.5
+		evidence = append(evidence, "WebRTC public IP differs from HTTP source IP")
+	}
+
+	if strength < 1 {
+		return nil
+	}
+	return []signal{{
+		Code:   models.ReasonProxySuspected,
+		Weight: fs.proxy.Weight,
+		Reason: "Residential proxy suspected: " + strings.Join(evidence, "; "),
+	}}
+}
+
+// initialTTL 按观测到的TTL推断发送方的初始TTL（64、128或255）
+func initialTTL(ttl int) int {
+	switch {
+	case ttl <= 64:
+		return 64
+	case ttl <= 128:
+		return 128
+	default:
+		return 255
+	}
+}
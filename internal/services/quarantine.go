@@ -0,0 +1,103 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+// Quarantine 保存被拒绝的提交，同一IP以相同原因提交的相同请求体只累计次数和最后出现时间
+// entry 中的 Body 等请求体相关字段由 body 计算，未启用隔离存储时不做任何事
+func (fs *FingerprintService) Quarantine(ctx context.Context, entry models.QuarantineEntry, body []byte) error {
+	if fs.quarantine.Retention <= 0 {
+		return nil
+	}
+	sum := sha256.Sum256(body)
+	entry.BodyHash = hex.EncodeToString(sum[:])
+	entry.BodySize = len(body)
+	if len(body) > fs.quarantine.MaxBodyBytes {
+		body = body[:fs.quarantine.MaxBodyBytes]
+	}
+	entry.Body, entry.BodyEncoding = string(body), ""
+	if !utf8.Valid(body) {
+		entry.Body, entry.BodyEncoding = base64.StdEncoding.EncodeToString(body), "base64"
+	}
+	headers, err := json.Marshal(entry.Headers)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if _, err := fs.db.DB.ExecContext(ctx, `
+		INSERT INTO quarantine (site_id, ip_address, reason, detail, headers, body, body_encoding, body_size, body_hash, count, first_seen, last_seen)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 1, ?, ?)
+		ON CONFLICT (ip_address, reason, body_hash) DO UPDATE SET
+			site_id = excluded.site_id,
+			detail = excluded.detail,
+			headers = excluded.headers,
+			count = count + 1,
+			last_seen = excluded.last_seen`,
+		entry.SiteID, entry.IPAddress, entry.Reason, entry.Detail, string(headers),
+		entry.Body, entry.BodyEncoding, entry.BodySize, entry.BodyHash, now, now); err != nil {
+		return fmt.Errorf("failed to save quarantine entry: %w", err)
+	}
+	return nil
+}
+
+// GetQuarantine 按最后出现时间倒序返回隔离记录，site_id、reason、ip 为空时不过滤
+func (fs *FingerprintService) GetQuarantine(ctx context.Context, siteID, reason, ip string, limit int) ([]models.QuarantineEntry, error) {
+	query := `SELECT id, site_id, ip_address, reason, detail, headers, body, body_encoding, body_size, body_hash, count, first_seen, last_seen
+		FROM quarantine WHERE 1 = 1`
+	var args []interface{}
+	if siteID != "" {
+		query += " AND site_id = ?"
+		args = append(args, siteID)
+	}
+	if reason != "" {
+		query += " AND reason = ?"
+		args = append(args, reason)
+	}
+	if ip != "" {
+		query += " AND ip_address = ?"
+		args = append(args, ip)
+	}
+	query += " ORDER BY last_seen DESC, id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := fs.db.Read.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.QuarantineEntry{}
+	for rows.Next() {
+		var e models.QuarantineEntry
+		var headers string
+		if err := rows.Scan(&e.ID, &e.SiteID, &e.IPAddress, &e.Reason, &e.Detail, &headers, &e.Body, &e.BodyEncoding,
+			&e.BodySize, &e.BodyHash, &e.Count, &e.FirstSeen, &e.LastSeen); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(headers), &e.Headers); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// purgeQuarantine 删除最后出现时间超过保留期的隔离记录
+func (fs *FingerprintService) purgeQuarantine(ctx context.Context) error {
+	if fs.quarantine.Retention <= 0 {
+		return nil
+	}
+	_, err := fs.db.DB.ExecContext(ctx,
+		"DELETE FROM quarantine WHERE last_seen < ?", time.Now().Add(-fs.quarantine.Retention.Std()))
+	return err
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// queryRefreshTimeout 后台重新查询缓存结果的超时
+const queryRefreshTimeout = time.Minute
+
+// queryEntry 缓存的一个查询结果；loading 为 true 时首次查询尚未完成，完成后关闭 ready
+type queryEntry struct {
+	value      interface{}
+	err        error
+	storedAt   time.Time
+	stale      bool
+	refreshing bool
+	loading    bool
+	ready      chan struct{}
+}
+
+// queryCache 统计接口的查询结果缓存，按查询名和参数缓存
+type queryCache struct {
+	mu      sync.Mutex
+	entries map[string]*queryEntry
+}
+
+// queryKey 查询名和参数组成的缓存键，时间参数为 nil 时为空
+func queryKey(name string, from, to *time.Time, params ...string) string {
+	parts := append([]string{name, "", ""}, params...)
+	if from != nil {
+		parts[1] = from.UTC().Format(time.RFC3339Nano)
+	}
+	if to != nil {
+		parts[2] = to.UTC().Format(time.RFC3339Nano)
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// invalidate 使所有缓存结果按过期处理：下次请求先返回旧结果并在后台重新查询
+func (c *queryCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range c.entries {
+		if !e.loading {
+			e.stale = true
+		}
+	}
+}
+
+// insert 保存新的缓存项，达到上限时先清除超过 ttl+stale 的结果，仍然已满时清空缓存
+func (c *queryCache) insert(key string, e *queryEntry, now time.Time, maxAge time.Duration, maxEntries int) {
+	if len(c.entries) >= maxEntries {
+		for k, old := range c.entries {
+			if !old.loading && now.Sub(old.storedAt) >= maxAge {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxEntries {
+			c.entries = make(map[string]*queryEntry)
+		}
+	}
+	c.entries[key] = e
+}
+
+// cachedQuery 返回缓存的查询结果：未超过 ttl 时直接返回，超过 ttl 但未超过 ttl+stale 时返回旧结果并在后台重新查询，
+// 否则同步查询；同一个键同时只有一个查询，其他请求等待其结果
+func cachedQuery[T any](ctx context.Context, fs *FingerprintService, key string, load func(context.Context) (T, error)) (T, error) {
+	ttl, stale := fs.queryCacheCfg.TTL.Std(), fs.queryCacheCfg.Stale.Std()
+	if ttl <= 0 {
+		return load(ctx)
+	}
+	c := &fs.queryCache
+	now := time.Now()
+
+	c.mu.Lock()
+	e := c.entries[key]
+	if e != nil && e.loading {
+		c.mu.Unlock()
+		return awaitQuery[T](ctx, e)
+	}
+	if e != nil {
+		age := now.Sub(e.storedAt)
+		if !e.stale && age < ttl {
+			c.mu.Unlock()
+			return e.value.(T), nil
+		}
+		if age < ttl+stale {
+			if !e.refreshing {
+				e.refreshing = true
+				go refreshQuery(fs, key, e, load)
+			}
+			c.mu.Unlock()
+			return e.value.(T), nil
+		}
+	}
+	e = &queryEntry{loading: true, ready: make(chan struct{})}
+	c.insert(key, e, now, ttl+stale, fs.queryCacheCfg.MaxEntries)
+	c.mu.Unlock()
+
+	// 共享的首次查询不随发起请求取消，否则其他等待的请求都会得到该请求的取消错误
+	go loadQuery(context.WithoutCancel(ctx), fs, key, e, load)
+	return awaitQuery[T](ctx, e)
+}
+
+// awaitQuery 等待首次查询完成并返回其结果，ctx 取消时提前返回
+func awaitQuery[T any](ctx context.Context, e *queryEntry) (T, error) {
+	var zero T
+	select {
+	case <-e.ready:
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+	if e.err != nil {
+		return zero, e.err
+	}
+	return e.value.(T), nil
+}
+
+// loadQuery 执行首次查询并保存结果，超时与后台重新查询相同；失败时移除缓存项，下次请求重新查询
+func loadQuery[T any](ctx context.Context, fs *FingerprintService, key string, e *queryEntry, load func(context.Context) (T, error)) {
+	ctx, cancel := context.WithTimeout(ctx, queryRefreshTimeout)
+	defer cancel()
+	value, err := load(ctx)
+
+	c := &fs.queryCache
+	c.mu.Lock()
+	if err != nil {
+		e.err = err
+		if c.entries[key] == e {
+			delete(c.entries, key)
+		}
+	} else {
+		e.value, e.storedAt = value, time.Now()
+	}
+	e.loading = false
+	c.mu.Unlock()
+	close(e.ready)
+}
+
+// refreshQuery 在后台重新查询并替换缓存结果，失败时保留旧结果直到超过 ttl+stale
+func refreshQuery[T any](fs *FingerprintService, key string, old *queryEntry, load func(context.Context) (T, error)) {
+	ctx, cancel := context.WithTimeout(context.Background(), queryRefreshTimeout)
+	defer cancel()
+	value, err := load(ctx)
+
+	c := &fs.queryCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		old.refreshing = false
+		log.Printf("Failed to refresh cached query: %v", err)
+		return
+	}
+	if c.entries[key] == old {
+		c.entries[key] = &queryEntry{value: value, storedAt: time.Now()}
+	}
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// storedFingerprint 数据库中的指纹记录及其自增ID
+type storedFingerprint struct {
+	id int
+	fp models.Fingerprint
+}
+
+// loadFingerprints 读取满足条件的指纹记录
+func (fs *FingerprintService) loadFingerprints(ctx context.Context, where string, args ...interface{}) ([]storedFingerprint, error) {
+	var fp models.Fingerprint
+	var id int
+	fields := fingerprintFields(&fp)
+	columns := make([]string, len(fields))
+	dest := make([]interface{}, len(fields)+1)
+	dest[0] = &id
+	for i, f := range fields {
+		columns[i] = f.column
+		dest[i+1] = f.ptr
+	}
+
+	query := fmt.Sprintf("SELECT id, %s FROM fingerprints WHERE %s", strings.Join(columns, ", "), where)
+	rows, err := fs.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stored []storedFingerprint
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		stored = append(stored, storedFingerprint{id: id, fp: fp})
+	}
+	return stored, rows.Err()
+}
+
+// moveFingerprint 在事务中把指纹记录及 utils.FingerprintRefs 中所有引用它的行迁移到新的指纹哈希，
+// 并替换组件哈希；新哈希已有的行被迁移过来的行覆盖
+func moveFingerprint(ctx context.Context, tx *sql.Tx, s storedFingerprint, newHash string, components map[string]string) error {
+	oldHash := s.fp.FingerprintHash
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE OR REPLACE fingerprints SET fingerprint_hash = ?, hash_v2 = ?, fingerprint_version = ? WHERE id = ?",
+		newHash, s.fp.HashV2, s.fp.FingerprintVersion, s.id); err != nil {
+		return fmt.Errorf("failed to update fingerprint %d: %w", s.id, err)
+	}
+	if newHash != oldHash {
+		for _, ref := range utils.FingerprintRefs {
+			query := fmt.Sprintf("UPDATE OR REPLACE %s SET %s = ? WHERE %s = ?", ref.Table, ref.Column, ref.Column)
+			if ref.Filter != "" {
+				query += " AND " + ref.Filter
+			}
+			if _, err := tx.ExecContext(ctx, query, newHash, oldHash); err != nil {
+				return fmt.Errorf("failed to update %s of fingerprint %d: %w", ref.Table, s.id, err)
+			}
+		}
+	}
+	if err := replaceComponents(ctx, tx, oldHash, newHash, components); err != nil {
+		return fmt.Errorf("failed to update components of fingerprint %d: %w", s.id, err)
+	}
+	// 访客历史中的组件哈希同步更新，避免密钥轮换后被误判为漂移
+	for name, hash := range components {
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE component_history SET hash = ? WHERE fingerprint_hash = ? AND component = ?",
+			hash, newHash, name); err != nil {
+			return fmt.Errorf("failed to update component history of fingerprint %d: %w", s.id, err)
+		}
+	}
+	return nil
+}
+
+// RehashSite 使用站点当前配置的密钥重新计算该站点已存储指纹的哈希
+// 用于启用或更换 hash_secret 之后：指纹及所有引用它的记录在同一事务中迁移到新哈希，
+// 返回哈希发生变化的记录数
+func (fs *FingerprintService) RehashSite(ctx context.Context, siteID string) (int, error) {
+	site, ok := fs.sites[siteID]
+	if !ok {
+		return 0, fmt.Errorf("unknown site %q", siteID)
+	}
+
+	stored, err := fs.loadFingerprints(ctx, "site_id = ?", siteID)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	changed := 0
+	for _, s := range stored {
+		components := hashComponents(&s.fp, site.HashSecret)
+		newHash := combineComponents(components)
+		if newHash == s.fp.FingerprintHash {
+			continue
+		}
+		s.fp.HashV2 = newHash
+		if err := moveFingerprint(ctx, tx, s, newHash, components); err != nil {
+			return 0, err
+		}
+		changed++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	if changed > 0 {
+		fs.invalidateVectorIndex()
+	}
+	log.Printf("Rehashed %d of %d fingerprints for site %s", changed, len(stored), siteID)
+	return changed, nil
+}
+
+// MigrateFingerprintVersions 为引入版本号之前存储的指纹推断结构版本，并按版本重新计算组件哈希
+// 服务端计算的指纹哈希随之更新；前端提交的指纹哈希保持不变
+func (fs *FingerprintService) MigrateFingerprintVersions(ctx context.Context) (int, error) {
+	stored, err := fs.loadFingerprints(ctx, "fingerprint_version = 0")
+	if err != nil || len(stored) == 0 {
+		return 0, err
+	}
+
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	for _, s := range stored {
+		serverHashed := s.fp.HashV2 != "" && s.fp.FingerprintHash == s.fp.HashV2
+		s.fp.FingerprintVersion = inferFingerprintVersion(&s.fp)
+		components := hashComponents(&s.fp, fs.hashSecret(s.fp.SiteID))
+		s.fp.HashV2 = combineComponents(components)
+		newHash := s.fp.FingerprintHash
+		if serverHashed {
+			newHash = s.fp.HashV2
+		}
+		if err := moveFingerprint(ctx, tx, s, newHash, components); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	fs.invalidateVectorIndex()
+	log.Printf("Migrated %d fingerprints to versioned hashing", len(stored))
+	return len(stored), nil
+}
+
+// VersionStats 统计各指纹结构版本的记录数；结果按 query_cache 缓存
+func (fs *FingerprintService) VersionStats(ctx context.Context) ([]models.VersionCount, error) {
+	return cachedQuery(ctx, fs, queryKey("versions", nil, nil), func(ctx context.Context) ([]models.VersionCount, error) {
+		return fs.versionStats(ctx)
+	})
+}
+
+// versionStats 查询指纹结构版本统计，不使用缓存
+func (fs *FingerprintService) versionStats(ctx context.Context) ([]models.VersionCount, error) {
+	rows, err := fs.db.Read.QueryContext(ctx,
+		"SELECT fingerprint_version, COUNT(*) FROM fingerprints GROUP BY fingerprint_version ORDER BY fingerprint_version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []models.VersionCount{}
+	for rows.Next() {
+		var c models.VersionCount
+		if err := rows.Scan(&c.Version, &c.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,368 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"browser-detection/internal/releases"
+	"browser-detection/internal/utils"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// releasesSetting settings 表中从更新源获取的浏览器版本表的键
+const releasesSetting = "browser_releases"
+
+// releasesCheckInterval 检查版本表是否需要更新或重新加载的间隔
+const releasesCheckInterval = time.Minute
+
+// ErrReleasesNotConfigured 未配置 detection.browser_releases.feed
+var ErrReleasesNotConfigured = errors.New("detection.browser_releases.feed is not configured")
+
+// clientHintBrandPattern Sec-CH-UA 中的一个品牌及其主版本，如 "Google Chrome";v="119"
+var clientHintBrandPattern = regexp.MustCompile(`"([^"]*)"\s*;\s*v\s*=\s*"(\d+)`)
+
+// clientHintFamilies Sec-CH-UA 品牌对应的浏览器家族；Chromium 品牌的版本即内核版本，与Chrome的发布节奏一致
+var clientHintFamilies = map[string]string{
+	"Google Chrome":    utils.BrowserChrome,
+	"Chromium":         utils.BrowserChrome,
+	"Microsoft Edge":   utils.BrowserEdge,
+	"Opera":            utils.BrowserOpera,
+	"Samsung Internet": utils.BrowserSamsung,
+}
+
+// browserVersion 声明的浏览器家族和主版本，source 为声明来源
+type browserVersion struct {
+	family string
+	major  int
+	source string
+}
+
+// parseClientHints 解析 Sec-CH-UA 中可识别的品牌，忽略 GREASE 等无关品牌
+func parseClientHints(header string) []browserVersion {
+	var versions []browserVersion
+	for _, m := range clientHintBrandPattern.FindAllStringSubmatch(header, -1) {
+		family, ok := clientHintFamilies[m[1]]
+		if !ok {
+			continue
+		}
+		if major, err := strconv.Atoi(m[2]); err == nil && major > 0 {
+			versions = append(versions, browserVersion{family: family, major: major, source: "Sec-CH-UA " + m[1]})
+		}
+	}
+	return versions
+}
+
+// releaseTable 返回当前使用的版本表
+func (fs *FingerprintService) releaseTable() *releases.Table {
+	if t := fs.releases.Load(); t != nil {
+		return t
+	}
+	return releases.Embedded()
+}
+
+// LoadBrowserReleases 加载最近一次从更新源获取并保存的版本表，没有或无法解析时使用内置的版本表
+func (fs *FingerprintService) LoadBrowserReleases(ctx context.Context) error {
+	var value string
+	var updatedAt time.Time
+	err := fs.db.DB.QueryRowContext(ctx,
+		"SELECT value, updated_at FROM settings WHERE key = ?", releasesSetting).Scan(&value, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	fs.releasesMu.Lock()
+	defer fs.releasesMu.Unlock()
+	if fs.releasesUpdated != nil && !updatedAt.After(*fs.releasesUpdated) {
+		return nil
+	}
+	var stored releases.Table
+	if err := json.Unmarshal([]byte(value), &stored); err != nil {
+		log.Printf("Ignoring invalid stored browser releases: %v", err)
+		return nil
+	}
+	t, err := releases.Parse([]byte(value), stored.Source)
+	if err != nil {
+		log.Printf("Ignoring invalid stored browser releases: %v", err)
+		return nil
+	}
+	fs.releases.Store(t)
+	fs.releasesUpdated = &updatedAt
+	return nil
+}
+
+// RefreshBrowserReleases 从更新源获取版本表，保存后供所有实例使用并写入审计记录；失败时保留当前版本表
+func (fs *FingerprintService) RefreshBrowserReleases(ctx context.Context, actor string) (models.BrowserReleasesStatus, error) {
+	if fs.releaseConfig.Feed == "" {
+		return fs.BrowserReleases(), ErrReleasesNotConfigured
+	}
+	t, err := releases.Fetch(ctx, fs.releaseConfig.Feed, fs.releaseConfig.Timeout.Std())
+
+	fs.releasesMu.Lock()
+	defer fs.releasesMu.Unlock()
+	fs.releasesAttempt = time.Now()
+	if err != nil {
+		fs.releasesError = err.Error()
+		return fs.releasesStatus(), err
+	}
+
+	value, err := json.Marshal(t)
+	if err != nil {
+		return fs.releasesStatus(), err
+	}
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fs.releasesStatus(), err
+	}
+	defer tx.Rollback()
+	now := time.Now()
+	if _, err := tx.ExecContext(ctx,
+		"INSERT OR REPLACE INTO settings (key, value, updated_at) VALUES (?, ?, ?)",
+		releasesSetting, string(value), now); err != nil {
+		return fs.releasesStatus(), fmt.Errorf("failed to save browser releases: %w", err)
+	}
+	before := fs.releasesStatus()
+	previous, previousUpdated := fs.releases.Load(), fs.releasesUpdated
+	fs.releases.Store(t)
+	fs.releasesUpdated = &now
+	fs.releasesError = ""
+	after := fs.releasesStatus()
+	err = recordAudit(ctx, tx, actor, "refresh_browser_releases", t.Source, before, after)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		fs.releases.Store(previous)
+		fs.releasesUpdated = previousUpdated
+		return before, err
+	}
+	return after, nil
+}
+
+// RunBrowserReleases 定期更新版本表：持有租约的实例按 interval 从更新源获取，其他实例重新加载已保存的版本表
+func (fs *FingerprintService) RunBrowserReleases(ctx context.Context) {
+	if fs.releaseConfig.Feed == "" {
+		return
+	}
+	ticker := time.NewTicker(releasesCheckInterval)
+	defer ticker.Stop()
+	for {
+		if err := fs.LoadBrowserReleases(ctx); err != nil {
+			log.Printf("Failed to load browser releases: %v", err)
+		}
+		if fs.IsLeader() && fs.releasesDue(time.Now()) {
+			if _, err := fs.RefreshBrowserReleases(ctx, "system"); err != nil {
+				log.Printf("Failed to refresh browser releases: %v", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// releasesDue 距上次成功更新和上次尝试都超过 interval 时需要更新
+func (fs *FingerprintService) releasesDue(now time.Time) bool {
+	fs.releasesMu.Lock()
+	defer fs.releasesMu.Unlock()
+	interval := fs.releaseConfig.Interval.Std()
+	if now.Sub(fs.releasesAttempt) < interval {
+		return false
+	}
+	return fs.releasesUpdated == nil || now.Sub(*fs.releasesUpdated) >= interval
+}
+
+// BrowserReleases 返回当前使用的版本表
+func (fs *FingerprintService) BrowserReleases() models.BrowserReleasesStatus {
+	fs.releasesMu.Lock()
+	defer fs.releasesMu.Unlock()
+	return fs.releasesStatus()
+}
+
+// releasesStatus 返回版本表的状态，调用方须持有 releasesMu
+func (fs *FingerprintService) releasesStatus() models.BrowserReleasesStatus {
+	t := fs.releaseTable()
+	now := time.Now()
+	status := models.BrowserReleasesStatus{
+		Version:   t.Version,
+		Source:    t.Source,
+		Feed:      fs.releaseConfig.Feed,
+		UpdatedAt: fs.releasesUpdated,
+		Browsers:  make([]models.BrowserRelease, 0, len(t.Browsers)),
+		Error:     fs.releasesError,
+	}
+	for i := range t.Browsers {
+		r := &t.Browsers[i]
+		status.Browsers = append(status.Browsers, models.BrowserRelease{
+			Family:      r.Family,
+			Stable:      r.Stable,
+			ReleasedAt:  r.ReleasedAt,
+			CadenceDays: r.CadenceDays,
+			Expected:    r.Expected(now),
+		})
+	}
+	return status
+}
+
+// 版本相对版本表的新旧
+const (
+	versionCurrent    = "current"
+	versionOutdated   = "outdated"
+	versionObsolete   = "obsolete"
+	versionUnreleased = "unreleased"
+)
+
+// classifyVersion 判断主版本在 now 时的新旧，家族不在版本表中或主版本未知时 ok 为false
+func (fs *FingerprintService) classifyVersion(t *releases.Table, family string, major int, now time.Time) (class string, r *releases.Release, ok bool) {
+	r, ok = t.Lookup(family)
+	if !ok || major <= 0 {
+		return "", nil, false
+	}
+	if major > r.Expected(now)+fs.releaseConfig.FutureMajors {
+		return versionUnreleased, r, true
+	}
+	switch superseded := r.Superseded(major, now); {
+	case superseded > fs.releaseConfig.ObsoleteAfter.Std():
+		return versionObsolete, r, true
+	case superseded > fs.releaseConfig.OutdatedAfter.Std():
+		return versionOutdated, r, true
+	}
+	return versionCurrent, r, true
+}
+
+// checkBrowserVersion 检查User Agent和 Sec-CH-UA 声明的主版本：比当前稳定版高出 future_majors 以上的尚未发布，
+// 被取代超过 obsolete_after 的不可能仍在使用（浏览器会自动更新），常见于随意拼写或多年未更新的爬虫UA
+func (fs *FingerprintService) checkBrowserVersion(fp *models.Fingerprint) []signal {
+	if fs.releaseConfig.Weight <= 0 {
+		return nil
+	}
+	ua := utils.ParseUserAgent(fp.UserAgent)
+	versions := append([]browserVersion{{family: ua.Family, major: ua.Major, source: "User-Agent"}}, parseClientHints(fp.ClientHintsUA)...)
+
+	t := fs.releaseTable()
+	now := time.Now()
+	var signals []signal
+	seen := make(map[string]bool)
+	for _, v := range versions {
+		class, r, ok := fs.classifyVersion(t, v.family, v.major, now)
+		if !ok || seen[class] {
+			continue
+		}
+		switch class {
+		case versionUnreleased:
+			signals = append(signals, signal{
+				Code:   models.ReasonBrowserVersionUnreleased,
+				Weight: fs.releaseConfig.Weight,
+				Reason: fmt.Sprintf("%s %d in %s is not released yet (current stable %d)", v.family, v.major, v.source, r.Expected(now)),
+			})
+		case versionObsolete:
+			signals = append(signals, signal{
+				Code:   models.ReasonBrowserVersionObsolete,
+				Weight: fs.releaseConfig.Weight,
+				Reason: fmt.Sprintf("%s %d in %s was superseded %.1f years ago (current stable %d)",
+					v.family, v.major, v.source, r.Superseded(v.major, now).Hours()/24/365, r.Expected(now)),
+			})
+		default:
+			continue
+		}
+		seen[class] = true
+	}
+	return signals
+}
+
+// BrowserVersionStats 按浏览器家族统计当前、过时、不可能仍在使用和尚未发布的版本的指纹数及其中判定为爬虫的数量，
+// 按首次出现时间和站点过滤；版本新旧按 to（为空时为当前时间）判断；结果按 query_cache 缓存
+func (fs *FingerprintService) BrowserVersionStats(ctx context.Context, from, to *time.Time, siteID string) ([]models.BrowserVersionStats, error) {
+	return cachedQuery(ctx, fs, queryKey("browsers", from, to, siteID), func(ctx context.Context) ([]models.BrowserVersionStats, error) {
+		return fs.browserVersionStats(ctx, from, to, siteID)
+	})
+}
+
+// browserVersionStats 查询浏览器版本统计，不使用缓存
+func (fs *FingerprintService) browserVersionStats(ctx context.Context, from, to *time.Time, siteID string) ([]models.BrowserVersionStats, error) {
+	where := []string{"f.deleted_at IS NULL"}
+	var args []interface{}
+	if from != nil {
+		where = append(where, "f.created_at >= ?")
+		args = append(args, *from)
+	}
+	if to != nil {
+		where = append(where, "f.created_at < ?")
+		args = append(args, *to)
+	}
+	if siteID != "" {
+		where = append(where, "f.site_id = ?")
+		args = append(args, siteID)
+	}
+
+	rows, err := fs.db.Read.QueryContext(ctx, `
+		SELECT f.user_agent, COUNT(*), COALESCE(SUM(CASE WHEN a.is_bot THEN 1 ELSE 0 END), 0)
+		FROM fingerprints f LEFT JOIN analysis a ON a.fingerprint_hash = f.fingerprint_hash
+		WHERE `+strings.Join(where, " AND ")+`
+		GROUP BY f.user_agent`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	if to != nil {
+		now = *to
+	}
+	t := fs.releaseTable()
+	families := make(map[string]*models.BrowserVersionStats)
+	for rows.Next() {
+		var userAgent string
+		var count, bots int
+		if err := rows.Scan(&userAgent, &count, &bots); err != nil {
+			return nil, err
+		}
+		ua := utils.ParseUserAgent(userAgent)
+		class, r, ok := fs.classifyVersion(t, ua.Family, ua.Major, now)
+		if !ok {
+			continue
+		}
+		s := families[r.Family]
+		if s == nil {
+			s = &models.BrowserVersionStats{Family: r.Family, Expected: r.Expected(now)}
+			families[r.Family] = s
+		}
+		traffic := map[string]*models.VersionTraffic{
+			versionCurrent:    &s.Current,
+			versionOutdated:   &s.Outdated,
+			versionObsolete:   &s.Obsolete,
+			versionUnreleased: &s.Unreleased,
+		}[class]
+		traffic.Count += count
+		traffic.Bots += bots
+		s.Count += count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	stats := make([]models.BrowserVersionStats, 0, len(families))
+	for _, s := range families {
+		s.OutdatedRate = ratio(s.Outdated.Count+s.Obsolete.Count, s.Count)
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		return stats[i].Family < stats[j].Family
+	})
+	return stats, nil
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+)
+
+// replayBatchSize 重放时每批读取的指纹数
+const replayBatchSize = 500
+
+// LoadCandidateRules 读取候选评分规则文件
+func LoadCandidateRules(path string) (*models.CandidateRules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules models.CandidateRules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid rules file: %w", err)
+	}
+	if rules.Thresholds != nil {
+		if errs := validateThresholds(*rules.Thresholds); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid thresholds: %s %s", errs[0].Field, errs[0].Constraint)
+		}
+	}
+	return &rules, nil
+}
+
+// candidateRulesFor 返回站点在候选规则下的评分规则
+func (fs *FingerprintService) candidateRulesFor(c *models.CandidateRules, siteID string) scoringRules {
+	rules := fs.rulesFor(siteID)
+	if c.Thresholds != nil {
+		rules.thresholds = *c.Thresholds
+	}
+	if site, ok := c.Sites[siteID]; ok {
+		rules.override.BotThreshold = site.BotThreshold
+		rules.override.RuleWeights = site.RuleWeights
+	}
+	if len(c.RuleWeights) > 0 {
+		weights := make(map[string]float64, len(c.RuleWeights)+len(rules.override.RuleWeights))
+		for code, w := range c.RuleWeights {
+			weights[code] = w
+		}
+		for code, w := range rules.override.RuleWeights {
+			weights[code] = w
+		}
+		rules.override.RuleWeights = weights
+	}
+	return rules
+}
+
+// ReplayRules 用当前规则和候选规则分别对已存储的指纹重新评分并比较，siteID 为空时包含全部站点
+// 两次评分使用相同的检测信号；只读取数据库，不修改存储的分析结果。
+// 客户端上报的噪点结论没有存储，重放时两侧都不计入
+func (fs *FingerprintService) ReplayRules(ctx context.Context, candidate *models.CandidateRules, siteID string, limit int) (*models.ReplayReport, error) {
+	report := &models.ReplayReport{RiskTransitions: make(map[string]int), Changes: []models.ReplayChange{}}
+	var baseScores, candidateScores []float64
+	baseRisk, candidateRisk := make(map[string]int), make(map[string]int)
+	baseBots, candidateBots := 0, 0
+
+	where := "id > ? AND deleted_at IS NULL"
+	if siteID != "" {
+		where += " AND site_id = ?"
+	}
+	where += " ORDER BY id LIMIT ?"
+
+	lastID := 0
+	for {
+		args := []interface{}{lastID}
+		if siteID != "" {
+			args = append(args, siteID)
+		}
+		batch, err := fs.loadFingerprints(ctx, where, append(args, replayBatchSize)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load fingerprints: %w", err)
+		}
+		for i := range batch {
+			fp := &batch[i].fp
+			req := &models.FingerprintRequest{
+				WebRTCLocalIPs:  utils.JSONToStringSlice(fp.WebRTCLocalIPs),
+				WebRTCPublicIPs: utils.JSONToStringSlice(fp.WebRTCPublicIPs),
+			}
+			variants := func(column, value string) int {
+				return fs.componentVariants(ctx, fp, column, value)
+			}
+			signals := fs.evaluateSignals(ctx, fp, req)
+			// 规则权重在信号上原地修改，两次评分各用一份
+			base := fs.scoreFingerprint(fp, req, append([]signal(nil), signals...), fs.rulesFor(fp.SiteID), variants)
+			cand := fs.scoreFingerprint(fp, req, signals, fs.candidateRulesFor(candidate, fp.SiteID), variants)
+
+			report.Fingerprints++
+			baseScores = append(baseScores, base.BotScore)
+			candidateScores = append(candidateScores, cand.BotScore)
+			baseRisk[base.RiskLevel]++
+			candidateRisk[cand.RiskLevel]++
+			if base.IsBot {
+				baseBots++
+			}
+			if cand.IsBot {
+				candidateBots++
+			}
+			switch {
+			case cand.IsBot && !base.IsBot:
+				report.BecameBot++
+			case base.IsBot && !cand.IsBot:
+				report.BecameHuman++
+			}
+			if cand.RiskLevel != base.RiskLevel {
+				report.RiskTransitions[base.RiskLevel+"->"+cand.RiskLevel]++
+			}
+			if cand.IsBot != base.IsBot || cand.RiskLevel != base.RiskLevel {
+				report.Changes = append(report.Changes, models.ReplayChange{
+					FingerprintHash: fp.FingerprintHash,
+					SiteID:          fp.SiteID,
+					BaselineScore:   base.BotScore,
+					CandidateScore:  cand.BotScore,
+					BaselineBot:     base.IsBot,
+					CandidateBot:    cand.IsBot,
+					BaselineRisk:    base.RiskLevel,
+					CandidateRisk:   cand.RiskLevel,
+					ReasonCodes:     utils.JSONToStringSlice(cand.ReasonCodes),
+				})
+			}
+		}
+		if len(batch) < replayBatchSize {
+			break
+		}
+		lastID = batch[len(batch)-1].id
+	}
+
+	report.Baseline = scoreDistribution(baseScores, baseRisk, baseBots)
+	report.Candidate = scoreDistribution(candidateScores, candidateRisk, candidateBots)
+	sort.SliceStable(report.Changes, func(i, j int) bool {
+		di := math.Abs(report.Changes[i].CandidateScore - report.Changes[i].BaselineScore)
+		dj := math.Abs(report.Changes[j].CandidateScore - report.Changes[j].BaselineScore)
+		return di > dj
+	})
+	if limit >= 0 && len(report.Changes) > limit {
+		report.Changes = report.Changes[:limit]
+	}
+	return report, nil
+}
+
+// scoreDistribution 计算评分分布
+func scoreDistribution(scores []float64, risk map[string]int, bots int) models.ScoreDistribution {
+	d := models.ScoreDistribution{RiskLevels: risk, Bots: bots}
+	if len(scores) == 0 {
+		return d
+	}
+	sorted := append([]float64(nil), scores...)
+	sort.Float64s(sorted)
+	sum := 0.0
+	for _, s := range sorted {
+		sum += s
+		d.Histogram[min(int(s*10), 9)]++
+	}
+	d.Mean = sum / float64(len(sorted))
+	at := func(p float64) float64 {
+		return sorted[min(int(p*float64(len(sorted))), len(sorted)-1)]
+	}
+	d.P50, d.P90, d.P99 = at(0.5), at(0.9), at(0.99)
+	return d
+}
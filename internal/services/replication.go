@@ -0,0 +1,506 @@
+package services
+
+import (
+	"browser-detection/internal/config"
+	"browser-detection/internal/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrReplicationDisabled 未配置 replication.region 时不提供同步状态
+var ErrReplicationDisabled = errors.New("replication is disabled")
+
+// 删除标记的实体类型
+const (
+	tombstoneBlock = "blocklist"
+	tombstoneLabel = "label"
+)
+
+// maxReplicationBatchBytes 一次拉取的响应体上限
+const maxReplicationBatchBytes = 64 << 20
+
+// logReplication 在写入本区域变更的同一事务中记录变更，供其他区域拉取；未配置 replication.region 时不记录。
+// 只记录本区域产生的变更，从其他区域拉取并应用的变更不再记录，避免在区域之间来回转发
+func (fs *FingerprintService) logReplication(ctx context.Context, db execer, kind string, data interface{}) error {
+	if fs.replication.Region == "" {
+		return nil
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx,
+		"INSERT INTO replication_log (kind, payload, created_at) VALUES (?, ?, ?)",
+		kind, string(payload), time.Now()); err != nil {
+		return fmt.Errorf("failed to record replication change: %w", err)
+	}
+	return nil
+}
+
+// saveTombstone 记录封禁名单条目或标注的删除时间，早于该时间产生的同一条目的远端变更不再应用
+func (fs *FingerprintService) saveTombstone(ctx context.Context, db execer, entity, key string, deletedAt time.Time) error {
+	if fs.replication.Region == "" {
+		return nil
+	}
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO replication_tombstones (entity, key, deleted_at) VALUES (?, ?, ?)
+		ON CONFLICT (entity, key) DO UPDATE SET deleted_at = MAX(deleted_at, excluded.deleted_at)`,
+		entity, key, deletedAt)
+	return err
+}
+
+// tombstone 返回条目的删除时间，没有删除标记时返回 nil
+func tombstone(ctx context.Context, tx *sql.Tx, entity, key string) (*time.Time, error) {
+	var deletedAt time.Time
+	err := tx.QueryRowContext(ctx,
+		"SELECT deleted_at FROM replication_tombstones WHERE entity = ? AND key = ?", entity, key).Scan(&deletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &deletedAt, nil
+}
+
+// ReplicationChanges 返回序号大于 since 的本区域变更，最多 limit 条。
+// since 之后的部分变更已超过保留期被删除时 Truncated 为 true；since 超过最新序号（本区域数据库已重建）时从头返回
+func (fs *FingerprintService) ReplicationChanges(ctx context.Context, since int64, limit int) (*models.ReplicationBatch, error) {
+	if fs.replication.Region == "" {
+		return nil, ErrReplicationDisabled
+	}
+	var lastSeq, oldest sql.NullInt64
+	if err := fs.db.DB.QueryRowContext(ctx,
+		"SELECT seq FROM sqlite_sequence WHERE name = 'replication_log'").Scan(&lastSeq); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err := fs.db.DB.QueryRowContext(ctx, "SELECT MIN(seq) FROM replication_log").Scan(&oldest); err != nil {
+		return nil, err
+	}
+
+	batch := &models.ReplicationBatch{Region: fs.replication.Region, Changes: []models.ReplicationChange{}}
+	if since > lastSeq.Int64 {
+		since = 0
+		batch.Truncated = lastSeq.Int64 > 0
+	}
+	if !oldest.Valid {
+		oldest.Int64 = lastSeq.Int64 + 1
+	}
+	if since+1 < oldest.Int64 {
+		batch.Truncated = true
+	}
+
+	rows, err := fs.db.DB.QueryContext(ctx,
+		"SELECT seq, kind, payload, created_at FROM replication_log WHERE seq > ? ORDER BY seq LIMIT ?", since, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var change models.ReplicationChange
+		var payload string
+		if err := rows.Scan(&change.Seq, &change.Kind, &payload, &change.CreatedAt); err != nil {
+			return nil, err
+		}
+		change.Data = json.RawMessage(payload)
+		batch.Changes = append(batch.Changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(batch.Changes) > limit {
+		batch.Changes = batch.Changes[:limit]
+		batch.More = true
+	}
+	batch.Next = since
+	if n := len(batch.Changes); n > 0 {
+		batch.Next = batch.Changes[n-1].Seq
+	}
+	return batch, nil
+}
+
+// ReplicationStatus 返回本区域最新的变更序号和从各区域拉取的进度
+func (fs *FingerprintService) ReplicationStatus(ctx context.Context) (*models.ReplicationStatus, error) {
+	if fs.replication.Region == "" {
+		return nil, ErrReplicationDisabled
+	}
+	status := &models.ReplicationStatus{Region: fs.replication.Region, Peers: []models.ReplicationPeerStatus{}}
+	var lastSeq sql.NullInt64
+	if err := fs.db.Read.QueryRowContext(ctx,
+		"SELECT seq FROM sqlite_sequence WHERE name = 'replication_log'").Scan(&lastSeq); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	status.LastSeq = lastSeq.Int64
+
+	for _, peer := range fs.replication.Peers {
+		p := models.ReplicationPeerStatus{Region: peer.Region, URL: peer.URL}
+		var lastPull, lastSuccess sql.NullTime
+		err := fs.db.Read.QueryRowContext(ctx, `
+			SELECT cursor, applied, last_pull_at, last_success_at, last_error FROM replication_peers WHERE region = ?`,
+			peer.Region).Scan(&p.Cursor, &p.Applied, &lastPull, &lastSuccess, &p.LastError)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		if lastPull.Valid {
+			p.LastPullAt = &lastPull.Time
+		}
+		if lastSuccess.Valid {
+			p.LastSuccessAt = &lastSuccess.Time
+		}
+		status.Peers = append(status.Peers, p)
+	}
+	return status, nil
+}
+
+// RunReplication 每隔 replication.interval 从各区域拉取变更，直到 ctx 取消；
+// 多实例共享数据库时只在主实例上拉取，未配置 replication.region 或没有其他区域时直接返回
+func (fs *FingerprintService) RunReplication(ctx context.Context) {
+	if fs.replication.Region == "" || len(fs.replication.Peers) == 0 {
+		return
+	}
+	client := &http.Client{Timeout: fs.replication.Timeout.Std()}
+	ticker := time.NewTicker(fs.replication.Interval.Std())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !fs.IsLeader() {
+				continue
+			}
+			var wg sync.WaitGroup
+			for _, peer := range fs.replication.Peers {
+				wg.Add(1)
+				go func(peer config.ReplicationPeer) {
+					defer wg.Done()
+					if err := fs.pullReplication(ctx, client, peer); err != nil {
+						log.Printf("Replication pull from %s failed: %v", peer.Region, err)
+					}
+				}(peer)
+			}
+			wg.Wait()
+		}
+	}
+}
+
+// pullReplication 从一个区域拉取并应用游标之后的全部变更，每批在一个事务中应用并推进游标
+func (fs *FingerprintService) pullReplication(ctx context.Context, client *http.Client, peer config.ReplicationPeer) error {
+	var cursor int64
+	if err := fs.db.DB.QueryRowContext(ctx,
+		"SELECT cursor FROM replication_peers WHERE region = ?", peer.Region).Scan(&cursor); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	for {
+		batch, err := fetchReplication(ctx, client, peer, cursor, fs.replication.BatchSize)
+		if err != nil {
+			fs.recordReplicationError(ctx, peer.Region, err)
+			return err
+		}
+		if batch.Truncated {
+			log.Printf("Replication from %s skipped changes purged before they were pulled; import a bundle from %s to resync", peer.Region, peer.Region)
+		}
+		if err := fs.applyReplication(ctx, peer.Region, batch); err != nil {
+			fs.recordReplicationError(ctx, peer.Region, err)
+			return err
+		}
+		cursor = batch.Next
+		if !batch.More {
+			return nil
+		}
+	}
+}
+
+// fetchReplication 请求对方区域的 GET /api/replication/changes
+func fetchReplication(ctx context.Context, client *http.Client, peer config.ReplicationPeer, since int64, limit int) (*models.ReplicationBatch, error) {
+	query := url.Values{"since": {strconv.FormatInt(since, 10)}, "limit": {strconv.Itoa(limit)}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		strings.TrimSuffix(peer.URL, "/")+"/api/replication/changes?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+peer.Token)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch changes: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("changes endpoint returned status %d", resp.StatusCode)
+	}
+	var batch models.ReplicationBatch
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxReplicationBatchBytes)).Decode(&batch); err != nil {
+		return nil, fmt.Errorf("failed to decode changes: %w", err)
+	}
+	if batch.Region != peer.Region {
+		return nil, fmt.Errorf("peer reports region %q", batch.Region)
+	}
+	return &batch, nil
+}
+
+// recordReplicationError 记录最近一次拉取失败的原因
+func (fs *FingerprintService) recordReplicationError(ctx context.Context, region string, pullErr error) {
+	if _, err := fs.db.DB.ExecContext(ctx, `
+		INSERT INTO replication_peers (region, last_pull_at, last_error) VALUES (?, ?, ?)
+		ON CONFLICT (region) DO UPDATE SET last_pull_at = excluded.last_pull_at, last_error = excluded.last_error`,
+		region, time.Now(), pullErr.Error()); err != nil {
+		log.Printf("Failed to record replication error for %s: %v", region, err)
+	}
+}
+
+// replicatedBlock 应用远端变更后需要同步到封禁名单缓存的条目
+type replicatedBlock struct {
+	kind, key string
+	expiresAt time.Time
+	removed   bool
+}
+
+// applyReplication 在一个事务中应用一批远端变更并推进该区域的游标，提交后更新封禁名单缓存
+func (fs *FingerprintService) applyReplication(ctx context.Context, region string, batch *models.ReplicationBatch) error {
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var blocks []replicatedBlock
+	applied := 0
+	for _, change := range batch.Changes {
+		ok, block, err := fs.applyChange(ctx, tx, change)
+		if err != nil {
+			return fmt.Errorf("failed to apply change %d: %w", change.Seq, err)
+		}
+		if ok {
+			applied++
+		}
+		if block != nil {
+			blocks = append(blocks, *block)
+		}
+	}
+	now := time.Now()
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO replication_peers (region, cursor, applied, last_pull_at, last_success_at, last_error) VALUES (?, ?, ?, ?, ?, '')
+		ON CONFLICT (region) DO UPDATE SET
+			cursor = excluded.cursor,
+			applied = applied + excluded.applied,
+			last_pull_at = excluded.last_pull_at,
+			last_success_at = excluded.last_success_at,
+			last_error = ''`,
+		region, batch.Next, applied, now, now); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, b := range blocks {
+		if b.removed {
+			fs.blocks.remove(b.kind, b.key)
+		} else if b.expiresAt.After(now) {
+			fs.blocks.put(b.kind, b.key, b.expiresAt)
+		}
+	}
+	return nil
+}
+
+// applyChange 应用一条远端变更，返回是否改变了本区域的数据；未知类型的变更（对方版本较新）跳过
+func (fs *FingerprintService) applyChange(ctx context.Context, tx *sql.Tx, change models.ReplicationChange) (bool, *replicatedBlock, error) {
+	switch change.Kind {
+	case models.ReplicationBlock:
+		var e models.BlockEntry
+		if err := json.Unmarshal(change.Data, &e); err != nil {
+			return false, nil, err
+		}
+		return applyBlock(ctx, tx, e)
+	case models.ReplicationUnblock:
+		var d models.ReplicationDelete
+		if err := json.Unmarshal(change.Data, &d); err != nil {
+			return false, nil, err
+		}
+		return fs.applyUnblock(ctx, tx, d)
+	case models.ReplicationReputation:
+		var r models.ReputationDelta
+		if err := json.Unmarshal(change.Data, &r); err != nil {
+			return false, nil, err
+		}
+		ok, err := fs.applyReputation(ctx, tx, r)
+		return ok, nil, err
+	case models.ReplicationLabel:
+		var l models.Label
+		if err := json.Unmarshal(change.Data, &l); err != nil {
+			return false, nil, err
+		}
+		ok, err := applyLabel(ctx, tx, l)
+		return ok, nil, err
+	case models.ReplicationUnlabel:
+		var d models.ReplicationDelete
+		if err := json.Unmarshal(change.Data, &d); err != nil {
+			return false, nil, err
+		}
+		ok, err := fs.applyUnlabel(ctx, tx, d)
+		return ok, nil, err
+	}
+	return false, nil, nil
+}
+
+// applyBlock 应用远端封禁：本区域在封禁之后解除过该条目时丢弃，已存在时取较晚的过期时间，原因取较新的封禁
+func applyBlock(ctx context.Context, tx *sql.Tx, e models.BlockEntry) (bool, *replicatedBlock, error) {
+	switch e.Kind {
+	case models.BlockFingerprint, models.BlockIP, models.BlockIPRange, models.BlockVisitor:
+	default:
+		return false, nil, nil
+	}
+	// 各区域的时间按本地时区存储，转换后才能与本区域的记录比较
+	createdAt, expiresAt := e.CreatedAt.Local(), e.ExpiresAt.Local()
+	deletedAt, err := tombstone(ctx, tx, tombstoneBlock, e.Kind+":"+e.Key)
+	if err != nil {
+		return false, nil, err
+	}
+	if deletedAt != nil && !deletedAt.Before(createdAt) {
+		return false, nil, nil
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO blocklist (kind, key, reason, created_at, expires_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (kind, key) DO UPDATE SET
+			reason = CASE WHEN excluded.created_at > created_at THEN excluded.reason ELSE reason END,
+			created_at = MAX(created_at, excluded.created_at),
+			expires_at = MAX(expires_at, excluded.expires_at)`,
+		e.Kind, e.Key, e.Reason, createdAt, expiresAt); err != nil {
+		return false, nil, err
+	}
+	return true, &replicatedBlock{kind: e.Kind, key: e.Key, expiresAt: expiresAt}, nil
+}
+
+// applyUnblock 应用远端解除封禁：只删除在解除之前产生的封禁，本区域之后重新封禁的条目保留
+func (fs *FingerprintService) applyUnblock(ctx context.Context, tx *sql.Tx, d models.ReplicationDelete) (bool, *replicatedBlock, error) {
+	deletedAt := d.DeletedAt.Local()
+	if err := fs.saveTombstone(ctx, tx, tombstoneBlock, d.Kind+":"+d.Key, deletedAt); err != nil {
+		return false, nil, err
+	}
+	res, err := tx.ExecContext(ctx,
+		"DELETE FROM blocklist WHERE kind = ? AND key = ? AND created_at <= ?", d.Kind, d.Key, deletedAt)
+	if err != nil {
+		return false, nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil, nil
+	}
+	return true, &replicatedBlock{kind: d.Kind, key: d.Key, removed: true}, nil
+}
+
+// applyReputation 将远端的IP信誉增量按其发生时间衰减后累加，结果与应用顺序无关
+func (fs *FingerprintService) applyReputation(ctx context.Context, tx *sql.Tx, r models.ReputationDelta) (bool, error) {
+	halfLife := fs.ipReputation.HalfLife.Std()
+	if halfLife <= 0 {
+		return false, nil
+	}
+	now, at := time.Now(), r.At.Local()
+	var bots, challenges, feeds float64
+	var updatedAt time.Time
+	err := tx.QueryRowContext(ctx,
+		"SELECT bot_detections, challenge_failures, feed_hits, updated_at FROM ip_reputation WHERE ip = ?", r.IP).
+		Scan(&bots, &challenges, &feeds, &updatedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+	factor, deltaFactor := decayFactor(now.Sub(updatedAt), halfLife), decayFactor(now.Sub(at), halfLife)
+	bots = bots*factor + r.BotDetections*deltaFactor
+	challenges = challenges*factor + r.ChallengeFailures*deltaFactor
+	feeds *= factor
+
+	var lastBot, lastChallenge *time.Time
+	if r.BotDetections > 0 {
+		lastBot = &at
+	}
+	if r.ChallengeFailures > 0 {
+		lastChallenge = &at
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO ip_reputation (ip, bot_detections, challenge_failures, feed_hits, submissions, first_seen, last_seen,
+			last_bot_at, last_challenge_failure_at, updated_at)
+		VALUES (?, ?, ?, ?, 0, ?, ?, ?, ?, ?)
+		ON CONFLICT (ip) DO UPDATE SET
+			bot_detections = excluded.bot_detections,
+			challenge_failures = excluded.challenge_failures,
+			feed_hits = excluded.feed_hits,
+			first_seen = MIN(COALESCE(first_seen, excluded.first_seen), excluded.first_seen),
+			last_seen = MAX(COALESCE(last_seen, excluded.last_seen), excluded.last_seen),
+			last_bot_at = MAX(COALESCE(last_bot_at, excluded.last_bot_at), COALESCE(excluded.last_bot_at, last_bot_at)),
+			last_challenge_failure_at = MAX(COALESCE(last_challenge_failure_at, excluded.last_challenge_failure_at),
+				COALESCE(excluded.last_challenge_failure_at, last_challenge_failure_at)),
+			updated_at = excluded.updated_at`,
+		r.IP, bots, challenges, feeds, at, at, lastBot, lastChallenge, now); err != nil {
+		return false, fmt.Errorf("failed to save IP reputation: %w", err)
+	}
+	return true, nil
+}
+
+// applyLabel 应用远端标注，按更新时间以较新的为准，时间相同时保留本区域的标注；
+// 本区域没有该指纹时也保存，指纹之后在本区域提交时即可参与评估
+func applyLabel(ctx context.Context, tx *sql.Tx, l models.Label) (bool, error) {
+	if l.Label != models.LabelBot && l.Label != models.LabelHuman {
+		return false, nil
+	}
+	createdAt, updatedAt := l.CreatedAt.Local(), l.UpdatedAt.Local()
+	deletedAt, err := tombstone(ctx, tx, tombstoneLabel, l.FingerprintHash)
+	if err != nil {
+		return false, err
+	}
+	if deletedAt != nil && !deletedAt.Before(updatedAt) {
+		return false, nil
+	}
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO labels (fingerprint_hash, label, note, actor, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (fingerprint_hash) DO UPDATE SET
+			label = excluded.label,
+			note = excluded.note,
+			actor = excluded.actor,
+			created_at = MIN(created_at, excluded.created_at),
+			updated_at = excluded.updated_at
+		WHERE excluded.updated_at > labels.updated_at`,
+		l.FingerprintHash, l.Label, l.Note, l.Actor, createdAt, updatedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to save label: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// applyUnlabel 应用远端删除标注：只删除在删除之前更新的标注
+func (fs *FingerprintService) applyUnlabel(ctx context.Context, tx *sql.Tx, d models.ReplicationDelete) (bool, error) {
+	deletedAt := d.DeletedAt.Local()
+	if err := fs.saveTombstone(ctx, tx, tombstoneLabel, d.Key, deletedAt); err != nil {
+		return false, err
+	}
+	res, err := tx.ExecContext(ctx,
+		"DELETE FROM labels WHERE fingerprint_hash = ? AND updated_at <= ?", d.Key, deletedAt)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// purgeReplication 删除超过 replication.retention 的变更记录和删除标记
+func (fs *FingerprintService) purgeReplication(ctx context.Context) error {
+	if fs.replication.Region == "" {
+		return nil
+	}
+	cutoff := time.Now().Add(-fs.replication.Retention.Std())
+	if _, err := fs.db.DB.ExecContext(ctx, "DELETE FROM replication_log WHERE created_at < ?", cutoff); err != nil {
+		return err
+	}
+	_, err := fs.db.DB.ExecContext(ctx, "DELETE FROM replication_tombstones WHERE deleted_at < ?", cutoff)
+	return err
+}
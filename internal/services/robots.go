@@ -0,0 +1,361 @@
+package services
+
+import (
+	"browser-detection/internal/config"
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// robotsRetryInterval 抓取 robots.txt 失败后的重试间隔
+const robotsRetryInterval = 10 * time.Minute
+
+// maxRobotsViolations 报告中每个爬虫列出的禁止路径数
+const maxRobotsViolations = 10
+
+// robotsEntry 站点 robots.txt 的缓存，robots 为 nil 表示从未成功获取
+type robotsEntry struct {
+	url       string
+	robots    *utils.Robots
+	fetchedAt time.Time
+	attempt   time.Time
+	err       string
+	fetching  bool
+}
+
+// siteRobotsURL 返回站点的 robots.txt 地址：robots_url，未配置时为 cors.allowed_origins 中第一个不含通配符的源
+func siteRobotsURL(site config.SiteConfig) string {
+	if site.RobotsURL != "" {
+		return site.RobotsURL
+	}
+	for _, origin := range site.CORS.AllowedOrigins {
+		if origin == "" || strings.Contains(origin, "*") {
+			continue
+		}
+		return strings.TrimSuffix(origin, "/") + "/robots.txt"
+	}
+	return ""
+}
+
+// robotsPath 由页面路径或完整URL得到 robots.txt 规则匹配的路径（含查询串），无法解析时返回空
+func robotsPath(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	p := u.EscapedPath()
+	if p == "" {
+		p = "/"
+	}
+	if u.RawQuery != "" {
+		p += "?" + u.RawQuery
+	}
+	if len(p) > maxNavigationPathLength {
+		p = p[:maxNavigationPathLength]
+	}
+	return p
+}
+
+// fingerprintPage 返回采集页面：采集端上报的 location.pathname，未上报时为 Referer
+func fingerprintPage(fp *models.Fingerprint, req *models.FingerprintRequest) string {
+	if req != nil && req.Navigation != nil && req.Navigation.Page != "" {
+		return req.Navigation.Page
+	}
+	return fp.PageURL
+}
+
+// siteRobots 返回站点缓存的 robots.txt；过期或尚未获取时在后台抓取，抓取完成前返回之前的内容，从未成功时为 nil
+func (fs *FingerprintService) siteRobots(siteID string) *utils.Robots {
+	site, ok := fs.sites[siteID]
+	if !ok {
+		return nil
+	}
+	fs.robotsMu.Lock()
+	defer fs.robotsMu.Unlock()
+	e := fs.robotsCache[siteID]
+	if e == nil {
+		u := siteRobotsURL(site)
+		if u == "" {
+			return nil
+		}
+		e = &robotsEntry{url: u}
+		fs.robotsCache[siteID] = e
+	}
+	now := time.Now()
+	stale := e.robots == nil || now.Sub(e.fetchedAt) >= fs.robotsConfig.TTL.Std()
+	if stale && !e.fetching && (e.err == "" || now.Sub(e.attempt) >= robotsRetryInterval) {
+		e.fetching = true
+		e.attempt = now
+		go fs.refreshRobots(siteID, e.url)
+	}
+	return e.robots
+}
+
+// refreshRobots 抓取站点的 robots.txt 并更新缓存，失败时保留之前的内容
+func (fs *FingerprintService) refreshRobots(siteID, robotsURL string) {
+	robots, err := fetchRobots(context.Background(), robotsURL, fs.robotsConfig.Timeout.Std())
+
+	fs.robotsMu.Lock()
+	defer fs.robotsMu.Unlock()
+	e := fs.robotsCache[siteID]
+	e.fetching = false
+	if err != nil {
+		e.err = err.Error()
+		log.Printf("Failed to fetch robots.txt for site %s from %s: %v", siteID, robotsURL, err)
+		return
+	}
+	e.robots, e.fetchedAt, e.err = robots, time.Now(), ""
+}
+
+// fetchRobots 下载并解析 robots.txt；4xx 视为不存在，允许所有路径，其他非2xx状态和网络错误返回错误
+func fetchRobots(ctx context.Context, robotsURL string, timeout time.Duration) (*utils.Robots, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download robots.txt: %w", err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return utils.ParseRobots(nil), nil
+	default:
+		return nil, fmt.Errorf("robots.txt returned status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, utils.MaxRobotsBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read robots.txt: %w", err)
+	}
+	return utils.ParseRobots(data), nil
+}
+
+// auditRobots 检查爬虫访问的路径是否被站点的 robots.txt 禁止并计入审计记录，
+// 未启用审计、未识别为爬虫、没有路径或 robots.txt 尚未获取时 disallowed 为 nil
+func (fs *FingerprintService) auditRobots(ctx context.Context, siteID string, c *models.Crawler, rawPath string) (disallowed *bool, rule string, err error) {
+	if fs.robotsConfig.TTL <= 0 || c == nil || rawPath == "" {
+		return nil, "", nil
+	}
+	p := robotsPath(rawPath)
+	if p == "" {
+		return nil, "", nil
+	}
+	robots := fs.siteRobots(siteID)
+	if robots == nil {
+		return nil, "", nil
+	}
+	allowed, rule := robots.Allowed(fs.crawlerTable.RobotsAgents(c.Name), p)
+
+	var violations int
+	if !allowed {
+		violations = 1
+	}
+	now := time.Now()
+	if _, err := fs.db.DB.ExecContext(ctx, `
+		INSERT INTO robots_audit (site_id, day, crawler, operator, category, purpose, requests, disallowed)
+		VALUES (?, ?, ?, ?, ?, ?, 1, ?)
+		ON CONFLICT (site_id, day, crawler) DO UPDATE SET
+			requests = requests + 1,
+			disallowed = disallowed + excluded.disallowed,
+			operator = excluded.operator,
+			category = excluded.category,
+			purpose = excluded.purpose`,
+		siteID, now.UTC().Truncate(crawlerDay).Unix(), c.Name, c.Operator, c.Category, c.Purpose, violations); err != nil {
+		return nil, "", err
+	}
+	if !allowed {
+		if _, err := fs.db.DB.ExecContext(ctx, `
+			INSERT INTO robots_violations (site_id, crawler, path, rule, count, first_seen, last_seen)
+			VALUES (?, ?, ?, ?, 1, ?, ?)
+			ON CONFLICT (site_id, crawler, path) DO UPDATE SET
+				count = count + 1,
+				rule = excluded.rule,
+				last_seen = excluded.last_seen`,
+			siteID, c.Name, p, rule, now, now); err != nil {
+			return nil, "", err
+		}
+	}
+	blocked := !allowed
+	return &blocked, rule, nil
+}
+
+// auditPageView 对已识别爬虫的 page_view 事件审计 robots.txt 遵守情况
+func (fs *FingerprintService) auditPageView(ctx context.Context, event *models.Event) error {
+	if fs.robotsConfig.TTL <= 0 || event.EventType != models.EventPageView {
+		return nil
+	}
+	p, ok := pageViewPath(event.Metadata)
+	if !ok {
+		return nil
+	}
+	c, err := fs.fingerprintCrawler(ctx, event.FingerprintHash)
+	if err != nil || c == nil {
+		return err
+	}
+	_, _, err = fs.auditRobots(ctx, event.SiteID, c, p)
+	return err
+}
+
+// purgeRobotsViolations 删除超过保留期未再出现的违规路径记录
+func (fs *FingerprintService) purgeRobotsViolations(ctx context.Context) error {
+	if fs.robotsConfig.TTL <= 0 {
+		return nil
+	}
+	_, err := fs.db.DB.ExecContext(ctx,
+		"DELETE FROM robots_violations WHERE last_seen < ?", time.Now().Add(-fs.robotsConfig.Retention.Std()))
+	return err
+}
+
+// robotsStatus 返回站点 robots.txt 的缓存状态，siteID 为空时返回所有站点
+func (fs *FingerprintService) robotsStatus(siteID string) []models.RobotsStatus {
+	statuses := []models.RobotsStatus{}
+	fs.robotsMu.Lock()
+	defer fs.robotsMu.Unlock()
+	for id, site := range fs.sites {
+		if siteID != "" && id != siteID {
+			continue
+		}
+		s := models.RobotsStatus{SiteID: id, URL: siteRobotsURL(site)}
+		if e := fs.robotsCache[id]; e != nil {
+			s.Error = e.err
+			if e.robots != nil {
+				fetchedAt := e.fetchedAt
+				s.Groups, s.FetchedAt = e.robots.Groups(), &fetchedAt
+			}
+		}
+		statuses = append(statuses, s)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].SiteID < statuses[j].SiteID })
+	return statuses
+}
+
+// RobotsReport 返回 [from, to] 内已识别爬虫的 robots.txt 遵守情况，访问过禁止路径的爬虫单独列出并给出最近的禁止路径；
+// from、to 为空时取最近30天，siteID 为空时合并所有站点；结果按 query_cache 缓存
+func (fs *FingerprintService) RobotsReport(ctx context.Context, from, to *time.Time, siteID string) (*models.RobotsReport, error) {
+	return cachedQuery(ctx, fs, queryKey("robots", from, to, siteID), func(ctx context.Context) (*models.RobotsReport, error) {
+		return fs.robotsReport(ctx, from, to, siteID)
+	})
+}
+
+// robotsReport 查询robots.txt 遵守情况报告，不使用缓存
+func (fs *FingerprintService) robotsReport(ctx context.Context, from, to *time.Time, siteID string) (*models.RobotsReport, error) {
+	end := time.Now()
+	if to != nil {
+		end = *to
+	}
+	start := end.Add(-30 * crawlerDay)
+	if from != nil {
+		start = *from
+	}
+	report := &models.RobotsReport{
+		SiteID:       siteID,
+		From:         start,
+		To:           end,
+		NonCompliant: []models.RobotsCrawlerStats{},
+		Compliant:    []models.RobotsCrawlerStats{},
+		Robots:       fs.robotsStatus(siteID),
+	}
+
+	query := `
+		SELECT crawler, MAX(operator), MAX(category), MAX(purpose), SUM(requests), SUM(disallowed)
+		FROM robots_audit WHERE day >= ? AND day <= ?`
+	args := []interface{}{start.UTC().Truncate(crawlerDay).Unix(), end.UTC().Truncate(crawlerDay).Unix()}
+	if siteID != "" {
+		query += " AND site_id = ?"
+		args = append(args, siteID)
+	}
+	rows, err := fs.db.Read.QueryContext(ctx, query+" GROUP BY crawler ORDER BY SUM(disallowed) DESC, crawler", args...)
+	if err != nil {
+		return nil, err
+	}
+	var crawlers []models.RobotsCrawlerStats
+	for rows.Next() {
+		var s models.RobotsCrawlerStats
+		if err := rows.Scan(&s.Name, &s.Operator, &s.Category, &s.Purpose, &s.Requests, &s.Disallowed); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		s.ComplianceRate = 1 - ratio(s.Disallowed, s.Requests)
+		crawlers = append(crawlers, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, s := range crawlers {
+		if s.Disallowed == 0 {
+			report.Compliant = append(report.Compliant, s)
+			continue
+		}
+		if s.Violations, err = fs.robotsViolations(ctx, s.Name, siteID, start, end); err != nil {
+			return nil, err
+		}
+		report.NonCompliant = append(report.NonCompliant, s)
+	}
+	return report, nil
+}
+
+// robotsViolations 返回爬虫在 [from, to] 内最后访问过的禁止路径，按访问次数排序
+func (fs *FingerprintService) robotsViolations(ctx context.Context, crawler, siteID string, from, to time.Time) ([]models.RobotsViolation, error) {
+	query := `
+		SELECT path, rule, count, last_seen FROM robots_violations
+		WHERE crawler = ? AND last_seen >= ? AND last_seen <= ?`
+	args := []interface{}{crawler, from, to}
+	if siteID != "" {
+		query += " AND site_id = ?"
+		args = append(args, siteID)
+	}
+	rows, err := fs.db.Read.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// 合并所有站点时同一路径可能出现在多个站点
+	byPath := make(map[string]*models.RobotsViolation)
+	for rows.Next() {
+		var v models.RobotsViolation
+		if err := rows.Scan(&v.Path, &v.Rule, &v.Count, &v.LastSeen); err != nil {
+			return nil, err
+		}
+		if merged := byPath[v.Path]; merged != nil {
+			merged.Count += v.Count
+			if v.LastSeen.After(merged.LastSeen) {
+				merged.LastSeen = v.LastSeen
+			}
+			continue
+		}
+		byPath[v.Path] = &v
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	violations := make([]models.RobotsViolation, 0, len(byPath))
+	for _, v := range byPath {
+		violations = append(violations, *v)
+	}
+	sort.Slice(violations, func(i, j int) bool {
+		if violations[i].Count != violations[j].Count {
+			return violations[i].Count > violations[j].Count
+		}
+		return violations[i].Path < violations[j].Path
+	})
+	if len(violations) > maxRobotsViolations {
+		violations = violations[:maxRobotsViolations]
+	}
+	return violations, nil
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"browser-detection/internal/config"
+	"browser-detection/internal/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ruleSetSetting settings 表中当前生效的规则集版本的键
+const ruleSetSetting = "rule_set"
+
+// activeRuleSet 当前生效的规则集及其编译后的表达式
+type activeRuleSet struct {
+	version int64
+	rules   []exprRule
+}
+
+// LoadRuleSet 加载通过管理API设置的规则集版本，未设置过时使用配置文件中的表达式（版本0）
+func (fs *FingerprintService) LoadRuleSet(ctx context.Context) error {
+	var value string
+	err := fs.db.DB.QueryRowContext(ctx,
+		"SELECT value FROM settings WHERE key = ?", ruleSetSetting).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	version, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid stored rule set version %q", value)
+	}
+	set, err := fs.RuleSetVersion(ctx, version)
+	if err != nil {
+		return fmt.Errorf("failed to load rule set version %d: %w", version, err)
+	}
+	fs.ruleSet.Store(&activeRuleSet{version: version, rules: newExprRules(set.Rules)})
+	return nil
+}
+
+// validateRules 校验规则集：名称符合格式、不重复且不与内置原因代码重名，表达式可编译且只引用已定义的变量，
+// 权重在 [-1, 1] 内
+func validateRules(rules []models.ScoreRule) []models.FieldError {
+	builtinCodes := make(map[string]bool, len(models.ReasonCodes))
+	for _, code := range models.ReasonCodes {
+		builtinCodes[code] = true
+	}
+	names := make(map[string]bool, len(rules))
+	var errs []models.FieldError
+	for i, r := range rules {
+		field := "rules[" + strconv.Itoa(i) + "]"
+		switch {
+		case !config.ExpressionNamePattern.MatchString(r.Name):
+			errs = append(errs, models.FieldError{Field: field + ".name", Constraint: "match " + config.ExpressionNamePattern.String(), Got: r.Name})
+		case builtinCodes[r.Name]:
+			errs = append(errs, models.FieldError{Field: field + ".name", Constraint: "differ from built-in reason codes", Got: r.Name})
+		case names[r.Name]:
+			errs = append(errs, models.FieldError{Field: field + ".name", Constraint: "unique", Got: r.Name})
+		}
+		names[r.Name] = true
+		if _, err := compileExprRule(r); err != nil {
+			errs = append(errs, models.FieldError{Field: field + ".expr", Constraint: err.Error(), Got: r.Expr})
+		}
+		if r.Weight < -1 || r.Weight > 1 {
+			errs = append(errs, models.FieldError{Field: field + ".weight", Constraint: "range [-1, 1]", Got: r.Weight})
+		}
+	}
+	return errs
+}
+
+// ValidateRules 校验规则集但不保存，供管理页面在发布前检查
+func (fs *FingerprintService) ValidateRules(rules []models.ScoreRule) []models.FieldError {
+	return validateRules(rules)
+}
+
+// RuleSet 返回当前生效的规则集
+func (fs *FingerprintService) RuleSet(ctx context.Context) (*models.RuleSet, error) {
+	return fs.RuleSetVersion(ctx, fs.ruleSet.Load().version)
+}
+
+// RuleSetVersion 返回规则集的一个版本，版本0为配置文件中的表达式；版本不存在时返回 sql.ErrNoRows
+func (fs *FingerprintService) RuleSetVersion(ctx context.Context, version int64) (*models.RuleSet, error) {
+	active := fs.ruleSet.Load().version
+	if version == 0 {
+		return &models.RuleSet{Rules: fs.configRules, Actor: "config", Active: active == 0}, nil
+	}
+	set := &models.RuleSet{Version: version, Active: active == version}
+	var rules string
+	var createdAt time.Time
+	if err := fs.db.Read.QueryRowContext(ctx,
+		"SELECT rules, comment, actor, created_at FROM rule_sets WHERE version = ?", version).
+		Scan(&rules, &set.Comment, &set.Actor, &createdAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(rules), &set.Rules); err != nil {
+		return nil, fmt.Errorf("invalid stored rule set version %d: %w", version, err)
+	}
+	set.CreatedAt = &createdAt
+	return set, nil
+}
+
+// RuleSetVersions 按版本倒序返回最近 limit 个规则集版本，最后是配置文件中的表达式（版本0）
+func (fs *FingerprintService) RuleSetVersions(ctx context.Context, limit int) ([]models.RuleSet, error) {
+	rows, err := fs.db.Read.QueryContext(ctx,
+		"SELECT version FROM rule_sets ORDER BY version DESC LIMIT ?", limit)
+	if err != nil {
+		return nil, err
+	}
+	var versions []int64
+	for rows.Next() {
+		var v int64
+		if err := rows.Scan(&v); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(versions) < limit {
+		versions = append(versions, 0)
+	}
+
+	sets := make([]models.RuleSet, 0, len(versions))
+	for _, v := range versions {
+		set, err := fs.RuleSetVersion(ctx, v)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, *set)
+	}
+	return sets, nil
+}
+
+// SaveRuleSet 校验并保存新的规则集版本，立即对之后的提交生效，并写入审计记录；
+// 规则不合法时返回字段级错误
+func (fs *FingerprintService) SaveRuleSet(ctx context.Context, rules []models.ScoreRule, comment, actor string) (*models.RuleSet, []models.FieldError, error) {
+	if errs := validateRules(rules); len(errs) > 0 {
+		return nil, errs, nil
+	}
+	set, err := fs.saveRuleSet(ctx, rules, comment, actor, "update_rules")
+	return set, nil, err
+}
+
+// RollbackRuleSet 以指定版本的规则创建新的规则集版本并生效，写入审计记录；
+// 版本不存在时返回 sql.ErrNoRows，旧版本的规则已不合法（如与新增的内置原因代码重名）时返回字段级错误
+func (fs *FingerprintService) RollbackRuleSet(ctx context.Context, version int64, comment, actor string) (*models.RuleSet, []models.FieldError, error) {
+	target, err := fs.RuleSetVersion(ctx, version)
+	if err != nil {
+		return nil, nil, err
+	}
+	if errs := validateRules(target.Rules); len(errs) > 0 {
+		return nil, errs, nil
+	}
+	if comment == "" {
+		comment = "rollback to version " + strconv.FormatInt(version, 10)
+	}
+	set, err := fs.saveRuleSet(ctx, target.Rules, comment, actor, "rollback_rules")
+	return set, nil, err
+}
+
+// saveRuleSet 在事务中写入新的规则集版本、更新生效版本并写入审计记录，提交后替换当前规则
+func (fs *FingerprintService) saveRuleSet(ctx context.Context, rules []models.ScoreRule, comment, actor, action string) (*models.RuleSet, error) {
+	if rules == nil {
+		rules = []models.ScoreRule{}
+	}
+	value, err := json.Marshal(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	fs.rulesMu.Lock()
+	defer fs.rulesMu.Unlock()
+	before := fs.ruleSet.Load().version
+
+	now := time.Now()
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		"INSERT INTO rule_sets (rules, comment, actor, created_at) VALUES (?, ?, ?, ?)",
+		string(value), comment, actor, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save rule set: %w", err)
+	}
+	version, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"INSERT OR REPLACE INTO settings (key, value, updated_at) VALUES (?, ?, ?)",
+		ruleSetSetting, strconv.FormatInt(version, 10), now); err != nil {
+		return nil, fmt.Errorf("failed to activate rule set: %w", err)
+	}
+	if err := recordAudit(ctx, tx, actor, action, ruleSetSetting,
+		map[string]int64{"version": before}, map[string]interface{}{"version": version, "rules": len(rules), "comment": comment}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	fs.ruleSet.Store(&activeRuleSet{version: version, rules: newExprRules(rules)})
+	return &models.RuleSet{Version: version, Rules: rules, Comment: comment, Actor: actor, CreatedAt: &now, Active: true}, nil
+}
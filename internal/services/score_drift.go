@@ -0,0 +1,353 @@
+package services
+
+import (
+	"browser-detection/internal/alerting"
+	"browser-detection/internal/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+// scoreBins 评分直方图的区间数，每个区间宽0.05
+const scoreBins = 20
+
+// psiEpsilon 计算PSI时区间比例的下限，避免空区间取对数
+const psiEpsilon = 1e-4
+
+// scoreHistogram 评分直方图：各区间的提交数和全部评分之和
+type scoreHistogram struct {
+	counts [scoreBins]int
+	sum    float64
+}
+
+// scoreBin 返回评分所在的区间，1.0 归入最后一个区间
+func scoreBin(score float64) int {
+	return max(0, min(int(score*scoreBins), scoreBins-1))
+}
+
+// total 返回提交数
+func (h *scoreHistogram) total() int {
+	n := 0
+	for _, c := range h.counts {
+		n += c
+	}
+	return n
+}
+
+// mean 返回平均评分
+func (h *scoreHistogram) mean() float64 {
+	if n := h.total(); n > 0 {
+		return h.sum / float64(n)
+	}
+	return 0
+}
+
+// merge 累加另一个直方图
+func (h *scoreHistogram) merge(o *scoreHistogram) {
+	for i, c := range o.counts {
+		h.counts[i] += c
+	}
+	h.sum += o.sum
+}
+
+// quantile 返回分位数，在所在区间内按均匀分布插值
+func (h *scoreHistogram) quantile(q float64) float64 {
+	n := h.total()
+	if n == 0 {
+		return 0
+	}
+	target := q * float64(n)
+	seen := 0.0
+	for i, c := range h.counts {
+		if c > 0 && seen+float64(c) >= target {
+			return (float64(i) + (target-seen)/float64(c)) / scoreBins
+		}
+		seen += float64(c)
+	}
+	return 1
+}
+
+// populationStability 计算当前分布相对基线的群体稳定性指数：Σ(p-q)·ln(p/q)
+func populationStability(current, baseline *scoreHistogram) float64 {
+	n, m := current.total(), baseline.total()
+	if n == 0 || m == 0 {
+		return 0
+	}
+	psi := 0.0
+	for i := range current.counts {
+		p := math.Max(float64(current.counts[i])/float64(n), psiEpsilon)
+		q := math.Max(float64(baseline.counts[i])/float64(m), psiEpsilon)
+		psi += (p - q) * math.Log(p/q)
+	}
+	return psi
+}
+
+// scoreBucket 返回时间所在评分统计桶的起始时间（Unix秒）
+func (fs *FingerprintService) scoreBucket(t time.Time) int64 {
+	return t.Truncate(fs.anomaly.Scores.Bucket.Std()).Unix()
+}
+
+// recordScore 累计站点当前评分统计桶的直方图
+func (fs *FingerprintService) recordScore(ctx context.Context, siteID string, score float64) error {
+	if fs.anomaly.Scores.Bucket <= 0 {
+		return nil
+	}
+	_, err := fs.db.DB.ExecContext(ctx, `
+		INSERT INTO score_stats (site_id, bucket, bin, count, score_sum) VALUES (?, ?, ?, 1, ?)
+		ON CONFLICT (site_id, bucket, bin) DO UPDATE SET
+			count = count + 1,
+			score_sum = score_sum + excluded.score_sum`,
+		siteID, fs.scoreBucket(time.Now()), scoreBin(score), score)
+	return err
+}
+
+// loadScoreHistograms 读取 [from, to) 内各统计桶的直方图，siteID 为空时合并所有站点
+func loadScoreHistograms(ctx context.Context, db *sql.DB, siteID string, from, to int64) (map[int64]*scoreHistogram, error) {
+	query := "SELECT bucket, bin, count, score_sum FROM score_stats WHERE bucket >= ? AND bucket < ?"
+	args := []interface{}{from, to}
+	if siteID != "" {
+		query += " AND site_id = ?"
+		args = append(args, siteID)
+	}
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	histograms := make(map[int64]*scoreHistogram)
+	for rows.Next() {
+		var bucket int64
+		var bin, count int
+		var sum float64
+		if err := rows.Scan(&bucket, &bin, &count, &sum); err != nil {
+			return nil, err
+		}
+		if bin < 0 || bin >= scoreBins {
+			continue
+		}
+		h := histograms[bucket]
+		if h == nil {
+			h = &scoreHistogram{}
+			histograms[bucket] = h
+		}
+		h.counts[bin] += count
+		h.sum += sum
+	}
+	return histograms, rows.Err()
+}
+
+// scoreDrift 比较 bucket 与之前滚动基线的评分分布，历史不足基线窗口的四分之一或当前桶没有提交时返回 nil
+func (fs *FingerprintService) scoreDrift(ctx context.Context, db *sql.DB, siteID string, bucket int64) (*models.ScoreDrift, error) {
+	cfg := fs.anomaly.Scores
+	step := int64(cfg.Bucket.Std() / time.Second)
+	histograms, err := loadScoreHistograms(ctx, db, siteID, bucket-int64(cfg.BaselineBuckets)*step, bucket+step)
+	if err != nil {
+		return nil, err
+	}
+	current := histograms[bucket]
+	if current == nil {
+		return nil, nil
+	}
+
+	first := bucket
+	baseline := &scoreHistogram{}
+	for b, h := range histograms {
+		if b < bucket {
+			first = min(first, b)
+			baseline.merge(h)
+		}
+	}
+	if (bucket-first)/step < int64(max(1, cfg.BaselineBuckets/4)) {
+		return nil, nil
+	}
+
+	drift := &models.ScoreDrift{
+		BucketStart:         time.Unix(bucket, 0),
+		Submissions:         current.total(),
+		BaselineSubmissions: baseline.total(),
+		Mean:                current.mean(),
+		BaselineMean:        baseline.mean(),
+		PSI:                 populationStability(current, baseline),
+	}
+	drift.Drifted = drift.PSI >= cfg.PSI && drift.Submissions >= cfg.MinSubmissions
+	return drift, nil
+}
+
+// CheckScoreDrift 检查 now 之前最后一个完整评分统计桶，评分分布相对基线的PSI超过配置值时记录异常并告警
+func (fs *FingerprintService) CheckScoreDrift(ctx context.Context, now time.Time) ([]models.Anomaly, error) {
+	cfg := fs.anomaly.Scores
+	if cfg.Bucket <= 0 {
+		return nil, nil
+	}
+	bucket := fs.scoreBucket(now) - int64(cfg.Bucket.Std()/time.Second)
+
+	rows, err := fs.db.DB.QueryContext(ctx,
+		"SELECT site_id FROM score_stats WHERE bucket = ? GROUP BY site_id HAVING SUM(count) >= ?", bucket, cfg.MinSubmissions)
+	if err != nil {
+		return nil, err
+	}
+	var sites []string
+	for rows.Next() {
+		var siteID string
+		if err := rows.Scan(&siteID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		sites = append(sites, siteID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var anomalies []models.Anomaly
+	for _, siteID := range sites {
+		drift, err := fs.scoreDrift(ctx, fs.db.DB, siteID, bucket)
+		if err != nil {
+			return nil, err
+		}
+		if drift == nil || !drift.Drifted {
+			continue
+		}
+
+		a := models.Anomaly{
+			SiteID:      siteID,
+			Kind:        models.AnomalyScoreDrift,
+			BucketStart: drift.BucketStart,
+			Observed:    drift.PSI,
+			Baseline:    cfg.PSI,
+			Factor:      drift.PSI / cfg.PSI,
+		}
+		a.Message = fmt.Sprintf("%s for site %q: PSI %.3f over %d submissions, mean score %.3f vs baseline %.3f",
+			a.Kind, siteID, drift.PSI, drift.Submissions, drift.Mean, drift.BaselineMean)
+		recorded, err := fs.saveAnomaly(ctx, &a)
+		if err != nil {
+			return nil, err
+		}
+		if !recorded {
+			continue
+		}
+		anomalies = append(anomalies, a)
+		if err := fs.alerts.Notify(ctx, alerting.Alert{
+			Kind:    a.Kind,
+			SiteID:  a.SiteID,
+			Message: a.Message,
+			Details: map[string]interface{}{
+				"bucket_start":  a.BucketStart,
+				"psi":           drift.PSI,
+				"submissions":   drift.Submissions,
+				"mean":          drift.Mean,
+				"baseline_mean": drift.BaselineMean,
+			},
+			Time: a.CreatedAt,
+		}); err != nil {
+			log.Printf("Failed to send score drift alert: %v", err)
+		}
+	}
+	return anomalies, nil
+}
+
+// RunScoreDriftDetection 每个评分统计桶结束后检查评分分布漂移，直到 ctx 结束
+func (fs *FingerprintService) RunScoreDriftDetection(ctx context.Context) {
+	if fs.anomaly.Scores.Bucket <= 0 {
+		return
+	}
+	ticker := time.NewTicker(fs.anomaly.Scores.Bucket.Std())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			// 统计桶已结束，各实例缓存的统计结果按过期处理
+			fs.queryCache.invalidate()
+			if !fs.IsLeader() {
+				continue
+			}
+			if _, err := fs.CheckScoreDrift(ctx, now); err != nil {
+				log.Printf("Score drift detection failed: %v", err)
+			}
+		}
+	}
+}
+
+// ScoreStats 返回 [from, to) 内的评分分布、按统计桶的变化和最后一个完整桶的漂移情况，
+// from、to 为空时取最近24小时，siteID 为空时合并所有站点；结果按 query_cache 缓存
+func (fs *FingerprintService) ScoreStats(ctx context.Context, from, to *time.Time, siteID string) (*models.ScoreStats, error) {
+	return cachedQuery(ctx, fs, queryKey("scores", from, to, siteID), func(ctx context.Context) (*models.ScoreStats, error) {
+		return fs.scoreStats(ctx, from, to, siteID)
+	})
+}
+
+// scoreStats 查询评分统计，不使用缓存
+func (fs *FingerprintService) scoreStats(ctx context.Context, from, to *time.Time, siteID string) (*models.ScoreStats, error) {
+	cfg := fs.anomaly.Scores
+	dist := &models.ScoreStats{
+		SiteID:       siteID,
+		Bucket:       cfg.Bucket.Std().String(),
+		BotThreshold: fs.rulesFor(siteID).botThreshold(),
+		Histogram:    []models.ScoreBin{},
+		Series:       []models.ScoreBucketStats{},
+	}
+	end := time.Now()
+	if to != nil {
+		end = *to
+	}
+	start := end.Add(-24 * time.Hour)
+	if from != nil {
+		start = *from
+	}
+	dist.From, dist.To = start, end
+	if cfg.Bucket <= 0 {
+		return dist, nil
+	}
+
+	step := int64(cfg.Bucket.Std() / time.Second)
+	first, last := fs.scoreBucket(start), fs.scoreBucket(end)
+	histograms, err := loadScoreHistograms(ctx, fs.db.Read, siteID, first, last+step)
+	if err != nil {
+		return nil, err
+	}
+	total := &scoreHistogram{}
+	for b := first; b <= last; b += step {
+		h := histograms[b]
+		if h == nil {
+			continue
+		}
+		total.merge(h)
+		dist.Series = append(dist.Series, models.ScoreBucketStats{
+			Start:       time.Unix(b, 0),
+			Submissions: h.total(),
+			Mean:        h.mean(),
+			P50:         h.quantile(0.5),
+			P90:         h.quantile(0.9),
+		})
+	}
+
+	n := total.total()
+	dist.Submissions = n
+	dist.Mean = total.mean()
+	dist.P50, dist.P90, dist.P99 = total.quantile(0.5), total.quantile(0.9), total.quantile(0.99)
+	remaining := n
+	for i, c := range total.counts {
+		dist.Histogram = append(dist.Histogram, models.ScoreBin{
+			Min:     float64(i) / scoreBins,
+			Max:     float64(i+1) / scoreBins,
+			Count:   c,
+			Share:   ratio(c, n),
+			AtLeast: ratio(remaining, n),
+		})
+		remaining -= c
+	}
+
+	// 漂移按结束时间之前最后一个完整的统计桶计算
+	dist.Drift, err = fs.scoreDrift(ctx, fs.db.Read, siteID, fs.scoreBucket(end)-step)
+	if err != nil {
+		return nil, err
+	}
+	return dist, nil
+}
@@ -0,0 +1,284 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxScrapingViews 分析访问序列时读取的最近页面浏览数
+	maxScrapingViews = 500
+	// maxScrapingSamples 检测记录保存的页面路径数
+	maxScrapingSamples = 10
+	// orderedTraversalRatio 同一目录下相邻两次访问中按同一方向排序的比例达到该值时视为依次遍历
+	orderedTraversalRatio = 0.9
+)
+
+// pageView 一次页面浏览，assets 无效表示未上报静态资源加载数
+type pageView struct {
+	path   string
+	assets sql.NullInt64
+}
+
+// pageViewPath 由 page_view 事件的 metadata.path 得到页面路径（保留查询串，ID常在其中），未上报时返回 false
+func pageViewPath(metadata map[string]string) (string, bool) {
+	raw := metadata["path"]
+	if raw == "" {
+		return "", false
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Path == "" {
+		return "", false
+	}
+	p := u.Path
+	if u.RawQuery != "" {
+		p += "?" + u.RawQuery
+	}
+	if len(p) > maxNavigationPathLength {
+		p = p[:maxNavigationPathLength]
+	}
+	return p, true
+}
+
+// validatePageViewAssets 校验 page_view 事件的 metadata.assets，须为非负整数
+func validatePageViewAssets(metadata map[string]string) []models.FieldError {
+	raw, ok := metadata["assets"]
+	if !ok {
+		return nil
+	}
+	if n, err := strconv.Atoi(raw); err != nil || n < 0 {
+		return []models.FieldError{{Field: "metadata.assets", Constraint: "non-negative integer", Got: raw}}
+	}
+	return nil
+}
+
+// recordPageView 记录 page_view 事件的页面路径和静态资源加载数，并分析该指纹的访问序列
+func (fs *FingerprintService) recordPageView(ctx context.Context, event *models.Event) error {
+	if fs.scraping.Window <= 0 || event.EventType != models.EventPageView {
+		return nil
+	}
+	p, ok := pageViewPath(event.Metadata)
+	if !ok {
+		return nil
+	}
+	var assets sql.NullInt64
+	if n, err := strconv.Atoi(event.Metadata["assets"]); err == nil {
+		assets = sql.NullInt64{Int64: int64(n), Valid: true}
+	}
+	if _, err := fs.db.DB.ExecContext(ctx, `
+		INSERT INTO page_views (site_id, fingerprint_hash, path, assets, created_at) VALUES (?, ?, ?, ?, ?)`,
+		event.SiteID, event.FingerprintHash, p, assets, event.CreatedAt); err != nil {
+		return err
+	}
+	_, err := fs.detectScraping(ctx, event)
+	return err
+}
+
+// detectScraping 分析指纹在窗口内的页面浏览序列，命中采集模式且窗口内尚未记录同一模式时写入检测记录
+func (fs *FingerprintService) detectScraping(ctx context.Context, event *models.Event) (*models.ScrapingDetection, error) {
+	cfg := fs.scraping
+	since := event.CreatedAt.Add(-cfg.Window.Std())
+	rows, err := fs.db.DB.QueryContext(ctx, `
+		SELECT path, assets FROM page_views
+		WHERE fingerprint_hash = ? AND created_at >= ?
+		ORDER BY id DESC LIMIT ?`, event.FingerprintHash, since, maxScrapingViews)
+	if err != nil {
+		return nil, err
+	}
+	var views []pageView
+	for rows.Next() {
+		var v pageView
+		if err := rows.Scan(&v.path, &v.assets); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		views = append(views, v)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(views) < cfg.MinPages {
+		return nil, nil
+	}
+	for i, j := 0, len(views)-1; i < j; i, j = i+1, j-1 {
+		views[i], views[j] = views[j], views[i]
+	}
+
+	pattern := scrapingPattern(views, cfg.MinPages, fs.contentPages)
+	if pattern == "" {
+		return nil, nil
+	}
+	var recorded int
+	if err := fs.db.DB.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM scraping_detections WHERE fingerprint_hash = ? AND pattern = ? AND created_at >= ?`,
+		event.FingerprintHash, pattern, since).Scan(&recorded); err != nil {
+		return nil, err
+	}
+	if recorded > 0 {
+		return nil, nil
+	}
+
+	detection := &models.ScrapingDetection{
+		SiteID:          event.SiteID,
+		FingerprintHash: event.FingerprintHash,
+		Pattern:         pattern,
+		Pages:           len(views),
+		CreatedAt:       event.CreatedAt,
+	}
+	for _, v := range views[len(views)-min(len(views), maxScrapingSamples):] {
+		detection.Samples = append(detection.Samples, v.path)
+	}
+	samples, err := json.Marshal(detection.Samples)
+	if err != nil {
+		return nil, err
+	}
+	result, err := fs.db.DB.ExecContext(ctx, `
+		INSERT INTO scraping_detections (site_id, fingerprint_hash, pattern, pages, samples, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		detection.SiteID, detection.FingerprintHash, detection.Pattern, detection.Pages, string(samples), detection.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save scraping detection: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	detection.ID = int(id)
+	return detection, nil
+}
+
+// scrapingPattern 返回页面浏览序列（按访问顺序）命中的采集模式，未命中时返回空字符串
+func scrapingPattern(views []pageView, minPages int, contentPages *regexp.Regexp) string {
+	paths := make([]string, len(views))
+	for i, v := range views {
+		paths[i] = v.path
+	}
+	if orderedTraversal(paths, minPages) {
+		return models.ScrapingOrderedTraversal
+	}
+	for _, v := range views {
+		if !v.assets.Valid || v.assets.Int64 > 0 || !contentPages.MatchString(v.path) {
+			return ""
+		}
+	}
+	return models.ScrapingContentOnly
+}
+
+// orderedTraversal 判断访问序列是否按ID或字典序依次遍历：只比较同一目录下的相邻两次访问，
+// 这样的访问对至少占一半且其中按同一方向排序的达到 orderedTraversalRatio；重复访问同一页面不计
+func orderedTraversal(paths []string, minPages int) bool {
+	var pairs, asc, desc int
+	for i := 1; i < len(paths); i++ {
+		prev, cur := paths[i-1], paths[i]
+		if prev == cur || path.Dir(prev) != path.Dir(cur) {
+			continue
+		}
+		pairs++
+		switch comparePageKeys(path.Base(prev), path.Base(cur)) {
+		case -1:
+			asc++
+		case 1:
+			desc++
+		}
+	}
+	if pairs < (minPages-1)/2 || pairs == 0 {
+		return false
+	}
+	return float64(max(asc, desc)) >= orderedTraversalRatio*float64(pairs)
+}
+
+// trailingNumber 拆出页面名最后一段数字（去掉前导零）及其前缀和后缀
+var trailingNumber = regexp.MustCompile(`^(.*?)0*(\d+)(\D*)$`)
+
+// comparePageKeys 比较同一目录下的两个页面名：前缀和后缀相同且都含数字时按最后一段数字的数值比较，否则按字典序
+func comparePageKeys(a, b string) int {
+	ma, mb := trailingNumber.FindStringSubmatch(a), trailingNumber.FindStringSubmatch(b)
+	if ma != nil && mb != nil && ma[1] == mb[1] && ma[3] == mb[3] {
+		if len(ma[2]) != len(mb[2]) {
+			if len(ma[2]) < len(mb[2]) {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(ma[2], mb[2])
+	}
+	return strings.Compare(a, b)
+}
+
+// checkScrapingPattern 指纹在窗口内有采集爬虫检测记录时给出信号
+func (fs *FingerprintService) checkScrapingPattern(ctx context.Context, fp *models.Fingerprint) []signal {
+	if fs.scraping.Window <= 0 {
+		return nil
+	}
+	var pattern string
+	var pages int
+	err := fs.db.DB.QueryRowContext(ctx, `
+		SELECT pattern, pages FROM scraping_detections
+		WHERE fingerprint_hash = ? AND created_at >= ? ORDER BY id DESC LIMIT 1`,
+		fp.FingerprintHash, time.Now().Add(-fs.scraping.Window.Std())).Scan(&pattern, &pages)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		log.Printf("Failed to query scraping detections: %v", err)
+		return nil
+	}
+	return []signal{{
+		Code:   models.ReasonScrapingPattern,
+		Weight: fs.scraping.Weight,
+		Reason: fmt.Sprintf("Page view sequence matches scraping pattern %s over %d pages", pattern, pages),
+	}}
+}
+
+// purgePageViews 删除超出分析窗口的页面浏览记录
+func (fs *FingerprintService) purgePageViews(ctx context.Context) error {
+	if fs.scraping.Window <= 0 {
+		return nil
+	}
+	_, err := fs.db.DB.ExecContext(ctx,
+		"DELETE FROM page_views WHERE created_at < ?", time.Now().Add(-fs.scraping.Window.Std()))
+	return err
+}
+
+// GetScrapingDetections 按时间倒序返回采集爬虫检测记录，siteID 为空时返回所有站点
+func (fs *FingerprintService) GetScrapingDetections(ctx context.Context, siteID string, limit int) ([]models.ScrapingDetection, error) {
+	query := "SELECT id, site_id, fingerprint_hash, pattern, pages, samples, created_at FROM scraping_detections"
+	var args []interface{}
+	if siteID != "" {
+		query += " WHERE site_id = ?"
+		args = append(args, siteID)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := fs.db.Read.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	detections := []models.ScrapingDetection{}
+	for rows.Next() {
+		var d models.ScrapingDetection
+		var samples string
+		if err := rows.Scan(&d.ID, &d.SiteID, &d.FingerprintHash, &d.Pattern, &d.Pages, &samples, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(samples), &d.Samples); err != nil {
+			return nil, err
+		}
+		detections = append(detections, d)
+	}
+	return detections, rows.Err()
+}
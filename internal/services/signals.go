@@ -0,0 +1,124 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"context"
+	"net"
+	"strings"
+)
+
+// signal 扩展检测信号：Weight 计入爬虫评分，Code 与 Reason 写入分析结果
+type signal struct {
+	Code   string
+	Weight float64
+	Reason string
+}
+
+// evaluateSignals 计算扩展检测信号
+func (fs *FingerprintService) evaluateSignals(ctx context.Context, fp *models.Fingerprint, req *models.FingerprintRequest) []signal {
+	signals := fs.statelessSignals(fp, req)
+	signals = append(signals, fs.checkCanvasRandomization(ctx, fp)...)
+	signals = append(signals, fs.checkRenderDrift(ctx, fp)...)
+	signals = append(signals, fs.checkCookieMismatch(ctx, fp)...)
+	signals = append(signals, fs.checkCookieCycling(ctx, fp)...)
+	signals = append(signals, fs.checkFarmMember(ctx, fp)...)
+	signals = append(signals, fs.checkBlocklist(ctx, fp)...)
+	signals = append(signals, fs.checkThreatIntel(fp)...)
+	signals = append(signals, fs.checkIPReputation(ctx, fp)...)
+	signals = append(signals, fs.checkCDNBotScore(ctx, fp)...)
+	signals = append(signals, fs.checkResidentialProxy(ctx, fp, req)...)
+	signals = append(signals, fs.checkDirectDeepHits(ctx, fp, req)...)
+	signals = append(signals, fs.checkScrapingPattern(ctx, fp)...)
+	signals = append(signals, fs.checkConcurrentSessions(ctx, fp)...)
+	signals = append(signals, fs.checkExecutionProof(ctx, fp, req)...)
+	signals = append(signals, fs.checkCanvasDualRender(ctx, fp, req)...)
+	signals = append(signals, fs.detectorSignals(ctx, fp, req)...)
+	signals = append(signals, fs.checkExpressions(fp, signals)...)
+	return signals
+}
+
+// statelessSignals 只依据本次提交计算的检测信号（User Agent 与各项特征的一致性），不访问数据库
+func (fs *FingerprintService) statelessSignals(fp *models.Fingerprint, req *models.FingerprintRequest) []signal {
+	var signals []signal
+	signals = append(signals, checkWebRTCLeak(fp, req)...)
+	signals = append(signals, checkHardware(fp)...)
+	signals = append(signals, checkMediaDevices(fp)...)
+	signals = append(signals, checkMobileSensors(fp)...)
+	signals = append(signals, checkEmulator(fp)...)
+	signals = append(signals, checkScriptedInput(fp, req)...)
+	signals = append(signals, checkMissingComponents(fp)...)
+	signals = append(signals, checkScreenMetrics(fp)...)
+	signals = append(signals, fs.checkMathEngine(fp)...)
+	signals = append(signals, checkFeatureVersion(fp)...)
+	signals = append(signals, checkFontPlatform(fp)...)
+	signals = append(signals, checkPluginProfile(fp)...)
+	signals = append(signals, checkExtensions(fp, fs.sitePolicy(fp.SiteID))...)
+	signals = append(signals, checkCanvasNoise(fp)...)
+	signals = append(signals, fs.checkWebGLParams(fp)...)
+	signals = append(signals, checkGPUPlatform(fp)...)
+	signals = append(signals, fs.checkBrowserVersion(fp)...)
+	signals = append(signals, fs.checkCrawler(fp)...)
+	signals = append(signals, checkAudioNoise(fp)...)
+	signals = append(signals, checkLanguageConsistency(fp)...)
+	signals = append(signals, fs.checkCollectionTiming(fp, req)...)
+	return signals
+}
+
+// signalCodes 提取信号的原因代码
+func signalCodes(signals []signal) []string {
+	codes := make([]string, 0, len(signals))
+	for _, sig := range signals {
+		codes = append(codes, sig.Code)
+	}
+	return codes
+}
+
+// isMobileUA 判断User Agent是否声明为移动设备
+func isMobileUA(userAgent string) bool {
+	ua := strings.ToLower(userAgent)
+	for _, keyword := range []string{"mobile", "android", "iphone", "ipad", "ipod"} {
+		if strings.Contains(ua, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDesktopChrome 判断User Agent是否为桌面版Chrome（排除Edge、Opera等Chromium衍生浏览器）
+func isDesktopChrome(userAgent string) bool {
+	ua := strings.ToLower(userAgent)
+	return strings.Contains(ua, "chrome/") && !isMobileUA(userAgent) &&
+		!strings.Contains(ua, "edg/") && !strings.Contains(ua, "opr/")
+}
+
+// checkWebRTCLeak 比较WebRTC探测到的公网IP与HTTP来源IP
+// 来源IP本身是内网/回环地址时（未经反向代理的本地访问）不做比较；
+// 只比较同一地址族，双栈客户端的IPv4/IPv6出口不同属于正常情况
+func checkWebRTCLeak(fp *models.Fingerprint, req *models.FingerprintRequest) []signal {
+	sourceIP := net.ParseIP(fp.IPAddress)
+	if sourceIP == nil || sourceIP.IsLoopback() || sourceIP.IsPrivate() {
+		return nil
+	}
+	sourceIsV4 := sourceIP.To4() != nil
+
+	compared := false
+	for _, raw := range req.WebRTCPublicIPs {
+		ip := net.ParseIP(raw)
+		if ip == nil || (ip.To4() != nil) != sourceIsV4 {
+			continue
+		}
+		if ip.Equal(sourceIP) {
+			return nil
+		}
+		compared = true
+	}
+	if !compared {
+		return nil
+	}
+
+	return []signal{{
+		Code:   models.ReasonWebRTCIPMismatch,
+		Weight: 0.2,
+		Reason: "WebRTC public IP differs from HTTP source IP (possible proxy/VPN)",
+	}}
+}
@@ -0,0 +1,21 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"fmt"
+)
+
+// audioNoiseThreshold 服务端音频噪点置信度达到该值才计分
+const audioNoiseThreshold = 0.5
+
+// checkAudioNoise 根据服务端对原始采样的分析结果判断音频是否被注入抖动
+func checkAudioNoise(fp *models.Fingerprint) []signal {
+	if fp.AudioNoise < audioNoiseThreshold {
+		return nil
+	}
+	return []signal{{
+		Code:   models.ReasonAudioNoiseInjected,
+		Weight: 0.3 * fp.AudioNoise,
+		Reason: fmt.Sprintf("Server-side audio analysis found injected jitter (confidence %.2f)", fp.AudioNoise),
+	}}
+}
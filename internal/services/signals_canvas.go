@@ -0,0 +1,56 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"context"
+	"fmt"
+	"log"
+)
+
+const (
+	// canvasNoiseThreshold 孤立噪点比例阈值，正常渲染的平坦区域几乎为0
+	canvasNoiseThreshold = 0.01
+	// canvasVariantThreshold 同一访客感知相同但像素不同的其他Canvas数量阈值
+	canvasVariantThreshold = 2
+)
+
+// checkCanvasNoise 根据服务端计算的噪点比例判断Canvas是否被注入噪点
+func checkCanvasNoise(fp *models.Fingerprint) []signal {
+	if fp.CanvasNoise < canvasNoiseThreshold {
+		return nil
+	}
+	return []signal{{
+		Code:   models.ReasonCanvasNoiseInjected,
+		Weight: 0.25,
+		Reason: fmt.Sprintf("Server-side canvas analysis found injected pixel noise (%.2f%% of flat pixels)", fp.CanvasNoise*100),
+	}}
+}
+
+// checkCanvasRandomization 比较同一访客（相同IP与User Agent）的历次提交
+// 感知哈希相同而精确哈希不同，说明每次绘制都被随机化；
+// 要求至少出现两个其他变体，避免同一出口下不同设备的渲染差异造成误报
+func (fs *FingerprintService) checkCanvasRandomization(ctx context.Context, fp *models.Fingerprint) []signal {
+	if fp.CanvasPHash == "" {
+		return nil
+	}
+
+	var variants int
+	err := fs.db.DB.QueryRowContext(ctx, `
+		SELECT COUNT(DISTINCT canvas_hash) FROM fingerprints
+		WHERE canvas_phash = ? AND ip_address = ? AND user_agent = ?
+		  AND canvas_hash != ? AND fingerprint_hash != ?`,
+		fp.CanvasPHash, fp.IPAddress, fp.UserAgent, fp.CanvasHash, fp.FingerprintHash).Scan(&variants)
+	if err != nil {
+		log.Printf("Failed to query canvas variants: %v", err)
+		return nil
+	}
+	if variants < canvasVariantThreshold {
+		return nil
+	}
+
+	return []signal{{
+		Code:   models.ReasonCanvasRandomized,
+		Weight: 0.3,
+		Reason: fmt.Sprintf("Canvas is randomized per session (%d perceptually identical variants from this visitor)", variants),
+	}}
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"fmt"
+	"strings"
+)
+
+const (
+	// missingComponentWeight 每缺少一个指纹组件计入的权重
+	missingComponentWeight = 0.1
+	// maxMissingComponentsWeight 缺少组件信号的最大权重，组件全部缺失也不单独判定为爬虫
+	maxMissingComponentsWeight = 0.3
+)
+
+// missingComponents 返回请求中缺少的指纹组件（JSON字段名）
+// 字体和插件只有未提交时才算缺少，提交空数组表示浏览器确实没有
+func missingComponents(req *models.FingerprintRequest) []string {
+	missing := []string{}
+	for _, c := range []struct {
+		name    string
+		missing bool
+	}{
+		{"canvas", req.Canvas == ""},
+		{"webgl", req.WebGL == ""},
+		{"audio", req.Audio == ""},
+		{"fonts", req.Fonts == nil},
+		{"plugins", req.Plugins == nil},
+	} {
+		if c.missing {
+			missing = append(missing, c.name)
+		}
+	}
+	return missing
+}
+
+// componentMissing 返回指纹提交时缺少的组件集合，旧版本导出包中的指纹没有该字段，按未缺少处理
+func componentMissing(fp *models.Fingerprint) map[string]bool {
+	missing := make(map[string]bool)
+	if fp.MissingComponents == "" {
+		return missing
+	}
+	for _, name := range utils.JSONToStringSlice(fp.MissingComponents) {
+		missing[name] = true
+	}
+	return missing
+}
+
+// checkMissingComponents 提交缺少指纹组件时给出信号，权重随缺少的组件数增加
+func checkMissingComponents(fp *models.Fingerprint) []signal {
+	if fp.MissingComponents == "" {
+		return nil
+	}
+	missing := utils.JSONToStringSlice(fp.MissingComponents)
+	if len(missing) == 0 {
+		return nil
+	}
+	return []signal{{
+		Code:   models.ReasonComponentsMissing,
+		Weight: min(float64(len(missing))*missingComponentWeight, maxMissingComponentsWeight),
+		Reason: fmt.Sprintf("Fingerprint components missing from submission: %s", strings.Join(missing, ", ")),
+	}}
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// checkHardware 检查CPU核数与设备内存的合理性
+// deviceMemory 由浏览器按2的幂取整（0.25、0.5、1、2、4、8...），其他取值只可能来自伪造
+func checkHardware(fp *models.Fingerprint) []signal {
+	var signals []signal
+	cores, memory := fp.HardwareConcurrency, fp.DeviceMemory
+
+	if memory > 0 {
+		if exp := math.Log2(memory); exp != math.Trunc(exp) {
+			signals = append(signals, signal{
+				Code:   models.ReasonHardwareInvalid,
+				Weight: 0.2,
+				Reason: "Reported device memory is not a value browsers report",
+			})
+		}
+	}
+
+	if cores >= 32 && memory > 0 && memory <= 0.5 || cores > 256 {
+		signals = append(signals, signal{
+			Code:   models.ReasonHardwareImpossible,
+			Weight: 0.3,
+			Reason: "Impossible CPU core count and device memory combination",
+		})
+	}
+
+	if cores == 1 && !isMobileUA(fp.UserAgent) {
+		signals = append(signals, signal{
+			Code:   models.ReasonHardwareCIProfile,
+			Weight: 0.15,
+			Reason: "Single-core desktop profile typical of CI containers",
+		})
+	}
+
+	return signals
+}
+
+// checkMediaDevices 桌面Chrome即使未授权也会枚举出默认音频输出等设备，
+// 所有类别都为0说明运行在没有媒体栈的无头环境中
+func checkMediaDevices(fp *models.Fingerprint) []signal {
+	if fp.MediaAudioInputs < 0 || !isDesktopChrome(fp.UserAgent) {
+		return nil
+	}
+	if fp.MediaAudioInputs+fp.MediaAudioOutputs+fp.MediaVideoInputs > 0 {
+		return nil
+	}
+	return []signal{{
+		Code:   models.ReasonMediaDevicesNone,
+		Weight: 0.35,
+		Reason: "Desktop Chrome reports no media devices (headless indicator)",
+	}}
+}
+
+// checkMobileSensors 真实手机都带有加速度计/陀螺仪，声明为移动设备却没有任何传感器数据
+// 通常是桌面浏览器伪装的移动UA
+func checkMobileSensors(fp *models.Fingerprint) []signal {
+	if !fp.SensorsCollected || !isMobileUA(fp.UserAgent) {
+		return nil
+	}
+	if fp.HasAccelerometer || fp.HasGyroscope {
+		return nil
+	}
+	return []signal{{
+		Code:   models.ReasonMobileNoSensors,
+		Weight: 0.3,
+		Reason: "Mobile user agent without any motion sensors",
+	}}
+}
+
+const (
+	// minScriptedTouches 判断触摸输入是否由脚本产生所需的最少触点数
+	minScriptedTouches = 10
+	// minScriptedMotionSamples 判断运动传感器读数是否由脚本产生所需的最少读数数
+	minScriptedMotionSamples = 30
+	// flatMotionEntropy 运动传感器读数的熵低于该值（比特）视为没有变化；真实设备静置时也有传感器噪声
+	flatMotionEntropy = 0.1
+)
+
+// checkScriptedInput 声明为移动设备的提交带有触摸和运动传感器读数的汇总时，检查读数是否完全没有变化：
+// 真实手指的压力和接触面积在触点之间总有差异（部分设备不报告压力，因此两者都为0才判定），
+// 真实传感器即使设备静置也有噪声；脚本合成的触摸事件和模拟器的传感器读数则是固定值
+func checkScriptedInput(fp *models.Fingerprint, req *models.FingerprintRequest) []signal {
+	if req == nil || req.Interaction == nil || !isMobileUA(fp.UserAgent) {
+		return nil
+	}
+	in := req.Interaction
+	var findings []string
+	if in.Touches >= minScriptedTouches && in.PressureVariance == 0 && in.SizeVariance == 0 {
+		findings = append(findings, fmt.Sprintf("%d touches with constant pressure and contact size", in.Touches))
+	}
+	if in.MotionSamples >= minScriptedMotionSamples && in.MotionEntropy < flatMotionEntropy {
+		findings = append(findings, fmt.Sprintf("%d motion readings with %.2f bits of entropy", in.MotionSamples, in.MotionEntropy))
+	}
+	if len(findings) == 0 {
+		return nil
+	}
+	return []signal{{
+		Code:   models.ReasonMobileScriptedInput,
+		Weight: 0.4,
+		Reason: "Scripted mobile input: " + strings.Join(findings, ", "),
+	}}
+}
+
+// validColorDepths 真实显示设备会报告的色深
+var validColorDepths = map[int]bool{8: true, 15: true, 16: true, 24: true, 30: true, 32: true, 48: true}
+
+// checkScreenMetrics 检查扩展屏幕参数
+// 无头Chrome的 outerWidth/outerHeight 为0；像素比超出 (0, 5]、色深不在常见取值内，
+// 或可用区域大于屏幕分辨率，都说明屏幕参数被伪造
+func checkScreenMetrics(fp *models.Fingerprint) []signal {
+	if fp.OuterWidth < 0 {
+		return nil
+	}
+
+	var signals []signal
+	if fp.OuterWidth == 0 || fp.OuterHeight == 0 {
+		signals = append(signals, signal{
+			Code:   models.ReasonScreenOuterZero,
+			Weight: 0.35,
+			Reason: "Window outer dimensions are zero (headless indicator)",
+		})
+	}
+
+	invalid := fp.DevicePixelRatio <= 0 || fp.DevicePixelRatio > 5 || !validColorDepths[fp.ColorDepth]
+	var width, height int
+	if _, err := fmt.Sscanf(fp.ScreenResolution, "%dx%d", &width, &height); err == nil {
+		if fp.AvailWidth > width || fp.AvailHeight > height {
+			invalid = true
+		}
+	}
+	if invalid {
+		signals = append(signals, signal{
+			Code:   models.ReasonScreenMetricsInvalid,
+			Weight: 0.2,
+			Reason: "Unrealistic screen metrics (pixel ratio, color depth or available area)",
+		})
+	}
+
+	return signals
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"context"
+	"log"
+	"strings"
+)
+
+// renderComponents 由渲染栈决定的组件：同一台设备上三者不会在一次访问之间同时改变
+var renderComponents = []string{"canvas", "webgl", "audio"}
+
+// checkRenderDrift 比较同一访客Cookie上一次记录的渲染组件
+// 浏览器升级或驱动更新通常只改变其中一项；Canvas、WebGL和音频同时改变，
+// 说明反检测浏览器在保留Cookie的同时切换了伪造的设备配置
+func (fs *FingerprintService) checkRenderDrift(ctx context.Context, fp *models.Fingerprint) []signal {
+	if fp.VisitorID == "" {
+		return nil
+	}
+	latest, err := fs.latestComponentHashes(ctx, fp.VisitorID)
+	if err != nil {
+		log.Printf("Failed to query component history: %v", err)
+		return nil
+	}
+
+	current, err := fs.componentHashes(ctx, fp.FingerprintHash)
+	if err != nil {
+		log.Printf("Failed to query fingerprint components: %v", err)
+		return nil
+	}
+
+	for _, name := range renderComponents {
+		prev, ok := latest[name]
+		if !ok || current[name] == "" || current[name] == prev {
+			return nil
+		}
+	}
+
+	return []signal{{
+		Code:   models.ReasonRenderDrift,
+		Weight: 0.3,
+		Reason: "Visitor's " + strings.Join(renderComponents, ", ") + " fingerprints all changed at once",
+	}}
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// emulatorRenderers 模拟器的WebGL渲染器名关键字（小写）：Android模拟器的 goldfish/ranchu 虚拟GPU、
+// 转发宿主机GPU的 gfxstream 和 "Android Emulator OpenGL ES Translator"，以及虚拟机和软件渲染
+var emulatorRenderers = []string{
+	"android emulator", "goldfish", "ranchu", "gfxstream",
+	"swiftshader", "llvmpipe", "virtualbox", "vmware", "bluestacks",
+}
+
+// emulatorModels Android模拟器镜像的通用设备型号
+var emulatorModels = regexp.MustCompile(`(?i)\b(?:sdk_gphone\w*|sdk_google\w*|google_sdk|Android SDK built for \w+|AOSP on IA Emulator|generic_x86\w*|vbox86p|Genymotion)\b`)
+
+// emulatorResolutions 模拟器默认设备配置和开发者工具设备模式的屏幕分辨率（CSS像素）
+var emulatorResolutions = map[string]bool{
+	"320x480": true, "360x640": true, "411x731": true, "411x823": true,
+	"412x732": true, "412x915": true, "393x786": true, "768x1280": true,
+}
+
+// minWeakEmulatorIndicators 没有渲染器或设备型号证据时，判定模拟器所需的弱特征数
+const minWeakEmulatorIndicators = 2
+
+// webglRenderer 从前端 collectWebGLBasicInfo 的JSON中取出渲染器名，无法解析时返回空字符串
+// 模块化采集脚本将渲染器放在 basicInfo 中，未开启 WEBGL_debug_renderer_info 时为 unknown
+func webglRenderer(webgl string) string {
+	var info struct {
+		Renderer  string `json:"renderer"`
+		BasicInfo struct {
+			Renderer string `json:"renderer"`
+		} `json:"basicInfo"`
+	}
+	if json.Unmarshal([]byte(webgl), &info) != nil {
+		return ""
+	}
+	renderer := info.Renderer
+	if renderer == "" {
+		renderer = info.BasicInfo.Renderer
+	}
+	if renderer == "unknown" {
+		return ""
+	}
+	return renderer
+}
+
+// emulatorIndicators 返回声明为移动设备的指纹上的模拟器特征：strong 为模拟器GPU渲染器或通用设备型号，单独即可判定；
+// weak 为缺少运动传感器、模拟器默认分辨率、与移动UA不符的桌面平台或x86平台、超出iPhone的CPU核数，需同时出现多项
+func emulatorIndicators(fp *models.Fingerprint) (strong, weak []string) {
+	if !isMobileUA(fp.UserAgent) {
+		return nil, nil
+	}
+	if renderer := webglRenderer(fp.WebGL); renderer != "" {
+		lower := strings.ToLower(renderer)
+		for _, keyword := range emulatorRenderers {
+			if strings.Contains(lower, keyword) {
+				strong = append(strong, "emulator GPU renderer "+renderer)
+				break
+			}
+		}
+	}
+	if model := emulatorModels.FindString(fp.UserAgent); model != "" {
+		strong = append(strong, "generic device model "+model)
+	}
+
+	if fp.SensorsCollected && !fp.HasAccelerometer && !fp.HasGyroscope {
+		weak = append(weak, "no motion sensors")
+	}
+	if emulatorResolutions[fp.ScreenResolution] {
+		weak = append(weak, "default emulator resolution "+fp.ScreenResolution)
+	}
+	platform := strings.ToLower(fp.Platform)
+	if platform == "win32" || platform == "macintel" || strings.Contains(platform, "x86") || strings.Contains(platform, "i686") {
+		weak = append(weak, "desktop or x86 platform "+fp.Platform)
+	}
+	if strings.Contains(fp.UserAgent, "iPhone") && fp.HardwareConcurrency > 6 {
+		weak = append(weak, "more CPU cores than any iPhone")
+	}
+	return strong, weak
+}
+
+// checkEmulator 识别Android模拟器和iOS模拟器（包括开发者工具的设备模式）：
+// 有模拟器GPU渲染器或通用设备型号，或同时出现至少 minWeakEmulatorIndicators 项弱特征时判定，分析结果的 emulator_suspected 为 true
+func checkEmulator(fp *models.Fingerprint) []signal {
+	strong, weak := emulatorIndicators(fp)
+	if len(strong) == 0 && len(weak) < minWeakEmulatorIndicators {
+		return nil
+	}
+	return []signal{{
+		Code:   models.ReasonEmulatorSuspected,
+		Weight: 0.5,
+		Reason: "Mobile emulator suspected: " + strings.Join(append(strong, weak...), ", "),
+	}}
+}
+
+// emulatorSuspected 检测信号中是否包含模拟器判定
+func emulatorSuspected(signals []signal) bool {
+	for _, sig := range signals {
+		if sig.Code == models.ReasonEmulatorSuspected {
+			return true
+		}
+	}
+	return false
+}
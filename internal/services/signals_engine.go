@@ -0,0 +1,123 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"fmt"
+	"strings"
+)
+
+// checkMathEngine 比较Math结果向量所属的JS引擎与User Agent声明的浏览器
+// 同一引擎的Math结果只随引擎实现变化；结果向量的哈希在基线数据中收录为另一引擎（V8/SpiderMonkey/JavaScriptCore）时，说明User Agent被伪造
+func (fs *FingerprintService) checkMathEngine(fp *models.Fingerprint) []signal {
+	if fp.MathHash == "" {
+		return nil
+	}
+	claimed := utils.ParseUserAgent(fp.UserAgent).Engine
+	if claimed == utils.EngineUnknown {
+		return nil
+	}
+	engine := fs.mathEngine(fp.MathHash)
+	if engine == "" || engine == claimed {
+		return nil
+	}
+	return []signal{{
+		Code:   models.ReasonMathEngineMismatch,
+		Weight: 0.3,
+		Reason: fmt.Sprintf("Math precision signature belongs to %s but User Agent claims %s", engine, claimed),
+	}}
+}
+
+// featureProbe 一项特性探测及各浏览器开始支持的主版本号，0 表示不检查该浏览器
+type featureProbe struct {
+	Name    string
+	Chrome  int
+	Firefox int
+	Safari  int
+}
+
+// since 返回浏览器开始支持该特性的主版本号，0 表示不检查
+func (p featureProbe) since(family string) int {
+	switch family {
+	case utils.BrowserChrome, utils.BrowserEdge:
+		return p.Chrome
+	case utils.BrowserFirefox:
+		return p.Firefox
+	case utils.BrowserSafari:
+		return p.Safari
+	}
+	return 0
+}
+
+// featureProbes 与前端 BrowserUtils.getFeatureProbes 的探测顺序一一对应，只能在末尾追加
+var featureProbes = []featureProbe{
+	{"Promise.allSettled", 76, 71, 13},
+	{"String.prototype.replaceAll", 85, 77, 14},
+	{"Array.prototype.at", 92, 90, 16},
+	{"Object.hasOwn", 93, 92, 16},
+	{"structuredClone", 98, 94, 16},
+	{"CSS :has()", 105, 121, 16},
+	{"CSS aspect-ratio", 88, 89, 15},
+	{"CSS container queries", 105, 110, 16},
+	{"Array.prototype.findLast", 97, 104, 16},
+	{"Array.prototype.toSorted", 110, 115, 16},
+	{"IntersectionObserver", 58, 55, 13},
+	{"ResizeObserver", 64, 69, 14},
+	{"BigInt", 67, 68, 14},
+	{"globalThis", 71, 65, 13},
+	{"Intl.Segmenter", 87, 125, 15},
+	{"WeakRef", 84, 79, 15},
+	{"CSS grid", 57, 52, 11},
+	{"Array.fromAsync", 121, 115, 17},
+	{"Promise.withResolvers", 119, 121, 18},
+}
+
+// featureMismatchThreshold 缺失的特性达到该数量才报告，避免个别API被扩展或企业策略禁用时误报
+const featureMismatchThreshold = 2
+
+// checkFeatureVersion 比较特性探测位图与User Agent声明的浏览器版本
+// 声明的版本早已支持、实际却缺失的特性说明User Agent被伪造
+func checkFeatureVersion(fp *models.Fingerprint) []signal {
+	if fp.FeatureCount <= 0 {
+		return nil
+	}
+	ua := utils.ParseUserAgent(fp.UserAgent)
+	// iOS上的第三方浏览器使用系统WebKit，特性取决于iOS版本而不是浏览器版本
+	if ua.Major == 0 || (ua.OS == "iOS" && ua.Family != utils.BrowserSafari) {
+		return nil
+	}
+
+	var missing []string
+	for i, probe := range featureProbes {
+		if i >= fp.FeatureCount || i >= 64 {
+			break
+		}
+		if since := probe.since(ua.Family); since == 0 || ua.Major < since {
+			continue
+		}
+		if uint64(fp.FeatureBits)&(1<<uint(i)) == 0 {
+			missing = append(missing, probe.Name)
+		}
+	}
+	if len(missing) < featureMismatchThreshold {
+		return nil
+	}
+
+	return []signal{{
+		Code:   models.ReasonFeatureVersionMismatch,
+		Weight: 0.3,
+		Reason: fmt.Sprintf("%s %d lacks features it supports: %s", ua.Family, ua.Major, strings.Join(missing, ", ")),
+	}}
+}
+
+// ExpectedFeatures 返回User Agent声明的浏览器版本应有的特性探测结果（合成指纹使用）
+func ExpectedFeatures(userAgent string) *models.FeatureProbes {
+	ua := utils.ParseUserAgent(userAgent)
+	probes := &models.FeatureProbes{Count: len(featureProbes)}
+	for i, probe := range featureProbes {
+		if since := probe.since(ua.Family); since > 0 && ua.Major >= since {
+			probes.Bits |= 1 << uint(i)
+		}
+	}
+	return probes
+}
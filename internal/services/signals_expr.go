@@ -0,0 +1,169 @@
+package services
+
+import (
+	"browser-detection/internal/config"
+	"browser-detection/internal/expr"
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+)
+
+// exprListColumns 以JSON数组保存的指纹列，在表达式中作为列表
+var exprListColumns = map[string]bool{
+	"fonts":              true,
+	"plugins":            true,
+	"extensions":         true,
+	"webrtc_local_ips":   true,
+	"webrtc_public_ips":  true,
+	"missing_components": true,
+}
+
+// exprRule 编译后的评分表达式
+type exprRule struct {
+	models.ScoreRule
+	program *expr.Program
+	sites   map[string]bool
+}
+
+// configRules 将配置文件中的评分表达式转为规则
+func configRules(exprs []config.ScoreExpression) []models.ScoreRule {
+	rules := make([]models.ScoreRule, len(exprs))
+	for i, e := range exprs {
+		rules[i] = models.ScoreRule{Name: e.Name, Expr: e.Expr, Weight: e.Weight, Reason: e.Reason, Sites: e.Sites}
+	}
+	return rules
+}
+
+// compileExprRule 编译一条评分表达式，语法错误或引用了未定义的变量时返回错误
+func compileExprRule(r models.ScoreRule) (exprRule, error) {
+	program, err := expr.Compile(r.Expr)
+	if err != nil {
+		return exprRule{}, err
+	}
+	known := exprEnv(&models.Fingerprint{}, nil)
+	var unknown []string
+	for _, v := range program.Variables() {
+		if _, ok := known[v]; !ok {
+			unknown = append(unknown, v)
+		}
+	}
+	if len(unknown) > 0 {
+		return exprRule{}, fmt.Errorf("undefined variables %s", strings.Join(unknown, ", "))
+	}
+	sites := make(map[string]bool, len(r.Sites))
+	for _, site := range r.Sites {
+		sites[site] = true
+	}
+	return exprRule{ScoreRule: r, program: program, sites: sites}, nil
+}
+
+// newExprRules 编译评分表达式（配置加载和保存规则集时已校验语法）；引用了未定义变量的表达式不启用
+func newExprRules(rules []models.ScoreRule) []exprRule {
+	var compiled []exprRule
+	for _, r := range rules {
+		rule, err := compileExprRule(r)
+		if err != nil {
+			log.Printf("Expression %s disabled: %v", r.Name, err)
+			continue
+		}
+		compiled = append(compiled, rule)
+	}
+	return compiled
+}
+
+// exprEnv 构造表达式的求值环境：指纹的全部列（数值列为数字，JSON数组列为列表），
+// User Agent 解析结果 ua_family、ua_major、ua_os、ua_mobile、ua_engine，
+// 以及此前各项检查给出的原因代码 signals 和权重之和 signal_weight
+func exprEnv(fp *models.Fingerprint, signals []signal) expr.Env {
+	env := make(expr.Env)
+	for _, f := range fingerprintFields(fp) {
+		switch v := f.ptr.(type) {
+		case *string:
+			if exprListColumns[f.column] {
+				env[f.column] = exprList(utils.JSONToStringSlice(*v))
+			} else {
+				env[f.column] = *v
+			}
+		case *int:
+			env[f.column] = float64(*v)
+		case *int64:
+			env[f.column] = float64(*v)
+		case *float64:
+			env[f.column] = *v
+		case *bool:
+			env[f.column] = *v
+		case *time.Time:
+			env[f.column] = float64(v.Unix())
+		}
+	}
+	ua := utils.ParseUserAgent(fp.UserAgent)
+	env["ua_family"] = ua.Family
+	env["ua_major"] = float64(ua.Major)
+	env["ua_os"] = ua.OS
+	env["ua_mobile"] = ua.Mobile
+	env["ua_engine"] = ua.Engine
+	env["signals"] = exprList(signalCodes(signals))
+	weight := 0.0
+	for _, sig := range signals {
+		weight += sig.Weight
+	}
+	env["signal_weight"] = weight
+	return env
+}
+
+// exprList 将字符串切片转为表达式的列表
+func exprList(values []string) []interface{} {
+	list := make([]interface{}, len(values))
+	for i, v := range values {
+		list[i] = v
+	}
+	return list
+}
+
+// checkExpressions 对本次提交求值当前规则集中的评分表达式，signals 为此前各项检查给出的信号；
+// 求值出错（变量类型不符、除以0等）或结果不是布尔值和数字时记录日志并跳过该表达式
+func (fs *FingerprintService) checkExpressions(fp *models.Fingerprint, signals []signal) []signal {
+	set := fs.ruleSet.Load()
+	if len(set.rules) == 0 || !fs.featureEnabled(FeatureExpressions) {
+		return nil
+	}
+	env := exprEnv(fp, signals)
+	var out []signal
+	for _, rule := range set.rules {
+		if len(rule.sites) > 0 && !rule.sites[fp.SiteID] {
+			continue
+		}
+		result, err := rule.program.Eval(env)
+		if err != nil {
+			log.Printf("Expression %s failed: %v", rule.Name, err)
+			continue
+		}
+		weight := 0.0
+		switch v := result.(type) {
+		case bool:
+			if v {
+				weight = rule.Weight
+			}
+		case float64:
+			if !math.IsNaN(v) {
+				weight = math.Max(-1, math.Min(v, 1))
+			}
+		default:
+			log.Printf("Expression %s returned %T, want boolean or number", rule.Name, result)
+			continue
+		}
+		if weight == 0 {
+			continue
+		}
+		reason := rule.Reason
+		if reason == "" {
+			reason = "Expression matched: " + rule.Expr
+		}
+		out = append(out, signal{Code: rule.Name, Weight: weight, Reason: reason})
+	}
+	return out
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"fmt"
+	"strings"
+)
+
+// osBaselineFonts 各操作系统默认安装的字体，均在前端 BrowserUtils.getFontInfo 的探测列表中
+// 正常设备至少能检测到其中一部分；一个都没有说明声明的平台与实际系统不符
+var osBaselineFonts = map[string][]string{
+	"Windows": {
+		"Arial", "Calibri", "Cambria", "Consolas", "Courier New", "Georgia", "Lucida Console",
+		"Microsoft Sans Serif", "Segoe UI", "Tahoma", "Times New Roman", "Trebuchet MS", "Verdana",
+	},
+	"macOS": {
+		"Apple Symbols", "Avenir", "Baskerville", "Futura", "Gill Sans", "Helvetica Neue",
+		"Lucida Grande", "Menlo", "Monaco", "Optima",
+	},
+	"Linux": {
+		"DejaVu Sans", "DejaVu Sans Mono", "DejaVu Serif", "Liberation Mono", "Liberation Sans",
+		"Liberation Serif", "Noto Sans", "Noto Serif", "Ubuntu", "Ubuntu Mono",
+	},
+	"Android": {
+		"Roboto", "Noto Sans", "Noto Serif", "Noto Color Emoji", "Droid Sans", "Droid Sans Mono", "Droid Serif",
+	},
+	"iOS": {
+		"Apple Color Emoji", "Avenir", "Baskerville", "Futura", "Gill Sans", "Helvetica Neue", "Menlo", "Optima",
+	},
+}
+
+// checkFontPlatform 检查字体列表是否与User Agent声明的操作系统一致
+// 字体列表为空时已由基础规则处理，这里不重复计分
+func checkFontPlatform(fp *models.Fingerprint) []signal {
+	fonts := utils.JSONToStringSlice(fp.Fonts)
+	if len(fonts) == 0 {
+		return nil
+	}
+	platform := utils.ParseUserAgent(fp.UserAgent).OS
+	baseline, ok := osBaselineFonts[platform]
+	if !ok {
+		return nil
+	}
+
+	present := make(map[string]bool, len(fonts))
+	for _, font := range fonts {
+		present[strings.ToLower(font)] = true
+	}
+	for _, font := range baseline {
+		if present[strings.ToLower(font)] {
+			return nil
+		}
+	}
+
+	return []signal{{
+		Code:   models.ReasonFontPlatformMismatch,
+		Weight: 0.25,
+		Reason: fmt.Sprintf("None of the default %s fonts detected", platform),
+	}}
+}
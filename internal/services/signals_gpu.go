@@ -0,0 +1,91 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// gpuPlatforms 只会出现在部分操作系统上的GPU家族；未列出的家族不校验
+// Windows on ARM 笔记本使用 Adreno，ChromeOS 和 Linux 开发板使用 Mali、PowerVR，因此这些组合不视为矛盾
+var gpuPlatforms = map[string]map[string]bool{
+	utils.GPUApple:   {"macOS": true, "iOS": true},
+	utils.GPUAdreno:  {"Android": true, "Windows": true, "Linux": true, "ChromeOS": true},
+	utils.GPUMali:    {"Android": true, "Linux": true, "ChromeOS": true},
+	utils.GPUPowerVR: {"Android": true, "Linux": true, "ChromeOS": true},
+}
+
+// checkGPUPlatform 检查GPU家族是否与User Agent声明的操作系统一致：如Windows上的Apple GPU，
+// 或iOS设备上的非Apple GPU（iOS设备只使用Apple GPU），说明User Agent或WebGL渲染器被伪造
+func checkGPUPlatform(fp *models.Fingerprint) []signal {
+	if fp.GPUFamily == "" || fp.GPUFamily == utils.GPUSoftware {
+		return nil
+	}
+	platform := utils.ParseUserAgent(fp.UserAgent).OS
+	if platform == "Unknown" {
+		return nil
+	}
+	mismatch := platform == "iOS" && fp.GPUFamily != utils.GPUApple
+	if allowed, ok := gpuPlatforms[fp.GPUFamily]; ok && !allowed[platform] {
+		mismatch = true
+	}
+	if !mismatch {
+		return nil
+	}
+	return []signal{{
+		Code:   models.ReasonGPUPlatformMismatch,
+		Weight: 0.4,
+		Reason: fmt.Sprintf("%s GPU is not used on %s", fp.GPUFamily, platform),
+	}}
+}
+
+// GPUStats 按GPU家族统计指纹数和其中判定为爬虫的数量，按首次出现时间和站点过滤，from、to 为空时不限制；结果按 query_cache 缓存
+func (fs *FingerprintService) GPUStats(ctx context.Context, from, to *time.Time, siteID string) ([]models.GPUFamilyCount, error) {
+	return cachedQuery(ctx, fs, queryKey("gpu", from, to, siteID), func(ctx context.Context) ([]models.GPUFamilyCount, error) {
+		return fs.gpuStats(ctx, from, to, siteID)
+	})
+}
+
+// gpuStats 查询GPU 系列统计，不使用缓存
+func (fs *FingerprintService) gpuStats(ctx context.Context, from, to *time.Time, siteID string) ([]models.GPUFamilyCount, error) {
+	where := []string{"f.deleted_at IS NULL"}
+	var args []interface{}
+	if from != nil {
+		where = append(where, "f.created_at >= ?")
+		args = append(args, *from)
+	}
+	if to != nil {
+		where = append(where, "f.created_at < ?")
+		args = append(args, *to)
+	}
+	if siteID != "" {
+		where = append(where, "f.site_id = ?")
+		args = append(args, siteID)
+	}
+
+	rows, err := fs.db.Read.QueryContext(ctx, `
+		SELECT f.gpu_family, COUNT(*) AS n, COALESCE(SUM(CASE WHEN a.is_bot THEN 1 ELSE 0 END), 0)
+		FROM fingerprints f LEFT JOIN analysis a ON a.fingerprint_hash = f.fingerprint_hash
+		WHERE `+strings.Join(where, " AND ")+`
+		GROUP BY f.gpu_family ORDER BY n DESC, f.gpu_family`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	families := []models.GPUFamilyCount{}
+	for rows.Next() {
+		var c models.GPUFamilyCount
+		if err := rows.Scan(&c.Family, &c.Count, &c.Bots); err != nil {
+			return nil, err
+		}
+		if c.Count > 0 {
+			c.BotRate = float64(c.Bots) / float64(c.Count)
+		}
+		families = append(families, c)
+	}
+	return families, rows.Err()
+}
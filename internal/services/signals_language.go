@@ -0,0 +1,102 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"fmt"
+	"strings"
+)
+
+// countryLanguages 常见国家通行的语言（ISO 639-1），用于比较浏览器语言与访客国家
+// 未收录的国家不做比较
+var countryLanguages = map[string][]string{
+	"US": {"en", "es"}, "GB": {"en"}, "CA": {"en", "fr"}, "AU": {"en"}, "NZ": {"en"}, "IE": {"en", "ga"},
+	"CN": {"zh"}, "TW": {"zh"}, "HK": {"zh", "en"}, "MO": {"zh", "pt"}, "SG": {"en", "zh", "ms", "ta"},
+	"JP": {"ja"}, "KR": {"ko"}, "IN": {"en", "hi", "bn", "te", "mr", "ta", "ur", "gu", "kn", "ml", "pa"},
+	"DE": {"de"}, "AT": {"de"}, "CH": {"de", "fr", "it", "rm"}, "FR": {"fr"}, "BE": {"nl", "fr", "de"},
+	"NL": {"nl"}, "LU": {"lb", "fr", "de"}, "ES": {"es", "ca", "eu", "gl"}, "PT": {"pt"}, "IT": {"it"},
+	"PL": {"pl"}, "CZ": {"cs"}, "SK": {"sk"}, "HU": {"hu"}, "RO": {"ro"}, "BG": {"bg"}, "GR": {"el"},
+	"SE": {"sv"}, "NO": {"nb", "nn", "no"}, "DK": {"da"}, "FI": {"fi", "sv"}, "IS": {"is"},
+	"EE": {"et"}, "LV": {"lv"}, "LT": {"lt"}, "UA": {"uk", "ru"}, "BY": {"be", "ru"}, "RU": {"ru"},
+	"KZ": {"kk", "ru"}, "TR": {"tr"}, "IL": {"he", "ar"}, "SA": {"ar"}, "AE": {"ar", "en"}, "EG": {"ar"},
+	"IR": {"fa"}, "PK": {"ur", "en"}, "BD": {"bn"}, "TH": {"th"}, "VN": {"vi"}, "ID": {"id"},
+	"MY": {"ms", "en", "zh"}, "PH": {"en", "fil", "tl"}, "BR": {"pt"}, "MX": {"es"}, "AR": {"es"},
+	"CL": {"es"}, "CO": {"es"}, "PE": {"es"}, "VE": {"es"}, "ZA": {"en", "af", "zu", "xh"},
+	"NG": {"en"}, "KE": {"en", "sw"}, "HR": {"hr"}, "RS": {"sr"}, "SI": {"sl"},
+}
+
+// acceptLanguageTags 解析 Accept-Language 请求头，按出现顺序返回语言标签（小写），忽略 * 和 q=0 的项
+func acceptLanguageTags(header string) []string {
+	var tags []string
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		excluded := false
+		for _, param := range fields[1:] {
+			param = strings.ReplaceAll(param, " ", "")
+			if param == "q=0" || strings.HasPrefix(param, "q=0.") && strings.Trim(param[4:], "0") == "" {
+				excluded = true
+			}
+		}
+		if !excluded {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// baseLanguage 返回语言标签的主语言子标签，如 zh-Hans-CN 返回 zh
+func baseLanguage(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		return tag[:i]
+	}
+	return tag
+}
+
+// checkLanguageConsistency 比较提交请求的 Accept-Language 与页面脚本报告的 navigator.language，以及访客国家
+// 浏览器按语言偏好生成 Accept-Language，navigator.language 就是其中的首选语言，
+// 主语言完全不在请求头中说明其中之一被改写；未收到请求头（如由接入方后端转发）时只与访客国家比较
+func checkLanguageConsistency(fp *models.Fingerprint) []signal {
+	tags := acceptLanguageTags(fp.AcceptLanguage)
+	language := baseLanguage(fp.Language)
+	if language == "" {
+		return nil
+	}
+
+	var signals []signal
+	found := false
+	for _, tag := range tags {
+		if baseLanguage(tag) == language {
+			found = true
+			break
+		}
+	}
+	if len(tags) > 0 && !found {
+		signals = append(signals, signal{
+			Code:   models.ReasonLanguageMismatch,
+			Weight: 0.3,
+			Reason: fmt.Sprintf("navigator.language %q is not in Accept-Language %q", fp.Language, fp.AcceptLanguage),
+		})
+	}
+
+	expected, ok := countryLanguages[fp.Country]
+	if !ok {
+		return signals
+	}
+	languages := append(tags, strings.ToLower(fp.Language))
+	for _, tag := range languages {
+		for _, lang := range expected {
+			if baseLanguage(tag) == lang {
+				return signals
+			}
+		}
+	}
+	return append(signals, signal{
+		Code:   models.ReasonLanguageCountryMismatch,
+		Weight: 0.1,
+		Reason: fmt.Sprintf("None of the browser languages (%s) is commonly used in %s", strings.Join(languages, ", "), fp.Country),
+	})
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"browser-detection/internal/config"
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"fmt"
+	"strings"
+)
+
+// standardPDFPlugins 现代浏览器按HTML规范报告的固定插件列表（NPAPI移除之后）
+// 内置PDF查看器启用时报告全部5项，禁用时报告空列表
+var standardPDFPlugins = map[string]bool{
+	"PDF Viewer":                true,
+	"Chrome PDF Viewer":         true,
+	"Chromium PDF Viewer":       true,
+	"Microsoft Edge PDF Viewer": true,
+	"WebKit built-in PDF":       true,
+}
+
+// pluginProfile 浏览器报告固定插件列表的起始版本
+type pluginProfile struct {
+	// Since 开始报告固定列表的主版本号
+	Since int
+	// EmptyAllowed 桌面端是否可能报告空列表（Firefox/Safari可关闭内置PDF查看器）
+	EmptyAllowed bool
+}
+
+var pluginProfiles = map[string]pluginProfile{
+	utils.BrowserChrome:  {Since: 94},
+	utils.BrowserEdge:    {Since: 94},
+	utils.BrowserOpera:   {Since: 80},
+	utils.BrowserFirefox: {Since: 99, EmptyAllowed: true},
+	utils.BrowserSafari:  {Since: 15, EmptyAllowed: true},
+}
+
+// checkPluginProfile 按浏览器预期的插件列表检查插件
+// 移动端浏览器不报告插件；桌面Chromium报告空列表是旧版无头Chrome的特征；
+// 固定列表之外的插件名（如 "Native Client"）说明插件列表是伪造的
+func checkPluginProfile(fp *models.Fingerprint) []signal {
+	plugins := utils.JSONToStringSlice(fp.Plugins)
+	mismatch := func(reason string) []signal {
+		return []signal{{Code: models.ReasonPluginProfileMismatch, Weight: 0.1, Reason: reason}}
+	}
+
+	if len(plugins) > 50 {
+		return mismatch(fmt.Sprintf("Too many plugins reported (%d)", len(plugins)))
+	}
+
+	ua := utils.ParseUserAgent(fp.UserAgent)
+	profile, ok := pluginProfiles[ua.Family]
+	if !ok || ua.Major < profile.Since {
+		return nil
+	}
+	if ua.Mobile {
+		if len(plugins) > 0 {
+			return mismatch(fmt.Sprintf("Mobile %s should not report plugins", ua.Family))
+		}
+		return nil
+	}
+
+	if len(plugins) == 0 {
+		if profile.EmptyAllowed {
+			return nil
+		}
+		return mismatch(fmt.Sprintf("No plugins detected (desktop %s reports built-in PDF plugins)", ua.Family))
+	}
+	for _, plugin := range plugins {
+		if !standardPDFPlugins[plugin] {
+			return mismatch(fmt.Sprintf("Unexpected plugin for %s %d: %s", ua.Family, ua.Major, plugin))
+		}
+	}
+	return nil
+}
+
+// checkExtensions 按站点策略把广告拦截和扩展痕迹计入爬虫评分
+// 两者在真实用户中很常见，默认不计分，由站点按需开启
+func checkExtensions(fp *models.Fingerprint, policy config.SitePolicy) []signal {
+	var signals []signal
+	if policy.ContentBlockingAffectsRisk && fp.AdBlockBlocked > 0 {
+		signals = append(signals, signal{
+			Code:   models.ReasonContentBlocking,
+			Weight: 0.1,
+			Reason: fmt.Sprintf("Content blocker hid %d of %d bait elements", fp.AdBlockBlocked, fp.AdBlockBaits),
+		})
+	}
+	if extensions := utils.JSONToStringSlice(fp.Extensions); policy.ExtensionsAffectRisk && len(extensions) > 0 {
+		signals = append(signals, signal{
+			Code:   models.ReasonExtensionsDetected,
+			Weight: 0.05,
+			Reason: fmt.Sprintf("Browser extensions detected: %s", strings.Join(extensions, ", ")),
+		})
+	}
+	return signals
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 隐私浏览器
+const (
+	PrivacyBrave      = "Brave"
+	PrivacyFirefoxRFP = "Firefox resistFingerprinting"
+)
+
+// privacyTolerantCodes 隐私浏览器的随机化（farbling）会触发、但不代表自动化的信号
+// Brave会随机化插件名和字体列表，Firefox RFP会限制字体并取整屏幕尺寸，随机化后的固定Canvas图案也不再匹配已知哈希；
+// 服务端验证的噪点和跨访问信号（canvas_noise_injected、canvas_randomized、audio_noise_injected、render_drift）、
+// 组件缺失和统计离群不在其中，照常计分
+var privacyTolerantCodes = map[string]bool{
+	models.ReasonFontPlatformMismatch:  true,
+	models.ReasonPluginProfileMismatch: true,
+	models.ReasonScreenMetricsInvalid:  true,
+	models.ReasonCanvasStaticMismatch:  true,
+}
+
+// detectPrivacyBrowser 识别隐私浏览器的反指纹模式，返回浏览器名，未识别时返回空字符串
+// 客户端的声明和可伪造的环境特征都不足以认定，还需服务端确认渲染结果确实被随机化（见 farblingVerified）：
+//   - Brave：前端上报 navigator.brave，且UA为Chromium系（V8引擎）
+//   - Firefox RFP：Firefox UA、时区为UTC，且屏幕尺寸取整到 200x100 的倍数、CPU核数为2（RFP同时固定两者，
+//     只有其一的UTC时区Firefox或2核虚拟机不认定）
+//
+// variants 返回同一访客的其他提交中该组件出现过的不同取值数
+func detectPrivacyBrowser(fp *models.Fingerprint, req *models.FingerprintRequest, variants func(column, value string) int) string {
+	ua := utils.ParseUserAgent(fp.UserAgent)
+	browser := ""
+	switch {
+	case req.Brave:
+		if ua.Engine == utils.EngineV8 && ua.OS != "iOS" {
+			browser = PrivacyBrave
+		}
+	case ua.Family == utils.BrowserFirefox:
+		switch fp.Timezone {
+		case "UTC", "Etc/UTC", "Etc/GMT", "GMT":
+			if isLetterboxedResolution(fp.ScreenResolution) && fp.HardwareConcurrency == 2 {
+				browser = PrivacyFirefoxRFP
+			}
+		}
+	}
+	if browser == "" || !farblingVerified(fp, variants) {
+		return ""
+	}
+	return browser
+}
+
+// farblingVerified 服务端是否确认了渲染结果被随机化：Canvas图像分析的噪点比例超过阈值、
+// 服务端分析音频采样发现抖动，或同一访客（相同IP与User Agent）提交过其他Canvas（跨访问的随机化差异）
+func farblingVerified(fp *models.Fingerprint, variants func(column, value string) int) bool {
+	if fp.CanvasNoise >= canvasNoiseThreshold || fp.AudioNoise >= audioNoiseThreshold {
+		return true
+	}
+	return fp.CanvasHash != "" && variants("canvas_hash", fp.CanvasHash) > 0
+}
+
+// isLetterboxedResolution 判断分辨率是否为RFP取整后的尺寸（宽为200的倍数、高为100的倍数）
+func isLetterboxedResolution(resolution string) bool {
+	width, height, ok := strings.Cut(resolution, "x")
+	if !ok {
+		return false
+	}
+	w, errW := strconv.Atoi(width)
+	h, errH := strconv.Atoi(height)
+	return errW == nil && errH == nil && w > 0 && h > 0 && w%200 == 0 && h%100 == 0
+}
+
+// applyPrivacyMode 隐私浏览器下移除随机化导致的信号，并记录识别结果（不计分）
+// 只豁免 privacyTolerantCodes 中的信号，UA关键字、无头特征、服务端验证的噪点等仍然照常计分
+func applyPrivacyMode(signals []signal, privacyBrowser string) []signal {
+	if privacyBrowser == "" {
+		return signals
+	}
+	kept := make([]signal, 0, len(signals)+1)
+	for _, sig := range signals {
+		if !privacyTolerantCodes[sig.Code] {
+			kept = append(kept, sig)
+		}
+	}
+	return append(kept, signal{
+		Code:   models.ReasonPrivacyBrowser,
+		Reason: fmt.Sprintf("Privacy browser detected (%s); fingerprint randomization is not treated as bot behavior", privacyBrowser),
+	})
+}
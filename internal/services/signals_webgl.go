@@ -0,0 +1,180 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// webglSpoofWeight webgl_spoof 信号的权重
+const webglSpoofWeight = 0.5
+
+// webglPowerOfTwoParams 真实实现中总是2的幂的尺寸参数
+var webglPowerOfTwoParams = []string{"MAX_TEXTURE_SIZE", "MAX_CUBE_MAP_TEXTURE_SIZE", "MAX_RENDERBUFFER_SIZE"}
+
+// webglMinimums WebGL 1.0 规范要求的最小值
+var webglMinimums = []struct {
+	name string
+	min  int
+}{
+	{"MAX_TEXTURE_SIZE", 64},
+	{"MAX_CUBE_MAP_TEXTURE_SIZE", 16},
+	{"MAX_RENDERBUFFER_SIZE", 1},
+	{"MAX_VERTEX_ATTRIBS", 8},
+	{"MAX_VERTEX_UNIFORM_VECTORS", 128},
+	{"MAX_VARYING_VECTORS", 8},
+	{"MAX_COMBINED_TEXTURE_IMAGE_UNITS", 8},
+	{"MAX_TEXTURE_IMAGE_UNITS", 8},
+	{"MAX_FRAGMENT_UNIFORM_VECTORS", 16},
+}
+
+// webglExtensionPrefixes 已注册的WebGL扩展名前缀
+var webglExtensionPrefixes = []string{"ANGLE_", "EXT_", "KHR_", "OES_", "OVR_", "WEBGL_", "WEBKIT_", "MOZ_"}
+
+// webglParamsJSON 将WebGL参数序列化后保存，未采集时为空字符串
+func webglParamsJSON(params *models.WebGLParams) string {
+	if params == nil {
+		return ""
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// webglParamProblems 检查WebGL参数自身的一致性：尺寸参数须为2的幂且不低于规范的最小值，
+// 纹理单元总数不少于片元着色器的纹理单元数，扩展名不重复且使用已注册的前缀，
+// 着色器精度不超过单精度浮点数（指数127、尾数23位）且高精度不低于中精度
+func webglParamProblems(p *models.WebGLParams) []string {
+	var problems []string
+	for _, name := range webglPowerOfTwoParams {
+		if v, ok := p.Params[name]; ok && (v <= 0 || v&(v-1) != 0) {
+			problems = append(problems, fmt.Sprintf("%s %d is not a power of two", name, v))
+		}
+	}
+	for _, m := range webglMinimums {
+		if v, ok := p.Params[m.name]; ok && v < m.min {
+			problems = append(problems, fmt.Sprintf("%s %d is below the WebGL minimum %d", m.name, v, m.min))
+		}
+	}
+	if combined, ok := p.Params["MAX_COMBINED_TEXTURE_IMAGE_UNITS"]; ok {
+		if units, ok := p.Params["MAX_TEXTURE_IMAGE_UNITS"]; ok && combined < units {
+			problems = append(problems, fmt.Sprintf("MAX_COMBINED_TEXTURE_IMAGE_UNITS %d is below MAX_TEXTURE_IMAGE_UNITS %d", combined, units))
+		}
+	}
+
+	seen := make(map[string]bool, len(p.Extensions))
+	for _, ext := range p.Extensions {
+		if seen[ext] {
+			problems = append(problems, "duplicate extension "+ext)
+			break
+		}
+		seen[ext] = true
+		if !hasAnyPrefix(ext, webglExtensionPrefixes) {
+			problems = append(problems, "unknown extension "+ext)
+			break
+		}
+	}
+
+	for _, shader := range []string{"VERTEX_SHADER", "FRAGMENT_SHADER"} {
+		high, okHigh := p.Precisions[shader+".HIGH_FLOAT"]
+		medium, okMedium := p.Precisions[shader+".MEDIUM_FLOAT"]
+		for _, f := range [][3]int{high, medium} {
+			if f[0] > 127 || f[1] > 127 || f[2] > 23 {
+				problems = append(problems, fmt.Sprintf("%s float precision %v exceeds single precision", shader, f))
+				break
+			}
+		}
+		if okHigh && okMedium && high != [3]int{} && high[2] < medium[2] {
+			problems = append(problems, fmt.Sprintf("%s HIGH_FLOAT precision %d is below MEDIUM_FLOAT %d", shader, high[2], medium[2]))
+		}
+	}
+	return problems
+}
+
+// hasAnyPrefix 判断字符串是否以任一前缀开头
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// webglBaselineProblems 将WebGL参数与基线数据中该GPU类别的真实取值比较：参数取值不在已知范围内，或缺少真实实现总会提供的扩展
+func (fs *FingerprintService) webglBaselineProblems(fp *models.Fingerprint, p *models.WebGLParams) []string {
+	gpu := fp.GPUFamily
+	if gpu == "" {
+		return nil
+	}
+	ua := utils.ParseUserAgent(fp.UserAgent)
+	extensions := make(map[string]bool, len(p.Extensions))
+	for _, ext := range p.Extensions {
+		extensions[ext] = true
+	}
+
+	var problems []string
+	for _, b := range fs.corpus.Load().WebGL {
+		if !strings.EqualFold(b.GPU, gpu) ||
+			(b.Browser != "" && !strings.EqualFold(b.Browser, ua.Family)) ||
+			(b.OS != "" && !strings.EqualFold(b.OS, ua.OS)) {
+			continue
+		}
+		names := make([]string, 0, len(b.Params))
+		for name := range b.Params {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			v, ok := p.Params[name]
+			if ok && !containsInt(b.Params[name], v) {
+				problems = append(problems, fmt.Sprintf("%s %d is not seen on %s GPUs (expected %v)", name, v, gpu, b.Params[name]))
+			}
+		}
+		if len(p.Extensions) == 0 {
+			continue
+		}
+		for _, ext := range b.Extensions {
+			if !extensions[ext] {
+				problems = append(problems, fmt.Sprintf("%s GPUs always expose %s", gpu, ext))
+			}
+		}
+	}
+	return problems
+}
+
+// containsInt 判断切片中是否包含该值
+func containsInt(values []int, v int) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// checkWebGLParams 校验WebGL参数向量：自相矛盾或与渲染器所属GPU类别的真实取值不符，说明参数被伪造
+func (fs *FingerprintService) checkWebGLParams(fp *models.Fingerprint) []signal {
+	if fp.WebGLParams == "" {
+		return nil
+	}
+	var p models.WebGLParams
+	if err := json.Unmarshal([]byte(fp.WebGLParams), &p); err != nil {
+		return nil
+	}
+	problems := webglParamProblems(&p)
+	problems = append(problems, fs.webglBaselineProblems(fp, &p)...)
+	if len(problems) == 0 {
+		return nil
+	}
+	return []signal{{
+		Code:   models.ReasonWebGLSpoof,
+		Weight: webglSpoofWeight,
+		Reason: "WebGL parameters are inconsistent: " + strings.Join(problems, "; "),
+	}}
+}
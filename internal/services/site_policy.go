@@ -0,0 +1,435 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// retentionInterval 数据保留清理任务的运行间隔
+const retentionInterval = time.Hour
+
+// riskRank 风险等级的次序，用于比较挑战策略
+var riskRank = map[string]int{"LOW": 0, "MEDIUM": 1, "HIGH": 2}
+
+// LoadSitePolicies 从数据库加载站点的评分与策略覆盖
+func (fs *FingerprintService) LoadSitePolicies(ctx context.Context) error {
+	rows, err := fs.db.DB.QueryContext(ctx,
+		"SELECT site_id, bot_threshold, rule_weights, event_thresholds, challenge_policy, retention_days, country_policy, crawler_policy, actions, updated_at FROM site_policies")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	policies := make(map[string]models.SitePolicyOverride)
+	for rows.Next() {
+		var p models.SitePolicyOverride
+		var threshold sql.NullFloat64
+		var weights, eventThresholds, countryPolicy, crawlerPolicy, actions string
+		if err := rows.Scan(&p.SiteID, &threshold, &weights, &eventThresholds, &p.ChallengePolicy, &p.RetentionDays, &countryPolicy, &crawlerPolicy, &actions, &p.UpdatedAt); err != nil {
+			return err
+		}
+		if threshold.Valid {
+			p.BotThreshold = &threshold.Float64
+		}
+		if err := json.Unmarshal([]byte(weights), &p.RuleWeights); err != nil {
+			return fmt.Errorf("invalid rule weights for site %s: %w", p.SiteID, err)
+		}
+		if err := json.Unmarshal([]byte(eventThresholds), &p.EventBotScore); err != nil {
+			return fmt.Errorf("invalid event thresholds for site %s: %w", p.SiteID, err)
+		}
+		if countryPolicy != "" {
+			if err := json.Unmarshal([]byte(countryPolicy), &p.CountryPolicy); err != nil {
+				return fmt.Errorf("invalid country policy for site %s: %w", p.SiteID, err)
+			}
+		}
+		if crawlerPolicy != "" {
+			if err := json.Unmarshal([]byte(crawlerPolicy), &p.CrawlerPolicy); err != nil {
+				return fmt.Errorf("invalid crawler policy for site %s: %w", p.SiteID, err)
+			}
+		}
+		if err := json.Unmarshal([]byte(actions), &p.Actions); err != nil {
+			return fmt.Errorf("invalid action policies for site %s: %w", p.SiteID, err)
+		}
+		policies[p.SiteID] = p
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	fs.policyMu.Lock()
+	fs.policies = policies
+	fs.policyMu.Unlock()
+	return nil
+}
+
+// siteOverride 返回站点的策略覆盖，未配置时为零值
+func (fs *FingerprintService) siteOverride(siteID string) models.SitePolicyOverride {
+	fs.policyMu.RLock()
+	defer fs.policyMu.RUnlock()
+	return fs.policies[siteID]
+}
+
+// GetSitePolicy 返回站点的策略覆盖，站点未配置或尚无覆盖时返回 sql.ErrNoRows
+func (fs *FingerprintService) GetSitePolicy(siteID string) (*models.SitePolicyOverride, error) {
+	if _, ok := fs.sites[siteID]; !ok {
+		return nil, sql.ErrNoRows
+	}
+	fs.policyMu.RLock()
+	defer fs.policyMu.RUnlock()
+	p, ok := fs.policies[siteID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &p, nil
+}
+
+// validateSitePolicy 校验策略覆盖的取值，返回字段级错误
+func (fs *FingerprintService) validateSitePolicy(p *models.SitePolicyOverride) []models.FieldError {
+	var errs []models.FieldError
+	if p.BotThreshold != nil && (*p.BotThreshold <= 0 || *p.BotThreshold > 1) {
+		errs = append(errs, models.FieldError{Field: "bot_threshold", Constraint: "range (0, 1]", Got: *p.BotThreshold})
+	}
+	known := make(map[string]bool, len(models.ReasonCodes))
+	for _, code := range models.ReasonCodes {
+		known[code] = true
+	}
+	codes := make([]string, 0, len(p.RuleWeights))
+	for code := range p.RuleWeights {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		weight := p.RuleWeights[code]
+		if !known[code] {
+			errs = append(errs, models.FieldError{Field: "rule_weights." + code, Constraint: "known reason code"})
+		} else if weight < 0 || weight > 1 {
+			errs = append(errs, models.FieldError{Field: "rule_weights." + code, Constraint: "range [0, 1]", Got: weight})
+		}
+	}
+	errs = append(errs, validateEventThresholds(p.EventBotScore)...)
+	switch p.ChallengePolicy {
+	case "", models.ChallengeOff, models.ChallengeHigh, models.ChallengeMedium:
+	default:
+		errs = append(errs, models.FieldError{Field: "challenge_policy", Constraint: "one of off, high, medium", Got: p.ChallengePolicy})
+	}
+	if p.RetentionDays < 0 {
+		errs = append(errs, models.FieldError{Field: "retention_days", Constraint: "non-negative", Got: p.RetentionDays})
+	}
+	if p.CountryPolicy != nil {
+		errs = append(errs, validateCountryPolicy(p.CountryPolicy)...)
+	}
+	if p.CrawlerPolicy != nil {
+		errs = append(errs, fs.validateCrawlerPolicy(p.CrawlerPolicy)...)
+	}
+	errs = append(errs, validateActionPolicies(p.Actions)...)
+	return errs
+}
+
+// SaveSitePolicy 校验并保存站点的策略覆盖，立即对之后的提交生效，并写入审计记录
+// 站点未配置时返回 sql.ErrNoRows，取值不合法时返回字段级错误
+func (fs *FingerprintService) SaveSitePolicy(ctx context.Context, p *models.SitePolicyOverride, actor string) ([]models.FieldError, error) {
+	if _, ok := fs.sites[p.SiteID]; !ok {
+		return nil, sql.ErrNoRows
+	}
+	if errs := fs.validateSitePolicy(p); len(errs) > 0 {
+		return errs, nil
+	}
+	if p.ChallengePolicy == "" {
+		p.ChallengePolicy = models.ChallengeOff
+	}
+	if p.RuleWeights == nil {
+		p.RuleWeights = map[string]float64{}
+	}
+	if p.EventBotScore == nil {
+		p.EventBotScore = map[string]float64{}
+	}
+	if p.Actions == nil {
+		p.Actions = map[string]models.ActionPolicy{}
+	}
+	weights, err := json.Marshal(p.RuleWeights)
+	if err != nil {
+		return nil, err
+	}
+	eventThresholds, err := json.Marshal(p.EventBotScore)
+	if err != nil {
+		return nil, err
+	}
+	actions, err := json.Marshal(p.Actions)
+	if err != nil {
+		return nil, err
+	}
+	var countryPolicy string
+	if p.CountryPolicy != nil {
+		b, err := json.Marshal(p.CountryPolicy)
+		if err != nil {
+			return nil, err
+		}
+		countryPolicy = string(b)
+	}
+	var crawlerPolicy string
+	if p.CrawlerPolicy != nil {
+		b, err := json.Marshal(p.CrawlerPolicy)
+		if err != nil {
+			return nil, err
+		}
+		crawlerPolicy = string(b)
+	}
+	var threshold sql.NullFloat64
+	if p.BotThreshold != nil {
+		threshold = sql.NullFloat64{Float64: *p.BotThreshold, Valid: true}
+	}
+	p.UpdatedAt = time.Now()
+
+	fs.policyMu.Lock()
+	defer fs.policyMu.Unlock()
+	var before *models.SitePolicyOverride
+	if old, ok := fs.policies[p.SiteID]; ok {
+		before = &old
+	}
+
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT OR REPLACE INTO site_policies (site_id, bot_threshold, rule_weights, event_thresholds, challenge_policy, retention_days, country_policy, crawler_policy, actions, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.SiteID, threshold, string(weights), string(eventThresholds), p.ChallengePolicy, p.RetentionDays, countryPolicy, crawlerPolicy, string(actions), p.UpdatedAt); err != nil {
+		return nil, fmt.Errorf("failed to save site policy: %w", err)
+	}
+	if err := recordAudit(ctx, tx, actor, "update_site_policy", p.SiteID, before, p); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	fs.policies[p.SiteID] = *p
+	return nil, nil
+}
+
+// DeleteSitePolicy 删除站点的策略覆盖，恢复默认策略，并写入审计记录
+func (fs *FingerprintService) DeleteSitePolicy(ctx context.Context, siteID, actor string) error {
+	fs.policyMu.Lock()
+	defer fs.policyMu.Unlock()
+	before, ok := fs.policies[siteID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM site_policies WHERE site_id = ?", siteID); err != nil {
+		return err
+	}
+	if err := recordAudit(ctx, tx, actor, "delete_site_policy", siteID, before, nil); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	delete(fs.policies, siteID)
+	return nil
+}
+
+// applyRuleWeights 按站点的规则权重覆盖调整信号权重
+func applyRuleWeights(signals []signal, weights map[string]float64) []signal {
+	if len(weights) == 0 {
+		return signals
+	}
+	for i := range signals {
+		if w, ok := weights[signals[i].Code]; ok {
+			signals[i].Weight = w
+		}
+	}
+	return signals
+}
+
+// botThreshold 返回策略覆盖中的爬虫判定阈值，未覆盖时使用全局阈值
+func (fs *FingerprintService) botThreshold(p models.SitePolicyOverride) float64 {
+	return scoringRules{thresholds: fs.Thresholds(), override: p}.botThreshold()
+}
+
+// scoringRules 一次评分使用的全局阈值与站点覆盖
+type scoringRules struct {
+	thresholds models.Thresholds
+	override   models.SitePolicyOverride
+}
+
+// rulesFor 返回站点当前生效的评分规则
+func (fs *FingerprintService) rulesFor(siteID string) scoringRules {
+	return scoringRules{thresholds: fs.Thresholds(), override: fs.siteOverride(siteID)}
+}
+
+// botThreshold 返回爬虫判定阈值，站点覆盖优先于全局阈值
+func (r scoringRules) botThreshold() float64 {
+	if r.override.BotThreshold != nil {
+		return *r.override.BotThreshold
+	}
+	return r.thresholds.BotScore
+}
+
+// needsChallenge 按站点的挑战策略判断是否需要人机验证
+func needsChallenge(p models.SitePolicyOverride, riskLevel string) bool {
+	switch p.ChallengePolicy {
+	case models.ChallengeHigh:
+		return riskRank[riskLevel] >= riskRank["HIGH"]
+	case models.ChallengeMedium:
+		return riskRank[riskLevel] >= riskRank["MEDIUM"]
+	}
+	return false
+}
+
+// PurgeExpired 按各站点的保留天数删除长期未出现的指纹及其分析结果、组件哈希和索引
+func (fs *FingerprintService) PurgeExpired(ctx context.Context) (int, error) {
+	fs.policyMu.RLock()
+	retention := make(map[string]int)
+	for siteID, p := range fs.policies {
+		if p.RetentionDays > 0 {
+			retention[siteID] = p.RetentionDays
+		}
+	}
+	fs.policyMu.RUnlock()
+
+	total := 0
+	for siteID, days := range retention {
+		n, err := fs.purgeSite(ctx, siteID, time.Now().AddDate(0, 0, -days))
+		if err != nil {
+			return total, fmt.Errorf("failed to purge site %s: %w", siteID, err)
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// purgeSite 在事务中删除站点在 cutoff 之前最后出现的指纹
+func (fs *FingerprintService) purgeSite(ctx context.Context, siteID string, cutoff time.Time) (int, error) {
+	rows, err := fs.db.DB.QueryContext(ctx,
+		"SELECT fingerprint_hash FROM fingerprints WHERE site_id = ? AND updated_at < ?", siteID, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		hashes = append(hashes, hash)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(hashes) == 0 {
+		return 0, err
+	}
+
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	tables := []string{"fingerprints", "analysis", "fingerprint_components", "canvas_lsh", "component_history",
+		"events", "account_devices", "account_signals", "visitor_fingerprints", "fingerprint_asns", "navigation_log",
+		"page_views", "scraping_detections", "challenges"}
+	for _, hash := range hashes {
+		for _, table := range tables {
+			if _, err := tx.ExecContext(ctx,
+				"DELETE FROM "+table+" WHERE fingerprint_hash = ?", hash); err != nil {
+				return 0, fmt.Errorf("failed to delete from %s: %w", table, err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	if fs.vectors != nil {
+		for _, hash := range hashes {
+			fs.vectors.Remove(hash)
+		}
+	}
+	log.Printf("Purged %d expired fingerprints for site %s", len(hashes), siteID)
+	return len(hashes), nil
+}
+
+// RunRetention 定期按站点的保留天数清理过期指纹，直到 ctx 结束
+func (fs *FingerprintService) RunRetention(ctx context.Context) {
+	ticker := time.NewTicker(retentionInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !fs.IsLeader() {
+				continue
+			}
+			if _, err := fs.PurgeExpired(ctx); err != nil {
+				log.Printf("Retention purge failed: %v", err)
+			}
+			if err := fs.purgeIPReputation(ctx); err != nil {
+				log.Printf("IP reputation purge failed: %v", err)
+			}
+			if err := fs.purgeFingerprintASNs(ctx); err != nil {
+				log.Printf("Fingerprint ASN purge failed: %v", err)
+			}
+			if err := fs.purgeSubnetActivity(ctx); err != nil {
+				log.Printf("Subnet activity purge failed: %v", err)
+			}
+			if err := fs.purgeCanvasRenders(ctx); err != nil {
+				log.Printf("Canvas render purge failed: %v", err)
+			}
+			if err := fs.purgeQuarantine(ctx); err != nil {
+				log.Printf("Quarantine purge failed: %v", err)
+			}
+			if err := fs.purgeBlocklist(ctx); err != nil {
+				log.Printf("Blocklist purge failed: %v", err)
+			}
+			if err := fs.purgeDetectionHits(ctx); err != nil {
+				log.Printf("Detection hit purge failed: %v", err)
+			}
+			if err := fs.purgeNavigation(ctx); err != nil {
+				log.Printf("Navigation log purge failed: %v", err)
+			}
+			if err := fs.purgePageViews(ctx); err != nil {
+				log.Printf("Page view purge failed: %v", err)
+			}
+			if err := fs.purgeRobotsViolations(ctx); err != nil {
+				log.Printf("Robots violation purge failed: %v", err)
+			}
+			if err := fs.purgeAccessLogClients(ctx); err != nil {
+				log.Printf("Access log client purge failed: %v", err)
+			}
+			if err := fs.purgeJA4Stats(ctx); err != nil {
+				log.Printf("JA4 stats purge failed: %v", err)
+			}
+			if err := fs.purgeReplication(ctx); err != nil {
+				log.Printf("Replication log purge failed: %v", err)
+			}
+			if err := fs.purgeSessionActivity(ctx); err != nil {
+				log.Printf("Session activity purge failed: %v", err)
+			}
+			if err := fs.purgeChallenges(ctx); err != nil {
+				log.Printf("Challenge purge failed: %v", err)
+			}
+			if err := fs.purgeProofSeeds(ctx); err != nil {
+				log.Printf("Proof seed purge failed: %v", err)
+			}
+			fs.queryCache.invalidate()
+		}
+	}
+}
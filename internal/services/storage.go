@@ -0,0 +1,89 @@
+package services
+
+import (
+	"browser-detection/internal/counters"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// ErrStorageUnavailable 存储故障或熔断期间拒绝写入，指纹哈希仍会返回
+var ErrStorageUnavailable = errors.New("storage unavailable")
+
+// isRetryable 判断错误是否为重试可能成功的瞬时错误：锁冲突或连接失效
+func isRetryable(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+	return false
+}
+
+// isStorageFailure 判断错误是否说明存储本身不可用，计入熔断
+func isStorageFailure(err error) bool {
+	if isRetryable(err) {
+		return true
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code {
+		case sqlite3.ErrIoErr, sqlite3.ErrCantOpen, sqlite3.ErrFull, sqlite3.ErrReadonly, sqlite3.ErrCorrupt:
+			return true
+		}
+	}
+	return false
+}
+
+// withStorage 在熔断器允许时执行写入，瞬时错误按指数退避重试
+// 熔断期间或重试后仍为存储故障时返回包装了 ErrStorageUnavailable 的错误
+func (fs *FingerprintService) withStorage(ctx context.Context, fn func() error) error {
+	if !fs.breaker.Allow() {
+		return ErrStorageUnavailable
+	}
+	backoff := fs.storage.RetryBackoff.Std()
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || !isRetryable(err) || attempt >= fs.storage.RetryAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-time.After(backoff):
+			backoff *= 2
+			continue
+		}
+		break
+	}
+
+	if err == nil || !isStorageFailure(err) {
+		fs.breaker.Success()
+		return err
+	}
+	if fs.breaker.Failure() {
+		log.Printf("Storage circuit breaker opened for %s: %v", fs.storage.BreakerCooldown.Std(), err)
+	}
+	return errors.Join(ErrStorageUnavailable, err)
+}
+
+// StorageState 返回存储熔断器的状态
+func (fs *FingerprintService) StorageState() string {
+	return fs.breaker.State()
+}
+
+// StorageRetryAfter 返回熔断期间距离下次探测的时长
+func (fs *FingerprintService) StorageRetryAfter() time.Duration {
+	return fs.breaker.RetryAfter()
+}
+
+// Counters 返回速率限制和速度窗口共用的计数
+func (fs *FingerprintService) Counters() *counters.Counters {
+	return fs.counters
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"browser-detection/internal/alerting"
+	"browser-detection/internal/models"
+	"context"
+	"fmt"
+	"log"
+)
+
+// detectStuffing 在记录登录失败事件之前，按指纹和IP段统计窗口内的登录失败
+// 失败次数和涉及的账号数（均包括本次事件）都达到阈值时记录撞库检测、封禁并告警
+// 已在封禁中的键不重复检测
+func (fs *FingerprintService) detectStuffing(ctx context.Context, event *models.Event, ipRange string) ([]models.StuffingDetection, error) {
+	cfg := fs.stuffing
+	if cfg.Window <= 0 || event.EventType != models.EventLogin || event.Outcome != models.OutcomeFailure {
+		return nil, nil
+	}
+	since := event.CreatedAt.Add(-cfg.Window.Std())
+
+	dimensions := []struct{ kind, column, key string }{
+		{models.BlockFingerprint, "fingerprint_hash", event.FingerprintHash},
+		{models.BlockIPRange, "ip_range", ipRange},
+	}
+	var detections []models.StuffingDetection
+	for _, d := range dimensions {
+		if d.key == "" {
+			continue
+		}
+		var failures, accounts, seen int
+		if err := fs.db.DB.QueryRowContext(ctx, `
+			SELECT COUNT(*), COUNT(DISTINCT NULLIF(account_id, '')), COALESCE(SUM(account_id = ?), 0) FROM events
+			WHERE site_id = ? AND event_type = ? AND outcome = ? AND `+d.column+` = ? AND created_at >= ?`,
+			event.AccountID, event.SiteID, models.EventLogin, models.OutcomeFailure, d.key, since).
+			Scan(&failures, &accounts, &seen); err != nil {
+			return nil, err
+		}
+		failures++
+		if event.AccountID != "" && seen == 0 {
+			accounts++
+		}
+		if failures < cfg.MinFailures || accounts < cfg.MinAccounts {
+			continue
+		}
+
+		var active int
+		if err := fs.db.DB.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM blocklist WHERE kind = ? AND key = ? AND expires_at > ?",
+			d.kind, d.key, event.CreatedAt).Scan(&active); err != nil {
+			return nil, err
+		}
+		if active > 0 {
+			continue
+		}
+
+		detection := models.StuffingDetection{
+			SiteID:    event.SiteID,
+			Kind:      d.kind,
+			Key:       d.key,
+			Failures:  failures,
+			Accounts:  accounts,
+			CreatedAt: event.CreatedAt,
+		}
+		result, err := fs.db.DB.ExecContext(ctx, `
+			INSERT INTO credential_stuffing (site_id, kind, key, failures, accounts, created_at)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			detection.SiteID, detection.Kind, detection.Key, detection.Failures, detection.Accounts, detection.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save credential stuffing detection: %w", err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		detection.ID = int(id)
+		if _, err := fs.Block(ctx, d.kind, d.key, models.ReasonCredentialStuffing, cfg.BlockDuration.Std()); err != nil {
+			return nil, err
+		}
+		detections = append(detections, detection)
+
+		if err := fs.alerts.Notify(ctx, alerting.Alert{
+			Kind:   models.ReasonCredentialStuffing,
+			SiteID: detection.SiteID,
+			Message: fmt.Sprintf("credential_stuffing for site %q: %s %s failed %d logins across %d accounts within %s",
+				detection.SiteID, detection.Kind, detection.Key, detection.Failures, detection.Accounts, cfg.Window.Std()),
+			Details: map[string]interface{}{
+				"kind":     detection.Kind,
+				"key":      detection.Key,
+				"failures": detection.Failures,
+				"accounts": detection.Accounts,
+			},
+			Time: detection.CreatedAt,
+		}); err != nil {
+			log.Printf("Failed to send credential stuffing alert: %v", err)
+		}
+	}
+	return detections, nil
+}
+
+// GetStuffingDetections 按时间倒序返回撞库检测记录，siteID 为空时返回所有站点
+func (fs *FingerprintService) GetStuffingDetections(ctx context.Context, siteID string, limit int) ([]models.StuffingDetection, error) {
+	query := "SELECT id, site_id, kind, key, failures, accounts, created_at FROM credential_stuffing"
+	var args []interface{}
+	if siteID != "" {
+		query += " WHERE site_id = ?"
+		args = append(args, siteID)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := fs.db.Read.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	detections := []models.StuffingDetection{}
+	for rows.Next() {
+		var d models.StuffingDetection
+		if err := rows.Scan(&d.ID, &d.SiteID, &d.Kind, &d.Key, &d.Failures, &d.Accounts, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		detections = append(detections, d)
+	}
+	return detections, rows.Err()
+}
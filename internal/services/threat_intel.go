@@ -0,0 +1,320 @@
+package services
+
+import (
+	"browser-detection/internal/config"
+	"browser-detection/internal/models"
+	"browser-detection/internal/threatintel"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// threatFeedCheckInterval 检查情报源是否到期更新的间隔
+const threatFeedCheckInterval = time.Minute
+
+// threatFeed 已配置的情报源
+type threatFeed struct {
+	feed threatintel.Feed
+	cfg  config.ThreatFeedConfig
+}
+
+// newThreatFeeds 按配置创建情报源，类型在加载配置时已校验
+func newThreatFeeds(cfg config.ThreatIntelConfig) []threatFeed {
+	feeds := make([]threatFeed, 0, len(cfg.Feeds))
+	for _, fc := range cfg.Feeds {
+		feed, err := threatintel.New(fc, cfg.Timeout.Std())
+		if err != nil {
+			log.Printf("Skipping threat feed %s: %v", fc.Name, err)
+			continue
+		}
+		feeds = append(feeds, threatFeed{feed: feed, cfg: fc})
+	}
+	return feeds
+}
+
+// threatFeedByName 按名称查找已配置的情报源
+func (fs *FingerprintService) threatFeedByName(name string) (threatFeed, bool) {
+	for _, f := range fs.threatFeeds {
+		if f.cfg.Name == name {
+			return f, true
+		}
+	}
+	return threatFeed{}, false
+}
+
+// LoadThreatIntel 登记已配置的情报源，并从数据库重建已启用情报源的索引
+func (fs *FingerprintService) LoadThreatIntel(ctx context.Context) error {
+	if len(fs.threatFeeds) == 0 {
+		return nil
+	}
+	enabled := make(map[string]bool, len(fs.threatFeeds))
+	for _, f := range fs.threatFeeds {
+		if _, err := fs.db.DB.ExecContext(ctx,
+			"INSERT OR IGNORE INTO threat_feeds (name) VALUES (?)", f.cfg.Name); err != nil {
+			return fmt.Errorf("failed to register threat feed %s: %w", f.cfg.Name, err)
+		}
+		var on bool
+		if err := fs.db.DB.QueryRowContext(ctx,
+			"SELECT enabled FROM threat_feeds WHERE name = ?", f.cfg.Name).Scan(&on); err != nil {
+			return err
+		}
+		enabled[f.cfg.Name] = on
+	}
+
+	rows, err := fs.db.DB.QueryContext(ctx, "SELECT feed, prefix, reference, confidence FROM threat_intel")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	entries := make(map[string][]threatintel.Entry)
+	for rows.Next() {
+		var feed, prefix string
+		var e threatintel.Entry
+		if err := rows.Scan(&feed, &prefix, &e.Reference, &e.Confidence); err != nil {
+			return err
+		}
+		// 已停用或已从配置中移除的情报源不参与评分
+		if !enabled[feed] {
+			continue
+		}
+		if e.Prefix, err = netip.ParsePrefix(prefix); err != nil {
+			log.Printf("Skipping invalid threat intel prefix %q from %s", prefix, feed)
+			continue
+		}
+		entries[feed] = append(entries[feed], e)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	fs.threatIndex.Store(threatintel.NewIndex(entries))
+	return nil
+}
+
+// RunThreatIntel 定期更新到期的情报源，多实例部署时只在持有租约的实例上下载
+func (fs *FingerprintService) RunThreatIntel(ctx context.Context) {
+	if len(fs.threatFeeds) == 0 {
+		return
+	}
+	ticker := time.NewTicker(threatFeedCheckInterval)
+	defer ticker.Stop()
+	for {
+		if fs.IsLeader() {
+			fs.updateDueThreatFeeds(ctx)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// updateDueThreatFeeds 更新已启用且距上次尝试超过更新间隔的情报源
+func (fs *FingerprintService) updateDueThreatFeeds(ctx context.Context) {
+	now := time.Now()
+	for _, f := range fs.threatFeeds {
+		var enabled bool
+		var lastAttempt sql.NullTime
+		if err := fs.db.DB.QueryRowContext(ctx,
+			"SELECT enabled, last_attempt FROM threat_feeds WHERE name = ?", f.cfg.Name).
+			Scan(&enabled, &lastAttempt); err != nil {
+			log.Printf("Failed to query threat feed %s: %v", f.cfg.Name, err)
+			continue
+		}
+		if !enabled || (lastAttempt.Valid && now.Sub(lastAttempt.Time) < f.cfg.Interval.Std()) {
+			continue
+		}
+		if err := fs.updateThreatFeed(ctx, f); err != nil {
+			log.Printf("Failed to update threat feed %s: %v", f.cfg.Name, err)
+		}
+	}
+}
+
+// UpdateThreatFeed 立即更新情报源，情报源未配置时返回 sql.ErrNoRows
+func (fs *FingerprintService) UpdateThreatFeed(ctx context.Context, name string) (*models.ThreatFeedStatus, error) {
+	f, ok := fs.threatFeedByName(name)
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	updateErr := fs.updateThreatFeed(ctx, f)
+	status, err := fs.threatFeedStatus(ctx, f, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return status, updateErr
+}
+
+// updateThreatFeed 下载情报源并整体替换其记录，失败时保留上次导入的记录
+func (fs *FingerprintService) updateThreatFeed(ctx context.Context, f threatFeed) error {
+	fs.threatMu.Lock()
+	defer fs.threatMu.Unlock()
+
+	started := time.Now()
+	entries, err := f.feed.Fetch(ctx)
+	if err != nil {
+		if _, dbErr := fs.db.DB.ExecContext(ctx,
+			"UPDATE threat_feeds SET last_attempt = ?, last_error = ? WHERE name = ?",
+			started, err.Error(), f.cfg.Name); dbErr != nil {
+			log.Printf("Failed to record threat feed error: %v", dbErr)
+		}
+		return err
+	}
+
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, "DELETE FROM threat_intel WHERE feed = ?", f.cfg.Name); err != nil {
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx,
+		"INSERT OR REPLACE INTO threat_intel (feed, prefix, reference, confidence) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, e := range entries {
+		if _, err := stmt.ExecContext(ctx, f.cfg.Name, e.Prefix.String(), e.Reference, e.Confidence); err != nil {
+			return fmt.Errorf("failed to save threat intel entry: %w", err)
+		}
+	}
+	var count int
+	if err := tx.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM threat_intel WHERE feed = ?", f.cfg.Name).Scan(&count); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE threat_feeds SET entries = ?, last_attempt = ?, last_success = ?, last_error = '' WHERE name = ?",
+		count, started, time.Now(), f.cfg.Name); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("Imported %d entries from threat feed %s", count, f.cfg.Name)
+	return fs.LoadThreatIntel(ctx)
+}
+
+// ThreatFeeds 返回已配置情报源的状态与过期情况
+func (fs *FingerprintService) ThreatFeeds(ctx context.Context) ([]models.ThreatFeedStatus, error) {
+	now := time.Now()
+	feeds := make([]models.ThreatFeedStatus, 0, len(fs.threatFeeds))
+	for _, f := range fs.threatFeeds {
+		status, err := fs.threatFeedStatus(ctx, f, now)
+		if err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, *status)
+	}
+	return feeds, nil
+}
+
+// threatFeedStatus 读取情报源的状态
+func (fs *FingerprintService) threatFeedStatus(ctx context.Context, f threatFeed, now time.Time) (*models.ThreatFeedStatus, error) {
+	status := &models.ThreatFeedStatus{Name: f.cfg.Name, Type: f.feed.Type(), AgeSeconds: -1}
+	var lastAttempt, lastSuccess sql.NullTime
+	if err := fs.db.DB.QueryRowContext(ctx,
+		"SELECT enabled, entries, last_attempt, last_success, last_error FROM threat_feeds WHERE name = ?", f.cfg.Name).
+		Scan(&status.Enabled, &status.Entries, &lastAttempt, &lastSuccess, &status.LastError); err != nil {
+		return nil, err
+	}
+	if lastAttempt.Valid {
+		status.LastAttempt = &lastAttempt.Time
+	}
+	if lastSuccess.Valid {
+		status.LastSuccess = &lastSuccess.Time
+		status.AgeSeconds = int64(now.Sub(lastSuccess.Time).Seconds())
+	}
+	status.Stale = status.Enabled &&
+		(!lastSuccess.Valid || now.Sub(lastSuccess.Time) > fs.threatStaleAfter)
+	return status, nil
+}
+
+// SetThreatFeedEnabled 启用或停用情报源并写入审计记录，停用后其记录立即不再参与评分；
+// 情报源未配置时返回 sql.ErrNoRows
+func (fs *FingerprintService) SetThreatFeedEnabled(ctx context.Context, name string, enabled bool, actor string) (*models.ThreatFeedStatus, error) {
+	f, ok := fs.threatFeedByName(name)
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	before, err := fs.threatFeedStatus(ctx, f, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE threat_feeds SET enabled = ? WHERE name = ?", enabled, name); err != nil {
+		return nil, err
+	}
+	if err := recordAudit(ctx, tx, actor, "set_threat_feed", name,
+		map[string]bool{"enabled": before.Enabled}, map[string]bool{"enabled": enabled}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	if err := fs.LoadThreatIntel(ctx); err != nil {
+		return nil, err
+	}
+	return fs.threatFeedStatus(ctx, f, time.Now())
+}
+
+// threatIntelHits 返回已启用的情报源中覆盖该IP的记录
+func (fs *FingerprintService) threatIntelHits(addr netip.Addr) []models.ThreatIntelHit {
+	hits := []models.ThreatIntelHit{}
+	for _, hit := range fs.threatIndex.Load().Lookup(addr) {
+		hits = append(hits, models.ThreatIntelHit{
+			Feed:       hit.Feed,
+			Prefix:     hit.Prefix.String(),
+			Reference:  hit.Reference,
+			Confidence: hit.Confidence,
+		})
+	}
+	return hits
+}
+
+// checkThreatIntel 来源IP被情报源列出时给出信号，原因中记录每条命中的情报源、前缀和记录标识；
+// 权重取各命中按可信度折算后的最大值；功能停用时不检查
+func (fs *FingerprintService) checkThreatIntel(fp *models.Fingerprint) []signal {
+	if !fs.featureEnabled(FeatureThreatIntel) {
+		return nil
+	}
+	addr, err := netip.ParseAddr(fp.IPAddress)
+	if err != nil || addr.IsLoopback() || addr.IsPrivate() {
+		return nil
+	}
+	hits := fs.threatIntelHits(addr)
+	if len(hits) == 0 {
+		return nil
+	}
+
+	weight := 0.0
+	sources := make([]string, 0, len(hits))
+	for _, hit := range hits {
+		f, _ := fs.threatFeedByName(hit.Feed)
+		if w := f.cfg.Weight * float64(hit.Confidence) / 100; w > weight {
+			weight = w
+		}
+		source := hit.Feed + " " + hit.Prefix
+		if hit.Reference != "" {
+			source += " (" + hit.Reference + ")"
+		}
+		sources = append(sources, source)
+	}
+	return []signal{{
+		Code:   models.ReasonThreatIntel,
+		Weight: weight,
+		Reason: "IP listed by threat intel feeds: " + strings.Join(sources, "; "),
+	}}
+}
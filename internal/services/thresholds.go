@@ -0,0 +1,166 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// thresholdsSetting settings 表中全局评分阈值的键
+const thresholdsSetting = "thresholds"
+
+// DefaultThresholds 未通过管理API修改时的评分阈值
+var DefaultThresholds = models.Thresholds{
+	BotScore:   0.7,
+	RiskHigh:   0.7,
+	RiskMedium: 0.4,
+	EventBotScore: map[string]float64{
+		models.EventLogin:    0.5,
+		models.EventSignup:   0.5,
+		models.EventCheckout: 0.6,
+	},
+}
+
+// LoadThresholds 加载持久化的评分阈值，未修改过时使用默认值
+func (fs *FingerprintService) LoadThresholds(ctx context.Context) error {
+	var value string
+	err := fs.db.DB.QueryRowContext(ctx,
+		"SELECT value FROM settings WHERE key = ?", thresholdsSetting).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// 引入按事件类型的阈值之前保存的设置沿用默认的事件阈值
+	t := DefaultThresholds
+	t.EventBotScore = nil
+	if err := json.Unmarshal([]byte(value), &t); err != nil {
+		return fmt.Errorf("invalid stored thresholds: %w", err)
+	}
+	if t.EventBotScore == nil {
+		t.EventBotScore = DefaultThresholds.EventBotScore
+	}
+	if errs := validateThresholds(t); len(errs) > 0 {
+		return fmt.Errorf("invalid stored thresholds: %s %s", errs[0].Field, errs[0].Constraint)
+	}
+
+	fs.thresholdMu.Lock()
+	fs.thresholds = t
+	fs.thresholdMu.Unlock()
+	return nil
+}
+
+// Thresholds 返回当前的评分阈值
+func (fs *FingerprintService) Thresholds() models.Thresholds {
+	fs.thresholdMu.RLock()
+	defer fs.thresholdMu.RUnlock()
+	return fs.thresholds
+}
+
+// validateThresholds 校验评分阈值：均在 (0, 1] 内且 MEDIUM 边界低于 HIGH 边界
+func validateThresholds(t models.Thresholds) []models.FieldError {
+	var errs []models.FieldError
+	for _, f := range []struct {
+		field string
+		value float64
+	}{
+		{"bot_score", t.BotScore},
+		{"risk_high", t.RiskHigh},
+		{"risk_medium", t.RiskMedium},
+	} {
+		if f.value <= 0 || f.value > 1 {
+			errs = append(errs, models.FieldError{Field: f.field, Constraint: "range (0, 1]", Got: f.value})
+		}
+	}
+	if len(errs) == 0 && t.RiskMedium >= t.RiskHigh {
+		errs = append(errs, models.FieldError{Field: "risk_medium", Constraint: "less than risk_high", Got: t.RiskMedium})
+	}
+	return append(errs, validateEventThresholds(t.EventBotScore)...)
+}
+
+// UpdateThresholds 校验并持久化评分阈值，立即对之后的提交生效，并写入审计记录
+// 取值不合法时返回字段级错误
+func (fs *FingerprintService) UpdateThresholds(ctx context.Context, t models.Thresholds, actor string) ([]models.FieldError, error) {
+	if errs := validateThresholds(t); len(errs) > 0 {
+		return errs, nil
+	}
+
+	fs.thresholdMu.Lock()
+	defer fs.thresholdMu.Unlock()
+	before := fs.thresholds
+
+	value, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT OR REPLACE INTO settings (key, value, updated_at) VALUES (?, ?, ?)",
+		thresholdsSetting, string(value), time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to save thresholds: %w", err)
+	}
+	if err := recordAudit(ctx, tx, actor, "update_thresholds", thresholdsSetting, before, t); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	fs.thresholds = t
+	return nil, nil
+}
+
+// recordAudit 在事务中写入管理操作的审计记录，同时输出到日志
+func recordAudit(ctx context.Context, tx *sql.Tx, actor, action, target string, before, after interface{}) error {
+	beforeJSON, err := json.Marshal(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := json.Marshal(after)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO audit_log (actor, action, target, before, after, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		actor, action, target, string(beforeJSON), string(afterJSON), time.Now()); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	log.Printf("AUDIT %s by %s on %s: %s -> %s", action, actor, target, beforeJSON, afterJSON)
+	return nil
+}
+
+// GetAuditLog 按时间倒序返回管理操作的审计记录
+func (fs *FingerprintService) GetAuditLog(ctx context.Context, limit int) ([]models.AuditEntry, error) {
+	rows, err := fs.db.Read.QueryContext(ctx, `
+		SELECT id, actor, action, target, before, after, created_at
+		FROM audit_log ORDER BY id DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var e models.AuditEntry
+		var before, after string
+		if err := rows.Scan(&e.ID, &e.Actor, &e.Action, &e.Target, &before, &after, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.Before, e.After = json.RawMessage(before), json.RawMessage(after)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
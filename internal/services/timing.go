@@ -0,0 +1,75 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// timingLayout 采集时间校验结果中的时间格式，精确到毫秒
+const timingLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// ErrInvalidTiming 采集时间不合理，开启 detection.timing.reject 时拒绝提交
+var ErrInvalidTiming = errors.New("invalid collection timing")
+
+// collectionTimingProblem 校验提交中的采集时间，返回不合理的原因，合理或未启用时返回空字符串
+// 采集端时钟可能与服务端不一致，耗时只用采集端的两个时间计算；完成时间与接收时间的偏差超过 max_skew 时判定，
+// 带有执行证明种子时，种子中签名的签发时间须落在按接收时间推算的采集过程内，预先录制的提交无法满足
+func (fs *FingerprintService) collectionTimingProblem(req *models.FingerprintRequest, receivedAt time.Time) string {
+	cfg := fs.timing
+	if cfg.MaxDuration <= 0 || req == nil {
+		return ""
+	}
+	t := req.Timing
+	if t == nil || t.StartedAt <= 0 || t.FinishedAt <= 0 {
+		if cfg.Required {
+			return "missing"
+		}
+		return ""
+	}
+
+	elapsed := t.FinishedAt - t.StartedAt
+	switch {
+	case elapsed < 0:
+		return "finished before it started"
+	case elapsed < cfg.MinDuration.Std().Milliseconds():
+		return fmt.Sprintf("took %dms, below the minimum of %s", elapsed, cfg.MinDuration.Std())
+	case elapsed > cfg.MaxDuration.Std().Milliseconds():
+		return fmt.Sprintf("took %dms, above the maximum of %s", elapsed, cfg.MaxDuration.Std())
+	}
+
+	skew := receivedAt.Sub(time.UnixMilli(t.FinishedAt))
+	if skew > cfg.MaxSkew.Std() || skew < -cfg.MaxSkew.Std() {
+		return fmt.Sprintf("finished %s away from the server receive time, above the maximum skew of %s",
+			skew.Round(time.Second), cfg.MaxSkew.Std())
+	}
+
+	if req.Proof != nil && req.Proof.Seed != "" {
+		// 签名无效或已过期的种子由执行证明校验处理
+		if issuedAt, err := fs.verifyProofSeed(req.Proof.Seed, receivedAt); err == nil {
+			started := receivedAt.Add(-time.Duration(elapsed) * time.Millisecond)
+			if issuedAt.Before(started.Add(-cfg.Latency.Std())) || issuedAt.After(receivedAt) {
+				return fmt.Sprintf("proof seed issued at %s outside the collection window %s to %s",
+					issuedAt.UTC().Format(timingLayout), started.UTC().Format(timingLayout), receivedAt.UTC().Format(timingLayout))
+			}
+		}
+	}
+	return ""
+}
+
+// checkCollectionTiming 采集时间不合理时给出信号；只校验本次提交，重新分析和规则重放（没有接收时间）不校验
+func (fs *FingerprintService) checkCollectionTiming(fp *models.Fingerprint, req *models.FingerprintRequest) []signal {
+	if fp.ReceivedAt.IsZero() {
+		return nil
+	}
+	problem := fs.collectionTimingProblem(req, fp.ReceivedAt)
+	if problem == "" {
+		return nil
+	}
+	return []signal{{
+		Code:   models.ReasonCollectionTimingInvalid,
+		Weight: fs.timing.Weight,
+		Reason: "Collection timing " + problem + ": the payload may have been recorded and replayed",
+	}}
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTokenRevoked 访客令牌已被吊销
+var ErrTokenRevoked = errors.New("token revoked")
+
+// randomHex 返回 n 字节随机数的十六进制
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// LoadTokenKeys 加载访客令牌的签名密钥，没有密钥时生成第一个
+func (fs *FingerprintService) LoadTokenKeys(ctx context.Context) error {
+	rows, err := fs.db.DB.QueryContext(ctx,
+		"SELECT id, secret, created_at, retired_at FROM token_keys ORDER BY created_at")
+	if err != nil {
+		return err
+	}
+	var keys []models.TokenKey
+	for rows.Next() {
+		var k models.TokenKey
+		var retired sql.NullTime
+		if err := rows.Scan(&k.ID, &k.Secret, &k.CreatedAt, &retired); err != nil {
+			rows.Close()
+			return err
+		}
+		if retired.Valid {
+			k.RetiredAt = &retired.Time
+		}
+		keys = append(keys, k)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	fs.tokenMu.Lock()
+	fs.tokenKeys = keys
+	fs.tokenMu.Unlock()
+	if len(keys) == 0 || keys[len(keys)-1].RetiredAt != nil {
+		_, err := fs.RotateTokenKey(ctx, "system")
+		return err
+	}
+	return nil
+}
+
+// RotateTokenKey 生成新的签发密钥并停用当前密钥，写入审计记录
+// 停用的密钥在令牌有效期内仍可用于校验，之后删除
+func (fs *FingerprintService) RotateTokenKey(ctx context.Context, actor string) (*models.TokenKey, error) {
+	id, err := randomHex(8)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	key := models.TokenKey{ID: id, Secret: secret, CreatedAt: now}
+
+	fs.tokenMu.Lock()
+	defer fs.tokenMu.Unlock()
+
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE token_keys SET retired_at = ? WHERE retired_at IS NULL", now); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM token_keys WHERE retired_at < ?", now.Add(-fs.tokens.TTL.Std())); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO token_keys (id, secret, created_at) VALUES (?, ?, ?)", key.ID, key.Secret, key.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to save token key: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM token_revocations WHERE expires_at < ?", now); err != nil {
+		return nil, err
+	}
+	// 审计记录不包含密钥
+	if err := recordAudit(ctx, tx, actor, "rotate_token_key", key.ID, nil, map[string]string{"id": key.ID}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	keys := []models.TokenKey{}
+	for _, k := range fs.tokenKeys {
+		if k.RetiredAt == nil {
+			k.RetiredAt = &now
+		}
+		if k.RetiredAt.After(now.Add(-fs.tokens.TTL.Std())) {
+			keys = append(keys, k)
+		}
+	}
+	fs.tokenKeys = append(keys, key)
+	return &key, nil
+}
+
+// TokenVerificationKeys 返回仍可用于校验的签名密钥，供接入方的中间件在本地校验令牌
+func (fs *FingerprintService) TokenVerificationKeys() []models.TokenKey {
+	fs.tokenMu.RLock()
+	defer fs.tokenMu.RUnlock()
+	cutoff := time.Now().Add(-fs.tokens.TTL.Std())
+	keys := []models.TokenKey{}
+	for _, k := range fs.tokenKeys {
+		if k.RetiredAt == nil || k.RetiredAt.After(cutoff) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// tokenSecret 按 kid 返回可用于校验的密钥
+func (fs *FingerprintService) tokenSecret(kid string) ([]byte, bool) {
+	for _, k := range fs.TokenVerificationKeys() {
+		if k.ID == kid {
+			secret, err := hex.DecodeString(k.Secret)
+			return secret, err == nil
+		}
+	}
+	return nil, false
+}
+
+// activeTokenKey 返回当前签发密钥的ID和密钥
+func (fs *FingerprintService) activeTokenKey() (string, []byte, error) {
+	fs.tokenMu.RLock()
+	var active *models.TokenKey
+	if n := len(fs.tokenKeys); n > 0 && fs.tokenKeys[n-1].RetiredAt == nil {
+		k := fs.tokenKeys[n-1]
+		active = &k
+	}
+	fs.tokenMu.RUnlock()
+	if active == nil {
+		return "", nil, errors.New("no active token key")
+	}
+	secret, err := hex.DecodeString(active.Secret)
+	if err != nil {
+		return "", nil, err
+	}
+	return active.ID, secret, nil
+}
+
+// issueVisitorToken 用当前签发密钥签发绑定指纹哈希和IP的访客令牌，未启用时返回空
+func (fs *FingerprintService) issueVisitorToken(fingerprintHash string, meta models.RequestMeta) (string, error) {
+	if fs.tokens.TTL <= 0 {
+		return "", nil
+	}
+	kid, secret, err := fs.activeTokenKey()
+	if err != nil {
+		return "", err
+	}
+	jti, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	return utils.SignVisitorToken(kid, secret, utils.VisitorClaims{
+		Subject:   fingerprintHash,
+		IP:        meta.IPAddress,
+		SiteID:    meta.SiteID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(fs.tokens.TTL.Std()).Unix(),
+		ID:        jti,
+	})
+}
+
+// VerifyVisitorToken 校验访客令牌的签名、有效期、绑定的IP和吊销记录，ip 为空时不校验IP
+func (fs *FingerprintService) VerifyVisitorToken(ctx context.Context, token, ip string) (*utils.VisitorClaims, error) {
+	claims, err := utils.ParseVisitorToken(token, fs.tokenSecret, ip, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	var revoked int
+	if err := fs.db.DB.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM token_revocations
+		WHERE (kind = 'jti' AND key = ?) OR (kind = 'fingerprint' AND key = ? AND revoked_at >= ?)`,
+		claims.ID, claims.Subject, time.Unix(claims.IssuedAt, 0)).Scan(&revoked); err != nil {
+		return nil, err
+	}
+	if revoked > 0 {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
+
+// RevokeVisitorTokens 按令牌ID吊销单个令牌，或吊销指纹在此之前签发的所有令牌，并写入审计记录
+func (fs *FingerprintService) RevokeVisitorTokens(ctx context.Context, r *models.TokenRevocation, actor string) error {
+	kind, key := "jti", r.JTI
+	if key == "" {
+		kind, key = "fingerprint", r.FingerprintHash
+	}
+	now := time.Now()
+
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT OR REPLACE INTO token_revocations (kind, key, revoked_at, expires_at) VALUES (?, ?, ?, ?)`,
+		kind, key, now, now.Add(fs.tokens.TTL.Std())); err != nil {
+		return fmt.Errorf("failed to save token revocation: %w", err)
+	}
+	if err := recordAudit(ctx, tx, actor, "revoke_visitor_tokens", kind+":"+key, nil, r); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// maxTypingProfileWeight 更新账号节奏档案时新样本的最小权重为 1/maxTypingProfileWeight，使档案能缓慢跟随习惯的变化
+const maxTypingProfileWeight = 20
+
+// validateTyping 校验打字节奏特征，只接受登录、注册事件
+func validateTyping(eventType string, typing *models.TypingFeatures) []models.FieldError {
+	if typing == nil {
+		return nil
+	}
+	if eventType != models.EventLogin && eventType != models.EventSignup {
+		return []models.FieldError{{Field: "typing", Constraint: "only for login, signup events", Got: eventType}}
+	}
+	var errs []models.FieldError
+	if typing.Keystrokes < 0 {
+		errs = append(errs, models.FieldError{Field: "typing.keystrokes", Constraint: "non-negative", Got: typing.Keystrokes})
+	}
+	if typing.MeanInterval < 0 {
+		errs = append(errs, models.FieldError{Field: "typing.mean_interval", Constraint: "non-negative", Got: typing.MeanInterval})
+	}
+	if typing.IntervalVariance < 0 {
+		errs = append(errs, models.FieldError{Field: "typing.interval_variance", Constraint: "non-negative", Got: typing.IntervalVariance})
+	}
+	if typing.BackspaceRate < 0 || typing.BackspaceRate > 1 {
+		errs = append(errs, models.FieldError{Field: "typing.backspace_rate", Constraint: "range [0, 1]", Got: typing.BackspaceRate})
+	}
+	return errs
+}
+
+// typingVariation 按键间隔的变异系数（标准差/均值）
+func typingVariation(typing *models.TypingFeatures) float64 {
+	if typing.MeanInterval <= 0 {
+		return 0
+	}
+	return math.Sqrt(typing.IntervalVariance) / typing.MeanInterval
+}
+
+// evaluateTyping 计算事件的打字节奏信号：节奏过快或过于均匀时给出 robotic_typing；
+// 带账号的事件与账号的节奏档案比较，平均间隔差异过大时给出 typing_inconsistent 并记入账号接管信号。
+// 成功且不是机械节奏的事件更新账号档案
+func (fs *FingerprintService) evaluateTyping(ctx context.Context, event *models.Event, typing *models.TypingFeatures) ([]models.AccountSignal, error) {
+	cfg := fs.typing
+	if cfg.MinKeystrokes <= 0 || typing.Keystrokes < cfg.MinKeystrokes {
+		return nil, nil
+	}
+	var signals []models.AccountSignal
+	variation := typingVariation(typing)
+	minInterval := float64(cfg.MinInterval.Std()) / float64(time.Millisecond)
+	robotic := typing.MeanInterval < minInterval || variation < cfg.MinVariation
+	if robotic {
+		signals = append(signals, models.AccountSignal{
+			Code: models.ReasonRoboticTyping,
+			Detail: fmt.Sprintf("%d keystrokes with mean interval %.0fms and variation %.2f",
+				typing.Keystrokes, typing.MeanInterval, variation),
+			FingerprintHash: event.FingerprintHash,
+			CreatedAt:       event.CreatedAt,
+		})
+	}
+	if event.AccountID == "" || cfg.ProfileSamples <= 0 {
+		return signals, nil
+	}
+
+	var samples int
+	var meanInterval, profileVariation, backspaceRate float64
+	err := fs.db.DB.QueryRowContext(ctx, `
+		SELECT samples, mean_interval, variation, backspace_rate FROM typing_profiles WHERE site_id = ? AND account_id = ?`,
+		event.SiteID, event.AccountID).Scan(&samples, &meanInterval, &profileVariation, &backspaceRate)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if samples >= cfg.ProfileSamples && meanInterval > 0 && typing.MeanInterval > 0 {
+		ratio := max(meanInterval, typing.MeanInterval) / min(meanInterval, typing.MeanInterval)
+		if ratio > cfg.MaxIntervalRatio {
+			s := models.AccountSignal{
+				Code: models.ReasonTypingInconsistent,
+				Detail: fmt.Sprintf("Mean key interval %.0fms differs from the account's usual %.0fms (backspace rate %.2f, usually %.2f)",
+					typing.MeanInterval, meanInterval, typing.BackspaceRate, backspaceRate),
+				FingerprintHash: event.FingerprintHash,
+				CreatedAt:       event.CreatedAt,
+			}
+			if _, err := fs.db.DB.ExecContext(ctx, `
+				INSERT INTO account_signals (site_id, account_id, fingerprint_hash, code, detail, created_at)
+				VALUES (?, ?, ?, ?, ?, ?)`,
+				event.SiteID, event.AccountID, s.FingerprintHash, s.Code, s.Detail, s.CreatedAt); err != nil {
+				return nil, fmt.Errorf("failed to save account signal: %w", err)
+			}
+			signals = append(signals, s)
+		}
+	}
+
+	if robotic || event.Outcome != models.OutcomeSuccess {
+		return signals, nil
+	}
+	// 按样本数加权的滑动平均，样本数达到上限后新样本的权重固定
+	weight := 1 / float64(min(samples+1, maxTypingProfileWeight))
+	if _, err := fs.db.DB.ExecContext(ctx, `
+		INSERT INTO typing_profiles (site_id, account_id, samples, mean_interval, variation, backspace_rate, updated_at)
+		VALUES (?, ?, 1, ?, ?, ?, ?)
+		ON CONFLICT (site_id, account_id) DO UPDATE SET
+			samples = samples + 1,
+			mean_interval = mean_interval + (excluded.mean_interval - mean_interval) * ?,
+			variation = variation + (excluded.variation - variation) * ?,
+			backspace_rate = backspace_rate + (excluded.backspace_rate - backspace_rate) * ?,
+			updated_at = excluded.updated_at`,
+		event.SiteID, event.AccountID, typing.MeanInterval, variation, typing.BackspaceRate, event.CreatedAt,
+		weight, weight, weight); err != nil {
+		return nil, fmt.Errorf("failed to update typing profile: %w", err)
+	}
+	return signals, nil
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// ErrUARegexesNotConfigured 未配置 detection.ua_parser.regexes_path
+var ErrUARegexesNotConfigured = errors.New("detection.ua_parser.regexes_path is not configured")
+
+// LoadUARegexes 启动时加载 uap-core 规则，未配置路径时只使用内置的家族正则，
+// 文件不可用时使用内置的精简副本
+func (fs *FingerprintService) LoadUARegexes() {
+	if fs.uaRegexesPath == "" {
+		return
+	}
+	fs.uaRegexesMu.Lock()
+	defer fs.uaRegexesMu.Unlock()
+
+	r, err := utils.LoadUARegexes(fs.uaRegexesPath)
+	if err != nil {
+		log.Printf("Failed to load UA regexes from %s, using embedded copy: %v", fs.uaRegexesPath, err)
+		r = utils.EmbeddedUARegexes()
+	}
+	fs.uaRegexes = activateUARegexes(r)
+	if err != nil {
+		fs.uaRegexes.Error = err.Error()
+	}
+	log.Printf("Loaded UA regexes from %s: %d browser parsers, %d OS parsers, %d skipped",
+		r.Source, r.BrowserParsers(), r.OSParsers(), r.Skipped)
+}
+
+// RefreshUARegexes 重新读取 regexes_path；读取或解析失败时保留当前规则并返回错误
+func (fs *FingerprintService) RefreshUARegexes(ctx context.Context, actor string) (models.UARegexesStatus, error) {
+	if fs.uaRegexesPath == "" {
+		return fs.UARegexes(), ErrUARegexesNotConfigured
+	}
+	fs.uaRegexesMu.Lock()
+	defer fs.uaRegexesMu.Unlock()
+
+	r, err := utils.LoadUARegexes(fs.uaRegexesPath)
+	if err != nil {
+		fs.uaRegexes.Error = err.Error()
+		return fs.uaRegexes, err
+	}
+
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fs.uaRegexes, err
+	}
+	defer tx.Rollback()
+	before := fs.uaRegexes
+	after := uaRegexesStatus(r)
+	if err := recordAudit(ctx, tx, actor, "refresh_ua_regexes", r.Source, before, after); err != nil {
+		return fs.uaRegexes, err
+	}
+	if err := tx.Commit(); err != nil {
+		return fs.uaRegexes, err
+	}
+
+	fs.uaRegexes = activateUARegexes(r)
+	return fs.uaRegexes, nil
+}
+
+// UARegexes 返回当前使用的User Agent解析规则
+func (fs *FingerprintService) UARegexes() models.UARegexesStatus {
+	fs.uaRegexesMu.Lock()
+	defer fs.uaRegexesMu.Unlock()
+	return fs.uaRegexes
+}
+
+// activateUARegexes 让 ParseUserAgent 使用规则并返回其状态
+func activateUARegexes(r *utils.UARegexes) models.UARegexesStatus {
+	utils.SetUARegexes(r)
+	return uaRegexesStatus(r)
+}
+
+// uaRegexesStatus 规则的状态
+func uaRegexesStatus(r *utils.UARegexes) models.UARegexesStatus {
+	now := time.Now()
+	return models.UARegexesStatus{
+		Source:         r.Source,
+		BrowserParsers: r.BrowserParsers(),
+		OSParsers:      r.OSParsers(),
+		Skipped:        r.Skipped,
+		LoadedAt:       &now,
+	}
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"browser-detection/internal/alerting"
+	"browser-detection/internal/models"
+	"browser-detection/internal/utils"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// ErrWebhookExists 同名的webhook已存在
+var ErrWebhookExists = errors.New("webhook already exists")
+
+// webhookNamePattern 告警webhook名称的格式
+var webhookNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// maxWebhookKinds 一个webhook最多订阅的告警类型数
+const maxWebhookKinds = 50
+
+// LoadWebhooks 从数据库加载告警webhook，替换当前发送的列表
+func (fs *FingerprintService) LoadWebhooks(ctx context.Context) error {
+	hooks, err := fs.Webhooks(ctx)
+	if err != nil {
+		return err
+	}
+	list := make([]alerting.Webhook, len(hooks))
+	for i, h := range hooks {
+		list[i] = alerting.Webhook{Name: h.Name, URL: h.URL, Kinds: h.Kinds, Enabled: h.Enabled}
+	}
+	fs.webhooks.Set(list)
+	return nil
+}
+
+// Webhooks 按名称返回通过管理API配置的告警webhook
+func (fs *FingerprintService) Webhooks(ctx context.Context) ([]models.Webhook, error) {
+	rows, err := fs.db.DB.QueryContext(ctx,
+		"SELECT name, url, kinds, enabled, created_at, updated_at FROM webhooks ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := []models.Webhook{}
+	for rows.Next() {
+		var h models.Webhook
+		var kinds string
+		if err := rows.Scan(&h.Name, &h.URL, &kinds, &h.Enabled, &h.CreatedAt, &h.UpdatedAt); err != nil {
+			return nil, err
+		}
+		h.Kinds = utils.JSONToStringSlice(kinds)
+		hooks = append(hooks, h)
+	}
+	return hooks, rows.Err()
+}
+
+// Webhook 返回一个告警webhook，不存在时返回 sql.ErrNoRows
+func (fs *FingerprintService) Webhook(ctx context.Context, name string) (*models.Webhook, error) {
+	var h models.Webhook
+	var kinds string
+	if err := fs.db.DB.QueryRowContext(ctx,
+		"SELECT name, url, kinds, enabled, created_at, updated_at FROM webhooks WHERE name = ?", name).
+		Scan(&h.Name, &h.URL, &kinds, &h.Enabled, &h.CreatedAt, &h.UpdatedAt); err != nil {
+		return nil, err
+	}
+	h.Kinds = utils.JSONToStringSlice(kinds)
+	return &h, nil
+}
+
+// validateWebhook 校验告警webhook：名称符合格式，地址为 http(s) URL，告警类型不为空
+func validateWebhook(h *models.Webhook) []models.FieldError {
+	var errs []models.FieldError
+	if !webhookNamePattern.MatchString(h.Name) {
+		errs = append(errs, models.FieldError{Field: "name", Constraint: "match " + webhookNamePattern.String(), Got: h.Name})
+	}
+	if u, err := url.Parse(h.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, models.FieldError{Field: "url", Constraint: "http or https URL", Got: h.URL})
+	}
+	if len(h.Kinds) > maxWebhookKinds {
+		errs = append(errs, models.FieldError{Field: "kinds", Constraint: "max items", Limit: maxWebhookKinds, Got: len(h.Kinds)})
+	}
+	for i, kind := range h.Kinds {
+		if kind == "" {
+			errs = append(errs, models.FieldError{Field: "kinds[" + strconv.Itoa(i) + "]", Constraint: "non-empty"})
+		}
+	}
+	return errs
+}
+
+// CreateWebhook 校验并添加告警webhook，立即对之后的告警生效，并写入审计记录；
+// 取值不合法时返回字段级错误，同名的webhook已存在时返回 ErrWebhookExists
+func (fs *FingerprintService) CreateWebhook(ctx context.Context, h *models.Webhook, actor string) ([]models.FieldError, error) {
+	if errs := validateWebhook(h); len(errs) > 0 {
+		return errs, nil
+	}
+	if h.Kinds == nil {
+		h.Kinds = []string{}
+	}
+	h.CreatedAt = time.Now()
+	h.UpdatedAt = h.CreatedAt
+
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO webhooks (name, url, kinds, enabled, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (name) DO NOTHING`,
+		h.Name, h.URL, utils.StringSliceToJSON(h.Kinds), h.Enabled, h.CreatedAt, h.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save webhook: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, ErrWebhookExists
+	}
+	if err := recordAudit(ctx, tx, actor, "create_webhook", h.Name, nil, h); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return nil, fs.LoadWebhooks(ctx)
+}
+
+// UpdateWebhook 校验并替换告警webhook的地址、告警类型和是否启用，立即对之后的告警生效，并写入审计记录；
+// 取值不合法时返回字段级错误，webhook不存在时返回 sql.ErrNoRows
+func (fs *FingerprintService) UpdateWebhook(ctx context.Context, h *models.Webhook, actor string) ([]models.FieldError, error) {
+	if errs := validateWebhook(h); len(errs) > 0 {
+		return errs, nil
+	}
+	if h.Kinds == nil {
+		h.Kinds = []string{}
+	}
+	before, err := fs.Webhook(ctx, h.Name)
+	if err != nil {
+		return nil, err
+	}
+	h.CreatedAt = before.CreatedAt
+	h.UpdatedAt = time.Now()
+
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		"UPDATE webhooks SET url = ?, kinds = ?, enabled = ?, updated_at = ? WHERE name = ?",
+		h.URL, utils.StringSliceToJSON(h.Kinds), h.Enabled, h.UpdatedAt, h.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save webhook: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, sql.ErrNoRows
+	}
+	if err := recordAudit(ctx, tx, actor, "update_webhook", h.Name, before, h); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return nil, fs.LoadWebhooks(ctx)
+}
+
+// DeleteWebhook 删除告警webhook并写入审计记录，不存在时返回 sql.ErrNoRows
+func (fs *FingerprintService) DeleteWebhook(ctx context.Context, name, actor string) error {
+	before, err := fs.Webhook(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	tx, err := fs.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM webhooks WHERE name = ?", name)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	if err := recordAudit(ctx, tx, actor, "delete_webhook", name, before, nil); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return fs.LoadWebhooks(ctx)
+}
+
+// TestWebhook 向告警webhook发送一条 test 类型的告警（不论是否启用和订阅的告警类型），
+// webhook不存在时返回 sql.ErrNoRows
+func (fs *FingerprintService) TestWebhook(ctx context.Context, name string) error {
+	h, err := fs.Webhook(ctx, name)
+	if err != nil {
+		return err
+	}
+	return fs.webhooks.Send(ctx, h.URL, alerting.Alert{
+		Kind:    "test",
+		Message: "Test alert for webhook " + h.Name,
+		Time:    time.Now(),
+	})
+}
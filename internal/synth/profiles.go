@@ -0,0 +1,253 @@
+package synth
+
+import "browser-detection/internal/utils"
+
+// profile 一类浏览器/操作系统/设备组合及其取值范围
+type profile struct {
+	Name   string
+	Weight float64
+	// UserAgent 以 %d 或 %[1]d 作为浏览器主版本号的占位符
+	UserAgent string
+	Majors    []int
+	Platform  string
+	Mobile    bool
+	Screens   [][2]int
+	// PixelRatios 与 Screens 无关，随机组合
+	PixelRatios []float64
+	// TaskbarHeight 可用区域比屏幕少的高度，移动端为0
+	TaskbarHeight int
+	Fonts         []string
+	Plugins       []string
+	Renderers     []string
+	Cores         []int
+	// Memory 为空表示浏览器不暴露 navigator.deviceMemory
+	Memory []float64
+	// Engine 决定Math结果向量与音频指纹
+	Engine string
+}
+
+// chromiumPlugins Chromium系浏览器按规范报告的固定插件列表
+var chromiumPlugins = []string{
+	"PDF Viewer", "Chrome PDF Viewer", "Chromium PDF Viewer", "Microsoft Edge PDF Viewer", "WebKit built-in PDF",
+}
+
+var (
+	windowsFonts = []string{
+		"Arial", "Calibri", "Cambria", "Consolas", "Courier New", "Georgia", "Lucida Console",
+		"Microsoft Sans Serif", "Segoe UI", "Tahoma", "Times New Roman", "Trebuchet MS", "Verdana",
+		"Comic Sans MS", "Impact", "Palatino Linotype", "Franklin Gothic Medium",
+	}
+	macFonts = []string{
+		"Apple Symbols", "Avenir", "Baskerville", "Futura", "Gill Sans", "Helvetica Neue",
+		"Lucida Grande", "Menlo", "Monaco", "Optima", "Arial", "Courier New", "Georgia",
+		"Times New Roman", "Verdana", "Tahoma",
+	}
+	linuxFonts = []string{
+		"DejaVu Sans", "DejaVu Sans Mono", "DejaVu Serif", "Liberation Mono", "Liberation Sans",
+		"Liberation Serif", "Noto Sans", "Noto Serif", "Ubuntu", "Ubuntu Mono",
+	}
+	iosFonts = []string{
+		"Helvetica Neue", "Avenir", "Baskerville", "Futura", "Gill Sans", "Menlo", "Optima",
+		"Arial", "Courier New", "Georgia", "Times New Roman", "Verdana",
+	}
+	androidFonts = []string{
+		"Roboto", "Noto Sans", "Noto Serif", "Droid Sans", "Droid Sans Mono", "Droid Serif",
+		"Cutive Mono", "Coming Soon", "Dancing Script", "Carrois Gothic",
+	}
+)
+
+var (
+	windowsRenderers = []string{
+		"ANGLE (NVIDIA, NVIDIA GeForce RTX 3060 Direct3D11 vs_5_0 ps_5_0, D3D11)",
+		"ANGLE (NVIDIA, NVIDIA GeForce GTX 1650 Direct3D11 vs_5_0 ps_5_0, D3D11)",
+		"ANGLE (Intel, Intel(R) UHD Graphics 620 Direct3D11 vs_5_0 ps_5_0, D3D11)",
+		"ANGLE (Intel, Intel(R) Iris(R) Xe Graphics Direct3D11 vs_5_0 ps_5_0, D3D11)",
+		"ANGLE (AMD, AMD Radeon RX 6600 Direct3D11 vs_5_0 ps_5_0, D3D11)",
+	}
+	macRenderers = []string{
+		"ANGLE (Apple, ANGLE Metal Renderer: Apple M1, Unspecified Version)",
+		"ANGLE (Apple, ANGLE Metal Renderer: Apple M2, Unspecified Version)",
+		"ANGLE (Intel Inc., Intel(R) Iris(TM) Plus Graphics 655, OpenGL 4.1)",
+	}
+	linuxRenderers = []string{
+		"Mesa Intel(R) UHD Graphics 620 (KBL GT2)",
+		"AMD Radeon RX 580 (polaris10, LLVM 15.0.7, DRM 3.49)",
+		"NVIDIA GeForce GTX 1060 6GB/PCIe/SSE2",
+	}
+	androidRenderers = []string{"Adreno (TM) 650", "Adreno (TM) 730", "Mali-G78", "Mali-G710"}
+)
+
+// profiles 按近似的真实流量占比加权
+var profiles = []profile{
+	{
+		Name:          "chrome-windows",
+		Weight:        0.33,
+		UserAgent:     "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%d.0.0.0 Safari/537.36",
+		Majors:        []int{118, 119, 120, 121, 122},
+		Platform:      "Win32",
+		Screens:       [][2]int{{1920, 1080}, {1366, 768}, {1536, 864}, {2560, 1440}, {1440, 900}},
+		PixelRatios:   []float64{1, 1, 1.25, 1.5},
+		TaskbarHeight: 40,
+		Fonts:         windowsFonts,
+		Plugins:       chromiumPlugins,
+		Renderers:     windowsRenderers,
+		Cores:         []int{4, 8, 12, 16},
+		Memory:        []float64{4, 8, 8},
+		Engine:        utils.EngineV8,
+	},
+	{
+		Name:          "edge-windows",
+		Weight:        0.05,
+		UserAgent:     "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%[1]d.0.0.0 Safari/537.36 Edg/%[1]d.0.0.0",
+		Majors:        []int{119, 120, 121},
+		Platform:      "Win32",
+		Screens:       [][2]int{{1920, 1080}, {1366, 768}, {1536, 864}},
+		PixelRatios:   []float64{1, 1.25, 1.5},
+		TaskbarHeight: 40,
+		Fonts:         windowsFonts,
+		Plugins:       chromiumPlugins,
+		Renderers:     windowsRenderers,
+		Cores:         []int{4, 8, 12},
+		Memory:        []float64{8},
+		Engine:        utils.EngineV8,
+	},
+	{
+		Name:          "firefox-windows",
+		Weight:        0.06,
+		UserAgent:     "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:%[1]d.0) Gecko/20100101 Firefox/%[1]d.0",
+		Majors:        []int{119, 120, 121, 122},
+		Platform:      "Win32",
+		Screens:       [][2]int{{1920, 1080}, {1366, 768}, {2560, 1440}},
+		PixelRatios:   []float64{1, 1.25},
+		TaskbarHeight: 40,
+		Fonts:         windowsFonts,
+		Plugins:       chromiumPlugins,
+		Renderers:     windowsRenderers,
+		Cores:         []int{4, 8, 16},
+		Engine:        utils.EngineSpiderMonkey,
+	},
+	{
+		Name:          "chrome-macos",
+		Weight:        0.09,
+		UserAgent:     "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%d.0.0.0 Safari/537.36",
+		Majors:        []int{119, 120, 121, 122},
+		Platform:      "MacIntel",
+		Screens:       [][2]int{{1440, 900}, {1512, 982}, {1728, 1117}, {2560, 1440}},
+		PixelRatios:   []float64{2},
+		TaskbarHeight: 25,
+		Fonts:         macFonts,
+		Plugins:       chromiumPlugins,
+		Renderers:     macRenderers,
+		Cores:         []int{8, 10, 12},
+		Memory:        []float64{8},
+		Engine:        utils.EngineV8,
+	},
+	{
+		Name:          "safari-macos",
+		Weight:        0.07,
+		UserAgent:     "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/%d.1 Safari/605.1.15",
+		Majors:        []int{16, 17},
+		Platform:      "MacIntel",
+		Screens:       [][2]int{{1440, 900}, {1512, 982}, {1728, 1117}},
+		PixelRatios:   []float64{2},
+		TaskbarHeight: 25,
+		Fonts:         macFonts,
+		Plugins:       chromiumPlugins,
+		Renderers:     []string{"Apple GPU"},
+		Cores:         []int{8},
+		Engine:        utils.EngineJSC,
+	},
+	{
+		Name:          "firefox-linux",
+		Weight:        0.02,
+		UserAgent:     "Mozilla/5.0 (X11; Linux x86_64; rv:%[1]d.0) Gecko/20100101 Firefox/%[1]d.0",
+		Majors:        []int{120, 121, 122},
+		Platform:      "Linux x86_64",
+		Screens:       [][2]int{{1920, 1080}, {2560, 1440}},
+		PixelRatios:   []float64{1},
+		TaskbarHeight: 32,
+		Fonts:         linuxFonts,
+		Plugins:       chromiumPlugins,
+		Renderers:     linuxRenderers,
+		Cores:         []int{4, 8, 16},
+		Engine:        utils.EngineSpiderMonkey,
+	},
+	{
+		Name:        "safari-ios",
+		Weight:      0.17,
+		UserAgent:   "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/%d.1 Mobile/15E148 Safari/604.1",
+		Majors:      []int{16, 17},
+		Platform:    "iPhone",
+		Mobile:      true,
+		Screens:     [][2]int{{390, 844}, {393, 852}, {428, 926}, {375, 667}},
+		PixelRatios: []float64{3, 3, 2},
+		Fonts:       iosFonts,
+		Renderers:   []string{"Apple GPU"},
+		Cores:       []int{4, 6},
+		Engine:      utils.EngineJSC,
+	},
+	{
+		Name:        "chrome-android",
+		Weight:      0.21,
+		UserAgent:   "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%d.0.0.0 Mobile Safari/537.36",
+		Majors:      []int{119, 120, 121, 122},
+		Platform:    "Linux armv81",
+		Mobile:      true,
+		Screens:     [][2]int{{412, 915}, {360, 800}, {393, 873}, {384, 854}},
+		PixelRatios: []float64{2.625, 3, 2.75},
+		Fonts:       androidFonts,
+		Renderers:   androidRenderers,
+		Cores:       []int{8},
+		Memory:      []float64{4, 8},
+		Engine:      utils.EngineV8,
+	},
+}
+
+// locale 时区与语言的组合，按占比加权
+type locale struct {
+	Timezone string
+	Language string
+	Weight   float64
+}
+
+var locales = []locale{
+	{"America/New_York", "en-US", 0.2},
+	{"America/Los_Angeles", "en-US", 0.12},
+	{"America/Chicago", "en-US", 0.08},
+	{"Europe/London", "en-GB", 0.1},
+	{"Europe/Berlin", "de-DE", 0.1},
+	{"Europe/Paris", "fr-FR", 0.08},
+	{"Asia/Shanghai", "zh-CN", 0.14},
+	{"Asia/Tokyo", "ja-JP", 0.08},
+	{"America/Sao_Paulo", "pt-BR", 0.06},
+	{"Asia/Kolkata", "en-IN", 0.04},
+}
+
+// mathVectors 各JS引擎的Math边界值结果，同一引擎的设备结果相同
+var mathVectors = map[string][]string{
+	utils.EngineV8: {
+		"-1.4214488238747245", "0.8178819121159085", "1.1752011936438014", "1.4436354751788103",
+		"1.9275814160560204e-50", "0.123456789", "1e+21",
+	},
+	utils.EngineSpiderMonkey: {
+		"-1.4214488238747245", "0.8178819121159085", "1.1752011936438016", "1.4436354751788103",
+		"1.9275814160560206e-50", "0.123456789", "1e+21",
+	},
+	utils.EngineJSC: {
+		"-1.4214488238747243", "0.8178819121159084", "1.1752011936438014", "1.4436354751788105",
+		"1.9275814160560204e-50", "0.123456789", "1e+21",
+	},
+}
+
+// audioValues 各JS引擎的音频压缩器指纹
+var audioValues = map[string]string{
+	utils.EngineV8:           "124.04347527516074",
+	utils.EngineSpiderMonkey: "35.73833402246237",
+	utils.EngineJSC:          "35.10892990825232",
+}
+
+// 爬虫使用的数据中心网段与住宅网段（合成IP的前两段）
+var (
+	datacenterPrefixes  = []string{"34.102", "35.201", "52.14", "104.248", "138.68", "167.99"}
+	residentialPrefixes = []string{"73.12", "86.143", "98.207", "112.96", "180.153", "24.56", "79.201", "190.14"}
+)
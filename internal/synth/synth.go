@@ -0,0 +1,313 @@
+// Package synth 生成逼真的合成浏览器指纹，用于测试与演示
+package synth
+
+import (
+	"browser-detection/internal/models"
+	"browser-detection/internal/services"
+	"browser-detection/internal/utils"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"strings"
+)
+
+// 合成指纹的类别
+const (
+	// KindHuman 配置自洽的真实设备
+	KindHuman = "human"
+	// KindBot 无头浏览器或伪造UA的自动化客户端
+	KindBot = "bot"
+	// KindNoisy 向Canvas/音频注入随机噪点的反指纹浏览器
+	KindNoisy = "noisy"
+)
+
+// 画布尺寸，与前端绘制的指纹画布相近
+const (
+	canvasWidth  = 200
+	canvasHeight = 50
+)
+
+// returningPool 保留用于回访的真实设备数
+const returningPool = 200
+
+// Options 生成参数
+type Options struct {
+	Seed int64
+	// BotRatio 爬虫样本占比
+	BotRatio float64
+	// NoiseRatio 噪点注入样本占比
+	NoiseRatio float64
+	// ReturnRatio 真实设备回访（重复提交同一指纹）的占比
+	ReturnRatio float64
+}
+
+// Sample 一个合成样本
+type Sample struct {
+	Kind    string `json:"kind"`
+	Profile string `json:"profile"`
+	// IP 提交时作为 X-Forwarded-For 使用
+	IP      string                    `json:"ip"`
+	Request models.FingerprintRequest `json:"request"`
+}
+
+// Generator 合成指纹生成器，同一种子生成相同的序列；不是并发安全的
+type Generator struct {
+	opts     Options
+	rng      *rand.Rand
+	returner []Sample
+}
+
+// New 创建生成器
+func New(opts Options) *Generator {
+	return &Generator{opts: opts, rng: rand.New(rand.NewSource(opts.Seed))}
+}
+
+// Next 生成下一个样本
+func (g *Generator) Next() Sample {
+	roll := g.rng.Float64()
+	switch {
+	case roll < g.opts.BotRatio:
+		return g.bot()
+	case roll < g.opts.BotRatio+g.opts.NoiseRatio:
+		return g.noisy()
+	}
+
+	if len(g.returner) > 0 && g.rng.Float64() < g.opts.ReturnRatio {
+		return g.returner[g.rng.Intn(len(g.returner))]
+	}
+	s := g.human()
+	if len(g.returner) < returningPool {
+		g.returner = append(g.returner, s)
+	} else {
+		g.returner[g.rng.Intn(returningPool)] = s
+	}
+	return s
+}
+
+// human 按权重选取设备类别并生成自洽的指纹
+func (g *Generator) human() Sample {
+	p := g.pickProfile()
+	major := p.Majors[g.rng.Intn(len(p.Majors))]
+	ua := fmt.Sprintf(p.UserAgent, major)
+	loc := g.pickLocale()
+	screen := p.Screens[g.rng.Intn(len(p.Screens))]
+	renderer := p.Renderers[g.rng.Intn(len(p.Renderers))]
+
+	req := models.FingerprintRequest{
+		UserAgent:           ua,
+		ScreenResolution:    fmt.Sprintf("%dx%d", screen[0], screen[1]),
+		Timezone:            loc.Timezone,
+		Language:            loc.Language,
+		Platform:            p.Platform,
+		Canvas:              canvasImage(p.Name+renderer, nil),
+		WebGL:               webglInfo(renderer),
+		Audio:               audioValues[p.Engine],
+		Fonts:               g.subset(p.Fonts, 0.7),
+		Plugins:             p.Plugins,
+		TouchSupport:        p.Mobile,
+		CookieEnabled:       true,
+		DoNotTrack:          "unspecified",
+		HardwareConcurrency: p.Cores[g.rng.Intn(len(p.Cores))],
+		Screen: &models.ScreenMetrics{
+			ColorDepth:       24,
+			DevicePixelRatio: p.PixelRatios[g.rng.Intn(len(p.PixelRatios))],
+			AvailWidth:       screen[0],
+			AvailHeight:      screen[1] - p.TaskbarHeight,
+			OuterWidth:       screen[0],
+			OuterHeight:      screen[1] - p.TaskbarHeight,
+		},
+		Math:               mathVectors[p.Engine],
+		Features:           services.ExpectedFeatures(ua),
+		FingerprintVersion: models.CurrentFingerprintVersion,
+	}
+	if req.Plugins == nil {
+		req.Plugins = []string{}
+	}
+	if len(p.Memory) > 0 {
+		req.DeviceMemory = p.Memory[g.rng.Intn(len(p.Memory))]
+	}
+	if p.Mobile {
+		req.Sensors = &models.SensorInfo{Accelerometer: true, Gyroscope: true}
+		req.MediaDevices = &models.MediaDeviceCounts{AudioInput: 1, AudioOutput: 1, VideoInput: 2}
+	} else {
+		req.MediaDevices = &models.MediaDeviceCounts{AudioInput: 1, AudioOutput: 1 + g.rng.Intn(2), VideoInput: g.rng.Intn(2)}
+	}
+
+	return Sample{Kind: KindHuman, Profile: p.Name, IP: g.ip(residentialPrefixes), Request: req}
+}
+
+// noisy 真实设备加上反指纹浏览器的Canvas/音频噪点，每次提交的Canvas都不同
+func (g *Generator) noisy() Sample {
+	s := g.human()
+	s.Kind = KindNoisy
+	seed := g.rng.Int63()
+	s.Request.Canvas = canvasImage(s.Profile+s.Request.WebGL, rand.New(rand.NewSource(seed)))
+	s.Request.CanvasNoiseDetection = &models.NoiseDetection{
+		HasNoise:   true,
+		Type:       "random_noise",
+		Confidence: 0.8 + g.rng.Float64()*0.2,
+	}
+	if g.rng.Intn(2) == 0 {
+		s.Request.Audio = fmt.Sprintf("%s%d", s.Request.Audio[:len(s.Request.Audio)-4], g.rng.Intn(10000))
+		s.Request.AudioNoiseDetection = &models.NoiseDetection{
+			HasNoise:   true,
+			Type:       "audio_anomaly",
+			Confidence: 0.6 + g.rng.Float64()*0.3,
+		}
+	}
+	return s
+}
+
+// bot 生成几类常见自动化客户端之一，均来自数据中心网段
+func (g *Generator) bot() Sample {
+	s := g.human()
+	s.Kind = KindBot
+	s.IP = g.ip(datacenterPrefixes)
+	req := &s.Request
+
+	switch g.rng.Intn(3) {
+	case 0:
+		// 无头Chrome：UA带 HeadlessChrome，没有插件与媒体设备，窗口尺寸为0，软件渲染
+		s.Profile = "headless-chrome"
+		req.UserAgent = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) HeadlessChrome/120.0.0.0 Safari/537.36"
+		req.Platform = "Linux x86_64"
+		req.ScreenResolution = "800x600"
+		req.Screen = &models.ScreenMetrics{ColorDepth: 24, DevicePixelRatio: 1, AvailWidth: 800, AvailHeight: 600}
+		req.Fonts = []string{"DejaVu Sans", "Liberation Sans"}
+		req.Plugins = []string{}
+		req.MediaDevices = &models.MediaDeviceCounts{}
+		req.WebGL = webglInfo("Google SwiftShader")
+		req.Canvas = canvasImage("swiftshader", nil)
+		req.Timezone, req.Language = "UTC", "en-US"
+		req.HardwareConcurrency, req.DeviceMemory = 2, 8
+	case 1:
+		// 伪造的桌面Chrome UA：字体与特性来自另一个平台和旧版本
+		s.Profile = "spoofed-chrome"
+		req.UserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/122.0.0.0 Safari/537.36"
+		req.Platform = "Win32"
+		req.Fonts = g.subset(linuxFonts, 0.8)
+		req.Plugins = chromiumPlugins
+		req.Features = services.ExpectedFeatures("Mozilla/5.0 Chrome/90.0.0.0")
+		req.MediaDevices = &models.MediaDeviceCounts{}
+		req.Sensors, req.TouchSupport = nil, false
+		req.HardwareConcurrency, req.DeviceMemory = 1, 0.5
+		req.Math = mathVectors[utils.EngineSpiderMonkey]
+	default:
+		// 桌面浏览器模拟移动UA：无触摸、无传感器、桌面分辨率
+		s.Profile = "mobile-emulation"
+		req.UserAgent = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1"
+		req.Platform = "iPhone"
+		req.TouchSupport = false
+		req.Sensors = &models.SensorInfo{}
+		req.ScreenResolution = "1920x1080"
+		req.Screen = &models.ScreenMetrics{ColorDepth: 24, DevicePixelRatio: 1, AvailWidth: 1920, AvailHeight: 1040, OuterWidth: 1920, OuterHeight: 1040}
+		req.Fonts = g.subset(windowsFonts, 0.8)
+	}
+	return s
+}
+
+// pickProfile 按权重选取设备类别
+func (g *Generator) pickProfile() profile {
+	total := 0.0
+	for _, p := range profiles {
+		total += p.Weight
+	}
+	roll := g.rng.Float64() * total
+	for _, p := range profiles {
+		if roll -= p.Weight; roll < 0 {
+			return p
+		}
+	}
+	return profiles[len(profiles)-1]
+}
+
+// pickLocale 按权重选取时区与语言
+func (g *Generator) pickLocale() locale {
+	total := 0.0
+	for _, l := range locales {
+		total += l.Weight
+	}
+	roll := g.rng.Float64() * total
+	for _, l := range locales {
+		if roll -= l.Weight; roll < 0 {
+			return l
+		}
+	}
+	return locales[len(locales)-1]
+}
+
+// subset 以概率 keep 保留每个字体，保持原有顺序
+func (g *Generator) subset(fonts []string, keep float64) []string {
+	out := make([]string, 0, len(fonts))
+	for _, f := range fonts {
+		if g.rng.Float64() < keep {
+			out = append(out, f)
+		}
+	}
+	if len(out) == 0 {
+		out = append(out, fonts[0])
+	}
+	return out
+}
+
+// ip 在给定网段中随机生成IP
+func (g *Generator) ip(prefixes []string) string {
+	return fmt.Sprintf("%s.%d.%d", prefixes[g.rng.Intn(len(prefixes))], g.rng.Intn(256), 1+g.rng.Intn(254))
+}
+
+// webglInfo 生成与前端 collectWebGLBasicInfo 结构一致的WebGL信息
+func webglInfo(renderer string) string {
+	vendor := "Google Inc."
+	switch {
+	case strings.HasPrefix(renderer, "Apple"):
+		vendor = "Apple Inc."
+	case strings.HasPrefix(renderer, "Adreno"):
+		vendor = "Qualcomm"
+	case strings.HasPrefix(renderer, "Mali"):
+		vendor = "ARM"
+	}
+	return fmt.Sprintf(`{"version":"WebGL 1.0","vendor":"WebKit","renderer":%q,"vendorUnmasked":%q}`, renderer, vendor)
+}
+
+// canvasImage 按设备键确定性地绘制指纹画布；noise 非空时在少量像素上叠加 ±1 的随机噪点
+func canvasImage(key string, noise *rand.Rand) string {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	rng := rand.New(rand.NewSource(int64(h.Sum64())))
+
+	img := image.NewNRGBA(image.Rect(0, 0, canvasWidth, canvasHeight))
+	bg := color.NRGBA{R: 255, G: 102, B: 0, A: 255}
+	for y := 0; y < canvasHeight; y++ {
+		for x := 0; x < canvasWidth; x++ {
+			if x < 125 && y >= 1 && y < 21 {
+				img.SetNRGBA(x, y, bg)
+			} else {
+				img.SetNRGBA(x, y, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+			}
+		}
+	}
+	// 模拟文字笔画：抗锯齿边缘随GPU与字体渲染而变化
+	for i := 0; i < 40; i++ {
+		x0, y0 := rng.Intn(canvasWidth-10), rng.Intn(canvasHeight-10)
+		c := color.NRGBA{R: uint8(rng.Intn(256)), G: uint8(rng.Intn(256)), B: uint8(rng.Intn(256)), A: 255}
+		for d := 0; d < 8; d++ {
+			img.SetNRGBA(x0+d, y0+d/2, c)
+		}
+	}
+	if noise != nil {
+		for i := 0; i < canvasWidth*canvasHeight/30; i++ {
+			off := noise.Intn(canvasWidth*canvasHeight)*4 + noise.Intn(3)
+			v := int(img.Pix[off]) + 2*noise.Intn(2) - 1
+			img.Pix[off] = uint8(max(0, min(255, v)))
+		}
+	}
+
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
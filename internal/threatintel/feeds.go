@@ -0,0 +1,146 @@
+package threatintel
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// spamhausFeed Spamhaus DROP/EDROP 列表
+type spamhausFeed struct {
+	source
+}
+
+func (f *spamhausFeed) Fetch(ctx context.Context) ([]Entry, error) {
+	data, err := f.get(ctx, f.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return parseSpamhaus(data)
+}
+
+// parseSpamhaus 解析 drop_v4.json（{"cidr": ..., "sblid": ...}，末行为元数据）或 drop.txt（"CIDR ; SBL编号"，";" 开头为注释）
+func parseSpamhaus(data []byte) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, ";") {
+			continue
+		}
+		var cidr, ref string
+		if strings.HasPrefix(text, "{") {
+			var rec struct {
+				CIDR  string `json:"cidr"`
+				SBLID string `json:"sblid"`
+			}
+			if err := json.Unmarshal([]byte(text), &rec); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			if rec.CIDR == "" {
+				continue // 元数据行
+			}
+			cidr, ref = rec.CIDR, rec.SBLID
+		} else {
+			cidr, ref, _ = strings.Cut(text, ";")
+			cidr, ref = strings.TrimSpace(cidr), strings.TrimSpace(ref)
+		}
+		prefix, err := ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		entries = append(entries, Entry{Prefix: prefix, Reference: ref, Confidence: 100})
+	}
+	return entries, scanner.Err()
+}
+
+// fireholFeed FireHOL 列表
+type fireholFeed struct {
+	source
+}
+
+func (f *fireholFeed) Fetch(ctx context.Context) ([]Entry, error) {
+	data, err := f.get(ctx, f.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return parseFireHOL(data)
+}
+
+// parseFireHOL 解析 .netset/.ipset：每行一个IP或CIDR，"#" 开头为注释
+func parseFireHOL(data []byte) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		prefix, err := ParsePrefix(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		entries = append(entries, Entry{Prefix: prefix, Confidence: 100})
+	}
+	return entries, scanner.Err()
+}
+
+// abuseIPDBFeed AbuseIPDB blacklist 接口
+type abuseIPDBFeed struct {
+	source
+	apiKey        string
+	minConfidence int
+}
+
+func (f *abuseIPDBFeed) Fetch(ctx context.Context) ([]Entry, error) {
+	u, err := url.Parse(f.url)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	if f.minConfidence > 0 {
+		q.Set("confidenceMinimum", strconv.Itoa(f.minConfidence))
+	}
+	u.RawQuery = q.Encode()
+	data, err := f.get(ctx, u.String(), http.Header{
+		"Key":    {f.apiKey},
+		"Accept": {"application/json"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseAbuseIPDB(data)
+}
+
+// parseAbuseIPDB 解析 {"data": [{"ipAddress": ..., "abuseConfidenceScore": ..., "lastReportedAt": ...}]}
+func parseAbuseIPDB(data []byte) ([]Entry, error) {
+	var resp struct {
+		Data []struct {
+			IPAddress            string `json:"ipAddress"`
+			AbuseConfidenceScore int    `json:"abuseConfidenceScore"`
+			LastReportedAt       string `json:"lastReportedAt"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("invalid AbuseIPDB response: %w", err)
+	}
+	entries := make([]Entry, 0, len(resp.Data))
+	for _, rec := range resp.Data {
+		prefix, err := ParsePrefix(rec.IPAddress)
+		if err != nil {
+			return nil, err
+		}
+		ref := ""
+		if rec.LastReportedAt != "" {
+			ref = "last reported " + rec.LastReportedAt
+		}
+		entries = append(entries, Entry{Prefix: prefix, Reference: ref, Confidence: rec.AbuseConfidenceScore})
+	}
+	return entries, nil
+}
@@ -0,0 +1,123 @@
+package threatintel
+
+import (
+	"net/netip"
+	"sort"
+)
+
+// Hit IP命中的情报记录及其来源
+type Hit struct {
+	Feed       string       `json:"feed"`
+	Prefix     netip.Prefix `json:"prefix"`
+	Reference  string       `json:"reference,omitempty"`
+	Confidence int          `json:"confidence"`
+}
+
+// span 一条记录覆盖的地址区间
+type span struct {
+	start, end netip.Addr
+	entry      Entry
+}
+
+// feedIndex 单个情报源的区间，按起始地址排序；maxEnd[i] 为前 i+1 个区间结束地址的最大值，
+// 用于在有嵌套的列表中向前查找覆盖该地址的区间
+type feedIndex struct {
+	name   string
+	spans  []span
+	maxEnd []netip.Addr
+}
+
+// Index 各情报源记录的只读索引，构建后可并发查询
+type Index struct {
+	feeds []feedIndex
+}
+
+// NewIndex 构建索引，feeds 的键为情报源名称
+func NewIndex(feeds map[string][]Entry) *Index {
+	names := make([]string, 0, len(feeds))
+	for name := range feeds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	idx := &Index{}
+	for _, name := range names {
+		entries := feeds[name]
+		fi := feedIndex{name: name, spans: make([]span, 0, len(entries))}
+		for _, e := range entries {
+			start := e.Prefix.Masked().Addr()
+			fi.spans = append(fi.spans, span{start: start, end: lastAddr(e.Prefix), entry: e})
+		}
+		sort.Slice(fi.spans, func(i, j int) bool {
+			return fi.spans[i].start.Less(fi.spans[j].start)
+		})
+		fi.maxEnd = make([]netip.Addr, len(fi.spans))
+		for i, s := range fi.spans {
+			fi.maxEnd[i] = s.end
+			if i > 0 && s.end.Less(fi.maxEnd[i-1]) {
+				fi.maxEnd[i] = fi.maxEnd[i-1]
+			}
+		}
+		idx.feeds = append(idx.feeds, fi)
+	}
+	return idx
+}
+
+// Lookup 返回各情报源中覆盖该IP的记录，每个情报源最多一条（最具体的前缀）
+func (idx *Index) Lookup(addr netip.Addr) []Hit {
+	if idx == nil || !addr.IsValid() {
+		return nil
+	}
+	addr = addr.Unmap()
+	var hits []Hit
+	for _, fi := range idx.feeds {
+		// 最后一个起始地址不大于 addr 的区间
+		i := sort.Search(len(fi.spans), func(i int) bool {
+			return addr.Less(fi.spans[i].start)
+		}) - 1
+		var best *Entry
+		for ; i >= 0 && !fi.maxEnd[i].Less(addr); i-- {
+			s := &fi.spans[i]
+			if s.start.BitLen() != addr.BitLen() || s.end.Less(addr) {
+				continue
+			}
+			if best == nil || s.entry.Prefix.Bits() > best.Prefix.Bits() {
+				best = &s.entry
+			}
+		}
+		if best != nil {
+			hits = append(hits, Hit{Feed: fi.name, Prefix: best.Prefix, Reference: best.Reference, Confidence: best.Confidence})
+		}
+	}
+	return hits
+}
+
+// Len 索引中的记录总数
+func (idx *Index) Len() int {
+	if idx == nil {
+		return 0
+	}
+	n := 0
+	for _, fi := range idx.feeds {
+		n += len(fi.spans)
+	}
+	return n
+}
+
+// lastAddr 前缀覆盖的最后一个地址
+func lastAddr(p netip.Prefix) netip.Addr {
+	p = p.Masked()
+	b := p.Addr().AsSlice()
+	bits := p.Bits()
+	for i := range b {
+		hostBits := 8*(i+1) - bits
+		switch {
+		case hostBits >= 8:
+			b[i] = 0xff
+		case hostBits > 0:
+			b[i] |= byte(1<<hostBits - 1)
+		}
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
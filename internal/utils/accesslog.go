@@ -0,0 +1,331 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 访问日志格式
+const (
+	// AccessLogNginx nginx 的 JSON 访问日志（log_format ... escape=json），字段名为 nginx 变量名
+	AccessLogNginx = "nginx"
+	// AccessLogCaddy caddy 的 JSON 访问日志（http.log.access）
+	AccessLogCaddy = "caddy"
+	// AccessLogCloudflare Cloudflare Logpush 的 http_requests 数据集（NDJSON），含 Bot Management 的 BotScore
+	AccessLogCloudflare = "cloudflare"
+	// AccessLogALB AWS Application Load Balancer 的访问日志（空格分隔的文本格式）
+	AccessLogALB = "alb"
+)
+
+// AccessLogFormats 支持的访问日志格式
+var AccessLogFormats = []string{AccessLogNginx, AccessLogCaddy, AccessLogCloudflare, AccessLogALB}
+
+// ValidAccessLogFormat 判断是否为支持的访问日志格式
+func ValidAccessLogFormat(format string) bool {
+	for _, f := range AccessLogFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// AccessLogEntry 访问日志中的一次请求
+type AccessLogEntry struct {
+	Time   time.Time
+	IP     string
+	Method string
+	Host   string
+	// Path 请求路径（含查询串）
+	Path      string
+	Status    int
+	UserAgent string
+	Referer   string
+	// BotScore CDN 给出的爬虫评分（Cloudflare 为 1-99，越低越可能是自动化程序），0 表示未提供
+	BotScore int
+}
+
+// ParseAccessLog 解析一行访问日志，缺少时间、客户端IP或请求路径时返回错误
+func ParseAccessLog(line []byte, format string) (AccessLogEntry, error) {
+	var entry AccessLogEntry
+	var err error
+	switch format {
+	case AccessLogNginx, AccessLogCaddy, AccessLogCloudflare:
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.UseNumber()
+		var fields map[string]interface{}
+		if err := dec.Decode(&fields); err != nil {
+			return AccessLogEntry{}, fmt.Errorf("invalid JSON: %w", err)
+		}
+		switch format {
+		case AccessLogNginx:
+			entry, err = nginxEntry(fields)
+		case AccessLogCaddy:
+			entry, err = caddyEntry(fields)
+		default:
+			entry, err = cloudflareEntry(fields)
+		}
+	case AccessLogALB:
+		entry, err = albEntry(string(line))
+	default:
+		return AccessLogEntry{}, fmt.Errorf("unknown access log format %q", format)
+	}
+	if err != nil {
+		return AccessLogEntry{}, err
+	}
+	if net.ParseIP(entry.IP) == nil {
+		return AccessLogEntry{}, fmt.Errorf("invalid client IP %q", entry.IP)
+	}
+	if entry.Path == "" || entry.Path[0] != '/' {
+		return AccessLogEntry{}, fmt.Errorf("invalid request path %q", entry.Path)
+	}
+	return entry, nil
+}
+
+// nginxEntry 按常用的 nginx 变量名读取字段：时间取 time_iso8601、msec 或 time_local，
+// 路径取 request_uri 或 request 行
+func nginxEntry(f map[string]interface{}) (AccessLogEntry, error) {
+	entry := AccessLogEntry{
+		IP:        logString(f["remote_addr"]),
+		Method:    logString(f["request_method"]),
+		Host:      firstLogString(f, "host", "http_host", "server_name"),
+		Path:      logString(f["request_uri"]),
+		UserAgent: logString(f["http_user_agent"]),
+		Referer:   logString(f["http_referer"]),
+	}
+	if request := logString(f["request"]); request != "" {
+		// request 为 "GET /path HTTP/1.1"
+		parts := strings.Fields(request)
+		if entry.Method == "" && len(parts) > 0 {
+			entry.Method = parts[0]
+		}
+		if entry.Path == "" && len(parts) > 1 {
+			entry.Path = parts[1]
+		}
+	}
+	status, err := logInt(f["status"])
+	if err != nil {
+		return AccessLogEntry{}, fmt.Errorf("invalid status: %w", err)
+	}
+	entry.Status = status
+
+	switch {
+	case logString(f["time_iso8601"]) != "":
+		entry.Time, err = time.Parse(time.RFC3339, logString(f["time_iso8601"]))
+	case logString(f["msec"]) != "":
+		entry.Time, err = unixSeconds(logString(f["msec"]))
+	case logString(f["time_local"]) != "":
+		entry.Time, err = time.Parse("02/Jan/2006:15:04:05 -0700", logString(f["time_local"]))
+	default:
+		err = fmt.Errorf("missing time_iso8601, msec or time_local")
+	}
+	if err != nil {
+		return AccessLogEntry{}, fmt.Errorf("invalid time: %w", err)
+	}
+	return entry, nil
+}
+
+// caddyEntry 读取 caddy 访问日志的 ts、request 和 status，客户端IP优先取 client_ip（经可信代理解析后的地址）
+func caddyEntry(f map[string]interface{}) (AccessLogEntry, error) {
+	request, ok := f["request"].(map[string]interface{})
+	if !ok {
+		return AccessLogEntry{}, fmt.Errorf("missing request")
+	}
+	entry := AccessLogEntry{
+		IP:     firstLogString(request, "client_ip", "remote_ip"),
+		Method: logString(request["method"]),
+		Host:   logString(request["host"]),
+		Path:   logString(request["uri"]),
+	}
+	if headers, ok := request["headers"].(map[string]interface{}); ok {
+		entry.UserAgent = headerValue(headers, "User-Agent")
+		entry.Referer = headerValue(headers, "Referer")
+	}
+	status, err := logInt(f["status"])
+	if err != nil {
+		return AccessLogEntry{}, fmt.Errorf("invalid status: %w", err)
+	}
+	entry.Status = status
+
+	// ts 默认为 Unix 秒（浮点数），time_format 配置为 rfc3339 或 iso8601 时为字符串
+	switch ts := f["ts"].(type) {
+	case json.Number:
+		entry.Time, err = unixSeconds(ts.String())
+	case string:
+		if entry.Time, err = time.Parse(time.RFC3339Nano, ts); err != nil {
+			entry.Time, err = time.Parse("2006-01-02T15:04:05.000Z0700", ts)
+		}
+	default:
+		err = fmt.Errorf("missing ts")
+	}
+	if err != nil {
+		return AccessLogEntry{}, fmt.Errorf("invalid ts: %w", err)
+	}
+	return entry, nil
+}
+
+// cloudflareEntry 读取 Logpush http_requests 数据集的字段，EdgeStartTimestamp 可为 rfc3339、unix 或 unixnano 格式；
+// BotScore 只在开通 Bot Management 并加入日志字段时存在，未计算时为0
+func cloudflareEntry(f map[string]interface{}) (AccessLogEntry, error) {
+	entry := AccessLogEntry{
+		IP:        logString(f["ClientIP"]),
+		Method:    logString(f["ClientRequestMethod"]),
+		Host:      logString(f["ClientRequestHost"]),
+		Path:      logString(f["ClientRequestURI"]),
+		UserAgent: logString(f["ClientRequestUserAgent"]),
+		Referer:   logString(f["ClientRequestReferer"]),
+	}
+	status, err := logInt(f["EdgeResponseStatus"])
+	if err != nil {
+		return AccessLogEntry{}, fmt.Errorf("invalid EdgeResponseStatus: %w", err)
+	}
+	entry.Status = status
+	if score, err := logInt(f["BotScore"]); err == nil && score >= 1 && score <= 99 {
+		entry.BotScore = score
+	}
+
+	switch ts := f["EdgeStartTimestamp"].(type) {
+	case json.Number:
+		var n int64
+		if n, err = ts.Int64(); err == nil {
+			// unixnano 格式的值远大于 unix 秒
+			if n > 1e12 {
+				entry.Time = time.Unix(0, n)
+			} else {
+				entry.Time = time.Unix(n, 0)
+			}
+		}
+	case string:
+		entry.Time, err = time.Parse(time.RFC3339Nano, ts)
+	default:
+		err = fmt.Errorf("missing EdgeStartTimestamp")
+	}
+	if err != nil {
+		return AccessLogEntry{}, fmt.Errorf("invalid EdgeStartTimestamp: %w", err)
+	}
+	return entry, nil
+}
+
+// albEntry 解析 ALB 访问日志：time 为第2个字段，client:port 为第4个，elb_status_code 为第9个，
+// 带引号的 request（"GET https://host:443/path HTTP/1.1"）和 user_agent 为第13、14个
+func albEntry(line string) (AccessLogEntry, error) {
+	fields := splitQuoted(line)
+	if len(fields) < 14 {
+		return AccessLogEntry{}, fmt.Errorf("expected at least 14 fields, got %d", len(fields))
+	}
+	var entry AccessLogEntry
+	var err error
+	if entry.Time, err = time.Parse(time.RFC3339Nano, fields[1]); err != nil {
+		return AccessLogEntry{}, fmt.Errorf("invalid time: %w", err)
+	}
+	client := fields[3]
+	if i := strings.LastIndexByte(client, ':'); i > 0 {
+		client = client[:i]
+	}
+	entry.IP = strings.Trim(client, "[]")
+	// 负载均衡未能得到响应时 elb_status_code 为 -
+	if fields[8] != "-" {
+		if entry.Status, err = strconv.Atoi(fields[8]); err != nil {
+			return AccessLogEntry{}, fmt.Errorf("invalid elb_status_code: %w", err)
+		}
+	}
+	parts := strings.Fields(fields[12])
+	if len(parts) < 2 {
+		return AccessLogEntry{}, fmt.Errorf("invalid request %q", fields[12])
+	}
+	entry.Method = parts[0]
+	if u, err := url.Parse(parts[1]); err == nil {
+		entry.Host = u.Hostname()
+		entry.Path = u.RequestURI()
+	}
+	entry.UserAgent = logString(fields[13])
+	return entry, nil
+}
+
+// splitQuoted 按空格拆分字段，双引号内的空格不拆分，引号内的 \" 和 \\ 为转义
+func splitQuoted(line string) []string {
+	var fields []string
+	var b strings.Builder
+	inQuotes, quoted := false, false
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+		switch {
+		case inQuotes && ch == '\\' && i+1 < len(line):
+			i++
+			b.WriteByte(line[i])
+		case ch == '"':
+			inQuotes = !inQuotes
+			quoted = true
+		case ch == ' ' && !inQuotes:
+			if b.Len() > 0 || quoted {
+				fields = append(fields, b.String())
+			}
+			b.Reset()
+			quoted = false
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	if b.Len() > 0 || quoted {
+		fields = append(fields, b.String())
+	}
+	return fields
+}
+
+// headerValue 返回 caddy 日志中请求头的第一个值，请求头名称不区分大小写
+func headerValue(headers map[string]interface{}, name string) string {
+	for key, v := range headers {
+		if !strings.EqualFold(key, name) {
+			continue
+		}
+		if values, ok := v.([]interface{}); ok && len(values) > 0 {
+			return logString(values[0])
+		}
+		return logString(v)
+	}
+	return ""
+}
+
+// firstLogString 返回第一个不为空的字段
+func firstLogString(f map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if s := logString(f[key]); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// logString 将字符串或数值字段转为字符串，nginx 未设置的变量记为 "-"，视为空
+func logString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		if v == "-" {
+			return ""
+		}
+		return v
+	case json.Number:
+		return v.String()
+	}
+	return ""
+}
+
+// logInt 将字符串或数值字段转为整数
+func logInt(v interface{}) (int, error) {
+	return strconv.Atoi(logString(v))
+}
+
+// unixSeconds 解析带小数的 Unix 秒
+func unixSeconds(s string) (time.Time, error) {
+	sec, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, int64(sec*float64(time.Second))), nil
+}
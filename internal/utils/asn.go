@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ASN 自治系统编号及名称
+type ASN struct {
+	Number      int
+	Description string
+}
+
+// asnRange 一段连续地址所属的ASN
+type asnRange struct {
+	start, end netip.Addr
+	asn        ASN
+}
+
+// ASNDatabase IP段到ASN的映射，按起始地址排序
+type ASNDatabase struct {
+	ranges []asnRange
+}
+
+// hostingKeywords ASN名称中出现这些关键词时视为云服务商或托管机房
+var hostingKeywords = []string{
+	"hosting", "cloud", "datacenter", "data center", "data-center", "server", "vps",
+	"amazon", "aws", "google", "microsoft", "azure", "digitalocean", "ovh", "hetzner", "linode",
+	"akamai", "vultr", "choopa", "oracle", "alibaba", "tencent", "leaseweb", "contabo", "m247",
+	"scaleway", "online s.a.s", "cdn77", "datacamp", "fastly", "cloudflare",
+}
+
+// LoadASNDatabase 读取 iptoasn.com 格式的TSV（起始地址、结束地址、ASN、国家、名称），文件名以 .gz 结尾时按gzip解压
+func LoadASNDatabase(path string) (*ASNDatabase, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return ParseASNDatabase(r)
+}
+
+// ParseASNDatabase 解析 iptoasn.com 格式的TSV，ASN为0（未路由）的地址段不收录
+func ParseASNDatabase(r io.Reader) (*ASNDatabase, error) {
+	db := &ASNDatabase{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, "\t")
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("line %d: expected at least 3 tab-separated fields", line)
+		}
+		start, err := netip.ParseAddr(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		end, err := netip.ParseAddr(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		number, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid ASN %q", line, fields[2])
+		}
+		if number == 0 {
+			continue
+		}
+		if start.Is4() != end.Is4() || end.Less(start) {
+			return nil, fmt.Errorf("line %d: invalid range %s-%s", line, start, end)
+		}
+		asn := ASN{Number: number}
+		if len(fields) >= 5 {
+			asn.Description = fields[4]
+		}
+		db.ranges = append(db.ranges, asnRange{start: start.Unmap(), end: end.Unmap(), asn: asn})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(db.ranges, func(i, j int) bool { return db.ranges[i].start.Less(db.ranges[j].start) })
+	return db, nil
+}
+
+// Len 返回收录的地址段数
+func (db *ASNDatabase) Len() int {
+	return len(db.ranges)
+}
+
+// Lookup 返回地址所属的ASN，未收录时 ok 为 false
+func (db *ASNDatabase) Lookup(addr netip.Addr) (ASN, bool) {
+	addr = addr.Unmap()
+	i := sort.Search(len(db.ranges), func(i int) bool { return addr.Less(db.ranges[i].start) })
+	if i == 0 {
+		return ASN{}, false
+	}
+	r := db.ranges[i-1]
+	if addr.Is4() != r.start.Is4() || r.end.Less(addr) {
+		return ASN{}, false
+	}
+	return r.asn, true
+}
+
+// IsHostingASN 按名称关键词判断ASN是否属于云服务商或托管机房
+func IsHostingASN(asn ASN) bool {
+	desc := strings.ToLower(asn.Description)
+	for _, keyword := range hostingKeywords {
+		if strings.Contains(desc, keyword) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"hash/maphash"
+	"math"
+)
+
+// BloomFilter 布隆过滤器：判定不在集合中的键一定不在，判定在集合中的键按误判率可能不在；
+// 不支持删除，只保存在内存中（哈希种子每个进程不同）。并发使用时由调用方加锁
+type BloomFilter struct {
+	words  []uint64
+	m      uint64
+	k      int
+	ones   uint64
+	added  int
+	seed1  maphash.Seed
+	seed2  maphash.Seed
+	target float64
+}
+
+// NewBloomFilter 按预计的键数 n 和目标误判率 p 创建过滤器，实际加入的键超过 n 后误判率随之上升
+func NewBloomFilter(n int, p float64) *BloomFilter {
+	if n < 1 {
+		n = 1
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := int(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &BloomFilter{
+		words:  make([]uint64, (m+63)/64),
+		m:      m,
+		k:      k,
+		seed1:  maphash.MakeSeed(),
+		seed2:  maphash.MakeSeed(),
+		target: p,
+	}
+}
+
+// locations 按双重哈希（h1 + i*h2）返回键对应的位
+func (b *BloomFilter) locations(key string, fn func(bit uint64) bool) {
+	h1 := maphash.String(b.seed1, key)
+	h2 := maphash.String(b.seed2, key) | 1
+	for i := 0; i < b.k; i++ {
+		if !fn((h1 + uint64(i)*h2) % b.m) {
+			return
+		}
+	}
+}
+
+// Add 将键加入过滤器
+func (b *BloomFilter) Add(key string) {
+	b.locations(key, func(bit uint64) bool {
+		word, mask := bit/64, uint64(1)<<(bit%64)
+		if b.words[word]&mask == 0 {
+			b.words[word] |= mask
+			b.ones++
+		}
+		return true
+	})
+	b.added++
+}
+
+// Test 报告键是否可能在过滤器中，返回 false 时一定不在
+func (b *BloomFilter) Test(key string) bool {
+	found := true
+	b.locations(key, func(bit uint64) bool {
+		if b.words[bit/64]&(uint64(1)<<(bit%64)) == 0 {
+			found = false
+		}
+		return found
+	})
+	return found
+}
+
+// BloomStats 过滤器的大小和按已置位比例估算的误判率
+type BloomStats struct {
+	Keys                int
+	Bits                uint64
+	Hashes              int
+	Bytes               int
+	TargetFalsePositive float64
+	FalsePositive       float64
+}
+
+// Stats 返回过滤器的统计，误判率为 (已置位比例)^k
+func (b *BloomFilter) Stats() BloomStats {
+	return BloomStats{
+		Keys:                b.added,
+		Bits:                b.m,
+		Hashes:              b.k,
+		Bytes:               len(b.words) * 8,
+		TargetFalsePositive: b.target,
+		FalsePositive:       math.Pow(float64(b.ones)/float64(b.m), float64(b.k)),
+	}
+}
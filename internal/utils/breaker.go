@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// 熔断器状态
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+// CircuitBreaker 连续失败达到阈值后熔断，冷却期内拒绝调用；冷却结束后放行一次探测，
+// 探测成功则恢复，失败则重新熔断
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// NewCircuitBreaker 创建熔断器，threshold 不大于0时从不熔断
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Allow 报告本次调用是否可以执行
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openUntil.IsZero() {
+		return true
+	}
+	if b.probing || time.Now().Before(b.openUntil) {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// Success 记录一次成功调用，熔断器恢复闭合
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.openUntil = time.Time{}
+	b.probing = false
+}
+
+// Failure 记录一次失败调用，返回熔断器是否因此断开
+func (b *CircuitBreaker) Failure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.threshold <= 0 || (!b.probing && b.failures < b.threshold) {
+		return false
+	}
+	b.openUntil = time.Now().Add(b.cooldown)
+	b.probing = false
+	return true
+}
+
+// State 返回熔断器当前状态
+func (b *CircuitBreaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case b.openUntil.IsZero():
+		return BreakerClosed
+	case b.probing || !time.Now().Before(b.openUntil):
+		return BreakerHalfOpen
+	default:
+		return BreakerOpen
+	}
+}
+
+// RetryAfter 返回熔断器断开时距离下次探测的时长
+func (b *CircuitBreaker) RetryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openUntil.IsZero() {
+		return 0
+	}
+	return max(time.Until(b.openUntil), 0)
+}
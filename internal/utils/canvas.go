@@ -0,0 +1,239 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/png"
+	"strings"
+)
+
+// maxCanvasPixels 服务端解码Canvas图像的像素上限，防止超大图像耗尽内存和CPU；
+// 采集脚本绘制的Canvas远小于此
+const maxCanvasPixels = 1024 * 1024
+
+// CanvasImageStats Canvas图像的服务端分析结果
+type CanvasImageStats struct {
+	// PHash 64位差值感知哈希（dHash），对像素级噪点不敏感
+	PHash string
+	// SimHash 64位局部敏感哈希：相近的Canvas输出只有少数位不同，用汉明距离衡量相似度
+	SimHash uint64
+	// NoiseRatio R、G、B通道的孤立噪点比例：
+	// 上下左右四个邻居取值相同、自身只相差1~2的像素数 / 四邻居取值相同的像素数
+	NoiseRatio [3]float64
+}
+
+// MaxNoiseRatio 返回各通道中最大的噪点比例
+func (s *CanvasImageStats) MaxNoiseRatio() float64 {
+	max := 0.0
+	for _, ratio := range s.NoiseRatio {
+		if ratio > max {
+			max = ratio
+		}
+	}
+	return max
+}
+
+// AnalyzeCanvasImage 解码Canvas的PNG data URL，计算感知哈希与逐通道噪点统计
+func AnalyzeCanvasImage(dataURL string) (*CanvasImageStats, error) {
+	const prefix = "data:image/png;base64,"
+	if !strings.HasPrefix(dataURL, prefix) {
+		return nil, errors.New("canvas is not a PNG data URL")
+	}
+	raw, err := base64.StdEncoding.DecodeString(dataURL[len(prefix):])
+	if err != nil {
+		return nil, fmt.Errorf("invalid canvas base64: %w", err)
+	}
+
+	cfg, err := png.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid canvas PNG: %w", err)
+	}
+	if cfg.Width*cfg.Height > maxCanvasPixels {
+		return nil, fmt.Errorf("canvas image too large: %dx%d", cfg.Width, cfg.Height)
+	}
+	img, err := png.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid canvas PNG: %w", err)
+	}
+
+	pixels := newRGBImage(img)
+	return &CanvasImageStats{
+		PHash:      differenceHash(pixels),
+		SimHash:    blockSimHash(pixels),
+		NoiseRatio: channelNoise(pixels),
+	}, nil
+}
+
+// rgbImage Canvas图像的8位RGB像素，每个像素3字节，取值与 color.Color.RGBA 的高8位一致（预乘Alpha）
+type rgbImage struct {
+	w, h int
+	pix  []uint8
+}
+
+// newRGBImage 提取图像的RGB像素；PNG解码常见的 NRGBA 和 RGBA 直接读取 Pix，其他格式逐像素转换
+func newRGBImage(img image.Image) *rgbImage {
+	bounds := img.Bounds()
+	m := &rgbImage{w: bounds.Dx(), h: bounds.Dy()}
+	m.pix = make([]uint8, 0, m.w*m.h*3)
+	switch src := img.(type) {
+	case *image.NRGBA:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			row := src.Pix[src.PixOffset(bounds.Min.X, y):src.PixOffset(bounds.Max.X, y)]
+			for i := 0; i < len(row); i += 4 {
+				a := uint32(row[i+3]) * 0x101
+				for ch := 0; ch < 3; ch++ {
+					m.pix = append(m.pix, uint8(uint32(row[i+ch])*0x101*a/0xffff>>8))
+				}
+			}
+		}
+	case *image.RGBA:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			row := src.Pix[src.PixOffset(bounds.Min.X, y):src.PixOffset(bounds.Max.X, y)]
+			for i := 0; i < len(row); i += 4 {
+				m.pix = append(m.pix, row[i], row[i+1], row[i+2])
+			}
+		}
+	default:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				r, g, b, _ := img.At(x, y).RGBA()
+				m.pix = append(m.pix, uint8(r>>8), uint8(g>>8), uint8(b>>8))
+			}
+		}
+	}
+	return m
+}
+
+// at 返回像素的8位RGB值
+func (m *rgbImage) at(x, y int) [3]uint8 {
+	i := (y*m.w + x) * 3
+	return [3]uint8{m.pix[i], m.pix[i+1], m.pix[i+2]}
+}
+
+// dHashMargin 相邻格亮度差超过该值才记为1，使平坦区域的低位噪点不会翻转哈希位
+const dHashMargin = 1.0
+
+// differenceHash 计算dHash：缩放为9x8灰度图，比较每行相邻像素的亮度
+func differenceHash(img *rgbImage) string {
+	w, h := img.w, img.h
+	if w == 0 || h == 0 {
+		return ""
+	}
+
+	var gray [8][9]float64
+	for gy := 0; gy < 8; gy++ {
+		y0, y1 := gy*h/8, (gy+1)*h/8
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for gx := 0; gx < 9; gx++ {
+			x0, x1 := gx*w/9, (gx+1)*w/9
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+			sum, n := 0.0, 0
+			for y := y0; y < y1 && y < h; y++ {
+				for x := x0; x < x1 && x < w; x++ {
+					c := img.at(x, y)
+					sum += 0.299*float64(c[0]) + 0.587*float64(c[1]) + 0.114*float64(c[2])
+					n++
+				}
+			}
+			if n > 0 {
+				gray[gy][gx] = sum / float64(n)
+			}
+		}
+	}
+
+	var hash uint64
+	for gy := 0; gy < 8; gy++ {
+		for gx := 0; gx < 8; gx++ {
+			hash <<= 1
+			if gray[gy][gx] > gray[gy][gx+1]+dHashMargin {
+				hash |= 1
+			}
+		}
+	}
+	return fmt.Sprintf("%016x", hash)
+}
+
+// simHashBlock SimHash特征块的边长（像素）
+const simHashBlock = 8
+
+// blockSimHash 计算Canvas图像的SimHash
+// 每个8x8块的平均颜色量化为16级后与块坐标组成一个特征；
+// 像素噪点几乎不改变量化后的平均色，文字或图形渲染差异只影响所在的块，
+// 因此汉明距离随改变的块数增长
+func blockSimHash(img *rgbImage) uint64 {
+	var weights [64]int
+	for by := 0; by < img.h; by += simHashBlock {
+		for bx := 0; bx < img.w; bx += simHashBlock {
+			var sum [3]int
+			n := 0
+			for y := by; y < by+simHashBlock && y < img.h; y++ {
+				for x := bx; x < bx+simHashBlock && x < img.w; x++ {
+					c := img.at(x, y)
+					sum[0] += int(c[0])
+					sum[1] += int(c[1])
+					sum[2] += int(c[2])
+					n++
+				}
+			}
+			h := fnv.New64a()
+			fmt.Fprintf(h, "%d,%d,%d,%d,%d", bx, by, sum[0]/n>>4, sum[1]/n>>4, sum[2]/n>>4)
+			feature := h.Sum64()
+			for bit := 0; bit < 64; bit++ {
+				if feature&(1<<uint(bit)) != 0 {
+					weights[bit]++
+				} else {
+					weights[bit]--
+				}
+			}
+		}
+	}
+
+	var hash uint64
+	for bit := 0; bit < 64; bit++ {
+		if weights[bit] > 0 {
+			hash |= 1 << uint(bit)
+		}
+	}
+	return hash
+}
+
+// channelNoise 统计各通道的孤立噪点比例
+// 正常渲染的平坦区域中像素与四邻居完全相同，抗锯齿边缘是连续渐变；
+// 噪点注入扩展随机改动个别像素的最低位，会在平坦区域留下孤立的 ±1~2 偏差
+func channelNoise(img *rgbImage) [3]float64 {
+	var spikes, flat [3]int
+	for y := 1; y < img.h-1; y++ {
+		for x := 1; x < img.w-1; x++ {
+			center := img.at(x, y)
+			left, right := img.at(x-1, y), img.at(x+1, y)
+			up, down := img.at(x, y-1), img.at(x, y+1)
+			for ch := 0; ch < 3; ch++ {
+				neighbor := left[ch]
+				if right[ch] != neighbor || up[ch] != neighbor || down[ch] != neighbor {
+					continue
+				}
+				flat[ch]++
+				diff := int(center[ch]) - int(neighbor)
+				if diff != 0 && diff >= -2 && diff <= 2 {
+					spikes[ch]++
+				}
+			}
+		}
+	}
+
+	var ratio [3]float64
+	for ch := 0; ch < 3; ch++ {
+		if flat[ch] > 0 {
+			ratio[ch] = float64(spikes[ch]) / float64(flat[ch])
+		}
+	}
+	return ratio
+}
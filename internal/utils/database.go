@@ -1,26 +1,45 @@
 package utils
 
 import (
+	"crypto/hmac"
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net"
+	"net/netip"
 	"sort"
 	"strings"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
 // Database 数据库连接管理
+// DB 用于写入和需要读到最新写入的查询；Read 用于统计和列表查询，
+// 配置了只读副本时指向副本，否则与 DB 相同
 type Database struct {
-	DB *sql.DB
+	DB   *sql.DB
+	Read *sql.DB
+	// Shards 指纹和分析结果按哈希分片的文件数，为0时与其他表一起保存在主库
+	Shards int
 }
 
-// NewDatabase 创建新的数据库连接
-func NewDatabase(dbPath string) (*Database, error) {
-	db, err := sql.Open("sqlite3", dbPath)
+// NewDatabase 创建新的数据库连接，busyTimeout 为遇到锁时在驱动内等待的时长；
+// shards 大于1时指纹和分析结果按哈希分片到 shards 个文件（见 ShardPath）
+func NewDatabase(dbPath string, busyTimeout time.Duration, shards int) (*Database, error) {
+	sep := "?"
+	if strings.Contains(dbPath, "?") {
+		sep = "&"
+	}
+	dsn := fmt.Sprintf("%s%s_busy_timeout=%d", dbPath, sep, busyTimeout.Milliseconds())
+	if shards > 1 {
+		return newShardedDatabase(dbPath, dsn, shards)
+	}
+	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -29,7 +48,7 @@ func NewDatabase(dbPath string) (*Database, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	database := &Database{DB: db}
+	database := &Database{DB: db, Read: db}
 	if err := database.CreateTables(); err != nil {
 		return nil, fmt.Errorf("failed to create tables: %w", err)
 	}
@@ -80,20 +99,1016 @@ func (d *Database) CreateTables() error {
 		FOREIGN KEY (fingerprint_hash) REFERENCES fingerprints (fingerprint_hash)
 	);`
 
-	if _, err := d.DB.Exec(fingerprintTable); err != nil {
+	// 归一化组件哈希，用于部分匹配
+	componentsTable := `
+	CREATE TABLE IF NOT EXISTS fingerprint_components (
+		fingerprint_hash TEXT NOT NULL,
+		component TEXT NOT NULL,
+		hash TEXT NOT NULL,
+		PRIMARY KEY (fingerprint_hash, component)
+	);`
+
+	// Canvas SimHash 的分段索引：64位切成4段16位，汉明距离不超过3的两个哈希至少有一段完全相同
+	canvasLSHTable := `
+	CREATE TABLE IF NOT EXISTS canvas_lsh (
+		band INTEGER NOT NULL,
+		value INTEGER NOT NULL,
+		fingerprint_hash TEXT NOT NULL,
+		PRIMARY KEY (band, value, fingerprint_hash)
+	);`
+
+	// 设备农场检测报告
+	farmsTable := `
+	CREATE TABLE IF NOT EXISTS farms (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		kind TEXT NOT NULL,
+		key TEXT NOT NULL,
+		fingerprints INTEGER NOT NULL,
+		user_agents INTEGER NOT NULL,
+		samples TEXT NOT NULL,
+		blocked BOOLEAN NOT NULL DEFAULT 0,
+		first_detected DATETIME NOT NULL,
+		last_detected DATETIME NOT NULL,
+		UNIQUE (kind, key)
+	);`
+
+	// 按站点和统计桶（Unix秒）累计的提交量与爬虫数
+	trafficTable := `
+	CREATE TABLE IF NOT EXISTS traffic_stats (
+		site_id TEXT NOT NULL,
+		bucket INTEGER NOT NULL,
+		submissions INTEGER NOT NULL DEFAULT 0,
+		bots INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (site_id, bucket)
+	);`
+
+	// 按站点、统计桶（Unix秒）和评分区间累计的提交数与评分之和
+	scoreStatsTable := `
+	CREATE TABLE IF NOT EXISTS score_stats (
+		site_id TEXT NOT NULL,
+		bucket INTEGER NOT NULL,
+		bin INTEGER NOT NULL,
+		count INTEGER NOT NULL DEFAULT 0,
+		score_sum REAL NOT NULL DEFAULT 0,
+		PRIMARY KEY (site_id, bucket, bin)
+	);`
+
+	// 按站点、天（UTC）和爬虫累计的已识别爬虫请求数，category、purpose 为识别时特征库中的值
+	crawlerStatsTable := `
+	CREATE TABLE IF NOT EXISTS crawler_stats (
+		site_id TEXT NOT NULL,
+		day INTEGER NOT NULL,
+		crawler TEXT NOT NULL,
+		operator TEXT NOT NULL DEFAULT '',
+		category TEXT NOT NULL,
+		purpose TEXT NOT NULL DEFAULT '',
+		requests INTEGER NOT NULL DEFAULT 0,
+		challenged INTEGER NOT NULL DEFAULT 0,
+		denied INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (site_id, day, crawler)
+	);`
+
+	// 按站点、天（UTC）和爬虫累计的 robots.txt 审计请求数及其中访问禁止路径的数量
+	robotsAuditTable := `
+	CREATE TABLE IF NOT EXISTS robots_audit (
+		site_id TEXT NOT NULL,
+		day INTEGER NOT NULL,
+		crawler TEXT NOT NULL,
+		operator TEXT NOT NULL DEFAULT '',
+		category TEXT NOT NULL,
+		purpose TEXT NOT NULL DEFAULT '',
+		requests INTEGER NOT NULL DEFAULT 0,
+		disallowed INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (site_id, day, crawler)
+	);`
+
+	// 爬虫访问的被 robots.txt 禁止的路径
+	robotsViolationsTable := `
+	CREATE TABLE IF NOT EXISTS robots_violations (
+		site_id TEXT NOT NULL,
+		crawler TEXT NOT NULL,
+		path TEXT NOT NULL,
+		rule TEXT NOT NULL,
+		count INTEGER NOT NULL DEFAULT 0,
+		first_seen DATETIME NOT NULL,
+		last_seen DATETIME NOT NULL,
+		PRIMARY KEY (site_id, crawler, path)
+	);`
+
+	// 访问日志中各客户端（IP + User Agent）按站点和天（UTC）汇总的请求，pending 表示有新请求尚未评分，
+	// fingerprint_hash 为空表示未关联到执行过采集脚本的指纹
+	accessLogClientsTable := `
+	CREATE TABLE IF NOT EXISTS access_log_clients (
+		site_id TEXT NOT NULL,
+		day INTEGER NOT NULL,
+		ip_address TEXT NOT NULL,
+		user_agent TEXT NOT NULL,
+		requests INTEGER NOT NULL DEFAULT 0,
+		pages INTEGER NOT NULL DEFAULT 0,
+		assets INTEGER NOT NULL DEFAULT 0,
+		errors INTEGER NOT NULL DEFAULT 0,
+		first_seen DATETIME NOT NULL,
+		last_seen DATETIME NOT NULL,
+		crawler TEXT NOT NULL DEFAULT '',
+		fingerprint_hash TEXT NOT NULL DEFAULT '',
+		bot_score REAL,
+		reasons TEXT NOT NULL DEFAULT '[]',
+		pending BOOLEAN NOT NULL DEFAULT 1,
+		PRIMARY KEY (site_id, day, ip_address, user_agent)
+	);`
+
+	// 各 JA4 TLS 指纹按站点和小时汇总的指纹提交数及其中判定为爬虫的数量，用于边缘节点在没有指纹时决策
+	ja4StatsTable := `
+	CREATE TABLE IF NOT EXISTS ja4_stats (
+		site_id TEXT NOT NULL,
+		ja4 TEXT NOT NULL,
+		hour INTEGER NOT NULL,
+		submissions INTEGER NOT NULL DEFAULT 0,
+		bots INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (site_id, ja4, hour)
+	);`
+
+	// 本区域产生的待其他区域拉取的变更，seq 作为拉取游标
+	replicationLogTable := `
+	CREATE TABLE IF NOT EXISTS replication_log (
+		seq INTEGER PRIMARY KEY AUTOINCREMENT,
+		kind TEXT NOT NULL,
+		payload TEXT NOT NULL,
+		created_at DATETIME NOT NULL
+	);`
+
+	// 从其他区域拉取变更的进度
+	replicationPeersTable := `
+	CREATE TABLE IF NOT EXISTS replication_peers (
+		region TEXT PRIMARY KEY,
+		cursor INTEGER NOT NULL DEFAULT 0,
+		applied INTEGER NOT NULL DEFAULT 0,
+		last_pull_at DATETIME,
+		last_success_at DATETIME,
+		last_error TEXT NOT NULL DEFAULT ''
+	);`
+
+	// 解除封禁和删除标注的时间，用于丢弃其他区域在此之前产生的同一条目的变更
+	replicationTombstonesTable := `
+	CREATE TABLE IF NOT EXISTS replication_tombstones (
+		entity TEXT NOT NULL,
+		key TEXT NOT NULL,
+		deleted_at DATETIME NOT NULL,
+		PRIMARY KEY (entity, key)
+	);`
+
+	// 流量异常记录，同一站点同一统计桶的同类异常只记录一次
+	anomaliesTable := `
+	CREATE TABLE IF NOT EXISTS anomalies (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		site_id TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		bucket_start DATETIME NOT NULL,
+		observed REAL NOT NULL,
+		baseline REAL NOT NULL,
+		factor REAL NOT NULL,
+		message TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		UNIQUE (site_id, kind, bucket_start)
+	);`
+
+	// 站点的评分与策略覆盖
+	sitePoliciesTable := `
+	CREATE TABLE IF NOT EXISTS site_policies (
+		site_id TEXT PRIMARY KEY,
+		bot_threshold REAL,
+		rule_weights TEXT NOT NULL DEFAULT '{}',
+		event_thresholds TEXT NOT NULL DEFAULT '{}',
+		challenge_policy TEXT NOT NULL DEFAULT '',
+		retention_days INTEGER NOT NULL DEFAULT 0,
+		country_policy TEXT NOT NULL DEFAULT '',
+		updated_at DATETIME NOT NULL
+	);`
+
+	// 接入方提交的业务事件及当时的处理建议
+	eventsTable := `
+	CREATE TABLE IF NOT EXISTS events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		fingerprint_hash TEXT NOT NULL,
+		site_id TEXT NOT NULL,
+		event_type TEXT NOT NULL,
+		outcome TEXT NOT NULL,
+		metadata TEXT NOT NULL,
+		ip_address TEXT NOT NULL,
+		bot_score REAL NOT NULL,
+		threshold REAL NOT NULL,
+		is_bot BOOLEAN NOT NULL,
+		risk_level TEXT NOT NULL,
+		action TEXT NOT NULL,
+		created_at DATETIME NOT NULL
+	);`
+
+	// 账号使用过的设备
+	accountDevicesTable := `
+	CREATE TABLE IF NOT EXISTS account_devices (
+		site_id TEXT NOT NULL,
+		account_id TEXT NOT NULL,
+		fingerprint_hash TEXT NOT NULL,
+		first_seen DATETIME NOT NULL,
+		last_seen DATETIME NOT NULL,
+		last_ip TEXT NOT NULL,
+		last_country TEXT NOT NULL,
+		PRIMARY KEY (site_id, account_id, fingerprint_hash)
+	);`
+
+	// 账号接管信号
+	accountSignalsTable := `
+	CREATE TABLE IF NOT EXISTS account_signals (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		site_id TEXT NOT NULL,
+		account_id TEXT NOT NULL,
+		fingerprint_hash TEXT NOT NULL,
+		code TEXT NOT NULL,
+		detail TEXT NOT NULL,
+		created_at DATETIME NOT NULL
+	);`
+
+	// 临时封禁名单：指纹、IP、IP段或访客，决策时通过内存缓存查询
+	blocklistTable := `
+	CREATE TABLE IF NOT EXISTS blocklist (
+		kind TEXT NOT NULL,
+		key TEXT NOT NULL,
+		reason TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		expires_at DATETIME NOT NULL,
+		PRIMARY KEY (kind, key)
+	);`
+
+	// 自动封禁规则计数的检测结果：风险等级为 HIGH 或判定为爬虫的提交
+	detectionHitsTable := `
+	CREATE TABLE IF NOT EXISTS detection_hits (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		fingerprint_hash TEXT NOT NULL,
+		ip_address TEXT NOT NULL,
+		ip_range TEXT NOT NULL,
+		risk_level TEXT NOT NULL,
+		is_bot BOOLEAN NOT NULL,
+		created_at DATETIME NOT NULL
+	);`
+
+	// 撞库检测记录
+	stuffingTable := `
+	CREATE TABLE IF NOT EXISTS credential_stuffing (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		site_id TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		key TEXT NOT NULL,
+		failures INTEGER NOT NULL,
+		accounts INTEGER NOT NULL,
+		created_at DATETIME NOT NULL
+	);`
+
+	// 访客令牌的签名密钥，retired_at 为空的是当前签发密钥
+	tokenKeysTable := `
+	CREATE TABLE IF NOT EXISTS token_keys (
+		id TEXT PRIMARY KEY,
+		secret TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		retired_at DATETIME
+	);`
+
+	// 访客令牌的吊销记录，kind 为 jti 或 fingerprint，令牌全部过期后可删除
+	tokenRevocationsTable := `
+	CREATE TABLE IF NOT EXISTS token_revocations (
+		kind TEXT NOT NULL,
+		key TEXT NOT NULL,
+		revoked_at DATETIME NOT NULL,
+		expires_at DATETIME NOT NULL,
+		PRIMARY KEY (kind, key)
+	);`
+
+	// 指纹的人工标注（bot/human），作为评估检测质量的真实值
+	labelsTable := `
+	CREATE TABLE IF NOT EXISTS labels (
+		fingerprint_hash TEXT PRIMARY KEY,
+		label TEXT NOT NULL,
+		note TEXT NOT NULL DEFAULT '',
+		actor TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL
+	);`
+
+	// IP信誉情报源的状态，enabled 可在运行时通过管理API切换
+	threatFeedsTable := `
+	CREATE TABLE IF NOT EXISTS threat_feeds (
+		name TEXT PRIMARY KEY,
+		enabled INTEGER NOT NULL DEFAULT 1,
+		entries INTEGER NOT NULL DEFAULT 0,
+		last_attempt DATETIME,
+		last_success DATETIME,
+		last_error TEXT NOT NULL DEFAULT ''
+	);`
+
+	// 情报源导入的IP段，每次更新整体替换该情报源的记录
+	threatIntelTable := `
+	CREATE TABLE IF NOT EXISTS threat_intel (
+		feed TEXT NOT NULL,
+		prefix TEXT NOT NULL,
+		reference TEXT NOT NULL DEFAULT '',
+		confidence INTEGER NOT NULL,
+		PRIMARY KEY (feed, prefix)
+	);`
+
+	// 按IP（IPv6按/64）累计的历史信誉，计数为 updated_at 时刻衰减后的值
+	ipReputationTable := `
+	CREATE TABLE IF NOT EXISTS ip_reputation (
+		ip TEXT PRIMARY KEY,
+		bot_detections REAL NOT NULL DEFAULT 0,
+		challenge_failures REAL NOT NULL DEFAULT 0,
+		feed_hits REAL NOT NULL DEFAULT 0,
+		submissions INTEGER NOT NULL DEFAULT 0,
+		first_seen DATETIME NOT NULL,
+		last_seen DATETIME NOT NULL,
+		last_bot_at DATETIME,
+		last_challenge_failure_at DATETIME,
+		last_feed_hit_at DATETIME,
+		updated_at DATETIME NOT NULL
+	);`
+
+	// 指纹近期出现过的住宅ASN，用于检测住宅代理轮换
+	fingerprintASNsTable := `
+	CREATE TABLE IF NOT EXISTS fingerprint_asns (
+		fingerprint_hash TEXT NOT NULL,
+		asn INTEGER NOT NULL,
+		last_seen DATETIME NOT NULL,
+		PRIMARY KEY (fingerprint_hash, asn)
+	);`
+
+	// 按聚合网段（IPv4 /24，IPv6 /64）每小时的提交量
+	subnetVelocityTable := `
+	CREATE TABLE IF NOT EXISTS subnet_velocity (
+		subnet TEXT NOT NULL,
+		bucket DATETIME NOT NULL,
+		submissions INTEGER NOT NULL DEFAULT 0,
+		bot_submissions INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (subnet, bucket)
+	);`
+
+	// 聚合网段内近期出现的地址和指纹
+	subnetAddressesTable := `
+	CREATE TABLE IF NOT EXISTS subnet_addresses (
+		subnet TEXT NOT NULL,
+		ip TEXT NOT NULL,
+		fingerprint_hash TEXT NOT NULL,
+		last_seen DATETIME NOT NULL,
+		PRIMARY KEY (subnet, ip, fingerprint_hash)
+	);`
+
+	// 每次提交时采集页面的导航上下文
+	navigationTable := `
+	CREATE TABLE IF NOT EXISTS navigation_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		site_id TEXT NOT NULL,
+		fingerprint_hash TEXT NOT NULL,
+		subnet TEXT NOT NULL,
+		page_host TEXT NOT NULL,
+		page_path TEXT NOT NULL,
+		referrer_host TEXT NOT NULL,
+		direct BOOLEAN NOT NULL,
+		deep BOOLEAN NOT NULL,
+		created_at DATETIME NOT NULL
+	);`
+
+	// page_view 事件中的页面路径和静态资源加载数，assets 为空表示未上报
+	pageViewsTable := `
+	CREATE TABLE IF NOT EXISTS page_views (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		site_id TEXT NOT NULL,
+		fingerprint_hash TEXT NOT NULL,
+		path TEXT NOT NULL,
+		assets INTEGER,
+		created_at DATETIME NOT NULL
+	);`
+
+	// 采集爬虫检测记录
+	scrapingTable := `
+	CREATE TABLE IF NOT EXISTS scraping_detections (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		site_id TEXT NOT NULL,
+		fingerprint_hash TEXT NOT NULL,
+		pattern TEXT NOT NULL,
+		pages INTEGER NOT NULL,
+		samples TEXT NOT NULL,
+		created_at DATETIME NOT NULL
+	);`
+
+	// 指纹和访客Cookie最近出现的网段和国家，kind 为 fingerprint 或 visitor
+	sessionActivityTable := `
+	CREATE TABLE IF NOT EXISTS session_activity (
+		kind TEXT NOT NULL,
+		key TEXT NOT NULL,
+		subnet TEXT NOT NULL,
+		country TEXT NOT NULL,
+		last_seen DATETIME NOT NULL,
+		PRIMARY KEY (kind, key, subnet)
+	);`
+
+	// 会话并发的告警记录，用于在窗口内去重
+	sessionAlertsTable := `
+	CREATE TABLE IF NOT EXISTS session_alerts (
+		kind TEXT NOT NULL,
+		key TEXT NOT NULL,
+		alerted_at DATETIME NOT NULL,
+		PRIMARY KEY (kind, key)
+	);`
+
+	// 下发的人机验证及其结果，outcome 为空表示尚未提交结果
+	challengesTable := `
+	CREATE TABLE IF NOT EXISTS challenges (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		site_id TEXT NOT NULL,
+		fingerprint_hash TEXT NOT NULL,
+		ip_range TEXT NOT NULL,
+		reason_codes TEXT NOT NULL,
+		type TEXT NOT NULL,
+		outcome TEXT NOT NULL,
+		issued_at DATETIME NOT NULL,
+		resolved_at DATETIME
+	);`
+
+	// 执行证明种子的首次使用，用于发现复制到其他指纹的证明
+	proofSeedsTable := `
+	CREATE TABLE IF NOT EXISTS proof_seeds (
+		seed TEXT PRIMARY KEY,
+		fingerprint_hash TEXT NOT NULL,
+		first_used DATETIME NOT NULL
+	);`
+
+	// 双Canvas校验中种子与种子图案哈希的对应，用于发现不可复现或被重放到其他种子的结果
+	canvasRendersTable := `
+	CREATE TABLE IF NOT EXISTS canvas_renders (
+		seed TEXT NOT NULL,
+		seeded_hash TEXT NOT NULL,
+		first_seen DATETIME NOT NULL,
+		PRIMARY KEY (seed, seeded_hash)
+	);`
+
+	// 账号的打字节奏档案，只保存汇总特征的滑动平均
+	typingProfilesTable := `
+	CREATE TABLE IF NOT EXISTS typing_profiles (
+		site_id TEXT NOT NULL,
+		account_id TEXT NOT NULL,
+		samples INTEGER NOT NULL,
+		mean_interval REAL NOT NULL,
+		variation REAL NOT NULL,
+		backspace_rate REAL NOT NULL,
+		updated_at DATETIME NOT NULL,
+		PRIMARY KEY (site_id, account_id)
+	);`
+
+	// 被拒绝的提交，同一IP以相同原因提交的相同请求体只保留一条并累计次数
+	quarantineTable := `
+	CREATE TABLE IF NOT EXISTS quarantine (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		site_id TEXT NOT NULL,
+		ip_address TEXT NOT NULL,
+		reason TEXT NOT NULL,
+		detail TEXT NOT NULL,
+		headers TEXT NOT NULL,
+		body TEXT NOT NULL,
+		body_encoding TEXT NOT NULL,
+		body_size INTEGER NOT NULL,
+		body_hash TEXT NOT NULL,
+		count INTEGER NOT NULL DEFAULT 1,
+		first_seen DATETIME NOT NULL,
+		last_seen DATETIME NOT NULL,
+		UNIQUE (ip_address, reason, body_hash)
+	);`
+
+	// 多实例部署时后台任务的租约，同一时刻只有一个实例持有
+	leasesTable := `
+	CREATE TABLE IF NOT EXISTS leases (
+		name TEXT PRIMARY KEY,
+		holder TEXT NOT NULL,
+		expires_at DATETIME NOT NULL
+	);`
+
+	// 运行时可修改的全局设置，value 为JSON
+	settingsTable := `
+	CREATE TABLE IF NOT EXISTS settings (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL,
+		updated_at DATETIME NOT NULL
+	);`
+
+	// 管理操作的审计记录
+	auditLogTable := `
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		actor TEXT NOT NULL,
+		action TEXT NOT NULL,
+		target TEXT NOT NULL,
+		before TEXT NOT NULL,
+		after TEXT NOT NULL,
+		created_at DATETIME NOT NULL
+	);`
+
+	// 评分表达式规则集的各个版本，当前生效的版本号保存在 settings 表中
+	ruleSetsTable := `
+	CREATE TABLE IF NOT EXISTS rule_sets (
+		version INTEGER PRIMARY KEY AUTOINCREMENT,
+		rules TEXT NOT NULL,
+		comment TEXT NOT NULL DEFAULT '',
+		actor TEXT NOT NULL,
+		created_at DATETIME NOT NULL
+	);`
+
+	// 通过管理API配置的告警webhook，kinds 为JSON数组
+	webhooksTable := `
+	CREATE TABLE IF NOT EXISTS webhooks (
+		name TEXT PRIMARY KEY,
+		url TEXT NOT NULL,
+		kinds TEXT NOT NULL DEFAULT '[]',
+		enabled BOOLEAN NOT NULL DEFAULT 1,
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL
+	);`
+
+	// 访客的组件哈希历史，只在组件哈希变化（或首次出现）时记录
+	componentHistoryTable := `
+	CREATE TABLE IF NOT EXISTS component_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		visitor_id TEXT NOT NULL,
+		component TEXT NOT NULL,
+		hash TEXT NOT NULL,
+		fingerprint_hash TEXT NOT NULL,
+		observed_at DATETIME NOT NULL
+	);`
+
+	// 访客Cookie与指纹的关联
+	visitorFingerprintsTable := `
+	CREATE TABLE IF NOT EXISTS visitor_fingerprints (
+		visitor_id TEXT NOT NULL,
+		fingerprint_hash TEXT NOT NULL,
+		first_seen DATETIME NOT NULL,
+		last_seen DATETIME NOT NULL,
+		submissions INTEGER NOT NULL DEFAULT 1,
+		PRIMARY KEY (visitor_id, fingerprint_hash)
+	);`
+
+	if err := d.checkShards(); err != nil {
+		return err
+	}
+	if err := d.createTable("fingerprints", fingerprintTable); err != nil {
 		return fmt.Errorf("failed to create fingerprints table: %w", err)
 	}
 
-	if _, err := d.DB.Exec(analysisTable); err != nil {
+	if err := d.createTable("analysis", analysisTable); err != nil {
 		return fmt.Errorf("failed to create analysis table: %w", err)
 	}
 
+	if _, err := d.DB.Exec(componentsTable); err != nil {
+		return fmt.Errorf("failed to create fingerprint_components table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(componentHistoryTable); err != nil {
+		return fmt.Errorf("failed to create component_history table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(visitorFingerprintsTable); err != nil {
+		return fmt.Errorf("failed to create visitor_fingerprints table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(canvasLSHTable); err != nil {
+		return fmt.Errorf("failed to create canvas_lsh table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(farmsTable); err != nil {
+		return fmt.Errorf("failed to create farms table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(trafficTable); err != nil {
+		return fmt.Errorf("failed to create traffic_stats table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(scoreStatsTable); err != nil {
+		return fmt.Errorf("failed to create score_stats table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(crawlerStatsTable); err != nil {
+		return fmt.Errorf("failed to create crawler_stats table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(robotsAuditTable); err != nil {
+		return fmt.Errorf("failed to create robots_audit table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(robotsViolationsTable); err != nil {
+		return fmt.Errorf("failed to create robots_violations table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(accessLogClientsTable); err != nil {
+		return fmt.Errorf("failed to create access_log_clients table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(ja4StatsTable); err != nil {
+		return fmt.Errorf("failed to create ja4_stats table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(replicationLogTable); err != nil {
+		return fmt.Errorf("failed to create replication_log table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(replicationPeersTable); err != nil {
+		return fmt.Errorf("failed to create replication_peers table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(replicationTombstonesTable); err != nil {
+		return fmt.Errorf("failed to create replication_tombstones table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(anomaliesTable); err != nil {
+		return fmt.Errorf("failed to create anomalies table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(sitePoliciesTable); err != nil {
+		return fmt.Errorf("failed to create site_policies table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(eventsTable); err != nil {
+		return fmt.Errorf("failed to create events table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(accountDevicesTable); err != nil {
+		return fmt.Errorf("failed to create account_devices table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(accountSignalsTable); err != nil {
+		return fmt.Errorf("failed to create account_signals table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(blocklistTable); err != nil {
+		return fmt.Errorf("failed to create blocklist table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(detectionHitsTable); err != nil {
+		return fmt.Errorf("failed to create detection hits table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(stuffingTable); err != nil {
+		return fmt.Errorf("failed to create credential_stuffing table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(tokenKeysTable); err != nil {
+		return fmt.Errorf("failed to create token_keys table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(tokenRevocationsTable); err != nil {
+		return fmt.Errorf("failed to create token_revocations table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(labelsTable); err != nil {
+		return fmt.Errorf("failed to create labels table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(threatFeedsTable); err != nil {
+		return fmt.Errorf("failed to create threat_feeds table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(threatIntelTable); err != nil {
+		return fmt.Errorf("failed to create threat_intel table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(ipReputationTable); err != nil {
+		return fmt.Errorf("failed to create ip_reputation table: %w", err)
+	}
+	if _, err := d.DB.Exec(fingerprintASNsTable); err != nil {
+		return fmt.Errorf("failed to create fingerprint_asns table: %w", err)
+	}
+	if _, err := d.DB.Exec(subnetVelocityTable); err != nil {
+		return fmt.Errorf("failed to create subnet_velocity table: %w", err)
+	}
+	if _, err := d.DB.Exec(subnetAddressesTable); err != nil {
+		return fmt.Errorf("failed to create subnet_addresses table: %w", err)
+	}
+	if _, err := d.DB.Exec(navigationTable); err != nil {
+		return fmt.Errorf("failed to create navigation_log table: %w", err)
+	}
+	if _, err := d.DB.Exec(pageViewsTable); err != nil {
+		return fmt.Errorf("failed to create page_views table: %w", err)
+	}
+	if _, err := d.DB.Exec(scrapingTable); err != nil {
+		return fmt.Errorf("failed to create scraping_detections table: %w", err)
+	}
+	if _, err := d.DB.Exec(sessionActivityTable); err != nil {
+		return fmt.Errorf("failed to create session_activity table: %w", err)
+	}
+	if _, err := d.DB.Exec(sessionAlertsTable); err != nil {
+		return fmt.Errorf("failed to create session_alerts table: %w", err)
+	}
+	if _, err := d.DB.Exec(challengesTable); err != nil {
+		return fmt.Errorf("failed to create challenges table: %w", err)
+	}
+	if _, err := d.DB.Exec(proofSeedsTable); err != nil {
+		return fmt.Errorf("failed to create proof_seeds table: %w", err)
+	}
+	if _, err := d.DB.Exec(canvasRendersTable); err != nil {
+		return fmt.Errorf("failed to create canvas_renders table: %w", err)
+	}
+	if _, err := d.DB.Exec(typingProfilesTable); err != nil {
+		return fmt.Errorf("failed to create typing_profiles table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(quarantineTable); err != nil {
+		return fmt.Errorf("failed to create quarantine table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(leasesTable); err != nil {
+		return fmt.Errorf("failed to create leases table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(settingsTable); err != nil {
+		return fmt.Errorf("failed to create settings table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(auditLogTable); err != nil {
+		return fmt.Errorf("failed to create audit_log table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(ruleSetsTable); err != nil {
+		return fmt.Errorf("failed to create rule_sets table: %w", err)
+	}
+
+	if _, err := d.DB.Exec(webhooksTable); err != nil {
+		return fmt.Errorf("failed to create webhooks table: %w", err)
+	}
+
+	if err := d.migrate(); err != nil {
+		return err
+	}
+	if d.Shards > 1 {
+		if err := d.moveIntoShards(); err != nil {
+			return fmt.Errorf("failed to move tables into shards: %w", err)
+		}
+	}
+
 	log.Println("Database tables created successfully")
 	return nil
 }
 
+// FingerprintRef 保存指纹哈希的列，Filter 非空时只有满足条件的行保存的是指纹哈希
+type FingerprintRef struct {
+	Table  string
+	Column string
+	Filter string
+}
+
+// FingerprintRefs 除 fingerprints 表外所有保存指纹哈希的列，重新计算哈希时在同一事务中全部迁移到新哈希。
+// 新增含 fingerprint_hash 列的表须同时加入此列表，否则启动时迁移检查失败
+var FingerprintRefs = []FingerprintRef{
+	{Table: "analysis", Column: "fingerprint_hash"},
+	{Table: "fingerprint_components", Column: "fingerprint_hash"},
+	{Table: "canvas_lsh", Column: "fingerprint_hash"},
+	{Table: "component_history", Column: "fingerprint_hash"},
+	{Table: "visitor_fingerprints", Column: "fingerprint_hash"},
+	{Table: "access_log_clients", Column: "fingerprint_hash"},
+	{Table: "events", Column: "fingerprint_hash"},
+	{Table: "account_devices", Column: "fingerprint_hash"},
+	{Table: "account_signals", Column: "fingerprint_hash"},
+	{Table: "detection_hits", Column: "fingerprint_hash"},
+	{Table: "labels", Column: "fingerprint_hash"},
+	{Table: "fingerprint_asns", Column: "fingerprint_hash"},
+	{Table: "subnet_addresses", Column: "fingerprint_hash"},
+	{Table: "navigation_log", Column: "fingerprint_hash"},
+	{Table: "page_views", Column: "fingerprint_hash"},
+	{Table: "scraping_detections", Column: "fingerprint_hash"},
+	{Table: "challenges", Column: "fingerprint_hash"},
+	{Table: "proof_seeds", Column: "fingerprint_hash"},
+	{Table: "blocklist", Column: "key", Filter: "kind = 'fingerprint'"},
+	{Table: "token_revocations", Column: "key", Filter: "kind = 'fingerprint'"},
+	{Table: "session_activity", Column: "key", Filter: "kind = 'fingerprint'"},
+	{Table: "session_alerts", Column: "key", Filter: "kind = 'fingerprint'"},
+}
+
+// checkFingerprintRefs 确认 FingerprintRefs 中的列都存在，且含 fingerprint_hash 列的表都已列入
+func (d *Database) checkFingerprintRefs() error {
+	listed := map[string]bool{"fingerprints": true}
+	for _, ref := range FingerprintRefs {
+		ok, err := d.columnExists(d.physicalTables(ref.Table)[0], ref.Column)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("fingerprint reference %s.%s does not exist", ref.Table, ref.Column)
+		}
+		listed[ref.Table] = true
+	}
+
+	rows, err := d.DB.Query(`
+		SELECT m.name FROM sqlite_master m, pragma_table_info(m.name) p
+		WHERE m.type = 'table' AND p.name = 'fingerprint_hash'`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return err
+		}
+		if !listed[table] {
+			return fmt.Errorf("table %s has fingerprint_hash but is not in FingerprintRefs", table)
+		}
+	}
+	return rows.Err()
+}
+
+// schemaColumns 建表之后新增的列，启动时自动补齐到已有数据库
+var schemaColumns = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{"fingerprints", "webrtc_local_ips", "TEXT NOT NULL DEFAULT '[]'"},
+	{"fingerprints", "webrtc_public_ips", "TEXT NOT NULL DEFAULT '[]'"},
+	{"fingerprints", "hardware_concurrency", "INTEGER NOT NULL DEFAULT 0"},
+	{"fingerprints", "device_memory", "REAL NOT NULL DEFAULT 0"},
+	{"fingerprints", "media_audio_inputs", "INTEGER NOT NULL DEFAULT -1"},
+	{"fingerprints", "media_audio_outputs", "INTEGER NOT NULL DEFAULT -1"},
+	{"fingerprints", "media_video_inputs", "INTEGER NOT NULL DEFAULT -1"},
+	{"fingerprints", "battery_supported", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"fingerprints", "battery_charging", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"fingerprints", "battery_level", "REAL NOT NULL DEFAULT -1"},
+	{"fingerprints", "sensors_collected", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"fingerprints", "has_accelerometer", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"fingerprints", "has_gyroscope", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"fingerprints", "color_depth", "INTEGER NOT NULL DEFAULT 0"},
+	{"fingerprints", "device_pixel_ratio", "REAL NOT NULL DEFAULT 0"},
+	{"fingerprints", "avail_width", "INTEGER NOT NULL DEFAULT 0"},
+	{"fingerprints", "avail_height", "INTEGER NOT NULL DEFAULT 0"},
+	{"fingerprints", "outer_width", "INTEGER NOT NULL DEFAULT -1"},
+	{"fingerprints", "outer_height", "INTEGER NOT NULL DEFAULT -1"},
+	{"fingerprints", "math_hash", "TEXT NOT NULL DEFAULT ''"},
+	{"fingerprints", "feature_bits", "INTEGER NOT NULL DEFAULT 0"},
+	{"fingerprints", "feature_count", "INTEGER NOT NULL DEFAULT 0"},
+	{"fingerprints", "font_metrics_hash", "TEXT NOT NULL DEFAULT ''"},
+	{"fingerprints", "adblock_baits", "INTEGER NOT NULL DEFAULT 0"},
+	{"fingerprints", "adblock_blocked", "INTEGER NOT NULL DEFAULT -1"},
+	{"fingerprints", "extensions", "TEXT NOT NULL DEFAULT '[]'"},
+	{"fingerprints", "site_id", "TEXT NOT NULL DEFAULT ''"},
+	{"fingerprints", "canvas_phash", "TEXT NOT NULL DEFAULT ''"},
+	{"fingerprints", "canvas_noise", "REAL NOT NULL DEFAULT -1"},
+	{"fingerprints", "audio_sum", "REAL NOT NULL DEFAULT 0"},
+	{"fingerprints", "audio_noise", "REAL NOT NULL DEFAULT -1"},
+	{"fingerprints", "hash_v2", "TEXT NOT NULL DEFAULT ''"},
+	{"fingerprints", "fingerprint_version", "INTEGER NOT NULL DEFAULT 0"},
+	{"fingerprints", "visitor_id", "TEXT NOT NULL DEFAULT ''"},
+	{"fingerprints", "country", "TEXT NOT NULL DEFAULT ''"},
+	{"fingerprints", "canvas_simhash", "INTEGER NOT NULL DEFAULT 0"},
+	{"site_policies", "event_thresholds", "TEXT NOT NULL DEFAULT '{}'"},
+	{"events", "account_id", "TEXT NOT NULL DEFAULT ''"},
+	{"events", "country", "TEXT NOT NULL DEFAULT ''"},
+	{"events", "ip_range", "TEXT NOT NULL DEFAULT ''"},
+	{"fingerprints", "deleted_at", "DATETIME"},
+	{"analysis", "deleted_at", "DATETIME"},
+	{"analysis", "reason_codes", "TEXT NOT NULL DEFAULT '[]'"},
+	{"analysis", "privacy_mode", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"site_policies", "country_policy", "TEXT NOT NULL DEFAULT ''"},
+	{"fingerprints", "accept_language", "TEXT NOT NULL DEFAULT ''"},
+	{"analysis", "emulator_suspected", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"site_policies", "actions", "TEXT NOT NULL DEFAULT '{}'"},
+	{"fingerprints", "missing_components", "TEXT NOT NULL DEFAULT '[]'"},
+	{"fingerprints", "webgl_params", "TEXT NOT NULL DEFAULT ''"},
+	{"fingerprints", "gpu_family", "TEXT NOT NULL DEFAULT ''"},
+	{"detection_hits", "visitor_id", "TEXT NOT NULL DEFAULT ''"},
+	{"analysis", "ml_score", "REAL"},
+	{"analysis", "ml_model", "TEXT NOT NULL DEFAULT ''"},
+	{"analysis", "outlier_score", "REAL"},
+	{"analysis", "outlier_features", "TEXT NOT NULL DEFAULT ''"},
+	{"site_policies", "crawler_policy", "TEXT NOT NULL DEFAULT ''"},
+	{"access_log_clients", "cdn_bot_score", "INTEGER"},
+}
+
+// schemaIndexes 查询用到的索引
+var schemaIndexes = []string{
+	"CREATE INDEX IF NOT EXISTS idx_fingerprints_math_hash ON fingerprints (math_hash)",
+	"CREATE INDEX IF NOT EXISTS idx_fingerprints_canvas_phash ON fingerprints (canvas_phash)",
+	"CREATE INDEX IF NOT EXISTS idx_fingerprint_components_hash ON fingerprint_components (component, hash)",
+	"CREATE INDEX IF NOT EXISTS idx_component_history_visitor ON component_history (visitor_id, component, id)",
+	"CREATE INDEX IF NOT EXISTS idx_component_history_fingerprint ON component_history (fingerprint_hash)",
+	"CREATE INDEX IF NOT EXISTS idx_visitor_fingerprints_fingerprint ON visitor_fingerprints (fingerprint_hash, last_seen)",
+	"CREATE INDEX IF NOT EXISTS idx_canvas_lsh_fingerprint ON canvas_lsh (fingerprint_hash)",
+	"CREATE INDEX IF NOT EXISTS idx_events_fingerprint ON events (fingerprint_hash)",
+	"CREATE INDEX IF NOT EXISTS idx_events_site_type ON events (site_id, event_type, created_at)",
+	"CREATE INDEX IF NOT EXISTS idx_events_account ON events (site_id, account_id, created_at)",
+	"CREATE INDEX IF NOT EXISTS idx_events_login_fingerprint ON events (site_id, event_type, outcome, fingerprint_hash, created_at)",
+	"CREATE INDEX IF NOT EXISTS idx_events_login_ip_range ON events (site_id, event_type, outcome, ip_range, created_at)",
+	"CREATE INDEX IF NOT EXISTS idx_account_devices_fingerprint ON account_devices (site_id, fingerprint_hash, last_seen)",
+	"CREATE INDEX IF NOT EXISTS idx_account_signals_account ON account_signals (site_id, account_id, created_at)",
+	"CREATE INDEX IF NOT EXISTS idx_navigation_fingerprint ON navigation_log (fingerprint_hash, created_at)",
+	"CREATE INDEX IF NOT EXISTS idx_navigation_subnet ON navigation_log (subnet, created_at)",
+	"CREATE INDEX IF NOT EXISTS idx_navigation_site ON navigation_log (site_id, created_at)",
+	"CREATE INDEX IF NOT EXISTS idx_page_views_fingerprint ON page_views (fingerprint_hash, created_at)",
+	"CREATE INDEX IF NOT EXISTS idx_robots_violations_last_seen ON robots_violations (last_seen)",
+	"CREATE INDEX IF NOT EXISTS idx_access_log_clients_pending ON access_log_clients (pending, last_seen)",
+	"CREATE INDEX IF NOT EXISTS idx_access_log_clients_last_seen ON access_log_clients (last_seen)",
+	"CREATE INDEX IF NOT EXISTS idx_fingerprints_ip_address ON fingerprints (ip_address, user_agent)",
+	"CREATE INDEX IF NOT EXISTS idx_fingerprints_created_at ON fingerprints (created_at)",
+	"CREATE INDEX IF NOT EXISTS idx_analysis_last_seen ON analysis (last_seen)",
+	"CREATE INDEX IF NOT EXISTS idx_ja4_stats_hour ON ja4_stats (hour)",
+	"CREATE INDEX IF NOT EXISTS idx_replication_log_created_at ON replication_log (created_at)",
+	"CREATE INDEX IF NOT EXISTS idx_replication_tombstones_deleted_at ON replication_tombstones (deleted_at)",
+	"CREATE INDEX IF NOT EXISTS idx_access_log_clients_ip_address ON access_log_clients (ip_address, user_agent, day)",
+	"CREATE INDEX IF NOT EXISTS idx_scraping_fingerprint ON scraping_detections (fingerprint_hash, created_at)",
+	"CREATE INDEX IF NOT EXISTS idx_session_activity_last_seen ON session_activity (last_seen)",
+	"CREATE INDEX IF NOT EXISTS idx_challenges_fingerprint ON challenges (fingerprint_hash, issued_at)",
+	"CREATE INDEX IF NOT EXISTS idx_challenges_ip_range ON challenges (ip_range, issued_at)",
+	"CREATE INDEX IF NOT EXISTS idx_challenges_issued ON challenges (issued_at)",
+	"CREATE INDEX IF NOT EXISTS idx_quarantine_last_seen ON quarantine (last_seen)",
+	"CREATE INDEX IF NOT EXISTS idx_canvas_renders_hash ON canvas_renders (seeded_hash)",
+	"CREATE INDEX IF NOT EXISTS idx_canvas_renders_first_seen ON canvas_renders (first_seen)",
+	"CREATE INDEX IF NOT EXISTS idx_detection_hits_fingerprint ON detection_hits (fingerprint_hash, created_at)",
+	"CREATE INDEX IF NOT EXISTS idx_detection_hits_ip ON detection_hits (ip_address, created_at)",
+	"CREATE INDEX IF NOT EXISTS idx_detection_hits_ip_range ON detection_hits (ip_range, created_at)",
+	"CREATE INDEX IF NOT EXISTS idx_detection_hits_visitor ON detection_hits (visitor_id, created_at)",
+	"CREATE INDEX IF NOT EXISTS idx_detection_hits_created ON detection_hits (created_at)",
+	"CREATE INDEX IF NOT EXISTS idx_blocklist_expires ON blocklist (expires_at)",
+}
+
+// migrate 为已有数据库补充新增的列和索引
+func (d *Database) migrate() error {
+	for _, col := range schemaColumns {
+		for _, table := range d.physicalTables(col.table) {
+			if err := d.addColumnIfMissing(table, col.column, col.definition); err != nil {
+				return fmt.Errorf("failed to migrate %s.%s: %w", table, col.column, err)
+			}
+		}
+	}
+	for _, index := range schemaIndexes {
+		for _, stmt := range d.indexStatements(index) {
+			if _, err := d.DB.Exec(stmt); err != nil {
+				return fmt.Errorf("failed to create index: %w", err)
+			}
+		}
+	}
+	return d.checkFingerprintRefs()
+}
+
+// addColumnIfMissing 列不存在时执行 ALTER TABLE ADD COLUMN，table 可带库名
+func (d *Database) addColumnIfMissing(table, column, definition string) error {
+	ok, err := d.columnExists(table, column)
+	if err != nil || ok {
+		return err
+	}
+	_, err = d.DB.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
+// columnExists 判断表中是否有该列，table 可带库名
+func (d *Database) columnExists(table, column string) (bool, error) {
+	rows, err := d.DB.Query(tableInfoQuery(table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			colType    string
+			notNull    bool
+			defaultVal sql.NullString
+			primaryKey int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultVal, &primaryKey); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// OpenReadReplica 以只读方式打开由外部复制（如 LiteFS、Litestream）维护的数据库副本，之后的统计和列表查询改走副本
+// 副本可能落后于主库，不用于写入后立即读取的场景
+func (d *Database) OpenReadReplica(path string) error {
+	if d.Shards > 1 {
+		return errors.New("read replicas are not supported with sharded storage")
+	}
+	replica, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("failed to open read replica: %w", err)
+	}
+	if err := replica.Ping(); err != nil {
+		replica.Close()
+		return fmt.Errorf("failed to ping read replica: %w", err)
+	}
+	d.Read = replica
+	return nil
+}
+
 // Close 关闭数据库连接
 func (d *Database) Close() error {
+	if d.Read != d.DB {
+		d.Read.Close()
+	}
 	return d.DB.Close()
 }
 
@@ -126,20 +1141,36 @@ func GenerateCanvasHash(canvasData string) string {
 	return hex.EncodeToString(hash[:])
 }
 
+// GenerateListHash 按顺序生成字符串列表的哈希，列表为空时返回空字符串
+func GenerateListHash(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	hash := sha256.Sum256([]byte(strings.Join(values, "|")))
+	return hex.EncodeToString(hash[:])
+}
+
+// GenerateKeyedHash 使用密钥生成HMAC-SHA256哈希
+func GenerateKeyedHash(secret, value string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 // processCanvasData 处理Canvas数据去除噪点
 func processCanvasData(data string) string {
 	// 简单的去噪处理示例
 	// 实际应用中可能需要更复杂的算法
-	
+
 	// 1. 移除可能的随机噪点（假设噪点会导致数据长度异常）
 	if len(data) > 10000 { // 异常长的数据可能包含噪点
 		data = data[:10000]
 	}
-	
+
 	// 2. 标准化处理
 	data = strings.TrimSpace(data)
 	data = strings.ToLower(data)
-	
+
 	return data
 }
 
@@ -164,24 +1195,79 @@ func JSONToStringSlice(jsonStr string) []string {
 	return slice
 }
 
-// GetClientIP 获取客户端IP地址
+// GetClientIP 获取客户端IP地址，结果经 NormalizeIP 规范化
 func GetClientIP(xff, realIP, remoteAddr string) string {
 	if xff != "" {
 		// X-Forwarded-For可能包含多个IP，取第一个
 		ips := strings.Split(xff, ",")
 		if len(ips) > 0 {
-			return strings.TrimSpace(ips[0])
+			return NormalizeIP(ips[0])
 		}
 	}
-	
+
 	if realIP != "" {
-		return realIP
+		return NormalizeIP(realIP)
 	}
-	
+
 	// 从RemoteAddr中提取IP
-	if idx := strings.LastIndex(remoteAddr, ":"); idx != -1 {
-		return remoteAddr[:idx]
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return NormalizeIP(host)
+	}
+
+	return NormalizeIP(remoteAddr)
+}
+
+// NormalizeIP 将IP地址转为规范形式：去掉方括号、端口和IPv6区域标识，IPv4映射地址转为IPv4，
+// IPv6使用小写的压缩写法；无法解析时返回去掉空白的原值
+func NormalizeIP(ip string) string {
+	ip = strings.TrimSpace(ip)
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	ip = strings.TrimSuffix(strings.TrimPrefix(ip, "["), "]")
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	return addr.WithZone("").Unmap().String()
+}
+
+// IPSubnet 返回IP所在的聚合网段：IPv4为/24，IPv6为/64（同一客户端常在/64内轮换地址），
+// 无法解析时 ok 为 false
+func IPSubnet(ip string) (netip.Prefix, bool) {
+	addr, err := netip.ParseAddr(NormalizeIP(ip))
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	bits := 64
+	if addr.Is4() {
+		bits = 24
+	}
+	return netip.PrefixFrom(addr, bits).Masked(), true
+}
+
+// IPRange 返回IP所在的网段：IPv4为/24，IPv6为/48，无法解析时返回原值
+func IPRange(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ip
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: parsed.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+}
+
+// NormalizeCountry 规范化ISO 3166-1二位国家代码，非法取值（包括CDN用于未知来源的 XX、T1）返回空字符串
+func NormalizeCountry(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 2 || code == "XX" || code == "T1" {
+		return ""
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return ""
+		}
 	}
-	
-	return remoteAddr
+	return code
 }
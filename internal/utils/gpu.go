@@ -0,0 +1,43 @@
+package utils
+
+import "strings"
+
+// GPU家族
+const (
+	GPUIntel    = "intel"
+	GPUNVIDIA   = "nvidia"
+	GPUAMD      = "amd"
+	GPUApple    = "apple"
+	GPUAdreno   = "adreno"
+	GPUMali     = "mali"
+	GPUPowerVR  = "powervr"
+	GPUSoftware = "software"
+)
+
+// gpuFamilyKeywords WebGL渲染器名关键字（小写）对应的GPU家族，按顺序匹配：
+// 软件渲染器先于硬件厂商匹配（ANGLE 的 SwiftShader 渲染器名中含有 Google 等厂商名）
+var gpuFamilyKeywords = []struct {
+	keyword string
+	family  string
+}{
+	{"swiftshader", GPUSoftware}, {"llvmpipe", GPUSoftware}, {"softpipe", GPUSoftware},
+	{"basic render driver", GPUSoftware}, {"software", GPUSoftware},
+	{"nvidia", GPUNVIDIA}, {"geforce", GPUNVIDIA}, {"quadro", GPUNVIDIA}, {"tegra", GPUNVIDIA},
+	{"radeon", GPUAMD}, {"amd", GPUAMD}, {"ati technologies", GPUAMD},
+	{"intel", GPUIntel},
+	{"adreno", GPUAdreno},
+	{"mali", GPUMali},
+	{"powervr", GPUPowerVR},
+	{"apple", GPUApple},
+}
+
+// GPUFamily 从WebGL渲染器名判断GPU家族，渲染器被隐藏（如 Firefox 抗指纹模式）或无法识别时返回空字符串
+func GPUFamily(renderer string) string {
+	lower := strings.ToLower(renderer)
+	for _, k := range gpuFamilyKeywords {
+		if strings.Contains(lower, k.keyword) {
+			return k.family
+		}
+	}
+	return ""
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/zeebo/blake3"
+)
+
+// 子哈希算法
+const (
+	HashSHA256 = "sha256"
+	HashXXHash = "xxhash"
+	HashBLAKE3 = "blake3"
+)
+
+// SubHasher Canvas/WebGL/音频子哈希的计算器
+// 子哈希只用于去重和同一访客的跨访问比较，不需要抗碰撞，高吞吐时可换用更快的算法；
+// 主指纹哈希始终使用SHA-256
+type SubHasher struct {
+	sum func(data []byte) string
+}
+
+// NewSubHasher 创建指定算法的子哈希计算器
+func NewSubHasher(algorithm string) (*SubHasher, error) {
+	switch algorithm {
+	case HashSHA256, "":
+		return &SubHasher{sum: func(data []byte) string {
+			hash := sha256.Sum256(data)
+			return hex.EncodeToString(hash[:])
+		}}, nil
+	case HashXXHash:
+		return &SubHasher{sum: func(data []byte) string {
+			return fmt.Sprintf("%016x", xxhash.Sum64(data))
+		}}, nil
+	case HashBLAKE3:
+		return &SubHasher{sum: func(data []byte) string {
+			hash := blake3.Sum256(data)
+			return hex.EncodeToString(hash[:])
+		}}, nil
+	default:
+		return nil, fmt.Errorf("unknown hash algorithm %q", algorithm)
+	}
+}
+
+// Canvas 生成Canvas子哈希（去噪处理），SHA-256时与 GenerateCanvasHash 一致
+func (h *SubHasher) Canvas(canvasData string) string {
+	return h.sum([]byte(processCanvasData(canvasData)))
+}
+
+// Field 生成单个字段的子哈希，SHA-256时与 GenerateFingerprintHash(map[name]value) 一致
+func (h *SubHasher) Field(name, value string) string {
+	return h.sum([]byte(name + ":" + value))
+}
@@ -0,0 +1,360 @@
+package utils
+
+import (
+	"container/heap"
+	"encoding/gob"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// HNSW 分层可导航小世界图（Hierarchical Navigable Small World），用于向量的近似最近邻搜索
+// 更新已存在的ID时旧节点只做删除标记，仍参与图的连通；删除节点过多时在加载时重建
+type HNSW struct {
+	mu             sync.RWMutex
+	m              int
+	efConstruction int
+	levelMult      float64
+	nodes          []*hnswNode
+	ids            map[string]int
+	entry          int
+	maxLevel       int
+	rng            *rand.Rand
+	dirty          bool
+}
+
+// hnswNode 图中的一个向量节点，Links[l] 为第 l 层的邻居
+type hnswNode struct {
+	ID      string
+	Vector  []float32
+	Links   [][]int
+	Deleted bool
+}
+
+// hnswSnapshot 持久化到磁盘的索引内容
+type hnswSnapshot struct {
+	M              int
+	EfConstruction int
+	Nodes          []*hnswNode
+	Entry          int
+	MaxLevel       int
+}
+
+// VectorMatch 近邻搜索结果
+type VectorMatch struct {
+	ID       string  `json:"fingerprint_hash"`
+	Distance float64 `json:"distance"`
+}
+
+// NewHNSW 创建空索引，m 为每层的邻居数，efConstruction 为建图时的候选集大小
+func NewHNSW(m, efConstruction int) *HNSW {
+	return &HNSW{
+		m:              m,
+		efConstruction: efConstruction,
+		levelMult:      1 / math.Log(float64(m)),
+		ids:            make(map[string]int),
+		entry:          -1,
+		rng:            rand.New(rand.NewSource(1)),
+	}
+}
+
+// Len 返回未删除的节点数
+func (h *HNSW) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.ids)
+}
+
+// distance 欧氏距离的平方
+func distance(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		d := float64(a[i] - b[i])
+		sum += d * d
+	}
+	return sum
+}
+
+// maxLinks 返回某层允许的最大邻居数，第0层为 2m
+func (h *HNSW) maxLinks(level int) int {
+	if level == 0 {
+		return 2 * h.m
+	}
+	return h.m
+}
+
+// Add 插入或更新向量
+func (h *HNSW) Add(id string, vector []float32) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.add(id, vector)
+}
+
+// add 插入向量，调用方持有写锁
+func (h *HNSW) add(id string, vector []float32) {
+	if idx, ok := h.ids[id]; ok {
+		if distance(h.nodes[idx].Vector, vector) == 0 {
+			return
+		}
+		h.nodes[idx].Deleted = true
+		delete(h.ids, id)
+	}
+	h.dirty = true
+
+	level := int(-math.Log(1-h.rng.Float64()) * h.levelMult)
+	node := &hnswNode{ID: id, Vector: vector, Links: make([][]int, level+1)}
+	n := len(h.nodes)
+	h.nodes = append(h.nodes, node)
+	h.ids[id] = n
+
+	if h.entry < 0 {
+		h.entry, h.maxLevel = n, level
+		return
+	}
+
+	ep := h.entry
+	for l := h.maxLevel; l > level; l-- {
+		ep = h.greedy(vector, ep, l)
+	}
+	for l := min(level, h.maxLevel); l >= 0; l-- {
+		candidates := h.searchLayer(vector, ep, h.efConstruction, l)
+		neighbors := candidates
+		if len(neighbors) > h.m {
+			neighbors = neighbors[:h.m]
+		}
+		node.Links[l] = make([]int, 0, len(neighbors))
+		for _, c := range neighbors {
+			node.Links[l] = append(node.Links[l], c.index)
+			h.link(c.index, n, l)
+		}
+		ep = candidates[0].index
+	}
+	if level > h.maxLevel {
+		h.entry, h.maxLevel = n, level
+	}
+}
+
+// link 为节点 from 在第 level 层添加邻居 to，超出上限时只保留最近的邻居
+func (h *HNSW) link(from, to, level int) {
+	node := h.nodes[from]
+	node.Links[level] = append(node.Links[level], to)
+	limit := h.maxLinks(level)
+	if len(node.Links[level]) <= limit {
+		return
+	}
+	candidates := make([]hnswCandidate, len(node.Links[level]))
+	for i, idx := range node.Links[level] {
+		candidates[i] = hnswCandidate{index: idx, dist: distance(node.Vector, h.nodes[idx].Vector)}
+	}
+	sortCandidates(candidates)
+	node.Links[level] = node.Links[level][:0]
+	for _, c := range candidates[:limit] {
+		node.Links[level] = append(node.Links[level], c.index)
+	}
+}
+
+// greedy 在第 level 层从 ep 出发贪心地走向最近的节点
+func (h *HNSW) greedy(vector []float32, ep, level int) int {
+	best := distance(vector, h.nodes[ep].Vector)
+	for changed := true; changed; {
+		changed = false
+		for _, idx := range h.nodes[ep].Links[level] {
+			if d := distance(vector, h.nodes[idx].Vector); d < best {
+				best, ep, changed = d, idx, true
+			}
+		}
+	}
+	return ep
+}
+
+// searchLayer 在第 level 层做宽度为 ef 的最佳优先搜索，返回按距离升序排列的候选
+func (h *HNSW) searchLayer(vector []float32, ep, ef, level int) []hnswCandidate {
+	visited := map[int]bool{ep: true}
+	start := hnswCandidate{index: ep, dist: distance(vector, h.nodes[ep].Vector)}
+	candidates := &candidateHeap{items: []hnswCandidate{start}}
+	results := &candidateHeap{items: []hnswCandidate{start}, max: true}
+
+	for candidates.Len() > 0 {
+		c := heap.Pop(candidates).(hnswCandidate)
+		if c.dist > results.items[0].dist && results.Len() >= ef {
+			break
+		}
+		for _, idx := range h.nodes[c.index].Links[level] {
+			if visited[idx] {
+				continue
+			}
+			visited[idx] = true
+			d := distance(vector, h.nodes[idx].Vector)
+			if results.Len() < ef || d < results.items[0].dist {
+				heap.Push(candidates, hnswCandidate{index: idx, dist: d})
+				heap.Push(results, hnswCandidate{index: idx, dist: d})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	sorted := append([]hnswCandidate(nil), results.items...)
+	sortCandidates(sorted)
+	return sorted
+}
+
+// Search 返回与向量最近的 k 个未删除节点，ef 为搜索宽度（不小于k）
+func (h *HNSW) Search(vector []float32, k, ef int) []VectorMatch {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.entry < 0 || k <= 0 {
+		return nil
+	}
+	if ef < k {
+		ef = k
+	}
+	// 已删除的节点会占用候选位置，按删除比例放大搜索宽度
+	if live := len(h.ids); live > 0 {
+		ef = ef * len(h.nodes) / live
+	}
+
+	ep := h.entry
+	for l := h.maxLevel; l > 0; l-- {
+		ep = h.greedy(vector, ep, l)
+	}
+	var matches []VectorMatch
+	for _, c := range h.searchLayer(vector, ep, ef, 0) {
+		node := h.nodes[c.index]
+		if node.Deleted {
+			continue
+		}
+		matches = append(matches, VectorMatch{ID: node.ID, Distance: math.Sqrt(c.dist)})
+		if len(matches) == k {
+			break
+		}
+	}
+	return matches
+}
+
+// Remove 删除向量：节点只做删除标记，仍参与图的连通
+func (h *HNSW) Remove(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if idx, ok := h.ids[id]; ok {
+		h.nodes[idx].Deleted = true
+		delete(h.ids, id)
+		h.dirty = true
+	}
+}
+
+// Vector 返回ID对应的向量
+func (h *HNSW) Vector(id string) ([]float32, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	idx, ok := h.ids[id]
+	if !ok {
+		return nil, false
+	}
+	return h.nodes[idx].Vector, true
+}
+
+// Save 将索引写入文件（先写临时文件再重命名），索引未变化时跳过
+func (h *HNSW) Save(path string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.dirty {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	snapshot := hnswSnapshot{
+		M:              h.m,
+		EfConstruction: h.efConstruction,
+		Nodes:          h.nodes,
+		Entry:          h.entry,
+		MaxLevel:       h.maxLevel,
+	}
+	if err := gob.NewEncoder(tmp).Encode(&snapshot); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to encode vector index: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	h.dirty = false
+	return nil
+}
+
+// LoadHNSW 从文件加载索引；删除标记的节点超过一半时重建以回收空间
+func LoadHNSW(path string) (*HNSW, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var snapshot hnswSnapshot
+	if err := gob.NewDecoder(f).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode vector index: %w", err)
+	}
+
+	h := NewHNSW(snapshot.M, snapshot.EfConstruction)
+	h.nodes, h.entry, h.maxLevel = snapshot.Nodes, snapshot.Entry, snapshot.MaxLevel
+	for i, node := range h.nodes {
+		if !node.Deleted {
+			h.ids[node.ID] = i
+		}
+	}
+
+	if len(h.ids)*2 < len(h.nodes) {
+		rebuilt := NewHNSW(snapshot.M, snapshot.EfConstruction)
+		for _, node := range h.nodes {
+			if !node.Deleted {
+				rebuilt.add(node.ID, node.Vector)
+			}
+		}
+		return rebuilt, nil
+	}
+	return h, nil
+}
+
+// hnswCandidate 搜索中的候选节点
+type hnswCandidate struct {
+	index int
+	dist  float64
+}
+
+// sortCandidates 按距离升序排序
+func sortCandidates(c []hnswCandidate) {
+	sort.Slice(c, func(i, j int) bool { return c[i].dist < c[j].dist })
+}
+
+// candidateHeap 按距离排序的堆，max 为 true 时为最大堆
+type candidateHeap struct {
+	items []hnswCandidate
+	max   bool
+}
+
+func (h *candidateHeap) Len() int { return len(h.items) }
+func (h *candidateHeap) Less(i, j int) bool {
+	if h.max {
+		return h.items[i].dist > h.items[j].dist
+	}
+	return h.items[i].dist < h.items[j].dist
+}
+func (h *candidateHeap) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *candidateHeap) Push(x interface{}) { h.items = append(h.items, x.(hnswCandidate)) }
+func (h *candidateHeap) Pop() interface{} {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
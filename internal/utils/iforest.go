@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"math"
+	"math/rand"
+)
+
+// eulerGamma 欧拉常数，用于估计二叉搜索树中未成功查找的平均路径长度
+const eulerGamma = 0.5772156649015329
+
+// IsolationForest 孤立森林：随机选择特征和切分点递归划分样本，离群点通常在很少的划分后就被孤立，
+// 平均路径越短异常分数越高。只需要无标注的样本，训练后只读，可并发评分
+type IsolationForest struct {
+	trees      []*iforestNode
+	sampleSize int
+	features   int
+}
+
+// iforestNode 孤立树的节点，叶子节点的 size 为落入该节点的训练样本数；
+// 内部节点的 lo、hi 为到达该节点的训练样本在切分特征上的取值范围
+type iforestNode struct {
+	feature     int
+	split       float64
+	lo, hi      float64
+	left, right *iforestNode
+	size        int
+}
+
+// NewIsolationForest 用样本训练孤立森林：每棵树从样本中无放回抽取 sampleSize 个，
+// 树高限制为 ceil(log2(sampleSize))。样本须等长
+func NewIsolationForest(samples [][]float64, trees, sampleSize int, rng *rand.Rand) *IsolationForest {
+	sampleSize = min(sampleSize, len(samples))
+	f := &IsolationForest{sampleSize: sampleSize}
+	if len(samples) == 0 || sampleSize < 2 {
+		return f
+	}
+	f.features = len(samples[0])
+	maxDepth := int(math.Ceil(math.Log2(float64(sampleSize))))
+	for i := 0; i < trees; i++ {
+		subset := make([][]float64, sampleSize)
+		for j, k := range rng.Perm(len(samples))[:sampleSize] {
+			subset[j] = samples[k]
+		}
+		f.trees = append(f.trees, buildITree(subset, 0, maxDepth, rng))
+	}
+	return f
+}
+
+// buildITree 递归构建孤立树，在样本取值不全相同的特征中随机选择一个，在其取值范围内随机切分
+func buildITree(samples [][]float64, depth, maxDepth int, rng *rand.Rand) *iforestNode {
+	if depth >= maxDepth || len(samples) <= 1 {
+		return &iforestNode{size: len(samples)}
+	}
+
+	features := len(samples[0])
+	for _, feature := range rng.Perm(features) {
+		lo, hi := samples[0][feature], samples[0][feature]
+		for _, s := range samples[1:] {
+			lo = math.Min(lo, s[feature])
+			hi = math.Max(hi, s[feature])
+		}
+		if lo == hi {
+			continue
+		}
+		split := lo + rng.Float64()*(hi-lo)
+		var left, right [][]float64
+		for _, s := range samples {
+			if s[feature] < split {
+				left = append(left, s)
+			} else {
+				right = append(right, s)
+			}
+		}
+		return &iforestNode{
+			feature: feature,
+			split:   split,
+			lo:      lo,
+			hi:      hi,
+			left:    buildITree(left, depth+1, maxDepth, rng),
+			right:   buildITree(right, depth+1, maxDepth, rng),
+		}
+	}
+	// 所有特征的取值都相同，无法再划分
+	return &iforestNode{size: len(samples)}
+}
+
+// averagePathLength n 个样本的二叉搜索树中未成功查找的平均路径长度，用于归一化
+func averagePathLength(n int) float64 {
+	switch {
+	case n <= 1:
+		return 0
+	case n == 2:
+		return 1
+	}
+	return 2*(math.Log(float64(n-1))+eulerGamma) - 2*float64(n-1)/float64(n)
+}
+
+// Trees 返回树的数量，为0时表示样本不足未能训练
+func (f *IsolationForest) Trees() int { return len(f.trees) }
+
+// Score 返回异常分数（0~1，0.5左右为正常，越接近1越异常）和各特征的贡献（和为1）：
+// 每棵树中孤立该样本的路径上用到的特征按 1/路径长度 累加，路径越短的树权重越大。
+// 取值超出节点训练样本范围时在该节点即视为被孤立，否则超出范围的取值会与边界上的样本落在一起，分数偏低
+func (f *IsolationForest) Score(x []float64) (float64, []float64) {
+	contributions := make([]float64, f.features)
+	if len(f.trees) == 0 || len(x) != f.features {
+		return 0, contributions
+	}
+
+	total := 0.0
+	var path []int
+	for _, root := range f.trees {
+		path = path[:0]
+		node := root
+		for node.left != nil {
+			path = append(path, node.feature)
+			if v := x[node.feature]; v < node.lo || v > node.hi {
+				node = &iforestNode{}
+				break
+			}
+			if x[node.feature] < node.split {
+				node = node.left
+			} else {
+				node = node.right
+			}
+		}
+		length := float64(len(path)) + averagePathLength(node.size)
+		total += length
+		if length > 0 {
+			for _, feature := range path {
+				contributions[feature] += 1 / length
+			}
+		}
+	}
+
+	sum := 0.0
+	for _, c := range contributions {
+		sum += c
+	}
+	if sum > 0 {
+		for i := range contributions {
+			contributions[i] /= sum
+		}
+	}
+	mean := total / float64(len(f.trees))
+	return math.Pow(2, -mean/averagePathLength(f.sampleSize)), contributions
+}
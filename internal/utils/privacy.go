@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+)
+
+// NoiseSource 差分隐私噪声源
+// 噪声由密钥和调用方给定的键确定：键相同（同一分组、同一真实计数）时噪声相同，
+// 重复查询无法通过取平均消除噪声
+type NoiseSource struct {
+	secret []byte
+}
+
+// NewNoiseSource 创建噪声源，secret 为空时随机生成
+func NewNoiseSource(secret string) *NoiseSource {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic("failed to generate noise secret: " + err.Error())
+		}
+	}
+	return &NoiseSource{secret: key}
+}
+
+// Laplace 返回尺度为 scale 的拉普拉斯噪声
+func (n *NoiseSource) Laplace(scale float64, key string) float64 {
+	mac := hmac.New(sha256.New, n.secret)
+	mac.Write([]byte(key))
+	sum := mac.Sum(nil)
+	// 取53位映射到 (0, 1) 开区间，再变换为 (-0.5, 0.5)
+	u := (float64(binary.BigEndian.Uint64(sum[:8])>>11)+0.5)/(1<<53) - 0.5
+	sign := 1.0
+	if u < 0 {
+		sign = -1
+	}
+	return -scale * sign * math.Log(1-2*math.Abs(u))
+}
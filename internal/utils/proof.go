@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// proofLanes 执行证明的状态字数
+const proofLanes = 8
+
+// ExecutionProof 按种子计算采集脚本的执行证明，与 static/js/modern-fingerprint.js 中的 computeExecutionProof 逐位一致：
+// 以FNV-1a散列种子，经 murmur3 的终结混合展开为8个32位状态字，再按种子决定的轮数（2048~4095）交叉旋转、乘法混合，
+// 最后输出8个状态字的十六进制。所有运算都在32位无符号整数上进行，对应脚本中的 Math.imul 和 >>>
+func ExecutionProof(seed string) string {
+	h := uint32(0x811c9dc5)
+	for i := 0; i < len(seed); i++ {
+		h = (h ^ uint32(seed[i])) * 0x01000193
+	}
+
+	var lanes [proofLanes]uint32
+	for i := range lanes {
+		h = (h ^ h>>16) * 0x85ebca6b
+		h = (h ^ h>>13) * 0xc2b2ae35
+		h ^= h >> 16
+		lanes[i] = h
+	}
+
+	rounds := 2048 + int(lanes[0]&2047)
+	for r := 0; r < rounds; r++ {
+		i := r & (proofLanes - 1)
+		j := (lanes[i] >> 3) & (proofLanes - 1)
+		x := lanes[i] ^ bits.RotateLeft32(lanes[j], 7)
+		x = x*0x9e3779b1 + uint32(r)
+		lanes[i] = x ^ x>>11
+	}
+
+	var b strings.Builder
+	for _, v := range lanes {
+		fmt.Fprintf(&b, "%08x", v)
+	}
+	return b.String()
+}
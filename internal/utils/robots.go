@@ -0,0 +1,169 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+)
+
+// MaxRobotsBytes robots.txt 解析的内容上限，RFC 9309 要求爬虫至少解析前 500 KiB
+const MaxRobotsBytes = 500 << 10
+
+// Robots 解析后的 robots.txt（RFC 9309），不包含任何规则时允许所有路径
+type Robots struct {
+	groups []robotsGroup
+}
+
+// robotsGroup 一组 User-agent 行及其后的规则
+type robotsGroup struct {
+	// agents 小写的 User-agent 名称
+	agents []string
+	rules  []robotsRule
+}
+
+// robotsRule 一条 Allow 或 Disallow 规则
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// String 返回规则原文，如 Disallow: /private/
+func (r robotsRule) String() string {
+	if r.allow {
+		return "Allow: " + r.pattern
+	}
+	return "Disallow: " + r.pattern
+}
+
+// ParseRobots 解析 robots.txt，忽略无法识别的行和超出 MaxRobotsBytes 的内容
+func ParseRobots(data []byte) *Robots {
+	if len(data) > MaxRobotsBytes {
+		data = data[:MaxRobotsBytes]
+	}
+	r := &Robots{}
+	var current *robotsGroup
+	// 连续的 User-agent 行属于同一组，规则之后出现的 User-agent 行开始新的一组
+	inRules := true
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 4096), MaxRobotsBytes)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if inRules || current == nil {
+				r.groups = append(r.groups, robotsGroup{})
+				current = &r.groups[len(r.groups)-1]
+				inRules = false
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			if current == nil {
+				continue
+			}
+			inRules = true
+			// 空的 Disallow 表示不禁止任何路径
+			if value == "" {
+				continue
+			}
+			current.rules = append(current.rules, robotsRule{allow: key == "allow", pattern: value})
+		}
+	}
+	return r
+}
+
+// Groups 返回分组数
+func (r *Robots) Groups() int { return len(r.groups) }
+
+// Allowed 判断按 agents 中任意一个名称（不区分大小写）访问 path 是否被允许，并返回生效的规则：
+// 合并所有匹配名称的分组，没有时使用 * 分组；最长的匹配规则生效，长度相同时 Allow 优先。
+// 没有规则匹配时允许，rule 为空
+func (r *Robots) Allowed(agents []string, path string) (allowed bool, rule string) {
+	if path == "" || path[0] != '/' {
+		path = "/" + path
+	}
+	if path == "/robots.txt" {
+		return true, ""
+	}
+	rules := r.rulesFor(agents)
+	var best *robotsRule
+	for i := range rules {
+		rule := &rules[i]
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		if best == nil || len(rule.pattern) > len(best.pattern) ||
+			(len(rule.pattern) == len(best.pattern) && rule.allow && !best.allow) {
+			best = rule
+		}
+	}
+	if best == nil {
+		return true, ""
+	}
+	return best.allow, best.String()
+}
+
+// rulesFor 返回适用于 agents 的规则
+func (r *Robots) rulesFor(agents []string) []robotsRule {
+	var matched, wildcard []robotsRule
+	for _, g := range r.groups {
+		for _, agent := range g.agents {
+			if agent == "*" {
+				wildcard = append(wildcard, g.rules...)
+				continue
+			}
+			if containsFold(agents, agent) {
+				matched = append(matched, g.rules...)
+				break
+			}
+		}
+	}
+	if matched != nil {
+		return matched
+	}
+	return wildcard
+}
+
+// containsFold 判断 list 中是否有与 s 忽略大小写相同的项
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// robotsMatch 按 robots.txt 的路径模式匹配：* 匹配任意字符序列，结尾的 $ 要求匹配到路径末尾，其余为前缀匹配
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	if len(parts) == 1 {
+		return !anchored || rest == ""
+	}
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+	last := parts[len(parts)-1]
+	if anchored {
+		return strings.HasSuffix(rest, last)
+	}
+	return strings.Contains(rest, last)
+}
@@ -0,0 +1,457 @@
+package utils
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// MaxShards 分片数上限：SQLite 单个连接最多附加10个库，冷库归档和备份还要各附加一个
+const MaxShards = 8
+
+// ShardedTables 按指纹哈希分片的表，其余表保存在主库
+var ShardedTables = []string{"fingerprints", "analysis"}
+
+// shardsSetting settings 表中记录分片数的键
+const shardsSetting = "storage_shards"
+
+// ShardPath 返回第 i 个分片文件的路径：在主库文件的扩展名前插入 .shard<i>，主库路径中的查询参数不计入
+func ShardPath(dbPath string, i int) string {
+	path, _, _ := strings.Cut(dbPath, "?")
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s.shard%d%s", strings.TrimSuffix(path, ext), i, ext)
+}
+
+// ShardOf 返回指纹哈希所在的分片：十六进制哈希按首位均分到各分片，其他哈希按FNV哈希取模
+func ShardOf(hash string, shards int) int {
+	if shards <= 1 || hash == "" {
+		return 0
+	}
+	if v, err := strconv.ParseUint(hash[:1], 16, 8); err == nil {
+		return int(v) * shards / 16
+	}
+	h := fnv.New32a()
+	h.Write([]byte(hash))
+	return int(h.Sum32() % uint32(shards))
+}
+
+// shardTable 返回表在分片 i 中的名称。临时触发器只能以不带库名的表名引用附加库中的表，各分片的表名因此互不相同
+func shardTable(table string, i int) string {
+	return fmt.Sprintf("%s_%d", table, i)
+}
+
+// isShardedTable 判断表是否按指纹哈希分片
+func isShardedTable(table string) bool {
+	for _, t := range ShardedTables {
+		if t == table {
+			return true
+		}
+	}
+	return false
+}
+
+// shardConnector 每个新连接都附加分片文件的连接器
+type shardConnector struct {
+	driver *sqlite3.SQLiteDriver
+	dsn    string
+}
+
+func (c *shardConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c *shardConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// openShards 打开主库并在每个连接上附加 shards 个分片文件（库名 shard0、shard1…），注册 shard_of(hash) 函数；
+// routed 为真时再在连接上创建路由的临时视图，建表和迁移期间为假
+func openShards(dbPath, dsn string, shards int, routed bool) *sql.DB {
+	drv := &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if err := conn.RegisterFunc("shard_of", func(hash string) int { return ShardOf(hash, shards) }, true); err != nil {
+				return err
+			}
+			for i := 0; i < shards; i++ {
+				if _, err := conn.Exec(fmt.Sprintf("ATTACH DATABASE ? AS shard%d", i), []driver.Value{ShardPath(dbPath, i)}); err != nil {
+					return fmt.Errorf("failed to attach shard %d: %w", i, err)
+				}
+			}
+			if !routed {
+				return nil
+			}
+			return createShardViews(conn, shards)
+		},
+	}
+	return sql.OpenDB(&shardConnector{driver: drv, dsn: dsn})
+}
+
+// newShardedDatabase 打开按指纹哈希分片的数据库：先在不带视图的连接上建表、迁移并把主库中原有的记录移入分片，
+// 之后的连接以同名临时视图合并各分片，服务层的SQL不必区分分片
+func newShardedDatabase(dbPath, dsn string, shards int) (*Database, error) {
+	setup := openShards(dbPath, dsn, shards, false)
+	if err := setup.Ping(); err != nil {
+		setup.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+	database := &Database{DB: setup, Read: setup, Shards: shards}
+	err := database.CreateTables()
+	setup.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tables: %w", err)
+	}
+
+	db := openShards(dbPath, dsn, shards, true)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+	return &Database{DB: db, Read: db, Shards: shards}, nil
+}
+
+// shardColumn 分片表的一列
+type shardColumn struct {
+	name string
+	// dflt 列的默认值表达式，没有默认值时为空
+	dflt string
+}
+
+// shardColumns 返回分片0中表的列（不含 id），各分片的表结构相同
+func shardColumns(conn *sqlite3.SQLiteConn, table string) ([]shardColumn, error) {
+	rows, err := conn.Query(fmt.Sprintf("PRAGMA shard0.table_info(%s)", shardTable(table, 0)), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []shardColumn
+	values := make([]driver.Value, len(rows.Columns()))
+	for {
+		if err := rows.Next(values); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		column := shardColumn{name: fmt.Sprintf("%s", values[1])}
+		if values[4] != nil {
+			column.dflt = fmt.Sprintf("%s", values[4])
+		}
+		if column.name != "id" {
+			columns = append(columns, column)
+		}
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("shard table %s does not exist", shardTable(table, 0))
+	}
+	return columns, nil
+}
+
+// createShardViews 为每张分片的表创建同名临时视图，以 UNION ALL 合并各分片；
+// 视图上的写入由 INSTEAD OF 触发器按 shard_of(fingerprint_hash) 转到对应分片，
+// 更新改变了指纹哈希所在的分片时从原分片删除并插入新分片。外层语句的 OR REPLACE 同样作用于触发器中的语句
+func createShardViews(conn *sqlite3.SQLiteConn, shards int) error {
+	for _, table := range ShardedTables {
+		columns, err := shardColumns(conn, table)
+		if err != nil {
+			return err
+		}
+		var names, inserted, moved, sets []string
+		for _, c := range columns {
+			names = append(names, c.name)
+			moved = append(moved, "NEW."+c.name)
+			sets = append(sets, fmt.Sprintf("%s = NEW.%s", c.name, c.name))
+			// 视图上的插入不使用表的默认值，省略的列由触发器补上
+			if c.dflt != "" {
+				inserted = append(inserted, fmt.Sprintf("COALESCE(NEW.%s, %s)", c.name, c.dflt))
+			} else {
+				inserted = append(inserted, "NEW."+c.name)
+			}
+		}
+		list := strings.Join(names, ", ")
+
+		selects := make([]string, shards)
+		for i := range selects {
+			selects[i] = fmt.Sprintf("SELECT id, %s FROM %s", list, shardTable(table, i))
+		}
+		stmts := []string{fmt.Sprintf("CREATE TEMP VIEW %s AS %s", table, strings.Join(selects, " UNION ALL "))}
+		for i := 0; i < shards; i++ {
+			t := shardTable(table, i)
+			stmts = append(stmts,
+				fmt.Sprintf(`CREATE TEMP TRIGGER %[1]s_insert INSTEAD OF INSERT ON %[2]s
+					WHEN shard_of(NEW.fingerprint_hash) = %[3]d
+					BEGIN INSERT INTO %[1]s (%[4]s) VALUES (%[5]s); END`,
+					t, table, i, list, strings.Join(inserted, ", ")),
+				fmt.Sprintf(`CREATE TEMP TRIGGER %[1]s_delete INSTEAD OF DELETE ON %[2]s
+					WHEN shard_of(OLD.fingerprint_hash) = %[3]d
+					BEGIN DELETE FROM %[1]s WHERE id = OLD.id; END`,
+					t, table, i),
+				fmt.Sprintf(`CREATE TEMP TRIGGER %[1]s_update INSTEAD OF UPDATE ON %[2]s
+					WHEN shard_of(OLD.fingerprint_hash) = %[3]d AND shard_of(NEW.fingerprint_hash) = %[3]d
+					BEGIN UPDATE %[1]s SET %[4]s WHERE id = OLD.id; END`,
+					t, table, i, strings.Join(sets, ", ")),
+				fmt.Sprintf(`CREATE TEMP TRIGGER %[1]s_move_out INSTEAD OF UPDATE ON %[2]s
+					WHEN shard_of(OLD.fingerprint_hash) = %[3]d AND shard_of(NEW.fingerprint_hash) <> %[3]d
+					BEGIN DELETE FROM %[1]s WHERE id = OLD.id; END`,
+					t, table, i),
+				fmt.Sprintf(`CREATE TEMP TRIGGER %[1]s_move_in INSTEAD OF UPDATE ON %[2]s
+					WHEN shard_of(NEW.fingerprint_hash) = %[3]d AND shard_of(OLD.fingerprint_hash) <> %[3]d
+					BEGIN INSERT INTO %[1]s (%[4]s) VALUES (%[5]s); END`,
+					t, table, i, list, strings.Join(moved, ", ")),
+			)
+		}
+		for _, stmt := range stmts {
+			if _, err := conn.Exec(stmt, nil); err != nil {
+				return fmt.Errorf("failed to route %s: %w", table, err)
+			}
+		}
+	}
+	return nil
+}
+
+// physicalTables 返回表的实际位置：分片的表为各分片中带库名的表，其余为表名本身
+func (d *Database) physicalTables(table string) []string {
+	if d.Shards <= 1 || !isShardedTable(table) {
+		return []string{table}
+	}
+	tables := make([]string, d.Shards)
+	for i := range tables {
+		tables[i] = fmt.Sprintf("shard%d.%s", i, shardTable(table, i))
+	}
+	return tables
+}
+
+// createTable 执行建表语句；分片的表在每个分片中各建一张带分片序号的表，
+// 自增ID从 i<<40 开始，各分片的ID互不重复
+func (d *Database) createTable(table, ddl string) error {
+	if d.Shards <= 1 || !isShardedTable(table) {
+		_, err := d.DB.Exec(ddl)
+		return err
+	}
+	for i := 0; i < d.Shards; i++ {
+		stmt := strings.Replace(ddl, "EXISTS "+table+" (", fmt.Sprintf("EXISTS shard%d.%s (", i, shardTable(table, i)), 1)
+		for _, t := range ShardedTables {
+			stmt = strings.ReplaceAll(stmt, "REFERENCES "+t+" (", "REFERENCES "+shardTable(t, i)+" (")
+		}
+		if _, err := d.DB.Exec(stmt); err != nil {
+			return err
+		}
+		if i == 0 {
+			continue
+		}
+		if _, err := d.DB.Exec(fmt.Sprintf(`
+			INSERT INTO shard%[1]d.sqlite_sequence (name, seq) SELECT ?, ?
+			WHERE NOT EXISTS (SELECT 1 FROM shard%[1]d.sqlite_sequence WHERE name = ?)`, i),
+			shardTable(table, i), int64(i)<<40, shardTable(table, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexStatements 返回建索引语句的实际执行语句，建在分片的表上的索引在每个分片中各建一个
+func (d *Database) indexStatements(stmt string) []string {
+	head, tail, _ := strings.Cut(stmt, " ON ")
+	table, columns, _ := strings.Cut(tail, " ")
+	if d.Shards <= 1 || !isShardedTable(table) {
+		return []string{stmt}
+	}
+	fields := strings.Fields(head)
+	name := fields[len(fields)-1]
+	prefix := strings.TrimSuffix(head, name)
+	stmts := make([]string, d.Shards)
+	for i := range stmts {
+		stmts[i] = fmt.Sprintf("%sshard%d.%s ON %s %s", prefix, i, name, shardTable(table, i), columns)
+	}
+	return stmts
+}
+
+// tableExists 判断 schema 库中是否有该表
+func (d *Database) tableExists(schema, table string) (bool, error) {
+	var n int
+	err := d.DB.QueryRow(fmt.Sprintf(
+		"SELECT COUNT(*) FROM %s.sqlite_master WHERE type = 'table' AND name = ?", schema), table).Scan(&n)
+	return n > 0, err
+}
+
+// checkShards 确认配置的分片数与数据库记录的一致：已分片的数据无法按其他分片数读取
+func (d *Database) checkShards() error {
+	ok, err := d.tableExists("main", "settings")
+	if err != nil || !ok {
+		return err
+	}
+	var value string
+	err = d.DB.QueryRow("SELECT value FROM settings WHERE key = ?", shardsSetting).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	recorded, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid recorded shard count %q", value)
+	}
+	if recorded != d.Shards {
+		return fmt.Errorf("database is split into %d shards but storage.shards is %d; "+
+			"restore a backup to change the shard count", recorded, d.Shards)
+	}
+	return nil
+}
+
+// moveIntoShards 把主库中原有的指纹和分析结果按哈希移入各分片并删除主库中的表，然后记录分片数；
+// 主库中没有这些表时（新库或已分片）只记录分片数
+func (d *Database) moveIntoShards() error {
+	// 先移分析结果，其外键引用指纹
+	tables := []string{"analysis", "fingerprints"}
+	columns := make(map[string]string, len(tables))
+	for _, table := range tables {
+		ok, err := d.tableExists("main", table)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		shardCols, err := d.tableColumnNames(d.physicalTables(table)[0])
+		if err != nil {
+			return err
+		}
+		mainCols, err := d.tableColumnNames("main." + table)
+		if err != nil {
+			return err
+		}
+		// 主库可能来自旧版本，只复制两侧都有的列，其余列取默认值；ID在分片中重新分配
+		existing := make(map[string]bool, len(mainCols))
+		for _, c := range mainCols {
+			existing[c] = true
+		}
+		var list []string
+		for _, c := range shardCols {
+			if c != "id" && existing[c] {
+				list = append(list, c)
+			}
+		}
+		columns[table] = strings.Join(list, ", ")
+	}
+
+	tx, err := d.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range tables {
+		list, ok := columns[table]
+		if !ok {
+			continue
+		}
+		var moved int64
+		for i := 0; i < d.Shards; i++ {
+			result, err := tx.Exec(fmt.Sprintf(
+				"INSERT INTO shard%d.%s (%s) SELECT %s FROM main.%s WHERE shard_of(fingerprint_hash) = ? ORDER BY id",
+				i, shardTable(table, i), list, list, table), i)
+			if err != nil {
+				return fmt.Errorf("failed to move %s into shard %d: %w", table, i, err)
+			}
+			n, _ := result.RowsAffected()
+			moved += n
+		}
+		if _, err := tx.Exec("DROP TABLE main." + table); err != nil {
+			return err
+		}
+		log.Printf("Moved %d rows of %s into %d shards", moved, table, d.Shards)
+	}
+	if _, err := tx.Exec("INSERT OR REPLACE INTO settings (key, value, updated_at) VALUES (?, ?, ?)",
+		shardsSetting, strconv.Itoa(d.Shards), time.Now()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// tableColumnNames 返回表的列名，table 可带库名
+func (d *Database) tableColumnNames(table string) ([]string, error) {
+	rows, err := d.DB.Query(tableInfoQuery(table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			colType    string
+			notNull    bool
+			defaultVal sql.NullString
+			primaryKey int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultVal, &primaryKey); err != nil {
+			return nil, err
+		}
+		columns = append(columns, name)
+	}
+	return columns, rows.Err()
+}
+
+// tableInfoQuery 返回查询表结构的 PRAGMA 语句，table 可带库名
+func tableInfoQuery(table string) string {
+	if schema, name, ok := strings.Cut(table, "."); ok {
+		return fmt.Sprintf("PRAGMA %s.table_info(%s)", schema, name)
+	}
+	return fmt.Sprintf("PRAGMA table_info(%s)", table)
+}
+
+// CopyShards 把各分片中的指纹和分析结果连同表结构复制到 path 处由 VACUUM INTO 生成的主库快照中，
+// 快照因此是不分片的完整数据库，可恢复到任意分片数的部署。分片在主库快照之后复制，其间的新提交可能只有分片部分
+func (d *Database) CopyShards(ctx context.Context, path string) error {
+	conn, err := d.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS snapshot", path); err != nil {
+		return fmt.Errorf("failed to attach snapshot: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "DETACH DATABASE snapshot")
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range ShardedTables {
+		var ddl string
+		if err := tx.QueryRowContext(ctx,
+			"SELECT sql FROM shard0.sqlite_master WHERE type = 'table' AND name = ?", shardTable(table, 0)).Scan(&ddl); err != nil {
+			return fmt.Errorf("failed to read schema of %s: %w", table, err)
+		}
+		ddl = strings.Replace(ddl, "CREATE TABLE "+shardTable(table, 0), "CREATE TABLE snapshot."+table, 1)
+		for _, t := range ShardedTables {
+			ddl = strings.ReplaceAll(ddl, "REFERENCES "+shardTable(t, 0)+" (", "REFERENCES "+t+" (")
+		}
+		if _, err := tx.ExecContext(ctx, ddl); err != nil {
+			return fmt.Errorf("failed to create %s in snapshot: %w", table, err)
+		}
+		// 视图的列顺序与分片0的表相同
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO snapshot.%[1]s SELECT * FROM %[1]s", table)); err != nil {
+			return fmt.Errorf("failed to copy %s into snapshot: %w", table, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM snapshot.settings WHERE key = ?", shardsSetting); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShardOf(t *testing.T) {
+	tests := []struct {
+		hash   string
+		shards int
+		want   int
+	}{
+		{"0abc", 4, 0},
+		{"3abc", 4, 0},
+		{"4abc", 4, 1},
+		{"Bf00", 4, 2},
+		{"ffff", 4, 3},
+		{"ffff", 8, 7},
+		{"7fff", 2, 0},
+		{"8000", 2, 1},
+		{"ffff", 0, 0},
+		{"", 4, 0},
+	}
+	for _, tt := range tests {
+		if got := ShardOf(tt.hash, tt.shards); got != tt.want {
+			t.Errorf("ShardOf(%q, %d) = %d, want %d", tt.hash, tt.shards, got, tt.want)
+		}
+	}
+	if got := ShardOf("not-hex", 4); got < 0 || got >= 4 {
+		t.Errorf("ShardOf of a non-hex hash = %d, want 0..3", got)
+	}
+}
+
+func TestShardPath(t *testing.T) {
+	for path, want := range map[string]string{
+		"fingerprints.db":          "fingerprints.shard2.db",
+		"/data/fp.db?cache=shared": "/data/fp.shard2.db",
+		"fingerprints":             "fingerprints.shard2",
+	} {
+		if got := ShardPath(path, 2); got != want {
+			t.Errorf("ShardPath(%q, 2) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+// 分片后服务层的SQL照常作用于 fingerprints 和 analysis，记录按哈希落在对应分片
+func TestShardedDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "fp.db")
+	unsharded, err := NewDatabase(dbPath, time.Second, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	insert := func(d *Database, hash string) {
+		t.Helper()
+		if _, err := d.DB.Exec(`INSERT OR REPLACE INTO fingerprints (fingerprint_hash, user_agent, screen_resolution, timezone,
+			language, platform, canvas, canvas_hash, webgl, webgl_hash, audio, audio_hash, fonts, plugins, touch_support,
+			cookie_enabled, do_not_track, ip_address) VALUES (?, 'ua', '', '', '', '', '', '', '', '', '', '', '[]', '[]', 0, 1, '', '')`,
+			hash); err != nil {
+			t.Fatalf("insert %s: %v", hash, err)
+		}
+		if _, err := d.DB.Exec(`INSERT OR REPLACE INTO analysis (fingerprint_hash, uniqueness_score, bot_score, risk_level,
+			is_bot, reasons) VALUES (?, 0, 0, 'LOW', 0, '[]')`, hash); err != nil {
+			t.Fatalf("insert analysis %s: %v", hash, err)
+		}
+	}
+	insert(unsharded, "0aaa")
+	insert(unsharded, "faaa")
+	unsharded.Close()
+
+	// 主库中原有的记录在首次分片启动时移入分片
+	d, err := NewDatabase(dbPath, time.Second, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	insert(d, "5aaa")
+	insert(d, "5aaa")
+
+	count := func(query string, args ...interface{}) int {
+		t.Helper()
+		var n int
+		if err := d.DB.QueryRow(query, args...).Scan(&n); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		return n
+	}
+	if n := count("SELECT COUNT(*) FROM fingerprints f JOIN analysis a ON a.fingerprint_hash = f.fingerprint_hash"); n != 3 {
+		t.Fatalf("joined rows = %d, want 3", n)
+	}
+	for hash, shard := range map[string]int{"0aaa": 0, "5aaa": 1, "faaa": 3} {
+		if n := count("SELECT COUNT(*) FROM "+shardTable("fingerprints", shard)+" WHERE fingerprint_hash = ?", hash); n != 1 {
+			t.Errorf("%s in shard %d: %d rows, want 1", hash, shard, n)
+		}
+	}
+	if n := count("SELECT visit_count FROM analysis WHERE fingerprint_hash = '5aaa'"); n != 1 {
+		t.Errorf("visit_count default = %d, want 1", n)
+	}
+
+	// 改变哈希的更新在分片间移动记录，删除作用于所在分片
+	if _, err := d.DB.Exec("UPDATE fingerprints SET fingerprint_hash = 'baaa' WHERE fingerprint_hash = '0aaa'"); err != nil {
+		t.Fatal(err)
+	}
+	if n := count("SELECT COUNT(*) FROM " + shardTable("fingerprints", 2) + " WHERE fingerprint_hash = 'baaa'"); n != 1 {
+		t.Errorf("moved fingerprint in shard 2: %d rows, want 1", n)
+	}
+	if n := count("SELECT COUNT(*) FROM " + shardTable("fingerprints", 0)); n != 0 {
+		t.Errorf("shard 0 after the move: %d rows, want 0", n)
+	}
+	if _, err := d.DB.Exec("DELETE FROM fingerprints WHERE fingerprint_hash = 'faaa'"); err != nil {
+		t.Fatal(err)
+	}
+	if n := count("SELECT COUNT(DISTINCT id) FROM fingerprints"); n != 2 {
+		t.Errorf("fingerprints after delete = %d, want 2", n)
+	}
+	d.Close()
+
+	if _, err := NewDatabase(dbPath, time.Second, 2); err == nil || !strings.Contains(err.Error(), "split into 4 shards") {
+		t.Fatalf("reopening with 2 shards error = %v, want shard count error", err)
+	}
+	if _, err := NewDatabase(dbPath, time.Second, 0); err == nil || !strings.Contains(err.Error(), "split into 4 shards") {
+		t.Fatalf("reopening unsharded error = %v, want shard count error", err)
+	}
+}
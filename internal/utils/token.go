@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// 访客令牌校验错误
+var (
+	ErrTokenMalformed  = errors.New("malformed token")
+	ErrTokenUnknownKey = errors.New("unknown signing key")
+	ErrTokenSignature  = errors.New("invalid token signature")
+	ErrTokenExpired    = errors.New("token expired")
+	ErrTokenIPMismatch = errors.New("token bound to a different IP")
+)
+
+// VisitorClaims 访客令牌（HS256 JWT）的声明
+type VisitorClaims struct {
+	// Subject 指纹哈希
+	Subject   string `json:"sub"`
+	IP        string `json:"ip"`
+	SiteID    string `json:"site,omitempty"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	ID        string `json:"jti"`
+}
+
+// tokenHeader JWT头部，kid 指明签名密钥以支持密钥轮换
+type tokenHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid"`
+}
+
+// SignVisitorToken 用 HS256 签发访客令牌
+func SignVisitorToken(kid string, secret []byte, claims VisitorClaims) (string, error) {
+	header, err := json.Marshal(tokenHeader{Alg: "HS256", Typ: "JWT", Kid: kid})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(tokenSignature(secret, signingInput)), nil
+}
+
+// ParseVisitorToken 校验访客令牌的签名、有效期和绑定的IP，ip 为空时不校验IP
+// keys 按 kid 返回验证密钥，接入方的中间件可用导出的密钥在本地校验
+func ParseVisitorToken(token string, keys func(kid string) ([]byte, bool), ip string, now time.Time) (*VisitorClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenMalformed
+	}
+	var header tokenHeader
+	if raw, err := base64.RawURLEncoding.DecodeString(parts[0]); err != nil || json.Unmarshal(raw, &header) != nil {
+		return nil, ErrTokenMalformed
+	}
+	if header.Alg != "HS256" {
+		return nil, ErrTokenMalformed
+	}
+	secret, ok := keys(header.Kid)
+	if !ok {
+		return nil, ErrTokenUnknownKey
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+	if !hmac.Equal(signature, tokenSignature(secret, parts[0]+"."+parts[1])) {
+		return nil, ErrTokenSignature
+	}
+
+	var claims VisitorClaims
+	if raw, err := base64.RawURLEncoding.DecodeString(parts[1]); err != nil || json.Unmarshal(raw, &claims) != nil {
+		return nil, ErrTokenMalformed
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return nil, ErrTokenExpired
+	}
+	if ip != "" && claims.IP != ip {
+		return nil, ErrTokenIPMismatch
+	}
+	return &claims, nil
+}
+
+// tokenSignature 计算 HMAC-SHA256 签名
+func tokenSignature(secret []byte, signingInput string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signingInput))
+	return mac.Sum(nil)
+}
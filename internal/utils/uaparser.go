@@ -0,0 +1,211 @@
+package utils
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"gopkg.in/yaml.v3"
+)
+
+// embeddedUARegexes 内置的 uap-core 格式定义，加载外部文件失败时使用
+//
+//go:embed regexes.yaml
+var embeddedUARegexes []byte
+
+// uapFamilies uap-core 浏览器家族到内部家族的映射，未列出的家族保留 uap-core 的名称
+var uapFamilies = map[string]string{
+	"Chrome":                     BrowserChrome,
+	"Chrome Mobile":              BrowserChrome,
+	"Chrome Mobile iOS":          BrowserChrome,
+	"Chrome Mobile WebView":      BrowserChrome,
+	"HeadlessChrome":             BrowserChrome,
+	"Chromium":                   BrowserChrome,
+	"Edge":                       BrowserEdge,
+	"Edge Mobile":                BrowserEdge,
+	"Opera":                      BrowserOpera,
+	"Opera Mobile":               BrowserOpera,
+	"Samsung Internet":           BrowserSamsung,
+	"Firefox":                    BrowserFirefox,
+	"Firefox Mobile":             BrowserFirefox,
+	"Firefox iOS":                BrowserFirefox,
+	"Safari":                     BrowserSafari,
+	"Mobile Safari":              BrowserSafari,
+	"Mobile Safari UI/WKWebView": BrowserSafari,
+}
+
+// uapOSes uap-core 操作系统到内部名称的映射，未列出的系统保留 uap-core 的名称
+var uapOSes = map[string]string{
+	"Windows":   "Windows",
+	"Mac OS X":  "macOS",
+	"iOS":       "iOS",
+	"Android":   "Android",
+	"Chrome OS": "ChromeOS",
+	"Linux":     "Linux",
+	"Ubuntu":    "Linux",
+	"Fedora":    "Linux",
+	"Debian":    "Linux",
+}
+
+// uapOther uap-core 无法识别时返回的名称
+const uapOther = "Other"
+
+// uapParser regexes.yaml 中的一条规则
+type uapParser struct {
+	Regex             string `yaml:"regex"`
+	RegexFlag         string `yaml:"regex_flag"`
+	FamilyReplacement string `yaml:"family_replacement"`
+	V1Replacement     string `yaml:"v1_replacement"`
+	OSReplacement     string `yaml:"os_replacement"`
+	OSV1Replacement   string `yaml:"os_v1_replacement"`
+
+	pattern *regexp.Regexp
+}
+
+// UARegexes 编译后的 uap-core 规则
+type UARegexes struct {
+	// Source 规则来源：文件路径或 "embedded"（内置副本）
+	Source  string
+	browser []uapParser
+	os      []uapParser
+	// Skipped Go正则不支持（如环视）而跳过的规则数
+	Skipped int
+}
+
+// BrowserParsers 浏览器规则数
+func (r *UARegexes) BrowserParsers() int {
+	return len(r.browser)
+}
+
+// OSParsers 操作系统规则数
+func (r *UARegexes) OSParsers() int {
+	return len(r.os)
+}
+
+// ParseUARegexes 解析 uap-core regexes.yaml，设备规则（device_parsers）不使用
+func ParseUARegexes(data []byte, source string) (*UARegexes, error) {
+	var doc struct {
+		UserAgentParsers []uapParser `yaml:"user_agent_parsers"`
+		OSParsers        []uapParser `yaml:"os_parsers"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid regexes.yaml: %w", err)
+	}
+	r := &UARegexes{Source: source}
+	r.browser, r.Skipped = compileUAParsers(doc.UserAgentParsers)
+	var skipped int
+	r.os, skipped = compileUAParsers(doc.OSParsers)
+	r.Skipped += skipped
+	if len(r.browser) == 0 {
+		return nil, fmt.Errorf("regexes.yaml has no usable user_agent_parsers")
+	}
+	return r, nil
+}
+
+// compileUAParsers 编译规则，Go正则不支持的规则跳过
+func compileUAParsers(parsers []uapParser) ([]uapParser, int) {
+	compiled := make([]uapParser, 0, len(parsers))
+	skipped := 0
+	for _, p := range parsers {
+		expr := p.Regex
+		if p.RegexFlag == "i" {
+			expr = "(?i)" + expr
+		}
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			skipped++
+			continue
+		}
+		p.pattern = pattern
+		compiled = append(compiled, p)
+	}
+	return compiled, skipped
+}
+
+// LoadUARegexes 从文件加载 uap-core 规则
+func LoadUARegexes(path string) (*UARegexes, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseUARegexes(data, path)
+}
+
+var (
+	embeddedOnce    sync.Once
+	embeddedParsed  *UARegexes
+	activeUARegexes atomic.Pointer[UARegexes]
+)
+
+// EmbeddedUARegexes 内置的 uap-core 规则
+func EmbeddedUARegexes() *UARegexes {
+	embeddedOnce.Do(func() {
+		r, err := ParseUARegexes(embeddedUARegexes, "embedded")
+		if err != nil {
+			panic(err)
+		}
+		embeddedParsed = r
+	})
+	return embeddedParsed
+}
+
+// SetUARegexes 设置 ParseUserAgent 使用的 uap-core 规则，为 nil 时只使用内置的家族正则
+func SetUARegexes(r *UARegexes) {
+	activeUARegexes.Store(r)
+}
+
+// ActiveUARegexes 当前使用的 uap-core 规则，未设置时为 nil
+func ActiveUARegexes() *UARegexes {
+	return activeUARegexes.Load()
+}
+
+// parse 返回 uap-core 的浏览器家族、主版本号和操作系统，无法识别的部分为 "Other"
+func (r *UARegexes) parse(userAgent string) (family string, major int, osName string) {
+	family, osName = uapOther, uapOther
+	for _, p := range r.browser {
+		m := p.pattern.FindStringSubmatch(userAgent)
+		if m == nil {
+			continue
+		}
+		family = uapReplace(p.FamilyReplacement, m, 1)
+		major, _ = strconv.Atoi(uapReplace(p.V1Replacement, m, 2))
+		break
+	}
+	for _, p := range r.os {
+		m := p.pattern.FindStringSubmatch(userAgent)
+		if m == nil {
+			continue
+		}
+		osName = uapReplace(p.OSReplacement, m, 1)
+		break
+	}
+	if family == "" {
+		family = uapOther
+	}
+	if osName == "" {
+		osName = uapOther
+	}
+	return family, major, osName
+}
+
+// uapReplace 按 uap-core 约定取值：有替换模板时替换其中的 $1~$9，否则取第 group 个分组
+func uapReplace(replacement string, m []string, group int) string {
+	if replacement == "" {
+		if group < len(m) {
+			return m[group]
+		}
+		return ""
+	}
+	if !strings.Contains(replacement, "$") {
+		return replacement
+	}
+	for i := len(m) - 1; i >= 1 && i <= 9; i-- {
+		replacement = strings.ReplaceAll(replacement, "$"+strconv.Itoa(i), m[i])
+	}
+	return strings.TrimSpace(replacement)
+}
@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 浏览器家族
+const (
+	BrowserChrome  = "Chrome"
+	BrowserEdge    = "Edge"
+	BrowserOpera   = "Opera"
+	BrowserSamsung = "Samsung Internet"
+	BrowserFirefox = "Firefox"
+	BrowserSafari  = "Safari"
+	BrowserUnknown = "Unknown"
+)
+
+// JavaScript引擎
+const (
+	EngineV8           = "V8"
+	EngineSpiderMonkey = "SpiderMonkey"
+	EngineJSC          = "JavaScriptCore"
+	EngineUnknown      = "Unknown"
+)
+
+// UserAgentInfo User Agent解析结果
+type UserAgentInfo struct {
+	Family string `json:"family"`
+	// Major 主版本号，无法解析时为0
+	Major  int    `json:"major"`
+	OS     string `json:"os"`
+	Mobile bool   `json:"mobile"`
+	// Engine 实际运行的JavaScript引擎；iOS上所有浏览器都使用JavaScriptCore
+	Engine string `json:"engine"`
+}
+
+// uaFamilyPatterns 按优先级排列：Chromium衍生浏览器的UA同时包含 Chrome/，需要先匹配
+var uaFamilyPatterns = []struct {
+	family  string
+	pattern *regexp.Regexp
+}{
+	{BrowserEdge, regexp.MustCompile(`(?:Edg|EdgA|EdgiOS)/(\d+)`)},
+	{BrowserOpera, regexp.MustCompile(`(?:OPR|OPT)/(\d+)`)},
+	{BrowserSamsung, regexp.MustCompile(`SamsungBrowser/(\d+)`)},
+	{BrowserFirefox, regexp.MustCompile(`(?:Firefox|FxiOS)/(\d+)`)},
+	{BrowserChrome, regexp.MustCompile(`(?:Chrome|CriOS|HeadlessChrome)/(\d+)`)},
+	{BrowserSafari, regexp.MustCompile(`Version/(\d+)[.\d]* (?:Mobile/\S+ )?Safari/`)},
+}
+
+// ParseUserAgent 解析User Agent中的浏览器家族、主版本号和操作系统
+// 配置了 uap-core 规则（SetUARegexes）时以其结果为准，无法识别的部分保留内置正则的结果
+func ParseUserAgent(userAgent string) UserAgentInfo {
+	info := parseBuiltin(userAgent)
+	r := ActiveUARegexes()
+	if r == nil {
+		return info
+	}
+
+	family, major, osName := r.parse(userAgent)
+	if osName != uapOther {
+		info.OS = osName
+		if mapped, ok := uapOSes[osName]; ok {
+			info.OS = mapped
+		}
+		info.Mobile = info.Mobile || info.OS == "Android" || info.OS == "iOS"
+	}
+	if family != uapOther {
+		info.Major = major
+		mapped, ok := uapFamilies[family]
+		if !ok {
+			// 其他浏览器保留 uap-core 的名称，引擎沿用内置正则的判断（如 Vivaldi 的UA同时包含 Chrome/）
+			info.Family = family
+			return info
+		}
+		info.Family = mapped
+	}
+	info.Engine = engineFor(info)
+	return info
+}
+
+// parseBuiltin 用内置的家族正则解析User Agent
+func parseBuiltin(userAgent string) UserAgentInfo {
+	info := UserAgentInfo{
+		Family: BrowserUnknown,
+		OS:     parseOS(userAgent),
+		Engine: EngineUnknown,
+	}
+	lower := strings.ToLower(userAgent)
+	info.Mobile = strings.Contains(lower, "mobile") || info.OS == "Android" || info.OS == "iOS"
+
+	for _, p := range uaFamilyPatterns {
+		if m := p.pattern.FindStringSubmatch(userAgent); m != nil {
+			info.Family = p.family
+			info.Major, _ = strconv.Atoi(m[1])
+			break
+		}
+	}
+	info.Engine = engineFor(info)
+	return info
+}
+
+// engineFor 根据浏览器家族和操作系统判断实际运行的JavaScript引擎
+func engineFor(info UserAgentInfo) string {
+	switch {
+	case info.OS == "iOS":
+		return EngineJSC
+	case info.Family == BrowserFirefox:
+		return EngineSpiderMonkey
+	case info.Family == BrowserSafari:
+		return EngineJSC
+	case info.Family != BrowserUnknown:
+		return EngineV8
+	default:
+		return EngineUnknown
+	}
+}
+
+// parseOS 识别操作系统
+func parseOS(userAgent string) string {
+	switch {
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"), strings.Contains(userAgent, "iPod"):
+		return "iOS"
+	case strings.Contains(userAgent, "Android"):
+		return "Android"
+	case strings.Contains(userAgent, "Windows"):
+		return "Windows"
+	case strings.Contains(userAgent, "CrOS"):
+		return "ChromeOS"
+	case strings.Contains(userAgent, "Mac OS X"), strings.Contains(userAgent, "Macintosh"):
+		return "macOS"
+	case strings.Contains(userAgent, "Linux"):
+		return "Linux"
+	default:
+		return "Unknown"
+	}
+}
@@ -2,9 +2,103 @@
  * 重构后的指纹收集器 - 使用工具类提高代码复用性和可读性
  */
 class ModernFingerprintCollector {
+    /**
+     * 指纹结构版本，新增参与哈希的信号时递增，服务端按版本只比较双方都采集的信号
+     */
+    static FINGERPRINT_VERSION = 2;
+
+    /**
+     * 每类交互读数最多保留的样本数
+     */
+    static MAX_INTERACTION_SAMPLES = 200;
+
     constructor() {
         this.fingerprint = {};
         this.isCollecting = false;
+        this.interaction = { pressures: [], sizes: [], motion: [] };
+        this.recordInteraction();
+    }
+
+    /**
+     * 开始记录触摸压力、接触半径和加速度读数，提交时只包含汇总的方差和熵
+     * 页面加载后才有交互，在用户操作后（如提交表单时）再次采集可以得到更完整的汇总
+     */
+    recordInteraction() {
+        if (typeof window === 'undefined' || typeof window.addEventListener !== 'function') {
+            return;
+        }
+        const limit = ModernFingerprintCollector.MAX_INTERACTION_SAMPLES;
+        const { pressures, sizes, motion } = this.interaction;
+        const onTouch = event => {
+            for (const touch of Array.from(event.changedTouches || [])) {
+                if (pressures.length >= limit) {
+                    return;
+                }
+                pressures.push(touch.force || 0);
+                sizes.push(((touch.radiusX || 0) + (touch.radiusY || 0)) / 2);
+            }
+        };
+        const onMotion = event => {
+            const acc = event.accelerationIncludingGravity;
+            if (!acc || acc.x === null || motion.length >= limit) {
+                return;
+            }
+            motion.push(Math.hypot(acc.x, acc.y || 0, acc.z || 0));
+        };
+        window.addEventListener('touchstart', onTouch, { passive: true });
+        window.addEventListener('touchmove', onTouch, { passive: true });
+        window.addEventListener('devicemotion', onMotion);
+    }
+
+    /**
+     * 汇总已记录的交互读数，没有任何读数时返回null
+     * @returns {Object|null} 触点数、压力和接触半径的方差、加速度读数数及其熵
+     */
+    collectInteraction() {
+        const { pressures, sizes, motion } = this.interaction;
+        if (pressures.length === 0 && motion.length === 0) {
+            return null;
+        }
+        return {
+            touches: pressures.length,
+            pressure_variance: ModernFingerprintCollector.variance(pressures),
+            size_variance: ModernFingerprintCollector.variance(sizes),
+            motion_samples: motion.length,
+            motion_entropy: ModernFingerprintCollector.entropy(motion, 0.05)
+        };
+    }
+
+    /**
+     * 计算方差
+     * @param {Array<number>} values 样本
+     * @returns {number} 总体方差，样本为空时为0
+     */
+    static variance(values) {
+        if (values.length === 0) {
+            return 0;
+        }
+        const mean = values.reduce((sum, v) => sum + v, 0) / values.length;
+        return values.reduce((sum, v) => sum + (v - mean) ** 2, 0) / values.length;
+    }
+
+    /**
+     * 按固定宽度分桶后计算香农熵
+     * @param {Array<number>} values 样本
+     * @param {number} step 分桶宽度
+     * @returns {number} 熵（比特）
+     */
+    static entropy(values, step) {
+        const counts = new Map();
+        for (const v of values) {
+            const bucket = Math.round(v / step);
+            counts.set(bucket, (counts.get(bucket) || 0) + 1);
+        }
+        let bits = 0;
+        for (const count of counts.values()) {
+            const p = count / values.length;
+            bits -= p * Math.log2(p);
+        }
+        return bits;
     }
 
     /**
@@ -17,17 +111,21 @@ class ModernFingerprintCollector {
         }
 
         this.isCollecting = true;
+        const startedAt = Date.now();
         try {
             // 并行收集基础信息
             const basicTasks = [
                 this.collectBasicBrowserInfo(),
                 this.collectScreenInfo(),
                 this.collectTimezoneInfo(),
-                this.collectHardwareInfo()
+                this.collectHardwareInfo(),
+                this.collectMathInfo(),
+                this.collectFeatureProbes(),
+                this.collectFontMetrics()
             ];
 
             // 收集基础信息
-            const [basicInfo, screenInfo, timezoneInfo, hardwareInfo] = await Promise.all(basicTasks);
+            const [basicInfo, screenInfo, timezoneInfo, hardwareInfo, mathInfo, featureProbes, fontMetrics] = await Promise.all(basicTasks);
             
             // 收集高级指纹信息
             const advancedTasks = [
@@ -35,10 +133,17 @@ class ModernFingerprintCollector {
                 this.collectWebGLFingerprint(),
                 this.collectAudioFingerprint(),
                 this.collectFontInfo(),
-                this.collectStorageInfo()
+                this.collectStorageInfo(),
+                this.collectWebRTCInfo(),
+                this.collectMediaDevices(),
+                this.collectBatteryInfo(),
+                this.collectSensorInfo(),
+                this.collectContentBlocking(),
+                this.collectNetworkTiming(),
+                this.collectExecutionProof()
             ];
 
-            const [canvasInfo, webglInfo, audioInfo, fontInfo, storageInfo] = await Promise.all(advancedTasks);
+            const [canvasInfo, webglInfo, audioInfo, fontInfo, storageInfo, webrtcInfo, mediaDevices, batteryInfo, sensorInfo, contentBlocking, networkTiming, executionProof] = await Promise.all(advancedTasks);
 
             // 合并所有信息
             this.fingerprint = {
@@ -56,6 +161,19 @@ class ModernFingerprintCollector {
                     fingerprint: CryptoUtils.simpleHash(JSON.stringify(basicInfo.plugins || []))
                 },
                 storage: storageInfo,
+                webrtc: webrtcInfo,
+                mediaDevices: mediaDevices,
+                battery: batteryInfo,
+                sensors: sensorInfo,
+                math: mathInfo,
+                features: featureProbes,
+                fontMetrics: fontMetrics,
+                contentBlocking: contentBlocking,
+                network: networkTiming,
+                proof: executionProof,
+                canvasCheck: await this.collectCanvasCheck(executionProof && executionProof.seed),
+                interaction: this.collectInteraction(),
+                extensions: this.collectExtensions(),
                 timestamp: Date.now(),
                 version: '2.0'
             };
@@ -63,6 +181,9 @@ class ModernFingerprintCollector {
             // 生成主指纹
             this.fingerprint.mainFingerprint = await this.generateMainFingerprint();
 
+            // 采集开始和结束时间，服务端据此识别预先录制后重放的提交
+            this.fingerprint.collectionTiming = { started_at: startedAt, finished_at: Date.now() };
+
             // 添加噪声检测数据
             this.fingerprint.canvasNoiseDetection = this.generateNoiseDetectionData('canvas');
             this.fingerprint.webglNoiseDetection = this.generateNoiseDetectionData('webgl');
@@ -162,6 +283,208 @@ class ModernFingerprintCollector {
         }
     }
 
+    /**
+     * 收集WebRTC地址信息
+     * @returns {Promise<Object>} WebRTC地址信息
+     */
+    async collectWebRTCInfo() {
+        try {
+            return await BrowserUtils.getWebRTCIPs();
+        } catch (e) {
+            return { supported: false, localIPs: [], publicIPs: [], error: e.message };
+        }
+    }
+
+    /**
+     * 收集媒体设备数量
+     * @returns {Promise<Object|null>} 媒体设备数量
+     */
+    async collectMediaDevices() {
+        try {
+            return await BrowserUtils.getMediaDeviceCounts();
+        } catch (e) {
+            return null;
+        }
+    }
+
+    /**
+     * 收集电池状态
+     * @returns {Promise<Object|null>} 电池信息
+     */
+    async collectBatteryInfo() {
+        try {
+            return await BrowserUtils.getBatteryInfo();
+        } catch (e) {
+            return null;
+        }
+    }
+
+    /**
+     * 收集运动传感器可用性
+     * @returns {Promise<Object|null>} 传感器信息
+     */
+    async collectSensorInfo() {
+        try {
+            return await BrowserUtils.getSensorInfo();
+        } catch (e) {
+            return null;
+        }
+    }
+
+    /**
+     * 收集Math函数结果向量
+     * @returns {Array<string>|null} 结果向量
+     */
+    collectMathInfo() {
+        try {
+            return BrowserUtils.getMathInfo();
+        } catch (e) {
+            return null;
+        }
+    }
+
+    /**
+     * 收集特性探测位图
+     * @returns {Object|null} 特性位图
+     */
+    collectFeatureProbes() {
+        try {
+            return BrowserUtils.getFeatureProbes();
+        } catch (e) {
+            return null;
+        }
+    }
+
+    /**
+     * 收集字体渲染尺寸
+     * @returns {Object|null} 渲染尺寸及其哈希
+     */
+    collectFontMetrics() {
+        try {
+            const metrics = BrowserUtils.getFontMetrics();
+            return {
+                metrics,
+                fingerprint: CryptoUtils.simpleHash(JSON.stringify(metrics))
+            };
+        } catch (e) {
+            return null;
+        }
+    }
+
+    /**
+     * 收集广告拦截检测结果
+     * @returns {Promise<Object|null>} 诱饵元素检测结果
+     */
+    async collectContentBlocking() {
+        try {
+            return await BrowserUtils.getContentBlocking();
+        } catch (e) {
+            return null;
+        }
+    }
+
+    /**
+     * 测量到服务端的请求往返时延，与反向代理测得的TCP时延比较可发现中继
+     * 第一次请求用于建立连接，不计入结果
+     * @returns {Promise<Object|null>} 往返时延中位数（毫秒）
+     */
+    async collectNetworkTiming() {
+        try {
+            const samples = [];
+            for (let i = 0; i < 4; i++) {
+                const start = performance.now();
+                await fetch('/api/health', { cache: 'no-store' });
+                if (i > 0) {
+                    samples.push(performance.now() - start);
+                }
+            }
+            samples.sort((a, b) => a - b);
+            return { ping_ms: Math.round(samples[1] * 10) / 10 };
+        } catch (e) {
+            return null;
+        }
+    }
+
+    /**
+     * 获取服务端签发的种子并计算执行证明，证明随指纹提交，服务端以同样的计算校验
+     * @returns {Promise<Object|null>} 种子和证明值，服务端未启用时为 null
+     */
+    async collectExecutionProof() {
+        try {
+            const response = await fetch('/api/proof/seed', { cache: 'no-store' });
+            if (!response.ok) {
+                return null;
+            }
+            const { seed } = await response.json();
+            return { seed, value: ModernFingerprintCollector.computeExecutionProof(seed) };
+        } catch (e) {
+            return null;
+        }
+    }
+
+    /**
+     * 绘制双Canvas：固定图案供服务端与已知哈希比对，种子图案须随种子变化，用于发现重放和随机化的Canvas
+     * @param {string} seed 执行证明的种子，没有种子时只绘制固定图案
+     * @returns {Promise<Object|null>} 两张图案 toDataURL 的SHA-256，绘制失败时为 null
+     */
+    async collectCanvasCheck(seed) {
+        try {
+            const staticCanvas = CanvasUtils.createCanvas(240, 60);
+            const result = { static: await CryptoUtils.hashString(CanvasUtils.drawStaticPattern(staticCanvas)) };
+            if (seed) {
+                const seededCanvas = CanvasUtils.createCanvas(240, 60);
+                result.seed = seed;
+                result.seeded = await CryptoUtils.hashString(CanvasUtils.drawSeededPattern(seededCanvas, seed));
+            }
+            return result;
+        } catch (e) {
+            return null;
+        }
+    }
+
+    /**
+     * 由种子计算执行证明，须与服务端 utils.ExecutionProof 逐位一致
+     * @param {string} seed 种子（ASCII）
+     * @returns {string} 64个十六进制字符
+     */
+    static computeExecutionProof(seed) {
+        let h = 0x811c9dc5;
+        for (let i = 0; i < seed.length; i++) {
+            h = Math.imul(h ^ seed.charCodeAt(i), 0x01000193);
+        }
+
+        const lanes = new Array(8);
+        for (let i = 0; i < 8; i++) {
+            h = Math.imul(h ^ (h >>> 16), 0x85ebca6b);
+            h = Math.imul(h ^ (h >>> 13), 0xc2b2ae35);
+            h ^= h >>> 16;
+            lanes[i] = h;
+        }
+
+        const rounds = 2048 + (lanes[0] & 2047);
+        for (let r = 0; r < rounds; r++) {
+            const i = r & 7;
+            const j = (lanes[i] >>> 3) & 7;
+            let x = lanes[i] ^ ((lanes[j] << 7) | (lanes[j] >>> 25));
+            x = Math.imul(x, 0x9e3779b1) + r;
+            lanes[i] = x ^ (x >>> 11);
+        }
+
+        return lanes.map(v => (v >>> 0).toString(16).padStart(8, '0')).join('');
+    }
+
+    /**
+     * 收集浏览器扩展痕迹
+     * @returns {Array<string>} 扩展名
+     */
+    collectExtensions() {
+        try {
+            return BrowserUtils.getExtensionArtifacts();
+        } catch (e) {
+            return [];
+        }
+    }
+
     /**
      * 收集Canvas指纹
      * @returns {Promise<Object>} Canvas指纹信息
@@ -274,8 +597,9 @@ class ModernFingerprintCollector {
                 };
             }
 
-            // 收集WebGL基础信息
+            // 收集WebGL基础信息和参数向量
             const basicInfo = WebGLUtils.collectBasicInfo(gl);
+            const parameters = WebGLUtils.collectParameters(gl);
 
             // 执行绘制测试
             const redRectangleSuccess = WebGLUtils.drawRedRectangle(canvas);
@@ -307,6 +631,7 @@ class ModernFingerprintCollector {
             return {
                 support,
                 basicInfo,
+                parameters,
                 redRectangle: redRectangleResult,
                 coloredCube: coloredCubeResult,
                 spoofingDetection,
@@ -896,7 +1221,8 @@ class ModernFingerprintCollector {
                 this.fingerprint.webgl?.fingerprint || '',
                 this.fingerprint.audio?.fingerprint || '',
                 this.fingerprint.fonts?.fingerprint || '',
-                this.fingerprint.storage?.fingerprint || ''
+                this.fingerprint.storage?.fingerprint || '',
+                this.fingerprint.fontMetrics?.fingerprint || ''
             ];
 
             const combinedFingerprint = fingerprintComponents.join('|');
@@ -926,6 +1252,7 @@ class ModernFingerprintCollector {
         const audioInfo = this.fingerprint.audio || {};
         const fontInfo = this.fingerprint.fonts || {};
         const pluginInfo = this.fingerprint.plugins || {};
+        const webrtcInfo = this.fingerprint.webrtc || {};
 
         console.log('数据路径调试:');
         console.log('basicInfo:', basicInfo);
@@ -945,6 +1272,7 @@ class ModernFingerprintCollector {
         const result = {
             // 主指纹哈希 - 前端计算
             fingerprint_hash: this.fingerprint.mainFingerprint,
+            fingerprint_version: ModernFingerprintCollector.FINGERPRINT_VERSION,
             
             // 基础浏览器信息 - 必需字段，修正字段访问路径
             user_agent: basicInfo.userAgent?.full || navigator.userAgent,
@@ -980,7 +1308,49 @@ class ModernFingerprintCollector {
             // 噪声检测数据（可选）
             canvasNoiseDetection: this.generateNoiseDetectionData('canvas'),
             webglNoiseDetection: this.generateNoiseDetectionData('webgl'),
-            audioNoiseDetection: this.generateNoiseDetectionData('audio')
+            audioNoiseDetection: this.generateNoiseDetectionData('audio'),
+
+            // 硬件信息
+            hardware_concurrency: hardwareInfo.hardwareConcurrency || 0,
+            device_memory: hardwareInfo.deviceMemory || 0,
+            media_devices: this.fingerprint.mediaDevices || undefined,
+            battery: this.fingerprint.battery || undefined,
+            sensors: this.fingerprint.sensors || undefined,
+            math: this.fingerprint.math || undefined,
+            features: this.fingerprint.features || undefined,
+            font_metrics: this.fingerprint.fontMetrics?.metrics || undefined,
+            content_blocking: this.fingerprint.contentBlocking || undefined,
+            network: this.fingerprint.network || undefined,
+            // 执行证明（证明请求由运行本脚本产生）
+            proof: this.fingerprint.proof || undefined,
+            // WebGL参数、扩展和着色器精度（服务端按GPU类别校验）
+            webgl_params: webglInfo.parameters || undefined,
+            // 双Canvas渲染（固定图案与按种子绘制的图案）
+            canvas_check: this.fingerprint.canvasCheck || undefined,
+            // 采集时间（用于识别重放的提交）
+            timing: this.fingerprint.collectionTiming || undefined,
+            // 触摸和运动传感器读数的汇总（用于识别脚本产生的移动端输入）
+            interaction: this.fingerprint.interaction || undefined,
+            // 导航上下文（用于识别没有来源直接访问深层页面的爬虫）
+            navigation: {
+                referrer: document.referrer || '',
+                page: location.pathname
+            },
+            extensions: this.fingerprint.extensions || [],
+            brave: !!(navigator.brave && typeof navigator.brave.isBrave === 'function'),
+            audio_samples: (audioInfo.compressor?.testResults || []).map(test => test.samples).filter(Boolean),
+            screen: {
+                color_depth: screenInfo.colorDepth || 0,
+                device_pixel_ratio: screenInfo.devicePixelRatio || 0,
+                avail_width: screenInfo.availWidth || 0,
+                avail_height: screenInfo.availHeight || 0,
+                outer_width: screenInfo.outerWidth || 0,
+                outer_height: screenInfo.outerHeight || 0
+            },
+
+            // WebRTC探测到的地址（用于代理/VPN检测）
+            webrtc_local_ips: webrtcInfo.localIPs || [],
+            webrtc_public_ips: webrtcInfo.publicIPs || []
         };
 
         // 调试：验证canvas字段
@@ -208,6 +208,8 @@ class AudioUtils {
                 testIndex,
                 fingerprint: fingerprintData.mainFingerprint,
                 detailedFingerprint: fingerprintData.detailedFingerprint,
+                // 主区段原始采样，提交给服务端做数值分析
+                samples: Array.from(channelData.slice(4500, 5000)),
                 renderTime,
                 bufferLength: channelData.length,
                 timestamp: Date.now(),
@@ -34,7 +34,9 @@ class BrowserUtils {
             colorDepth: screen.colorDepth,
             pixelDepth: screen.pixelDepth,
             orientation: screen.orientation ? screen.orientation.type : undefined,
-            devicePixelRatio: window.devicePixelRatio || 1
+            devicePixelRatio: window.devicePixelRatio || 1,
+            outerWidth: window.outerWidth,
+            outerHeight: window.outerHeight
         };
     }
 
@@ -230,6 +232,95 @@ class BrowserUtils {
         };
     }
 
+    /**
+     * 测量探测字符串在各个字体族下的渲染尺寸
+     * 尺寸取决于系统实际安装的字体与渲染引擎，伪造字体列表无法改变它
+     * @returns {Array<Object>} [{ font, width, height }]
+     */
+    static getFontMetrics() {
+        const families = ['monospace', 'sans-serif', 'serif', 'cursive', 'fantasy', 'system-ui', 'emoji', 'math'];
+        const probeString = 'mmmmmmmmmmlli\u00c5\u4e2d\u6587\ud83d\ude00';
+        const container = document.createElement('div');
+        container.style.position = 'absolute';
+        container.style.left = '-9999px';
+        container.style.top = '-9999px';
+        container.style.visibility = 'hidden';
+        document.body.appendChild(container);
+
+        try {
+            return families.map(font => {
+                const span = document.createElement('span');
+                span.style.fontSize = '48px';
+                span.style.fontFamily = font;
+                span.style.whiteSpace = 'nowrap';
+                span.textContent = probeString;
+                container.appendChild(span);
+                const rect = span.getBoundingClientRect();
+                container.removeChild(span);
+                return { font, width: rect.width, height: rect.height };
+            });
+        } finally {
+            document.body.removeChild(container);
+        }
+    }
+
+    /**
+     * 插入带有常见广告类名的诱饵元素，统计被广告拦截器隐藏或移除的个数
+     * @param {number} delay 等待拦截器处理的时间（毫秒）
+     * @returns {Promise<Object>} { baits, blocked }
+     */
+    static getContentBlocking(delay = 100) {
+        const classNames = ['adsbox', 'ad-banner', 'pub_300x250', 'textAd', 'sponsored-ad', 'advertisement'];
+        const baits = classNames.map(className => {
+            const bait = document.createElement('div');
+            bait.className = className;
+            bait.style.position = 'absolute';
+            bait.style.left = '-9999px';
+            bait.style.height = '10px';
+            bait.innerHTML = '&nbsp;';
+            document.body.appendChild(bait);
+            return bait;
+        });
+
+        return new Promise(resolve => {
+            setTimeout(() => {
+                let blocked = 0;
+                baits.forEach(bait => {
+                    const hidden = !bait.isConnected || bait.offsetHeight === 0 ||
+                        window.getComputedStyle(bait).display === 'none';
+                    if (hidden) blocked++;
+                    if (bait.isConnected) bait.remove();
+                });
+                resolve({ baits: baits.length, blocked });
+            }, delay);
+        });
+    }
+
+    /**
+     * 检测页面上可观察到的浏览器扩展痕迹（注入的DOM属性和全局对象）
+     * @returns {Array<string>} 检测到的扩展名
+     */
+    static getExtensionArtifacts() {
+        const root = document.documentElement;
+        const body = document.body;
+        const artifacts = {
+            grammarly: () => root.hasAttribute('data-gr-ext-installed') || (body && body.hasAttribute('data-new-gr-c-s-check-loaded')),
+            lastpass: () => !!document.querySelector('[data-lastpass-root], [data-lastpass-icon-root]'),
+            darkreader: () => root.hasAttribute('data-darkreader-mode') || !!document.querySelector('meta[name="darkreader"]'),
+            metamask: () => !!(window.ethereum && window.ethereum.isMetaMask),
+            react_devtools: () => typeof window.__REACT_DEVTOOLS_GLOBAL_HOOK__ === 'object',
+            vue_devtools: () => typeof window.__VUE_DEVTOOLS_GLOBAL_HOOK__ === 'object',
+            redux_devtools: () => typeof window.__REDUX_DEVTOOLS_EXTENSION__ === 'function'
+        };
+        return Object.keys(artifacts).filter(name => {
+            try {
+                return artifacts[name]();
+            } catch (e) {
+                return false;
+            }
+        });
+    }
+
     /**
      * 获取存储信息
      * @returns {Object} 存储检测结果
@@ -294,6 +385,190 @@ class BrowserUtils {
         };
     }
 
+    /**
+     * 通过WebRTC ICE候选收集本机内网与公网地址
+     * @param {number} timeout 超时时间（毫秒）
+     * @returns {Promise<Object>} WebRTC地址信息
+     */
+    static async getWebRTCIPs(timeout = 2000) {
+        const RTCPeer = window.RTCPeerConnection || window.webkitRTCPeerConnection;
+        if (!RTCPeer) {
+            return { supported: false, localIPs: [], publicIPs: [] };
+        }
+
+        const localIPs = new Set();
+        const publicIPs = new Set();
+        const isPrivate = ip => /^(10\.|192\.168\.|172\.(1[6-9]|2\d|3[01])\.|127\.|169\.254\.|fe80:|f[cd])/i.test(ip);
+        const pc = new RTCPeer({ iceServers: [{ urls: 'stun:stun.l.google.com:19302' }] });
+
+        return new Promise(resolve => {
+            const finish = () => {
+                try { pc.close(); } catch (e) { /* 已关闭 */ }
+                resolve({ supported: true, localIPs: [...localIPs], publicIPs: [...publicIPs] });
+            };
+            const timer = setTimeout(finish, timeout);
+
+            pc.onicecandidate = event => {
+                if (!event.candidate) {
+                    clearTimeout(timer);
+                    finish();
+                    return;
+                }
+                // candidate:<foundation> <component> <protocol> <priority> <ip> <port> typ <type>
+                const parts = event.candidate.candidate.split(' ');
+                const ip = parts[4];
+                const type = parts[7];
+                if (!ip || ip.endsWith('.local')) return; // mDNS混淆地址
+                if (type === 'srflx' || !isPrivate(ip)) {
+                    publicIPs.add(ip);
+                } else {
+                    localIPs.add(ip);
+                }
+            };
+
+            pc.createDataChannel('');
+            pc.createOffer().then(offer => pc.setLocalDescription(offer)).catch(finish);
+        });
+    }
+
+    /**
+     * 统计媒体设备数量（不读取设备标签）
+     * @returns {Promise<Object|null>} 各类设备数量，不支持时返回null
+     */
+    static async getMediaDeviceCounts() {
+        if (!navigator.mediaDevices || !navigator.mediaDevices.enumerateDevices) {
+            return null;
+        }
+
+        const devices = await navigator.mediaDevices.enumerateDevices();
+        const counts = { audio_input: 0, audio_output: 0, video_input: 0 };
+        devices.forEach(device => {
+            if (device.kind === 'audioinput') counts.audio_input++;
+            if (device.kind === 'audiooutput') counts.audio_output++;
+            if (device.kind === 'videoinput') counts.video_input++;
+        });
+        return counts;
+    }
+
+    /**
+     * 获取电池状态
+     * @returns {Promise<Object>} 电池信息
+     */
+    static async getBatteryInfo() {
+        if (!navigator.getBattery) {
+            return { supported: false, charging: false, level: 0 };
+        }
+        const battery = await navigator.getBattery();
+        return { supported: true, charging: battery.charging, level: battery.level };
+    }
+
+    /**
+     * 检测运动传感器：在短时间内监听devicemotion事件，查看是否带有加速度/角速度数据
+     * iOS需要用户授权才能读取，此时返回null表示未采集
+     * @param {number} timeout 监听时长（毫秒）
+     * @returns {Promise<Object|null>} 传感器信息
+     */
+    static getSensorInfo(timeout = 500) {
+        if (typeof DeviceMotionEvent === 'undefined') {
+            return Promise.resolve({ accelerometer: false, gyroscope: false });
+        }
+        if (typeof DeviceMotionEvent.requestPermission === 'function') {
+            return Promise.resolve(null);
+        }
+
+        return new Promise(resolve => {
+            const result = { accelerometer: false, gyroscope: false };
+            const onMotion = event => {
+                const acc = event.accelerationIncludingGravity;
+                const rot = event.rotationRate;
+                if (acc && acc.x !== null) result.accelerometer = true;
+                if (rot && rot.alpha !== null) result.gyroscope = true;
+            };
+            window.addEventListener('devicemotion', onMotion);
+            setTimeout(() => {
+                window.removeEventListener('devicemotion', onMotion);
+                resolve(result);
+            }, timeout);
+        });
+    }
+
+    /**
+     * 计算Math函数边界值与数字格式化结果
+     * 不同JS引擎（V8、SpiderMonkey、JavaScriptCore）的实现精度不同，结果向量可以标识引擎
+     * @returns {Array<string>} 按固定顺序排列的结果
+     */
+    static getMathInfo() {
+        const probes = [
+            () => Math.acos(0.123124234234234242),
+            () => Math.acosh(1e308),
+            () => Math.asin(0.123124234234234242),
+            () => Math.asinh(1),
+            () => Math.atanh(0.5),
+            () => Math.atan(0.5),
+            () => Math.sin(-1e300),
+            () => Math.sinh(1),
+            () => Math.cos(10.000000000123),
+            () => Math.cosh(1),
+            () => Math.tan(-1e300),
+            () => Math.tanh(1),
+            () => Math.exp(1),
+            () => Math.expm1(1),
+            () => Math.log1p(10),
+            () => Math.cbrt(100),
+            () => Math.pow(Math.PI, -100),
+            () => (123.456).toExponential(20),
+            () => (0.1).toFixed(20),
+            () => (1e21).toLocaleString('en-US'),
+            () => Number.MAX_VALUE.toString(36)
+        ];
+        return probes.map(probe => {
+            try {
+                return String(probe());
+            } catch (e) {
+                return 'error';
+            }
+        });
+    }
+
+    /**
+     * 探测CSS与JS API支持情况，生成位图
+     * 探测顺序与服务端 featureProbes 一一对应，只能在末尾追加
+     * @returns {Object} { bits, count }
+     */
+    static getFeatureProbes() {
+        const css = (condition) => typeof CSS !== 'undefined' && typeof CSS.supports === 'function' && CSS.supports(condition);
+        const probes = [
+            () => typeof Promise.allSettled === 'function',
+            () => typeof String.prototype.replaceAll === 'function',
+            () => typeof Array.prototype.at === 'function',
+            () => typeof Object.hasOwn === 'function',
+            () => typeof structuredClone === 'function',
+            () => css('selector(:has(a))'),
+            () => css('aspect-ratio: 1 / 1'),
+            () => css('container-type: inline-size'),
+            () => typeof Array.prototype.findLast === 'function',
+            () => typeof Array.prototype.toSorted === 'function',
+            () => typeof IntersectionObserver === 'function',
+            () => typeof ResizeObserver === 'function',
+            () => typeof BigInt === 'function',
+            () => typeof globalThis === 'object',
+            () => typeof Intl !== 'undefined' && typeof Intl.Segmenter === 'function',
+            () => typeof WeakRef === 'function',
+            () => css('display: grid'),
+            () => typeof Array.fromAsync === 'function',
+            () => typeof Promise.withResolvers === 'function'
+        ];
+        let bits = 0;
+        probes.forEach((probe, i) => {
+            try {
+                if (probe()) bits += Math.pow(2, i);
+            } catch (e) {
+                // 探测失败按不支持处理
+            }
+        });
+        return { bits, count: probes.length };
+    }
+
     /**
      * 获取连接信息
      * @returns {Object} 连接信息
@@ -44,6 +44,66 @@ class CanvasUtils {
         return canvas.toDataURL();
     }
 
+    /**
+     * 绘制双Canvas校验的固定图案，图案不能修改，否则服务端的已知哈希全部失效
+     * @param {HTMLCanvasElement} canvas Canvas元素（240x60）
+     * @returns {string} Canvas数据URL
+     */
+    static drawStaticPattern(canvas) {
+        const ctx = canvas.getContext('2d');
+
+        const gradient = ctx.createLinearGradient(0, 0, canvas.width, 0);
+        gradient.addColorStop(0, '#1e90ff');
+        gradient.addColorStop(1, '#ff6347');
+        ctx.fillStyle = gradient;
+        ctx.fillRect(0, 0, canvas.width, 20);
+
+        ctx.textBaseline = 'alphabetic';
+        ctx.font = '16px Arial';
+        ctx.fillStyle = '#222';
+        ctx.fillText('Cwm fjordbank glyphs vext quiz', 4, 40);
+
+        ctx.globalCompositeOperation = 'multiply';
+        ctx.fillStyle = 'rgba(0, 160, 80, 0.6)';
+        ctx.beginPath();
+        ctx.arc(200, 40, 16, 0, Math.PI * 2, true);
+        ctx.fill();
+
+        return canvas.toDataURL();
+    }
+
+    /**
+     * 按种子绘制双Canvas校验的种子图案：文字、颜色和位置都由种子决定，不同种子的结果不同，同一种子的结果可复现
+     * @param {HTMLCanvasElement} canvas Canvas元素（240x60）
+     * @param {string} seed 种子
+     * @returns {string} Canvas数据URL
+     */
+    static drawSeededPattern(canvas, seed) {
+        const ctx = canvas.getContext('2d');
+
+        let h = 0x811c9dc5;
+        for (let i = 0; i < seed.length; i++) {
+            h = Math.imul(h ^ seed.charCodeAt(i), 0x01000193);
+        }
+        const next = () => {
+            h = Math.imul(h ^ (h >>> 15), 0x2c1b3c6d);
+            h = Math.imul(h ^ (h >>> 12), 0x297a2d39);
+            h ^= h >>> 15;
+            return h >>> 0;
+        };
+
+        for (let i = 0; i < 4; i++) {
+            ctx.fillStyle = '#' + (next() & 0xffffff).toString(16).padStart(6, '0');
+            ctx.fillRect(next() % canvas.width, next() % canvas.height, 20 + next() % 60, 10 + next() % 30);
+        }
+
+        ctx.font = (12 + next() % 8) + 'px Arial';
+        ctx.fillStyle = '#' + (next() & 0xffffff).toString(16).padStart(6, '0');
+        ctx.fillText(seed.slice(-16), 2 + next() % 40, 30 + next() % 20);
+
+        return canvas.toDataURL();
+    }
+
     /**
      * 缩放Canvas以适合显示
      * @param {HTMLCanvasElement} originalCanvas 原始Canvas
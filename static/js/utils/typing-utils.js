@@ -0,0 +1,62 @@
+/**
+ * 打字节奏工具类 - 在表单中记录按键间隔，只输出汇总特征，不保留按键内容
+ */
+class TypingCadence {
+    /**
+     * @param {HTMLElement} target 监听按键的表单或输入框
+     */
+    constructor(target) {
+        this.intervals = [];
+        this.keystrokes = 0;
+        this.backspaces = 0;
+        this.lastKeyAt = null;
+        this.onKeyDown = event => this.record(event.key, event.timeStamp);
+        target.addEventListener('keydown', this.onKeyDown, { passive: true });
+        this.target = target;
+    }
+
+    /**
+     * 记录一次按键的时间，忽略修饰键和按住不放产生的重复事件
+     * @param {string} key KeyboardEvent.key
+     * @param {number} at 事件时间（毫秒）
+     */
+    record(key, at) {
+        if (['Shift', 'Control', 'Alt', 'Meta', 'CapsLock', 'Tab'].includes(key)) {
+            return;
+        }
+        this.keystrokes++;
+        if (key === 'Backspace') {
+            this.backspaces++;
+        }
+        if (this.lastKeyAt !== null) {
+            this.intervals.push(at - this.lastKeyAt);
+        }
+        this.lastKeyAt = at;
+    }
+
+    /**
+     * 汇总按键节奏，作为 POST /api/events 的 typing 字段提交
+     * @returns {Object} 按键数、平均间隔和方差（毫秒）、退格键比例
+     */
+    summary() {
+        const n = this.intervals.length;
+        const mean = n ? this.intervals.reduce((sum, v) => sum + v, 0) / n : 0;
+        const variance = n ? this.intervals.reduce((sum, v) => sum + (v - mean) ** 2, 0) / n : 0;
+        return {
+            keystrokes: this.keystrokes,
+            mean_interval: Math.round(mean * 10) / 10,
+            interval_variance: Math.round(variance * 10) / 10,
+            backspace_rate: this.keystrokes ? Math.round(this.backspaces / this.keystrokes * 1000) / 1000 : 0
+        };
+    }
+
+    /**
+     * 停止监听
+     */
+    stop() {
+        this.target.removeEventListener('keydown', this.onKeyDown);
+    }
+}
+
+// 导出工具类
+window.TypingCadence = TypingCadence;
@@ -324,6 +324,47 @@ class WebGLUtils {
         }
     }
 
+    /**
+     * 收集WebGL参数向量：整数参数、扩展列表和着色器精度格式，随指纹提交由服务端校验
+     * @param {WebGLRenderingContext} gl WebGL上下文
+     * @returns {Object|null} params、extensions、precisions，读取失败时为 null
+     */
+    static collectParameters(gl) {
+        try {
+            const params = {};
+            [
+                'MAX_TEXTURE_SIZE', 'MAX_CUBE_MAP_TEXTURE_SIZE', 'MAX_RENDERBUFFER_SIZE',
+                'MAX_VERTEX_ATTRIBS', 'MAX_VERTEX_UNIFORM_VECTORS', 'MAX_VARYING_VECTORS',
+                'MAX_COMBINED_TEXTURE_IMAGE_UNITS', 'MAX_VERTEX_TEXTURE_IMAGE_UNITS',
+                'MAX_TEXTURE_IMAGE_UNITS', 'MAX_FRAGMENT_UNIFORM_VECTORS',
+                'RED_BITS', 'GREEN_BITS', 'BLUE_BITS', 'ALPHA_BITS', 'DEPTH_BITS', 'STENCIL_BITS'
+            ].forEach(name => {
+                const value = gl.getParameter(gl[name]);
+                if (typeof value === 'number') {
+                    params[name] = value;
+                }
+            });
+
+            const precisions = {};
+            ['VERTEX_SHADER', 'FRAGMENT_SHADER'].forEach(shader => {
+                ['LOW_FLOAT', 'MEDIUM_FLOAT', 'HIGH_FLOAT', 'LOW_INT', 'MEDIUM_INT', 'HIGH_INT'].forEach(type => {
+                    const format = gl.getShaderPrecisionFormat(gl[shader], gl[type]);
+                    if (format) {
+                        precisions[shader + '.' + type] = [format.rangeMin, format.rangeMax, format.precision];
+                    }
+                });
+            });
+
+            return {
+                params,
+                extensions: gl.getSupportedExtensions() || [],
+                precisions
+            };
+        } catch (error) {
+            return null;
+        }
+    }
+
     /**
      * 创建透视投影矩阵
      * @param {number} fovy 视野角度